@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.0-devel
+// 	protoc-gen-go v1.36.6
 // 	protoc        v5.26.1
 // source: llmserver.proto
 
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -72,6 +73,264 @@ func (ModelStatus) EnumDescriptor() ([]byte, []int) {
 	return file_llmserver_proto_rawDescGZIP(), []int{0}
 }
 
+type FinishReason int32
+
+const (
+	FinishReason_FINISH_REASON_UNSPECIFIED FinishReason = 0
+	FinishReason_FINISH_REASON_STOP        FinishReason = 1 // end-of-generation or stop token
+	FinishReason_FINISH_REASON_LENGTH      FinishReason = 2 // max_tokens or the slot context budget reached
+)
+
+// Enum value maps for FinishReason.
+var (
+	FinishReason_name = map[int32]string{
+		0: "FINISH_REASON_UNSPECIFIED",
+		1: "FINISH_REASON_STOP",
+		2: "FINISH_REASON_LENGTH",
+	}
+	FinishReason_value = map[string]int32{
+		"FINISH_REASON_UNSPECIFIED": 0,
+		"FINISH_REASON_STOP":        1,
+		"FINISH_REASON_LENGTH":      2,
+	}
+)
+
+func (x FinishReason) Enum() *FinishReason {
+	p := new(FinishReason)
+	*p = x
+	return p
+}
+
+func (x FinishReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FinishReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_llmserver_proto_enumTypes[1].Descriptor()
+}
+
+func (FinishReason) Type() protoreflect.EnumType {
+	return &file_llmserver_proto_enumTypes[1]
+}
+
+func (x FinishReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FinishReason.Descriptor instead.
+func (FinishReason) EnumDescriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{1}
+}
+
+// How generated control tokens such as <|im_end|> are output. End-of-
+// generation and stop tokens end generation and are never output.
+type SpecialTokens int32
+
+const (
+	SpecialTokens_SPECIAL_TOKENS_UNSPECIFIED SpecialTokens = 0 // the server default (--special-tokens)
+	SpecialTokens_SPECIAL_TOKENS_RENDER      SpecialTokens = 1 // as text, like other tokens
+	SpecialTokens_SPECIAL_TOKENS_SKIP        SpecialTokens = 2 // left out; they still count as tokens
+	// Streamed in messages of their own with special_token set and no text,
+	// and left out of the text. Non-streaming calls skip them.
+	SpecialTokens_SPECIAL_TOKENS_EVENT SpecialTokens = 3
+)
+
+// Enum value maps for SpecialTokens.
+var (
+	SpecialTokens_name = map[int32]string{
+		0: "SPECIAL_TOKENS_UNSPECIFIED",
+		1: "SPECIAL_TOKENS_RENDER",
+		2: "SPECIAL_TOKENS_SKIP",
+		3: "SPECIAL_TOKENS_EVENT",
+	}
+	SpecialTokens_value = map[string]int32{
+		"SPECIAL_TOKENS_UNSPECIFIED": 0,
+		"SPECIAL_TOKENS_RENDER":      1,
+		"SPECIAL_TOKENS_SKIP":        2,
+		"SPECIAL_TOKENS_EVENT":       3,
+	}
+)
+
+func (x SpecialTokens) Enum() *SpecialTokens {
+	p := new(SpecialTokens)
+	*p = x
+	return p
+}
+
+func (x SpecialTokens) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SpecialTokens) Descriptor() protoreflect.EnumDescriptor {
+	return file_llmserver_proto_enumTypes[2].Descriptor()
+}
+
+func (SpecialTokens) Type() protoreflect.EnumType {
+	return &file_llmserver_proto_enumTypes[2]
+}
+
+func (x SpecialTokens) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SpecialTokens.Descriptor instead.
+func (SpecialTokens) EnumDescriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{2}
+}
+
+// What happens when the prompt leaves less room in the slot's context than
+// max_tokens asks for.
+type MaxTokensPolicy int32
+
+const (
+	MaxTokensPolicy_MAX_TOKENS_POLICY_UNSPECIFIED MaxTokensPolicy = 0 // the server default (--max-tokens-policy)
+	MaxTokensPolicy_MAX_TOKENS_POLICY_CLAMP       MaxTokensPolicy = 1 // lower max_tokens to the room left
+	MaxTokensPolicy_MAX_TOKENS_POLICY_REJECT      MaxTokensPolicy = 2 // fail with CONTEXT_LENGTH_EXCEEDED
+)
+
+// Enum value maps for MaxTokensPolicy.
+var (
+	MaxTokensPolicy_name = map[int32]string{
+		0: "MAX_TOKENS_POLICY_UNSPECIFIED",
+		1: "MAX_TOKENS_POLICY_CLAMP",
+		2: "MAX_TOKENS_POLICY_REJECT",
+	}
+	MaxTokensPolicy_value = map[string]int32{
+		"MAX_TOKENS_POLICY_UNSPECIFIED": 0,
+		"MAX_TOKENS_POLICY_CLAMP":       1,
+		"MAX_TOKENS_POLICY_REJECT":      2,
+	}
+)
+
+func (x MaxTokensPolicy) Enum() *MaxTokensPolicy {
+	p := new(MaxTokensPolicy)
+	*p = x
+	return p
+}
+
+func (x MaxTokensPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MaxTokensPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_llmserver_proto_enumTypes[3].Descriptor()
+}
+
+func (MaxTokensPolicy) Type() protoreflect.EnumType {
+	return &file_llmserver_proto_enumTypes[3]
+}
+
+func (x MaxTokensPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MaxTokensPolicy.Descriptor instead.
+func (MaxTokensPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{3}
+}
+
+// Where the effective max_tokens of a prediction came from.
+type MaxTokensReason int32
+
+const (
+	MaxTokensReason_MAX_TOKENS_REASON_UNSPECIFIED MaxTokensReason = 0
+	MaxTokensReason_MAX_TOKENS_REASON_REQUESTED   MaxTokensReason = 1 // the request's max_tokens
+	MaxTokensReason_MAX_TOKENS_REASON_CONTEXT     MaxTokensReason = 2 // lowered to the room the prompt left
+)
+
+// Enum value maps for MaxTokensReason.
+var (
+	MaxTokensReason_name = map[int32]string{
+		0: "MAX_TOKENS_REASON_UNSPECIFIED",
+		1: "MAX_TOKENS_REASON_REQUESTED",
+		2: "MAX_TOKENS_REASON_CONTEXT",
+	}
+	MaxTokensReason_value = map[string]int32{
+		"MAX_TOKENS_REASON_UNSPECIFIED": 0,
+		"MAX_TOKENS_REASON_REQUESTED":   1,
+		"MAX_TOKENS_REASON_CONTEXT":     2,
+	}
+)
+
+func (x MaxTokensReason) Enum() *MaxTokensReason {
+	p := new(MaxTokensReason)
+	*p = x
+	return p
+}
+
+func (x MaxTokensReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MaxTokensReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_llmserver_proto_enumTypes[4].Descriptor()
+}
+
+func (MaxTokensReason) Type() protoreflect.EnumType {
+	return &file_llmserver_proto_enumTypes[4]
+}
+
+func (x MaxTokensReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MaxTokensReason.Descriptor instead.
+func (MaxTokensReason) EnumDescriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{4}
+}
+
+type LoadStage int32
+
+const (
+	LoadStage_LOAD_STAGE_UNSPECIFIED LoadStage = 0
+	LoadStage_LOAD_STAGE_READING     LoadStage = 1 // opening the file, parsing GGUF metadata
+	LoadStage_LOAD_STAGE_LOADING     LoadStage = 2 // reading tensors and offloading GPU layers
+	LoadStage_LOAD_STAGE_READY       LoadStage = 3
+)
+
+// Enum value maps for LoadStage.
+var (
+	LoadStage_name = map[int32]string{
+		0: "LOAD_STAGE_UNSPECIFIED",
+		1: "LOAD_STAGE_READING",
+		2: "LOAD_STAGE_LOADING",
+		3: "LOAD_STAGE_READY",
+	}
+	LoadStage_value = map[string]int32{
+		"LOAD_STAGE_UNSPECIFIED": 0,
+		"LOAD_STAGE_READING":     1,
+		"LOAD_STAGE_LOADING":     2,
+		"LOAD_STAGE_READY":       3,
+	}
+)
+
+func (x LoadStage) Enum() *LoadStage {
+	p := new(LoadStage)
+	*p = x
+	return p
+}
+
+func (x LoadStage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LoadStage) Descriptor() protoreflect.EnumDescriptor {
+	return file_llmserver_proto_enumTypes[5].Descriptor()
+}
+
+func (LoadStage) Type() protoreflect.EnumType {
+	return &file_llmserver_proto_enumTypes[5]
+}
+
+func (x LoadStage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LoadStage.Descriptor instead.
+func (LoadStage) EnumDescriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{5}
+}
+
 type Backend int32
 
 const (
@@ -111,11 +370,11 @@ func (x Backend) String() string {
 }
 
 func (Backend) Descriptor() protoreflect.EnumDescriptor {
-	return file_llmserver_proto_enumTypes[1].Descriptor()
+	return file_llmserver_proto_enumTypes[6].Descriptor()
 }
 
 func (Backend) Type() protoreflect.EnumType {
-	return &file_llmserver_proto_enumTypes[1]
+	return &file_llmserver_proto_enumTypes[6]
 }
 
 func (x Backend) Number() protoreflect.EnumNumber {
@@ -124,22 +383,78 @@ func (x Backend) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Backend.Descriptor instead.
 func (Backend) EnumDescriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{1}
+	return file_llmserver_proto_rawDescGZIP(), []int{6}
+}
+
+type ModelEventType int32
+
+const (
+	ModelEventType_MODEL_EVENT_UNSPECIFIED    ModelEventType = 0
+	ModelEventType_MODEL_EVENT_LOAD_STARTED   ModelEventType = 1
+	ModelEventType_MODEL_EVENT_LOAD_COMPLETED ModelEventType = 2
+	ModelEventType_MODEL_EVENT_LOAD_FAILED    ModelEventType = 3
+	ModelEventType_MODEL_EVENT_LOAD_CANCELED  ModelEventType = 4
+	ModelEventType_MODEL_EVENT_UNLOADED       ModelEventType = 5
+)
+
+// Enum value maps for ModelEventType.
+var (
+	ModelEventType_name = map[int32]string{
+		0: "MODEL_EVENT_UNSPECIFIED",
+		1: "MODEL_EVENT_LOAD_STARTED",
+		2: "MODEL_EVENT_LOAD_COMPLETED",
+		3: "MODEL_EVENT_LOAD_FAILED",
+		4: "MODEL_EVENT_LOAD_CANCELED",
+		5: "MODEL_EVENT_UNLOADED",
+	}
+	ModelEventType_value = map[string]int32{
+		"MODEL_EVENT_UNSPECIFIED":    0,
+		"MODEL_EVENT_LOAD_STARTED":   1,
+		"MODEL_EVENT_LOAD_COMPLETED": 2,
+		"MODEL_EVENT_LOAD_FAILED":    3,
+		"MODEL_EVENT_LOAD_CANCELED":  4,
+		"MODEL_EVENT_UNLOADED":       5,
+	}
+)
+
+func (x ModelEventType) Enum() *ModelEventType {
+	p := new(ModelEventType)
+	*p = x
+	return p
+}
+
+func (x ModelEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ModelEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_llmserver_proto_enumTypes[7].Descriptor()
+}
+
+func (ModelEventType) Type() protoreflect.EnumType {
+	return &file_llmserver_proto_enumTypes[7]
+}
+
+func (x ModelEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ModelEventType.Descriptor instead.
+func (ModelEventType) EnumDescriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{7}
 }
 
 type PingRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PingRequest) Reset() {
 	*x = PingRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_llmserver_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *PingRequest) String() string {
@@ -150,7 +465,7 @@ func (*PingRequest) ProtoMessage() {}
 
 func (x *PingRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_llmserver_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -166,18 +481,16 @@ func (*PingRequest) Descriptor() ([]byte, []int) {
 }
 
 type PingResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PingResponse) Reset() {
 	*x = PingResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_llmserver_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *PingResponse) String() string {
@@ -188,7 +501,7 @@ func (*PingResponse) ProtoMessage() {}
 
 func (x *PingResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_llmserver_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -204,22 +517,31 @@ func (*PingResponse) Descriptor() ([]byte, []int) {
 }
 
 type LoadModelRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Path            string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	TrustRemoteCode bool     `protobuf:"varint,2,opt,name=trust_remote_code,json=trustRemoteCode,proto3" json:"trust_remote_code,omitempty"`
-	Backend         *Backend `protobuf:"varint,3,opt,name=backend,proto3,enum=proto.Backend,oneof" json:"backend,omitempty"`
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Path            string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	TrustRemoteCode bool                   `protobuf:"varint,2,opt,name=trust_remote_code,json=trustRemoteCode,proto3" json:"trust_remote_code,omitempty"`
+	Backend         *Backend               `protobuf:"varint,3,opt,name=backend,proto3,enum=proto.Backend,oneof" json:"backend,omitempty"`
+	// Per-model overrides of the server flags. They only apply if this call
+	// starts the load; an already loaded model is returned unchanged.
+	NGpuLayers  *int32  `protobuf:"varint,4,opt,name=n_gpu_layers,json=nGpuLayers,proto3,oneof" json:"n_gpu_layers,omitempty"`
+	UseMmap     *bool   `protobuf:"varint,5,opt,name=use_mmap,json=useMmap,proto3,oneof" json:"use_mmap,omitempty"`
+	UseMlock    *bool   `protobuf:"varint,6,opt,name=use_mlock,json=useMlock,proto3,oneof" json:"use_mlock,omitempty"`
+	KvCacheType *string `protobuf:"bytes,7,opt,name=kv_cache_type,json=kvCacheType,proto3,oneof" json:"kv_cache_type,omitempty"` // f16, q8_0 or q4_0
+	CtxSize     *int32  `protobuf:"varint,8,opt,name=ctx_size,json=ctxSize,proto3,oneof" json:"ctx_size,omitempty"`              // total KV cache size of the shared context
+	// Scheduler knobs while the model is in use, see --max-batch-tokens,
+	// --max-batch-seqs and --batch-wait.
+	MaxBatchTokens *int32 `protobuf:"varint,9,opt,name=max_batch_tokens,json=maxBatchTokens,proto3,oneof" json:"max_batch_tokens,omitempty"`
+	MaxBatchSeqs   *int32 `protobuf:"varint,10,opt,name=max_batch_seqs,json=maxBatchSeqs,proto3,oneof" json:"max_batch_seqs,omitempty"`
+	BatchWaitMs    *int32 `protobuf:"varint,11,opt,name=batch_wait_ms,json=batchWaitMs,proto3,oneof" json:"batch_wait_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *LoadModelRequest) Reset() {
 	*x = LoadModelRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[2]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_llmserver_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *LoadModelRequest) String() string {
@@ -230,7 +552,7 @@ func (*LoadModelRequest) ProtoMessage() {}
 
 func (x *LoadModelRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_llmserver_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -266,21 +588,81 @@ func (x *LoadModelRequest) GetBackend() Backend {
 	return Backend_BACKEND_UNSPECIFIED
 }
 
+func (x *LoadModelRequest) GetNGpuLayers() int32 {
+	if x != nil && x.NGpuLayers != nil {
+		return *x.NGpuLayers
+	}
+	return 0
+}
+
+func (x *LoadModelRequest) GetUseMmap() bool {
+	if x != nil && x.UseMmap != nil {
+		return *x.UseMmap
+	}
+	return false
+}
+
+func (x *LoadModelRequest) GetUseMlock() bool {
+	if x != nil && x.UseMlock != nil {
+		return *x.UseMlock
+	}
+	return false
+}
+
+func (x *LoadModelRequest) GetKvCacheType() string {
+	if x != nil && x.KvCacheType != nil {
+		return *x.KvCacheType
+	}
+	return ""
+}
+
+func (x *LoadModelRequest) GetCtxSize() int32 {
+	if x != nil && x.CtxSize != nil {
+		return *x.CtxSize
+	}
+	return 0
+}
+
+func (x *LoadModelRequest) GetMaxBatchTokens() int32 {
+	if x != nil && x.MaxBatchTokens != nil {
+		return *x.MaxBatchTokens
+	}
+	return 0
+}
+
+func (x *LoadModelRequest) GetMaxBatchSeqs() int32 {
+	if x != nil && x.MaxBatchSeqs != nil {
+		return *x.MaxBatchSeqs
+	}
+	return 0
+}
+
+func (x *LoadModelRequest) GetBatchWaitMs() int32 {
+	if x != nil && x.BatchWaitMs != nil {
+		return *x.BatchWaitMs
+	}
+	return 0
+}
+
 type LoadModelResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Progress    float32                `protobuf:"fixed32,1,opt,name=progress,proto3" json:"progress,omitempty"` // 0..1 over the whole load
+	Stage       LoadStage              `protobuf:"varint,2,opt,name=stage,proto3,enum=proto.LoadStage" json:"stage,omitempty"`
+	BytesLoaded int64                  `protobuf:"varint,3,opt,name=bytes_loaded,json=bytesLoaded,proto3" json:"bytes_loaded,omitempty"` // of the model file
+	BytesTotal  int64                  `protobuf:"varint,4,opt,name=bytes_total,json=bytesTotal,proto3" json:"bytes_total,omitempty"`    // 0 if unknown
+	EtaMs       float32                `protobuf:"fixed32,5,opt,name=eta_ms,json=etaMs,proto3" json:"eta_ms,omitempty"`                  // estimated remaining time, 0 until known
+	// Set with LOAD_STAGE_READY: load settings that were changed to let the
+	// model load.
+	Warnings      []*LoadWarning `protobuf:"bytes,6,rep,name=warnings,proto3" json:"warnings,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Progress float32 `protobuf:"fixed32,1,opt,name=progress,proto3" json:"progress,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *LoadModelResponse) Reset() {
 	*x = LoadModelResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[3]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_llmserver_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *LoadModelResponse) String() string {
@@ -291,7 +673,7 @@ func (*LoadModelResponse) ProtoMessage() {}
 
 func (x *LoadModelResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_llmserver_proto_msgTypes[3]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -313,32 +695,69 @@ func (x *LoadModelResponse) GetProgress() float32 {
 	return 0
 }
 
-type UnloadModelRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *LoadModelResponse) GetStage() LoadStage {
+	if x != nil {
+		return x.Stage
+	}
+	return LoadStage_LOAD_STAGE_UNSPECIFIED
+}
 
-	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+func (x *LoadModelResponse) GetBytesLoaded() int64 {
+	if x != nil {
+		return x.BytesLoaded
+	}
+	return 0
 }
 
-func (x *UnloadModelRequest) Reset() {
-	*x = UnloadModelRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[4]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *LoadModelResponse) GetBytesTotal() int64 {
+	if x != nil {
+		return x.BytesTotal
 	}
+	return 0
 }
 
-func (x *UnloadModelRequest) String() string {
+func (x *LoadModelResponse) GetEtaMs() float32 {
+	if x != nil {
+		return x.EtaMs
+	}
+	return 0
+}
+
+func (x *LoadModelResponse) GetWarnings() []*LoadWarning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+// A load setting that was changed, e.g. reason "CTX_SIZE_CLAMPED" with
+// metadata "requested", "ctx_size" and "limit" ("max_ctx_size",
+// "n_ctx_train" or "memory") when the context size was reduced.
+type LoadWarning struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Metadata      map[string]string      `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadWarning) Reset() {
+	*x = LoadWarning{}
+	mi := &file_llmserver_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadWarning) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UnloadModelRequest) ProtoMessage() {}
+func (*LoadWarning) ProtoMessage() {}
 
-func (x *UnloadModelRequest) ProtoReflect() protoreflect.Message {
+func (x *LoadWarning) ProtoReflect() protoreflect.Message {
 	mi := &file_llmserver_proto_msgTypes[4]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -348,42 +767,2336 @@ func (x *UnloadModelRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UnloadModelRequest.ProtoReflect.Descriptor instead.
-func (*UnloadModelRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use LoadWarning.ProtoReflect.Descriptor instead.
+func (*LoadWarning) Descriptor() ([]byte, []int) {
 	return file_llmserver_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *UnloadModelRequest) GetPath() string {
+func (x *LoadWarning) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *LoadWarning) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *LoadWarning) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// Aborts a running load; LoadModel calls waiting on it fail with CANCELLED.
+type CancelLoadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelLoadRequest) Reset() {
+	*x = CancelLoadRequest{}
+	mi := &file_llmserver_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelLoadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelLoadRequest) ProtoMessage() {}
+
+func (x *CancelLoadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelLoadRequest.ProtoReflect.Descriptor instead.
+func (*CancelLoadRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancelLoadRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type CancelLoadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelLoadResponse) Reset() {
+	*x = CancelLoadResponse{}
+	mi := &file_llmserver_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelLoadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelLoadResponse) ProtoMessage() {}
+
+func (x *CancelLoadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelLoadResponse.ProtoReflect.Descriptor instead.
+func (*CancelLoadResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{6}
+}
+
+// Returns the llama.cpp output captured while a loaded model was loading.
+type GetLoadLogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLoadLogRequest) Reset() {
+	*x = GetLoadLogRequest{}
+	mi := &file_llmserver_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLoadLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLoadLogRequest) ProtoMessage() {}
+
+func (x *GetLoadLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLoadLogRequest.ProtoReflect.Descriptor instead.
+func (*GetLoadLogRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetLoadLogRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type GetLoadLogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lines         []string               `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLoadLogResponse) Reset() {
+	*x = GetLoadLogResponse{}
+	mi := &file_llmserver_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLoadLogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLoadLogResponse) ProtoMessage() {}
+
+func (x *GetLoadLogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLoadLogResponse.ProtoReflect.Descriptor instead.
+func (*GetLoadLogResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetLoadLogResponse) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+// Describes a loaded model, including the special tokens clients need to
+// build raw prompts for it.
+type GetModelInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetModelInfoRequest) Reset() {
+	*x = GetModelInfoRequest{}
+	mi := &file_llmserver_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetModelInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModelInfoRequest) ProtoMessage() {}
+
+func (x *GetModelInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModelInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetModelInfoRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetModelInfoRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type SpecialToken struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Piece         string                 `protobuf:"bytes,2,opt,name=piece,proto3" json:"piece,omitempty"` // rendered text, e.g. "<|im_end|>"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SpecialToken) Reset() {
+	*x = SpecialToken{}
+	mi := &file_llmserver_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpecialToken) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpecialToken) ProtoMessage() {}
+
+func (x *SpecialToken) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpecialToken.ProtoReflect.Descriptor instead.
+func (*SpecialToken) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SpecialToken) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SpecialToken) GetPiece() string {
+	if x != nil {
+		return x.Piece
+	}
+	return ""
+}
+
+type GetModelInfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Description   string                 `protobuf:"bytes,1,opt,name=description,proto3" json:"description,omitempty"` // e.g. "qwen2 1.5B Q4_K - Medium"
+	Size          uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`              // bytes
+	NParams       uint64                 `protobuf:"varint,3,opt,name=n_params,json=nParams,proto3" json:"n_params,omitempty"`
+	AddBos        bool                   `protobuf:"varint,4,opt,name=add_bos,json=addBos,proto3" json:"add_bos,omitempty"` // the tokenizer prepends BOS to prompts
+	Bos           *SpecialToken          `protobuf:"bytes,5,opt,name=bos,proto3" json:"bos,omitempty"`                      // unset if the vocab has no such token
+	Eos           *SpecialToken          `protobuf:"bytes,6,opt,name=eos,proto3" json:"eos,omitempty"`
+	Eot           *SpecialToken          `protobuf:"bytes,7,opt,name=eot,proto3" json:"eot,omitempty"`
+	NCtxTrain     int32                  `protobuf:"varint,8,opt,name=n_ctx_train,json=nCtxTrain,proto3" json:"n_ctx_train,omitempty"` // context size the model was trained with
+	NEmbd         int32                  `protobuf:"varint,9,opt,name=n_embd,json=nEmbd,proto3" json:"n_embd,omitempty"`
+	NLayer        int32                  `protobuf:"varint,10,opt,name=n_layer,json=nLayer,proto3" json:"n_layer,omitempty"`
+	NHead         int32                  `protobuf:"varint,11,opt,name=n_head,json=nHead,proto3" json:"n_head,omitempty"`
+	RopeType      string                 `protobuf:"bytes,12,opt,name=rope_type,json=ropeType,proto3" json:"rope_type,omitempty"` // "none", "norm", "neox", "mrope" or "vision"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetModelInfoResponse) Reset() {
+	*x = GetModelInfoResponse{}
+	mi := &file_llmserver_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetModelInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModelInfoResponse) ProtoMessage() {}
+
+func (x *GetModelInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModelInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetModelInfoResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetModelInfoResponse) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *GetModelInfoResponse) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *GetModelInfoResponse) GetNParams() uint64 {
+	if x != nil {
+		return x.NParams
+	}
+	return 0
+}
+
+func (x *GetModelInfoResponse) GetAddBos() bool {
+	if x != nil {
+		return x.AddBos
+	}
+	return false
+}
+
+func (x *GetModelInfoResponse) GetBos() *SpecialToken {
+	if x != nil {
+		return x.Bos
+	}
+	return nil
+}
+
+func (x *GetModelInfoResponse) GetEos() *SpecialToken {
+	if x != nil {
+		return x.Eos
+	}
+	return nil
+}
+
+func (x *GetModelInfoResponse) GetEot() *SpecialToken {
+	if x != nil {
+		return x.Eot
+	}
+	return nil
+}
+
+func (x *GetModelInfoResponse) GetNCtxTrain() int32 {
+	if x != nil {
+		return x.NCtxTrain
+	}
+	return 0
+}
+
+func (x *GetModelInfoResponse) GetNEmbd() int32 {
+	if x != nil {
+		return x.NEmbd
+	}
+	return 0
+}
+
+func (x *GetModelInfoResponse) GetNLayer() int32 {
+	if x != nil {
+		return x.NLayer
+	}
+	return 0
+}
+
+func (x *GetModelInfoResponse) GetNHead() int32 {
+	if x != nil {
+		return x.NHead
+	}
+	return 0
+}
+
+func (x *GetModelInfoResponse) GetRopeType() string {
+	if x != nil {
+		return x.RopeType
+	}
+	return ""
+}
+
+type UnloadModelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadModelRequest) Reset() {
+	*x = UnloadModelRequest{}
+	mi := &file_llmserver_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadModelRequest) ProtoMessage() {}
+
+func (x *UnloadModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadModelRequest.ProtoReflect.Descriptor instead.
+func (*UnloadModelRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UnloadModelRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type UnloadModelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadModelResponse) Reset() {
+	*x = UnloadModelResponse{}
+	mi := &file_llmserver_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadModelResponse) ProtoMessage() {}
+
+func (x *UnloadModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadModelResponse.ProtoReflect.Descriptor instead.
+func (*UnloadModelResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{13}
+}
+
+// One turn of a conversation for PredictRequest.messages.
+type Message struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"` // "system", "user", "assistant" or "tool"
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_llmserver_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Message) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+// An image attached to a prompt for a vision model.
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`                         // encoded image file
+	MimeType      string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"` // e.g. "image/png"; detected from data if empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_llmserver_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Image) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Image) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+type PredictRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Model path or alias (--model-alias); empty uses --default-model.
+	Model string `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	// The raw prompt. Alternatively set messages and leave this empty.
+	Prompt    string `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Stream    bool   `protobuf:"varint,3,opt,name=stream,proto3" json:"stream,omitempty"`
+	MaxTokens int32  `protobuf:"varint,4,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	// 0 decodes greedily, taking the most likely token at each step; the
+	// other sampling options are then ignored. top_k 1 does the same.
+	Temperature float32                 `protobuf:"fixed32,5,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	TopP        float32                 `protobuf:"fixed32,6,opt,name=top_p,json=topP,proto3" json:"top_p,omitempty"`
+	TopK        int32                   `protobuf:"varint,7,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	Options     *PredictRequest_Options `protobuf:"bytes,8,opt,name=options,proto3" json:"options,omitempty"`
+	// The conversation to continue, rendered by the server with the ChatML
+	// chat template and ending with an open assistant turn. Mutually exclusive
+	// with prompt.
+	Messages []*Message `protobuf:"bytes,9,rep,name=messages,proto3" json:"messages,omitempty"`
+	// Images for a vision model. They need a multimodal projector loaded with
+	// the model; without one the request fails with NO_PROJECTOR.
+	Images        []*Image `protobuf:"bytes,10,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PredictRequest) Reset() {
+	*x = PredictRequest{}
+	mi := &file_llmserver_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PredictRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PredictRequest) ProtoMessage() {}
+
+func (x *PredictRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PredictRequest.ProtoReflect.Descriptor instead.
+func (*PredictRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PredictRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *PredictRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *PredictRequest) GetStream() bool {
+	if x != nil {
+		return x.Stream
+	}
+	return false
+}
+
+func (x *PredictRequest) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *PredictRequest) GetTemperature() float32 {
+	if x != nil {
+		return x.Temperature
+	}
+	return 0
+}
+
+func (x *PredictRequest) GetTopP() float32 {
+	if x != nil {
+		return x.TopP
+	}
+	return 0
+}
+
+func (x *PredictRequest) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+func (x *PredictRequest) GetOptions() *PredictRequest_Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *PredictRequest) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *PredictRequest) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+// A message of PredictUpload, a Predict whose prompt is sent in pieces so
+// that it may exceed the gRPC message size limit. The first message carries
+// the request, whose prompt is the first piece or empty; the following ones
+// carry the next pieces of the prompt in prompt_chunk. Pieces may split
+// UTF-8 characters. The server starts predicting when the client closes its
+// side of the stream, and then responds as to Predict.
+type PredictUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Request       *PredictRequest        `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	PromptChunk   []byte                 `protobuf:"bytes,2,opt,name=prompt_chunk,json=promptChunk,proto3" json:"prompt_chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PredictUploadRequest) Reset() {
+	*x = PredictUploadRequest{}
+	mi := &file_llmserver_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PredictUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PredictUploadRequest) ProtoMessage() {}
+
+func (x *PredictUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PredictUploadRequest.ProtoReflect.Descriptor instead.
+func (*PredictUploadRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PredictUploadRequest) GetRequest() *PredictRequest {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *PredictUploadRequest) GetPromptChunk() []byte {
+	if x != nil {
+		return x.PromptChunk
+	}
+	return nil
+}
+
+type PredictResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Message []byte                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// The last token of the message; token_ids lists all of them.
+	Token int32 `protobuf:"varint,2,opt,name=token,proto3" json:"token,omitempty"`
+	// prompt_tokens + completion_tokens.
+	Tokens int32 `protobuf:"varint,3,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	// Set on progress/keepalive messages sent while the prompt is processed,
+	// before the first token. Such messages carry no text.
+	PrefillProgress *PrefillProgress `protobuf:"bytes,4,opt,name=prefill_progress,json=prefillProgress,proto3" json:"prefill_progress,omitempty"`
+	// Set on the final response only. When streaming, it is sent in an extra
+	// message without text after the last token.
+	Timings *PredictTimings `protobuf:"bytes,5,opt,name=timings,proto3" json:"timings,omitempty"`
+	// On streamed text messages, the prompt length and the number of tokens
+	// generated so far, the message's included; on the final response, the
+	// totals.
+	PromptTokens     int32 `protobuf:"varint,6,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int32 `protobuf:"varint,7,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	// Set on the final response only, like timings.
+	FinishReason FinishReason `protobuf:"varint,8,opt,name=finish_reason,json=finishReason,proto3,enum=proto.FinishReason" json:"finish_reason,omitempty"`
+	// Set on messages sent while the server auto-loads the requested model,
+	// before prefill starts. Such messages carry no text.
+	LoadProgress *LoadModelResponse `protobuf:"bytes,9,opt,name=load_progress,json=loadProgress,proto3" json:"load_progress,omitempty"`
+	// Set on the final response of a prompt-lookup prediction.
+	DraftTokens         int32 `protobuf:"varint,10,opt,name=draft_tokens,json=draftTokens,proto3" json:"draft_tokens,omitempty"`
+	DraftAcceptedTokens int32 `protobuf:"varint,11,opt,name=draft_accepted_tokens,json=draftAcceptedTokens,proto3" json:"draft_accepted_tokens,omitempty"`
+	// Set on messages carrying a control token in SPECIAL_TOKENS_EVENT mode.
+	// Such messages carry no text.
+	SpecialToken *SpecialToken `protobuf:"bytes,12,opt,name=special_token,json=specialToken,proto3" json:"special_token,omitempty"`
+	// Set on the final response when the completion was replayed from the
+	// server's completion cache; timings then only cover the replay.
+	Cached bool `protobuf:"varint,13,opt,name=cached,proto3" json:"cached,omitempty"`
+	// Set on the final response: annotations added by the server's
+	// post-processing hooks, e.g. guardrail verdicts.
+	Annotations map[string]string `protobuf:"bytes,14,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Set on the final response of a beam search with return_beams: all
+	// finished beams, best first. The first one is the returned text.
+	Beams []*Beam `protobuf:"bytes,15,rep,name=beams,proto3" json:"beams,omitempty"`
+	// Set on the final response: the token limit the prediction ran with and
+	// whether it is the requested max_tokens or the room left in the context.
+	MaxTokens       int32           `protobuf:"varint,16,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	MaxTokensReason MaxTokensReason `protobuf:"varint,17,opt,name=max_tokens_reason,json=maxTokensReason,proto3,enum=proto.MaxTokensReason" json:"max_tokens_reason,omitempty"`
+	// Set on streamed text messages: the IDs of the tokens the message was
+	// decoded from, several if they were coalesced (stream_interval_tokens,
+	// stream_interval_ms), e.g. to detokenize or cache on the client. A beam
+	// search streams its best beam as one message.
+	TokenIds      []int32 `protobuf:"varint,18,rep,packed,name=token_ids,json=tokenIds,proto3" json:"token_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PredictResponse) Reset() {
+	*x = PredictResponse{}
+	mi := &file_llmserver_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PredictResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PredictResponse) ProtoMessage() {}
+
+func (x *PredictResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PredictResponse.ProtoReflect.Descriptor instead.
+func (*PredictResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PredictResponse) GetMessage() []byte {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetToken() int32 {
+	if x != nil {
+		return x.Token
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetPrefillProgress() *PrefillProgress {
+	if x != nil {
+		return x.PrefillProgress
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetTimings() *PredictTimings {
+	if x != nil {
+		return x.Timings
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetFinishReason() FinishReason {
+	if x != nil {
+		return x.FinishReason
+	}
+	return FinishReason_FINISH_REASON_UNSPECIFIED
+}
+
+func (x *PredictResponse) GetLoadProgress() *LoadModelResponse {
+	if x != nil {
+		return x.LoadProgress
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetDraftTokens() int32 {
+	if x != nil {
+		return x.DraftTokens
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetDraftAcceptedTokens() int32 {
+	if x != nil {
+		return x.DraftAcceptedTokens
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetSpecialToken() *SpecialToken {
+	if x != nil {
+		return x.SpecialToken
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *PredictResponse) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetBeams() []*Beam {
+	if x != nil {
+		return x.Beams
+	}
+	return nil
+}
+
+func (x *PredictResponse) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *PredictResponse) GetMaxTokensReason() MaxTokensReason {
+	if x != nil {
+		return x.MaxTokensReason
+	}
+	return MaxTokensReason_MAX_TOKENS_REASON_UNSPECIFIED
+}
+
+func (x *PredictResponse) GetTokenIds() []int32 {
+	if x != nil {
+		return x.TokenIds
+	}
+	return nil
+}
+
+type Beam struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Tokens        int32                  `protobuf:"varint,2,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	Logprob       float64                `protobuf:"fixed64,3,opt,name=logprob,proto3" json:"logprob,omitempty"` // sum of the token log probabilities
+	Score         float64                `protobuf:"fixed64,4,opt,name=score,proto3" json:"score,omitempty"`     // logprob / tokens^length_penalty; beams are ranked by it
+	FinishReason  FinishReason           `protobuf:"varint,5,opt,name=finish_reason,json=finishReason,proto3,enum=proto.FinishReason" json:"finish_reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Beam) Reset() {
+	*x = Beam{}
+	mi := &file_llmserver_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Beam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Beam) ProtoMessage() {}
+
+func (x *Beam) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Beam.ProtoReflect.Descriptor instead.
+func (*Beam) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Beam) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Beam) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *Beam) GetLogprob() float64 {
+	if x != nil {
+		return x.Logprob
+	}
+	return 0
+}
+
+func (x *Beam) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Beam) GetFinishReason() FinishReason {
+	if x != nil {
+		return x.FinishReason
+	}
+	return FinishReason_FINISH_REASON_UNSPECIFIED
+}
+
+// Fetches the result of a Predict call by its request ID (the x-request-id
+// metadata), e.g. after the client lost the stream. Waits for the generation
+// to finish. Needs --result-ttl; only the client's own requests are found.
+type GetResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResultRequest) Reset() {
+	*x = GetResultRequest{}
+	mi := &file_llmserver_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultRequest) ProtoMessage() {}
+
+func (x *GetResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultRequest.ProtoReflect.Descriptor instead.
+func (*GetResultRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetResultRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+// Scores a text under the model without generating: the log-likelihood of
+// each token given the ones before it.
+type ScoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	TokenLogprobs bool                   `protobuf:"varint,3,opt,name=token_logprobs,json=tokenLogprobs,proto3" json:"token_logprobs,omitempty"` // also return the per-token log probabilities
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoreRequest) Reset() {
+	*x = ScoreRequest{}
+	mi := &file_llmserver_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScoreRequest) ProtoMessage() {}
+
+func (x *ScoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScoreRequest.ProtoReflect.Descriptor instead.
+func (*ScoreRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ScoreRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ScoreRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ScoreRequest) GetTokenLogprobs() bool {
+	if x != nil {
+		return x.TokenLogprobs
+	}
+	return false
+}
+
+type ScoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tokens        int32                  `protobuf:"varint,1,opt,name=tokens,proto3" json:"tokens,omitempty"`                                     // scored tokens: all but the first
+	LogLikelihood float64                `protobuf:"fixed64,2,opt,name=log_likelihood,json=logLikelihood,proto3" json:"log_likelihood,omitempty"` // natural log
+	Perplexity    float64                `protobuf:"fixed64,3,opt,name=perplexity,proto3" json:"perplexity,omitempty"`
+	TokenLogprobs []float32              `protobuf:"fixed32,4,rep,packed,name=token_logprobs,json=tokenLogprobs,proto3" json:"token_logprobs,omitempty"`
+	TotalMs       float32                `protobuf:"fixed32,5,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoreResponse) Reset() {
+	*x = ScoreResponse{}
+	mi := &file_llmserver_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScoreResponse) ProtoMessage() {}
+
+func (x *ScoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScoreResponse.ProtoReflect.Descriptor instead.
+func (*ScoreResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ScoreResponse) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *ScoreResponse) GetLogLikelihood() float64 {
+	if x != nil {
+		return x.LogLikelihood
+	}
+	return 0
+}
+
+func (x *ScoreResponse) GetPerplexity() float64 {
+	if x != nil {
+		return x.Perplexity
+	}
+	return 0
+}
+
+func (x *ScoreResponse) GetTokenLogprobs() []float32 {
+	if x != nil {
+		return x.TokenLogprobs
+	}
+	return nil
+}
+
+func (x *ScoreResponse) GetTotalMs() float32 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+// Computes one L2-normalized embedding per text. Texts of concurrent calls
+// are decoded together, so batching on the client is not required for
+// throughput.
+type EmbedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Texts         []string               `protobuf:"bytes,2,rep,name=texts,proto3" json:"texts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbedRequest) Reset() {
+	*x = EmbedRequest{}
+	mi := &file_llmserver_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbedRequest) ProtoMessage() {}
+
+func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbedRequest.ProtoReflect.Descriptor instead.
+func (*EmbedRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *EmbedRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EmbedRequest) GetTexts() []string {
+	if x != nil {
+		return x.Texts
+	}
+	return nil
+}
+
+type Embedding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	mi := &file_llmserver_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Embedding) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type EmbedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Embeddings    []*Embedding           `protobuf:"bytes,1,rep,name=embeddings,proto3" json:"embeddings,omitempty"` // in the order of the texts
+	PromptTokens  int32                  `protobuf:"varint,2,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	TotalMs       float32                `protobuf:"fixed32,3,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbedResponse) Reset() {
+	*x = EmbedResponse{}
+	mi := &file_llmserver_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbedResponse) ProtoMessage() {}
+
+func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbedResponse.ProtoReflect.Descriptor instead.
+func (*EmbedResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *EmbedResponse) GetEmbeddings() []*Embedding {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+func (x *EmbedResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *EmbedResponse) GetTotalMs() float32 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+// Runs texts through the classification head of a reranker, reward or judge
+// model.
+type ClassifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Texts         []string               `protobuf:"bytes,2,rep,name=texts,proto3" json:"texts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClassifyRequest) Reset() {
+	*x = ClassifyRequest{}
+	mi := &file_llmserver_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClassifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClassifyRequest) ProtoMessage() {}
+
+func (x *ClassifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClassifyRequest.ProtoReflect.Descriptor instead.
+func (*ClassifyRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ClassifyRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ClassifyRequest) GetTexts() []string {
+	if x != nil {
+		return x.Texts
+	}
+	return nil
+}
+
+type LabelScore struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Score         float32                `protobuf:"fixed32,2,opt,name=score,proto3" json:"score,omitempty"`             // raw output
+	Probability   float32                `protobuf:"fixed32,3,opt,name=probability,proto3" json:"probability,omitempty"` // softmax over the labels; sigmoid for a single output
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelScore) Reset() {
+	*x = LabelScore{}
+	mi := &file_llmserver_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelScore) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelScore) ProtoMessage() {}
+
+func (x *LabelScore) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelScore.ProtoReflect.Descriptor instead.
+func (*LabelScore) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *LabelScore) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *LabelScore) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *LabelScore) GetProbability() float32 {
+	if x != nil {
+		return x.Probability
+	}
+	return 0
+}
+
+type Classification struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Labels        []*LabelScore          `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Classification) Reset() {
+	*x = Classification{}
+	mi := &file_llmserver_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Classification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Classification) ProtoMessage() {}
+
+func (x *Classification) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Classification.ProtoReflect.Descriptor instead.
+func (*Classification) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *Classification) GetLabels() []*LabelScore {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type ClassifyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*Classification      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"` // in the order of the texts
+	PromptTokens  int32                  `protobuf:"varint,2,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	TotalMs       float32                `protobuf:"fixed32,3,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClassifyResponse) Reset() {
+	*x = ClassifyResponse{}
+	mi := &file_llmserver_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClassifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClassifyResponse) ProtoMessage() {}
+
+func (x *ClassifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClassifyResponse.ProtoReflect.Descriptor instead.
+func (*ClassifyResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ClassifyResponse) GetResults() []*Classification {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ClassifyResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *ClassifyResponse) GetTotalMs() float32 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+// Scores candidate texts by cosine similarity of their embeddings to the
+// query's, so clients need not fetch the vectors.
+type SimilarityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Candidates    []string               `protobuf:"bytes,3,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SimilarityRequest) Reset() {
+	*x = SimilarityRequest{}
+	mi := &file_llmserver_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SimilarityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SimilarityRequest) ProtoMessage() {}
+
+func (x *SimilarityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SimilarityRequest.ProtoReflect.Descriptor instead.
+func (*SimilarityRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *SimilarityRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *SimilarityRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SimilarityRequest) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+type SimilarityResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scores        []float32              `protobuf:"fixed32,1,rep,packed,name=scores,proto3" json:"scores,omitempty"` // in the order of the candidates, in [-1, 1]
+	PromptTokens  int32                  `protobuf:"varint,2,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	TotalMs       float32                `protobuf:"fixed32,3,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SimilarityResponse) Reset() {
+	*x = SimilarityResponse{}
+	mi := &file_llmserver_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SimilarityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SimilarityResponse) ProtoMessage() {}
+
+func (x *SimilarityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SimilarityResponse.ProtoReflect.Descriptor instead.
+func (*SimilarityResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *SimilarityResponse) GetScores() []float32 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+func (x *SimilarityResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *SimilarityResponse) GetTotalMs() float32 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+// Runs llama-bench style throughput tests on random tokens: prompt
+// processing of n_prompt tokens (ppN) and generation of n_gen tokens (tgN).
+// Zero values default to pp512, tg128 and 3 repetitions; a negative n_prompt
+// or n_gen skips that test.
+type BenchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	NPrompt       int32                  `protobuf:"varint,2,opt,name=n_prompt,json=nPrompt,proto3" json:"n_prompt,omitempty"`
+	NGen          int32                  `protobuf:"varint,3,opt,name=n_gen,json=nGen,proto3" json:"n_gen,omitempty"`
+	Repetitions   int32                  `protobuf:"varint,4,opt,name=repetitions,proto3" json:"repetitions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BenchRequest) Reset() {
+	*x = BenchRequest{}
+	mi := &file_llmserver_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BenchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BenchRequest) ProtoMessage() {}
+
+func (x *BenchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BenchRequest.ProtoReflect.Descriptor instead.
+func (*BenchRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *BenchRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *BenchRequest) GetNPrompt() int32 {
+	if x != nil {
+		return x.NPrompt
+	}
+	return 0
+}
+
+func (x *BenchRequest) GetNGen() int32 {
+	if x != nil {
+		return x.NGen
+	}
+	return 0
+}
+
+func (x *BenchRequest) GetRepetitions() int32 {
+	if x != nil {
+		return x.Repetitions
+	}
+	return 0
+}
+
+type BenchResult struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Test            string                 `protobuf:"bytes,1,opt,name=test,proto3" json:"test,omitempty"` // e.g. "pp512" or "tg128"
+	NPrompt         int32                  `protobuf:"varint,2,opt,name=n_prompt,json=nPrompt,proto3" json:"n_prompt,omitempty"`
+	NGen            int32                  `protobuf:"varint,3,opt,name=n_gen,json=nGen,proto3" json:"n_gen,omitempty"`
+	TokensPerSecond float64                `protobuf:"fixed64,4,opt,name=tokens_per_second,json=tokensPerSecond,proto3" json:"tokens_per_second,omitempty"` // mean over the repetitions
+	Stddev          float64                `protobuf:"fixed64,5,opt,name=stddev,proto3" json:"stddev,omitempty"`
+	SamplesMs       []float32              `protobuf:"fixed32,6,rep,packed,name=samples_ms,json=samplesMs,proto3" json:"samples_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *BenchResult) Reset() {
+	*x = BenchResult{}
+	mi := &file_llmserver_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BenchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BenchResult) ProtoMessage() {}
+
+func (x *BenchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BenchResult.ProtoReflect.Descriptor instead.
+func (*BenchResult) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *BenchResult) GetTest() string {
+	if x != nil {
+		return x.Test
+	}
+	return ""
+}
+
+func (x *BenchResult) GetNPrompt() int32 {
+	if x != nil {
+		return x.NPrompt
+	}
+	return 0
+}
+
+func (x *BenchResult) GetNGen() int32 {
+	if x != nil {
+		return x.NGen
+	}
+	return 0
+}
+
+func (x *BenchResult) GetTokensPerSecond() float64 {
+	if x != nil {
+		return x.TokensPerSecond
+	}
+	return 0
+}
+
+func (x *BenchResult) GetStddev() float64 {
+	if x != nil {
+		return x.Stddev
+	}
+	return 0
+}
+
+func (x *BenchResult) GetSamplesMs() []float32 {
+	if x != nil {
+		return x.SamplesMs
+	}
+	return nil
+}
+
+type BenchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*BenchResult         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BenchResponse) Reset() {
+	*x = BenchResponse{}
+	mi := &file_llmserver_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BenchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BenchResponse) ProtoMessage() {}
+
+func (x *BenchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BenchResponse.ProtoReflect.Descriptor instead.
+func (*BenchResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *BenchResponse) GetResults() []*BenchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type PrefillProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Processed     int32                  `protobuf:"varint,1,opt,name=processed,proto3" json:"processed,omitempty"` // prompt tokens processed so far
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`         // prompt length in tokens
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrefillProgress) Reset() {
+	*x = PrefillProgress{}
+	mi := &file_llmserver_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrefillProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefillProgress) ProtoMessage() {}
+
+func (x *PrefillProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefillProgress.ProtoReflect.Descriptor instead.
+func (*PrefillProgress) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *PrefillProgress) GetProcessed() int32 {
+	if x != nil {
+		return x.Processed
+	}
+	return 0
+}
+
+func (x *PrefillProgress) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// Server-side timings of a prediction, measured in the engine so they are
+// not skewed by network or client buffering.
+type PredictTimings struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	QueueMs            float32                `protobuf:"fixed32,1,opt,name=queue_ms,json=queueMs,proto3" json:"queue_ms,omitempty"`                                        // waiting for a free slot
+	TimeToFirstTokenMs float32                `protobuf:"fixed32,2,opt,name=time_to_first_token_ms,json=timeToFirstTokenMs,proto3" json:"time_to_first_token_ms,omitempty"` // request received until first token sampled
+	TotalMs            float32                `protobuf:"fixed32,3,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	InterTokenLatency  *LatencyDistribution   `protobuf:"bytes,4,opt,name=inter_token_latency,json=interTokenLatency,proto3" json:"inter_token_latency,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *PredictTimings) Reset() {
+	*x = PredictTimings{}
+	mi := &file_llmserver_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PredictTimings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PredictTimings) ProtoMessage() {}
+
+func (x *PredictTimings) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PredictTimings.ProtoReflect.Descriptor instead.
+func (*PredictTimings) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *PredictTimings) GetQueueMs() float32 {
+	if x != nil {
+		return x.QueueMs
+	}
+	return 0
+}
+
+func (x *PredictTimings) GetTimeToFirstTokenMs() float32 {
+	if x != nil {
+		return x.TimeToFirstTokenMs
+	}
+	return 0
+}
+
+func (x *PredictTimings) GetTotalMs() float32 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+func (x *PredictTimings) GetInterTokenLatency() *LatencyDistribution {
+	if x != nil {
+		return x.InterTokenLatency
+	}
+	return nil
+}
+
+type LatencyDistribution struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	MeanMs        float32                `protobuf:"fixed32,2,opt,name=mean_ms,json=meanMs,proto3" json:"mean_ms,omitempty"`
+	P50Ms         float32                `protobuf:"fixed32,3,opt,name=p50_ms,json=p50Ms,proto3" json:"p50_ms,omitempty"`
+	P90Ms         float32                `protobuf:"fixed32,4,opt,name=p90_ms,json=p90Ms,proto3" json:"p90_ms,omitempty"`
+	P99Ms         float32                `protobuf:"fixed32,5,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
+	MaxMs         float32                `protobuf:"fixed32,6,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LatencyDistribution) Reset() {
+	*x = LatencyDistribution{}
+	mi := &file_llmserver_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LatencyDistribution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LatencyDistribution) ProtoMessage() {}
+
+func (x *LatencyDistribution) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LatencyDistribution.ProtoReflect.Descriptor instead.
+func (*LatencyDistribution) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *LatencyDistribution) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *LatencyDistribution) GetMeanMs() float32 {
+	if x != nil {
+		return x.MeanMs
+	}
+	return 0
+}
+
+func (x *LatencyDistribution) GetP50Ms() float32 {
+	if x != nil {
+		return x.P50Ms
+	}
+	return 0
+}
+
+func (x *LatencyDistribution) GetP90Ms() float32 {
+	if x != nil {
+		return x.P90Ms
+	}
+	return 0
+}
+
+func (x *LatencyDistribution) GetP99Ms() float32 {
+	if x != nil {
+		return x.P99Ms
+	}
+	return 0
+}
+
+func (x *LatencyDistribution) GetMaxMs() float32 {
+	if x != nil {
+		return x.MaxMs
+	}
+	return 0
+}
+
+type GetModelStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetModelStatusRequest) Reset() {
+	*x = GetModelStatusRequest{}
+	mi := &file_llmserver_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetModelStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModelStatusRequest) ProtoMessage() {}
+
+func (x *GetModelStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModelStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetModelStatusRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *GetModelStatusRequest) GetPath() string {
 	if x != nil {
 		return x.Path
 	}
 	return ""
 }
 
-type UnloadModelResponse struct {
-	state         protoimpl.MessageState
+type GetModelStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Status        ModelStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=proto.ModelStatus" json:"status,omitempty"`
+	Progress      float32                `protobuf:"fixed32,3,opt,name=progress,proto3" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetModelStatusResponse) Reset() {
+	*x = GetModelStatusResponse{}
+	mi := &file_llmserver_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetModelStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModelStatusResponse) ProtoMessage() {}
+
+func (x *GetModelStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModelStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetModelStatusResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *GetModelStatusResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *GetModelStatusResponse) GetStatus() ModelStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ModelStatus_UNKNOWN
+}
+
+func (x *GetModelStatusResponse) GetProgress() float32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+type ListModelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UnloadModelResponse) Reset() {
-	*x = UnloadModelResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[5]
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	mi := &file_llmserver_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[40]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
+	return mi.MessageOf(x)
 }
 
-func (x *UnloadModelResponse) String() string {
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{40}
+}
+
+type ListModelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	mi := &file_llmserver_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UnloadModelResponse) ProtoMessage() {}
+func (*ListModelsResponse) ProtoMessage() {}
 
-func (x *UnloadModelResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[5]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[41]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -393,44 +3106,140 @@ func (x *UnloadModelResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UnloadModelResponse.ProtoReflect.Descriptor instead.
-func (*UnloadModelResponse) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{41}
 }
 
-type PredictRequest struct {
-	state         protoimpl.MessageState
+type GetServerStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServerStatusRequest) Reset() {
+	*x = GetServerStatusRequest{}
+	mi := &file_llmserver_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServerStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServerStatusRequest) ProtoMessage() {}
+
+func (x *GetServerStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServerStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetServerStatusRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{42}
+}
+
+type TenantQueueStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"` // non-secret identifier derived from the client key
+	Weight        float32                `protobuf:"fixed32,2,opt,name=weight,proto3" json:"weight,omitempty"`
+	QueueDepth    int32                  `protobuf:"varint,3,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-	Model       string                  `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
-	Prompt      string                  `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
-	Stream      bool                    `protobuf:"varint,3,opt,name=stream,proto3" json:"stream,omitempty"`
-	MaxTokens   int32                   `protobuf:"varint,4,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
-	Temperature float32                 `protobuf:"fixed32,5,opt,name=temperature,proto3" json:"temperature,omitempty"`
-	TopP        float32                 `protobuf:"fixed32,6,opt,name=top_p,json=topP,proto3" json:"top_p,omitempty"`
-	TopK        int32                   `protobuf:"varint,7,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
-	Options     *PredictRequest_Options `protobuf:"bytes,8,opt,name=options,proto3" json:"options,omitempty"`
+func (x *TenantQueueStatus) Reset() {
+	*x = TenantQueueStatus{}
+	mi := &file_llmserver_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *PredictRequest) Reset() {
-	*x = PredictRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[6]
+func (x *TenantQueueStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TenantQueueStatus) ProtoMessage() {}
+
+func (x *TenantQueueStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[43]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
+	return mi.MessageOf(x)
 }
 
-func (x *PredictRequest) String() string {
+// Deprecated: Use TenantQueueStatus.ProtoReflect.Descriptor instead.
+func (*TenantQueueStatus) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *TenantQueueStatus) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *TenantQueueStatus) GetWeight() float32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *TenantQueueStatus) GetQueueDepth() int32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+type GetServerStatusResponse struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	NParallel             int32                  `protobuf:"varint,1,opt,name=n_parallel,json=nParallel,proto3" json:"n_parallel,omitempty"`
+	ActiveSlots           int32                  `protobuf:"varint,2,opt,name=active_slots,json=activeSlots,proto3" json:"active_slots,omitempty"`
+	QueueDepth            int32                  `protobuf:"varint,3,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`
+	Tenants               []*TenantQueueStatus   `protobuf:"bytes,4,rep,name=tenants,proto3" json:"tenants,omitempty"`
+	LoadedModels          []string               `protobuf:"bytes,5,rep,name=loaded_models,json=loadedModels,proto3" json:"loaded_models,omitempty"`
+	SystemInfo            *SystemInfo            `protobuf:"bytes,6,opt,name=system_info,json=systemInfo,proto3" json:"system_info,omitempty"`
+	MaxConcurrentRequests int32                  `protobuf:"varint,7,opt,name=max_concurrent_requests,json=maxConcurrentRequests,proto3" json:"max_concurrent_requests,omitempty"` // requests decoded at once, at most n_parallel
+	MaxQueue              int32                  `protobuf:"varint,8,opt,name=max_queue,json=maxQueue,proto3" json:"max_queue,omitempty"`                                          // pending requests before QUEUE_FULL; 0 = unlimited
+	KvCells               int32                  `protobuf:"varint,9,opt,name=kv_cells,json=kvCells,proto3" json:"kv_cells,omitempty"`                                             // size of the shared KV cache; 0 before the first request
+	KvCellsUsed           int32                  `protobuf:"varint,10,opt,name=kv_cells_used,json=kvCellsUsed,proto3" json:"kv_cells_used,omitempty"`                              // KV cache cells held by all slots
+	ModelHealth           []*ModelHealth         `protobuf:"bytes,11,rep,name=model_health,json=modelHealth,proto3" json:"model_health,omitempty"`                                 // last health probe per model; empty if the check is off
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *GetServerStatusResponse) Reset() {
+	*x = GetServerStatusResponse{}
+	mi := &file_llmserver_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServerStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PredictRequest) ProtoMessage() {}
+func (*GetServerStatusResponse) ProtoMessage() {}
 
-func (x *PredictRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[6]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *GetServerStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[44]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -440,95 +3249,251 @@ func (x *PredictRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PredictRequest.ProtoReflect.Descriptor instead.
-func (*PredictRequest) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use GetServerStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetServerStatusResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *PredictRequest) GetModel() string {
+func (x *GetServerStatusResponse) GetNParallel() int32 {
+	if x != nil {
+		return x.NParallel
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetActiveSlots() int32 {
+	if x != nil {
+		return x.ActiveSlots
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetQueueDepth() int32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetTenants() []*TenantQueueStatus {
+	if x != nil {
+		return x.Tenants
+	}
+	return nil
+}
+
+func (x *GetServerStatusResponse) GetLoadedModels() []string {
+	if x != nil {
+		return x.LoadedModels
+	}
+	return nil
+}
+
+func (x *GetServerStatusResponse) GetSystemInfo() *SystemInfo {
+	if x != nil {
+		return x.SystemInfo
+	}
+	return nil
+}
+
+func (x *GetServerStatusResponse) GetMaxConcurrentRequests() int32 {
+	if x != nil {
+		return x.MaxConcurrentRequests
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetMaxQueue() int32 {
+	if x != nil {
+		return x.MaxQueue
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetKvCells() int32 {
+	if x != nil {
+		return x.KvCells
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetKvCellsUsed() int32 {
+	if x != nil {
+		return x.KvCellsUsed
+	}
+	return 0
+}
+
+func (x *GetServerStatusResponse) GetModelHealth() []*ModelHealth {
+	if x != nil {
+		return x.ModelHealth
+	}
+	return nil
+}
+
+type ModelHealth struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Model           string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Healthy         bool                   `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Error           string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`                            // why the probe failed; empty if healthy
+	LatencyMs       float32                `protobuf:"fixed32,4,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"` // time spent generating the probe token
+	CheckedAtUnixMs int64                  `protobuf:"varint,5,opt,name=checked_at_unix_ms,json=checkedAtUnixMs,proto3" json:"checked_at_unix_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ModelHealth) Reset() {
+	*x = ModelHealth{}
+	mi := &file_llmserver_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModelHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelHealth) ProtoMessage() {}
+
+func (x *ModelHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelHealth.ProtoReflect.Descriptor instead.
+func (*ModelHealth) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *ModelHealth) GetModel() string {
 	if x != nil {
 		return x.Model
 	}
-	return ""
+	return ""
+}
+
+func (x *ModelHealth) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
 }
 
-func (x *PredictRequest) GetPrompt() string {
+func (x *ModelHealth) GetError() string {
 	if x != nil {
-		return x.Prompt
+		return x.Error
 	}
 	return ""
 }
 
-func (x *PredictRequest) GetStream() bool {
+func (x *ModelHealth) GetLatencyMs() float32 {
 	if x != nil {
-		return x.Stream
+		return x.LatencyMs
 	}
-	return false
+	return 0
 }
 
-func (x *PredictRequest) GetMaxTokens() int32 {
+func (x *ModelHealth) GetCheckedAtUnixMs() int64 {
 	if x != nil {
-		return x.MaxTokens
+		return x.CheckedAtUnixMs
 	}
 	return 0
 }
 
-func (x *PredictRequest) GetTemperature() float32 {
+type SystemInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CpuFeatures   []string               `protobuf:"bytes,1,rep,name=cpu_features,json=cpuFeatures,proto3" json:"cpu_features,omitempty"` // enabled CPU features, e.g. "AVX2", "F16C"
+	Devices       []*Device              `protobuf:"bytes,2,rep,name=devices,proto3" json:"devices,omitempty"`
+	Raw           string                 `protobuf:"bytes,3,opt,name=raw,proto3" json:"raw,omitempty"` // llama_print_system_info() output
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SystemInfo) Reset() {
+	*x = SystemInfo{}
+	mi := &file_llmserver_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SystemInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SystemInfo) ProtoMessage() {}
+
+func (x *SystemInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[46]
 	if x != nil {
-		return x.Temperature
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *PredictRequest) GetTopP() float32 {
+// Deprecated: Use SystemInfo.ProtoReflect.Descriptor instead.
+func (*SystemInfo) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *SystemInfo) GetCpuFeatures() []string {
 	if x != nil {
-		return x.TopP
+		return x.CpuFeatures
 	}
-	return 0
+	return nil
 }
 
-func (x *PredictRequest) GetTopK() int32 {
+func (x *SystemInfo) GetDevices() []*Device {
 	if x != nil {
-		return x.TopK
+		return x.Devices
 	}
-	return 0
+	return nil
 }
 
-func (x *PredictRequest) GetOptions() *PredictRequest_Options {
+func (x *SystemInfo) GetRaw() string {
 	if x != nil {
-		return x.Options
+		return x.Raw
 	}
-	return nil
+	return ""
 }
 
-type PredictResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+type Device struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // e.g. "CUDA0", "CPU"
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`                                   // cpu, gpu, igpu or accel
+	MemoryFree    uint64                 `protobuf:"varint,4,opt,name=memory_free,json=memoryFree,proto3" json:"memory_free,omitempty"`    // bytes, as of server start
+	MemoryTotal   uint64                 `protobuf:"varint,5,opt,name=memory_total,json=memoryTotal,proto3" json:"memory_total,omitempty"` // bytes
 	unknownFields protoimpl.UnknownFields
-
-	Message []byte `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
-	Token   int32  `protobuf:"varint,2,opt,name=token,proto3" json:"token,omitempty"`
-	Tokens  int32  `protobuf:"varint,3,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PredictResponse) Reset() {
-	*x = PredictResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[7]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_llmserver_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *PredictResponse) String() string {
+func (x *Device) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PredictResponse) ProtoMessage() {}
+func (*Device) ProtoMessage() {}
 
-func (x *PredictResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[7]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[47]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -538,58 +3503,68 @@ func (x *PredictResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PredictResponse.ProtoReflect.Descriptor instead.
-func (*PredictResponse) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{47}
 }
 
-func (x *PredictResponse) GetMessage() []byte {
+func (x *Device) GetName() string {
 	if x != nil {
-		return x.Message
+		return x.Name
 	}
-	return nil
+	return ""
 }
 
-func (x *PredictResponse) GetToken() int32 {
+func (x *Device) GetDescription() string {
 	if x != nil {
-		return x.Token
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Device) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Device) GetMemoryFree() uint64 {
+	if x != nil {
+		return x.MemoryFree
 	}
 	return 0
 }
 
-func (x *PredictResponse) GetTokens() int32 {
+func (x *Device) GetMemoryTotal() uint64 {
 	if x != nil {
-		return x.Tokens
+		return x.MemoryTotal
 	}
 	return 0
 }
 
-type GetModelStatusRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+type GetVersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
-
-	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetModelStatusRequest) Reset() {
-	*x = GetModelStatusRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[8]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+func (x *GetVersionRequest) Reset() {
+	*x = GetVersionRequest{}
+	mi := &file_llmserver_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetModelStatusRequest) String() string {
+func (x *GetVersionRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetModelStatusRequest) ProtoMessage() {}
+func (*GetVersionRequest) ProtoMessage() {}
 
-func (x *GetModelStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[8]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *GetVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[48]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -599,46 +3574,38 @@ func (x *GetModelStatusRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetModelStatusRequest.ProtoReflect.Descriptor instead.
-func (*GetModelStatusRequest) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{8}
-}
-
-func (x *GetModelStatusRequest) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
+// Deprecated: Use GetVersionRequest.ProtoReflect.Descriptor instead.
+func (*GetVersionRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{48}
 }
 
-type GetModelStatusResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Path     string      `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Status   ModelStatus `protobuf:"varint,2,opt,name=status,proto3,enum=proto.ModelStatus" json:"status,omitempty"`
-	Progress float32     `protobuf:"fixed32,3,opt,name=progress,proto3" json:"progress,omitempty"`
+type GetVersionResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Version         string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"` // server release
+	GitCommit       string                 `protobuf:"bytes,2,opt,name=git_commit,json=gitCommit,proto3" json:"git_commit,omitempty"`
+	GoVersion       string                 `protobuf:"bytes,3,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	LlamaCppVersion string                 `protobuf:"bytes,4,opt,name=llama_cpp_version,json=llamaCppVersion,proto3" json:"llama_cpp_version,omitempty"` // llama.cpp build tag, e.g. "b8323"
+	Backends        []string               `protobuf:"bytes,5,rep,name=backends,proto3" json:"backends,omitempty"`                                        // registered GGML backends, e.g. "CPU", "CUDA"
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *GetModelStatusResponse) Reset() {
-	*x = GetModelStatusResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[9]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+func (x *GetVersionResponse) Reset() {
+	*x = GetVersionResponse{}
+	mi := &file_llmserver_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetModelStatusResponse) String() string {
+func (x *GetVersionResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetModelStatusResponse) ProtoMessage() {}
+func (*GetVersionResponse) ProtoMessage() {}
 
-func (x *GetModelStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[9]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *GetVersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[49]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -648,56 +3615,68 @@ func (x *GetModelStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetModelStatusResponse.ProtoReflect.Descriptor instead.
-func (*GetModelStatusResponse) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use GetVersionResponse.ProtoReflect.Descriptor instead.
+func (*GetVersionResponse) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{49}
 }
 
-func (x *GetModelStatusResponse) GetPath() string {
+func (x *GetVersionResponse) GetVersion() string {
 	if x != nil {
-		return x.Path
+		return x.Version
 	}
 	return ""
 }
 
-func (x *GetModelStatusResponse) GetStatus() ModelStatus {
+func (x *GetVersionResponse) GetGitCommit() string {
 	if x != nil {
-		return x.Status
+		return x.GitCommit
 	}
-	return ModelStatus_UNKNOWN
+	return ""
 }
 
-func (x *GetModelStatusResponse) GetProgress() float32 {
+func (x *GetVersionResponse) GetGoVersion() string {
 	if x != nil {
-		return x.Progress
+		return x.GoVersion
 	}
-	return 0
+	return ""
 }
 
-type ListModelsRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *GetVersionResponse) GetLlamaCppVersion() string {
+	if x != nil {
+		return x.LlamaCppVersion
+	}
+	return ""
 }
 
-func (x *ListModelsRequest) Reset() {
-	*x = ListModelsRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[10]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *GetVersionResponse) GetBackends() []string {
+	if x != nil {
+		return x.Backends
 	}
+	return nil
 }
 
-func (x *ListModelsRequest) String() string {
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_llmserver_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListModelsRequest) ProtoMessage() {}
+func (*WatchEventsRequest) ProtoMessage() {}
 
-func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[10]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[50]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -707,35 +3686,38 @@ func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
-func (*ListModelsRequest) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{50}
 }
 
-type ListModelsResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+type ModelEvent struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Type            ModelEventType         `protobuf:"varint,1,opt,name=type,proto3,enum=proto.ModelEventType" json:"type,omitempty"`
+	Path            string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	TimestampUnixMs int64                  `protobuf:"varint,3,opt,name=timestamp_unix_ms,json=timestampUnixMs,proto3" json:"timestamp_unix_ms,omitempty"`
+	Error           string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                                             // set for LOAD_FAILED and LOAD_CANCELED
+	LoadDurationMs  float32                `protobuf:"fixed32,5,opt,name=load_duration_ms,json=loadDurationMs,proto3" json:"load_duration_ms,omitempty"` // set when a load ends
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *ListModelsResponse) Reset() {
-	*x = ListModelsResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[11]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+func (x *ModelEvent) Reset() {
+	*x = ModelEvent{}
+	mi := &file_llmserver_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListModelsResponse) String() string {
+func (x *ModelEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListModelsResponse) ProtoMessage() {}
+func (*ModelEvent) ProtoMessage() {}
 
-func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[11]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *ModelEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_llmserver_proto_msgTypes[51]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -745,37 +3727,112 @@ func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
-func (*ListModelsResponse) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use ModelEvent.ProtoReflect.Descriptor instead.
+func (*ModelEvent) Descriptor() ([]byte, []int) {
+	return file_llmserver_proto_rawDescGZIP(), []int{51}
 }
 
-type PredictRequest_Options struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *ModelEvent) GetType() ModelEventType {
+	if x != nil {
+		return x.Type
+	}
+	return ModelEventType_MODEL_EVENT_UNSPECIFIED
+}
+
+func (x *ModelEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ModelEvent) GetTimestampUnixMs() int64 {
+	if x != nil {
+		return x.TimestampUnixMs
+	}
+	return 0
+}
+
+func (x *ModelEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ModelEvent) GetLoadDurationMs() float32 {
+	if x != nil {
+		return x.LoadDurationMs
+	}
+	return 0
+}
 
-	MinP              *float32 `protobuf:"fixed32,1,opt,name=min_p,json=minP,proto3,oneof" json:"min_p,omitempty"`
-	MinTokensToKeep   *int32   `protobuf:"varint,2,opt,name=min_tokens_to_keep,json=minTokensToKeep,proto3,oneof" json:"min_tokens_to_keep,omitempty"`
-	MaxKvSize         *int32   `protobuf:"varint,3,opt,name=max_kv_size,json=maxKvSize,proto3,oneof" json:"max_kv_size,omitempty"`
-	PrefillStepSize   *int32   `protobuf:"varint,4,opt,name=prefill_step_size,json=prefillStepSize,proto3,oneof" json:"prefill_step_size,omitempty"`
+type PredictRequest_Options struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	MinP  *float32               `protobuf:"fixed32,1,opt,name=min_p,json=minP,proto3,oneof" json:"min_p,omitempty"`
+	// The fewest candidate tokens top_p and min_p may leave, so a small
+	// top_p or a large min_p cannot collapse sampling to greedy. Default 1;
+	// it may not exceed top_k (UNSUPPORTED_OPTION otherwise).
+	MinTokensToKeep *int32 `protobuf:"varint,2,opt,name=min_tokens_to_keep,json=minTokensToKeep,proto3,oneof" json:"min_tokens_to_keep,omitempty"`
+	// Cap the tokens this request may keep in the KV cache (prompt and
+	// output) below the slot size.
+	MaxKvSize *int32 `protobuf:"varint,3,opt,name=max_kv_size,json=maxKvSize,proto3,oneof" json:"max_kv_size,omitempty"`
+	// Evaluate at most this many prompt tokens per engine step, so a long
+	// prompt does not hold up the other requests for a whole batch.
+	PrefillStepSize *int32 `protobuf:"varint,4,opt,name=prefill_step_size,json=prefillStepSize,proto3,oneof" json:"prefill_step_size,omitempty"`
+	// KV cache quantization is chosen per model at load time; these must be
+	// unset or 0, or the call fails with UNSUPPORTED_OPTION.
 	KvBits            *int32   `protobuf:"varint,5,opt,name=kv_bits,json=kvBits,proto3,oneof" json:"kv_bits,omitempty"`
 	KvGroupSize       *int32   `protobuf:"varint,6,opt,name=kv_group_size,json=kvGroupSize,proto3,oneof" json:"kv_group_size,omitempty"`
 	QuantizedKvStart  *int32   `protobuf:"varint,7,opt,name=quantized_kv_start,json=quantizedKvStart,proto3,oneof" json:"quantized_kv_start,omitempty"`
 	RepetitionPenalty *float32 `protobuf:"fixed32,8,opt,name=repetition_penalty,json=repetitionPenalty,proto3,oneof" json:"repetition_penalty,omitempty"`
 	LengthPenalty     *float32 `protobuf:"fixed32,9,opt,name=length_penalty,json=lengthPenalty,proto3,oneof" json:"length_penalty,omitempty"`
-	DiversityPenalty  *float32 `protobuf:"fixed32,10,opt,name=diversity_penalty,json=diversityPenalty,proto3,oneof" json:"diversity_penalty,omitempty"`
-	NoRepeatNgramSize *int32   `protobuf:"varint,11,opt,name=no_repeat_ngram_size,json=noRepeatNgramSize,proto3,oneof" json:"no_repeat_ngram_size,omitempty"`
-	RandomSeed        *int32   `protobuf:"varint,12,opt,name=random_seed,json=randomSeed,proto3,oneof" json:"random_seed,omitempty"`
+	// Not implemented; must be unset or 0.
+	DiversityPenalty *float32 `protobuf:"fixed32,10,opt,name=diversity_penalty,json=diversityPenalty,proto3,oneof" json:"diversity_penalty,omitempty"`
+	// Never generate an n-gram of this size that already occurs in the
+	// prompt or output. Unset or 0 disables.
+	NoRepeatNgramSize *int32 `protobuf:"varint,11,opt,name=no_repeat_ngram_size,json=noRepeatNgramSize,proto3,oneof" json:"no_repeat_ngram_size,omitempty"`
+	RandomSeed        *int32 `protobuf:"varint,12,opt,name=random_seed,json=randomSeed,proto3,oneof" json:"random_seed,omitempty"`
+	// Coalesce streamed tokens into one response every N tokens and/or every
+	// M milliseconds, whichever comes first. Unset or 0 disables the limit.
+	StreamIntervalTokens *int32 `protobuf:"varint,13,opt,name=stream_interval_tokens,json=streamIntervalTokens,proto3,oneof" json:"stream_interval_tokens,omitempty"`
+	StreamIntervalMs     *int32 `protobuf:"varint,14,opt,name=stream_interval_ms,json=streamIntervalMs,proto3,oneof" json:"stream_interval_ms,omitempty"`
+	// Prompt-lookup decoding: draft up to N tokens per step by matching the
+	// last tokens against the prompt and output so far. Speeds up outputs
+	// that copy from the prompt (summaries, extraction). Unset or 0 disables.
+	PromptLookup *int32 `protobuf:"varint,15,opt,name=prompt_lookup,json=promptLookup,proto3,oneof" json:"prompt_lookup,omitempty"`
+	// Constrain the output to match this regular expression (RE2 syntax) in
+	// full. It is compiled to a GBNF grammar; word boundaries and ^/$ other
+	// than at the ends are not supported.
+	Regex *string `protobuf:"bytes,16,opt,name=regex,proto3,oneof" json:"regex,omitempty"`
+	// End generation when the model samples one of these tokens, as with an
+	// end-of-generation token. The stop token is not returned.
+	StopTokenIds []int32 `protobuf:"varint,17,rep,packed,name=stop_token_ids,json=stopTokenIds,proto3" json:"stop_token_ids,omitempty"`
+	// Phrases the output must not contain: the token that would complete one
+	// is never sampled, so the model continues differently. Matching is on
+	// the phrase's tokenization (with and without a leading space).
+	BannedStrings []string `protobuf:"bytes,18,rep,name=banned_strings,json=bannedStrings,proto3" json:"banned_strings,omitempty"`
+	// How generated control tokens are output.
+	SpecialTokens SpecialTokens `protobuf:"varint,19,opt,name=special_tokens,json=specialTokens,proto3,enum=proto.SpecialTokens" json:"special_tokens,omitempty"`
+	// Generate with a beam search over this many beams (at most 16) instead
+	// of sampling; the sampling options are then ignored and length_penalty
+	// normalizes the beam scores. The text arrives in one message when the
+	// search is done. Not combinable with regex, banned_strings or
+	// prompt_lookup. Unset or 1 samples as usual.
+	BeamWidth *int32 `protobuf:"varint,20,opt,name=beam_width,json=beamWidth,proto3,oneof" json:"beam_width,omitempty"`
+	// With beam_width, return all finished beams in the final response.
+	ReturnBeams bool `protobuf:"varint,21,opt,name=return_beams,json=returnBeams,proto3" json:"return_beams,omitempty"`
+	// What to do when the prompt leaves less room than max_tokens.
+	MaxTokensPolicy MaxTokensPolicy `protobuf:"varint,22,opt,name=max_tokens_policy,json=maxTokensPolicy,proto3,enum=proto.MaxTokensPolicy" json:"max_tokens_policy,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *PredictRequest_Options) Reset() {
 	*x = PredictRequest_Options{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_llmserver_proto_msgTypes[12]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_llmserver_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *PredictRequest_Options) String() string {
@@ -785,8 +3842,8 @@ func (x *PredictRequest_Options) String() string {
 func (*PredictRequest_Options) ProtoMessage() {}
 
 func (x *PredictRequest_Options) ProtoReflect() protoreflect.Message {
-	mi := &file_llmserver_proto_msgTypes[12]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_llmserver_proto_msgTypes[53]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -798,7 +3855,7 @@ func (x *PredictRequest_Options) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PredictRequest_Options.ProtoReflect.Descriptor instead.
 func (*PredictRequest_Options) Descriptor() ([]byte, []int) {
-	return file_llmserver_proto_rawDescGZIP(), []int{6, 0}
+	return file_llmserver_proto_rawDescGZIP(), []int{16, 0}
 }
 
 func (x *PredictRequest_Options) GetMinP() float32 {
@@ -885,186 +3942,612 @@ func (x *PredictRequest_Options) GetRandomSeed() int32 {
 	return 0
 }
 
-var File_llmserver_proto protoreflect.FileDescriptor
+func (x *PredictRequest_Options) GetStreamIntervalTokens() int32 {
+	if x != nil && x.StreamIntervalTokens != nil {
+		return *x.StreamIntervalTokens
+	}
+	return 0
+}
+
+func (x *PredictRequest_Options) GetStreamIntervalMs() int32 {
+	if x != nil && x.StreamIntervalMs != nil {
+		return *x.StreamIntervalMs
+	}
+	return 0
+}
+
+func (x *PredictRequest_Options) GetPromptLookup() int32 {
+	if x != nil && x.PromptLookup != nil {
+		return *x.PromptLookup
+	}
+	return 0
+}
+
+func (x *PredictRequest_Options) GetRegex() string {
+	if x != nil && x.Regex != nil {
+		return *x.Regex
+	}
+	return ""
+}
+
+func (x *PredictRequest_Options) GetStopTokenIds() []int32 {
+	if x != nil {
+		return x.StopTokenIds
+	}
+	return nil
+}
+
+func (x *PredictRequest_Options) GetBannedStrings() []string {
+	if x != nil {
+		return x.BannedStrings
+	}
+	return nil
+}
 
-var file_llmserver_proto_rawDesc = []byte{
-	0x0a, 0x0f, 0x6c, 0x6c, 0x6d, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x0d, 0x0a, 0x0b, 0x50, 0x69, 0x6e, 0x67,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x0e, 0x0a, 0x0c, 0x50, 0x69, 0x6e, 0x67, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x8d, 0x01, 0x0a, 0x10, 0x4c, 0x6f, 0x61, 0x64,
-	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04,
-	0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68,
-	0x12, 0x2a, 0x0a, 0x11, 0x74, 0x72, 0x75, 0x73, 0x74, 0x5f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65,
-	0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0f, 0x74, 0x72, 0x75,
-	0x73, 0x74, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x2d, 0x0a, 0x07,
-	0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52,
-	0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x88, 0x01, 0x01, 0x42, 0x0a, 0x0a, 0x08, 0x5f,
-	0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x22, 0x2f, 0x0a, 0x11, 0x4c, 0x6f, 0x61, 0x64, 0x4d,
-	0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08,
-	0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08,
-	0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x22, 0x28, 0x0a, 0x12, 0x55, 0x6e, 0x6c, 0x6f,
-	0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
-	0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61,
-	0x74, 0x68, 0x22, 0x15, 0x0a, 0x13, 0x55, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65,
-	0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0xf5, 0x07, 0x0a, 0x0e, 0x50, 0x72,
-	0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05,
-	0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x64,
-	0x65, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x72, 0x6f, 0x6d, 0x70, 0x74, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x06, 0x70, 0x72, 0x6f, 0x6d, 0x70, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74,
-	0x72, 0x65, 0x61, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x73, 0x74, 0x72, 0x65,
-	0x61, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x61, 0x78, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x73,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6d, 0x61, 0x78, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
-	0x73, 0x12, 0x20, 0x0a, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65,
-	0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74,
-	0x75, 0x72, 0x65, 0x12, 0x13, 0x0a, 0x05, 0x74, 0x6f, 0x70, 0x5f, 0x70, 0x18, 0x06, 0x20, 0x01,
-	0x28, 0x02, 0x52, 0x04, 0x74, 0x6f, 0x70, 0x50, 0x12, 0x13, 0x0a, 0x05, 0x74, 0x6f, 0x70, 0x5f,
-	0x6b, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x74, 0x6f, 0x70, 0x4b, 0x12, 0x37, 0x0a,
-	0x07, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x07, 0x6f,
-	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x1a, 0xf8, 0x05, 0x0a, 0x07, 0x4f, 0x70, 0x74, 0x69, 0x6f,
-	0x6e, 0x73, 0x12, 0x18, 0x0a, 0x05, 0x6d, 0x69, 0x6e, 0x5f, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x02, 0x48, 0x00, 0x52, 0x04, 0x6d, 0x69, 0x6e, 0x50, 0x88, 0x01, 0x01, 0x12, 0x30, 0x0a, 0x12,
-	0x6d, 0x69, 0x6e, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x5f, 0x74, 0x6f, 0x5f, 0x6b, 0x65,
-	0x65, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x0f, 0x6d, 0x69, 0x6e, 0x54,
-	0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x54, 0x6f, 0x4b, 0x65, 0x65, 0x70, 0x88, 0x01, 0x01, 0x12, 0x23,
-	0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x6b, 0x76, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x05, 0x48, 0x02, 0x52, 0x09, 0x6d, 0x61, 0x78, 0x4b, 0x76, 0x53, 0x69, 0x7a, 0x65,
-	0x88, 0x01, 0x01, 0x12, 0x2f, 0x0a, 0x11, 0x70, 0x72, 0x65, 0x66, 0x69, 0x6c, 0x6c, 0x5f, 0x73,
-	0x74, 0x65, 0x70, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x03,
-	0x52, 0x0f, 0x70, 0x72, 0x65, 0x66, 0x69, 0x6c, 0x6c, 0x53, 0x74, 0x65, 0x70, 0x53, 0x69, 0x7a,
-	0x65, 0x88, 0x01, 0x01, 0x12, 0x1c, 0x0a, 0x07, 0x6b, 0x76, 0x5f, 0x62, 0x69, 0x74, 0x73, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x05, 0x48, 0x04, 0x52, 0x06, 0x6b, 0x76, 0x42, 0x69, 0x74, 0x73, 0x88,
-	0x01, 0x01, 0x12, 0x27, 0x0a, 0x0d, 0x6b, 0x76, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x73,
-	0x69, 0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x48, 0x05, 0x52, 0x0b, 0x6b, 0x76, 0x47,
-	0x72, 0x6f, 0x75, 0x70, 0x53, 0x69, 0x7a, 0x65, 0x88, 0x01, 0x01, 0x12, 0x31, 0x0a, 0x12, 0x71,
-	0x75, 0x61, 0x6e, 0x74, 0x69, 0x7a, 0x65, 0x64, 0x5f, 0x6b, 0x76, 0x5f, 0x73, 0x74, 0x61, 0x72,
-	0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x48, 0x06, 0x52, 0x10, 0x71, 0x75, 0x61, 0x6e, 0x74,
-	0x69, 0x7a, 0x65, 0x64, 0x4b, 0x76, 0x53, 0x74, 0x61, 0x72, 0x74, 0x88, 0x01, 0x01, 0x12, 0x32,
-	0x0a, 0x12, 0x72, 0x65, 0x70, 0x65, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x70, 0x65, 0x6e,
-	0x61, 0x6c, 0x74, 0x79, 0x18, 0x08, 0x20, 0x01, 0x28, 0x02, 0x48, 0x07, 0x52, 0x11, 0x72, 0x65,
-	0x70, 0x65, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x88,
-	0x01, 0x01, 0x12, 0x2a, 0x0a, 0x0e, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x5f, 0x70, 0x65, 0x6e,
-	0x61, 0x6c, 0x74, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x02, 0x48, 0x08, 0x52, 0x0d, 0x6c, 0x65,
-	0x6e, 0x67, 0x74, 0x68, 0x50, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x88, 0x01, 0x01, 0x12, 0x30,
-	0x0a, 0x11, 0x64, 0x69, 0x76, 0x65, 0x72, 0x73, 0x69, 0x74, 0x79, 0x5f, 0x70, 0x65, 0x6e, 0x61,
-	0x6c, 0x74, 0x79, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x02, 0x48, 0x09, 0x52, 0x10, 0x64, 0x69, 0x76,
-	0x65, 0x72, 0x73, 0x69, 0x74, 0x79, 0x50, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x88, 0x01, 0x01,
-	0x12, 0x34, 0x0a, 0x14, 0x6e, 0x6f, 0x5f, 0x72, 0x65, 0x70, 0x65, 0x61, 0x74, 0x5f, 0x6e, 0x67,
-	0x72, 0x61, 0x6d, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x05, 0x48, 0x0a,
-	0x52, 0x11, 0x6e, 0x6f, 0x52, 0x65, 0x70, 0x65, 0x61, 0x74, 0x4e, 0x67, 0x72, 0x61, 0x6d, 0x53,
-	0x69, 0x7a, 0x65, 0x88, 0x01, 0x01, 0x12, 0x24, 0x0a, 0x0b, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d,
-	0x5f, 0x73, 0x65, 0x65, 0x64, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x05, 0x48, 0x0b, 0x52, 0x0a, 0x72,
-	0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64, 0x88, 0x01, 0x01, 0x42, 0x08, 0x0a, 0x06,
-	0x5f, 0x6d, 0x69, 0x6e, 0x5f, 0x70, 0x42, 0x15, 0x0a, 0x13, 0x5f, 0x6d, 0x69, 0x6e, 0x5f, 0x74,
-	0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x5f, 0x74, 0x6f, 0x5f, 0x6b, 0x65, 0x65, 0x70, 0x42, 0x0e, 0x0a,
-	0x0c, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x6b, 0x76, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x42, 0x14, 0x0a,
-	0x12, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x69, 0x6c, 0x6c, 0x5f, 0x73, 0x74, 0x65, 0x70, 0x5f, 0x73,
-	0x69, 0x7a, 0x65, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x6b, 0x76, 0x5f, 0x62, 0x69, 0x74, 0x73, 0x42,
-	0x10, 0x0a, 0x0e, 0x5f, 0x6b, 0x76, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x73, 0x69, 0x7a,
-	0x65, 0x42, 0x15, 0x0a, 0x13, 0x5f, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x7a, 0x65, 0x64, 0x5f,
-	0x6b, 0x76, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x42, 0x15, 0x0a, 0x13, 0x5f, 0x72, 0x65, 0x70,
-	0x65, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x70, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x42,
-	0x11, 0x0a, 0x0f, 0x5f, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x5f, 0x70, 0x65, 0x6e, 0x61, 0x6c,
-	0x74, 0x79, 0x42, 0x14, 0x0a, 0x12, 0x5f, 0x64, 0x69, 0x76, 0x65, 0x72, 0x73, 0x69, 0x74, 0x79,
-	0x5f, 0x70, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x42, 0x17, 0x0a, 0x15, 0x5f, 0x6e, 0x6f, 0x5f,
-	0x72, 0x65, 0x70, 0x65, 0x61, 0x74, 0x5f, 0x6e, 0x67, 0x72, 0x61, 0x6d, 0x5f, 0x73, 0x69, 0x7a,
-	0x65, 0x42, 0x0e, 0x0a, 0x0c, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x5f, 0x73, 0x65, 0x65,
-	0x64, 0x22, 0x59, 0x0a, 0x0f, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x14,
-	0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x74,
-	0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x22, 0x2b, 0x0a, 0x15,
-	0x47, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x22, 0x74, 0x0a, 0x16, 0x47, 0x65, 0x74,
-	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x2a, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x12, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x22,
-	0x13, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x22, 0x14, 0x0a, 0x12, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65,
-	0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2a, 0x3f, 0x0a, 0x0b, 0x4d, 0x6f,
-	0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b,
-	0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x4c, 0x4f, 0x41, 0x44, 0x49, 0x4e,
-	0x47, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x4c, 0x4f, 0x41, 0x44, 0x45, 0x44, 0x10, 0x02, 0x12,
-	0x0a, 0x0a, 0x06, 0x46, 0x41, 0x49, 0x4c, 0x45, 0x44, 0x10, 0x03, 0x2a, 0x6a, 0x0a, 0x07, 0x42,
-	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x17, 0x0a, 0x13, 0x42, 0x41, 0x43, 0x4b, 0x45, 0x4e,
-	0x44, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
-	0x15, 0x0a, 0x11, 0x42, 0x41, 0x43, 0x4b, 0x45, 0x4e, 0x44, 0x5f, 0x4c, 0x4c, 0x41, 0x4d, 0x41,
-	0x5f, 0x43, 0x50, 0x50, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x41, 0x43, 0x4b, 0x45, 0x4e,
-	0x44, 0x5f, 0x4d, 0x4c, 0x58, 0x10, 0x02, 0x12, 0x0e, 0x0a, 0x0a, 0x42, 0x41, 0x43, 0x4b, 0x45,
-	0x4e, 0x44, 0x5f, 0x54, 0x46, 0x10, 0x03, 0x12, 0x0e, 0x0a, 0x0a, 0x42, 0x41, 0x43, 0x4b, 0x45,
-	0x4e, 0x44, 0x5f, 0x50, 0x54, 0x10, 0x04, 0x32, 0xc0, 0x01, 0x0a, 0x09, 0x4c, 0x4c, 0x4d, 0x53,
-	0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x31, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x12, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x42, 0x0a, 0x09, 0x4c, 0x6f, 0x61, 0x64,
-	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f,
-	0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x3c, 0x0a, 0x07,
-	0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x12, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x42, 0x38, 0x5a, 0x36, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x6d, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x68, 0x79, 0x70, 0x65, 0x72, 0x6e, 0x65,
-	0x74, 0x69, 0x78, 0x2f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x6c, 0x6c, 0x6d, 0x74, 0x65, 0x73, 0x74,
-	0x2f, 0x6c, 0x6c, 0x6d, 0x67, 0x72, 0x70, 0x63, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *PredictRequest_Options) GetSpecialTokens() SpecialTokens {
+	if x != nil {
+		return x.SpecialTokens
+	}
+	return SpecialTokens_SPECIAL_TOKENS_UNSPECIFIED
+}
+
+func (x *PredictRequest_Options) GetBeamWidth() int32 {
+	if x != nil && x.BeamWidth != nil {
+		return *x.BeamWidth
+	}
+	return 0
+}
+
+func (x *PredictRequest_Options) GetReturnBeams() bool {
+	if x != nil {
+		return x.ReturnBeams
+	}
+	return false
+}
+
+func (x *PredictRequest_Options) GetMaxTokensPolicy() MaxTokensPolicy {
+	if x != nil {
+		return x.MaxTokensPolicy
+	}
+	return MaxTokensPolicy_MAX_TOKENS_POLICY_UNSPECIFIED
 }
 
+var File_llmserver_proto protoreflect.FileDescriptor
+
+const file_llmserver_proto_rawDesc = "" +
+	"\n" +
+	"\x0fllmserver.proto\x12\x05proto\"\r\n" +
+	"\vPingRequest\"\x0e\n" +
+	"\fPingResponse\"\xc7\x04\n" +
+	"\x10LoadModelRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12*\n" +
+	"\x11trust_remote_code\x18\x02 \x01(\bR\x0ftrustRemoteCode\x12-\n" +
+	"\abackend\x18\x03 \x01(\x0e2\x0e.proto.BackendH\x00R\abackend\x88\x01\x01\x12%\n" +
+	"\fn_gpu_layers\x18\x04 \x01(\x05H\x01R\n" +
+	"nGpuLayers\x88\x01\x01\x12\x1e\n" +
+	"\buse_mmap\x18\x05 \x01(\bH\x02R\auseMmap\x88\x01\x01\x12 \n" +
+	"\tuse_mlock\x18\x06 \x01(\bH\x03R\buseMlock\x88\x01\x01\x12'\n" +
+	"\rkv_cache_type\x18\a \x01(\tH\x04R\vkvCacheType\x88\x01\x01\x12\x1e\n" +
+	"\bctx_size\x18\b \x01(\x05H\x05R\actxSize\x88\x01\x01\x12-\n" +
+	"\x10max_batch_tokens\x18\t \x01(\x05H\x06R\x0emaxBatchTokens\x88\x01\x01\x12)\n" +
+	"\x0emax_batch_seqs\x18\n" +
+	" \x01(\x05H\aR\fmaxBatchSeqs\x88\x01\x01\x12'\n" +
+	"\rbatch_wait_ms\x18\v \x01(\x05H\bR\vbatchWaitMs\x88\x01\x01B\n" +
+	"\n" +
+	"\b_backendB\x0f\n" +
+	"\r_n_gpu_layersB\v\n" +
+	"\t_use_mmapB\f\n" +
+	"\n" +
+	"_use_mlockB\x10\n" +
+	"\x0e_kv_cache_typeB\v\n" +
+	"\t_ctx_sizeB\x13\n" +
+	"\x11_max_batch_tokensB\x11\n" +
+	"\x0f_max_batch_seqsB\x10\n" +
+	"\x0e_batch_wait_ms\"\xe2\x01\n" +
+	"\x11LoadModelResponse\x12\x1a\n" +
+	"\bprogress\x18\x01 \x01(\x02R\bprogress\x12&\n" +
+	"\x05stage\x18\x02 \x01(\x0e2\x10.proto.LoadStageR\x05stage\x12!\n" +
+	"\fbytes_loaded\x18\x03 \x01(\x03R\vbytesLoaded\x12\x1f\n" +
+	"\vbytes_total\x18\x04 \x01(\x03R\n" +
+	"bytesTotal\x12\x15\n" +
+	"\x06eta_ms\x18\x05 \x01(\x02R\x05etaMs\x12.\n" +
+	"\bwarnings\x18\x06 \x03(\v2\x12.proto.LoadWarningR\bwarnings\"\xba\x01\n" +
+	"\vLoadWarning\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12<\n" +
+	"\bmetadata\x18\x03 \x03(\v2 .proto.LoadWarning.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"'\n" +
+	"\x11CancelLoadRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x14\n" +
+	"\x12CancelLoadResponse\"'\n" +
+	"\x11GetLoadLogRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"*\n" +
+	"\x12GetLoadLogResponse\x12\x14\n" +
+	"\x05lines\x18\x01 \x03(\tR\x05lines\")\n" +
+	"\x13GetModelInfoRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"4\n" +
+	"\fSpecialToken\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x14\n" +
+	"\x05piece\x18\x02 \x01(\tR\x05piece\"\xf9\x02\n" +
+	"\x14GetModelInfoResponse\x12 \n" +
+	"\vdescription\x18\x01 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x04R\x04size\x12\x19\n" +
+	"\bn_params\x18\x03 \x01(\x04R\anParams\x12\x17\n" +
+	"\aadd_bos\x18\x04 \x01(\bR\x06addBos\x12%\n" +
+	"\x03bos\x18\x05 \x01(\v2\x13.proto.SpecialTokenR\x03bos\x12%\n" +
+	"\x03eos\x18\x06 \x01(\v2\x13.proto.SpecialTokenR\x03eos\x12%\n" +
+	"\x03eot\x18\a \x01(\v2\x13.proto.SpecialTokenR\x03eot\x12\x1e\n" +
+	"\vn_ctx_train\x18\b \x01(\x05R\tnCtxTrain\x12\x15\n" +
+	"\x06n_embd\x18\t \x01(\x05R\x05nEmbd\x12\x17\n" +
+	"\an_layer\x18\n" +
+	" \x01(\x05R\x06nLayer\x12\x15\n" +
+	"\x06n_head\x18\v \x01(\x05R\x05nHead\x12\x1b\n" +
+	"\trope_type\x18\f \x01(\tR\bropeType\"(\n" +
+	"\x12UnloadModelRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x15\n" +
+	"\x13UnloadModelResponse\"7\n" +
+	"\aMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"8\n" +
+	"\x05Image\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1b\n" +
+	"\tmime_type\x18\x02 \x01(\tR\bmimeType\"\xec\f\n" +
+	"\x0ePredictRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x12\x16\n" +
+	"\x06stream\x18\x03 \x01(\bR\x06stream\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x04 \x01(\x05R\tmaxTokens\x12 \n" +
+	"\vtemperature\x18\x05 \x01(\x02R\vtemperature\x12\x13\n" +
+	"\x05top_p\x18\x06 \x01(\x02R\x04topP\x12\x13\n" +
+	"\x05top_k\x18\a \x01(\x05R\x04topK\x127\n" +
+	"\aoptions\x18\b \x01(\v2\x1d.proto.PredictRequest.OptionsR\aoptions\x12*\n" +
+	"\bmessages\x18\t \x03(\v2\x0e.proto.MessageR\bmessages\x12$\n" +
+	"\x06images\x18\n" +
+	" \x03(\v2\f.proto.ImageR\x06images\x1a\x9d\n" +
+	"\n" +
+	"\aOptions\x12\x18\n" +
+	"\x05min_p\x18\x01 \x01(\x02H\x00R\x04minP\x88\x01\x01\x120\n" +
+	"\x12min_tokens_to_keep\x18\x02 \x01(\x05H\x01R\x0fminTokensToKeep\x88\x01\x01\x12#\n" +
+	"\vmax_kv_size\x18\x03 \x01(\x05H\x02R\tmaxKvSize\x88\x01\x01\x12/\n" +
+	"\x11prefill_step_size\x18\x04 \x01(\x05H\x03R\x0fprefillStepSize\x88\x01\x01\x12\x1c\n" +
+	"\akv_bits\x18\x05 \x01(\x05H\x04R\x06kvBits\x88\x01\x01\x12'\n" +
+	"\rkv_group_size\x18\x06 \x01(\x05H\x05R\vkvGroupSize\x88\x01\x01\x121\n" +
+	"\x12quantized_kv_start\x18\a \x01(\x05H\x06R\x10quantizedKvStart\x88\x01\x01\x122\n" +
+	"\x12repetition_penalty\x18\b \x01(\x02H\aR\x11repetitionPenalty\x88\x01\x01\x12*\n" +
+	"\x0elength_penalty\x18\t \x01(\x02H\bR\rlengthPenalty\x88\x01\x01\x120\n" +
+	"\x11diversity_penalty\x18\n" +
+	" \x01(\x02H\tR\x10diversityPenalty\x88\x01\x01\x124\n" +
+	"\x14no_repeat_ngram_size\x18\v \x01(\x05H\n" +
+	"R\x11noRepeatNgramSize\x88\x01\x01\x12$\n" +
+	"\vrandom_seed\x18\f \x01(\x05H\vR\n" +
+	"randomSeed\x88\x01\x01\x129\n" +
+	"\x16stream_interval_tokens\x18\r \x01(\x05H\fR\x14streamIntervalTokens\x88\x01\x01\x121\n" +
+	"\x12stream_interval_ms\x18\x0e \x01(\x05H\rR\x10streamIntervalMs\x88\x01\x01\x12(\n" +
+	"\rprompt_lookup\x18\x0f \x01(\x05H\x0eR\fpromptLookup\x88\x01\x01\x12\x19\n" +
+	"\x05regex\x18\x10 \x01(\tH\x0fR\x05regex\x88\x01\x01\x12$\n" +
+	"\x0estop_token_ids\x18\x11 \x03(\x05R\fstopTokenIds\x12%\n" +
+	"\x0ebanned_strings\x18\x12 \x03(\tR\rbannedStrings\x12;\n" +
+	"\x0especial_tokens\x18\x13 \x01(\x0e2\x14.proto.SpecialTokensR\rspecialTokens\x12\"\n" +
+	"\n" +
+	"beam_width\x18\x14 \x01(\x05H\x10R\tbeamWidth\x88\x01\x01\x12!\n" +
+	"\freturn_beams\x18\x15 \x01(\bR\vreturnBeams\x12B\n" +
+	"\x11max_tokens_policy\x18\x16 \x01(\x0e2\x16.proto.MaxTokensPolicyR\x0fmaxTokensPolicyB\b\n" +
+	"\x06_min_pB\x15\n" +
+	"\x13_min_tokens_to_keepB\x0e\n" +
+	"\f_max_kv_sizeB\x14\n" +
+	"\x12_prefill_step_sizeB\n" +
+	"\n" +
+	"\b_kv_bitsB\x10\n" +
+	"\x0e_kv_group_sizeB\x15\n" +
+	"\x13_quantized_kv_startB\x15\n" +
+	"\x13_repetition_penaltyB\x11\n" +
+	"\x0f_length_penaltyB\x14\n" +
+	"\x12_diversity_penaltyB\x17\n" +
+	"\x15_no_repeat_ngram_sizeB\x0e\n" +
+	"\f_random_seedB\x19\n" +
+	"\x17_stream_interval_tokensB\x15\n" +
+	"\x13_stream_interval_msB\x10\n" +
+	"\x0e_prompt_lookupB\b\n" +
+	"\x06_regexB\r\n" +
+	"\v_beam_width\"j\n" +
+	"\x14PredictUploadRequest\x12/\n" +
+	"\arequest\x18\x01 \x01(\v2\x15.proto.PredictRequestR\arequest\x12!\n" +
+	"\fprompt_chunk\x18\x02 \x01(\fR\vpromptChunk\"\xef\x06\n" +
+	"\x0fPredictResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\fR\amessage\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\x05R\x05token\x12\x16\n" +
+	"\x06tokens\x18\x03 \x01(\x05R\x06tokens\x12A\n" +
+	"\x10prefill_progress\x18\x04 \x01(\v2\x16.proto.PrefillProgressR\x0fprefillProgress\x12/\n" +
+	"\atimings\x18\x05 \x01(\v2\x15.proto.PredictTimingsR\atimings\x12#\n" +
+	"\rprompt_tokens\x18\x06 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\a \x01(\x05R\x10completionTokens\x128\n" +
+	"\rfinish_reason\x18\b \x01(\x0e2\x13.proto.FinishReasonR\ffinishReason\x12=\n" +
+	"\rload_progress\x18\t \x01(\v2\x18.proto.LoadModelResponseR\floadProgress\x12!\n" +
+	"\fdraft_tokens\x18\n" +
+	" \x01(\x05R\vdraftTokens\x122\n" +
+	"\x15draft_accepted_tokens\x18\v \x01(\x05R\x13draftAcceptedTokens\x128\n" +
+	"\rspecial_token\x18\f \x01(\v2\x13.proto.SpecialTokenR\fspecialToken\x12\x16\n" +
+	"\x06cached\x18\r \x01(\bR\x06cached\x12I\n" +
+	"\vannotations\x18\x0e \x03(\v2'.proto.PredictResponse.AnnotationsEntryR\vannotations\x12!\n" +
+	"\x05beams\x18\x0f \x03(\v2\v.proto.BeamR\x05beams\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x10 \x01(\x05R\tmaxTokens\x12B\n" +
+	"\x11max_tokens_reason\x18\x11 \x01(\x0e2\x16.proto.MaxTokensReasonR\x0fmaxTokensReason\x12\x1b\n" +
+	"\ttoken_ids\x18\x12 \x03(\x05R\btokenIds\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x9c\x01\n" +
+	"\x04Beam\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x16\n" +
+	"\x06tokens\x18\x02 \x01(\x05R\x06tokens\x12\x18\n" +
+	"\alogprob\x18\x03 \x01(\x01R\alogprob\x12\x14\n" +
+	"\x05score\x18\x04 \x01(\x01R\x05score\x128\n" +
+	"\rfinish_reason\x18\x05 \x01(\x0e2\x13.proto.FinishReasonR\ffinishReason\"1\n" +
+	"\x10GetResultRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"_\n" +
+	"\fScoreRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12%\n" +
+	"\x0etoken_logprobs\x18\x03 \x01(\bR\rtokenLogprobs\"\xb0\x01\n" +
+	"\rScoreResponse\x12\x16\n" +
+	"\x06tokens\x18\x01 \x01(\x05R\x06tokens\x12%\n" +
+	"\x0elog_likelihood\x18\x02 \x01(\x01R\rlogLikelihood\x12\x1e\n" +
+	"\n" +
+	"perplexity\x18\x03 \x01(\x01R\n" +
+	"perplexity\x12%\n" +
+	"\x0etoken_logprobs\x18\x04 \x03(\x02R\rtokenLogprobs\x12\x19\n" +
+	"\btotal_ms\x18\x05 \x01(\x02R\atotalMs\":\n" +
+	"\fEmbedRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x14\n" +
+	"\x05texts\x18\x02 \x03(\tR\x05texts\"#\n" +
+	"\tEmbedding\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\"\x81\x01\n" +
+	"\rEmbedResponse\x120\n" +
+	"\n" +
+	"embeddings\x18\x01 \x03(\v2\x10.proto.EmbeddingR\n" +
+	"embeddings\x12#\n" +
+	"\rprompt_tokens\x18\x02 \x01(\x05R\fpromptTokens\x12\x19\n" +
+	"\btotal_ms\x18\x03 \x01(\x02R\atotalMs\"=\n" +
+	"\x0fClassifyRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x14\n" +
+	"\x05texts\x18\x02 \x03(\tR\x05texts\"Z\n" +
+	"\n" +
+	"LabelScore\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x02R\x05score\x12 \n" +
+	"\vprobability\x18\x03 \x01(\x02R\vprobability\";\n" +
+	"\x0eClassification\x12)\n" +
+	"\x06labels\x18\x01 \x03(\v2\x11.proto.LabelScoreR\x06labels\"\x83\x01\n" +
+	"\x10ClassifyResponse\x12/\n" +
+	"\aresults\x18\x01 \x03(\v2\x15.proto.ClassificationR\aresults\x12#\n" +
+	"\rprompt_tokens\x18\x02 \x01(\x05R\fpromptTokens\x12\x19\n" +
+	"\btotal_ms\x18\x03 \x01(\x02R\atotalMs\"_\n" +
+	"\x11SimilarityRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x03 \x03(\tR\n" +
+	"candidates\"l\n" +
+	"\x12SimilarityResponse\x12\x16\n" +
+	"\x06scores\x18\x01 \x03(\x02R\x06scores\x12#\n" +
+	"\rprompt_tokens\x18\x02 \x01(\x05R\fpromptTokens\x12\x19\n" +
+	"\btotal_ms\x18\x03 \x01(\x02R\atotalMs\"v\n" +
+	"\fBenchRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x19\n" +
+	"\bn_prompt\x18\x02 \x01(\x05R\anPrompt\x12\x13\n" +
+	"\x05n_gen\x18\x03 \x01(\x05R\x04nGen\x12 \n" +
+	"\vrepetitions\x18\x04 \x01(\x05R\vrepetitions\"\xb4\x01\n" +
+	"\vBenchResult\x12\x12\n" +
+	"\x04test\x18\x01 \x01(\tR\x04test\x12\x19\n" +
+	"\bn_prompt\x18\x02 \x01(\x05R\anPrompt\x12\x13\n" +
+	"\x05n_gen\x18\x03 \x01(\x05R\x04nGen\x12*\n" +
+	"\x11tokens_per_second\x18\x04 \x01(\x01R\x0ftokensPerSecond\x12\x16\n" +
+	"\x06stddev\x18\x05 \x01(\x01R\x06stddev\x12\x1d\n" +
+	"\n" +
+	"samples_ms\x18\x06 \x03(\x02R\tsamplesMs\"=\n" +
+	"\rBenchResponse\x12,\n" +
+	"\aresults\x18\x01 \x03(\v2\x12.proto.BenchResultR\aresults\"E\n" +
+	"\x0fPrefillProgress\x12\x1c\n" +
+	"\tprocessed\x18\x01 \x01(\x05R\tprocessed\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"\xc6\x01\n" +
+	"\x0ePredictTimings\x12\x19\n" +
+	"\bqueue_ms\x18\x01 \x01(\x02R\aqueueMs\x122\n" +
+	"\x16time_to_first_token_ms\x18\x02 \x01(\x02R\x12timeToFirstTokenMs\x12\x19\n" +
+	"\btotal_ms\x18\x03 \x01(\x02R\atotalMs\x12J\n" +
+	"\x13inter_token_latency\x18\x04 \x01(\v2\x1a.proto.LatencyDistributionR\x11interTokenLatency\"\xa0\x01\n" +
+	"\x13LatencyDistribution\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x12\x17\n" +
+	"\amean_ms\x18\x02 \x01(\x02R\x06meanMs\x12\x15\n" +
+	"\x06p50_ms\x18\x03 \x01(\x02R\x05p50Ms\x12\x15\n" +
+	"\x06p90_ms\x18\x04 \x01(\x02R\x05p90Ms\x12\x15\n" +
+	"\x06p99_ms\x18\x05 \x01(\x02R\x05p99Ms\x12\x15\n" +
+	"\x06max_ms\x18\x06 \x01(\x02R\x05maxMs\"+\n" +
+	"\x15GetModelStatusRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"t\n" +
+	"\x16GetModelStatusResponse\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12*\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x12.proto.ModelStatusR\x06status\x12\x1a\n" +
+	"\bprogress\x18\x03 \x01(\x02R\bprogress\"\x13\n" +
+	"\x11ListModelsRequest\"\x14\n" +
+	"\x12ListModelsResponse\"\x18\n" +
+	"\x16GetServerStatusRequest\"d\n" +
+	"\x11TenantQueueStatus\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12\x16\n" +
+	"\x06weight\x18\x02 \x01(\x02R\x06weight\x12\x1f\n" +
+	"\vqueue_depth\x18\x03 \x01(\x05R\n" +
+	"queueDepth\"\xd4\x03\n" +
+	"\x17GetServerStatusResponse\x12\x1d\n" +
+	"\n" +
+	"n_parallel\x18\x01 \x01(\x05R\tnParallel\x12!\n" +
+	"\factive_slots\x18\x02 \x01(\x05R\vactiveSlots\x12\x1f\n" +
+	"\vqueue_depth\x18\x03 \x01(\x05R\n" +
+	"queueDepth\x122\n" +
+	"\atenants\x18\x04 \x03(\v2\x18.proto.TenantQueueStatusR\atenants\x12#\n" +
+	"\rloaded_models\x18\x05 \x03(\tR\floadedModels\x122\n" +
+	"\vsystem_info\x18\x06 \x01(\v2\x11.proto.SystemInfoR\n" +
+	"systemInfo\x126\n" +
+	"\x17max_concurrent_requests\x18\a \x01(\x05R\x15maxConcurrentRequests\x12\x1b\n" +
+	"\tmax_queue\x18\b \x01(\x05R\bmaxQueue\x12\x19\n" +
+	"\bkv_cells\x18\t \x01(\x05R\akvCells\x12\"\n" +
+	"\rkv_cells_used\x18\n" +
+	" \x01(\x05R\vkvCellsUsed\x125\n" +
+	"\fmodel_health\x18\v \x03(\v2\x12.proto.ModelHealthR\vmodelHealth\"\x9f\x01\n" +
+	"\vModelHealth\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x18\n" +
+	"\ahealthy\x18\x02 \x01(\bR\ahealthy\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x04 \x01(\x02R\tlatencyMs\x12+\n" +
+	"\x12checked_at_unix_ms\x18\x05 \x01(\x03R\x0fcheckedAtUnixMs\"j\n" +
+	"\n" +
+	"SystemInfo\x12!\n" +
+	"\fcpu_features\x18\x01 \x03(\tR\vcpuFeatures\x12'\n" +
+	"\adevices\x18\x02 \x03(\v2\r.proto.DeviceR\adevices\x12\x10\n" +
+	"\x03raw\x18\x03 \x01(\tR\x03raw\"\x96\x01\n" +
+	"\x06Device\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1f\n" +
+	"\vmemory_free\x18\x04 \x01(\x04R\n" +
+	"memoryFree\x12!\n" +
+	"\fmemory_total\x18\x05 \x01(\x04R\vmemoryTotal\"\x13\n" +
+	"\x11GetVersionRequest\"\xb4\x01\n" +
+	"\x12GetVersionResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x1d\n" +
+	"\n" +
+	"git_commit\x18\x02 \x01(\tR\tgitCommit\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x03 \x01(\tR\tgoVersion\x12*\n" +
+	"\x11llama_cpp_version\x18\x04 \x01(\tR\x0fllamaCppVersion\x12\x1a\n" +
+	"\bbackends\x18\x05 \x03(\tR\bbackends\"\x14\n" +
+	"\x12WatchEventsRequest\"\xb7\x01\n" +
+	"\n" +
+	"ModelEvent\x12)\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x15.proto.ModelEventTypeR\x04type\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12*\n" +
+	"\x11timestamp_unix_ms\x18\x03 \x01(\x03R\x0ftimestampUnixMs\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12(\n" +
+	"\x10load_duration_ms\x18\x05 \x01(\x02R\x0eloadDurationMs*?\n" +
+	"\vModelStatus\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\v\n" +
+	"\aLOADING\x10\x01\x12\n" +
+	"\n" +
+	"\x06LOADED\x10\x02\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x03*_\n" +
+	"\fFinishReason\x12\x1d\n" +
+	"\x19FINISH_REASON_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12FINISH_REASON_STOP\x10\x01\x12\x18\n" +
+	"\x14FINISH_REASON_LENGTH\x10\x02*}\n" +
+	"\rSpecialTokens\x12\x1e\n" +
+	"\x1aSPECIAL_TOKENS_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15SPECIAL_TOKENS_RENDER\x10\x01\x12\x17\n" +
+	"\x13SPECIAL_TOKENS_SKIP\x10\x02\x12\x18\n" +
+	"\x14SPECIAL_TOKENS_EVENT\x10\x03*o\n" +
+	"\x0fMaxTokensPolicy\x12!\n" +
+	"\x1dMAX_TOKENS_POLICY_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17MAX_TOKENS_POLICY_CLAMP\x10\x01\x12\x1c\n" +
+	"\x18MAX_TOKENS_POLICY_REJECT\x10\x02*t\n" +
+	"\x0fMaxTokensReason\x12!\n" +
+	"\x1dMAX_TOKENS_REASON_UNSPECIFIED\x10\x00\x12\x1f\n" +
+	"\x1bMAX_TOKENS_REASON_REQUESTED\x10\x01\x12\x1d\n" +
+	"\x19MAX_TOKENS_REASON_CONTEXT\x10\x02*m\n" +
+	"\tLoadStage\x12\x1a\n" +
+	"\x16LOAD_STAGE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12LOAD_STAGE_READING\x10\x01\x12\x16\n" +
+	"\x12LOAD_STAGE_LOADING\x10\x02\x12\x14\n" +
+	"\x10LOAD_STAGE_READY\x10\x03*j\n" +
+	"\aBackend\x12\x17\n" +
+	"\x13BACKEND_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11BACKEND_LLAMA_CPP\x10\x01\x12\x0f\n" +
+	"\vBACKEND_MLX\x10\x02\x12\x0e\n" +
+	"\n" +
+	"BACKEND_TF\x10\x03\x12\x0e\n" +
+	"\n" +
+	"BACKEND_PT\x10\x04*\xc1\x01\n" +
+	"\x0eModelEventType\x12\x1b\n" +
+	"\x17MODEL_EVENT_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18MODEL_EVENT_LOAD_STARTED\x10\x01\x12\x1e\n" +
+	"\x1aMODEL_EVENT_LOAD_COMPLETED\x10\x02\x12\x1b\n" +
+	"\x17MODEL_EVENT_LOAD_FAILED\x10\x03\x12\x1d\n" +
+	"\x19MODEL_EVENT_LOAD_CANCELED\x10\x04\x12\x18\n" +
+	"\x14MODEL_EVENT_UNLOADED\x10\x052\xa1\b\n" +
+	"\tLLMServer\x121\n" +
+	"\x04Ping\x12\x12.proto.PingRequest\x1a\x13.proto.PingResponse\"\x00\x12B\n" +
+	"\tLoadModel\x12\x17.proto.LoadModelRequest\x1a\x18.proto.LoadModelResponse\"\x000\x01\x12C\n" +
+	"\n" +
+	"CancelLoad\x12\x18.proto.CancelLoadRequest\x1a\x19.proto.CancelLoadResponse\"\x00\x12C\n" +
+	"\n" +
+	"GetLoadLog\x12\x18.proto.GetLoadLogRequest\x1a\x19.proto.GetLoadLogResponse\"\x00\x12I\n" +
+	"\fGetModelInfo\x12\x1a.proto.GetModelInfoRequest\x1a\x1b.proto.GetModelInfoResponse\"\x00\x12<\n" +
+	"\aPredict\x12\x15.proto.PredictRequest\x1a\x16.proto.PredictResponse\"\x000\x01\x12J\n" +
+	"\rPredictUpload\x12\x1b.proto.PredictUploadRequest\x1a\x16.proto.PredictResponse\"\x00(\x010\x01\x12>\n" +
+	"\tGetResult\x12\x17.proto.GetResultRequest\x1a\x16.proto.PredictResponse\"\x00\x124\n" +
+	"\x05Score\x12\x13.proto.ScoreRequest\x1a\x14.proto.ScoreResponse\"\x00\x124\n" +
+	"\x05Embed\x12\x13.proto.EmbedRequest\x1a\x14.proto.EmbedResponse\"\x00\x12=\n" +
+	"\bClassify\x12\x16.proto.ClassifyRequest\x1a\x17.proto.ClassifyResponse\"\x00\x12C\n" +
+	"\n" +
+	"Similarity\x12\x18.proto.SimilarityRequest\x1a\x19.proto.SimilarityResponse\"\x00\x124\n" +
+	"\x05Bench\x12\x13.proto.BenchRequest\x1a\x14.proto.BenchResponse\"\x00\x12R\n" +
+	"\x0fGetServerStatus\x12\x1d.proto.GetServerStatusRequest\x1a\x1e.proto.GetServerStatusResponse\"\x00\x12C\n" +
+	"\n" +
+	"GetVersion\x12\x18.proto.GetVersionRequest\x1a\x19.proto.GetVersionResponse\"\x00\x12?\n" +
+	"\vWatchEvents\x12\x19.proto.WatchEventsRequest\x1a\x11.proto.ModelEvent\"\x000\x01B1Z/githum.com/hypernetix/llamacpp_server/api/protob\x06proto3"
+
 var (
 	file_llmserver_proto_rawDescOnce sync.Once
-	file_llmserver_proto_rawDescData = file_llmserver_proto_rawDesc
+	file_llmserver_proto_rawDescData []byte
 )
 
 func file_llmserver_proto_rawDescGZIP() []byte {
 	file_llmserver_proto_rawDescOnce.Do(func() {
-		file_llmserver_proto_rawDescData = protoimpl.X.CompressGZIP(file_llmserver_proto_rawDescData)
+		file_llmserver_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_llmserver_proto_rawDesc), len(file_llmserver_proto_rawDesc)))
 	})
 	return file_llmserver_proto_rawDescData
 }
 
-var file_llmserver_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_llmserver_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
-var file_llmserver_proto_goTypes = []interface{}{
-	(ModelStatus)(0),               // 0: proto.ModelStatus
-	(Backend)(0),                   // 1: proto.Backend
-	(*PingRequest)(nil),            // 2: proto.PingRequest
-	(*PingResponse)(nil),           // 3: proto.PingResponse
-	(*LoadModelRequest)(nil),       // 4: proto.LoadModelRequest
-	(*LoadModelResponse)(nil),      // 5: proto.LoadModelResponse
-	(*UnloadModelRequest)(nil),     // 6: proto.UnloadModelRequest
-	(*UnloadModelResponse)(nil),    // 7: proto.UnloadModelResponse
-	(*PredictRequest)(nil),         // 8: proto.PredictRequest
-	(*PredictResponse)(nil),        // 9: proto.PredictResponse
-	(*GetModelStatusRequest)(nil),  // 10: proto.GetModelStatusRequest
-	(*GetModelStatusResponse)(nil), // 11: proto.GetModelStatusResponse
-	(*ListModelsRequest)(nil),      // 12: proto.ListModelsRequest
-	(*ListModelsResponse)(nil),     // 13: proto.ListModelsResponse
-	(*PredictRequest_Options)(nil), // 14: proto.PredictRequest.Options
+var file_llmserver_proto_enumTypes = make([]protoimpl.EnumInfo, 8)
+var file_llmserver_proto_msgTypes = make([]protoimpl.MessageInfo, 55)
+var file_llmserver_proto_goTypes = []any{
+	(ModelStatus)(0),                // 0: proto.ModelStatus
+	(FinishReason)(0),               // 1: proto.FinishReason
+	(SpecialTokens)(0),              // 2: proto.SpecialTokens
+	(MaxTokensPolicy)(0),            // 3: proto.MaxTokensPolicy
+	(MaxTokensReason)(0),            // 4: proto.MaxTokensReason
+	(LoadStage)(0),                  // 5: proto.LoadStage
+	(Backend)(0),                    // 6: proto.Backend
+	(ModelEventType)(0),             // 7: proto.ModelEventType
+	(*PingRequest)(nil),             // 8: proto.PingRequest
+	(*PingResponse)(nil),            // 9: proto.PingResponse
+	(*LoadModelRequest)(nil),        // 10: proto.LoadModelRequest
+	(*LoadModelResponse)(nil),       // 11: proto.LoadModelResponse
+	(*LoadWarning)(nil),             // 12: proto.LoadWarning
+	(*CancelLoadRequest)(nil),       // 13: proto.CancelLoadRequest
+	(*CancelLoadResponse)(nil),      // 14: proto.CancelLoadResponse
+	(*GetLoadLogRequest)(nil),       // 15: proto.GetLoadLogRequest
+	(*GetLoadLogResponse)(nil),      // 16: proto.GetLoadLogResponse
+	(*GetModelInfoRequest)(nil),     // 17: proto.GetModelInfoRequest
+	(*SpecialToken)(nil),            // 18: proto.SpecialToken
+	(*GetModelInfoResponse)(nil),    // 19: proto.GetModelInfoResponse
+	(*UnloadModelRequest)(nil),      // 20: proto.UnloadModelRequest
+	(*UnloadModelResponse)(nil),     // 21: proto.UnloadModelResponse
+	(*Message)(nil),                 // 22: proto.Message
+	(*Image)(nil),                   // 23: proto.Image
+	(*PredictRequest)(nil),          // 24: proto.PredictRequest
+	(*PredictUploadRequest)(nil),    // 25: proto.PredictUploadRequest
+	(*PredictResponse)(nil),         // 26: proto.PredictResponse
+	(*Beam)(nil),                    // 27: proto.Beam
+	(*GetResultRequest)(nil),        // 28: proto.GetResultRequest
+	(*ScoreRequest)(nil),            // 29: proto.ScoreRequest
+	(*ScoreResponse)(nil),           // 30: proto.ScoreResponse
+	(*EmbedRequest)(nil),            // 31: proto.EmbedRequest
+	(*Embedding)(nil),               // 32: proto.Embedding
+	(*EmbedResponse)(nil),           // 33: proto.EmbedResponse
+	(*ClassifyRequest)(nil),         // 34: proto.ClassifyRequest
+	(*LabelScore)(nil),              // 35: proto.LabelScore
+	(*Classification)(nil),          // 36: proto.Classification
+	(*ClassifyResponse)(nil),        // 37: proto.ClassifyResponse
+	(*SimilarityRequest)(nil),       // 38: proto.SimilarityRequest
+	(*SimilarityResponse)(nil),      // 39: proto.SimilarityResponse
+	(*BenchRequest)(nil),            // 40: proto.BenchRequest
+	(*BenchResult)(nil),             // 41: proto.BenchResult
+	(*BenchResponse)(nil),           // 42: proto.BenchResponse
+	(*PrefillProgress)(nil),         // 43: proto.PrefillProgress
+	(*PredictTimings)(nil),          // 44: proto.PredictTimings
+	(*LatencyDistribution)(nil),     // 45: proto.LatencyDistribution
+	(*GetModelStatusRequest)(nil),   // 46: proto.GetModelStatusRequest
+	(*GetModelStatusResponse)(nil),  // 47: proto.GetModelStatusResponse
+	(*ListModelsRequest)(nil),       // 48: proto.ListModelsRequest
+	(*ListModelsResponse)(nil),      // 49: proto.ListModelsResponse
+	(*GetServerStatusRequest)(nil),  // 50: proto.GetServerStatusRequest
+	(*TenantQueueStatus)(nil),       // 51: proto.TenantQueueStatus
+	(*GetServerStatusResponse)(nil), // 52: proto.GetServerStatusResponse
+	(*ModelHealth)(nil),             // 53: proto.ModelHealth
+	(*SystemInfo)(nil),              // 54: proto.SystemInfo
+	(*Device)(nil),                  // 55: proto.Device
+	(*GetVersionRequest)(nil),       // 56: proto.GetVersionRequest
+	(*GetVersionResponse)(nil),      // 57: proto.GetVersionResponse
+	(*WatchEventsRequest)(nil),      // 58: proto.WatchEventsRequest
+	(*ModelEvent)(nil),              // 59: proto.ModelEvent
+	nil,                             // 60: proto.LoadWarning.MetadataEntry
+	(*PredictRequest_Options)(nil),  // 61: proto.PredictRequest.Options
+	nil,                             // 62: proto.PredictResponse.AnnotationsEntry
 }
 var file_llmserver_proto_depIdxs = []int32{
-	1,  // 0: proto.LoadModelRequest.backend:type_name -> proto.Backend
-	14, // 1: proto.PredictRequest.options:type_name -> proto.PredictRequest.Options
-	0,  // 2: proto.GetModelStatusResponse.status:type_name -> proto.ModelStatus
-	2,  // 3: proto.LLMServer.Ping:input_type -> proto.PingRequest
-	4,  // 4: proto.LLMServer.LoadModel:input_type -> proto.LoadModelRequest
-	8,  // 5: proto.LLMServer.Predict:input_type -> proto.PredictRequest
-	3,  // 6: proto.LLMServer.Ping:output_type -> proto.PingResponse
-	5,  // 7: proto.LLMServer.LoadModel:output_type -> proto.LoadModelResponse
-	9,  // 8: proto.LLMServer.Predict:output_type -> proto.PredictResponse
-	6,  // [6:9] is the sub-list for method output_type
-	3,  // [3:6] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	6,  // 0: proto.LoadModelRequest.backend:type_name -> proto.Backend
+	5,  // 1: proto.LoadModelResponse.stage:type_name -> proto.LoadStage
+	12, // 2: proto.LoadModelResponse.warnings:type_name -> proto.LoadWarning
+	60, // 3: proto.LoadWarning.metadata:type_name -> proto.LoadWarning.MetadataEntry
+	18, // 4: proto.GetModelInfoResponse.bos:type_name -> proto.SpecialToken
+	18, // 5: proto.GetModelInfoResponse.eos:type_name -> proto.SpecialToken
+	18, // 6: proto.GetModelInfoResponse.eot:type_name -> proto.SpecialToken
+	61, // 7: proto.PredictRequest.options:type_name -> proto.PredictRequest.Options
+	22, // 8: proto.PredictRequest.messages:type_name -> proto.Message
+	23, // 9: proto.PredictRequest.images:type_name -> proto.Image
+	24, // 10: proto.PredictUploadRequest.request:type_name -> proto.PredictRequest
+	43, // 11: proto.PredictResponse.prefill_progress:type_name -> proto.PrefillProgress
+	44, // 12: proto.PredictResponse.timings:type_name -> proto.PredictTimings
+	1,  // 13: proto.PredictResponse.finish_reason:type_name -> proto.FinishReason
+	11, // 14: proto.PredictResponse.load_progress:type_name -> proto.LoadModelResponse
+	18, // 15: proto.PredictResponse.special_token:type_name -> proto.SpecialToken
+	62, // 16: proto.PredictResponse.annotations:type_name -> proto.PredictResponse.AnnotationsEntry
+	27, // 17: proto.PredictResponse.beams:type_name -> proto.Beam
+	4,  // 18: proto.PredictResponse.max_tokens_reason:type_name -> proto.MaxTokensReason
+	1,  // 19: proto.Beam.finish_reason:type_name -> proto.FinishReason
+	32, // 20: proto.EmbedResponse.embeddings:type_name -> proto.Embedding
+	35, // 21: proto.Classification.labels:type_name -> proto.LabelScore
+	36, // 22: proto.ClassifyResponse.results:type_name -> proto.Classification
+	41, // 23: proto.BenchResponse.results:type_name -> proto.BenchResult
+	45, // 24: proto.PredictTimings.inter_token_latency:type_name -> proto.LatencyDistribution
+	0,  // 25: proto.GetModelStatusResponse.status:type_name -> proto.ModelStatus
+	51, // 26: proto.GetServerStatusResponse.tenants:type_name -> proto.TenantQueueStatus
+	54, // 27: proto.GetServerStatusResponse.system_info:type_name -> proto.SystemInfo
+	53, // 28: proto.GetServerStatusResponse.model_health:type_name -> proto.ModelHealth
+	55, // 29: proto.SystemInfo.devices:type_name -> proto.Device
+	7,  // 30: proto.ModelEvent.type:type_name -> proto.ModelEventType
+	2,  // 31: proto.PredictRequest.Options.special_tokens:type_name -> proto.SpecialTokens
+	3,  // 32: proto.PredictRequest.Options.max_tokens_policy:type_name -> proto.MaxTokensPolicy
+	8,  // 33: proto.LLMServer.Ping:input_type -> proto.PingRequest
+	10, // 34: proto.LLMServer.LoadModel:input_type -> proto.LoadModelRequest
+	13, // 35: proto.LLMServer.CancelLoad:input_type -> proto.CancelLoadRequest
+	15, // 36: proto.LLMServer.GetLoadLog:input_type -> proto.GetLoadLogRequest
+	17, // 37: proto.LLMServer.GetModelInfo:input_type -> proto.GetModelInfoRequest
+	24, // 38: proto.LLMServer.Predict:input_type -> proto.PredictRequest
+	25, // 39: proto.LLMServer.PredictUpload:input_type -> proto.PredictUploadRequest
+	28, // 40: proto.LLMServer.GetResult:input_type -> proto.GetResultRequest
+	29, // 41: proto.LLMServer.Score:input_type -> proto.ScoreRequest
+	31, // 42: proto.LLMServer.Embed:input_type -> proto.EmbedRequest
+	34, // 43: proto.LLMServer.Classify:input_type -> proto.ClassifyRequest
+	38, // 44: proto.LLMServer.Similarity:input_type -> proto.SimilarityRequest
+	40, // 45: proto.LLMServer.Bench:input_type -> proto.BenchRequest
+	50, // 46: proto.LLMServer.GetServerStatus:input_type -> proto.GetServerStatusRequest
+	56, // 47: proto.LLMServer.GetVersion:input_type -> proto.GetVersionRequest
+	58, // 48: proto.LLMServer.WatchEvents:input_type -> proto.WatchEventsRequest
+	9,  // 49: proto.LLMServer.Ping:output_type -> proto.PingResponse
+	11, // 50: proto.LLMServer.LoadModel:output_type -> proto.LoadModelResponse
+	14, // 51: proto.LLMServer.CancelLoad:output_type -> proto.CancelLoadResponse
+	16, // 52: proto.LLMServer.GetLoadLog:output_type -> proto.GetLoadLogResponse
+	19, // 53: proto.LLMServer.GetModelInfo:output_type -> proto.GetModelInfoResponse
+	26, // 54: proto.LLMServer.Predict:output_type -> proto.PredictResponse
+	26, // 55: proto.LLMServer.PredictUpload:output_type -> proto.PredictResponse
+	26, // 56: proto.LLMServer.GetResult:output_type -> proto.PredictResponse
+	30, // 57: proto.LLMServer.Score:output_type -> proto.ScoreResponse
+	33, // 58: proto.LLMServer.Embed:output_type -> proto.EmbedResponse
+	37, // 59: proto.LLMServer.Classify:output_type -> proto.ClassifyResponse
+	39, // 60: proto.LLMServer.Similarity:output_type -> proto.SimilarityResponse
+	42, // 61: proto.LLMServer.Bench:output_type -> proto.BenchResponse
+	52, // 62: proto.LLMServer.GetServerStatus:output_type -> proto.GetServerStatusResponse
+	57, // 63: proto.LLMServer.GetVersion:output_type -> proto.GetVersionResponse
+	59, // 64: proto.LLMServer.WatchEvents:output_type -> proto.ModelEvent
+	49, // [49:65] is the sub-list for method output_type
+	33, // [33:49] is the sub-list for method input_type
+	33, // [33:33] is the sub-list for extension type_name
+	33, // [33:33] is the sub-list for extension extendee
+	0,  // [0:33] is the sub-list for field type_name
 }
 
 func init() { file_llmserver_proto_init() }
@@ -1072,173 +4555,15 @@ func file_llmserver_proto_init() {
 	if File_llmserver_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_llmserver_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PingRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PingResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*LoadModelRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*LoadModelResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UnloadModelRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UnloadModelResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PredictRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PredictResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetModelStatusRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetModelStatusResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListModelsRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListModelsResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_llmserver_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PredictRequest_Options); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
-	file_llmserver_proto_msgTypes[2].OneofWrappers = []interface{}{}
-	file_llmserver_proto_msgTypes[12].OneofWrappers = []interface{}{}
+	file_llmserver_proto_msgTypes[2].OneofWrappers = []any{}
+	file_llmserver_proto_msgTypes[53].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_llmserver_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   13,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_llmserver_proto_rawDesc), len(file_llmserver_proto_rawDesc)),
+			NumEnums:      8,
+			NumMessages:   55,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -1248,7 +4573,6 @@ func file_llmserver_proto_init() {
 		MessageInfos:      file_llmserver_proto_msgTypes,
 	}.Build()
 	File_llmserver_proto = out.File
-	file_llmserver_proto_rawDesc = nil
 	file_llmserver_proto_goTypes = nil
 	file_llmserver_proto_depIdxs = nil
 }
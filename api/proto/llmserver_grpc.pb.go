@@ -19,9 +19,22 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	LLMServer_Ping_FullMethodName      = "/proto.LLMServer/Ping"
-	LLMServer_LoadModel_FullMethodName = "/proto.LLMServer/LoadModel"
-	LLMServer_Predict_FullMethodName   = "/proto.LLMServer/Predict"
+	LLMServer_Ping_FullMethodName            = "/proto.LLMServer/Ping"
+	LLMServer_LoadModel_FullMethodName       = "/proto.LLMServer/LoadModel"
+	LLMServer_CancelLoad_FullMethodName      = "/proto.LLMServer/CancelLoad"
+	LLMServer_GetLoadLog_FullMethodName      = "/proto.LLMServer/GetLoadLog"
+	LLMServer_GetModelInfo_FullMethodName    = "/proto.LLMServer/GetModelInfo"
+	LLMServer_Predict_FullMethodName         = "/proto.LLMServer/Predict"
+	LLMServer_PredictUpload_FullMethodName   = "/proto.LLMServer/PredictUpload"
+	LLMServer_GetResult_FullMethodName       = "/proto.LLMServer/GetResult"
+	LLMServer_Score_FullMethodName           = "/proto.LLMServer/Score"
+	LLMServer_Embed_FullMethodName           = "/proto.LLMServer/Embed"
+	LLMServer_Classify_FullMethodName        = "/proto.LLMServer/Classify"
+	LLMServer_Similarity_FullMethodName      = "/proto.LLMServer/Similarity"
+	LLMServer_Bench_FullMethodName           = "/proto.LLMServer/Bench"
+	LLMServer_GetServerStatus_FullMethodName = "/proto.LLMServer/GetServerStatus"
+	LLMServer_GetVersion_FullMethodName      = "/proto.LLMServer/GetVersion"
+	LLMServer_WatchEvents_FullMethodName     = "/proto.LLMServer/WatchEvents"
 )
 
 // LLMServerClient is the client API for LLMServer service.
@@ -30,7 +43,20 @@ const (
 type LLMServerClient interface {
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	LoadModel(ctx context.Context, in *LoadModelRequest, opts ...grpc.CallOption) (LLMServer_LoadModelClient, error)
+	CancelLoad(ctx context.Context, in *CancelLoadRequest, opts ...grpc.CallOption) (*CancelLoadResponse, error)
+	GetLoadLog(ctx context.Context, in *GetLoadLogRequest, opts ...grpc.CallOption) (*GetLoadLogResponse, error)
+	GetModelInfo(ctx context.Context, in *GetModelInfoRequest, opts ...grpc.CallOption) (*GetModelInfoResponse, error)
 	Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (LLMServer_PredictClient, error)
+	PredictUpload(ctx context.Context, opts ...grpc.CallOption) (LLMServer_PredictUploadClient, error)
+	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*PredictResponse, error)
+	Score(ctx context.Context, in *ScoreRequest, opts ...grpc.CallOption) (*ScoreResponse, error)
+	Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error)
+	Classify(ctx context.Context, in *ClassifyRequest, opts ...grpc.CallOption) (*ClassifyResponse, error)
+	Similarity(ctx context.Context, in *SimilarityRequest, opts ...grpc.CallOption) (*SimilarityResponse, error)
+	Bench(ctx context.Context, in *BenchRequest, opts ...grpc.CallOption) (*BenchResponse, error)
+	GetServerStatus(ctx context.Context, in *GetServerStatusRequest, opts ...grpc.CallOption) (*GetServerStatusResponse, error)
+	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (LLMServer_WatchEventsClient, error)
 }
 
 type lLMServerClient struct {
@@ -82,6 +108,33 @@ func (x *lLMServerLoadModelClient) Recv() (*LoadModelResponse, error) {
 	return m, nil
 }
 
+func (c *lLMServerClient) CancelLoad(ctx context.Context, in *CancelLoadRequest, opts ...grpc.CallOption) (*CancelLoadResponse, error) {
+	out := new(CancelLoadResponse)
+	err := c.cc.Invoke(ctx, LLMServer_CancelLoad_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) GetLoadLog(ctx context.Context, in *GetLoadLogRequest, opts ...grpc.CallOption) (*GetLoadLogResponse, error) {
+	out := new(GetLoadLogResponse)
+	err := c.cc.Invoke(ctx, LLMServer_GetLoadLog_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) GetModelInfo(ctx context.Context, in *GetModelInfoRequest, opts ...grpc.CallOption) (*GetModelInfoResponse, error) {
+	out := new(GetModelInfoResponse)
+	err := c.cc.Invoke(ctx, LLMServer_GetModelInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *lLMServerClient) Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (LLMServer_PredictClient, error) {
 	stream, err := c.cc.NewStream(ctx, &LLMServer_ServiceDesc.Streams[1], LLMServer_Predict_FullMethodName, opts...)
 	if err != nil {
@@ -114,13 +167,161 @@ func (x *lLMServerPredictClient) Recv() (*PredictResponse, error) {
 	return m, nil
 }
 
+func (c *lLMServerClient) PredictUpload(ctx context.Context, opts ...grpc.CallOption) (LLMServer_PredictUploadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LLMServer_ServiceDesc.Streams[2], LLMServer_PredictUpload_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &lLMServerPredictUploadClient{stream}
+	return x, nil
+}
+
+type LLMServer_PredictUploadClient interface {
+	Send(*PredictUploadRequest) error
+	Recv() (*PredictResponse, error)
+	grpc.ClientStream
+}
+
+type lLMServerPredictUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *lLMServerPredictUploadClient) Send(m *PredictUploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *lLMServerPredictUploadClient) Recv() (*PredictResponse, error) {
+	m := new(PredictResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *lLMServerClient) GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*PredictResponse, error) {
+	out := new(PredictResponse)
+	err := c.cc.Invoke(ctx, LLMServer_GetResult_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) Score(ctx context.Context, in *ScoreRequest, opts ...grpc.CallOption) (*ScoreResponse, error) {
+	out := new(ScoreResponse)
+	err := c.cc.Invoke(ctx, LLMServer_Score_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error) {
+	out := new(EmbedResponse)
+	err := c.cc.Invoke(ctx, LLMServer_Embed_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) Classify(ctx context.Context, in *ClassifyRequest, opts ...grpc.CallOption) (*ClassifyResponse, error) {
+	out := new(ClassifyResponse)
+	err := c.cc.Invoke(ctx, LLMServer_Classify_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) Similarity(ctx context.Context, in *SimilarityRequest, opts ...grpc.CallOption) (*SimilarityResponse, error) {
+	out := new(SimilarityResponse)
+	err := c.cc.Invoke(ctx, LLMServer_Similarity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) Bench(ctx context.Context, in *BenchRequest, opts ...grpc.CallOption) (*BenchResponse, error) {
+	out := new(BenchResponse)
+	err := c.cc.Invoke(ctx, LLMServer_Bench_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) GetServerStatus(ctx context.Context, in *GetServerStatusRequest, opts ...grpc.CallOption) (*GetServerStatusResponse, error) {
+	out := new(GetServerStatusResponse)
+	err := c.cc.Invoke(ctx, LLMServer_GetServerStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error) {
+	out := new(GetVersionResponse)
+	err := c.cc.Invoke(ctx, LLMServer_GetVersion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lLMServerClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (LLMServer_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LLMServer_ServiceDesc.Streams[3], LLMServer_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &lLMServerWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LLMServer_WatchEventsClient interface {
+	Recv() (*ModelEvent, error)
+	grpc.ClientStream
+}
+
+type lLMServerWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *lLMServerWatchEventsClient) Recv() (*ModelEvent, error) {
+	m := new(ModelEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // LLMServerServer is the server API for LLMServer service.
 // All implementations must embed UnimplementedLLMServerServer
 // for forward compatibility
 type LLMServerServer interface {
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	LoadModel(*LoadModelRequest, LLMServer_LoadModelServer) error
+	CancelLoad(context.Context, *CancelLoadRequest) (*CancelLoadResponse, error)
+	GetLoadLog(context.Context, *GetLoadLogRequest) (*GetLoadLogResponse, error)
+	GetModelInfo(context.Context, *GetModelInfoRequest) (*GetModelInfoResponse, error)
 	Predict(*PredictRequest, LLMServer_PredictServer) error
+	PredictUpload(LLMServer_PredictUploadServer) error
+	GetResult(context.Context, *GetResultRequest) (*PredictResponse, error)
+	Score(context.Context, *ScoreRequest) (*ScoreResponse, error)
+	Embed(context.Context, *EmbedRequest) (*EmbedResponse, error)
+	Classify(context.Context, *ClassifyRequest) (*ClassifyResponse, error)
+	Similarity(context.Context, *SimilarityRequest) (*SimilarityResponse, error)
+	Bench(context.Context, *BenchRequest) (*BenchResponse, error)
+	GetServerStatus(context.Context, *GetServerStatusRequest) (*GetServerStatusResponse, error)
+	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
+	WatchEvents(*WatchEventsRequest, LLMServer_WatchEventsServer) error
 	mustEmbedUnimplementedLLMServerServer()
 }
 
@@ -134,9 +335,48 @@ func (UnimplementedLLMServerServer) Ping(context.Context, *PingRequest) (*PingRe
 func (UnimplementedLLMServerServer) LoadModel(*LoadModelRequest, LLMServer_LoadModelServer) error {
 	return status.Errorf(codes.Unimplemented, "method LoadModel not implemented")
 }
+func (UnimplementedLLMServerServer) CancelLoad(context.Context, *CancelLoadRequest) (*CancelLoadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelLoad not implemented")
+}
+func (UnimplementedLLMServerServer) GetLoadLog(context.Context, *GetLoadLogRequest) (*GetLoadLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLoadLog not implemented")
+}
+func (UnimplementedLLMServerServer) GetModelInfo(context.Context, *GetModelInfoRequest) (*GetModelInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetModelInfo not implemented")
+}
 func (UnimplementedLLMServerServer) Predict(*PredictRequest, LLMServer_PredictServer) error {
 	return status.Errorf(codes.Unimplemented, "method Predict not implemented")
 }
+func (UnimplementedLLMServerServer) PredictUpload(LLMServer_PredictUploadServer) error {
+	return status.Errorf(codes.Unimplemented, "method PredictUpload not implemented")
+}
+func (UnimplementedLLMServerServer) GetResult(context.Context, *GetResultRequest) (*PredictResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedLLMServerServer) Score(context.Context, *ScoreRequest) (*ScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Score not implemented")
+}
+func (UnimplementedLLMServerServer) Embed(context.Context, *EmbedRequest) (*EmbedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Embed not implemented")
+}
+func (UnimplementedLLMServerServer) Classify(context.Context, *ClassifyRequest) (*ClassifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Classify not implemented")
+}
+func (UnimplementedLLMServerServer) Similarity(context.Context, *SimilarityRequest) (*SimilarityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Similarity not implemented")
+}
+func (UnimplementedLLMServerServer) Bench(context.Context, *BenchRequest) (*BenchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Bench not implemented")
+}
+func (UnimplementedLLMServerServer) GetServerStatus(context.Context, *GetServerStatusRequest) (*GetServerStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerStatus not implemented")
+}
+func (UnimplementedLLMServerServer) GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (UnimplementedLLMServerServer) WatchEvents(*WatchEventsRequest, LLMServer_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
 func (UnimplementedLLMServerServer) mustEmbedUnimplementedLLMServerServer() {}
 
 // UnsafeLLMServerServer may be embedded to opt out of forward compatibility for this service.
@@ -189,6 +429,60 @@ func (x *lLMServerLoadModelServer) Send(m *LoadModelResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _LLMServer_CancelLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelLoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).CancelLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_CancelLoad_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).CancelLoad(ctx, req.(*CancelLoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_GetLoadLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoadLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).GetLoadLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_GetLoadLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).GetLoadLog(ctx, req.(*GetLoadLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_GetModelInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetModelInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).GetModelInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_GetModelInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).GetModelInfo(ctx, req.(*GetModelInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _LLMServer_Predict_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(PredictRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -210,6 +504,197 @@ func (x *lLMServerPredictServer) Send(m *PredictResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _LLMServer_PredictUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LLMServerServer).PredictUpload(&lLMServerPredictUploadServer{stream})
+}
+
+type LLMServer_PredictUploadServer interface {
+	Send(*PredictResponse) error
+	Recv() (*PredictUploadRequest, error)
+	grpc.ServerStream
+}
+
+type lLMServerPredictUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *lLMServerPredictUploadServer) Send(m *PredictResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *lLMServerPredictUploadServer) Recv() (*PredictUploadRequest, error) {
+	m := new(PredictUploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _LLMServer_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).GetResult(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_Score_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).Score(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_Score_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).Score(ctx, req.(*ScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_Embed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).Embed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_Embed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).Embed(ctx, req.(*EmbedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_Classify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClassifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).Classify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_Classify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).Classify(ctx, req.(*ClassifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_Similarity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimilarityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).Similarity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_Similarity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).Similarity(ctx, req.(*SimilarityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_Bench_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BenchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).Bench(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_Bench_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).Bench(ctx, req.(*BenchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_GetServerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServerStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).GetServerStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_GetServerStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).GetServerStatus(ctx, req.(*GetServerStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LLMServerServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LLMServer_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LLMServerServer).GetVersion(ctx, req.(*GetVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LLMServer_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LLMServerServer).WatchEvents(m, &lLMServerWatchEventsServer{stream})
+}
+
+type LLMServer_WatchEventsServer interface {
+	Send(*ModelEvent) error
+	grpc.ServerStream
+}
+
+type lLMServerWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *lLMServerWatchEventsServer) Send(m *ModelEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // LLMServer_ServiceDesc is the grpc.ServiceDesc for LLMServer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -221,6 +706,50 @@ var LLMServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _LLMServer_Ping_Handler,
 		},
+		{
+			MethodName: "CancelLoad",
+			Handler:    _LLMServer_CancelLoad_Handler,
+		},
+		{
+			MethodName: "GetLoadLog",
+			Handler:    _LLMServer_GetLoadLog_Handler,
+		},
+		{
+			MethodName: "GetModelInfo",
+			Handler:    _LLMServer_GetModelInfo_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _LLMServer_GetResult_Handler,
+		},
+		{
+			MethodName: "Score",
+			Handler:    _LLMServer_Score_Handler,
+		},
+		{
+			MethodName: "Embed",
+			Handler:    _LLMServer_Embed_Handler,
+		},
+		{
+			MethodName: "Classify",
+			Handler:    _LLMServer_Classify_Handler,
+		},
+		{
+			MethodName: "Similarity",
+			Handler:    _LLMServer_Similarity_Handler,
+		},
+		{
+			MethodName: "Bench",
+			Handler:    _LLMServer_Bench_Handler,
+		},
+		{
+			MethodName: "GetServerStatus",
+			Handler:    _LLMServer_GetServerStatus_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _LLMServer_GetVersion_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -233,6 +762,17 @@ var LLMServer_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _LLMServer_Predict_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "PredictUpload",
+			Handler:       _LLMServer_PredictUpload_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _LLMServer_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "llmserver.proto",
 }
@@ -38,8 +38,9 @@ func main() {
 	modelParams := llamacppbindings.NewModelDefaultParams()
 	modelParams.SetNGpuLayers(99)
 	modelParams.SetUseMmap(false)
-	modelParams.SetProgressCallback(func(progress float32) {
+	modelParams.SetProgressCallback(func(progress float32) bool {
 		fmt.Printf("progress: %f\n", progress)
+		return true
 	})
 
 	defer modelParams.Free()
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/pkg/client"
+)
+
+// =============================================================================
+// Conversation test: a scripted multi-turn chat that resends the history
+// =============================================================================
+
+var conversationTurns = []string{
+	"My name is Alice and I live in Paris. Please remember that.",
+	"What city do I live in?",
+	"What is the name of the river that flows through that city?",
+	"What was my name again?",
+}
+
+// chatMLPrompt renders the conversation so far, ending with an open
+// assistant turn.
+func chatMLPrompt(users, assistants []string) string {
+	var b strings.Builder
+	for i, u := range users {
+		fmt.Fprintf(&b, "<|im_start|>user\n%s<|im_end|>\n<|im_start|>assistant\n", u)
+		if i < len(assistants) {
+			fmt.Fprintf(&b, "%s<|im_end|>\n", assistants[i])
+		}
+	}
+	return b.String()
+}
+
+// runConversationTest plays conversationTurns one after the other, each turn
+// sending the whole history so far. Every turn must produce output; the
+// cumulative token count grows with each turn. The server has no sessions
+// yet, so the KV cache state is rebuilt from the prompt on every turn.
+func runConversationTest(ctx context.Context, llmService client.Client, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 50
+	}
+
+	logger.Infof("=== CONVERSATION TEST CONFIGURATION ===")
+	logger.Infof("Turns: %d", len(conversationTurns))
+	logger.Infof("Max tokens per turn: %d", maxTokens)
+	logger.Infof("Model: %s", modelPath)
+	logger.Infof("=======================================")
+
+	var users, assistants []string
+	var totalTokens int
+	var totalTime time.Duration
+	allPassed := true
+
+	for i, turn := range conversationTurns {
+		users = append(users, turn)
+		prompt := chatMLPrompt(users, assistants)
+
+		r := runGreedyRequest(ctx, llmService, modelPath, prompt, maxTokens)
+		totalTime += r.duration
+		if r.err != nil {
+			logger.Errorf("  Turn %d: FAILED after %.2fs - %v", i+1, r.duration.Seconds(), r.err)
+			allPassed = false
+			break
+		}
+		reply := strings.TrimSpace(strings.TrimSuffix(r.response, "<|im_end|>"))
+		if reply == "" {
+			logger.Errorf("  Turn %d: FAILED - empty response", i+1)
+			allPassed = false
+			break
+		}
+		totalTokens += r.tokens
+		assistants = append(assistants, reply)
+
+		logger.Infof("  Turn %d: OK - %d tokens in %.2fs (cumulative %d tokens, prompt %d bytes)",
+			i+1, r.tokens, r.duration.Seconds(), totalTokens, len(prompt))
+		logger.Infof("    User:      %q", turn)
+		logger.Infof("    Assistant: %q", truncate(reply, 80))
+	}
+
+	logger.Infof("")
+	logger.Infof("Total generation time: %.2fs", totalTime.Seconds())
+	logger.Infof("Total tokens generated: %d", totalTokens)
+
+	if allPassed {
+		logger.Infof("RESULT: ALL %d CONVERSATION TURNS COMPLETED SUCCESSFULLY", len(conversationTurns))
+	} else {
+		logger.Errorf("RESULT: CONVERSATION TEST FAILED")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hypernetix/llamacpp_server/cmd/llamacppclienttest/tokendiff"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/pkg/client"
+)
+
+// =============================================================================
+// Determinism test: identical requests must produce identical tokens
+// =============================================================================
+
+// tokenRun is the token-by-token output of one prediction.
+type tokenRun struct {
+	tokens []int32
+	pieces []string
+}
+
+func collectTokens(ctx context.Context, svc client.Client, req client.PredictRequest) (tokenRun, error) {
+	stream, err := svc.Predict(ctx, req)
+	if err != nil {
+		return tokenRun{}, err
+	}
+	defer stream.Close()
+	var run tokenRun
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return run, nil
+		}
+		if err != nil {
+			return run, err
+		}
+		run.tokens = append(run.tokens, resp.Token)
+		run.pieces = append(run.pieces, resp.Message)
+	}
+}
+
+// diffContext is the number of tokens shown around each difference.
+const diffContext = 8
+
+// diffTokens returns run in the form tokendiff compares.
+func (run tokenRun) diffTokens() []tokendiff.Token {
+	tokens := make([]tokendiff.Token, len(run.tokens))
+	for i := range run.tokens {
+		tokens[i] = tokendiff.Token{ID: run.tokens[i], Text: run.pieces[i]}
+	}
+	return tokens
+}
+
+// tokenAt describes token i of run for the divergence report.
+func tokenAt(run tokenRun, i int) string {
+	if i >= len(run.tokens) {
+		return "<end>"
+	}
+	return fmt.Sprintf("%d %q", run.tokens[i], run.pieces[i])
+}
+
+// runDeterminismTest runs a seeded and a greedy request twice each and
+// compares the generated tokens one by one.
+func runDeterminismTest(ctx context.Context, llmService client.Client, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
+	prompt := "<|im_start|>user\nWrite a short poem about the sea.<|im_end|>\n<|im_start|>assistant\n"
+	seed := opts.RandomSeed
+	if seed < 0 {
+		seed = 12345
+	}
+
+	seeded := client.PredictRequest{
+		ModelName:         modelPath,
+		Message:           prompt,
+		MaxTokens:         opts.MaxTokens,
+		Temperature:       opts.Temperature,
+		Stream:            true,
+		TopP:              Float64Ptr(opts.TopP),
+		TopK:              IntPtr(opts.TopK),
+		MinP:              Float64Ptr(opts.MinP),
+		RepetitionPenalty: Float64Ptr(opts.RepeatPenalty),
+		RandomSeed:        IntPtr(seed),
+	}
+	greedy := client.PredictRequest{
+		ModelName:   modelPath,
+		Message:     prompt,
+		MaxTokens:   opts.MaxTokens,
+		Temperature: 0.0,
+		Stream:      true,
+	}
+
+	logger.Infof("=== DETERMINISM TEST CONFIGURATION ===")
+	logger.Infof("Model: %s", modelPath)
+	logger.Infof("Max tokens: %d", opts.MaxTokens)
+	logger.Infof("Seeded run: temperature=%.3f, seed=%d", opts.Temperature, seed)
+	logger.Infof("Greedy run: temperature=0")
+	logger.Infof("======================================")
+
+	allPassed := true
+	for _, c := range []struct {
+		name string
+		req  client.PredictRequest
+	}{
+		{"seeded", seeded},
+		{"greedy", greedy},
+	} {
+		var runs [2]tokenRun
+		for i := range runs {
+			run, err := collectTokens(ctx, llmService, c.req)
+			if err != nil {
+				logger.Errorf("  %s run %d: FAILED - %v", c.name, i+1, err)
+				os.Exit(1)
+			}
+			runs[i] = run
+		}
+
+		diff := tokendiff.Compare(runs[0].diffTokens(), runs[1].diffTokens())
+		if diff.Equal() {
+			logger.Infof("  %s: OK - %d identical tokens", c.name, len(runs[0].tokens))
+			continue
+		}
+		allPassed = false
+		logger.Errorf("  %s: DIVERGED at token %d (run 1: %d tokens, run 2: %d tokens)",
+			c.name, diff.First, len(runs[0].tokens), len(runs[1].tokens))
+		logger.Errorf("    common prefix: %q", truncate(strings.Join(runs[0].pieces[:diff.First], ""), 200))
+		for _, line := range diff.Format("run 1", "run 2", diffContext) {
+			logger.Errorf("    %s", line)
+		}
+	}
+
+	if allPassed {
+		logger.Infof("RESULT: OUTPUTS ARE DETERMINISTIC")
+	} else {
+		logger.Errorf("RESULT: NONDETERMINISTIC OUTPUT DETECTED")
+		os.Exit(1)
+	}
+}
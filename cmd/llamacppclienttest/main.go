@@ -3,14 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	llmservice "github.com/hypernetix/llamacpp_server/cmd/llamacppclienttest/llmservice"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/pkg/client"
 
 	flags "github.com/jessevdk/go-flags"
 )
@@ -28,8 +29,16 @@ type flagOptions struct {
 	MinP           float64 `long:"min-p" description:"min-p sampling" default:"0.05"`
 	RandomSeed     int     `long:"seed" description:"random seed for reproducible results (-1 for random)" default:"-1"`
 	MaxTokens      int     `long:"max-tokens" description:"maximum tokens to generate" default:"100"`
-	TestMode           string `long:"test-mode" description:"test mode: baseline, greedy, seeded, stress, parallel, or backpressure" default:"baseline"`
+	TestMode           string `long:"test-mode" description:"test mode: baseline, greedy, seeded, stress, parallel, backpressure, conversation, determinism, or reference" default:"baseline"`
 	ParallelN          int    `long:"parallel-n" description:"number of concurrent requests for parallel test mode" default:"4"`
+	LlamaCliPath       string `long:"llama-cli" description:"llama-cli binary for reference test mode" default:"llama-cli"`
+	ShutdownTimeout    time.Duration `long:"shutdown-timeout" description:"how long to wait for running streams before stopping the service" default:"10s"`
+
+	GRPCConns int `long:"grpc-conns" description:"number of gRPC connections to spread calls over" default:"1"`
+
+	StressMix      string        `long:"stress-mix" description:"stress mode request mix as class=weight pairs of chat, long and embed" default:"chat=6,long=3,embed=1"`
+	StressQPS      float64       `long:"stress-qps" description:"stress mode target rate in requests per second" default:"2"`
+	StressDuration time.Duration `long:"stress-duration" description:"how long stress mode starts requests" default:"30s"`
 }
 
 // Helper functions for pointer creation
@@ -101,15 +110,17 @@ func main() {
 
 	logger.Infof("Starting LLM service...")
 
-	llmServiceOptions := llmservice.LLMServiceOptions{
+	llmServiceOptions := client.Options{
 		ServerPath: opts.ServerPath,
 		AttachHost: opts.AttachHost,
 		AttachPort: opts.AttachPort,
-		Transport:  opts.Transport,
+		Transport:  client.Transport(opts.Transport),
 		NParallel:  opts.ParallelN,
+		GRPCConns:  opts.GRPCConns,
+		Logger:     logger,
 	}
 
-	llmService, err := llmservice.NewLlamacppLLMService(llmServiceOptions, logger)
+	llmService, err := client.New(llmServiceOptions)
 	if err != nil {
 		logger.Errorf("Failed to create LLM service: %v", err)
 		os.Exit(1)
@@ -117,7 +128,12 @@ func main() {
 
 	shutdown := func() {
 		logger.Infof("Stopping LLM service...")
-		llmService.Shutdown()
+		ctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+		defer cancel()
+		if err := llmService.ShutdownGraceful(ctx); err != nil {
+			logger.Warnf("LLM service stopped with streams still running: %v", err)
+			return
+		}
 		logger.Infof("LLM service stopped")
 	}
 
@@ -156,57 +172,38 @@ func main() {
 		os.Exit(1)
 	}
 
-	{
-		logger.Infof("Loading model...")
-
-		progressChan := make(chan float32)
-		defer close(progressChan)
-
-		wg := sync.WaitGroup{}
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			for progress := range progressChan {
-				fmt.Printf("Model progress: %f\n", progress)
-
-				if progress >= 1.0 {
-					break
-				}
-			}
-		}()
-
-		logger.Infof("Loading model from '%s'...", modelPath)
-
-		err = llmService.LoadModel(ctx, modelPath, progressChan)
-		if err != nil {
-			logger.Errorf("Failed to load model: %v", err)
-			os.Exit(1)
-		}
-
-		wg.Wait()
-
-		logger.Infof("Model loaded")
+	if _, err := loadModelHelper(ctx, llmService, modelPath, logger); err != nil {
+		logger.Errorf("Failed to load model: %v", err)
+		os.Exit(1)
 	}
 
-	if opts.TestMode == "parallel" {
+	switch opts.TestMode {
+	case "parallel":
 		runParallelTest(ctx, llmService, modelPath, opts, logger)
-	} else {
+	case "conversation":
+		runConversationTest(ctx, llmService, modelPath, opts, logger)
+	case "determinism":
+		runDeterminismTest(ctx, llmService, modelPath, opts, logger)
+	case "reference":
+		runReferenceTest(ctx, llmService, modelPath, opts, logger)
+	case "stress":
+		runStressTest(ctx, llmService, modelPath, opts, logger)
+	default:
 		runSingleTest(ctx, llmService, modelPath, opts, logger)
 	}
 
 	logger.Infof("Done")
 }
 
-func runSingleTest(ctx context.Context, llmService llmservice.LLMService, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
+func runSingleTest(ctx context.Context, llmService client.Client, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
 	prompt := "<|im_start|>user\nWhat is the capital of USA?<|im_end|>\n<|im_start|>assistant\n"
 
-	var predictRequest llmservice.PredictRequest
+	var predictRequest client.PredictRequest
 
 	switch opts.TestMode {
 	case "greedy":
 		logger.Infof("Running GREEDY test mode (deterministic)")
-		predictRequest = llmservice.PredictRequest{
+		predictRequest = client.PredictRequest{
 			ModelName:         modelPath,
 			Message:           prompt,
 			MaxTokens:         opts.MaxTokens,
@@ -225,7 +222,7 @@ func runSingleTest(ctx context.Context, llmService llmservice.LLMService, modelP
 
 	case "seeded":
 		logger.Infof("Running SEEDED test mode (reproducible randomized)")
-		predictRequest = llmservice.PredictRequest{
+		predictRequest = client.PredictRequest{
 			ModelName:         modelPath,
 			Message:           prompt,
 			MaxTokens:         opts.MaxTokens,
@@ -238,26 +235,9 @@ func runSingleTest(ctx context.Context, llmService llmservice.LLMService, modelP
 			RandomSeed:        IntPtr(12345),
 		}
 
-	case "stress":
-		logger.Infof("Running STRESS test mode (performance testing)")
-		predictRequest = llmservice.PredictRequest{
-			ModelName:         modelPath,
-			Message:           "Write a detailed explanation of machine learning concepts, including supervised learning, unsupervised learning, and neural networks. Include examples and applications.",
-			MaxTokens:         500,
-			Temperature:       opts.Temperature,
-			Stream:            true,
-			TopP:              Float64Ptr(opts.TopP),
-			TopK:              IntPtr(opts.TopK),
-			MinP:              Float64Ptr(opts.MinP),
-			RepetitionPenalty: Float64Ptr(opts.RepeatPenalty),
-		}
-		if opts.RandomSeed >= 0 {
-			predictRequest.RandomSeed = IntPtr(opts.RandomSeed)
-		}
-
 	default: // "baseline"
 		logger.Infof("Running BASELINE test mode (configurable parameters)")
-		predictRequest = llmservice.PredictRequest{
+		predictRequest = client.PredictRequest{
 			ModelName:         modelPath,
 			Message:           prompt,
 			MaxTokens:         opts.MaxTokens,
@@ -301,41 +281,33 @@ func runSingleTest(ctx context.Context, llmService llmservice.LLMService, modelP
 
 	logger.Infof("Predicting...")
 
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-
-	predictResponseChan := make(chan llmservice.PredictResponse)
-	defer close(predictResponseChan)
-
 	startTime := time.Now()
 
 	fullResponse := ""
 	var tokenCount int
 
-	go func() {
-		defer wg.Done()
-
-		for predictResponse := range predictResponseChan {
-			fmt.Printf("Predict response: %+v\n", predictResponse)
-
-			fullResponse += predictResponse.Message
-			if predictResponse.Tokens > 0 {
-				tokenCount = int(predictResponse.Tokens)
-			}
-
-			if predictResponse.Done {
-				break
-			}
-		}
-	}()
-
-	err := llmService.Predict(ctx, predictRequest, predictResponseChan)
+	stream, err := llmService.Predict(ctx, predictRequest)
 	if err != nil {
 		logger.Errorf("Failed to predict: %v", err)
 		os.Exit(1)
 	}
+	for {
+		predictResponse, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Errorf("Failed to predict: %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Predict response: %+v\n", predictResponse)
 
-	wg.Wait()
+		fullResponse += predictResponse.Message
+		if predictResponse.Tokens > 0 {
+			tokenCount = int(predictResponse.Tokens)
+		}
+	}
+	stream.Close()
 
 	generationTime := time.Since(startTime)
 	throughput := float64(tokenCount) / generationTime.Seconds()
@@ -359,7 +331,7 @@ type parallelResult struct {
 	duration time.Duration
 }
 
-func runParallelTest(ctx context.Context, llmService llmservice.LLMService, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
+func runParallelTest(ctx context.Context, llmService client.Client, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
 	nParallel := opts.ParallelN
 	if nParallel < 2 {
 		nParallel = 2
@@ -392,7 +364,7 @@ func runParallelTest(ctx context.Context, llmService llmservice.LLMService, mode
 			prompt := prompts[idx%len(prompts)]
 			reqStart := time.Now()
 
-			req := llmservice.PredictRequest{
+			req := client.PredictRequest{
 				ModelName:   modelPath,
 				Message:     prompt,
 				MaxTokens:   maxTokens,
@@ -400,29 +372,29 @@ func runParallelTest(ctx context.Context, llmService llmservice.LLMService, mode
 				Stream:      true,
 			}
 
-			respChan := make(chan llmservice.PredictResponse, 128)
-
-			err := llmService.Predict(ctx, req, respChan)
+			stream, err := llmService.Predict(ctx, req)
 			if err != nil {
 				results[idx] = parallelResult{index: idx, prompt: prompt, err: err, duration: time.Since(reqStart)}
 				return
 			}
+			defer stream.Close()
 
 			var fullResponse string
 			var tokenCount int
 
-			for resp := range respChan {
-				if resp.Error != nil {
-					results[idx] = parallelResult{index: idx, prompt: prompt, err: resp.Error, duration: time.Since(reqStart)}
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					results[idx] = parallelResult{index: idx, prompt: prompt, err: err, duration: time.Since(reqStart)}
 					return
 				}
 				fullResponse += resp.Message
 				if resp.Tokens > 0 {
 					tokenCount = int(resp.Tokens)
 				}
-				if resp.Done {
-					break
-				}
 			}
 
 			results[idx] = parallelResult{
@@ -477,7 +449,7 @@ func runParallelTest(ctx context.Context, llmService llmservice.LLMService, mode
 // Helpers for compare / backpressure test modes
 // =============================================================================
 
-func pingWithRetry(ctx context.Context, svc llmservice.LLMService, logger logging.SprintfLogger) error {
+func pingWithRetry(ctx context.Context, svc client.Client, logger logging.SprintfLogger) error {
 	retryAttempts := 10
 	retryInterval := 1 * time.Second
 	var err error
@@ -499,34 +471,40 @@ func pingWithRetry(ctx context.Context, svc llmservice.LLMService, logger loggin
 	return err
 }
 
-func loadModelHelper(ctx context.Context, svc llmservice.LLMService, modelPath string, logger logging.SprintfLogger) error {
-	progressChan := make(chan float32)
-	defer close(progressChan)
+// loadModelHelper loads the model, printing progress, and logs the load
+// duration and size reported back.
+func loadModelHelper(ctx context.Context, svc client.Client, modelPath string, logger logging.SprintfLogger) (client.LoadModelResult, error) {
+	progressChan := make(chan client.LoadProgress)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		for progress := range progressChan {
-			fmt.Printf("Model progress: %f\n", progress)
-			if progress >= 1.0 {
-				break
-			}
+			fmt.Printf("Model progress: %f (%s)\n", progress.Fraction, progress.Stage)
 		}
 	}()
 	logger.Infof("Loading model from '%s'...", modelPath)
-	err := svc.LoadModel(ctx, modelPath, progressChan)
+	result, err := svc.LoadModel(ctx, modelPath, progressChan)
+	close(progressChan)
 	wg.Wait()
-	return err
+	if err != nil {
+		return result, err
+	}
+	logger.Infof("Model loaded in %.2fs (%d bytes, %d progress updates)",
+		result.Duration.Seconds(), result.BytesTotal, result.Updates)
+	return result, nil
 }
 
-func createAndPrepareService(ctx context.Context, opts flagOptions, modelPath string, nParallel int, logger logging.SprintfLogger) (llmservice.LLMService, error) {
-	svcOpts := llmservice.LLMServiceOptions{
+func createAndPrepareService(ctx context.Context, opts flagOptions, modelPath string, nParallel int, logger logging.SprintfLogger) (client.Client, error) {
+	svcOpts := client.Options{
 		ServerPath: opts.ServerPath,
 		AttachHost: opts.AttachHost,
-		Transport:  opts.Transport,
+		Transport:  client.Transport(opts.Transport),
 		NParallel:  nParallel,
+		GRPCConns:  opts.GRPCConns,
+		Logger:     logger,
 	}
-	svc, err := llmservice.NewLlamacppLLMService(svcOpts, logger)
+	svc, err := client.New(svcOpts)
 	if err != nil {
 		return nil, fmt.Errorf("create service: %w", err)
 	}
@@ -534,40 +512,41 @@ func createAndPrepareService(ctx context.Context, opts flagOptions, modelPath st
 		svc.Shutdown()
 		return nil, fmt.Errorf("ping: %w", err)
 	}
-	if err := loadModelHelper(ctx, svc, modelPath, logger); err != nil {
+	if _, err := loadModelHelper(ctx, svc, modelPath, logger); err != nil {
 		svc.Shutdown()
 		return nil, fmt.Errorf("load model: %w", err)
 	}
 	return svc, nil
 }
 
-func runGreedyRequest(ctx context.Context, svc llmservice.LLMService, modelPath string, prompt string, maxTokens int) parallelResult {
+func runGreedyRequest(ctx context.Context, svc client.Client, modelPath string, prompt string, maxTokens int) parallelResult {
 	start := time.Now()
-	req := llmservice.PredictRequest{
+	req := client.PredictRequest{
 		ModelName:   modelPath,
 		Message:     prompt,
 		MaxTokens:   maxTokens,
 		Temperature: 0.0,
 		Stream:      true,
 	}
-	respChan := make(chan llmservice.PredictResponse, 128)
-	err := svc.Predict(ctx, req, respChan)
+	stream, err := svc.Predict(ctx, req)
 	if err != nil {
 		return parallelResult{prompt: prompt, err: err, duration: time.Since(start)}
 	}
+	defer stream.Close()
 	var fullResponse string
 	var tokenCount int
-	for resp := range respChan {
-		if resp.Error != nil {
-			return parallelResult{prompt: prompt, err: resp.Error, duration: time.Since(start)}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return parallelResult{prompt: prompt, err: err, duration: time.Since(start)}
 		}
 		fullResponse += resp.Message
 		if resp.Tokens > 0 {
 			tokenCount = int(resp.Tokens)
 		}
-		if resp.Done {
-			break
-		}
 	}
 	return parallelResult{
 		prompt:   prompt,
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/hypernetix/llamacpp_server/cmd/llamacppclienttest/tokendiff"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/pkg/client"
+)
+
+// =============================================================================
+// Reference test: compare the server's output with a local llama-cli run
+// =============================================================================
+
+// llamaCliArgs maps req to llama-cli flags so both run the same sampler chain.
+func llamaCliArgs(modelPath string, req client.PredictRequest) []string {
+	args := []string{
+		"-m", modelPath,
+		"-p", req.Message,
+		"-n", strconv.Itoa(req.MaxTokens),
+		"--temp", strconv.FormatFloat(req.Temperature, 'f', -1, 64),
+		"-no-cnv",
+		"--no-display-prompt",
+		"--simple-io",
+	}
+	if req.TopP != nil {
+		args = append(args, "--top-p", strconv.FormatFloat(*req.TopP, 'f', -1, 64))
+	}
+	if req.TopK != nil {
+		args = append(args, "--top-k", strconv.Itoa(*req.TopK))
+	}
+	if req.MinP != nil {
+		args = append(args, "--min-p", strconv.FormatFloat(*req.MinP, 'f', -1, 64))
+	}
+	if req.RepetitionPenalty != nil {
+		args = append(args, "--repeat-penalty", strconv.FormatFloat(*req.RepetitionPenalty, 'f', -1, 64))
+	}
+	if req.RandomSeed != nil {
+		args = append(args, "--seed", strconv.Itoa(*req.RandomSeed))
+	}
+	return args
+}
+
+// runLlamaCli returns the text llama-cli generated; its logs go to stderr.
+func runLlamaCli(ctx context.Context, path string, args []string, logger logging.SprintfLogger) (string, error) {
+	logger.Debugf("Running %s %q", path, args)
+	cmd := exec.CommandContext(ctx, path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", path, err, truncate(stderr.String(), 500))
+	}
+	return stdout.String(), nil
+}
+
+// divergence returns the index of the first server token whose text does not
+// continue the reference output, or -1 if the server output is a prefix of
+// it. llama-cli only prints text, so tokens are compared by their pieces.
+func divergence(run tokenRun, reference string) int {
+	rest := reference
+	for i, piece := range run.pieces {
+		if !strings.HasPrefix(rest, piece) {
+			return i
+		}
+		rest = rest[len(piece):]
+	}
+	return -1
+}
+
+// runReferenceTest generates with the same prompt and sampling parameters on
+// the server and with llama-cli, and reports the first diverging token.
+func runReferenceTest(ctx context.Context, llmService client.Client, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
+	prompt := "<|im_start|>user\nWhat is the capital of USA?<|im_end|>\n<|im_start|>assistant\n"
+	seed := opts.RandomSeed
+	if seed < 0 {
+		seed = 12345
+	}
+	req := client.PredictRequest{
+		ModelName:         modelPath,
+		Message:           prompt,
+		MaxTokens:         opts.MaxTokens,
+		Temperature:       opts.Temperature,
+		Stream:            true,
+		TopP:              Float64Ptr(opts.TopP),
+		TopK:              IntPtr(opts.TopK),
+		MinP:              Float64Ptr(opts.MinP),
+		RepetitionPenalty: Float64Ptr(opts.RepeatPenalty),
+		RandomSeed:        IntPtr(seed),
+	}
+
+	logger.Infof("=== REFERENCE TEST CONFIGURATION ===")
+	logger.Infof("Model: %s", modelPath)
+	logger.Infof("llama-cli: %s", opts.LlamaCliPath)
+	logger.Infof("Max tokens: %d", opts.MaxTokens)
+	logger.Infof("Sampling: temperature=%.3f, top_p=%.3f, top_k=%d, min_p=%.3f, repeat_penalty=%.3f, seed=%d",
+		opts.Temperature, opts.TopP, opts.TopK, opts.MinP, opts.RepeatPenalty, seed)
+	logger.Infof("====================================")
+
+	reference, err := runLlamaCli(ctx, opts.LlamaCliPath, llamaCliArgs(modelPath, req), logger)
+	if err != nil {
+		logger.Errorf("llama-cli run FAILED - %v", err)
+		os.Exit(1)
+	}
+	run, err := collectTokens(ctx, llmService, req)
+	if err != nil {
+		logger.Errorf("Server run FAILED - %v", err)
+		os.Exit(1)
+	}
+	output := strings.Join(run.pieces, "")
+	logger.Infof("  llama-cli: %q", truncate(reference, 200))
+	logger.Infof("  server:    %q", truncate(output, 200))
+
+	at := divergence(run, reference)
+	if at < 0 {
+		if len(output) < len(strings.TrimRight(reference, " \n")) {
+			logger.Warnf("  server stopped after %d tokens, before the end of the reference output", len(run.tokens))
+		}
+		logger.Infof("RESULT: SERVER OUTPUT MATCHES LLAMA-CLI (%d tokens)", len(run.tokens))
+		return
+	}
+	matched := strings.Join(run.pieces[:at], "")
+	logger.Errorf("  DIVERGED at token %d", at)
+	logger.Errorf("    common prefix: %q", truncate(matched, 200))
+	logger.Errorf("    server:    %s", tokenAt(run, at))
+	logger.Errorf("    llama-cli: %q", truncate(reference[len(matched):], 40))
+	// llama-cli only prints text, so the outputs are aligned word by word.
+	diff := tokendiff.Compare(tokendiff.FromText(output), tokendiff.FromText(reference))
+	for _, line := range diff.Format("server", "llama-cli", diffContext) {
+		logger.Errorf("    %s", line)
+	}
+	logger.Errorf("RESULT: SERVER OUTPUT DIVERGES FROM LLAMA-CLI")
+	os.Exit(1)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/pkg/client"
+)
+
+// =============================================================================
+// Stress test: a weighted mix of request classes at a target rate
+// =============================================================================
+
+const longGenerationPrompt = "Write a detailed explanation of machine learning concepts, including supervised learning, unsupervised learning, and neural networks. Include examples and applications."
+
+var embedTexts = []string{
+	"The quick brown fox jumps over the lazy dog.",
+	"Paris is the capital of France.",
+	"Water boils at 100 degrees Celsius at sea level.",
+	"The mitochondria is the powerhouse of the cell.",
+}
+
+// stressClass is a kind of request of the stress mix; run performs one and
+// returns the tokens it generated.
+type stressClass struct {
+	name   string
+	weight int
+	run    func(ctx context.Context) (int, error)
+}
+
+// stressStats are the outcomes of the requests of one class.
+type stressStats struct {
+	latencies []time.Duration // of the successful requests
+	errors    int
+	tokens    int
+	lastErr   error
+}
+
+// parseStressMix parses a mix such as "chat=6,long=3,embed=1" into the
+// weight of each class, rejecting unknown classes and negative weights.
+func parseStressMix(mix string, known []string) (map[string]int, error) {
+	weights := map[string]int{}
+	total := 0
+	for _, part := range strings.Split(mix, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid stress mix entry %q: want class=weight", part)
+		}
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown stress class %q: want one of %s", name, strings.Join(known, ", "))
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for stress class %q", value, name)
+		}
+		weights[name] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("stress mix %q has no weight", mix)
+	}
+	return weights, nil
+}
+
+// pickClass returns a class at random in proportion to the weights.
+func pickClass(classes []stressClass, total int) stressClass {
+	n := rand.IntN(total)
+	for _, c := range classes {
+		if n < c.weight {
+			return c
+		}
+		n -= c.weight
+	}
+	return classes[len(classes)-1]
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+// predictTokens runs req to the end and returns the number of tokens generated.
+func predictTokens(ctx context.Context, svc client.Client, req client.PredictRequest) (int, error) {
+	stream, err := svc.Predict(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	tokens := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return tokens, err
+		}
+		if resp.Tokens > 0 {
+			tokens = int(resp.Tokens)
+		}
+	}
+}
+
+// runStressTest starts requests of the classes of --stress-mix at
+// --stress-qps for --stress-duration, then waits for them and reports the
+// latency and error rate of each class.
+func runStressTest(ctx context.Context, llmService client.Client, modelPath string, opts flagOptions, logger logging.SprintfLogger) {
+	sampled := func(prompt string, maxTokens int) client.PredictRequest {
+		req := client.PredictRequest{
+			ModelName:         modelPath,
+			Message:           prompt,
+			MaxTokens:         maxTokens,
+			Temperature:       opts.Temperature,
+			Stream:            true,
+			TopP:              Float64Ptr(opts.TopP),
+			TopK:              IntPtr(opts.TopK),
+			MinP:              Float64Ptr(opts.MinP),
+			RepetitionPenalty: Float64Ptr(opts.RepeatPenalty),
+		}
+		if opts.RandomSeed >= 0 {
+			req.RandomSeed = IntPtr(opts.RandomSeed)
+		}
+		return req
+	}
+	classes := []stressClass{
+		{name: "chat", run: func(ctx context.Context) (int, error) {
+			prompt := testPrompts[rand.IntN(len(testPrompts))]
+			return predictTokens(ctx, llmService, sampled(prompt, opts.MaxTokens))
+		}},
+		{name: "long", run: func(ctx context.Context) (int, error) {
+			return predictTokens(ctx, llmService, sampled(longGenerationPrompt, 500))
+		}},
+		{name: "embed", run: func(ctx context.Context) (int, error) {
+			_, err := llmService.Embed(ctx, modelPath, embedTexts)
+			return 0, err
+		}},
+	}
+
+	var names []string
+	for _, c := range classes {
+		names = append(names, c.name)
+	}
+	weights, err := parseStressMix(opts.StressMix, names)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if opts.StressQPS <= 0 || opts.StressDuration <= 0 {
+		logger.Errorf("--stress-qps and --stress-duration must be positive")
+		os.Exit(1)
+	}
+	total := 0
+	for i := range classes {
+		classes[i].weight = weights[classes[i].name]
+		total += classes[i].weight
+	}
+
+	logger.Infof("=== STRESS TEST CONFIGURATION ===")
+	logger.Infof("Mix: %s", opts.StressMix)
+	logger.Infof("Target rate: %.2f requests/second for %s", opts.StressQPS, opts.StressDuration)
+	logger.Infof("Model: %s", modelPath)
+	logger.Infof("=================================")
+
+	stats := map[string]*stressStats{}
+	for _, c := range classes {
+		stats[c.name] = &stressStats{}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.StressQPS))
+	defer ticker.Stop()
+	deadline := time.After(opts.StressDuration)
+	startTime := time.Now()
+	started := 0
+
+loop:
+	for {
+		select {
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+		}
+		c := pickClass(classes, total)
+		started++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reqStart := time.Now()
+			tokens, err := c.run(ctx)
+			latency := time.Since(reqStart)
+
+			mu.Lock()
+			defer mu.Unlock()
+			s := stats[c.name]
+			s.tokens += tokens
+			if err != nil {
+				s.errors++
+				s.lastErr = err
+				return
+			}
+			s.latencies = append(s.latencies, latency)
+		}()
+	}
+	sendTime := time.Since(startTime)
+	logger.Infof("Started %d requests in %.2fs (%.2f requests/second), waiting for them...",
+		started, sendTime.Seconds(), float64(started)/sendTime.Seconds())
+	wg.Wait()
+	totalTime := time.Since(startTime)
+
+	logger.Infof("=== STRESS TEST RESULTS ===")
+	failed := 0
+	totalTokens := 0
+	for _, c := range classes {
+		s := stats[c.name]
+		n := len(s.latencies) + s.errors
+		if n == 0 {
+			continue
+		}
+		slices.Sort(s.latencies)
+		logger.Infof("  %-5s: %d requests, %d errors (%.1f%%), latency p50=%.2fs p95=%.2fs p99=%.2fs max=%.2fs, %d tokens",
+			c.name, n, s.errors, 100*float64(s.errors)/float64(n),
+			percentile(s.latencies, 50).Seconds(), percentile(s.latencies, 95).Seconds(),
+			percentile(s.latencies, 99).Seconds(), percentile(s.latencies, 100).Seconds(), s.tokens)
+		if s.lastErr != nil {
+			logger.Errorf("    last error: %v", s.lastErr)
+		}
+		failed += s.errors
+		totalTokens += s.tokens
+	}
+
+	logger.Infof("")
+	logger.Infof("Total wall-clock time: %.2fs", totalTime.Seconds())
+	logger.Infof("Total tokens generated: %d", totalTokens)
+	logger.Infof("Aggregate throughput: %.2f tokens/second", float64(totalTokens)/totalTime.Seconds())
+
+	if failed > 0 {
+		logger.Errorf("RESULT: %d OF %d STRESS REQUESTS FAILED", failed, started)
+		os.Exit(1)
+	}
+	logger.Infof("RESULT: ALL %d STRESS REQUESTS COMPLETED SUCCESSFULLY", started)
+}
@@ -0,0 +1,184 @@
+// Package tokendiff compares two generated token streams: it aligns them,
+// finds where they first diverge and renders the differences with some
+// tokens of context, for triaging output regressions.
+package tokendiff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Token is a generated token. ID is negative if only the text is known, as
+// for output split with FromText; tokens are then compared by text.
+type Token struct {
+	ID   int32
+	Text string
+}
+
+func (t Token) equal(o Token) bool {
+	if t.ID >= 0 && o.ID >= 0 {
+		return t.ID == o.ID
+	}
+	return t.Text == o.Text
+}
+
+func (t Token) String() string {
+	if t.ID < 0 {
+		return fmt.Sprintf("%q", t.Text)
+	}
+	return fmt.Sprintf("%d %q", t.ID, t.Text)
+}
+
+// FromText splits text into words with their leading spaces, the way BPE
+// vocabularies mostly cut it, so outputs known only as text can be aligned.
+func FromText(text string) []Token {
+	var tokens []Token
+	start, prevSpace := 0, false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > start && space && !prevSpace {
+			tokens = append(tokens, Token{ID: -1, Text: text[start:i]})
+			start = i
+		}
+		prevSpace = space
+	}
+	if start < len(text) {
+		tokens = append(tokens, Token{ID: -1, Text: text[start:]})
+	}
+	return tokens
+}
+
+// Hunk is a run of differing tokens: A[AStart:AEnd] of the first stream
+// stands where B[BStart:BEnd] of the second does. One side is empty for an
+// insertion or a deletion.
+type Hunk struct {
+	AStart, AEnd int
+	BStart, BEnd int
+}
+
+// Diff is the alignment of two token streams.
+type Diff struct {
+	A, B []Token
+	// First is the index of the first differing token, or -1 if the streams
+	// are equal. A stream that ends early differs at its length.
+	First int
+	Hunks []Hunk
+}
+
+// maxAligned bounds the tokens between the common prefix and suffix that
+// are aligned token by token; past it the rest is reported as one hunk to
+// keep the quadratic alignment cheap.
+const maxAligned = 4096
+
+// Compare aligns a and b along their longest common subsequence.
+func Compare(a, b []Token) Diff {
+	d := Diff{A: a, B: b, First: -1}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix].equal(b[prefix]) {
+		prefix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return d
+	}
+	d.First = prefix
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix].equal(b[len(b)-1-suffix]) {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma) > maxAligned || len(mb) > maxAligned {
+		d.Hunks = []Hunk{{prefix, prefix + len(ma), prefix, prefix + len(mb)}}
+		return d
+	}
+
+	// lcs[i][j] is the length of the common subsequence of ma[i:] and mb[j:].
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i].equal(mb[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var h *Hunk
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		if i < len(ma) && j < len(mb) && ma[i].equal(mb[j]) {
+			h = nil
+			i++
+			j++
+			continue
+		}
+		if h == nil {
+			d.Hunks = append(d.Hunks, Hunk{prefix + i, prefix + i, prefix + j, prefix + j})
+			h = &d.Hunks[len(d.Hunks)-1]
+		}
+		if j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]) {
+			i++
+			h.AEnd++
+		} else {
+			j++
+			h.BEnd++
+		}
+	}
+	return d
+}
+
+// Equal tells whether the streams are identical.
+func (d Diff) Equal() bool {
+	return d.First < 0
+}
+
+// maxHunks is the number of hunks Format renders.
+const maxHunks = 5
+
+// Format renders the hunks of d with up to context equal tokens of the
+// first stream around each, naming the streams nameA and nameB. It returns
+// one line per entry, for logging.
+func (d Diff) Format(nameA, nameB string, context int) []string {
+	width := max(len(nameA), len(nameB))
+	var lines []string
+	for n, h := range d.Hunks {
+		if n == maxHunks {
+			lines = append(lines, fmt.Sprintf("... %d more differences", len(d.Hunks)-n))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("@@ %s token %d, %s token %d @@", nameA, h.AStart, nameB, h.BStart))
+		if before := d.A[max(h.AStart-context, 0):h.AStart]; len(before) > 0 {
+			lines = append(lines, fmt.Sprintf("  %*s  %q", width, "", joinText(before)))
+		}
+		lines = append(lines, fmt.Sprintf("- %*s: %s", width, nameA, joinTokens(d.A[h.AStart:h.AEnd])))
+		lines = append(lines, fmt.Sprintf("+ %*s: %s", width, nameB, joinTokens(d.B[h.BStart:h.BEnd])))
+		if after := d.A[h.AEnd:min(h.AEnd+context, len(d.A))]; len(after) > 0 {
+			lines = append(lines, fmt.Sprintf("  %*s  %q", width, "", joinText(after)))
+		}
+	}
+	return lines
+}
+
+func joinText(tokens []Token) string {
+	var sb strings.Builder
+	for _, t := range tokens {
+		sb.WriteString(t.Text)
+	}
+	return sb.String()
+}
+
+func joinTokens(tokens []Token) string {
+	if len(tokens) == 0 {
+		return "<none>"
+	}
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, ", ")
+}
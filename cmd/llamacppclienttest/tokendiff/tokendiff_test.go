@@ -0,0 +1,58 @@
+package tokendiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tokens(ids ...int32) []Token {
+	out := make([]Token, len(ids))
+	for i, id := range ids {
+		out[i] = Token{ID: id, Text: string(rune('a' + id))}
+	}
+	return out
+}
+
+func TestCompareEqual(t *testing.T) {
+	d := Compare(tokens(1, 2, 3), tokens(1, 2, 3))
+	require.True(t, d.Equal())
+	require.Empty(t, d.Hunks)
+	require.Empty(t, d.Format("a", "b", 3))
+}
+
+func TestCompareAligns(t *testing.T) {
+	// 3 is replaced by 9, 5 is dropped and 8 added at the end.
+	d := Compare(tokens(1, 2, 3, 4, 5, 6, 7), tokens(1, 2, 9, 4, 6, 7, 8))
+	require.Equal(t, 2, d.First)
+	require.Equal(t, []Hunk{{2, 3, 2, 3}, {4, 5, 4, 4}, {7, 7, 6, 7}}, d.Hunks)
+}
+
+func TestCompareEarlyEnd(t *testing.T) {
+	d := Compare(tokens(1, 2), tokens(1, 2, 3))
+	require.Equal(t, 2, d.First)
+	require.Equal(t, []Hunk{{2, 2, 2, 3}}, d.Hunks)
+}
+
+func TestCompareByText(t *testing.T) {
+	// Without IDs on one side, tokens compare by text.
+	a := []Token{{ID: 5, Text: "The"}, {ID: 7, Text: " capital"}, {ID: 9, Text: " is"}}
+	d := Compare(a, FromText("The capital was"))
+	require.Equal(t, 2, d.First)
+	require.Equal(t, []string{
+		"@@ run token 2, ref token 2 @@",
+		"       \"The capital\"",
+		"- run: 9 \" is\"",
+		"+ ref: \" was\"",
+	}, d.Format("run", "ref", 2))
+}
+
+func TestFromText(t *testing.T) {
+	var words []string
+	for _, tok := range FromText("Hello world,\n\nhow are  you") {
+		require.Negative(t, tok.ID)
+		words = append(words, tok.Text)
+	}
+	require.Equal(t, []string{"Hello", " world,", "\n\nhow", " are", "  you"}, words)
+	require.Empty(t, FromText(""))
+}
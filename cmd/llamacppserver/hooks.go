@@ -0,0 +1,13 @@
+package main
+
+import "github.com/hypernetix/llamacpp_server/internal/llmservice"
+
+// hooks pre- and post-process every Predict call. Builds that add
+// guardrails, PII scrubbing or similar put a file into this package that
+// calls registerHook from an init function.
+var hooks []llmservice.Hook
+
+// registerHook adds h after the hooks registered before it.
+func registerHook(h llmservice.Hook) {
+	hooks = append(hooks, h)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+)
+
+// listener is one enabled endpoint of the server: a gRPC, HTTP or metrics
+// listener with the functions that serve and stop it.
+type listener struct {
+	name     string
+	ln       net.Listener
+	serve    func(net.Listener) error
+	shutdown func(context.Context) error
+}
+
+// listenerSet serves every listener and stops them together.
+type listenerSet struct {
+	listeners []listener
+	failed    chan error
+	logger    logging.SprintfLogger
+}
+
+func newListenerSet(logger logging.SprintfLogger) *listenerSet {
+	return &listenerSet{
+		failed: make(chan error, 1),
+		logger: logger,
+	}
+}
+
+func (s *listenerSet) add(l listener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// listen opens network/address; a stale Unix socket file is removed first.
+func listen(network, address string) (net.Listener, error) {
+	if network == "unix" {
+		if err := os.Remove(address); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// start serves all listeners. The first one that fails unexpectedly is
+// reported on Failed so the server can shut down instead of running with an
+// endpoint missing.
+func (s *listenerSet) start() {
+	for _, l := range s.listeners {
+		s.logger.Infof("%s listening at %s", l.name, l.ln.Addr().String())
+		go func(l listener) {
+			err := l.serve(l.ln)
+			if err == nil || errors.Is(err, http.ErrServerClosed) {
+				return
+			}
+			s.logger.Errorf("%s failed: %v", l.name, err)
+			select {
+			case s.failed <- err:
+			default:
+			}
+		}(l)
+	}
+}
+
+// Failed receives the error of the first listener that stopped serving.
+func (s *listenerSet) Failed() <-chan error {
+	return s.failed
+}
+
+// shutdown stops all listeners concurrently, each waiting for its in-flight
+// requests until ctx is done.
+func (s *listenerSet) shutdown(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, l := range s.listeners {
+		wg.Add(1)
+		go func(l listener) {
+			defer wg.Done()
+			s.logger.Infof("Stopping %s...", l.name)
+			if err := l.shutdown(ctx); err != nil {
+				s.logger.Errorf("%s shutdown error: %v", l.name, err)
+				return
+			}
+			s.logger.Infof("%s stopped", l.name)
+		}(l)
+	}
+	wg.Wait()
+}
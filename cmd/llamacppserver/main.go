@@ -4,40 +4,149 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
-	"os/signal"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/acl"
+	"github.com/hypernetix/llamacpp_server/internal/audit"
 	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
 	"github.com/hypernetix/llamacpp_server/internal/grpcserver"
 	"github.com/hypernetix/llamacpp_server/internal/httpserver"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
 	"github.com/hypernetix/llamacpp_server/internal/llmservice"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
+	"github.com/hypernetix/llamacpp_server/internal/version"
 
 	flags "github.com/jessevdk/go-flags"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 type flagOptions struct {
 	Host         string `long:"host" default:"127.0.0.1" description:"host address to bind (use 0.0.0.0 for Docker)"`
 	GRPCPort     string `long:"grpc-port" default:"50052" description:"port for gRPC server (disabled if empty)"`
 	HTTPPort     string `long:"http-port" default:"8082" description:"port for HTTP+SSE server (disabled if empty)"`
+	GRPCAddr     string `long:"grpc-addr" description:"host:port for gRPC server, overriding --host and --grpc-port"`
+	HTTPAddr     string `long:"http-addr" description:"host:port for HTTP+SSE server, overriding --host and --http-port"`
+	GRPCSocket   string `long:"grpc-socket" description:"Unix domain socket path for an additional gRPC listener (disabled if empty)"`
+	MetricsAddr  string `long:"metrics-addr" description:"host:port for a separate Prometheus /metrics listener (disabled if empty; /metrics stays on the HTTP server)"`
 	NGpuLayers   int    `long:"ngpu" default:"99" description:"number of GPU layers"`
 	UseMmap      bool   `long:"mmap" description:"use mmap"`
+	UseMlock     bool   `long:"mlock" description:"lock the model in RAM"`
 	SplitMode    string `long:"split-mode" default:"layer" description:"how to split model across GPUs: none, layer, row (row=tensor parallelism)"`
 	MainGpu      int    `long:"main-gpu" default:"0" description:"main GPU index when split-mode=none"`
 	TensorSplit  string `long:"tensor-split" default:"" description:"GPU split proportions, comma-separated (e.g. '0.5,0.5' for even 2-GPU split)"`
 	FlashAttn    bool   `long:"flash-attn" description:"enable flash attention for faster inference"`
 	NParallel    int    `long:"n-parallel" default:"1" description:"number of concurrent inference slots (default 1)"`
+	Parallel     int    `long:"parallel" default:"0" description:"alias for --n-parallel, matching llama.cpp server (overrides --n-parallel when set)"`
 	Threads      int    `long:"threads" default:"0" description:"number of threads for generation (0=auto-detect)"`
 	ThreadsBatch int    `long:"threads-batch" default:"0" description:"number of threads for batch/prompt processing (0=auto-detect)"`
 	CtxSize      int    `long:"ctx-size" default:"4096" description:"total KV cache size (per-slot budget = ctx-size / n-parallel)"`
+	MaxCtxSize   int    `long:"max-ctx-size" default:"0" description:"cap on the context size of any model, including LoadModel overrides (0=no cap); sizes are also reduced to n-parallel times the model's training context and to the free memory"`
 	BatchSize    int    `long:"batch-size" default:"2048" description:"batch size for prompt processing"`
+	KvType       string `long:"kv-type" default:"" description:"KV cache type: f16, q8_0 or q4_0 (default f16); LoadModel can override it per model"`
+
+	ReplicaGpus   string `long:"replica-gpus" default:"" description:"comma-separated GPU indices to load each model on once per GPU, e.g. '0,1'; requests are spread over the copies, each with its own n-parallel slots (needs two or more)"`
+	ReplicaPolicy string `long:"replica-policy" default:"least-loaded" description:"how requests are spread over --replica-gpus: least-loaded or round-robin"`
+
+	MaxBatchTokens int           `long:"max-batch-tokens" default:"0" description:"max tokens decoded per scheduler step; lower keeps streams responsive during long prefills (0=batch-size); LoadModel can override it per model"`
+	MaxBatchSeqs   int           `long:"max-batch-seqs" default:"0" description:"max slots adding tokens to a scheduler step; slots left out take turns (0=n-parallel); LoadModel can override it per model"`
+	BatchWait      time.Duration `long:"batch-wait" default:"0" description:"delay the first decode after idle by up to this long to batch requests arriving together, trading time to first token for throughput (0=dispatch at once); LoadModel can override it per model"`
+	SamplerCache   int           `long:"sampler-cache-size" default:"0" description:"sampler chains kept for reuse by requests with the same sampling parameters (0=2*n-parallel, -1 disables)"`
+
+	GgmlBackends   string `long:"ggml-backends" default:"" description:"comma-separated GGML backends to load, e.g. 'cpu,cuda' for libggml-cpu and libggml-cuda (default all found)"`
+	GgmlBackendDir string `long:"ggml-backend-dir" default:"" description:"directory of the GGML backend libraries (default the executable's directory)"`
+	LlamaLib       string `long:"llama-lib" default:"" description:"path of libllama to load at run time, with libggml and libggml-base from the same directory; only for builds with the llama_dl tag (default the system library path)"`
+
+	Models        []string `long:"model" description:"model file to load at startup, before serving (repeatable)"`
+	ModelSchedule []string `long:"model-schedule" description:"load or unload a model at a local time of day as '[DAYS] HH:MM load|unload MODEL', e.g. 'mon-fri 08:00 load big.gguf'; DAYS is a list of days or ranges such as mon-fri or sat,sun (default every day) (repeatable)"`
+
+	DefaultModel string   `long:"default-model" description:"model path or alias used by requests that name no model"`
+	ModelAliases []string `long:"model-alias" description:"another name for a model as NAME=PATH; all names of a path share its loaded instance (repeatable)"`
+
+	NativeLogLevel   string `long:"native-log-level" default:"info" description:"minimum level of llama.cpp log lines to forward: debug, info, warn, error or none"`
+	NativeLogRate    int    `long:"native-log-rate" default:"100" description:"max llama.cpp log lines per second, errors excepted (0=unlimited)"`
+	NoNativeLogDedup bool   `long:"no-native-log-dedup" description:"forward repeated identical llama.cpp log lines instead of counting them"`
+	LoadLogLines     int    `long:"load-log-lines" default:"500" description:"llama.cpp output lines kept per model load for GetLoadLog (0 disables)"`
+
+	AutoLoad bool `long:"auto-load" description:"load a model on its first Predict instead of failing with MODEL_NOT_FOUND"`
+
+	Backend        string        `long:"backend" default:"llama" description:"inference backend: llama (llama.cpp) or mock (deterministic synthetic output without a model file or GPU, for API tests and load tests)"`
+	MockTokenDelay time.Duration `long:"mock-token-delay" default:"20ms" description:"time the mock backend spends per generated token"`
+
+	MaxConcurrentRequests int      `long:"max-concurrent-requests" default:"0" description:"max requests decoded at once (0=n-parallel)"`
+	MaxQueue              int      `long:"max-queue" default:"512" description:"max requests waiting for a slot; more are rejected with QUEUE_FULL (0=unlimited)"`
+	TenantWeights         []string `long:"tenant-weight" description:"fair-queue weight for an API key as KEY=WEIGHT (repeatable; unlisted keys get 1)"`
+
+	CompletionCacheEntries int           `long:"completion-cache-entries" default:"0" description:"completions of greedy or fixed-seed requests kept for identical requests (0 disables the cache)"`
+	CompletionCacheMB      int           `long:"completion-cache-mb" default:"256" description:"max text held by the completion cache, in MiB (0=unlimited)"`
+	CompletionCacheTTL     time.Duration `long:"completion-cache-ttl" default:"1h" description:"how long a cached completion is served (0=until evicted)"`
+
+	AuditLog        string `long:"audit-log" description:"write one JSON record per request (request ID, hashed client key, model, parameters, token counts, latency, finish reason) to this file, or POST them to this http(s) URL (disabled if empty)"`
+	AuditPromptHash bool   `long:"audit-prompt-hash" description:"include the SHA-256 of each prompt in audit records"`
+
+	DailyTokenQuota   []string `long:"daily-token-quota" description:"tokens an API key may use per UTC day as KEY=TOKENS; KEY * sets the default for other keys, anonymous clients and periods a key does not set; 0 is unlimited (repeatable)"`
+	MonthlyTokenQuota []string `long:"monthly-token-quota" description:"tokens an API key may use per UTC month as KEY=TOKENS; KEY * sets the default (repeatable)"`
+	QuotaState        string   `long:"quota-state" description:"file that token usage is saved to every minute and on shutdown, and restored from at startup (usage is kept in memory only if empty)"`
+
+	ModelACL []string `long:"model-acl" description:"restrict models matching a glob to API keys as PATTERN=KEY1,KEY2; patterns without a slash match the file name, the first matching rule applies and unmatched models stay public (repeatable)"`
+
+	ModelKeyEnv     string `long:"model-key-env" default:"LLAMACPP_MODEL_KEY" description:"environment variable holding the AES-256 key (hex or base64) of encrypted models; removed from the environment once read"`
+	ModelKeyCommand string `long:"model-key-command" description:"command printing the key of encrypted models, e.g. a KMS decrypt call; used if the model-key-env variable is unset"`
+	DecryptDir      string `long:"decrypt-dir" description:"directory for decrypted model copies where models cannot be decrypted into memory (non-Linux); copies are wiped after loading (default: system temp directory)"`
+
+	ModerationModel     string   `long:"moderation-model" description:"classification model scoring every completion for output moderation; its labels are the categories (loaded at startup; disabled if empty)"`
+	ModerationThreshold float32  `long:"moderation-threshold" default:"0.5" description:"label probability from which a moderation category is flagged"`
+	ModerationIgnore    []string `long:"moderation-ignore" description:"classifier label that is never flagged, e.g. safe (repeatable)"`
+	ModerationBlock     []string `long:"moderation-block" description:"flagged category that rejects the completion with REQUEST_REJECTED instead of only annotating it; * for all (repeatable)"`
+
+	HealthCheckInterval   time.Duration `long:"health-check-interval" default:"0" description:"generate one token with each loaded model this often and report models whose probe fails as unhealthy in /health and the status RPCs (0 disables)"`
+	HealthCheckMaxLatency time.Duration `long:"health-check-max-latency" default:"0" description:"also report a model as unhealthy if its probe token took longer than this, not counting queueing (0=no limit)"`
+
+	SpecialTokens   string `long:"special-tokens" default:"render" description:"default output of generated control tokens such as <|im_end|>: render (as text), skip or event (as separate stream events); requests may override it"`
+	MaxTokensPolicy string `long:"max-tokens-policy" default:"clamp" description:"when a prompt leaves less room in the slot's context than max_tokens: clamp (lower max_tokens to the room left) or reject (fail with CONTEXT_LENGTH_EXCEEDED); requests may override it"`
+
+	StreamBuffer        int           `long:"stream-buffer" default:"256" description:"responses buffered per gRPC Predict stream before the slow-consumer policy applies; under abort a stream twice this far behind fails at once"`
+	SlowConsumerPolicy  string        `long:"slow-consumer-policy" default:"abort" description:"what to do when a stream buffer is full: abort (RESOURCE_EXHAUSTED after --slow-consumer-timeout) or pause (block generation)"`
+	SlowConsumerTimeout time.Duration `long:"slow-consumer-timeout" default:"10s" description:"how long to wait for a slow client before aborting its stream"`
+	PrefillKeepalive    time.Duration `long:"prefill-keepalive" default:"5s" description:"max silence on a Predict or /completions stream while the prompt is processed; repeats prefill progress (0 disables)"`
+	ReattachWindow      time.Duration `long:"reattach-window" default:"30s" description:"how long a Predict call with an idempotency key keeps running after its client disconnects, waiting for a retry to re-attach"`
+	ResultTTL           time.Duration `long:"result-ttl" default:"0" description:"keep Predict results this long for GetResult, and finish generations whose client disconnected (0 disables)"`
+	MaxUploadBytes      int           `long:"max-upload-bytes" default:"67108864" description:"largest prompt a PredictUpload call may send in pieces, in bytes (0=no limit)"`
+	GRPCCompression     string        `long:"grpc-compression" default:"auto" description:"gzip for gRPC responses: auto (when the request was compressed), always (when the client accepts it) or off; compressed requests are always accepted"`
+
+	KeepaliveMinTime             time.Duration `long:"keepalive-min-time" default:"5m" description:"minimum interval between client keepalive pings; faster clients are disconnected with ENHANCE_YOUR_CALM"`
+	KeepalivePermitWithoutStream bool          `long:"keepalive-permit-without-stream" description:"allow client keepalive pings on connections without active streams"`
+	KeepaliveTime                time.Duration `long:"keepalive-time" default:"2h" description:"ping a client after this long without activity to check the connection is alive"`
+	KeepaliveTimeout             time.Duration `long:"keepalive-timeout" default:"20s" description:"close the connection if a keepalive ping is not acknowledged within this time"`
+	MaxConnectionIdle            time.Duration `long:"max-connection-idle" default:"0" description:"close connections without active calls after this long (0=never)"`
+	MaxConnectionAge             time.Duration `long:"max-connection-age" default:"0" description:"gracefully close connections after this long, e.g. to rebalance behind a load balancer (0=never)"`
+	MaxConnectionAgeGrace        time.Duration `long:"max-connection-age-grace" default:"0" description:"time given to calls in flight on a connection closed by --max-connection-age (0=unlimited)"`
+
+	ShutdownTimeout time.Duration `long:"shutdown-timeout" default:"25s" description:"how long to wait on SIGINT/SIGTERM for in-flight requests to be canceled and listeners to stop before exiting"`
+}
+
+// listenAddr returns addr if set, else host:port; an empty port disables
+// the listener.
+func listenAddr(addr, host, port string) (string, error) {
+	if addr != "" {
+		return addr, nil
+	}
+	if port == "" {
+		return "", nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
 }
 
 func main() {
@@ -51,8 +160,30 @@ func main() {
 		os.Exit(1)
 	}
 
-	if opts.GRPCPort == "" && opts.HTTPPort == "" {
-		fmt.Printf("gRPC port or HTTP port is required")
+	grpcAddr, err := listenAddr(opts.GRPCAddr, opts.Host, opts.GRPCPort)
+	if err != nil {
+		fmt.Printf("Invalid gRPC port: %v\n", err)
+		os.Exit(1)
+	}
+	httpAddr, err := listenAddr(opts.HTTPAddr, opts.Host, opts.HTTPPort)
+	if err != nil {
+		fmt.Printf("Invalid HTTP port: %v\n", err)
+		os.Exit(1)
+	}
+	if grpcAddr == "" && opts.GRPCSocket == "" && httpAddr == "" {
+		fmt.Printf("gRPC port, gRPC socket or HTTP port is required")
+		os.Exit(1)
+	}
+
+	if opts.Parallel > 0 {
+		opts.NParallel = opts.Parallel
+	}
+	if opts.Backend != llmservice.BackendLlama && opts.Backend != llmservice.BackendMock {
+		fmt.Printf("Invalid backend %q: must be llama or mock\n", opts.Backend)
+		os.Exit(1)
+	}
+	if opts.MockTokenDelay < 0 {
+		fmt.Printf("Invalid mock-token-delay %s: must not be negative\n", opts.MockTokenDelay)
 		os.Exit(1)
 	}
 
@@ -85,26 +216,228 @@ func main() {
 		}
 	}
 
+	var replicaGpus []int
+	if opts.ReplicaGpus != "" {
+		for _, s := range strings.Split(opts.ReplicaGpus, ",") {
+			s = strings.TrimSpace(s)
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 0 {
+				fmt.Printf("Invalid replica-gpus value %q: want a GPU index\n", s)
+				os.Exit(1)
+			}
+			replicaGpus = append(replicaGpus, v)
+		}
+		if len(replicaGpus) < 2 {
+			fmt.Printf("Invalid replica-gpus %q: need at least two GPUs\n", opts.ReplicaGpus)
+			os.Exit(1)
+		}
+	}
+	replicaPolicy, err := inferenceengine.ParseReplicaPolicy(opts.ReplicaPolicy)
+	if err != nil {
+		fmt.Printf("Invalid replica-policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	tenantWeights := make(map[string]float64)
+	for _, tw := range opts.TenantWeights {
+		key, weightStr, ok := strings.Cut(tw, "=")
+		if !ok || key == "" {
+			fmt.Printf("Invalid tenant-weight %q: expected KEY=WEIGHT\n", tw)
+			os.Exit(1)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil || weight <= 0 {
+			fmt.Printf("Invalid tenant-weight %q: weight must be a positive number\n", tw)
+			os.Exit(1)
+		}
+		tenantWeights[key] = weight
+	}
+
+	modelAliases := make(map[string]string)
+	for _, ma := range opts.ModelAliases {
+		name, path, ok := strings.Cut(ma, "=")
+		if !ok || name == "" || path == "" {
+			fmt.Printf("Invalid model-alias %q: expected NAME=PATH\n", ma)
+			os.Exit(1)
+		}
+		modelAliases[name] = path
+	}
+	for name, path := range modelAliases {
+		if _, ok := modelAliases[path]; ok {
+			fmt.Printf("Invalid model-alias %s=%s: %s is an alias itself\n", name, path, path)
+			os.Exit(1)
+		}
+	}
+
+	var schedule []modelmanagement.ScheduleEntry
+	for _, ms := range opts.ModelSchedule {
+		entry, err := modelmanagement.ParseScheduleEntry(ms)
+		if err != nil {
+			fmt.Printf("Invalid model-schedule: %v\n", err)
+			os.Exit(1)
+		}
+		schedule = append(schedule, entry)
+	}
+
+	quotaOpts, err := parseQuotaOptions(opts.DailyTokenQuota, opts.MonthlyTokenQuota)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	modelACL, err := acl.Parse(opts.ModelACL)
+	if err != nil {
+		fmt.Printf("Invalid model-acl: %v\n", err)
+		os.Exit(1)
+	}
+
+	modelKey, err := loadModelKey(opts.ModelKeyEnv, opts.ModelKeyCommand)
+	if err != nil {
+		fmt.Printf("Invalid model key: %v\n", err)
+		os.Exit(1)
+	}
+
+	nativeLogLevel, err := llamacppbindings.ParseLogLevel(opts.NativeLogLevel)
+	if err != nil {
+		fmt.Printf("Invalid native-log-level: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := llmservice.ValidateKvCacheType(opts.KvType); err != nil {
+		fmt.Printf("Invalid kv-type: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.ShutdownTimeout <= 0 {
+		fmt.Printf("Invalid shutdown-timeout %s: must be positive\n", opts.ShutdownTimeout)
+		os.Exit(1)
+	}
+
+	if opts.CompletionCacheEntries < 0 || opts.CompletionCacheMB < 0 || opts.CompletionCacheTTL < 0 {
+		fmt.Printf("Invalid completion cache limits: they must not be negative\n")
+		os.Exit(1)
+	}
+
+	if opts.MaxBatchTokens < 0 || opts.MaxBatchSeqs < 0 || opts.BatchWait < 0 {
+		fmt.Printf("Invalid batching limits: max-batch-tokens, max-batch-seqs and batch-wait must not be negative\n")
+		os.Exit(1)
+	}
+
+	if opts.MaxConcurrentRequests < 0 || opts.MaxQueue < 0 {
+		fmt.Printf("Invalid request limits: max-concurrent-requests and max-queue must not be negative\n")
+		os.Exit(1)
+	}
+
+	slowConsumerPolicy, err := grpcserver.ParseSlowConsumerPolicy(opts.SlowConsumerPolicy)
+	if err != nil {
+		fmt.Printf("Invalid slow-consumer-policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	for name, d := range map[string]time.Duration{
+		"keepalive-min-time":       opts.KeepaliveMinTime,
+		"keepalive-time":           opts.KeepaliveTime,
+		"keepalive-timeout":        opts.KeepaliveTimeout,
+		"max-connection-idle":      opts.MaxConnectionIdle,
+		"max-connection-age":       opts.MaxConnectionAge,
+		"max-connection-age-grace": opts.MaxConnectionAgeGrace,
+	} {
+		if d < 0 {
+			fmt.Printf("Invalid %s %s: must not be negative\n", name, d)
+			os.Exit(1)
+		}
+	}
+
+	grpcCompression, err := grpcserver.ParseCompressionMode(opts.GRPCCompression)
+	if err != nil {
+		fmt.Printf("Invalid grpc-compression: %v\n", err)
+		os.Exit(1)
+	}
+
+	maxTokensPolicy, err := inferenceengine.ParseMaxTokensPolicy(opts.MaxTokensPolicy)
+	if err != nil {
+		fmt.Printf("Invalid max-tokens-policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	specialTokens, err := inferenceengine.ParseSpecialTokens(opts.SpecialTokens)
+	if err != nil {
+		fmt.Printf("Invalid special-tokens: %v\n", err)
+		os.Exit(1)
+	}
+
 	// --- Initialize llama.cpp and create shared service ---
 
+	info := version.Get()
+	logger.Infof("llamacppserver %s (commit %s, %s, llama.cpp %s)", info.Version, info.Commit, info.GoVersion, info.LlamaCppVersion)
 	logger.Infof("Initializing llama.cpp...")
-	llamacppbindings.Initialize(logger.With("module", "llama.cpp"))
+	llamacppbindings.SetNativeLogOptions(llamacppbindings.NativeLogOptions{
+		MinLevel:     nativeLogLevel,
+		Dedup:        !opts.NoNativeLogDedup,
+		RateLimit:    opts.NativeLogRate,
+		LoadLogLines: opts.LoadLogLines,
+	})
+	if err := llamacppbindings.Load(opts.LlamaLib); err != nil {
+		fmt.Printf("Invalid llama-lib: %v\n", err)
+		os.Exit(1)
+	}
+	backendOpts := llamacppbindings.BackendOptions{Dir: opts.GgmlBackendDir}
+	for _, name := range strings.Split(opts.GgmlBackends, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			backendOpts.Names = append(backendOpts.Names, name)
+		}
+	}
+	if err := llamacppbindings.InitializeBackends(logger.With("module", "llama.cpp"), backendOpts); err != nil {
+		fmt.Printf("Failed to initialize llama.cpp: %v\n", err)
+		os.Exit(1)
+	}
+	libVersion, libCommit := llamacppbindings.LibraryVersion()
+	logger.Infof("llama.cpp library: ggml %s, commit %s", libVersion, libCommit)
+	logger.Infof("GGML backends: %s", strings.Join(llamacppbindings.Backends(), ", "))
 
 	serviceOpts := llmservice.Options{
 		Model: llmservice.LoadModelOptions{
 			NGpuLayers:  opts.NGpuLayers,
 			UseMmap:     opts.UseMmap,
+			UseMlock:    opts.UseMlock,
 			SplitMode:   splitMode,
 			MainGpu:     opts.MainGpu,
 			TensorSplit: tensorSplit,
+			ReplicaGpus: replicaGpus,
+
+			DecryptionKey: modelKey,
+			DecryptDir:    opts.DecryptDir,
+			MaxCtxSize:    opts.MaxCtxSize,
 		},
 		Predict: llmservice.PredictOptions{
-			FlashAttn:          opts.FlashAttn,
-			NParallel:          opts.NParallel,
-			NThreads:           opts.Threads,
-			NThreadsBatch:      opts.ThreadsBatch,
-			CtxSize:   opts.CtxSize,
-			BatchSize: opts.BatchSize,
+			FlashAttn:     opts.FlashAttn,
+			NParallel:     opts.NParallel,
+			NThreads:      opts.Threads,
+			NThreadsBatch: opts.ThreadsBatch,
+			CtxSize:       opts.CtxSize,
+			BatchSize:     opts.BatchSize,
+			KvCacheType:   opts.KvType,
+			MaxConcurrent: opts.MaxConcurrentRequests,
+			MaxQueue:      opts.MaxQueue,
+			Batching: inferenceengine.Batching{
+				MaxTokens: opts.MaxBatchTokens,
+				MaxWait:   opts.BatchWait,
+				MaxSeqs:   opts.MaxBatchSeqs,
+			},
+			SamplerCache:  opts.SamplerCache,
+			TenantWeights: tenantWeights,
+			SpecialTokens: specialTokens,
+			ReplicaPolicy: replicaPolicy,
+
+			MaxTokensPolicy: maxTokensPolicy,
+		},
+		AutoLoad:       opts.AutoLoad,
+		Backend:        opts.Backend,
+		MockTokenDelay: opts.MockTokenDelay,
+		Cache: llmservice.CacheOptions{
+			MaxEntries: opts.CompletionCacheEntries,
+			MaxBytes:   int64(opts.CompletionCacheMB) << 20,
+			TTL:        opts.CompletionCacheTTL,
 		},
 	}
 
@@ -113,122 +446,254 @@ func main() {
 		logger.Infof("Tensor split: %v", tensorSplit)
 	}
 	logger.Infof("Inference slots (n_parallel): %d", opts.NParallel)
+	logger.Infof("Context size: %d, batch size: %d", opts.CtxSize, opts.BatchSize)
+	if opts.MaxBatchTokens > 0 || opts.MaxBatchSeqs > 0 || opts.BatchWait > 0 {
+		logger.Infof("Batching: max tokens %d, max seqs %d, wait %s", opts.MaxBatchTokens, opts.MaxBatchSeqs, opts.BatchWait)
+	}
+	if opts.KvType != "" {
+		logger.Infof("KV cache type: %s", opts.KvType)
+	}
 	if opts.FlashAttn {
 		logger.Infof("Flash attention: enabled")
 	}
 	logger.Infof("Mode: continuous batching")
+	if len(tenantWeights) > 0 {
+		logger.Infof("Fair queue: %d weighted tenant(s)", len(tenantWeights))
+	}
 
-	service := llmservice.NewService(serviceOpts, logger)
-
-	// --- Start gRPC server (if configured) ---
-
-	var grpcServer *grpc.Server
-	if opts.GRPCPort != "" {
-		grpcPort, err := strconv.Atoi(opts.GRPCPort)
+	var auditLog *audit.Logger
+	if opts.AuditLog != "" {
+		sink, err := audit.Open(opts.AuditLog)
 		if err != nil {
-			fmt.Printf("Invalid gRPC port: %v", err)
+			fmt.Printf("Failed to open audit log: %v\n", err)
 			os.Exit(1)
 		}
+		auditLog = audit.NewLogger(sink, audit.Options{HashPrompts: opts.AuditPromptHash}, logger)
+		serviceOpts.Audit = auditLog
+		logger.Infof("Audit log: %s", opts.AuditLog)
+	}
 
-		grpcListener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", opts.Host, grpcPort))
-		if err != nil {
-			fmt.Printf("Failed to listen for gRPC at %s:%d: %v", opts.Host, grpcPort, err)
-			os.Exit(1)
+	var quotaTracker *quota.Tracker
+	if len(opts.DailyTokenQuota)+len(opts.MonthlyTokenQuota) > 0 {
+		quotaTracker = quota.New(quotaOpts)
+		if opts.QuotaState != "" {
+			if err := quotaTracker.Load(opts.QuotaState); err != nil {
+				fmt.Printf("Failed to load quota state: %v\n", err)
+				os.Exit(1)
+			}
+			go saveQuotaState(quotaTracker, opts.QuotaState, logger)
 		}
+		serviceOpts.Quota = quotaTracker
+		logger.Infof("Token quotas: %d key(s), default daily %d, monthly %d",
+			len(quotaOpts.Limits), quotaOpts.Default.Daily, quotaOpts.Default.Monthly)
+	}
 
-		grpcServer = grpc.NewServer(
-			grpc.WriteBufferSize(1*1024*1024),
-			grpc.InitialWindowSize(1*1024*1024),
-			grpc.InitialConnWindowSize(1*1024*1024),
-		)
+	if opts.ModerationModel != "" {
+		serviceOpts.Moderation = llmservice.ModerationOptions{
+			Model: opts.ModerationModel,
+			Policy: llmservice.ModerationPolicy{
+				Threshold: opts.ModerationThreshold,
+				Ignore:    opts.ModerationIgnore,
+				Block:     opts.ModerationBlock,
+			},
+		}
+		if !slices.Contains(opts.Models, opts.ModerationModel) {
+			opts.Models = append(opts.Models, opts.ModerationModel)
+		}
+		logger.Infof("Output moderation with %s, blocking %v", opts.ModerationModel, opts.ModerationBlock)
+	}
 
-		proto.RegisterLLMServerServer(grpcServer, grpcserver.NewServer(service, logger))
+	serviceOpts.HealthCheck = llmservice.HealthCheckOptions{
+		Interval:   opts.HealthCheckInterval,
+		MaxLatency: opts.HealthCheckMaxLatency,
+	}
 
-		logger.Infof("gRPC server listening at %s", grpcListener.Addr().String())
-		go func() {
-			if err := grpcServer.Serve(grpcListener); err != nil {
-				logger.Errorf("gRPC server Serve failed: %v", err)
-			}
-		}()
+	serviceOpts.DefaultModel = opts.DefaultModel
+	serviceOpts.Aliases = modelAliases
+	if opts.DefaultModel != "" {
+		logger.Infof("Default model: %s", opts.DefaultModel)
+	}
+	for name, path := range modelAliases {
+		logger.Infof("Model alias: %s -> %s", name, path)
 	}
 
-	// --- Start HTTP server (if configured) ---
+	serviceOpts.Schedule = schedule
+	for _, entry := range schedule {
+		logger.Infof("Model schedule: %s", entry)
+	}
 
-	var httpSrv *httpserver.Server
-	if opts.HTTPPort != "" {
-		httpPort, err := strconv.Atoi(opts.HTTPPort)
-		if err != nil {
-			fmt.Printf("Invalid HTTP port: %v\n", err)
+	if len(hooks) > 0 {
+		serviceOpts.Hooks = hooks
+		logger.Infof("Predict hooks: %d", len(hooks))
+	}
+
+	if modelACL.Len() > 0 {
+		serviceOpts.ACL = modelACL
+		logger.Infof("Model access control: %d rule(s)", modelACL.Len())
+	}
+	service := llmservice.NewService(serviceOpts, logger)
+
+	// --- Preload models ---
+
+	for _, path := range opts.Models {
+		logger.Infof("Preloading model %s...", path)
+		start := time.Now()
+		onProgress := func(progress modelmanagement.LoadProgress) {
+			logger.Debugf("Preloading model %s: %.0f%% (%s)", path, progress.Fraction*100, progress.Stage)
+		}
+		if err := service.LoadModel(context.Background(), path, modelmanagement.LoadOverrides{}, onProgress); err != nil {
+			fmt.Printf("Failed to preload model %s: %v\n", path, err)
+			service.Stop()
 			os.Exit(1)
 		}
+		logger.Infof("Preloaded model %s in %.2fs", path, time.Since(start).Seconds())
+	}
 
-		httpListener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", opts.Host, httpPort))
+	// --- Open listeners ---
+
+	listeners := newListenerSet(logger)
+	mustListen := func(name, network, address string) net.Listener {
+		ln, err := listen(network, address)
 		if err != nil {
-			fmt.Printf("Failed to listen for HTTP at %s:%d: %v", opts.Host, httpPort, err)
+			fmt.Printf("Failed to listen for %s at %s: %v\n", name, address, err)
 			os.Exit(1)
 		}
+		return ln
+	}
 
-		httpSrv = httpserver.NewServer(service, httpListener.Addr().String(), logger)
-		go func() {
-			if err := httpSrv.Start(httpListener); err != nil && err.Error() != "http: Server closed" {
-				logger.Errorf("HTTP server failed: %v", err)
+	if grpcAddr != "" || opts.GRPCSocket != "" {
+		serverOpts := []grpc.ServerOption{
+			grpc.WriteBufferSize(1 * 1024 * 1024),
+			grpc.InitialWindowSize(1 * 1024 * 1024),
+			grpc.InitialConnWindowSize(1 * 1024 * 1024),
+			grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+				MinTime:             opts.KeepaliveMinTime,
+				PermitWithoutStream: opts.KeepalivePermitWithoutStream,
+			}),
+			grpc.KeepaliveParams(keepalive.ServerParameters{
+				MaxConnectionIdle:     opts.MaxConnectionIdle,
+				MaxConnectionAge:      opts.MaxConnectionAge,
+				MaxConnectionAgeGrace: opts.MaxConnectionAgeGrace,
+				Time:                  opts.KeepaliveTime,
+				Timeout:               opts.KeepaliveTimeout,
+			}),
+		}
+		serverOpts = append(serverOpts, grpcserver.LoggingOptions(logger)...)
+		serverOpts = append(serverOpts, grpcserver.CompressionOptions(grpcCompression)...)
+		serverOpts = append(serverOpts, grpcserver.AuthOptions(service)...)
+		grpcServer := grpc.NewServer(serverOpts...)
+
+		grpcOpts := grpcserver.Options{
+			Stream: grpcserver.StreamOptions{
+				BufferSize:          opts.StreamBuffer,
+				SlowConsumerPolicy:  slowConsumerPolicy,
+				SlowConsumerTimeout: opts.SlowConsumerTimeout,
+				PrefillKeepalive:    opts.PrefillKeepalive,
+			},
+			ReattachWindow: opts.ReattachWindow,
+			ResultTTL:      opts.ResultTTL,
+			MaxUploadBytes: opts.MaxUploadBytes,
+		}
+		proto.RegisterLLMServerServer(grpcServer, grpcserver.NewServer(service, grpcOpts, logger))
+
+		// Both gRPC listeners share the server; stopping it stops both.
+		stopGRPC := func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				grpcServer.Stop()
+				return fmt.Errorf("timed out, forced stop: %w", ctx.Err())
 			}
-		}()
+		}
+		if grpcAddr != "" {
+			listeners.add(listener{
+				name:     "gRPC server",
+				ln:       mustListen("gRPC", "tcp", grpcAddr),
+				serve:    grpcServer.Serve,
+				shutdown: stopGRPC,
+			})
+		}
+		if opts.GRPCSocket != "" {
+			listeners.add(listener{
+				name:     "gRPC socket server",
+				ln:       mustListen("gRPC", "unix", opts.GRPCSocket),
+				serve:    grpcServer.Serve,
+				shutdown: stopGRPC,
+			})
+		}
 	}
 
-	// --- Wait for shutdown signal ---
+	if httpAddr != "" {
+		httpListener := mustListen("HTTP", "tcp", httpAddr)
+		httpOpts := httpserver.Options{PrefillKeepalive: opts.PrefillKeepalive}
+		httpSrv := httpserver.NewServer(service, httpListener.Addr().String(), httpOpts, logger)
+		listeners.add(listener{
+			name:     "HTTP server",
+			ln:       httpListener,
+			serve:    httpSrv.Start,
+			shutdown: httpSrv.Shutdown,
+		})
+	}
 
-	sig := make(chan os.Signal, 1)
-	if runtime.GOOS == "windows" {
-		signal.Notify(sig)
-	} else {
-		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	if opts.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsSrv := &http.Server{Addr: opts.MetricsAddr, Handler: mux}
+		listeners.add(listener{
+			name:     "metrics server",
+			ln:       mustListen("metrics", "tcp", opts.MetricsAddr),
+			serve:    metricsSrv.Serve,
+			shutdown: metricsSrv.Shutdown,
+		})
 	}
 
-	receivedSignal := <-sig
+	listeners.start()
 
-	if runtime.GOOS == "windows" {
-		if receivedSignal != os.Interrupt {
-			logger.Errorf("Wrong signal received: got %q, want %q\n", receivedSignal, os.Interrupt)
-			os.Exit(42)
-		}
-	}
+	// --- Wait for shutdown signal ---
+
+	sig, shutdownDone := notifyShutdown()
+	defer shutdownDone()
 
-	logger.Infof("Received signal: %v", receivedSignal)
+	select {
+	case receivedSignal := <-sig:
+		logger.Infof("Received signal: %v", receivedSignal)
+	case err := <-listeners.Failed():
+		logger.Errorf("Listener failed, shutting down: %v", err)
+	}
 	logger.Infof("Stopping...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
 	defer cancel()
 
-	// Stop inference service first (drains in-flight predictions)
+	// Stop the inference service first: it cancels in-flight requests, so
+	// their handlers return and the listeners can stop gracefully.
 	logger.Infof("Stopping inference service...")
-	service.Stop()
-	logger.Infof("Inference service stopped")
-
-	// Stop HTTP server
-	if httpSrv != nil {
-		logger.Infof("Stopping HTTP server...")
-		if err := httpSrv.Shutdown(ctx); err != nil {
-			logger.Errorf("HTTP server shutdown error: %v", err)
-		}
-		logger.Infof("HTTP server stopped")
-	}
-
-	// Stop gRPC server
-	if grpcServer != nil {
-		logger.Infof("Stopping gRPC server...")
-		stopped := make(chan struct{})
-		go func() {
-			grpcServer.GracefulStop()
-			close(stopped)
-		}()
-
-		select {
-		case <-stopped:
-			logger.Infof("gRPC server stopped gracefully")
-		case <-ctx.Done():
-			logger.Infof("Shutdown timed out, forcing gRPC server stop")
-			grpcServer.Stop()
+	serviceStopped := make(chan struct{})
+	go func() {
+		service.Stop()
+		close(serviceStopped)
+	}()
+	select {
+	case <-serviceStopped:
+		logger.Infof("Inference service stopped")
+	case <-ctx.Done():
+		logger.Errorf("Inference service did not stop within %s", opts.ShutdownTimeout)
+	}
+
+	listeners.shutdown(ctx)
+
+	if err := auditLog.Close(ctx); err != nil {
+		logger.Errorf("Closing audit log: %v", err)
+	}
+	if quotaTracker != nil && opts.QuotaState != "" {
+		if err := quotaTracker.Save(opts.QuotaState); err != nil {
+			logger.Errorf("Saving token usage: %v", err)
 		}
 	}
 
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/hypernetix/llamacpp_server/internal/modelcrypt"
+)
+
+// loadModelKey returns the key for encrypted models from the environment
+// variable envName or, if that is unset, from the output of command, which
+// typically asks a KMS to unwrap the key. It returns nil if neither is set.
+// The variable is removed from the environment once read.
+func loadModelKey(envName, command string) ([]byte, error) {
+	if value, ok := os.LookupEnv(envName); ok && envName != "" {
+		os.Unsetenv(envName)
+		key, err := modelcrypt.ParseKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %w", envName, err)
+		}
+		return key, nil
+	}
+	if command == "" {
+		return nil, nil
+	}
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("model-key-command: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	key, err := modelcrypt.ParseKey(string(out))
+	if err != nil {
+		return nil, fmt.Errorf("model-key-command: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
+)
+
+// quotaSaveInterval is how often token usage is written to --quota-state.
+const quotaSaveInterval = time.Minute
+
+// parseQuotaOptions parses --daily-token-quota and --monthly-token-quota
+// values of the form KEY=TOKENS, where the key "*" sets the default for
+// other clients and for the period a key does not set. A key's 0 lifts its
+// cap even if the default has one.
+func parseQuotaOptions(daily, monthly []string) (quota.Options, error) {
+	opts := quota.Options{Limits: make(map[string]quota.Limits)}
+	parse := func(flag string, values []string, set func(*quota.Limits, int64)) error {
+		for _, v := range values {
+			key, tokensStr, ok := strings.Cut(v, "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid %s %q: expected KEY=TOKENS", flag, v)
+			}
+			tokens, err := strconv.ParseInt(strings.TrimSpace(tokensStr), 10, 64)
+			if err != nil || tokens < 0 {
+				return fmt.Errorf("invalid %s %q: tokens must be a non-negative integer", flag, v)
+			}
+			if key == "*" {
+				set(&opts.Default, tokens)
+				continue
+			}
+			if tokens == 0 {
+				tokens = quota.Unlimited
+			}
+			limits := opts.Limits[key]
+			set(&limits, tokens)
+			opts.Limits[key] = limits
+		}
+		return nil
+	}
+	if err := parse("daily-token-quota", daily, func(l *quota.Limits, n int64) { l.Daily = n }); err != nil {
+		return quota.Options{}, err
+	}
+	if err := parse("monthly-token-quota", monthly, func(l *quota.Limits, n int64) { l.Monthly = n }); err != nil {
+		return quota.Options{}, err
+	}
+	return opts, nil
+}
+
+// saveQuotaState writes token usage to path every quotaSaveInterval.
+func saveQuotaState(tracker *quota.Tracker, path string, logger logging.SprintfLogger) {
+	for range time.Tick(quotaSaveInterval) {
+		if err := tracker.Save(path); err != nil {
+			logger.Errorf("Saving token usage: %v", err)
+		}
+	}
+}
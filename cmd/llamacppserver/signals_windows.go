@@ -0,0 +1,77 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// consoleEvent is a Windows console control event delivered as a signal.
+type consoleEvent uint32
+
+const (
+	ctrlCEvent        consoleEvent = 0
+	ctrlBreakEvent    consoleEvent = 1
+	ctrlCloseEvent    consoleEvent = 2
+	ctrlLogoffEvent   consoleEvent = 5
+	ctrlShutdownEvent consoleEvent = 6
+)
+
+func (e consoleEvent) Signal() {}
+
+func (e consoleEvent) String() string {
+	switch e {
+	case ctrlCEvent:
+		return "CTRL_C_EVENT"
+	case ctrlBreakEvent:
+		return "CTRL_BREAK_EVENT"
+	case ctrlCloseEvent:
+		return "CTRL_CLOSE_EVENT"
+	case ctrlLogoffEvent:
+		return "CTRL_LOGOFF_EVENT"
+	case ctrlShutdownEvent:
+		return "CTRL_SHUTDOWN_EVENT"
+	default:
+		return fmt.Sprintf("console event %d", uint32(e))
+	}
+}
+
+// notifyShutdown installs a console control handler for Ctrl+C, Ctrl+Break,
+// closing the console window, logoff and system shutdown, mirroring SIGINT
+// and SIGTERM on Unix. It returns a channel that receives the event and a
+// function to call once shutdown is complete.
+//
+// Windows terminates the process as soon as the handler returns from a
+// close, logoff or shutdown event, so for those the handler blocks until
+// the returned function is called (the system still enforces its own
+// timeout, about 5 seconds for a console close).
+func notifyShutdown() (<-chan os.Signal, func()) {
+	sig := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+
+	handler := syscall.NewCallback(func(ctrlType uintptr) uintptr {
+		event := consoleEvent(ctrlType)
+		select {
+		case sig <- event:
+		default:
+		}
+		switch event {
+		case ctrlCloseEvent, ctrlLogoffEvent, ctrlShutdownEvent:
+			<-stopped
+		}
+		return 1 // handled; skip the default handler, which exits at once
+	})
+
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	r, _, err := kernel32.NewProc("SetConsoleCtrlHandler").Call(handler, 1)
+	if r == 0 {
+		fmt.Printf("SetConsoleCtrlHandler failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	var once sync.Once
+	return sig, func() { once.Do(func() { close(stopped) }) }
+}
@@ -0,0 +1,95 @@
+// Command modelencrypt encrypts GGUF models for llamacppserver, which
+// decrypts them at load time with the key given by --model-key-env or
+// --model-key-command.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/hypernetix/llamacpp_server/internal/modelcrypt"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+type flagOptions struct {
+	KeyEnv  string `long:"key-env" default:"LLAMACPP_MODEL_KEY" description:"environment variable holding the AES-256 key (hex or base64)"`
+	Decrypt bool   `long:"decrypt" description:"decrypt instead of encrypt"`
+	GenKey  bool   `long:"gen-key" description:"print a new random key as hex and exit"`
+	Args    struct {
+		Input  string `positional-arg-name:"input"`
+		Output string `positional-arg-name:"output"`
+	} `positional-args:"yes"`
+}
+
+func main() {
+	var opts flagOptions
+	parser := flags.NewParser(&opts, flags.HelpFlag)
+	if _, err := parser.Parse(); err != nil {
+		fmt.Printf("Command line flags parsing failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.GenKey {
+		key := make([]byte, modelcrypt.KeySize)
+		if _, err := rand.Read(key); err != nil {
+			fmt.Printf("Generating key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(hex.EncodeToString(key))
+		return
+	}
+
+	if opts.Args.Input == "" || opts.Args.Output == "" {
+		fmt.Println("Usage: modelencrypt [--decrypt] <input> <output>")
+		os.Exit(1)
+	}
+	key, err := modelcrypt.ParseKey(os.Getenv(opts.KeyEnv))
+	if err != nil {
+		fmt.Printf("Invalid key in $%s: %v\n", opts.KeyEnv, err)
+		os.Exit(1)
+	}
+
+	if err := run(opts.Args.Input, opts.Args.Output, key, opts.Decrypt); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// run writes the encrypted or decrypted input to output, which must not
+// exist yet. A partial output is removed.
+func run(input, output string, key []byte, decrypt bool) (err error) {
+	in, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(output)
+		}
+	}()
+
+	w := bufio.NewWriterSize(out, modelcrypt.ChunkSize)
+	if decrypt {
+		err = modelcrypt.Decrypt(context.Background(), w, bufio.NewReader(in), key)
+	} else {
+		err = modelcrypt.Encrypt(w, in, key)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
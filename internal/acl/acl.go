@@ -0,0 +1,131 @@
+// Package acl restricts which API keys may load and use which models, so
+// private models can be served next to public ones. Models that no rule
+// matches are open to every caller, including anonymous ones.
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrAccessDenied matches every *DeniedError.
+var ErrAccessDenied = errors.New("model access denied")
+
+// DeniedError is returned when a caller's key may not use a model.
+type DeniedError struct {
+	Model   string
+	Pattern string // the rule that matched Model
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("access to model %s denied", e.Model)
+}
+
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+// Rule allows Keys, and no one else, to use the models matching Pattern.
+// Pattern is a path.Match glob; one without a slash is matched against the
+// base name of the model path.
+type Rule struct {
+	Pattern string
+	Keys    []string
+}
+
+// List is an ordered set of rules; the first rule matching a model decides.
+// A nil *List allows everything.
+type List struct {
+	rules []rule
+}
+
+type rule struct {
+	pattern string
+	keys    map[string]bool
+}
+
+// New validates rules and builds a list from them.
+func New(rules []Rule) (*List, error) {
+	l := &List{}
+	for _, r := range rules {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+		}
+		keys := make(map[string]bool, len(r.Keys))
+		for _, k := range r.Keys {
+			if k == "" {
+				return nil, fmt.Errorf("pattern %q: empty key", r.Pattern)
+			}
+			keys[k] = true
+		}
+		l.rules = append(l.rules, rule{pattern: r.Pattern, keys: keys})
+	}
+	return l, nil
+}
+
+// Parse builds a list from values of the form PATTERN=KEY1,KEY2.
+func Parse(values []string) (*List, error) {
+	rules := make([]Rule, 0, len(values))
+	for _, v := range values {
+		pattern, keys, ok := strings.Cut(v, "=")
+		if !ok || pattern == "" || keys == "" {
+			return nil, fmt.Errorf("invalid rule %q: expected PATTERN=KEY1,KEY2", v)
+		}
+		r := Rule{Pattern: pattern}
+		for _, k := range strings.Split(keys, ",") {
+			r.Keys = append(r.Keys, strings.TrimSpace(k))
+		}
+		rules = append(rules, r)
+	}
+	return New(rules)
+}
+
+// Len returns the number of rules.
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.rules)
+}
+
+func (r rule) matches(model string) bool {
+	name := model
+	if !strings.Contains(r.pattern, "/") {
+		name = path.Base(model)
+	}
+	ok, _ := path.Match(r.pattern, name)
+	return ok
+}
+
+// Check returns a *DeniedError if the caller with key may not use model.
+func (l *List) Check(key, model string) error {
+	if l == nil {
+		return nil
+	}
+	for _, r := range l.rules {
+		if !r.matches(model) {
+			continue
+		}
+		if key != "" && r.keys[key] {
+			return nil
+		}
+		return &DeniedError{Model: model, Pattern: r.pattern}
+	}
+	return nil
+}
+
+// Filter returns the models the caller with key may use.
+func (l *List) Filter(key string, models []string) []string {
+	if l.Len() == 0 {
+		return models
+	}
+	allowed := make([]string, 0, len(models))
+	for _, m := range models {
+		if l.Check(key, m) == nil {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
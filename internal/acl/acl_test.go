@@ -0,0 +1,60 @@
+package acl
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	l, err := Parse([]string{
+		"internal-*.gguf=team-a, team-b",
+		"/models/private/*=admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, l.Len())
+
+	// Unmatched models are public.
+	require.NoError(t, l.Check("", "/models/llama.gguf"))
+	require.NoError(t, l.Check("someone", "/models/llama.gguf"))
+
+	// Patterns without a slash match the base name anywhere.
+	require.NoError(t, l.Check("team-a", "/data/internal-ft.gguf"))
+	require.NoError(t, l.Check("team-b", "/other/internal-ft.gguf"))
+	err = l.Check("team-c", "/data/internal-ft.gguf")
+	require.True(t, errors.Is(err, ErrAccessDenied))
+	var denied *DeniedError
+	require.ErrorAs(t, err, &denied)
+	require.Equal(t, "internal-*.gguf", denied.Pattern)
+	require.Error(t, l.Check("", "/data/internal-ft.gguf"))
+
+	require.NoError(t, l.Check("admin", "/models/private/x.gguf"))
+	require.Error(t, l.Check("team-a", "/models/private/x.gguf"))
+	// * does not cross directories.
+	require.NoError(t, l.Check("", "/models/private/sub/x.gguf"))
+
+	require.Equal(t, []string{"/models/llama.gguf", "/data/internal-ft.gguf"},
+		l.Filter("team-a", []string{"/models/llama.gguf", "/data/internal-ft.gguf", "/models/private/x.gguf"}))
+}
+
+func TestFirstRuleWins(t *testing.T) {
+	l, err := Parse([]string{"special.gguf=a", "*.gguf=b"})
+	require.NoError(t, err)
+	require.NoError(t, l.Check("a", "/m/special.gguf"))
+	require.Error(t, l.Check("b", "/m/special.gguf"))
+	require.NoError(t, l.Check("b", "/m/other.gguf"))
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, v := range []string{"", "pattern", "=key", "pattern=", "[=key", "p=a,,b"} {
+		_, err := Parse([]string{v})
+		require.Error(t, err, v)
+	}
+}
+
+func TestNilList(t *testing.T) {
+	var l *List
+	require.NoError(t, l.Check("", "/m.gguf"))
+	require.Equal(t, []string{"/m.gguf"}, l.Filter("", []string{"/m.gguf"}))
+}
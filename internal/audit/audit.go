@@ -0,0 +1,202 @@
+// Package audit records one structured record per inference request, for
+// compliance and capacity analysis. Records are queued and written by a
+// background goroutine, so a slow sink never delays requests; when the queue
+// is full, records are dropped and counted.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+)
+
+// Record describes one request. Client identities are hashed and prompts are
+// only recorded as a hash, if at all.
+type Record struct {
+	Time      time.Time `json:"time"` // when the request finished
+	RequestID string    `json:"request_id"`
+	// Client is a hash of the caller's API key; empty for anonymous calls.
+	Client    string `json:"client,omitempty"`
+	Operation string `json:"operation"` // predict, score, embed or classify
+	Model     string `json:"model"`
+	Params    Params `json:"params"`
+
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
+	LatencyMs        float64 `json:"latency_ms"`
+	QueueMs          float64 `json:"queue_ms,omitempty"`
+	FinishReason     string  `json:"finish_reason,omitempty"`
+	Cached           bool    `json:"cached,omitempty"`
+	Error            string  `json:"error,omitempty"`
+
+	// PromptHash is the SHA-256 of the prompt, set with
+	// Options.HashPrompts.
+	PromptHash string `json:"prompt_hash,omitempty"`
+}
+
+// Params summarizes the request parameters. Texts, grammars and token lists
+// are reduced to counts or flags.
+type Params struct {
+	MaxTokens         int     `json:"max_tokens,omitempty"`
+	Temperature       float32 `json:"temperature,omitempty"`
+	TopP              float32 `json:"top_p,omitempty"`
+	TopK              int32   `json:"top_k,omitempty"`
+	MinP              float32 `json:"min_p,omitempty"`
+	RepetitionPenalty float32 `json:"repetition_penalty,omitempty"`
+	Seed              *int    `json:"seed,omitempty"`
+	PromptLookup      int     `json:"prompt_lookup,omitempty"`
+	Regex             bool    `json:"regex,omitempty"`
+	StopTokens        int     `json:"stop_tokens,omitempty"`
+	BannedStrings     int     `json:"banned_strings,omitempty"`
+	Images            int     `json:"images,omitempty"`
+	Texts             int     `json:"texts,omitempty"` // inputs of embed and classify
+}
+
+// Sink stores records. Write is only called from one goroutine at a time.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Options configures a Logger.
+type Options struct {
+	// HashPrompts records the SHA-256 of each prompt.
+	HashPrompts bool
+	// QueueSize is the number of records buffered for the sink; 0 means
+	// 4096.
+	QueueSize int
+}
+
+// maxBatch is the most records handed to the sink in one Write.
+const maxBatch = 256
+
+var (
+	recordsWritten = metrics.NewCounter("llamacpp_audit_records_total",
+		"Audit records written to the audit sink.")
+	recordsDropped = metrics.NewCounter("llamacpp_audit_records_dropped_total",
+		"Audit records dropped because the queue was full or the sink failed.")
+)
+
+// Logger queues records for a Sink. A nil *Logger discards records.
+type Logger struct {
+	sink        Sink
+	hashPrompts bool
+	logger      logging.SprintfLogger
+
+	mx      sync.RWMutex // guards closing records against Log
+	closed  bool
+	records chan Record
+	done    chan struct{}
+	// ctx is canceled when Close gives up on the sink.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewLogger starts a logger writing to sink.
+func NewLogger(sink Sink, opts Options, logger logging.SprintfLogger) *Logger {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 4096
+	}
+	l := &Logger{
+		sink:        sink,
+		hashPrompts: opts.HashPrompts,
+		logger:      logger.With("module", "audit"),
+		records:     make(chan Record, opts.QueueSize),
+		done:        make(chan struct{}),
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	go l.run()
+	return l
+}
+
+// Log queues rec, hashing prompt into it if enabled. It never blocks.
+// Records logged after Close are dropped.
+func (l *Logger) Log(rec Record, prompt string) {
+	if l == nil {
+		return
+	}
+	if l.hashPrompts {
+		rec.PromptHash = hashString(prompt)
+	}
+	l.mx.RLock()
+	defer l.mx.RUnlock()
+	if l.closed {
+		recordsDropped.Inc()
+		return
+	}
+	select {
+	case l.records <- rec:
+	default:
+		recordsDropped.Inc()
+	}
+}
+
+// ClientID returns the identity recorded for an API key: a prefix of its
+// SHA-256, or empty for anonymous calls.
+func ClientID(key string) string {
+	if key == "" {
+		return ""
+	}
+	return hashString(key)[:16]
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	batch := make([]Record, 0, maxBatch)
+	for rec := range l.records {
+		batch = append(batch[:0], rec)
+	fill:
+		for len(batch) < maxBatch {
+			select {
+			case rec, ok := <-l.records:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, rec)
+			default:
+				break fill
+			}
+		}
+		if err := l.sink.Write(l.ctx, batch); err != nil {
+			l.logger.Errorf("dropped %d records: %v", len(batch), err)
+			recordsDropped.Add(float64(len(batch)))
+			continue
+		}
+		recordsWritten.Add(float64(len(batch)))
+	}
+}
+
+// Close writes the queued records and closes the sink. If ctx is done first,
+// the context passed to Sink.Write is canceled so the remaining writes end
+// quickly.
+func (l *Logger) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mx.Lock()
+	if l.closed {
+		l.mx.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.records)
+	l.mx.Unlock()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		l.cancel()
+		<-l.done
+	}
+	l.cancel()
+	return l.sink.Close()
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, r io.Reader) []Record {
+	t.Helper()
+	var records []Record
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var rec Record
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	require.NoError(t, sc.Err())
+	return records
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := Open(path)
+	require.NoError(t, err)
+	l := NewLogger(sink, Options{HashPrompts: true}, logging.NewSprintfLogger())
+	seed := 42
+	l.Log(Record{
+		RequestID:        "r1",
+		Client:           ClientID("secret-key"),
+		Operation:        "predict",
+		Model:            "/m.gguf",
+		Params:           Params{MaxTokens: 16, Seed: &seed},
+		PromptTokens:     5,
+		CompletionTokens: 16,
+		FinishReason:     "length",
+	}, "Hello")
+	l.Log(Record{RequestID: "r2", Operation: "embed", Params: Params{Texts: 2}}, "a\x00b")
+	require.NoError(t, l.Close(context.Background()))
+	// Closing again and logging after Close are harmless.
+	require.NoError(t, l.Close(context.Background()))
+	l.Log(Record{RequestID: "r3"}, "")
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	records := readRecords(t, f)
+	require.Len(t, records, 2)
+	require.Equal(t, "r1", records[0].RequestID)
+	require.Len(t, records[0].Client, 16)
+	require.NotContains(t, records[0].Client, "secret")
+	require.Equal(t, 42, *records[0].Params.Seed)
+	require.Equal(t, 16, records[0].CompletionTokens)
+	require.Equal(t, "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", records[0].PromptHash)
+	require.Equal(t, 2, records[1].Params.Texts)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestHTTPSink(t *testing.T) {
+	var mx sync.Mutex
+	var got []Record
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		records := readRecords(t, r.Body)
+		mx.Lock()
+		got = append(got, records...)
+		mx.Unlock()
+	}))
+	defer srv.Close()
+
+	sink, err := Open(srv.URL)
+	require.NoError(t, err)
+	l := NewLogger(sink, Options{}, logging.NewSprintfLogger())
+	for _, id := range []string{"a", "b", "c"} {
+		l.Log(Record{RequestID: id, Operation: "predict"}, "prompt")
+	}
+	require.NoError(t, l.Close(context.Background()))
+	require.Len(t, got, 3)
+	require.Equal(t, "c", got[2].RequestID)
+	require.Empty(t, got[0].PromptHash)
+}
+
+// blockingSink blocks each Write until ctx is done.
+type blockingSink struct{ writes int }
+
+func (s *blockingSink) Write(ctx context.Context, records []Record) error {
+	s.writes++
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestLoggerDropsWhenFull(t *testing.T) {
+	sink := &blockingSink{}
+	l := NewLogger(sink, Options{QueueSize: 2}, logging.NewSprintfLogger())
+	for i := 0; i < 10; i++ {
+		l.Log(Record{}, "") // never blocks
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.NoError(t, l.Close(ctx))
+	require.True(t, errors.Is(ctx.Err(), context.DeadlineExceeded))
+	require.GreaterOrEqual(t, sink.writes, 1)
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	l.Log(Record{}, "prompt")
+	require.NoError(t, l.Close(context.Background()))
+}
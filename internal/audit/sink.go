@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Open returns the sink for target: an http:// or https:// URL that batches
+// of records are POSTed to, or a file path that records are appended to.
+// Both receive JSON lines.
+func Open(target string) (Sink, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return NewHTTPSink(target, nil), nil
+	}
+	return NewFileSink(target)
+}
+
+// FileSink appends records to a file as JSON lines.
+type FileSink struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// NewFileSink opens path for appending, creating it if needed. The file is
+// only readable by its owner.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, records []Record) error {
+	if err := writeLines(s.w, records); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func (s *FileSink) Close() error {
+	err := s.w.Flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// HTTPSink POSTs each batch of records as JSON lines
+// (application/x-ndjson). A batch the endpoint does not accept with a 2xx
+// status is dropped.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink creates a sink posting to url. A nil client means one with a
+// 10s timeout.
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPSink{url: url, client: client}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, records []Record) error {
+	var body bytes.Buffer
+	if err := writeLines(&body, records); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPSink) Close() error {
+	return nil
+}
+
+func writeLines(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package llamacppbindings
+
+/*
+#include "llama.h"
+#include "ggml.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIncompatible is returned by InitializeBackends if the llama.cpp library
+// does not match the headers the bindings were compiled with.
+var ErrIncompatible = errors.New("incompatible llama.cpp library")
+
+// LibraryVersion returns the GGML version of the loaded library and the
+// llama.cpp commit it was built from.
+func LibraryVersion() (version, commit string) {
+	return C.GoString(C.ggml_version()), C.GoString(C.ggml_commit())
+}
+
+// checkABI compares the default parameters the library returns with the
+// defaults of the release the bindings were written for. A struct layout
+// change between releases, such as flash_attn becoming flash_attn_type in
+// b6770, shifts the fields after it, so it shows here instead of as a
+// crash or a silently ignored setting later.
+func checkABI() error {
+	cp := C.llama_context_default_params()
+	mp := C.llama_model_default_params()
+	checks := []struct {
+		field     string
+		got, want any
+	}{
+		{"llama_context_params.n_batch", int(cp.n_batch), 2048},
+		{"llama_context_params.n_ubatch", int(cp.n_ubatch), 512},
+		{"llama_context_params.n_seq_max", int(cp.n_seq_max), 1},
+		{"llama_context_params.flash_attn_type", int(cp.flash_attn_type), int(C.LLAMA_FLASH_ATTN_TYPE_AUTO)},
+		{"llama_context_params.embeddings", bool(cp.embeddings), false},
+		{"llama_context_params.offload_kqv", bool(cp.offload_kqv), true},
+		{"llama_model_params.use_mmap", bool(mp.use_mmap), true},
+		{"llama_model_params.use_mlock", bool(mp.use_mlock), false},
+		{"llama_model_params.vocab_only", bool(mp.vocab_only), false},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			version, commit := LibraryVersion()
+			return fmt.Errorf("%w (ggml %s, commit %s): default %s is %v instead of %v; build the server with the headers of the llama.cpp release it runs with",
+				ErrIncompatible, version, commit, c.field, c.got, c.want)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package llamacppbindings
+
+/*
+#include <stdlib.h>
+#include "ggml-backend.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"unsafe"
+)
+
+// BackendOptions selects the GGML backend libraries loaded by
+// InitializeBackends.
+type BackendOptions struct {
+	// Dir holds the backend libraries. Empty means the directories
+	// llama.cpp searches: the executable's and the working directory.
+	Dir string
+	// Names lists the backends to load, such as "cpu", "cuda", "vulkan"
+	// or "cpu-haswell", in order; see BackendLibrary. Empty loads every
+	// backend found.
+	Names []string
+}
+
+// BackendLibrary returns the file name of the library of the named backend
+// on this platform, e.g. "libggml-cuda.so" for "cuda".
+func BackendLibrary(name string) string {
+	if runtime.GOOS == "windows" {
+		return "ggml-" + name + ".dll"
+	}
+	return "libggml-" + name + ".so"
+}
+
+func loadBackends(opts BackendOptions) error {
+	if len(opts.Names) == 0 {
+		if opts.Dir == "" {
+			C.ggml_backend_load_all()
+			return nil
+		}
+		dir := C.CString(opts.Dir)
+		defer C.free(unsafe.Pointer(dir))
+		C.ggml_backend_load_all_from_path(dir)
+		return nil
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("backend directory: %w", err)
+		}
+		dir = filepath.Dir(exe)
+	}
+	for _, name := range opts.Names {
+		path := filepath.Join(dir, BackendLibrary(name))
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("backend %s: %w", name, err)
+		}
+		cpath := C.CString(path)
+		reg := C.ggml_backend_load(cpath)
+		C.free(unsafe.Pointer(cpath))
+		if reg == nil {
+			return fmt.Errorf("backend %s: cannot load %s", name, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+//go:build llama_dl
+
+package llamacppbindings
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <stdlib.h>
+
+int llamaDlOpen(const char *llama, const char *ggml, const char *ggmlBase, char *err, size_t errLen);
+*/
+import "C"
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Dynamic reports whether libllama is loaded at run time (llama_dl builds)
+// rather than linked.
+const Dynamic = true
+
+var (
+	loadMu   sync.Mutex
+	loadDone bool
+)
+
+// Load loads libllama from path, and libggml and libggml-base from the
+// same directory, and resolves every function the bindings call. An empty
+// path searches the system library path. It must be called before any
+// other function of the package; InitializeBackends calls it with an empty
+// path if it was not. Loading again is a no-op.
+func Load(path string) error {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	if loadDone {
+		return nil
+	}
+
+	llama, ggml, ggmlBase := libraryNames()
+	if path != "" {
+		dir := filepath.Dir(path)
+		llama, ggml, ggmlBase = path, filepath.Join(dir, ggml), filepath.Join(dir, ggmlBase)
+	}
+	cLlama, cGgml, cGgmlBase := C.CString(llama), C.CString(ggml), C.CString(ggmlBase)
+	defer C.free(unsafe.Pointer(cLlama))
+	defer C.free(unsafe.Pointer(cGgml))
+	defer C.free(unsafe.Pointer(cGgmlBase))
+
+	var errBuf [512]C.char
+	if C.llamaDlOpen(cLlama, cGgml, cGgmlBase, &errBuf[0], C.size_t(len(errBuf))) != 0 {
+		return fmt.Errorf("load llama.cpp: %s", C.GoString(&errBuf[0]))
+	}
+	loadDone = true
+	setLogCallback()
+	return nil
+}
+
+func ensureLoaded() error {
+	return Load("")
+}
+
+// libraryNames returns the file names of libllama, libggml and
+// libggml-base on this platform.
+func libraryNames() (llama, ggml, ggmlBase string) {
+	switch runtime.GOOS {
+	case "windows":
+		return "llama.dll", "ggml.dll", "ggml-base.dll"
+	case "darwin":
+		return "libllama.dylib", "libggml.dylib", "libggml-base.dylib"
+	default:
+		return "libllama.so", "libggml.so", "libggml-base.so"
+	}
+}
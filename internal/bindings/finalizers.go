@@ -0,0 +1,30 @@
+package llamacppbindings
+
+import "runtime"
+
+// Native objects are freed with Free. As a safety net against slow native
+// memory leaks in a long-running server, the Go objects that own one have a
+// finalizer that frees it if the object is garbage collected without Free,
+// and logs a warning naming the type so that the missing Free gets fixed.
+// Wrappers that do not own their native object, such as the samplers of a
+// chain, have none. An object must stay referenced while native code uses
+// it, e.g. a model while a context created from it exists; the wrappers
+// keep such references themselves.
+
+// freeUnreachable sets a finalizer on obj that warns and calls free. Free
+// methods clear it with runtime.SetFinalizer(obj, nil).
+func freeUnreachable[T any](obj *T, free func(*T)) {
+	runtime.SetFinalizer(obj, func(obj *T) {
+		warnUnfreed(obj)
+		free(obj)
+	})
+}
+
+func warnUnfreed(obj any) {
+	nativeLog.mx.Lock()
+	logger := nativeLog.global.logger
+	nativeLog.mx.Unlock()
+	if logger != nil {
+		logger.Warnf("%T was garbage collected without Free; freeing its native memory", obj)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !llama_dl
+
+package llamacppbindings
+
+/*
+// Updated for modern llama.cpp structure (ggml-base, ggml-cpu, etc.)
+// Note: Binary downloads on Windows only provide .dll files
+//       Run 'make prepare' to generate .dll.a import libraries
+// The CPU backend is linked in by llamacpp_cpu.go unless the ggml_dl build
+// tag is set, see BackendOptions.
+#cgo windows LDFLAGS: -l:libllama.dll.a -l:libggml.dll.a -l:libggml-base.dll.a
+#cgo linux LDFLAGS: -lllama -lggml -lggml-base
+#cgo darwin LDFLAGS: -lllama -lggml -lggml-base
+#cgo LDFLAGS: -lm -lstdc++
+#cgo linux LDFLAGS: -lgomp
+// macOS: libomp from Homebrew - search both Apple Silicon and Intel paths
+#cgo darwin LDFLAGS: -L/opt/homebrew/opt/libomp/lib -L/usr/local/opt/libomp/lib -lomp
+*/
+import "C"
+
+import "errors"
+
+// Dynamic reports whether libllama is loaded at run time (llama_dl builds)
+// rather than linked.
+const Dynamic = false
+
+func init() {
+	setLogCallback()
+}
+
+// Load does nothing as libllama is linked into this build; a path is an
+// error, since only llama_dl builds can load one.
+func Load(path string) error {
+	if path != "" {
+		return errors.New("libllama is linked into this build; rebuild with the llama_dl tag to load it at run time")
+	}
+	return nil
+}
+
+func ensureLoaded() error {
+	return nil
+}
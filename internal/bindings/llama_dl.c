@@ -0,0 +1,187 @@
+//go:build llama_dl
+
+// Definitions of the llama.cpp and GGML functions the bindings call, for
+// builds with the llama_dl tag, which do not link the libraries. Each one
+// calls through a pointer that llamaDlOpen resolves in the libraries it
+// loads.
+
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <stdio.h>
+#include "llama.h"
+#include "ggml.h"
+#include "gguf.h"
+#include "ggml-backend.h"
+
+#ifdef _WIN32
+#include <windows.h>
+typedef HMODULE dlHandle;
+static dlHandle dlOpen(const char *path) { return LoadLibraryA(path); }
+static void *dlSym(dlHandle h, const char *name) { return (void *)GetProcAddress(h, name); }
+static const char *dlError(void) { return "LoadLibrary failed"; }
+#else
+#include <dlfcn.h>
+typedef void *dlHandle;
+static dlHandle dlOpen(const char *path) { return dlopen(path, RTLD_NOW | RTLD_GLOBAL); }
+static void *dlSym(dlHandle h, const char *name) { return dlsym(h, name); }
+static const char *dlError(void) { return dlerror(); }
+#endif
+
+// Functions returning a value: F(return type, name, parameters, arguments).
+#define LLAMA_DL_FUNCS(F) \
+	F(const char *, ggml_version, (void), ()) \
+	F(const char *, ggml_commit, (void), ()) \
+	F(size_t, ggml_backend_dev_count, (void), ()) \
+	F(const char *, ggml_backend_dev_description, (ggml_backend_dev_t device), (device)) \
+	F(ggml_backend_dev_t, ggml_backend_dev_get, (size_t index), (index)) \
+	F(const char *, ggml_backend_dev_name, (ggml_backend_dev_t device), (device)) \
+	F(enum ggml_backend_dev_type, ggml_backend_dev_type, (ggml_backend_dev_t device), (device)) \
+	F(ggml_backend_reg_t, ggml_backend_load, (const char *path), (path)) \
+	F(size_t, ggml_backend_reg_count, (void), ()) \
+	F(ggml_backend_reg_t, ggml_backend_reg_get, (size_t index), (index)) \
+	F(const char *, ggml_backend_reg_name, (ggml_backend_reg_t reg), (reg)) \
+	F(int64_t, gguf_find_key, (const struct gguf_context *ctx, const char *key), (ctx, key)) \
+	F(const char *, gguf_get_val_str, (const struct gguf_context *ctx, int64_t key_id), (ctx, key_id)) \
+	F(struct gguf_context *, gguf_init_from_file, (const char *fname, struct gguf_init_params params), (fname, params)) \
+	F(struct llama_batch, llama_batch_get_one, (llama_token *tokens, int32_t n_tokens), (tokens, n_tokens)) \
+	F(struct llama_batch, llama_batch_init, (int32_t n_tokens, int32_t embd, int32_t n_seq_max), (n_tokens, embd, n_seq_max)) \
+	F(struct llama_context_params, llama_context_default_params, (void), ()) \
+	F(int32_t, llama_decode, (struct llama_context *ctx, struct llama_batch batch), (ctx, batch)) \
+	F(float *, llama_get_embeddings_seq, (struct llama_context *ctx, llama_seq_id seq_id), (ctx, seq_id)) \
+	F(float *, llama_get_logits_ith, (struct llama_context *ctx, int32_t i), (ctx, i)) \
+	F(llama_memory_t, llama_get_memory, (const struct llama_context *ctx), (ctx)) \
+	F(const struct llama_model *, llama_get_model, (const struct llama_context *ctx), (ctx)) \
+	F(struct llama_context *, llama_init_from_model, (struct llama_model *model, struct llama_context_params params), (model, params)) \
+	F(bool, llama_memory_can_shift, (llama_memory_t mem), (mem)) \
+	F(llama_pos, llama_memory_seq_pos_max, (llama_memory_t mem, llama_seq_id seq_id), (mem, seq_id)) \
+	F(llama_pos, llama_memory_seq_pos_min, (llama_memory_t mem, llama_seq_id seq_id), (mem, seq_id)) \
+	F(bool, llama_memory_seq_rm, (llama_memory_t mem, llama_seq_id seq_id, llama_pos p0, llama_pos p1), (mem, seq_id, p0, p1)) \
+	F(const char *, llama_model_cls_label, (const struct llama_model *model, uint32_t i), (model, i)) \
+	F(struct llama_model_params, llama_model_default_params, (void), ()) \
+	F(int32_t, llama_model_desc, (const struct llama_model *model, char *buf, size_t buf_size), (model, buf, buf_size)) \
+	F(const struct llama_vocab *, llama_model_get_vocab, (const struct llama_model *model), (model)) \
+	F(bool, llama_model_has_decoder, (const struct llama_model *model), (model)) \
+	F(bool, llama_model_has_encoder, (const struct llama_model *model), (model)) \
+	F(bool, llama_model_is_recurrent, (const struct llama_model *model), (model)) \
+	F(struct llama_model *, llama_model_load_from_file, (const char *path_model, struct llama_model_params params), (path_model, params)) \
+	F(uint32_t, llama_model_n_cls_out, (const struct llama_model *model), (model)) \
+	F(int32_t, llama_model_n_ctx_train, (const struct llama_model *model), (model)) \
+	F(int32_t, llama_model_n_embd, (const struct llama_model *model), (model)) \
+	F(int32_t, llama_model_n_head, (const struct llama_model *model), (model)) \
+	F(int32_t, llama_model_n_head_kv, (const struct llama_model *model), (model)) \
+	F(int32_t, llama_model_n_layer, (const struct llama_model *model), (model)) \
+	F(uint64_t, llama_model_n_params, (const struct llama_model *model), (model)) \
+	F(enum llama_rope_type, llama_model_rope_type, (const struct llama_model *model), (model)) \
+	F(uint64_t, llama_model_size, (const struct llama_model *model), (model)) \
+	F(uint32_t, llama_n_ctx, (const struct llama_context *ctx), (ctx)) \
+	F(uint32_t, llama_n_seq_max, (const struct llama_context *ctx), (ctx)) \
+	F(enum llama_pooling_type, llama_pooling_type, (const struct llama_context *ctx), (ctx)) \
+	F(const char *, llama_print_system_info, (void), ()) \
+	F(struct llama_sampler_chain_params, llama_sampler_chain_default_params, (void), ()) \
+	F(struct llama_sampler *, llama_sampler_chain_get, (const struct llama_sampler *chain, int32_t i), (chain, i)) \
+	F(struct llama_sampler *, llama_sampler_chain_init, (struct llama_sampler_chain_params params), (params)) \
+	F(int, llama_sampler_chain_n, (const struct llama_sampler *chain), (chain)) \
+	F(struct llama_sampler *, llama_sampler_clone, (const struct llama_sampler *smpl), (smpl)) \
+	F(struct llama_sampler *, llama_sampler_init_dist, (uint32_t seed), (seed)) \
+	F(struct llama_sampler *, llama_sampler_init_grammar, (const struct llama_vocab *vocab, const char *grammar_str, const char *grammar_root), (vocab, grammar_str, grammar_root)) \
+	F(struct llama_sampler *, llama_sampler_init_greedy, (void), ()) \
+	F(struct llama_sampler *, llama_sampler_init_min_p, (float p, size_t min_keep), (p, min_keep)) \
+	F(struct llama_sampler *, llama_sampler_init_penalties, (int32_t penalty_last_n, float penalty_repeat, float penalty_freq, float penalty_present), (penalty_last_n, penalty_repeat, penalty_freq, penalty_present)) \
+	F(struct llama_sampler *, llama_sampler_init_temp, (float t), (t)) \
+	F(struct llama_sampler *, llama_sampler_init_top_k, (int32_t k), (k)) \
+	F(struct llama_sampler *, llama_sampler_init_top_p, (float p, size_t min_keep), (p, min_keep)) \
+	F(const char *, llama_sampler_name, (const struct llama_sampler *smpl), (smpl)) \
+	F(llama_token, llama_sampler_sample, (struct llama_sampler *smpl, struct llama_context *ctx, int32_t idx), (smpl, ctx, idx)) \
+	F(int32_t, llama_token_to_piece, (const struct llama_vocab *vocab, llama_token token, char *buf, int32_t length, int32_t lstrip, bool special), (vocab, token, buf, length, lstrip, special)) \
+	F(int32_t, llama_tokenize, (const struct llama_vocab *vocab, const char *text, int32_t text_len, llama_token *tokens, int32_t n_tokens_max, bool add_special, bool parse_special), (vocab, text, text_len, tokens, n_tokens_max, add_special, parse_special)) \
+	F(llama_token, llama_vocab_bos, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_eos, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_eot, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_fim_mid, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_fim_pad, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_fim_pre, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_fim_rep, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_fim_sep, (const struct llama_vocab *vocab), (vocab)) \
+	F(llama_token, llama_vocab_fim_suf, (const struct llama_vocab *vocab), (vocab)) \
+	F(bool, llama_vocab_get_add_bos, (const struct llama_vocab *vocab), (vocab)) \
+	F(bool, llama_vocab_get_add_eos, (const struct llama_vocab *vocab), (vocab)) \
+	F(bool, llama_vocab_is_control, (const struct llama_vocab *vocab, llama_token token), (vocab, token)) \
+	F(bool, llama_vocab_is_eog, (const struct llama_vocab *vocab, llama_token token), (vocab, token)) \
+	F(int32_t, llama_vocab_n_tokens, (const struct llama_vocab *vocab), (vocab)) \
+	F(enum llama_vocab_type, llama_vocab_type, (const struct llama_vocab *vocab), (vocab))
+
+// Functions returning void, in the same form.
+#define LLAMA_DL_VOID_FUNCS(F) \
+	F(void, ggml_backend_dev_memory, (ggml_backend_dev_t device, size_t *free, size_t *total), (device, free, total)) \
+	F(void, ggml_backend_load_all, (void), ()) \
+	F(void, ggml_backend_load_all_from_path, (const char *dir_path), (dir_path)) \
+	F(void, gguf_free, (struct gguf_context *ctx), (ctx)) \
+	F(void, llama_backend_init, (void), ()) \
+	F(void, llama_batch_free, (struct llama_batch batch), (batch)) \
+	F(void, llama_free, (struct llama_context *ctx), (ctx)) \
+	F(void, llama_log_set, (ggml_log_callback log_callback, void *user_data), (log_callback, user_data)) \
+	F(void, llama_memory_clear, (llama_memory_t mem, bool data), (mem, data)) \
+	F(void, llama_memory_seq_add, (llama_memory_t mem, llama_seq_id seq_id, llama_pos p0, llama_pos p1, llama_pos delta), (mem, seq_id, p0, p1, delta)) \
+	F(void, llama_memory_seq_cp, (llama_memory_t mem, llama_seq_id seq_id_src, llama_seq_id seq_id_dst, llama_pos p0, llama_pos p1), (mem, seq_id_src, seq_id_dst, p0, p1)) \
+	F(void, llama_memory_seq_div, (llama_memory_t mem, llama_seq_id seq_id, llama_pos p0, llama_pos p1, int d), (mem, seq_id, p0, p1, d)) \
+	F(void, llama_memory_seq_keep, (llama_memory_t mem, llama_seq_id seq_id), (mem, seq_id)) \
+	F(void, llama_model_free, (struct llama_model *model), (model)) \
+	F(void, llama_sampler_accept, (struct llama_sampler *smpl, llama_token token), (smpl, token)) \
+	F(void, llama_sampler_chain_add, (struct llama_sampler *chain, struct llama_sampler *smpl), (chain, smpl)) \
+	F(void, llama_sampler_free, (struct llama_sampler *smpl), (smpl)) \
+	F(void, llama_sampler_reset, (struct llama_sampler *smpl), (smpl)) \
+	F(void, llama_synchronize, (struct llama_context *ctx), (ctx))
+
+#define LLAMA_DL_POINTER(ret, name, params, args) static ret (*dl_##name) params;
+#define LLAMA_DL_DEFINE(ret, name, params, args) ret name params { return dl_##name args; }
+#define LLAMA_DL_DEFINE_VOID(ret, name, params, args) ret name params { dl_##name args; }
+
+LLAMA_DL_FUNCS(LLAMA_DL_POINTER)
+LLAMA_DL_VOID_FUNCS(LLAMA_DL_POINTER)
+LLAMA_DL_FUNCS(LLAMA_DL_DEFINE)
+LLAMA_DL_VOID_FUNCS(LLAMA_DL_DEFINE_VOID)
+
+static dlHandle dlLibs[3];
+static int dlNLibs;
+
+static void *dlFind(const char *name) {
+	for (int i = 0; i < dlNLibs; i++) {
+		void *p = dlSym(dlLibs[i], name);
+		if (p != NULL) {
+			return p;
+		}
+	}
+	return NULL;
+}
+
+#define LLAMA_DL_RESOLVE(ret, name, params, args) \
+	dl_##name = (ret (*) params)dlFind(#name); \
+	if (dl_##name == NULL) { \
+		snprintf(err, errLen, "%s: function not found", #name); \
+		return -1; \
+	}
+
+// llamaDlOpen loads libllama, which is required, and libggml and
+// libggml-base, which are optional where libllama's dependencies are
+// searched too, and resolves every function. On failure it returns -1 with
+// a message in err.
+int llamaDlOpen(const char *llama, const char *ggml, const char *ggmlBase, char *err, size_t errLen) {
+	dlNLibs = 0;
+	dlHandle h = dlOpen(llama);
+	if (h == NULL) {
+		snprintf(err, errLen, "%s: %s", llama, dlError());
+		return -1;
+	}
+	dlLibs[dlNLibs++] = h;
+	if ((h = dlOpen(ggml)) != NULL) {
+		dlLibs[dlNLibs++] = h;
+	}
+	if ((h = dlOpen(ggmlBase)) != NULL) {
+		dlLibs[dlNLibs++] = h;
+	}
+
+	LLAMA_DL_FUNCS(LLAMA_DL_RESOLVE)
+	LLAMA_DL_VOID_FUNCS(LLAMA_DL_RESOLVE)
+	return 0;
+}
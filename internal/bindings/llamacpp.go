@@ -25,78 +25,177 @@ package llamacppbindings
 #cgo LDFLAGS: -L${SRCDIR}/../../build/llama-binaries/lib
 
 // === LINK LIBRARIES ===
-// Updated for modern llama.cpp structure (ggml-base, ggml-cpu, etc.)
-// Note: Binary downloads on Windows only provide .dll files
-//       Run 'make prepare' to generate .dll.a import libraries
-#cgo windows LDFLAGS: -l:libllama.dll.a -l:libggml.dll.a -l:libggml-base.dll.a -l:libggml-cpu.dll.a
-#cgo linux LDFLAGS: -lllama -lggml -lggml-base -lggml-cpu
-#cgo darwin LDFLAGS: -lllama -lggml -lggml-base -lggml-cpu
-#cgo LDFLAGS: -lm -lstdc++
-#cgo linux LDFLAGS: -lgomp
+// See link.go, or dl.go for builds with the llama_dl tag, which load
+// libllama at run time instead.
+
 // macOS: libomp from Homebrew - search both Apple Silicon and Intel paths
-#cgo darwin LDFLAGS: -L/opt/homebrew/opt/libomp/lib -L/usr/local/opt/libomp/lib -lomp
 #cgo darwin CPPFLAGS: -I/opt/homebrew/opt/libomp/include -I/usr/local/opt/libomp/include
 
 #include <stdlib.h>
+#include <stdint.h>
+#include <pthread.h>
 #include "llama.h"
 #include "gguf.h"
 #include "ggml-backend.h"
 extern bool llamaProgressCallback(float progress, void *user_data);
 extern void llamaLog(int level, char* text, void* user_data);
+
+static inline unsigned long long llamaThreadID(void) {
+	return (unsigned long long)(uintptr_t)pthread_self();
+}
+
+// llamaDecodeAndSample decodes batch and, if that succeeds, samples
+// tokens[i] with samplers[i] at batch position idxs[i].
+static int32_t llamaDecodeAndSample(struct llama_context *ctx, struct llama_batch batch,
+		struct llama_sampler **samplers, const int32_t *idxs, llama_token *tokens, int32_t n) {
+	int32_t result = llama_decode(ctx, batch);
+	if (result != 0) {
+		return result;
+	}
+	for (int32_t i = 0; i < n; i++) {
+		tokens[i] = llama_sampler_sample(samplers[i], ctx, idxs[i]);
+	}
+	return 0;
+}
 */
 import "C"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
 	"runtime/cgo"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/hypernetix/llamacpp_server/internal/logging"
 )
 
-func init() {
-	// Load all available GGML backends (CPU, CUDA, Metal, etc.)
-	// This is required in modern llama.cpp versions before loading models
-	C.ggml_backend_load_all()
-
-	// Set up logging callback
+// setLogCallback routes llama.cpp log output through llamaLog.
+func setLogCallback() {
 	C.llama_log_set(C.ggml_log_callback(C.llamaLog), nil)
 }
 
-var globalLogger logging.SprintfLogger
-
 //export llamaLog
 func llamaLog(level C.int, text *C.char, _ unsafe.Pointer) {
-	if globalLogger == nil {
-		return
-	}
+	tid := uint64(C.llamaThreadID())
 	switch int(level) {
 	case C.GGML_LOG_LEVEL_DEBUG:
-		globalLogger.Debugf(C.GoString(text))
+		nativeLog.write(tid, LogDebug, false, C.GoString(text))
 	case C.GGML_LOG_LEVEL_INFO:
-		globalLogger.Infof(C.GoString(text))
-	case C.GGML_LOG_LEVEL_ERROR:
-		globalLogger.Errorf(C.GoString(text))
+		nativeLog.write(tid, LogInfo, false, C.GoString(text))
 	case C.GGML_LOG_LEVEL_WARN:
-		globalLogger.Warnf(C.GoString(text))
+		nativeLog.write(tid, LogWarn, false, C.GoString(text))
+	case C.GGML_LOG_LEVEL_ERROR:
+		nativeLog.write(tid, LogError, false, C.GoString(text))
+	case C.GGML_LOG_LEVEL_CONT:
+		nativeLog.write(tid, LogInfo, true, C.GoString(text))
 	}
 }
 
 //export llamaProgressCallback
 func llamaProgressCallback(progress C.float, userData unsafe.Pointer) C.bool {
 	handle := *(*cgo.Handle)(userData)
-	callback := handle.Value().(func(float32))
-	callback(float32(progress))
-	return true
+	callback := handle.Value().(func(float32) bool)
+	return C.bool(callback(float32(progress)))
+}
+
+// WithNativeLogger runs fn with the llama.cpp output it causes on the calling
+// thread routed to logger, so native warnings carry the logger's context
+// (model, request ID). Output of llama.cpp's own worker threads still goes to
+// the logger passed to Initialize.
+func WithNativeLogger(logger logging.SprintfLogger, fn func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	tid := uint64(C.llamaThreadID())
+	sink, prev := nativeLog.enter(tid, logger, false)
+	defer nativeLog.exit(tid, sink, prev)
+	fn()
+}
+
+// Initialize loads all GGML backends found and routes llama.cpp log output
+// to logger; see SetNativeLogOptions. It panics if the llama.cpp library
+// is incompatible or, in llama_dl builds, cannot be loaded.
+func Initialize(logger logging.SprintfLogger) {
+	if err := InitializeBackends(logger, BackendOptions{}); err != nil {
+		panic(err)
+	}
 }
 
-func Initialize(logger logging.SprintfLogger) {
-	globalLogger = logger
-	C.ggml_backend_load_all()
+// InitializeBackends is Initialize loading the backends selected by opts.
+// It fails if one of them cannot be loaded, or with ErrIncompatible if the
+// llama.cpp library does not match the bindings. In llama_dl builds it
+// loads libllama first unless Load was called.
+func InitializeBackends(logger logging.SprintfLogger, opts BackendOptions) error {
+	nativeLog.setLogger(logger)
+	if err := ensureLoaded(); err != nil {
+		return err
+	}
+	if err := checkABI(); err != nil {
+		return err
+	}
+	if err := loadBackends(opts); err != nil {
+		return err
+	}
 	C.llama_backend_init()
+	caps := detectCapabilities()
+	capabilities.Store(&caps)
+	logCapabilities(logger, caps)
+	return nil
+}
+
+// SystemInfo returns llama_print_system_info(): the CPU features and build
+// options of the loaded backends, e.g. "CPU : SSE3 = 1 | AVX = 1 | ...".
+func SystemInfo() string {
+	return C.GoString(C.llama_print_system_info())
+}
+
+// Devices returns the compute devices of the registered GGML backends.
+func Devices() []Device {
+	n := int(C.ggml_backend_dev_count())
+	devices := make([]Device, 0, n)
+	for i := 0; i < n; i++ {
+		dev := C.ggml_backend_dev_get(C.size_t(i))
+		var free, total C.size_t
+		C.ggml_backend_dev_memory(dev, &free, &total)
+		devices = append(devices, Device{
+			Name:        C.GoString(C.ggml_backend_dev_name(dev)),
+			Description: C.GoString(C.ggml_backend_dev_description(dev)),
+			Type:        deviceTypeFromC(C.ggml_backend_dev_type(dev)),
+			MemoryFree:  uint64(free),
+			MemoryTotal: uint64(total),
+		})
+	}
+	return devices
+}
+
+func deviceTypeFromC(t C.enum_ggml_backend_dev_type) DeviceType {
+	switch t {
+	case C.GGML_BACKEND_DEVICE_TYPE_CPU:
+		return DeviceCPU
+	case C.GGML_BACKEND_DEVICE_TYPE_GPU:
+		return DeviceGPU
+	case C.GGML_BACKEND_DEVICE_TYPE_IGPU:
+		return DeviceIGPU
+	default:
+		return DeviceAccel
+	}
+}
+
+// Backends returns the names of the registered GGML backends, e.g. "CPU",
+// "CUDA" or "Metal".
+func Backends() []string {
+	n := int(C.ggml_backend_reg_count())
+	names := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		reg := C.ggml_backend_reg_get(C.size_t(i))
+		names = append(names, C.GoString(C.ggml_backend_reg_name(reg)))
+	}
+	return names
 }
 
 func GetModelArch(modelPath string) (string, error) {
@@ -132,11 +231,22 @@ type ModelParams struct {
 	impl              C.struct_llama_model_params
 	progressHandlePin *runtime.Pinner
 	tensorSplitPin    *runtime.Pinner
+	// load is shared with the progress callback, which must not refer to
+	// the params: its handle would keep them reachable.
+	load *modelLoad
+}
+
+// modelLoad is the state of a load in progress.
+type modelLoad struct {
+	aborted atomic.Bool
+	ctx     context.Context
 }
 
 func NewModelDefaultParams() *ModelParams {
 	impl := C.llama_model_default_params()
-	return &ModelParams{impl: impl}
+	p := &ModelParams{impl: impl, load: &modelLoad{}}
+	freeUnreachable(p, (*ModelParams).free)
+	return p
 }
 
 func (p *ModelParams) SetNGpuLayers(nGpuLayers int) {
@@ -182,7 +292,9 @@ func (p *ModelParams) SetTensorSplit(tensorSplit []float32) {
 	p.tensorSplitPin = &tensorSplitPin
 }
 
-func (p *ModelParams) SetProgressCallback(progress func(float32)) {
+// SetProgressCallback sets the model load progress callback. Returning false
+// from it aborts the load, and LoadModelFromFile then fails with ErrLoadAborted.
+func (p *ModelParams) SetProgressCallback(progress func(float32) bool) {
 	if progress == nil {
 		p.freeProgressHandle()
 		p.impl.progress_callback = nil
@@ -191,7 +303,15 @@ func (p *ModelParams) SetProgressCallback(progress func(float32)) {
 
 	p.impl.progress_callback = C.llama_progress_callback(C.llamaProgressCallback)
 
-	handle := cgo.NewHandle(progress)
+	load := p.load
+	callback := func(v float32) bool {
+		if (load.ctx != nil && load.ctx.Err() != nil) || !progress(v) {
+			load.aborted.Store(true)
+			return false
+		}
+		return true
+	}
+	handle := cgo.NewHandle(callback)
 	var handlePin runtime.Pinner
 	handlePin.Pin(&handle)
 	p.impl.progress_callback_user_data = unsafe.Pointer(&handle)
@@ -219,10 +339,19 @@ func (p *ModelParams) freeProgressHandle() {
 }
 
 func (p *ModelParams) Free() {
+	runtime.SetFinalizer(p, nil)
+	p.free()
+}
+
+func (p *ModelParams) free() {
 	p.freeProgressHandle()
 	p.freeTensorSplitPin()
 }
 
+// ErrLoadAborted is returned by LoadModelFromFile when the progress callback
+// aborted the load.
+var ErrLoadAborted = errors.New("model load aborted")
+
 type ModelInfo struct {
 	Desc        string
 	Size        uint64
@@ -230,45 +359,206 @@ type ModelInfo struct {
 	HasEncoder  bool
 	HasDecoder  bool
 	IsRecurrent bool
+	NCtxTrain   int // context size the model was trained with
+	NEmbd       int
+	NLayer      int
+	NHead       int
+	NHeadKV     int // fewer than NHead with grouped-query attention
+	RopeType    RopeType
+}
+
+// RopeType is the rotary position embedding a model uses.
+type RopeType int
+
+const (
+	RopeNone   RopeType = C.LLAMA_ROPE_TYPE_NONE // no RoPE, e.g. BERT
+	RopeNorm   RopeType = C.LLAMA_ROPE_TYPE_NORM
+	RopeNeox   RopeType = C.LLAMA_ROPE_TYPE_NEOX
+	RopeMrope  RopeType = C.LLAMA_ROPE_TYPE_MROPE
+	RopeVision RopeType = C.LLAMA_ROPE_TYPE_VISION
+)
+
+func (r RopeType) String() string {
+	switch r {
+	case RopeNone:
+		return "none"
+	case RopeNorm:
+		return "norm"
+	case RopeNeox:
+		return "neox"
+	case RopeMrope:
+		return "mrope"
+	case RopeVision:
+		return "vision"
+	default:
+		return "unknown"
+	}
 }
 
 type Model struct {
-	impl *C.struct_llama_model
+	impl    *C.struct_llama_model
+	loadLog []string
 }
 
 func LoadModelFromFile(modelPath string, params *ModelParams) (*Model, error) {
 	cModelPath := C.CString(modelPath)
 	defer C.free(unsafe.Pointer(cModelPath))
 
-	impl := C.llama_model_load_from_file(cModelPath, params.impl)
+	impl, loadLog := loadModelCapturingLog(cModelPath, params)
 	if impl == nil {
+		if params.load.aborted.Load() {
+			return nil, ErrLoadAborted
+		}
 		return nil, fmt.Errorf("unable to load model: %s", modelPath)
 	}
 
-	return &Model{impl: impl}, nil
+	m := &Model{impl: impl, loadLog: loadLog}
+	freeUnreachable(m, (*Model).free)
+	return m, nil
+}
+
+// loadModelCapturingLog loads a model, keeping the llama.cpp output of the
+// calling thread for Model.LoadLog.
+func loadModelCapturingLog(cModelPath *C.char, params *ModelParams) (*C.struct_llama_model, []string) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	tid := uint64(C.llamaThreadID())
+	sink, prev := nativeLog.enter(tid, nil, true)
+	impl := C.llama_model_load_from_file(cModelPath, params.impl)
+	nativeLog.exit(tid, sink, prev)
+	return impl, sink.capture.result()
+}
+
+// LoadModelFromFileContext is like LoadModelFromFile, but aborts the load via
+// the progress callback once ctx is done. The error then wraps both
+// ErrLoadAborted and ctx.Err().
+func LoadModelFromFileContext(ctx context.Context, modelPath string, params *ModelParams) (*Model, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadAborted, err)
+	}
+	if params.impl.progress_callback == nil {
+		params.SetProgressCallback(func(float32) bool { return true })
+	}
+	params.load.ctx = ctx
+	defer func() { params.load.ctx = nil }()
+
+	model, err := LoadModelFromFile(modelPath, params)
+	if errors.Is(err, ErrLoadAborted) && ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadAborted, ctx.Err())
+	}
+	return model, err
 }
 
+// Free frees the model. Freeing it again, or a nil model, does nothing.
 func (m *Model) Free() {
-	C.llama_model_free(m.impl)
+	if m == nil {
+		return
+	}
+	runtime.SetFinalizer(m, nil)
+	m.free()
+}
+
+func (m *Model) free() {
+	if m.impl != nil {
+		C.llama_model_free(m.impl)
+		m.impl = nil
+	}
+}
+
+// LoadLog returns the llama.cpp output captured while the model loaded, up
+// to NativeLogOptions.LoadLogLines lines.
+func (m *Model) LoadLog() []string {
+	return m.loadLog
+}
+
+// NEmbd returns the embedding dimension of the model.
+func (m *Model) NEmbd() int {
+	return int(C.llama_model_n_embd(m.impl))
+}
+
+// NCtxTrain returns the context size the model was trained with. Larger
+// contexts rely on RoPE scaling and usually degrade the output.
+func (m *Model) NCtxTrain() int {
+	return int(C.llama_model_n_ctx_train(m.impl))
+}
+
+// NLayer returns the number of layers of the model.
+func (m *Model) NLayer() int {
+	return int(C.llama_model_n_layer(m.impl))
+}
+
+// NHead returns the number of attention heads of the model.
+func (m *Model) NHead() int {
+	return int(C.llama_model_n_head(m.impl))
+}
+
+// NHeadKV returns the number of key-value heads of the model.
+func (m *Model) NHeadKV() int {
+	return int(C.llama_model_n_head_kv(m.impl))
+}
+
+// RopeType returns the rotary position embedding the model uses.
+func (m *Model) RopeType() RopeType {
+	return RopeType(C.llama_model_rope_type(m.impl))
+}
+
+// NClsOut returns the number of outputs of a classification head; 0 for
+// models without one.
+func (m *Model) NClsOut() int {
+	return int(C.llama_model_n_cls_out(m.impl))
+}
+
+// ClsLabel returns the label of classifier output i, or "" if the model does
+// not name it.
+func (m *Model) ClsLabel(i int) string {
+	label := C.llama_model_cls_label(m.impl, C.uint32_t(i))
+	if label == nil {
+		return ""
+	}
+	return C.GoString(label)
 }
 
 func (m *Model) Info() ModelInfo {
-	const bufLen = 1024
-	buf := make([]byte, bufLen)
-	descLen := int(C.llama_model_desc(m.impl, (*C.char)(unsafe.Pointer(&buf[0])), C.size_t(bufLen)))
+	var buf [128]byte
 	info := ModelInfo{
-		Desc:        string(buf[:descLen]),
+		Desc:        string(m.AppendDesc(buf[:0])),
 		Size:        uint64(C.llama_model_size(m.impl)),
 		NParams:     uint64(C.llama_model_n_params(m.impl)),
 		HasEncoder:  bool(C.llama_model_has_encoder(m.impl)),
 		HasDecoder:  bool(C.llama_model_has_decoder(m.impl)),
 		IsRecurrent: bool(C.llama_model_is_recurrent(m.impl)),
+		NCtxTrain:   m.NCtxTrain(),
+		NEmbd:       m.NEmbd(),
+		NLayer:      m.NLayer(),
+		NHead:       m.NHead(),
+		NHeadKV:     m.NHeadKV(),
+		RopeType:    m.RopeType(),
 	}
 	return info
 }
 
+// AppendDesc appends the description of the model, such as "llama 8B
+// Q4_K - Medium", to dst, growing it only if its spare capacity is too
+// small, and returns the extended slice.
+func (m *Model) AppendDesc(dst []byte) []byte {
+	dst = slices.Grow(dst, 64)
+	for {
+		spare := dst[len(dst):cap(dst)]
+		// Like snprintf, it returns the full length, which may not fit.
+		n := int(C.llama_model_desc(m.impl, (*C.char)(unsafe.Pointer(&spare[0])), C.size_t(len(spare))))
+		if n < 0 {
+			return dst
+		}
+		if n < len(spare) {
+			return dst[:len(dst)+n]
+		}
+		dst = slices.Grow(dst, n+1)
+	}
+}
+
 type Vocab struct {
-	impl *C.struct_llama_vocab
+	impl  *C.struct_llama_vocab
+	model *Model // owns impl
 }
 
 type VocabInfo struct {
@@ -276,7 +566,7 @@ type VocabInfo struct {
 }
 
 func (m *Model) Vocab() *Vocab {
-	return &Vocab{impl: C.llama_model_get_vocab(m.impl)}
+	return &Vocab{impl: C.llama_model_get_vocab(m.impl), model: m}
 }
 
 func (v *Vocab) Info() VocabInfo {
@@ -293,80 +583,193 @@ func (v *Vocab) AddBOS() bool {
 	return bool(C.llama_vocab_get_add_bos(v.impl))
 }
 
+// AddEOS reports whether the tokenizer appends EOS to tokenized text.
+func (v *Vocab) AddEOS() bool {
+	return bool(C.llama_vocab_get_add_eos(v.impl))
+}
+
 func (v *Vocab) IsEog(token int) bool {
 	return bool(C.llama_vocab_is_eog(v.impl, C.llama_token(token)))
 }
 
+// IsControl reports whether token is a control token such as <|im_end|>,
+// which TokenToPiece renders as its text.
+func (v *Vocab) IsControl(token int) bool {
+	return bool(C.llama_vocab_is_control(v.impl, C.llama_token(token)))
+}
+
+// Bos returns the beginning-of-sequence token, or -1 if there is none.
+func (v *Vocab) Bos() int {
+	return int(C.llama_vocab_bos(v.impl))
+}
+
+// Eos returns the end-of-sequence token, or -1 if there is none.
+func (v *Vocab) Eos() int {
+	return int(C.llama_vocab_eos(v.impl))
+}
+
+// Eot returns the end-of-turn token of chat models, or -1 if there is none.
+func (v *Vocab) Eot() int {
+	return int(C.llama_vocab_eot(v.impl))
+}
+
+// Fill-in-the-middle special tokens of code models. Each getter returns -1
+// (LLAMA_TOKEN_NULL) if the vocab does not define the token.
+
+// FimPre returns the token that starts the prefix of an infill prompt.
+func (v *Vocab) FimPre() int {
+	return int(C.llama_vocab_fim_pre(v.impl))
+}
+
+// FimSuf returns the token that starts the suffix of an infill prompt.
+func (v *Vocab) FimSuf() int {
+	return int(C.llama_vocab_fim_suf(v.impl))
+}
+
+// FimMid returns the token after which the model generates the middle.
+func (v *Vocab) FimMid() int {
+	return int(C.llama_vocab_fim_mid(v.impl))
+}
+
+// FimPad returns the FIM padding token.
+func (v *Vocab) FimPad() int {
+	return int(C.llama_vocab_fim_pad(v.impl))
+}
+
+// FimRep returns the token that starts a repository name in repo-level
+// infill prompts.
+func (v *Vocab) FimRep() int {
+	return int(C.llama_vocab_fim_rep(v.impl))
+}
+
+// FimSep returns the token that separates files in repo-level infill prompts.
+func (v *Vocab) FimSep() int {
+	return int(C.llama_vocab_fim_sep(v.impl))
+}
+
+// HasFim reports whether the vocab defines the prefix, suffix and middle
+// tokens needed to build an infill prompt.
+func (v *Vocab) HasFim() bool {
+	return v.FimPre() >= 0 && v.FimSuf() >= 0 && v.FimMid() >= 0
+}
+
+// Tokenize converts text to tokens. Invalid UTF-8 is replaced with U+FFFD;
+// text with NUL bytes fails with ErrInvalidText. addSpecial adds the BOS
+// and EOS tokens the model expects, parseSpecial tokenizes special token
+// text such as "<|im_end|>" as the special token. Text larger than
+// tokenizeChunkSize is tokenized in chunks split between words.
 func (v *Vocab) Tokenize(text string, addSpecial bool, parseSpecial bool) ([]int, error) {
-	maxTokens := len(text) + 2
-	cTokens := make([]C.llama_token, maxTokens)
-	cText := C.CString(text)
-	defer C.free(unsafe.Pointer(cText))
-
-	result := C.llama_tokenize(
-		v.impl,
-		cText,
-		C.int32_t(len(text)),
-		&cTokens[0],
-		C.int32_t(maxTokens),
-		C.bool(addSpecial),
-		C.bool(parseSpecial),
-	)
+	return v.AppendTokens(nil, text, addSpecial, parseSpecial)
+}
 
-	// if the result is negative, reallocate and retry with the correct buffer size
-	if result < 0 {
-		maxTokens = int(-result)
-		cTokens = make([]C.llama_token, maxTokens)
-		result = C.llama_tokenize(
+// AppendTokens is Tokenize appending the tokens to dst, so that a caller
+// tokenizing repeatedly can reuse one buffer. On error it returns nil.
+func (v *Vocab) AppendTokens(dst []int, text string, addSpecial bool, parseSpecial bool) ([]int, error) {
+	text, err := sanitizeText(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(text) <= tokenizeChunkSize {
+		return v.tokenize(dst, text, addSpecial, parseSpecial)
+	}
+
+	if addSpecial && v.AddBOS() {
+		dst = append(dst, v.Bos())
+	}
+	for _, chunk := range splitText(text, tokenizeChunkSize) {
+		if dst, err = v.tokenize(dst, chunk, false, parseSpecial); err != nil {
+			return nil, err
+		}
+	}
+	if addSpecial && v.AddEOS() {
+		dst = append(dst, v.Eos())
+	}
+	return dst, nil
+}
+
+// tokenBufs pools the llama_token buffers tokenize converts from.
+var tokenBufs = sync.Pool{New: func() any { return new([]C.llama_token) }}
+
+// emptyText stands in for the bytes of an empty string.
+var emptyText [1]C.char
+
+// tokenize appends the tokens of text to dst. llama_tokenize reads the text
+// in place, without a copy to C memory, and writes into a pooled buffer of
+// at least an estimate of about 3 bytes per token. If that is too small,
+// llama.cpp reports the exact count and the text is tokenized again into a
+// buffer of that size.
+func (v *Vocab) tokenize(dst []int, text string, addSpecial bool, parseSpecial bool) ([]int, error) {
+	cText := &emptyText[0]
+	if len(text) > 0 {
+		cText = (*C.char)(unsafe.Pointer(unsafe.StringData(text)))
+	}
+	run := func(cTokens []C.llama_token) int {
+		return int(C.llama_tokenize(
 			v.impl,
 			cText,
 			C.int32_t(len(text)),
 			&cTokens[0],
-			C.int32_t(maxTokens),
+			C.int32_t(len(cTokens)),
 			C.bool(addSpecial),
 			C.bool(parseSpecial),
-		)
-		if result < 0 {
-			return nil, fmt.Errorf("tokenization failed, required %d tokens", -result)
-		}
+		))
 	}
 
-	tokens := make([]int, result)
-	for i := range result {
-		tokens[i] = int(cTokens[i])
+	buf := tokenBufs.Get().(*[]C.llama_token)
+	defer tokenBufs.Put(buf)
+	if want := len(text)/3 + 8; cap(*buf) < want {
+		*buf = make([]C.llama_token, want)
+	}
+	cTokens := (*buf)[:cap(*buf)]
+	n := run(cTokens)
+	if n < 0 {
+		*buf = make([]C.llama_token, -n)
+		cTokens = *buf
+		if n = run(cTokens); n < 0 {
+			return nil, fmt.Errorf("tokenization failed, required %d tokens", -n)
+		}
 	}
 
-	return tokens, nil
+	dst = slices.Grow(dst, n)
+	for _, token := range cTokens[:n] {
+		dst = append(dst, int(token))
+	}
+	return dst, nil
 }
 
+// TokenToPiece returns the text of token. Control tokens are rendered as
+// their text too; use IsControl to tell them apart.
 func (v *Vocab) TokenToPiece(token int) (string, error) {
-	bufLen := 256
-	buf := make([]byte, bufLen)
-	bufLen = int(C.llama_token_to_piece(
-		v.impl,
-		C.int32_t(token),
-		(*C.char)(unsafe.Pointer(&buf[0])),
-		C.int32_t(bufLen),
-		C.int32_t(0),
-		C.bool(true),
-	))
+	var buf [64]byte
+	piece, err := v.AppendPiece(buf[:0], token)
+	return string(piece), err
+}
 
-	if bufLen < 0 {
-		bufLen = -bufLen
-		buf = make([]byte, bufLen)
-		if C.llama_token_to_piece(
+// AppendPiece appends the text of token to dst, growing it only if its
+// spare capacity is too small, and returns the extended slice.
+func (v *Vocab) AppendPiece(dst []byte, token int) ([]byte, error) {
+	if cap(dst)-len(dst) < 16 {
+		dst = slices.Grow(dst, 16)
+	}
+	for {
+		spare := dst[len(dst):cap(dst)]
+		n := int(C.llama_token_to_piece(
 			v.impl,
 			C.int32_t(token),
-			(*C.char)(unsafe.Pointer(&buf[0])),
-			C.int32_t(bufLen),
+			(*C.char)(unsafe.Pointer(&spare[0])),
+			C.int32_t(len(spare)),
 			C.int32_t(0),
 			C.bool(true),
-		) < 0 {
-			return "", fmt.Errorf("token to piece failed")
+		))
+		if n >= 0 {
+			return dst[:len(dst)+n], nil
+		}
+		if -n <= len(spare) {
+			return dst, fmt.Errorf("token to piece failed")
 		}
+		// A negative result is the size needed.
+		dst = slices.Grow(dst, -n)
 	}
-
-	return strings.TrimRight(string(buf), "\x00"), nil
 }
 
 type ContextParams struct {
@@ -422,6 +825,37 @@ func (p *ContextParams) SetEmbeddings(embeddings bool) {
 	p.impl.embeddings = C.bool(embeddings)
 }
 
+// SetNUbatch sets the physical batch size. Models with non-causal attention
+// need a whole sequence in one micro-batch, so embedding contexts set it to
+// n_batch.
+func (p *ContextParams) SetNUbatch(nUbatch int) {
+	p.impl.n_ubatch = C.uint32_t(nUbatch)
+}
+
+// PoolingType is how per-token embeddings are combined into a sequence
+// embedding.
+type PoolingType int
+
+const (
+	PoolingUnspecified PoolingType = C.LLAMA_POOLING_TYPE_UNSPECIFIED // use the model's
+	PoolingNone        PoolingType = C.LLAMA_POOLING_TYPE_NONE
+	PoolingMean        PoolingType = C.LLAMA_POOLING_TYPE_MEAN
+	PoolingCLS         PoolingType = C.LLAMA_POOLING_TYPE_CLS
+	PoolingLast        PoolingType = C.LLAMA_POOLING_TYPE_LAST
+	PoolingRank        PoolingType = C.LLAMA_POOLING_TYPE_RANK
+)
+
+func (p *ContextParams) SetPoolingType(pooling PoolingType) {
+	p.impl.pooling_type = C.enum_llama_pooling_type(pooling)
+}
+
+// SetKvUnified makes all sequences share one KV buffer, so that cells
+// copied between sequences with Memory.SeqCp are shared instead of
+// duplicated and n_ctx bounds the cells of all sequences together.
+func (p *ContextParams) SetKvUnified(unified bool) {
+	p.impl.kv_unified = C.bool(unified)
+}
+
 func (p *ContextParams) SetFlashAttention(flashAttention bool) {
 	// Note: In llama.cpp b6770+, flash_attn changed to flash_attn_type (enum)
 	// LLAMA_FLASH_ATTN_TYPE_DISABLED = 0, LLAMA_FLASH_ATTN_TYPE_ENABLED = 1
@@ -451,8 +885,19 @@ func kvCacheTypeFromStr(s string) C.enum_ggml_type {
 	}
 }
 
+// A Context holds the KV cache and the outputs of the last decode, so only
+// one goroutine may use it at a time: the engine goroutine that owns it.
+// Decode and DecodeAndSample enforce this and fail with ErrContextBusy when
+// another decode on the same context is in progress, rather than corrupting
+// its native state. The Model and Vocab behind a context are read-only once
+// loaded and safe for concurrent use, e.g. by several contexts.
 type Context struct {
-	impl *C.struct_llama_context
+	impl   *C.struct_llama_context
+	nVocab int
+	model  *Model // used by impl
+
+	// busy is held while a decode runs.
+	busy atomic.Bool
 }
 
 func NewContext(model *Model, params *ContextParams) (*Context, error) {
@@ -460,34 +905,77 @@ func NewContext(model *Model, params *ContextParams) (*Context, error) {
 	if impl == nil {
 		return nil, fmt.Errorf("unable to create context")
 	}
-	return &Context{impl: impl}, nil
+	nVocab := int(C.llama_vocab_n_tokens(C.llama_model_get_vocab(model.impl)))
+	c := &Context{impl: impl, nVocab: nVocab, model: model}
+	freeUnreachable(c, (*Context).free)
+	return c, nil
 }
 
+// Free frees the context. Freeing it again, or a nil context, does nothing.
 func (c *Context) Free() {
-	C.llama_free(c.impl)
+	if c == nil {
+		return
+	}
+	runtime.SetFinalizer(c, nil)
+	c.free()
+}
+
+func (c *Context) free() {
+	if c.impl != nil {
+		C.llama_free(c.impl)
+		c.impl = nil
+	}
 }
 
 func (c *Context) NCells() int {
 	return int(C.llama_n_ctx(c.impl))
 }
 
-// NCellsUsed returns an estimate of the number of used cells in the KV cache
-// for sequence 0. For multi-sequence contexts, use Memory().SeqPosMax() directly.
+// NSeqMax returns the maximum number of sequences of the context.
+func (c *Context) NSeqMax() int {
+	return int(C.llama_n_seq_max(c.impl))
+}
+
+// NCellsUsed returns the number of used cells in the KV cache, summed over
+// all sequences of the context. Each sequence is assumed to hold contiguous
+// positions, which stays true after SeqAdd shifts; cells shared between
+// sequences through SeqCp are counted once per sequence.
 func (c *Context) NCellsUsed() int {
 	mem := c.Memory()
 	if mem == nil {
 		return 0
 	}
-	maxPos := mem.SeqPosMax(0)
-	if maxPos < 0 {
-		return 0
+	used := 0
+	for seqId := 0; seqId < c.NSeqMax(); seqId++ {
+		used += mem.SeqLen(seqId)
 	}
-	return maxPos + 1
+	return used
 }
 
 var ErrKvCacheFull = errors.New("could not find a kv cache slot")
 
+// ErrContextBusy is returned by a decode on a context that another goroutine
+// is decoding on.
+var ErrContextBusy = errors.New("context is in use by another goroutine")
+
+// acquire takes the context for a decode; release gives it back.
+func (c *Context) acquire() error {
+	if !c.busy.CompareAndSwap(false, true) {
+		return ErrContextBusy
+	}
+	return nil
+}
+
+func (c *Context) release() {
+	c.busy.Store(false)
+}
+
 func (c *Context) Decode(batch *Batch) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+
 	// Positive return values does not mean a fatal error, but rather a warning.
 	//   0 - success
 	//   1 - could not find a KV slot for the batch (try reducing the size of the batch or increase the context)
@@ -505,12 +993,144 @@ func (c *Context) Decode(batch *Batch) error {
 	return nil
 }
 
+// SampleSet lists the samplers and batch positions to sample from after a
+// decode with DecodeAndSample. It can be reused for later decodes.
+type SampleSet struct {
+	samplers []*C.struct_llama_sampler
+	idxs     []C.int32_t
+	tokens   []C.llama_token
+}
+
+// Reset empties the set, keeping its memory.
+func (s *SampleSet) Reset() {
+	s.samplers = s.samplers[:0]
+	s.idxs = s.idxs[:0]
+	s.tokens = s.tokens[:0]
+}
+
+// Add appends a sample with sampler at batch position idx and returns its
+// index in the set.
+func (s *SampleSet) Add(sampler *Sampler, idx int) int {
+	s.samplers = append(s.samplers, sampler.impl)
+	s.idxs = append(s.idxs, C.int32_t(idx))
+	s.tokens = append(s.tokens, 0)
+	return len(s.samplers) - 1
+}
+
+// Len returns the number of samples in the set.
+func (s *SampleSet) Len() int {
+	return len(s.samplers)
+}
+
+// Token returns the token sampled for sample i by the last DecodeAndSample.
+func (s *SampleSet) Token(i int) int {
+	return int(s.tokens[i])
+}
+
+// DecodeAndSample is Decode followed by Sampler.Sample for each sample of
+// set, in one cgo call instead of one per call. Nothing is sampled if the
+// decode fails.
+func (c *Context) DecodeAndSample(batch *Batch, set *SampleSet) error {
+	if set.Len() == 0 {
+		return c.Decode(batch)
+	}
+	if err := c.acquire(); err != nil {
+		return err
+	}
+	defer c.release()
+	result := int(C.llamaDecodeAndSample(c.impl, batch.impl,
+		&set.samplers[0], &set.idxs[0], &set.tokens[0], C.int32_t(set.Len())))
+
+	if result < 0 {
+		return fmt.Errorf("failed to decode: %d", result)
+	}
+
+	if result > 0 {
+		return ErrKvCacheFull
+	}
+
+	return nil
+}
+
+// Synchronize waits until all computations of previous Decode calls have
+// finished; backends may run them asynchronously.
+func (c *Context) Synchronize() {
+	C.llama_synchronize(c.impl)
+}
+
+// PoolingType returns the pooling the context applies to embeddings.
+func (c *Context) PoolingType() PoolingType {
+	return PoolingType(C.llama_pooling_type(c.impl))
+}
+
+// ErrNoEmbeddings is returned by SeqEmbeddings for a sequence that was not in
+// the last batch, or by a context without pooling.
+var ErrNoEmbeddings = errors.New("no embeddings for sequence")
+
+// SeqEmbeddings returns the pooled embedding of sequence seqId from the last
+// Decode of a context created with SetEmbeddings(true). With PoolingRank it
+// holds the NClsOut classifier scores instead. The slice aliases llama.cpp
+// memory and is only valid until the next Decode or Free.
+func (c *Context) SeqEmbeddings(seqId int) ([]float32, error) {
+	embd := C.llama_get_embeddings_seq(c.impl, C.llama_seq_id(seqId))
+	if embd == nil {
+		return nil, fmt.Errorf("%w: %d", ErrNoEmbeddings, seqId)
+	}
+	n := int(C.llama_model_n_embd(C.llama_get_model(c.impl)))
+	if c.PoolingType() == PoolingRank {
+		n = int(C.llama_model_n_cls_out(C.llama_get_model(c.impl)))
+	}
+	return unsafe.Slice((*float32)(unsafe.Pointer(embd)), n), nil
+}
+
+// ErrNoLogits is returned by Logits for a batch position whose logits were
+// not requested (the token was added to the batch with logits=false).
+var ErrNoLogits = errors.New("no logits for batch position")
+
+// Logits returns the logits at batch position idx of the last Decode, one per
+// vocabulary token. Negative idx counts from the end (-1 is the last output).
+// The slice aliases llama.cpp memory and is only valid until the next Decode
+// or Free; copy it to keep it.
+func (c *Context) Logits(idx int) ([]float32, error) {
+	logits := C.llama_get_logits_ith(c.impl, C.int32_t(idx))
+	if logits == nil {
+		return nil, fmt.Errorf("%w: %d", ErrNoLogits, idx)
+	}
+	return unsafe.Slice((*float32)(unsafe.Pointer(logits)), c.nVocab), nil
+}
+
+// A Sampler is owned by its creator until it is added to a chain, which
+// then frees it. The samplers returned by a chain's Sampler and Get are
+// owned by the chain.
 type Sampler struct {
-	impl *C.struct_llama_sampler
+	impl  *C.struct_llama_sampler
+	owned bool   // freed by Free; false once in a chain
+	vocab *Vocab // used by grammar samplers
+}
+
+// newSampler wraps a sampler the caller owns.
+func newSampler(impl *C.struct_llama_sampler) *Sampler {
+	s := &Sampler{impl: impl, owned: true}
+	freeUnreachable(s, (*Sampler).free)
+	return s
 }
 
+// Free frees a sampler the caller owns. It does nothing for a sampler owned
+// by a chain, one already freed or a nil one.
 func (s *Sampler) Free() {
-	C.llama_sampler_free(s.impl)
+	if s == nil {
+		return
+	}
+	runtime.SetFinalizer(s, nil)
+	s.free()
+}
+
+func (s *Sampler) free() {
+	if s.owned {
+		C.llama_sampler_free(s.impl)
+		s.impl = nil
+		s.owned = false
+	}
 }
 
 func NewMinPSampler(minP float32, minKeep int) (*Sampler, error) {
@@ -518,7 +1138,7 @@ func NewMinPSampler(minP float32, minKeep int) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create min_p sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 func NewTempSampler(temp float32) (*Sampler, error) {
@@ -526,7 +1146,7 @@ func NewTempSampler(temp float32) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create temp sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // NewTopKSampler creates a top-k sampler
@@ -535,7 +1155,7 @@ func NewTopKSampler(k int) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create top_k sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // NewTopPSampler creates a top-p (nucleus) sampler
@@ -544,7 +1164,7 @@ func NewTopPSampler(p float32, minKeep int) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create top_p sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // NewPenaltiesSampler creates a penalties sampler for repetition control
@@ -558,7 +1178,7 @@ func NewPenaltiesSampler(penaltyLastN int, penaltyRepeat, penaltyFreq, penaltyPr
 	if impl == nil {
 		return nil, errors.New("unable to create penalties sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // NewDistSampler creates a distribution sampler for final token selection
@@ -567,7 +1187,7 @@ func NewDistSampler(seed uint32) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create dist sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // SafeNewTopKSampler creates a top-k sampler with fallback
@@ -583,7 +1203,7 @@ func SafeNewTopKSampler(k int) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create top_k sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // SafeNewTopPSampler creates a top-p sampler with fallback
@@ -598,7 +1218,7 @@ func SafeNewTopPSampler(p float32, minKeep int) (*Sampler, error) {
 	if impl == nil {
 		return nil, errors.New("unable to create top_p sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 // SafeNewPenaltiesSampler creates a penalties sampler with fallback
@@ -618,7 +1238,7 @@ func SafeNewPenaltiesSampler(penaltyLastN int, penaltyRepeat, penaltyFreq, penal
 	if impl == nil {
 		return nil, errors.New("unable to create penalties sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 func NewSeedSampler(seed uint32) (*Sampler, error) {
@@ -626,7 +1246,7 @@ func NewSeedSampler(seed uint32) (*Sampler, error) {
 	if impl == nil {
 		return nil, fmt.Errorf("unable to create seed sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 func NewGreedySampler() (*Sampler, error) {
@@ -634,7 +1254,7 @@ func NewGreedySampler() (*Sampler, error) {
 	if impl == nil {
 		return nil, fmt.Errorf("unable to create greedy sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	return newSampler(impl), nil
 }
 
 func NewGrammarSampler(vocab *Vocab, grammar string) (*Sampler, error) {
@@ -646,13 +1266,46 @@ func NewGrammarSampler(vocab *Vocab, grammar string) (*Sampler, error) {
 	if impl == nil {
 		return nil, fmt.Errorf("unable to create grammar sampler")
 	}
-	return &Sampler{impl: impl}, nil
+	s := newSampler(impl)
+	s.vocab = vocab
+	return s, nil
 }
 
+// Sample samples a token from the logits at idx and accepts it, so stateful
+// samplers (penalties, grammar) already account for the returned token.
 func (s *Sampler) Sample(context *Context, idx int) int {
 	return int(C.llama_sampler_sample(s.impl, context.impl, C.int32_t(idx)))
 }
 
+// Accept updates the sampler's state with a token that is part of the
+// sequence without having been sampled by it, e.g. a prompt token.
+func (s *Sampler) Accept(token int) {
+	C.llama_sampler_accept(s.impl, C.llama_token(token))
+}
+
+// Name returns the sampler's name, e.g. "top-k" or "chain".
+func (s *Sampler) Name() string {
+	return C.GoString(C.llama_sampler_name(s.impl))
+}
+
+// Reset returns the sampler to its initial state: penalties forget the
+// previous tokens, a grammar restarts and a seeded dist sampler reseeds.
+func (s *Sampler) Reset() {
+	C.llama_sampler_reset(s.impl)
+}
+
+// Clone returns an independent copy of the sampler, including its state.
+// The caller owns the copy and must Free it.
+func (s *Sampler) Clone() (*Sampler, error) {
+	impl := C.llama_sampler_clone(s.impl)
+	if impl == nil {
+		return nil, fmt.Errorf("unable to clone sampler %s", s.Name())
+	}
+	clone := newSampler(impl)
+	clone.vocab = s.vocab
+	return clone, nil
+}
+
 type SamplerChainParams struct {
 	impl C.struct_llama_sampler_chain_params
 }
@@ -663,7 +1316,8 @@ func NewSamplerChainDefaultParams() *SamplerChainParams {
 }
 
 type SamplerChain struct {
-	impl *C.struct_llama_sampler
+	impl   *C.struct_llama_sampler
+	vocabs []*Vocab // used by its grammar samplers
 }
 
 func NewSamplerChain(params *SamplerChainParams) (*SamplerChain, error) {
@@ -671,19 +1325,89 @@ func NewSamplerChain(params *SamplerChainParams) (*SamplerChain, error) {
 	if impl == nil {
 		return nil, fmt.Errorf("unable to create sampler chain")
 	}
-	return &SamplerChain{impl: impl}, nil
+	s := &SamplerChain{impl: impl}
+	freeUnreachable(s, (*SamplerChain).free)
+	return s, nil
 }
 
+// AddSampler appends sampler to the chain, which takes ownership of it:
+// it is freed with the chain, and its own Free does nothing from then on.
+// It panics if the caller does not own sampler, e.g. because it is already
+// in a chain, as two owners would free it twice.
 func (s *SamplerChain) AddSampler(sampler *Sampler) {
+	if !sampler.owned {
+		panic("llamacppbindings: AddSampler of a sampler owned by a chain or freed")
+	}
+	runtime.SetFinalizer(sampler, nil)
+	sampler.owned = false
+	if sampler.vocab != nil {
+		s.vocabs = append(s.vocabs, sampler.vocab)
+	}
 	C.llama_sampler_chain_add(s.impl, sampler.impl)
 }
 
+// Sampler returns the chain as a sampler, owned by the chain.
 func (s *SamplerChain) Sampler() *Sampler {
 	return &Sampler{impl: s.impl}
 }
 
+// N returns the number of samplers in the chain.
+func (s *SamplerChain) N() int {
+	return int(C.llama_sampler_chain_n(s.impl))
+}
+
+// Get returns the i-th sampler of the chain. It is owned by the chain, so
+// its Free does nothing.
+func (s *SamplerChain) Get(i int) *Sampler {
+	impl := C.llama_sampler_chain_get(s.impl, C.int32_t(i))
+	if impl == nil {
+		return nil
+	}
+	return &Sampler{impl: impl}
+}
+
+// Names returns the names of the samplers in the chain, in order.
+func (s *SamplerChain) Names() []string {
+	names := make([]string, s.N())
+	for i := range names {
+		names[i] = s.Get(i).Name()
+	}
+	return names
+}
+
+// Reset resets every sampler of the chain, so it can serve another request
+// with the same parameters.
+func (s *SamplerChain) Reset() {
+	C.llama_sampler_reset(s.impl)
+}
+
+// Clone returns an independent copy of the chain and its samplers.
+// The caller owns the copy and must Free it.
+func (s *SamplerChain) Clone() (*SamplerChain, error) {
+	impl := C.llama_sampler_clone(s.impl)
+	if impl == nil {
+		return nil, fmt.Errorf("unable to clone sampler chain")
+	}
+	clone := &SamplerChain{impl: impl, vocabs: slices.Clone(s.vocabs)}
+	freeUnreachable(clone, (*SamplerChain).free)
+	return clone, nil
+}
+
+// Free frees the chain and its samplers. Freeing it again, or a nil chain,
+// does nothing.
 func (s *SamplerChain) Free() {
-	C.llama_sampler_free(s.impl)
+	if s == nil {
+		return
+	}
+	runtime.SetFinalizer(s, nil)
+	s.free()
+}
+
+func (s *SamplerChain) free() {
+	if s.impl != nil {
+		C.llama_sampler_free(s.impl)
+		s.impl = nil
+	}
 }
 
 // =============================================================================
@@ -726,6 +1450,7 @@ func BatchInit(nTokens, embd, nSeqMax int) *Batch {
 func (b *Batch) Free() {
 	if b.owned {
 		C.llama_batch_free(b.impl)
+		b.owned = false
 	}
 }
 
@@ -861,7 +1586,8 @@ func (m *Memory) SeqKeep(seqId int) {
 }
 
 // SeqAdd adds relative position delta to all tokens that belong to the
-// specified sequence and have positions in [p0, p1).
+// specified sequence and have positions in [p0, p1), e.g. to shift the
+// context. p0 < 0: [0, p1]. p1 < 0: [p0, inf).
 func (m *Memory) SeqAdd(seqId, p0, p1, delta int) {
 	C.llama_memory_seq_add(
 		m.impl,
@@ -872,6 +1598,35 @@ func (m *Memory) SeqAdd(seqId, p0, p1, delta int) {
 	)
 }
 
+// SeqDiv divides the positions of all tokens that belong to the specified
+// sequence and have positions in [p0, p1) by d (integer division), as used
+// by self-extend. p0 < 0: [0, p1]. p1 < 0: [p0, inf).
+func (m *Memory) SeqDiv(seqId, p0, p1, d int) {
+	C.llama_memory_seq_div(
+		m.impl,
+		C.llama_seq_id(seqId),
+		C.llama_pos(p0),
+		C.llama_pos(p1),
+		C.int(d),
+	)
+}
+
+// CanShift reports whether the memory supports shifting positions with
+// SeqAdd and SeqDiv (recurrent models do not).
+func (m *Memory) CanShift() bool {
+	return bool(C.llama_memory_can_shift(m.impl))
+}
+
+// SeqLen returns the number of positions the specified sequence spans in
+// memory, from SeqPosMin to SeqPosMax. Returns 0 if the sequence is empty.
+func (m *Memory) SeqLen(seqId int) int {
+	maxPos := m.SeqPosMax(seqId)
+	if maxPos < 0 {
+		return 0
+	}
+	return maxPos - m.SeqPosMin(seqId) + 1
+}
+
 // SeqPosMin returns the smallest position present in memory for the
 // specified sequence. Returns -1 if the sequence is empty.
 func (m *Memory) SeqPosMin(seqId int) int {
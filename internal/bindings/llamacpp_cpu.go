@@ -0,0 +1,14 @@
+//go:build !ggml_dl && !llama_dl
+
+package llamacppbindings
+
+// Unless the ggml_dl or llama_dl build tag is set, the CPU backend is linked
+// into the binary. With either, every backend, the CPU one included, is a
+// library loaded at run time, so one build can ship several and pick them
+// with BackendOptions.
+
+/*
+#cgo windows LDFLAGS: -l:libggml-cpu.dll.a
+#cgo linux darwin LDFLAGS: -lggml-cpu
+*/
+import "C"
@@ -0,0 +1,345 @@
+package llamacppbindings
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+)
+
+// LogLevel is the severity of a llama.cpp log line.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+	// LogNone as a minimum level silences native logs.
+	LogNone
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	case LogNone:
+		return "none"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLogLevel parses debug, info, warn, error or none.
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LogDebug, nil
+	case "info":
+		return LogInfo, nil
+	case "warn", "warning":
+		return LogWarn, nil
+	case "error":
+		return LogError, nil
+	case "none", "off":
+		return LogNone, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn, error or none)", s)
+	}
+}
+
+// NativeLogOptions controls how llama.cpp log output is forwarded.
+type NativeLogOptions struct {
+	// MinLevel drops lines below this level.
+	MinLevel LogLevel
+	// Dedup collapses consecutive identical lines into a repeat count.
+	Dedup bool
+	// RateLimit caps forwarded lines per second; errors always pass.
+	// 0 means unlimited.
+	RateLimit int
+	// LoadLogLines is how many lines of a model's load output are kept for
+	// Model.LoadLog, regardless of MinLevel. 0 disables capturing.
+	LoadLogLines int
+}
+
+// DefaultNativeLogOptions forwards info and above, collapses repeats and
+// keeps the last 500 lines of each model load.
+func DefaultNativeLogOptions() NativeLogOptions {
+	return NativeLogOptions{
+		MinLevel:     LogInfo,
+		Dedup:        true,
+		RateLimit:    100,
+		LoadLogLines: 500,
+	}
+}
+
+// nativeLogger routes llama.cpp log output. Lines emitted on a thread that
+// entered a scope go to the scope's sink, everything else to the global one.
+type nativeLogger struct {
+	mx      sync.Mutex
+	opts    NativeLogOptions
+	global  *logSink
+	threads map[uint64]*logSink
+	spare   []*logSink // of ended scopes, reused by enter
+
+	// off is set while opts.MinLevel is LogNone, for NativeLogEnabled.
+	off atomic.Bool
+
+	// flush is armed while the global sink holds back a repeat count or
+	// rate limited lines, so they are reported even if no line follows.
+	flush *time.Timer
+}
+
+// logSink assembles log fragments into lines and forwards them, filtered,
+// deduplicated and rate limited, to a logger. A nil logger means the global
+// one.
+type logSink struct {
+	logger  logging.SprintfLogger
+	capture *logCapture
+
+	partial      strings.Builder
+	partialLevel LogLevel
+
+	lastLine  string
+	lastLevel LogLevel
+	repeats   int
+
+	windowStart time.Time
+	windowLines int
+	suppressed  int
+}
+
+var nativeLog = &nativeLogger{
+	opts:    DefaultNativeLogOptions(),
+	global:  &logSink{},
+	threads: make(map[uint64]*logSink),
+}
+
+// SetNativeLogOptions changes how llama.cpp log output is forwarded.
+func SetNativeLogOptions(opts NativeLogOptions) {
+	nativeLog.mx.Lock()
+	defer nativeLog.mx.Unlock()
+	nativeLog.flushLocked(nativeLog.global)
+	nativeLog.opts = opts
+	nativeLog.off.Store(opts.MinLevel >= LogNone)
+}
+
+// NativeLogEnabled tells whether any llama.cpp log output is forwarded, so
+// that hot paths can skip WithNativeLogger and the loggers it takes when
+// none is.
+func NativeLogEnabled() bool {
+	return !nativeLog.off.Load()
+}
+
+func (n *nativeLogger) setLogger(logger logging.SprintfLogger) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	n.global.logger = logger
+}
+
+// enter routes the output of thread tid to a new sink until exit is called
+// with it and the previous sink. Without a logger the sink inherits the one
+// of the scope it is nested in. capture keeps the lines for Model.LoadLog.
+func (n *nativeLogger) enter(tid uint64, logger logging.SprintfLogger, capture bool) (sink, prev *logSink) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	prev = n.threads[tid]
+	if logger == nil && prev != nil {
+		logger = prev.logger
+	}
+	if k := len(n.spare); k > 0 {
+		sink, n.spare = n.spare[k-1], n.spare[:k-1]
+	} else {
+		sink = &logSink{}
+	}
+	sink.logger = logger
+	if capture && n.opts.LoadLogLines > 0 {
+		sink.capture = &logCapture{max: n.opts.LoadLogLines}
+	}
+	n.threads[tid] = sink
+	return sink, prev
+}
+
+// exit ends the scope of sink on thread tid and restores prev.
+func (n *nativeLogger) exit(tid uint64, sink, prev *logSink) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if sink.partial.Len() > 0 {
+		n.lineLocked(sink, sink.partialLevel, sink.partial.String())
+		sink.partial.Reset()
+	}
+	n.flushLocked(sink)
+	if prev != nil {
+		n.threads[tid] = prev
+	} else {
+		delete(n.threads, tid)
+	}
+	// The lines of a capturing sink are read after exit.
+	if sink.capture == nil {
+		*sink = logSink{}
+		n.spare = append(n.spare, sink)
+	}
+}
+
+// write takes a fragment as passed to the llama.cpp log callback on thread
+// tid. cont marks a continuation of the previous fragment, which keeps its
+// level.
+func (n *nativeLogger) write(tid uint64, level LogLevel, cont bool, text string) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	sink, ok := n.threads[tid]
+	if !ok {
+		sink = n.global
+	}
+	if !cont && sink.partial.Len() > 0 {
+		n.lineLocked(sink, sink.partialLevel, sink.partial.String())
+		sink.partial.Reset()
+	}
+	if !cont || sink.partial.Len() == 0 {
+		sink.partialLevel = level
+	}
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			sink.partial.WriteString(text)
+			return
+		}
+		sink.partial.WriteString(text[:i])
+		n.lineLocked(sink, sink.partialLevel, sink.partial.String())
+		sink.partial.Reset()
+		text = text[i+1:]
+	}
+}
+
+func (n *nativeLogger) loggerLocked(sink *logSink) logging.SprintfLogger {
+	if sink.logger != nil {
+		return sink.logger
+	}
+	return n.global.logger
+}
+
+func (n *nativeLogger) lineLocked(sink *logSink, level LogLevel, line string) {
+	line = strings.TrimRight(line, " \r")
+	if line == "" {
+		return
+	}
+	if sink.capture != nil && level >= LogInfo {
+		sink.capture.add(line)
+	}
+	logger := n.loggerLocked(sink)
+	if logger == nil || level < n.opts.MinLevel {
+		return
+	}
+	if n.opts.Dedup && line == sink.lastLine && level == sink.lastLevel {
+		sink.repeats++
+		n.scheduleFlushLocked(sink)
+		return
+	}
+	n.flushRepeatsLocked(sink)
+
+	// Only forwarded lines count repeats; those of a suppressed line are
+	// suppressed as well.
+	if n.opts.RateLimit > 0 && level < LogError {
+		now := time.Now()
+		if now.Sub(sink.windowStart) >= time.Second {
+			n.flushSuppressedLocked(sink)
+			sink.windowStart, sink.windowLines = now, 0
+		}
+		if sink.windowLines >= n.opts.RateLimit {
+			sink.suppressed++
+			n.scheduleFlushLocked(sink)
+			return
+		}
+		sink.windowLines++
+	}
+	sink.lastLine, sink.lastLevel = line, level
+	emit(logger, level, line)
+}
+
+// scheduleFlushLocked arms the timer that flushes the global sink a second
+// later, when the rate limit window of the held back lines has ended. Scoped
+// sinks are flushed by exit.
+func (n *nativeLogger) scheduleFlushLocked(sink *logSink) {
+	if sink != n.global || n.flush != nil {
+		return
+	}
+	n.flush = time.AfterFunc(time.Second, n.flushGlobal)
+}
+
+func (n *nativeLogger) flushGlobal() {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	n.flush = nil
+	n.flushLocked(n.global)
+}
+
+// flushLocked reports the repeat count and the rate limited lines that sink
+// holds back.
+func (n *nativeLogger) flushLocked(sink *logSink) {
+	n.flushRepeatsLocked(sink)
+	n.flushSuppressedLocked(sink)
+}
+
+func (n *nativeLogger) flushRepeatsLocked(sink *logSink) {
+	if logger := n.loggerLocked(sink); sink.repeats > 0 && logger != nil {
+		emit(logger, sink.lastLevel, fmt.Sprintf("last message repeated %d times", sink.repeats))
+	}
+	sink.repeats = 0
+	sink.lastLine = ""
+}
+
+func (n *nativeLogger) flushSuppressedLocked(sink *logSink) {
+	if logger := n.loggerLocked(sink); sink.suppressed > 0 && logger != nil {
+		logger.Warnf("rate limit: suppressed %d llama.cpp log line(s)", sink.suppressed)
+	}
+	sink.suppressed = 0
+}
+
+func emit(logger logging.SprintfLogger, level LogLevel, line string) {
+	switch level {
+	case LogDebug:
+		logger.Debugf("%s", line)
+	case LogInfo:
+		logger.Infof("%s", line)
+	case LogWarn:
+		logger.Warnf("%s", line)
+	default:
+		logger.Errorf("%s", line)
+	}
+}
+
+// logCapture keeps the last max lines it was given.
+type logCapture struct {
+	max     int
+	lines   []string
+	dropped int
+}
+
+func (c *logCapture) add(line string) {
+	if len(c.lines) == c.max {
+		copy(c.lines, c.lines[1:])
+		c.lines = c.lines[:c.max-1]
+		c.dropped++
+	}
+	c.lines = append(c.lines, line)
+}
+
+// result returns the captured lines; nil for a nil capture.
+func (c *logCapture) result() []string {
+	if c == nil {
+		return nil
+	}
+	if c.dropped == 0 {
+		return c.lines
+	}
+	return append([]string{fmt.Sprintf("... %d earlier line(s) dropped", c.dropped)}, c.lines...)
+}
@@ -0,0 +1,165 @@
+package llamacppbindings
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordLogger keeps the lines logged to it, prefixed with their level.
+type recordLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordLogger) log(level, msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, level+" "+fmt.Sprintf(msg, args...))
+}
+
+func (l *recordLogger) recorded() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func (l *recordLogger) Level() string                          { return "debug" }
+func (l *recordLogger) Debugf(msg string, args ...interface{}) { l.log("debug", msg, args...) }
+func (l *recordLogger) Infof(msg string, args ...interface{})  { l.log("info", msg, args...) }
+func (l *recordLogger) Warnf(msg string, args ...interface{})  { l.log("warn", msg, args...) }
+func (l *recordLogger) Errorf(msg string, args ...interface{}) { l.log("error", msg, args...) }
+func (l *recordLogger) With(...interface{}) logging.SprintfLogger {
+	return l
+}
+
+// BenchmarkNativeLogScope measures the Go side of a WithNativeLogger scope
+// without output, as entered around every decode.
+func BenchmarkNativeLogScope(b *testing.B) {
+	n := &nativeLogger{opts: DefaultNativeLogOptions(), global: &logSink{}, threads: map[uint64]*logSink{}}
+	logger := &recordLogger{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink, prev := n.enter(1, logger, false)
+		n.exit(1, sink, prev)
+	}
+}
+
+// newTestNativeLogger returns a nativeLogger with opts whose global sink
+// logs to the returned recorder.
+func newTestNativeLogger(opts NativeLogOptions) (*nativeLogger, *recordLogger) {
+	global := &recordLogger{}
+	n := &nativeLogger{opts: opts, global: &logSink{logger: global}, threads: map[uint64]*logSink{}}
+	return n, global
+}
+
+func TestNativeLogAssemblesFragments(t *testing.T) {
+	n, global := newTestNativeLogger(NativeLogOptions{MinLevel: LogDebug})
+
+	// Continuations keep the level of the fragment they continue.
+	n.write(1, LogWarn, false, "load_tensors:")
+	n.write(1, LogInfo, true, " offloaded 33/33")
+	n.write(1, LogInfo, true, " layers\nnext ")
+	// A new fragment ends an unterminated line.
+	n.write(1, LogError, false, "failed\n")
+	// Blank lines are dropped.
+	n.write(1, LogInfo, false, " \r\n")
+	require.Equal(t, []string{
+		"warn load_tensors: offloaded 33/33 layers",
+		"warn next",
+		"error failed",
+	}, global.recorded())
+}
+
+func TestNativeLogCollapsesRepeats(t *testing.T) {
+	n, global := newTestNativeLogger(NativeLogOptions{MinLevel: LogInfo, Dedup: true})
+	for range 3 {
+		n.write(1, LogWarn, false, "kv cache full\n")
+	}
+	n.write(1, LogWarn, false, "other\n")
+	n.write(1, LogDebug, false, "filtered\n")
+	require.Equal(t, []string{
+		"warn kv cache full",
+		"warn last message repeated 2 times",
+		"warn other",
+	}, global.recorded())
+
+	n, global = newTestNativeLogger(NativeLogOptions{MinLevel: LogInfo})
+	for range 2 {
+		n.write(1, LogInfo, false, "line\n")
+	}
+	require.Equal(t, []string{"info line", "info line"}, global.recorded())
+}
+
+func TestNativeLogRateLimit(t *testing.T) {
+	n, global := newTestNativeLogger(NativeLogOptions{MinLevel: LogInfo, RateLimit: 2})
+	for i := range 5 {
+		n.write(1, LogInfo, false, fmt.Sprintf("line %d\n", i))
+	}
+	n.write(1, LogError, false, "errors pass\n")
+	require.Equal(t, []string{"info line 0", "info line 1", "error errors pass"}, global.recorded())
+
+	n.flushGlobal()
+	require.Equal(t, "warn rate limit: suppressed 3 llama.cpp log line(s)", global.recorded()[3])
+}
+
+func TestNativeLogFlushesGlobalSinkOnTimer(t *testing.T) {
+	n, global := newTestNativeLogger(NativeLogOptions{MinLevel: LogInfo, Dedup: true, RateLimit: 1})
+	n.write(1, LogInfo, false, "a\n")
+	n.write(1, LogInfo, false, "a\n")
+	n.write(1, LogInfo, false, "b\n")
+	n.write(1, LogInfo, false, "b\n")
+
+	// Nothing follows the held back lines; the timer reports them.
+	require.Eventually(t, func() bool { return len(global.recorded()) == 3 }, 3*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{
+		"info a",
+		"info last message repeated 1 times",
+		"warn rate limit: suppressed 2 llama.cpp log line(s)",
+	}, global.recorded())
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	require.Nil(t, n.flush)
+}
+
+func TestNativeLogScope(t *testing.T) {
+	n, global := newTestNativeLogger(NativeLogOptions{MinLevel: LogInfo, Dedup: true, RateLimit: 2})
+	scoped := &recordLogger{}
+	sink, prev := n.enter(1, scoped, false)
+	for _, line := range []string{"x", "x", "y", "z"} {
+		n.write(1, LogInfo, false, line+"\n")
+	}
+	n.write(2, LogInfo, false, "other thread\n")
+	n.write(1, LogInfo, false, "unterminated")
+
+	// Exit ends the pending line and reports what the scope held back.
+	n.exit(1, sink, prev)
+	require.Equal(t, []string{
+		"info x",
+		"info last message repeated 1 times",
+		"info y",
+		"warn rate limit: suppressed 2 llama.cpp log line(s)",
+	}, scoped.recorded())
+	require.Equal(t, []string{"info other thread"}, global.recorded())
+	require.Empty(t, n.threads)
+}
+
+func TestNativeLogCapture(t *testing.T) {
+	n, global := newTestNativeLogger(NativeLogOptions{MinLevel: LogError, LoadLogLines: 2})
+	sink, prev := n.enter(1, nil, true)
+	n.write(1, LogDebug, false, "not kept\n")
+	for _, line := range []string{"a", "b", "c"} {
+		n.write(1, LogInfo, false, line+"\n")
+	}
+	n.exit(1, sink, prev)
+
+	// Lines are captured regardless of MinLevel and the ring keeps the last.
+	require.Equal(t, []string{"... 1 earlier line(s) dropped", "b", "c"}, sink.capture.result())
+	require.Empty(t, global.recorded())
+	require.Empty(t, n.spare)
+}
@@ -0,0 +1,138 @@
+package llamacppbindings
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+)
+
+// DeviceType mirrors enum ggml_backend_dev_type.
+type DeviceType string
+
+const (
+	DeviceCPU   DeviceType = "cpu"
+	DeviceGPU   DeviceType = "gpu"
+	DeviceIGPU  DeviceType = "igpu"
+	DeviceAccel DeviceType = "accel"
+)
+
+// Device is a compute device of a GGML backend.
+type Device struct {
+	Name        string
+	Description string
+	Type        DeviceType
+	MemoryFree  uint64
+	MemoryTotal uint64
+}
+
+// Capabilities describes what the host and the llama.cpp build support.
+type Capabilities struct {
+	// CPUFeatures lists the features llama_print_system_info reports as
+	// enabled, e.g. "AVX2", "F16C", "NEON".
+	CPUFeatures []string
+	Devices     []Device
+	SystemInfo  string
+}
+
+// capabilities holds what Initialize detected.
+var capabilities atomic.Pointer[Capabilities]
+
+// DetectCapabilities returns the CPU features and devices detected by
+// Initialize, with the free device memory as of then. Before Initialize it
+// returns no capabilities.
+func DetectCapabilities() Capabilities {
+	if c := capabilities.Load(); c != nil {
+		return *c
+	}
+	return Capabilities{}
+}
+
+// detectCapabilities queries llama.cpp for CPU features and devices.
+func detectCapabilities() Capabilities {
+	info := SystemInfo()
+	return Capabilities{
+		CPUFeatures: parseSystemInfo(info),
+		Devices:     Devices(),
+		SystemInfo:  info,
+	}
+}
+
+// Has reports whether a CPU feature is enabled.
+func (c Capabilities) Has(feature string) bool {
+	for _, f := range c.CPUFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// FP16 reports hardware half-precision support: F16C on x86, FP16 vector
+// arithmetic on ARM.
+func (c Capabilities) FP16() bool {
+	return c.Has("F16C") || c.Has("FP16_VA")
+}
+
+// GPUs returns the discrete and integrated GPUs.
+func (c Capabilities) GPUs() []Device {
+	var gpus []Device
+	for _, d := range c.Devices {
+		if d.Type == DeviceGPU || d.Type == DeviceIGPU {
+			gpus = append(gpus, d)
+		}
+	}
+	return gpus
+}
+
+// parseSystemInfo extracts the enabled features from a system info string
+// such as "CPU : SSE3 = 1 | AVX = 1 | AVX512 = 0 | ". Build settings with
+// other values, e.g. "ARCHS = 500,610", are not features.
+func parseSystemInfo(info string) []string {
+	var features []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(info, "|") {
+		// Backend sections start with "NAME : ", keep only the feature part.
+		if i := strings.LastIndex(field, ":"); i >= 0 {
+			field = field[i+1:]
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value != "1" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		features = append(features, name)
+	}
+	return features
+}
+
+func logCapabilities(logger logging.SprintfLogger, c Capabilities) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	logger.Infof("CPU: AVX=%s AVX2=%s AVX512=%s FP16=%s",
+		yesNo(c.Has("AVX")), yesNo(c.Has("AVX2")), yesNo(c.Has("AVX512")), yesNo(c.FP16()))
+	logger.Debugf("System info: %s", c.SystemInfo)
+
+	gpus := c.GPUs()
+	if len(gpus) == 0 {
+		logger.Infof("GPU: none available, inference runs on the CPU")
+		return
+	}
+	for _, d := range gpus {
+		logger.Infof("GPU: %s (%s), %s free of %s", d.Name, d.Description,
+			formatMiB(d.MemoryFree), formatMiB(d.MemoryTotal))
+	}
+}
+
+func formatMiB(bytes uint64) string {
+	return fmt.Sprintf("%d MiB", bytes/(1024*1024))
+}
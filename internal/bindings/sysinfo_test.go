@@ -0,0 +1,69 @@
+package llamacppbindings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSystemInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want []string
+	}{
+		{
+			name: "x86 CPU",
+			info: "CPU : SSE3 = 1 | SSSE3 = 1 | AVX = 1 | AVX2 = 1 | F16C = 1 | FMA = 1 | BMI2 = 1 | AVX512 = 1 | AVX512_VBMI = 1 | AVX512_VNNI = 1 | LLAMAFILE = 1 | OPENMP = 1 | AARCH64_REPACK = 1 | ",
+			want: []string{"SSE3", "SSSE3", "AVX", "AVX2", "F16C", "FMA", "BMI2", "AVX512", "AVX512_VBMI", "AVX512_VNNI", "LLAMAFILE", "OPENMP", "AARCH64_REPACK"},
+		},
+		{
+			name: "CUDA and CPU",
+			info: "CUDA : ARCHS = 500,610,700,750,800 | USE_GRAPHS = 1 | PEER_MAX_BATCH_SIZE = 128 | CPU : SSE3 = 1 | SSSE3 = 1 | AVX = 1 | AVX2 = 1 | F16C = 1 | FMA = 1 | LLAMAFILE = 1 | OPENMP = 1 | AARCH64_REPACK = 1 | ",
+			want: []string{"USE_GRAPHS", "SSE3", "SSSE3", "AVX", "AVX2", "F16C", "FMA", "LLAMAFILE", "OPENMP", "AARCH64_REPACK"},
+		},
+		{
+			name: "Metal and ARM CPU",
+			info: "Metal : EMBED_LIBRARY = 1 | CPU : NEON = 1 | ARM_FMA = 1 | FP16_VA = 1 | MATMUL_INT8 = 1 | DOTPROD = 1 | ACCELERATE = 1 | REPACK = 1 | ",
+			want: []string{"EMBED_LIBRARY", "NEON", "ARM_FMA", "FP16_VA", "MATMUL_INT8", "DOTPROD", "ACCELERATE", "REPACK"},
+		},
+		{
+			name: "single section with disabled features",
+			info: "AVX = 1 | AVX_VNNI = 0 | AVX2 = 1 | AVX512 = 0 | AVX512_VBMI = 0 | AVX512_VNNI = 0 | AVX512_BF16 = 0 | FMA = 1 | NEON = 0 | SVE = 0 | ARM_FMA = 0 | F16C = 1 | FP16_VA = 0 | WASM_SIMD = 0 | BLAS = 0 | SSE3 = 1 | SSSE3 = 1 | VSX = 0 | MATMUL_INT8 = 0 | LLAMAFILE = 1 | ",
+			want: []string{"AVX", "AVX2", "FMA", "F16C", "SSE3", "SSSE3", "LLAMAFILE"},
+		},
+		{
+			name: "feature of several backends",
+			info: "CPU : AVX = 1 | OPENMP = 1 | CPU : AVX = 1 | ",
+			want: []string{"AVX", "OPENMP"},
+		},
+		{
+			name: "empty",
+			info: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, parseSystemInfo(tt.info))
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	x86 := Capabilities{CPUFeatures: []string{"AVX", "AVX2", "F16C"}}
+	require.True(t, x86.Has("AVX2"))
+	require.False(t, x86.Has("AVX512"))
+	require.True(t, x86.FP16())
+
+	arm := Capabilities{
+		CPUFeatures: []string{"NEON", "FP16_VA"},
+		Devices: []Device{
+			{Name: "CPU", Type: DeviceCPU},
+			{Name: "Metal", Type: DeviceIGPU},
+			{Name: "BLAS", Type: DeviceAccel},
+		},
+	}
+	require.True(t, arm.FP16())
+	require.Equal(t, []Device{{Name: "Metal", Type: DeviceIGPU}}, arm.GPUs())
+	require.False(t, Capabilities{CPUFeatures: []string{"AVX"}}.FP16())
+}
@@ -0,0 +1,56 @@
+package llamacppbindings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidText is returned by Tokenize for text llama.cpp cannot take,
+// such as text containing NUL bytes.
+var ErrInvalidText = errors.New("invalid text")
+
+// tokenizeChunkSize is the size above which text is tokenized in chunks, so
+// a huge prompt does not need one token buffer for all of it at once.
+const tokenizeChunkSize = 1 << 20
+
+// sanitizeText prepares text for the tokenizer: invalid UTF-8 sequences
+// become U+FFFD, as when the text is decoded from JSON, and NUL bytes are
+// rejected since llama.cpp would tokenize them inconsistently.
+func sanitizeText(text string) (string, error) {
+	if i := strings.IndexByte(text, 0); i >= 0 {
+		return "", fmt.Errorf("%w: NUL byte at offset %d", ErrInvalidText, i)
+	}
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "\uFFFD")
+	}
+	return text, nil
+}
+
+// splitText splits text into chunks of at most size bytes. A chunk ends
+// before a space that follows a non-space, where pre-tokenizers split words
+// anyway, so the chunks tokenize like the whole text. A chunk without such a
+// space is cut at a rune boundary.
+func splitText(text string, size int) []string {
+	var chunks []string
+	for len(text) > size {
+		cut := size
+		for cut > 0 && !(text[cut] == ' ' && !isSpace(text[cut-1])) {
+			cut--
+		}
+		if cut == 0 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	return append(chunks, text)
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
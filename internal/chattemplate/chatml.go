@@ -0,0 +1,103 @@
+// Package chattemplate renders chat messages as a raw prompt.
+//
+// ChatML is the most common format for instruction-tuned models (used by
+// SmolLM2-Instruct, Qwen, Mistral-Instruct, many others).
+package chattemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Message is one turn of a conversation. Role is "system", "user",
+// "assistant" or "tool".
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Vars are the variables llama.cpp's server passes to Jinja chat templates
+// besides the messages.
+type Vars struct {
+	// Tools are OpenAI tool definitions, listed in the system message in the
+	// Hermes format that Qwen and other ChatML models are trained on.
+	Tools []json.RawMessage
+	// DateString, if set, is stated in the system message.
+	DateString string
+	// SystemPrompt, if set, replaces the system messages of the request.
+	SystemPrompt *string
+}
+
+const toolsPrompt = `# Tools
+
+You may call one or more functions to assist with the user query.
+
+You are provided with function signatures within <tools></tools> XML tags:
+<tools>
+%s
+</tools>
+
+For each function call, return a json object with function name and arguments within <tool_call></tool_call> XML tags:
+<tool_call>
+{"name": <function-name>, "arguments": <args-json-object>}
+</tool_call>`
+
+// systemMessage returns the system message content for messages and vars,
+// or "" if there is none.
+func (vars Vars) systemMessage(messages []Message) string {
+	var parts []string
+	if vars.SystemPrompt != nil {
+		if *vars.SystemPrompt != "" {
+			parts = append(parts, *vars.SystemPrompt)
+		}
+	} else {
+		for _, msg := range messages {
+			if msg.Role == "system" {
+				parts = append(parts, msg.Content)
+			}
+		}
+	}
+	if len(parts) == 0 && len(vars.Tools) > 0 {
+		parts = append(parts, "You are a helpful assistant.")
+	}
+	if vars.DateString != "" {
+		parts = append(parts, "Current date: "+vars.DateString)
+	}
+	if len(vars.Tools) > 0 {
+		tools := make([]string, len(vars.Tools))
+		for i, t := range vars.Tools {
+			tools[i] = string(t)
+		}
+		parts = append(parts, fmt.Sprintf(toolsPrompt, strings.Join(tools, "\n")))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// ChatML renders messages as a ChatML prompt ending with an open assistant
+// turn. The system messages, merged with vars, come first; tool results are
+// sent as user turns wrapped in <tool_response> tags.
+func ChatML(messages []Message, vars Vars) string {
+	var sb strings.Builder
+	writeTurn := func(role, content string) {
+		sb.WriteString("<|im_start|>")
+		sb.WriteString(role)
+		sb.WriteString("\n")
+		sb.WriteString(content)
+		sb.WriteString("<|im_end|>\n")
+	}
+	if system := vars.systemMessage(messages); system != "" {
+		writeTurn("system", system)
+	}
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+		case "tool":
+			writeTurn("user", "<tool_response>\n"+msg.Content+"\n</tool_response>")
+		default:
+			writeTurn(msg.Role, msg.Content)
+		}
+	}
+	sb.WriteString("<|im_start|>assistant\n")
+	return sb.String()
+}
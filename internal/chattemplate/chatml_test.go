@@ -0,0 +1,38 @@
+package chattemplate
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatML(t *testing.T) {
+	prompt := ChatML([]Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello!"},
+		{Role: "tool", Content: `{"ok":true}`},
+	}, Vars{})
+	require.Equal(t, "<|im_start|>system\nBe brief.<|im_end|>\n"+
+		"<|im_start|>user\nHi<|im_end|>\n"+
+		"<|im_start|>assistant\nHello!<|im_end|>\n"+
+		"<|im_start|>user\n<tool_response>\n{\"ok\":true}\n</tool_response><|im_end|>\n"+
+		"<|im_start|>assistant\n", prompt)
+}
+
+func TestChatMLVars(t *testing.T) {
+	empty := ""
+	prompt := ChatML([]Message{
+		{Role: "system", Content: "ignored"},
+		{Role: "user", Content: "Hi"},
+	}, Vars{
+		Tools:        []json.RawMessage{json.RawMessage(`{"type":"function"}`)},
+		DateString:   "16 Oct 2026",
+		SystemPrompt: &empty,
+	})
+	require.True(t, strings.HasPrefix(prompt, "<|im_start|>system\nYou are a helpful assistant.\n\nCurrent date: 16 Oct 2026\n\n# Tools"))
+	require.Contains(t, prompt, "<tools>\n{\"type\":\"function\"}\n</tools>")
+	require.NotContains(t, prompt, "ignored")
+}
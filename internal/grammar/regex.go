@@ -0,0 +1,202 @@
+// Package grammar builds GBNF grammars for constrained generation.
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// ErrUnsupportedRegex is returned for valid regular expressions that have no
+// GBNF equivalent, like word boundaries.
+var ErrUnsupportedRegex = errors.New("unsupported regular expression")
+
+// FromRegex compiles a regular expression in Go (RE2) syntax into a GBNF
+// grammar whose language is the set of strings the expression matches in
+// full; the expression is implicitly anchored at both ends. ^ and $ are only
+// accepted at the start and end.
+func FromRegex(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	e, err := convert(trimAnchors(re))
+	if err != nil {
+		return "", err
+	}
+	return "root ::= " + e.text + "\n", nil
+}
+
+// expr is a GBNF expression. A term is one literal, character class or
+// parenthesized group, which a repetition operator applies to as a whole.
+type expr struct {
+	text string
+	term bool
+}
+
+func (e expr) grouped() string {
+	if e.term {
+		return e.text
+	}
+	return "(" + e.text + ")"
+}
+
+// trimAnchors drops a leading ^ and trailing $, which are implied.
+func trimAnchors(re *syntax.Regexp) *syntax.Regexp {
+	isBegin := func(r *syntax.Regexp) bool { return r.Op == syntax.OpBeginText || r.Op == syntax.OpBeginLine }
+	isEnd := func(r *syntax.Regexp) bool { return r.Op == syntax.OpEndText || r.Op == syntax.OpEndLine }
+	switch {
+	case isBegin(re) || isEnd(re):
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch}
+	case re.Op == syntax.OpConcat:
+		subs := re.Sub
+		if len(subs) > 0 && isBegin(subs[0]) {
+			subs = subs[1:]
+		}
+		if len(subs) > 0 && isEnd(subs[len(subs)-1]) {
+			subs = subs[:len(subs)-1]
+		}
+		return &syntax.Regexp{Op: syntax.OpConcat, Sub: subs}
+	}
+	return re
+}
+
+func convert(re *syntax.Regexp) (expr, error) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return expr{`""`, true}, nil
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase == 0 {
+			return expr{literal(re.Rune), true}, nil
+		}
+		parts := make([]string, len(re.Rune))
+		for i, r := range re.Rune {
+			parts[i] = charClass(foldRanges(r), false)
+		}
+		return expr{strings.Join(parts, " "), len(parts) == 1}, nil
+	case syntax.OpCharClass:
+		return expr{charClass(re.Rune, false), true}, nil
+	case syntax.OpAnyCharNotNL:
+		return expr{charClass([]rune{'\n', '\n'}, true), true}, nil
+	case syntax.OpAnyChar:
+		return expr{charClass([]rune{0, unicode.MaxRune}, false), true}, nil
+	case syntax.OpCapture:
+		return convert(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		sub, err := convert(re.Sub[0])
+		if err != nil {
+			return expr{}, err
+		}
+		return expr{sub.grouped() + repetition(re), false}, nil
+	case syntax.OpConcat:
+		if len(re.Sub) == 0 {
+			return expr{`""`, true}, nil
+		}
+		if len(re.Sub) == 1 {
+			return convert(re.Sub[0])
+		}
+		parts := make([]string, len(re.Sub))
+		for i, sub := range re.Sub {
+			e, err := convert(sub)
+			if err != nil {
+				return expr{}, err
+			}
+			parts[i] = e.text
+		}
+		return expr{strings.Join(parts, " "), false}, nil
+	case syntax.OpAlternate:
+		parts := make([]string, len(re.Sub))
+		for i, sub := range re.Sub {
+			e, err := convert(sub)
+			if err != nil {
+				return expr{}, err
+			}
+			parts[i] = e.text
+		}
+		return expr{"(" + strings.Join(parts, " | ") + ")", true}, nil
+	default:
+		return expr{}, fmt.Errorf("%w: %s", ErrUnsupportedRegex, re)
+	}
+}
+
+func repetition(re *syntax.Regexp) string {
+	switch {
+	case re.Op == syntax.OpStar:
+		return "*"
+	case re.Op == syntax.OpPlus:
+		return "+"
+	case re.Op == syntax.OpQuest:
+		return "?"
+	case re.Max == -1:
+		return fmt.Sprintf("{%d,}", re.Min)
+	case re.Min == re.Max:
+		return fmt.Sprintf("{%d}", re.Min)
+	default:
+		return fmt.Sprintf("{%d,%d}", re.Min, re.Max)
+	}
+}
+
+func literal(runes []rune) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range runes {
+		b.WriteString(escape(r, '"'))
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// charClass renders rune ranges (pairs of lo, hi) as a GBNF character class.
+func charClass(ranges []rune, negate bool) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if negate {
+		b.WriteByte('^')
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		b.WriteString(escape(lo, ']'))
+		if hi != lo {
+			b.WriteByte('-')
+			b.WriteString(escape(hi, ']'))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// foldRanges returns the case-folding orbit of r as single-rune ranges.
+func foldRanges(r rune) []rune {
+	ranges := []rune{r, r}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		ranges = append(ranges, f, f)
+	}
+	return ranges
+}
+
+// escape renders r for a GBNF literal or character class closed by delim.
+func escape(r rune, delim rune) string {
+	switch {
+	case r == delim || r == '\\' || (delim == ']' && r == '['):
+		return `\` + string(r)
+	case delim == ']' && (r == '^' || r == '-'):
+		// GBNF has no escapes for these.
+		return fmt.Sprintf(`\x%02X`, r)
+	case r == '\n':
+		return `\n`
+	case r == '\r':
+		return `\r`
+	case r == '\t':
+		return `\t`
+	case r < 0x20 || r == 0x7f:
+		return fmt.Sprintf(`\x%02X`, r)
+	case r > 0xFFFF:
+		return fmt.Sprintf(`\U%08X`, r)
+	case r >= 0x80 && !unicode.IsPrint(r):
+		return fmt.Sprintf(`\u%04X`, r)
+	default:
+		return string(r)
+	}
+}
@@ -0,0 +1,46 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{`yes|no`, `("yes" | "no")`},
+		{`^\d{3}-\d{4}$`, `[0-9]{3} "-" [0-9]{4}`},
+		{`[A-Z][a-z]+`, `[A-Z] [a-z]+`},
+		{`(ab)*c?`, `"ab"* "c"?`},
+		{`(ab|cd){2,}`, `("ab" | "cd"){2,}`},
+		{`x{1,3}`, `"x"{1,3}`},
+		{`.+`, `[^\n]+`},
+		{`[^"\\]`, `[\x00-!#-\[\]-\U0010FFFF]`},
+		{`[-^]`, `[\x2D\x5E]`},
+		{`"q"`, `"\"q\""`},
+		{`(?i)ok`, "[Oo] [Kk\u212A]"}, // U+212A is the Kelvin sign
+		{`a\nb`, `"a\nb"`},
+		{``, `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := FromRegex(tt.pattern)
+			require.NoError(t, err)
+			require.Equal(t, "root ::= "+tt.want+"\n", got)
+		})
+	}
+}
+
+func TestFromRegexErrors(t *testing.T) {
+	_, err := FromRegex(`a\b`)
+	require.ErrorIs(t, err, ErrUnsupportedRegex)
+
+	_, err = FromRegex(`a$b`)
+	require.ErrorIs(t, err, ErrUnsupportedRegex)
+
+	_, err = FromRegex(`(`)
+	require.Error(t, err)
+}
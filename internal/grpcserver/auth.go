@@ -0,0 +1,74 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/llmservice"
+
+	"google.golang.org/grpc"
+)
+
+// modelRequest is implemented by requests that run a model.
+type modelRequest interface{ GetModel() string }
+
+// pathRequest is implemented by requests that load or inspect a model.
+type pathRequest interface{ GetPath() string }
+
+// wrappedRequest is implemented by messages that carry a request, such as
+// the first message of a PredictUpload call.
+type wrappedRequest interface{ GetRequest() *proto.PredictRequest }
+
+// requestModel returns the model a request refers to, if any.
+func requestModel(req any) (string, bool) {
+	switch r := req.(type) {
+	case modelRequest:
+		return r.GetModel(), true
+	case pathRequest:
+		return r.GetPath(), true
+	case wrappedRequest:
+		if inner := r.GetRequest(); inner != nil {
+			return inner.GetModel(), true
+		}
+	}
+	return "", false
+}
+
+// checkAccess rejects req if the caller's API key may not use its model.
+func checkAccess(ctx context.Context, service *llmservice.Service, req any) error {
+	model, ok := requestModel(req)
+	if !ok {
+		return nil
+	}
+	return toStatus(service.CheckAccess(clientKey(ctx), model))
+}
+
+// AuthOptions returns the server options that enforce the model access
+// control list of service on every request naming a model.
+func AuthOptions(service *llmservice.Service) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := checkAccess(ctx, service, req); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &authStream{ServerStream: ss, service: service})
+		}),
+	}
+}
+
+// authStream checks each message a streaming handler receives, since the
+// request of a server-streaming call is only read inside the handler.
+type authStream struct {
+	grpc.ServerStream
+	service *llmservice.Service
+}
+
+func (s *authStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkAccess(s.Context(), s.service, m)
+}
@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/acl"
+	"github.com/hypernetix/llamacpp_server/internal/llmservice"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newAuthTestClient serves a mock backend whose private.gguf only the key
+// "allowed" may use, behind AuthOptions.
+func newAuthTestClient(t *testing.T) proto.LLMServerClient {
+	t.Helper()
+	list, err := acl.New([]acl.Rule{{Pattern: "private.gguf", Keys: []string{"allowed"}}})
+	require.NoError(t, err)
+	logger := logging.NewSprintfLogger()
+	service := llmservice.NewService(llmservice.Options{Backend: llmservice.BackendMock, ACL: list}, logger)
+	t.Cleanup(service.Stop)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer(AuthOptions(service)...)
+	proto.RegisterLLMServerServer(srv, NewServer(service, Options{}, logger))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewLLMServerClient(conn)
+}
+
+// upload sends a PredictUpload call for model with key and returns the
+// status code it ends with.
+func upload(t *testing.T, client proto.LLMServerClient, key, model string) codes.Code {
+	t.Helper()
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	stream, err := client.PredictUpload(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&proto.PredictUploadRequest{
+		Request: &proto.PredictRequest{Model: model, MaxTokens: 1},
+	}))
+	require.NoError(t, stream.Send(&proto.PredictUploadRequest{PromptChunk: []byte("hello")}))
+	require.NoError(t, stream.CloseSend())
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return status.Code(err)
+		}
+	}
+}
+
+func TestPredictUploadChecksAccess(t *testing.T) {
+	client := newAuthTestClient(t)
+
+	require.Equal(t, codes.PermissionDenied, upload(t, client, "other", "/models/private.gguf"))
+	require.Equal(t, codes.PermissionDenied, upload(t, client, "", "/models/private.gguf"))
+	require.NotEqual(t, codes.PermissionDenied, upload(t, client, "allowed", "/models/private.gguf"))
+	require.NotEqual(t, codes.PermissionDenied, upload(t, client, "other", "/models/public.gguf"))
+}
@@ -0,0 +1,81 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip" // registers the gzip compressor
+)
+
+// CompressionMode decides which responses are gzip-compressed. Compressed
+// requests are accepted in every mode.
+type CompressionMode int
+
+const (
+	// CompressionAuto compresses the responses of calls whose request was
+	// compressed, the default gRPC negotiation.
+	CompressionAuto CompressionMode = iota
+	// CompressionAlways compresses the responses of all calls from clients
+	// that accept gzip.
+	CompressionAlways
+	// CompressionOff never compresses responses.
+	CompressionOff
+)
+
+// ParseCompressionMode parses "auto", "always" or "off".
+func ParseCompressionMode(s string) (CompressionMode, error) {
+	switch strings.ToLower(s) {
+	case "auto":
+		return CompressionAuto, nil
+	case "always":
+		return CompressionAlways, nil
+	case "off":
+		return CompressionOff, nil
+	default:
+		return 0, fmt.Errorf("unknown compression mode %q (want auto, always or off)", s)
+	}
+}
+
+func (m CompressionMode) String() string {
+	switch m {
+	case CompressionAuto:
+		return "auto"
+	case CompressionAlways:
+		return "always"
+	case CompressionOff:
+		return "off"
+	default:
+		return "unknown"
+	}
+}
+
+// CompressionOptions returns the server options that apply mode.
+func CompressionOptions(mode CompressionMode) []grpc.ServerOption {
+	var compressor string
+	switch mode {
+	case CompressionAlways:
+		compressor = gzip.Name
+	case CompressionOff:
+		compressor = encoding.Identity
+	default:
+		return nil
+	}
+	// Fails if the client does not accept the compressor; the call then
+	// keeps the negotiated one.
+	setCompressor := func(ctx context.Context) {
+		_ = grpc.SetSendCompressor(ctx, compressor)
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			setCompressor(ctx)
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			setCompressor(ss.Context())
+			return handler(srv, ss)
+		}),
+	}
+}
@@ -0,0 +1,170 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/acl"
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/llmservice"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the ErrorInfo domain of errors raised by this server.
+const errorDomain = "llamacpp-server"
+
+// ErrorInfo reasons. Clients can branch on these instead of parsing messages.
+const (
+	ReasonModelNotFound   = "MODEL_NOT_FOUND"
+	ReasonModelLoading    = "MODEL_LOADING"
+	ReasonModelLoadFailed = "MODEL_LOAD_FAILED"
+	ReasonLoadCanceled    = "LOAD_CANCELED"
+	ReasonInvalidOption   = "INVALID_LOAD_OPTION"
+	ReasonNotLoading      = "MODEL_NOT_LOADING"
+	ReasonModelBusy       = "MODEL_BUSY"
+	ReasonContextExceeded = "CONTEXT_LENGTH_EXCEEDED"
+	ReasonTextTooShort    = "TEXT_TOO_SHORT"
+	ReasonEmptyInput      = "EMPTY_INPUT"
+	ReasonInvalidText     = "INVALID_TEXT"
+	ReasonWrongModelType  = "WRONG_MODEL_TYPE"
+	ReasonInvalidGrammar  = "INVALID_GRAMMAR"
+	ReasonBeamUnsupported = "BEAM_SEARCH_UNSUPPORTED"
+	ReasonOptionRejected  = "UNSUPPORTED_OPTION"
+	ReasonNoProjector     = "NO_PROJECTOR"
+	ReasonKvCacheFull     = "KV_CACHE_FULL"
+	ReasonQueueFull       = "QUEUE_FULL"
+	ReasonQuotaExceeded   = "QUOTA_EXCEEDED"
+	ReasonAccessDenied    = "MODEL_ACCESS_DENIED"
+	ReasonResultNotFound  = "RESULT_NOT_FOUND"
+	ReasonRejected        = "REQUEST_REJECTED"
+	ReasonShuttingDown    = "SHUTTING_DOWN"
+	ReasonInternal        = "INTERNAL"
+)
+
+// toStatus converts an internal error into a gRPC status error with an
+// ErrorInfo detail. Errors that already carry a status are returned as is.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var contextExceeded *inferenceengine.ContextExceededError
+	var quotaExceeded *quota.ExceededError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		return withErrorInfo(codes.NotFound, err, ReasonModelNotFound, nil)
+	case errors.Is(err, modelmanagement.ErrModelLoading):
+		return withErrorInfo(codes.Unavailable, err, ReasonModelLoading, nil)
+	case errors.Is(err, modelmanagement.ErrLoadCanceled):
+		return withErrorInfo(codes.Canceled, err, ReasonLoadCanceled, nil)
+	case errors.Is(err, modelmanagement.ErrModelNotLoading):
+		return withErrorInfo(codes.FailedPrecondition, err, ReasonNotLoading, nil)
+	case errors.Is(err, llmservice.ErrInvalidLoadOption):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonInvalidOption, nil)
+	case errors.Is(err, llmservice.ErrModelLoadFailed):
+		return withErrorInfo(codes.Internal, err, ReasonModelLoadFailed, nil)
+	case errors.Is(err, inferenceengine.ErrModelBusy):
+		return withErrorInfo(codes.Unavailable, err, ReasonModelBusy, nil)
+	case errors.As(err, &contextExceeded):
+		metadata := map[string]string{
+			"prompt_tokens": strconv.Itoa(contextExceeded.PromptTokens),
+			"slot_budget":   strconv.Itoa(contextExceeded.SlotBudget),
+		}
+		if contextExceeded.MaxTokens > 0 {
+			metadata["max_tokens"] = strconv.Itoa(contextExceeded.MaxTokens)
+		}
+		return withErrorInfo(codes.InvalidArgument, err, ReasonContextExceeded, metadata)
+	case errors.Is(err, inferenceengine.ErrNothingToScore):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonTextTooShort, nil)
+	case errors.Is(err, inferenceengine.ErrNothingToEmbed):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonEmptyInput, nil)
+	case errors.Is(err, llamacppbindings.ErrInvalidText):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonInvalidText, nil)
+	case errors.Is(err, inferenceengine.ErrInvalidGrammar):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonInvalidGrammar, nil)
+	case errors.Is(err, inferenceengine.ErrBeamUnsupported):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonBeamUnsupported, nil)
+	case errors.Is(err, inferenceengine.ErrUnsupportedOption):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonOptionRejected, nil)
+	case errors.Is(err, inferenceengine.ErrNotClassifier),
+		errors.Is(err, inferenceengine.ErrClassifierModel):
+		return withErrorInfo(codes.FailedPrecondition, err, ReasonWrongModelType, nil)
+	case errors.Is(err, inferenceengine.ErrNoProjector):
+		return withErrorInfo(codes.FailedPrecondition, err, ReasonNoProjector, nil)
+	case errors.Is(err, llamacppbindings.ErrKvCacheFull):
+		return withErrorInfo(codes.ResourceExhausted, err, ReasonKvCacheFull, nil)
+	case errors.Is(err, inferenceengine.ErrQueueFull):
+		return withErrorInfo(codes.ResourceExhausted, err, ReasonQueueFull, nil)
+	case errors.As(err, &quotaExceeded):
+		return quotaStatus(err, quotaExceeded)
+	case errors.Is(err, acl.ErrAccessDenied):
+		return withErrorInfo(codes.PermissionDenied, err, ReasonAccessDenied, nil)
+	case errors.Is(err, llmservice.ErrRequestRejected):
+		return withErrorInfo(codes.InvalidArgument, err, ReasonRejected, nil)
+	case errors.Is(err, inferenceengine.ErrEngineStopped),
+		errors.Is(err, modelmanagement.ErrModelManagerClosed):
+		return withErrorInfo(codes.Unavailable, err, ReasonShuttingDown, nil)
+	default:
+		return withErrorInfo(codes.Internal, err, ReasonInternal, nil)
+	}
+}
+
+// quotaStatus describes an exhausted token budget with an ErrorInfo, a
+// QuotaFailure and a RetryInfo that tells when the budget resets.
+func quotaStatus(err error, exceeded *quota.ExceededError) error {
+	st := status.New(codes.ResourceExhausted, err.Error())
+	detailed, detailErr := st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason: ReasonQuotaExceeded,
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"period":   exceeded.Period,
+				"limit":    strconv.FormatInt(exceeded.Limit, 10),
+				"used":     strconv.FormatInt(exceeded.Used, 10),
+				"reset_at": exceeded.ResetAt.Format(time.RFC3339),
+			},
+		},
+		&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{
+				Subject:     "client",
+				Description: err.Error(),
+			}},
+		},
+		&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(max(time.Until(exceeded.ResetAt), 0)),
+		},
+	)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func withErrorInfo(code codes.Code, err error, reason string, metadata map[string]string) error {
+	st := status.New(code, err.Error())
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: metadata,
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,111 @@
+package grpcserver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/acl"
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func errorInfo(t *testing.T, err error) (codes.Code, *errdetails.ErrorInfo) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return st.Code(), info
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return 0, nil
+}
+
+func TestToStatusMapsModelNotFound(t *testing.T) {
+	code, info := errorInfo(t, toStatus(fmt.Errorf("predict: %w", modelmanagement.ErrModelNotFound)))
+	require.Equal(t, codes.NotFound, code)
+	require.Equal(t, ReasonModelNotFound, info.Reason)
+	require.Equal(t, errorDomain, info.Domain)
+}
+
+func TestToStatusMapsContextExceeded(t *testing.T) {
+	err := &inferenceengine.ContextExceededError{PromptTokens: 5000, SlotBudget: 4096}
+	code, info := errorInfo(t, toStatus(err))
+	require.Equal(t, codes.InvalidArgument, code)
+	require.Equal(t, ReasonContextExceeded, info.Reason)
+	require.Equal(t, "5000", info.Metadata["prompt_tokens"])
+	require.Equal(t, "4096", info.Metadata["slot_budget"])
+}
+
+func TestToStatusMapsQueueFull(t *testing.T) {
+	code, info := errorInfo(t, toStatus(inferenceengine.ErrQueueFull))
+	require.Equal(t, codes.ResourceExhausted, code)
+	require.Equal(t, ReasonQueueFull, info.Reason)
+}
+
+func TestToStatusMapsQuotaExceeded(t *testing.T) {
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	err := fmt.Errorf("predict: %w", &quota.ExceededError{Period: "daily", Limit: 1000, Used: 1200, ResetAt: resetAt})
+	st := toStatus(err)
+	code, info := errorInfo(t, st)
+	require.Equal(t, codes.ResourceExhausted, code)
+	require.Equal(t, ReasonQuotaExceeded, info.Reason)
+	require.Equal(t, "daily", info.Metadata["period"])
+	require.Equal(t, "1000", info.Metadata["limit"])
+	require.Equal(t, "1200", info.Metadata["used"])
+	require.Equal(t, resetAt.Format(time.RFC3339), info.Metadata["reset_at"])
+
+	var failure *errdetails.QuotaFailure
+	var retry *errdetails.RetryInfo
+	for _, d := range status.Convert(st).Details() {
+		switch d := d.(type) {
+		case *errdetails.QuotaFailure:
+			failure = d
+		case *errdetails.RetryInfo:
+			retry = d
+		}
+	}
+	require.NotNil(t, failure)
+	require.Len(t, failure.Violations, 1)
+	require.NotNil(t, retry)
+	require.InDelta(t, time.Hour.Seconds(), retry.RetryDelay.AsDuration().Seconds(), 5)
+}
+
+func TestToStatusMapsInvalidText(t *testing.T) {
+	err := fmt.Errorf("tokenize: %w", llamacppbindings.ErrInvalidText)
+	code, info := errorInfo(t, toStatus(err))
+	require.Equal(t, codes.InvalidArgument, code)
+	require.Equal(t, ReasonInvalidText, info.Reason)
+}
+
+func TestToStatusMapsNoProjector(t *testing.T) {
+	code, info := errorInfo(t, toStatus(inferenceengine.ErrNoProjector))
+	require.Equal(t, codes.FailedPrecondition, code)
+	require.Equal(t, ReasonNoProjector, info.Reason)
+}
+
+func TestToStatusKeepsExistingStatus(t *testing.T) {
+	err := status.Error(codes.ResourceExhausted, "slow consumer")
+	require.Equal(t, err, toStatus(err))
+}
+
+func TestToStatusDefaultsToInternal(t *testing.T) {
+	code, info := errorInfo(t, toStatus(errors.New("boom")))
+	require.Equal(t, codes.Internal, code)
+	require.Equal(t, ReasonInternal, info.Reason)
+}
+
+func TestToStatusMapsAccessDenied(t *testing.T) {
+	code, info := errorInfo(t, toStatus(&acl.DeniedError{Model: "/m.gguf", Pattern: "*"}))
+	require.Equal(t, codes.PermissionDenied, code)
+	require.Equal(t, ReasonAccessDenied, info.Reason)
+}
@@ -0,0 +1,301 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/requestopts"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// errGenerationAbandoned aborts a keyed generation that no client re-attached
+// to within the re-attach window.
+var errGenerationAbandoned = errors.New("generation abandoned: no client re-attached")
+
+// idempotencyKey returns the "idempotency-key" metadata value, if any.
+func idempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get("idempotency-key"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// requestFingerprint identifies the contents of a Predict request so that a
+// key reused for a different request can be rejected.
+func requestFingerprint(req *proto.PredictRequest) [sha256.Size]byte {
+	data, _ := protobuf.MarshalOptions{Deterministic: true}.Marshal(req)
+	return sha256.Sum256(data)
+}
+
+// generation is a Predict call started with an idempotency key. Its responses
+// are recorded, so a client that retries after a disconnect replays what it
+// missed and then follows the live stream.
+type generation struct {
+	id          string
+	fingerprint [sha256.Size]byte
+	ctx         context.Context // canceled when the generation is abandoned
+	cancel      context.CancelFunc
+
+	mx          sync.Mutex
+	responses   []*proto.PredictResponse
+	changed     chan struct{} // closed and replaced whenever the state changes
+	done        bool
+	result      inferenceengine.Result
+	err         error
+	subscribers int
+	abandon     *time.Timer
+	abandoned   bool
+}
+
+func newGeneration(id string, fingerprint [sha256.Size]byte) *generation {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &generation{
+		id:          id,
+		fingerprint: fingerprint,
+		ctx:         ctx,
+		cancel:      cancel,
+		changed:     make(chan struct{}),
+	}
+}
+
+func (g *generation) notifyLocked() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// record appends a response; it fails once the generation was abandoned.
+func (g *generation) record(msg *proto.PredictResponse) error {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	if g.abandoned {
+		return errGenerationAbandoned
+	}
+	if msg != nil {
+		g.responses = append(g.responses, msg)
+		g.notifyLocked()
+	}
+	return nil
+}
+
+func (g *generation) finish(result inferenceengine.Result, err error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	g.done = true
+	g.result = result
+	g.err = err
+	if g.abandon != nil {
+		g.abandon.Stop()
+	}
+	g.notifyLocked()
+}
+
+// follow sends the recorded responses to a client, then the live ones as they
+// arrive, until the generation ends or the client goes away. Without stream
+// it only sends the final result. While the prompt is processed the latest
+// prefill progress is repeated every keepalive.
+func (g *generation) follow(ctx context.Context, send func(*proto.PredictResponse) error, stream bool, keepalive time.Duration) error {
+	next := 0
+	var lastPrefill *proto.PredictResponse
+	lastSent := time.Now()
+	for {
+		g.mx.Lock()
+		pending := g.responses[next:]
+		done, result, err, changed := g.done, g.result, g.err, g.changed
+		g.mx.Unlock()
+
+		if stream {
+			for _, msg := range pending {
+				if err := send(msg); err != nil {
+					return err
+				}
+				lastSent = time.Now()
+				if msg.PrefillProgress != nil {
+					lastPrefill = msg
+				} else {
+					lastPrefill = nil
+				}
+			}
+		}
+		next += len(pending)
+
+		if done {
+			if err != nil {
+				return toStatus(err)
+			}
+			if !stream {
+				msg := finalResponse(result)
+				msg.Message = []byte(result.Text)
+				return send(msg)
+			}
+			return nil
+		}
+
+		var timer *time.Timer
+		var keepaliveC <-chan time.Time
+		if stream && lastPrefill != nil && keepalive > 0 {
+			timer = time.NewTimer(keepalive - time.Since(lastSent))
+			keepaliveC = timer.C
+		}
+		var sendErr error
+		select {
+		case <-changed:
+		case <-keepaliveC:
+			sendErr = send(lastPrefill)
+			lastSent = time.Now()
+		case <-ctx.Done():
+			sendErr = status.FromContextError(ctx.Err()).Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if sendErr != nil {
+			return sendErr
+		}
+	}
+}
+
+// generationRegistry tracks running keyed generations.
+type generationRegistry struct {
+	window time.Duration
+
+	mx          sync.Mutex
+	generations map[string]*generation
+}
+
+func newGenerationRegistry(window time.Duration) *generationRegistry {
+	return &generationRegistry{
+		window:      window,
+		generations: make(map[string]*generation),
+	}
+}
+
+// attach subscribes to the running generation with the given id or, if there
+// is none, registers a new one. started reports whether the caller must run it.
+func (r *generationRegistry) attach(id string, fingerprint [sha256.Size]byte) (g *generation, started bool, err error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	g, ok := r.generations[id]
+	if !ok {
+		g = newGeneration(id, fingerprint)
+		r.generations[id] = g
+		started = true
+	} else if g.fingerprint != fingerprint {
+		return nil, false, status.Error(codes.InvalidArgument,
+			"idempotency key is already used by a different running request")
+	}
+
+	g.mx.Lock()
+	g.subscribers++
+	if g.abandon != nil {
+		g.abandon.Stop()
+		g.abandon = nil
+	}
+	g.mx.Unlock()
+	return g, started, nil
+}
+
+// detach unsubscribes a client. When the last client of a running generation
+// leaves, the generation is abandoned unless a client re-attaches in time.
+func (r *generationRegistry) detach(g *generation) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	g.subscribers--
+	if g.subscribers > 0 || g.done {
+		return
+	}
+	g.abandon = time.AfterFunc(r.window, func() {
+		g.mx.Lock()
+		if g.subscribers == 0 && !g.done {
+			g.abandoned = true
+			g.cancel()
+		}
+		g.mx.Unlock()
+	})
+}
+
+// remove forgets a finished generation; later requests with its key start anew.
+func (r *generationRegistry) remove(g *generation) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.generations[g.id] == g {
+		delete(r.generations, g.id)
+	}
+}
+
+// predictIdempotent serves a Predict call that carries an idempotency key.
+func (server *Server) predictIdempotent(key string, req *proto.PredictRequest, prompt string, args inferenceengine.PredictArgs, stream proto.LLMServer_PredictServer) error {
+	id := inferenceengine.TenantID(args.ClientKey) + "/" + key
+	g, started, err := server.generations.attach(id, requestFingerprint(req))
+	if err != nil {
+		return err
+	}
+	defer server.generations.detach(g)
+
+	if started {
+		go server.runGeneration(g, req, prompt, args)
+	} else {
+		server.logger.Infof("Predict: re-attached to running generation (idempotency key %q)", key)
+	}
+	return g.follow(stream.Context(), stream.Send, req.Stream, server.opts.Stream.PrefillKeepalive)
+}
+
+func (server *Server) runGeneration(g *generation, req *proto.PredictRequest, prompt string, args inferenceengine.PredictArgs) {
+	defer server.generations.remove(g)
+	defer g.cancel()
+
+	// Tokens and progress are only recorded for streaming requests, but the
+	// callbacks are always installed so an abandoned generation stops early.
+	args.PrefillProgress = func(processed, total int) error {
+		if !req.Stream {
+			return g.record(nil)
+		}
+		return g.record(prefillMessage(processed, total))
+	}
+	var streamFunc inferenceengine.StreamFunc = func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+		if !req.Stream {
+			return g.record(nil)
+		}
+		return g.record(chunkResponse(tokenIDs, promptTokens, completionTokens, message))
+	}
+	onLoad := func(progress modelmanagement.LoadProgress) {
+		if req.Stream {
+			_ = g.record(&proto.PredictResponse{LoadProgress: loadProgressToProto(progress)})
+		}
+	}
+	coalescer := requestopts.NewCoalescer(streamFunc, req.Options)
+	if req.Stream && coalescer.Enabled() {
+		streamFunc = coalescer.Stream
+	}
+	args.SpecialToken = specialTokenFunc(func(msg *proto.PredictResponse) error {
+		if !req.Stream {
+			return g.record(nil)
+		}
+		return g.record(msg)
+	}, coalescer)
+
+	result, err := server.service.Predict(g.ctx, req.Model, prompt, args, streamFunc, onLoad)
+	if err == nil && req.Stream && coalescer.Enabled() {
+		err = coalescer.Flush()
+	}
+	if err == nil && req.Stream {
+		err = g.record(finalResponse(result))
+	}
+	if err != nil {
+		server.logger.Errorf("Predict: keyed generation failed: %v", err)
+	}
+	g.finish(result, err)
+}
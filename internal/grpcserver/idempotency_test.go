@@ -0,0 +1,61 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReattachedClientReplaysEarlierResponses(t *testing.T) {
+	reg := newGenerationRegistry(time.Minute)
+	req := &proto.PredictRequest{Model: "m", Prompt: "p", Stream: true}
+
+	g, started, err := reg.attach("k", requestFingerprint(req))
+	require.NoError(t, err)
+	require.True(t, started)
+	require.NoError(t, g.record(&proto.PredictResponse{Message: []byte("a")}))
+	reg.detach(g)
+
+	again, started, err := reg.attach("k", requestFingerprint(req))
+	require.NoError(t, err)
+	require.False(t, started)
+	require.Same(t, g, again)
+
+	require.NoError(t, g.record(&proto.PredictResponse{Message: []byte("b")}))
+	g.finish(inferenceengine.Result{Text: "ab"}, nil)
+
+	var got []string
+	err = again.follow(context.Background(), func(msg *proto.PredictResponse) error {
+		got = append(got, string(msg.Message))
+		return nil
+	}, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, got)
+}
+
+func TestIdempotencyKeyReusedForDifferentRequest(t *testing.T) {
+	reg := newGenerationRegistry(time.Minute)
+	_, _, err := reg.attach("k", requestFingerprint(&proto.PredictRequest{Prompt: "one"}))
+	require.NoError(t, err)
+
+	_, _, err = reg.attach("k", requestFingerprint(&proto.PredictRequest{Prompt: "two"}))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAbandonedGenerationStops(t *testing.T) {
+	reg := newGenerationRegistry(0)
+	g, _, err := reg.attach("k", requestFingerprint(&proto.PredictRequest{}))
+	require.NoError(t, err)
+	reg.detach(g)
+
+	require.Eventually(t, func() bool {
+		return g.record(nil) == errGenerationAbandoned
+	}, time.Second, time.Millisecond)
+}
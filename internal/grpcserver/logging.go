@@ -0,0 +1,114 @@
+package grpcserver
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var requestDurationSeconds = metrics.NewHistogram("llamacpp_grpc_request_duration_seconds",
+	"Time from the start of a gRPC call until its handler returned.",
+	metrics.DefLatencyBuckets, "method", "code")
+
+// callLog collects what the log line of one call reports.
+type callLog struct {
+	method           string
+	peer             string
+	requestID        string
+	start            time.Time
+	promptTokens     int32
+	completionTokens int32
+}
+
+type promptTokenCounter interface{ GetPromptTokens() int32 }
+
+type completionTokenCounter interface{ GetCompletionTokens() int32 }
+
+// startCall begins the log of a call. If the client sent no request ID it
+// adds a new one to the incoming metadata, so that the handler reports the
+// same ID as the log line.
+func startCall(ctx context.Context, fullMethod string) (context.Context, *callLog) {
+	c := &callLog{method: path.Base(fullMethod), peer: "unknown", start: time.Now()}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		c.peer = p.Addr.String()
+	}
+	c.requestID = requestID(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Set("x-request-id", c.requestID)
+	return metadata.NewIncomingContext(ctx, md), c
+}
+
+// observe records the token counts of a response. Streamed responses carry
+// them in the last message, so counts of zero do not replace earlier ones.
+func (c *callLog) observe(msg any) {
+	if m, ok := msg.(promptTokenCounter); ok && m.GetPromptTokens() > 0 {
+		c.promptTokens = m.GetPromptTokens()
+	}
+	if m, ok := msg.(completionTokenCounter); ok && m.GetCompletionTokens() > 0 {
+		c.completionTokens = m.GetCompletionTokens()
+	}
+}
+
+func (c *callLog) finish(logger logging.SprintfLogger, err error) {
+	duration := time.Since(c.start)
+	code := status.Code(err)
+	requestDurationSeconds.ObserveWithExemplar(duration.Seconds(), metrics.Labels{"request_id": c.requestID}, c.method, code.String())
+
+	logf := logger.Infof
+	if err != nil {
+		logf = logger.Warnf
+	}
+	logf("%s: peer=%s, request_id=%s, code=%s, duration=%s, prompt_tokens=%d, completion_tokens=%d",
+		c.method, c.peer, c.requestID, code, duration.Round(time.Microsecond), c.promptTokens, c.completionTokens)
+}
+
+// LoggingOptions returns the server options that log one line per call,
+// with its status, duration and token counts, and record its duration in
+// the llamacpp_grpc_request_duration_seconds histogram, with the request ID
+// of the log line as exemplar.
+func LoggingOptions(logger logging.SprintfLogger) []grpc.ServerOption {
+	logger = logger.With("module", "grpcaccess")
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, c := startCall(ctx, info.FullMethod)
+			resp, err := handler(ctx, req)
+			if err == nil {
+				c.observe(resp)
+			}
+			c.finish(logger, err)
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, c := startCall(ss.Context(), info.FullMethod)
+			err := handler(srv, &loggingStream{ServerStream: ss, ctx: ctx, call: c})
+			c.finish(logger, err)
+			return err
+		}),
+	}
+}
+
+// loggingStream passes the request ID on to a streaming handler and
+// observes the messages it sends.
+type loggingStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	call *callLog
+}
+
+func (s *loggingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggingStream) SendMsg(m any) error {
+	s.call.observe(m)
+	return s.ServerStream.SendMsg(m)
+}
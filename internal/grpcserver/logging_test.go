@@ -0,0 +1,37 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestStartCallKeepsRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "abc"))
+	ctx, c := startCall(ctx, "/llmserver.LLMServer/Predict")
+	require.Equal(t, "Predict", c.method)
+	require.Equal(t, "abc", c.requestID)
+	require.Equal(t, "abc", requestID(ctx))
+
+	// A generated ID is handed on to the handler.
+	ctx, c = startCall(context.Background(), "/llmserver.LLMServer/Embed")
+	require.NotEmpty(t, c.requestID)
+	require.Equal(t, c.requestID, requestID(ctx))
+}
+
+func TestCallLogObserveKeepsFinalCounts(t *testing.T) {
+	c := &callLog{}
+	c.observe(&proto.PredictResponse{PromptTokens: 12, CompletionTokens: 5})
+	c.observe(&proto.PredictResponse{Message: []byte("trailing")})
+	require.Equal(t, int32(12), c.promptTokens)
+	require.Equal(t, int32(5), c.completionTokens)
+
+	c = &callLog{}
+	c.observe(&proto.EmbedResponse{PromptTokens: 7})
+	require.Equal(t, int32(7), c.promptTokens)
+	require.Zero(t, c.completionTokens)
+}
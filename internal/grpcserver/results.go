@@ -0,0 +1,147 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxStoredResults bounds the result store; past it the oldest finished
+// results are dropped before their TTL.
+const maxStoredResults = 10000
+
+var errResultNotFound = errors.New("no result with this request ID: unknown, expired or of another client")
+
+// storedResult is the outcome of a Predict call, kept for GetResult.
+type storedResult struct {
+	done    chan struct{} // closed when the generation finished
+	result  inferenceengine.Result
+	err     error
+	expires time.Time // set when done
+}
+
+// resultStore keeps the results of Predict calls for ResultTTL after they
+// finish, keyed by tenant and request ID, so that a client that lost its
+// stream can fetch the result with GetResult.
+type resultStore struct {
+	ttl time.Duration
+
+	mx       sync.Mutex
+	results  map[string]*storedResult
+	finished []string // ids of finished results, oldest first
+}
+
+func newResultStore(ttl time.Duration) *resultStore {
+	if ttl <= 0 {
+		return nil
+	}
+	return &resultStore{ttl: ttl, results: make(map[string]*storedResult)}
+}
+
+// start registers a running generation. It fails if a running generation
+// already uses id; a finished one with the same id is replaced.
+func (r *resultStore) start(id string) (*storedResult, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.pruneLocked(time.Now())
+	if old, ok := r.results[id]; ok && old.expires.IsZero() {
+		return nil, status.Error(codes.AlreadyExists, "the request ID is used by a running Predict call")
+	}
+	sr := &storedResult{done: make(chan struct{})}
+	r.results[id] = sr
+	return sr, nil
+}
+
+func (r *resultStore) finish(id string, sr *storedResult, result inferenceengine.Result, err error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	now := time.Now()
+	sr.result = result
+	sr.err = err
+	sr.expires = now.Add(r.ttl)
+	close(sr.done)
+	if r.results[id] == sr {
+		r.finished = append(r.finished, id)
+	}
+	r.pruneLocked(now)
+}
+
+// pruneLocked drops the expired results and, over maxStoredResults, the
+// oldest finished ones.
+func (r *resultStore) pruneLocked(now time.Time) {
+	for len(r.finished) > 0 {
+		id := r.finished[0]
+		sr, ok := r.results[id]
+		if ok && !sr.expires.IsZero() && now.Before(sr.expires) && len(r.results) <= maxStoredResults {
+			break
+		}
+		if ok && !sr.expires.IsZero() {
+			delete(r.results, id)
+		}
+		r.finished = r.finished[1:]
+	}
+}
+
+// wait returns the result with the given id, waiting for it to finish.
+func (r *resultStore) wait(ctx context.Context, id string) (*storedResult, error) {
+	r.mx.Lock()
+	r.pruneLocked(time.Now())
+	sr, ok := r.results[id]
+	r.mx.Unlock()
+	if !ok {
+		return nil, withErrorInfo(codes.NotFound, errResultNotFound, ReasonResultNotFound, nil)
+	}
+	select {
+	case <-sr.done:
+		return sr, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// detachable wraps the send function of a Predict stream so that, once the
+// client is gone, responses are dropped instead of failing the generation,
+// which then runs to completion for GetResult.
+func detachable(ctx context.Context, send func(*proto.PredictResponse) error) func(*proto.PredictResponse) error {
+	return func(msg *proto.PredictResponse) error {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := send(msg); err != nil && ctx.Err() == nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// GetResult returns the result of a Predict call of the same client, as the
+// single response of a non-streaming call.
+func (server *Server) GetResult(ctx context.Context, req *proto.GetResultRequest) (*proto.PredictResponse, error) {
+	if server.results == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the result store is disabled (see --result-ttl)")
+	}
+	if req.RequestId == "" {
+		return nil, status.Error(codes.InvalidArgument, "request_id is required")
+	}
+	sr, err := server.results.wait(ctx, resultID(clientKey(ctx), req.RequestId))
+	if err != nil {
+		return nil, err
+	}
+	if sr.err != nil {
+		return nil, toStatus(sr.err)
+	}
+	msg := finalResponse(sr.result)
+	msg.Message = []byte(sr.result.Text)
+	return msg, nil
+}
+
+func resultID(clientKey, requestID string) string {
+	return inferenceengine.TenantID(clientKey) + "/" + requestID
+}
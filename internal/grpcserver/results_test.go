@@ -0,0 +1,81 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestResultStoreWaitsForRunningGeneration(t *testing.T) {
+	store := newResultStore(time.Minute)
+	sr, err := store.start("t/r1")
+	require.NoError(t, err)
+
+	// A running request ID cannot be reused.
+	_, err = store.start("t/r1")
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	go store.finish("t/r1", sr, inferenceengine.Result{Text: "done"}, nil)
+	got, err := store.wait(context.Background(), "t/r1")
+	require.NoError(t, err)
+	require.Equal(t, "done", got.result.Text)
+
+	// Once finished, it can.
+	_, err = store.start("t/r1")
+	require.NoError(t, err)
+}
+
+func TestResultStoreNotFound(t *testing.T) {
+	store := newResultStore(time.Minute)
+	_, err := store.wait(context.Background(), "t/unknown")
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	sr, err := store.start("t/r1")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = store.wait(ctx, "t/r1")
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	store.finish("t/r1", sr, inferenceengine.Result{}, errors.New("boom"))
+}
+
+func TestResultStoreExpires(t *testing.T) {
+	store := newResultStore(time.Millisecond)
+	sr, err := store.start("t/r1")
+	require.NoError(t, err)
+	store.finish("t/r1", sr, inferenceengine.Result{Text: "done"}, nil)
+
+	require.Eventually(t, func() bool {
+		_, err := store.wait(context.Background(), "t/r1")
+		return status.Code(err) == codes.NotFound
+	}, time.Second, time.Millisecond)
+	require.Empty(t, store.results)
+}
+
+func TestResultStoreDisabled(t *testing.T) {
+	require.Nil(t, newResultStore(0))
+}
+
+func TestDetachableDropsResponsesAfterDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sendErr := errors.New("send failed")
+	sent := 0
+	send := detachable(ctx, func(*proto.PredictResponse) error {
+		sent++
+		return sendErr
+	})
+
+	// While the client is connected, send errors fail the generation.
+	require.ErrorIs(t, send(&proto.PredictResponse{}), sendErr)
+	cancel()
+	require.NoError(t, send(&proto.PredictResponse{}))
+	require.Equal(t, 1, sent)
+}
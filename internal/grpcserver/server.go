@@ -2,23 +2,60 @@ package grpcserver
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
 
 	"github.com/hypernetix/llamacpp_server/api/proto"
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/chattemplate"
 	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
 	"github.com/hypernetix/llamacpp_server/internal/llmservice"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/requestopts"
+	"github.com/hypernetix/llamacpp_server/internal/version"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+type Options struct {
+	Stream StreamOptions
+
+	// ReattachWindow is how long a Predict call started with an idempotency
+	// key keeps generating after its last client disconnected, waiting for a
+	// retry to re-attach. Zero cancels it as soon as the client is gone.
+	ReattachWindow time.Duration
+
+	// ResultTTL is how long the result of a Predict call is kept for
+	// GetResult after it finishes. While it is non-zero, a call without an
+	// idempotency key whose client disconnects keeps generating until done.
+	ResultTTL time.Duration
+
+	// MaxUploadBytes bounds the prompt of a PredictUpload call; 0 means no
+	// limit.
+	MaxUploadBytes int
+}
+
 type Server struct {
-	logger  logging.SprintfLogger
-	service *llmservice.Service
+	logger      logging.SprintfLogger
+	service     *llmservice.Service
+	opts        Options
+	generations *generationRegistry
+	results     *resultStore // nil if disabled
 	proto.UnimplementedLLMServerServer
 }
 
-func NewServer(service *llmservice.Service, logger logging.SprintfLogger) *Server {
+func NewServer(service *llmservice.Service, opts Options, logger logging.SprintfLogger) *Server {
 	return &Server{
-		service: service,
-		logger:  logger.With("module", "llamagrpcserver"),
+		service:     service,
+		opts:        opts,
+		generations: newGenerationRegistry(opts.ReattachWindow),
+		results:     newResultStore(opts.ResultTTL),
+		logger:      logger.With("module", "llamagrpcserver"),
 	}
 }
 
@@ -34,48 +71,203 @@ func (server *Server) Ping(ctx context.Context, pingRequest *proto.PingRequest)
 func (server *Server) LoadModel(loadModelRequest *proto.LoadModelRequest, stream proto.LLMServer_LoadModelServer) error {
 	server.logger.Debugf("LoadModel: %s", loadModelRequest.Path)
 
-	progressFunc := func(progress float32) {
-		msg := proto.LoadModelResponse{Progress: progress}
-		if err := stream.Send(&msg); err != nil {
+	progressFunc := func(progress modelmanagement.LoadProgress) {
+		if err := stream.Send(loadProgressToProto(progress)); err != nil {
 			server.logger.Errorf("LoadModel: stream Send failed: %v", err)
 		}
 	}
 
-	return server.service.LoadModel(loadModelRequest.Path, progressFunc)
+	if err := server.service.LoadModel(stream.Context(), loadModelRequest.Path, loadOverrides(loadModelRequest), progressFunc); err != nil {
+		server.logger.Errorf("LoadModel: failed: %v", err)
+		return toStatus(err)
+	}
+	return nil
+}
+
+func loadOverrides(req *proto.LoadModelRequest) modelmanagement.LoadOverrides {
+	var o modelmanagement.LoadOverrides
+	if req.NGpuLayers != nil {
+		v := int(*req.NGpuLayers)
+		o.NGpuLayers = &v
+	}
+	o.UseMmap = req.UseMmap
+	o.UseMlock = req.UseMlock
+	o.KvCacheType = req.GetKvCacheType()
+	o.CtxSize = int(req.GetCtxSize())
+	o.MaxBatchTokens = int(req.GetMaxBatchTokens())
+	o.MaxBatchSeqs = int(req.GetMaxBatchSeqs())
+	o.BatchWait = time.Duration(req.GetBatchWaitMs()) * time.Millisecond
+	return o
+}
+
+func loadProgressToProto(progress modelmanagement.LoadProgress) *proto.LoadModelResponse {
+	return &proto.LoadModelResponse{
+		Progress:    progress.Fraction,
+		Stage:       loadStageToProto(progress.Stage),
+		BytesLoaded: progress.BytesLoaded,
+		BytesTotal:  progress.BytesTotal,
+		EtaMs:       durationMs(progress.ETA),
+		Warnings:    loadWarningsToProto(progress.Warnings),
+	}
+}
+
+func loadWarningsToProto(warnings []modelmanagement.LoadWarning) []*proto.LoadWarning {
+	if len(warnings) == 0 {
+		return nil
+	}
+	msgs := make([]*proto.LoadWarning, len(warnings))
+	for i, w := range warnings {
+		msgs[i] = &proto.LoadWarning{Reason: w.Reason, Message: w.Message, Metadata: w.Metadata}
+	}
+	return msgs
+}
+
+func loadStageToProto(stage modelmanagement.LoadStage) proto.LoadStage {
+	switch stage {
+	case modelmanagement.LoadStageReading:
+		return proto.LoadStage_LOAD_STAGE_READING
+	case modelmanagement.LoadStageLoading:
+		return proto.LoadStage_LOAD_STAGE_LOADING
+	case modelmanagement.LoadStageReady:
+		return proto.LoadStage_LOAD_STAGE_READY
+	default:
+		return proto.LoadStage_LOAD_STAGE_UNSPECIFIED
+	}
+}
+
+func (server *Server) CancelLoad(ctx context.Context, req *proto.CancelLoadRequest) (*proto.CancelLoadResponse, error) {
+	server.logger.Infof("CancelLoad: %s", req.Path)
+	if err := server.service.CancelLoad(req.Path); err != nil {
+		return nil, toStatus(err)
+	}
+	return &proto.CancelLoadResponse{}, nil
+}
+
+func (server *Server) GetLoadLog(ctx context.Context, req *proto.GetLoadLogRequest) (*proto.GetLoadLogResponse, error) {
+	lines, err := server.service.LoadLog(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &proto.GetLoadLogResponse{Lines: lines}, nil
+}
+
+func (server *Server) GetModelInfo(ctx context.Context, req *proto.GetModelInfoRequest) (*proto.GetModelInfoResponse, error) {
+	info, err := server.service.ModelInfo(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &proto.GetModelInfoResponse{
+		Description: info.Desc,
+		Size:        info.Size,
+		NParams:     info.NParams,
+		AddBos:      info.AddBOS,
+		Bos:         specialTokenToProto(info.Bos),
+		Eos:         specialTokenToProto(info.Eos),
+		Eot:         specialTokenToProto(info.Eot),
+		NCtxTrain:   int32(info.NCtxTrain),
+		NEmbd:       int32(info.NEmbd),
+		NLayer:      int32(info.NLayer),
+		NHead:       int32(info.NHead),
+		RopeType:    info.RopeType.String(),
+	}, nil
+}
+
+func specialTokenToProto(t *llmservice.SpecialToken) *proto.SpecialToken {
+	if t == nil {
+		return nil
+	}
+	return &proto.SpecialToken{Id: int32(t.ID), Piece: t.Piece}
+}
+
+// predictPrompt returns the prompt of req: the raw prompt, or its messages
+// rendered with the chat template.
+func predictPrompt(req *proto.PredictRequest) (string, error) {
+	if len(req.Messages) == 0 {
+		return req.Prompt, nil
+	}
+	if req.Prompt != "" {
+		return "", status.Error(codes.InvalidArgument, "prompt and messages are mutually exclusive")
+	}
+	messages := make([]chattemplate.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = chattemplate.Message{Role: msg.Role, Content: msg.Content}
+	}
+	return chattemplate.ChatML(messages, chattemplate.Vars{}), nil
+}
+
+// validateImages rejects images without data or with a MIME type that is
+// not an image type.
+func validateImages(images []*proto.Image) error {
+	for i, img := range images {
+		if len(img.Data) == 0 {
+			return status.Errorf(codes.InvalidArgument, "image %d is empty", i)
+		}
+		if img.MimeType != "" && !strings.HasPrefix(img.MimeType, "image/") {
+			return status.Errorf(codes.InvalidArgument, "image %d: unsupported MIME type %q", i, img.MimeType)
+		}
+	}
+	return nil
 }
 
 func (server *Server) Predict(predictRequest *proto.PredictRequest, stream proto.LLMServer_PredictServer) error {
 	modelPath := predictRequest.Model
-	prompt := predictRequest.Prompt
-	maxTokens := int(predictRequest.MaxTokens)
+	prompt, err := predictPrompt(predictRequest)
+	if err != nil {
+		return err
+	}
+	if err := validateImages(predictRequest.Images); err != nil {
+		return err
+	}
 	streamMode := predictRequest.Stream
+	args := buildPredictArgs(predictRequest)
 
-	server.logger.Infof("Predict: model=%s, max_tokens=%d, stream=%v, temp=%.3f, top_p=%.3f, top_k=%d",
-		modelPath, maxTokens, streamMode,
-		predictRequest.Temperature, predictRequest.TopP, predictRequest.TopK)
+	server.logger.Debugf("Predict: model=%s, stream=%v, %s",
+		modelPath, streamMode, strings.Join(args.ActiveOptions(), ", "))
 	server.logger.Debugf("Predict: prompt: %s", prompt)
 
-	if predictRequest.Options != nil {
-		server.logPredictOptions(predictRequest.Options)
+	if predictRequest.MaxTokens == 0 {
+		return nil
+	}
+	if err := args.Validate(); err != nil {
+		return toStatus(err)
 	}
 
-	if maxTokens == 0 {
-		server.logger.Infof("Predict: maxTokens=0, skipping generation")
-		return nil
+	args.ClientKey = clientKey(stream.Context())
+	args.RequestID = requestID(stream.Context())
+	_ = stream.SetHeader(metadata.Pairs("x-request-id", args.RequestID))
+
+	if key := idempotencyKey(stream.Context()); key != "" {
+		return server.predictIdempotent(key, predictRequest, prompt, args, stream)
 	}
 
-	args := buildPredictArgs(predictRequest)
-	server.logSamplingBehavior(args)
+	// With the result store, the generation outlives its client.
+	ctx := stream.Context()
+	send := stream.Send
+	var stored *storedResult
+	if server.results != nil {
+		if stored, err = server.results.start(resultID(args.ClientKey, args.RequestID)); err != nil {
+			return err
+		}
+		ctx = context.WithoutCancel(ctx)
+		send = detachable(stream.Context(), send)
+	}
 
 	var streamFunc inferenceengine.StreamFunc
+	var onLoad modelmanagement.LoadModelProgressFunc
+	var sender *bufferedSender
+	var prefill *prefillReporter
 	if streamMode {
-		streamFunc = func(token, tokens int, message string) error {
-			msg := proto.PredictResponse{
-				Message: []byte(message),
-				Token:   int32(token),
-				Tokens:  int32(tokens),
-			}
-			if err := stream.Send(&msg); err != nil {
+		sender = newBufferedSender(send, server.opts.Stream)
+		prefill = startPrefillReporter(sender, server.opts.Stream.PrefillKeepalive)
+		args.PrefillProgress = prefill.Progress
+		onLoad = func(progress modelmanagement.LoadProgress) {
+			// A failed send surfaces on the next token.
+			_ = sender.Send(&proto.PredictResponse{LoadProgress: loadProgressToProto(progress)})
+		}
+		streamFunc = func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+			prefill.Generating()
+			msg := chunkResponse(tokenIDs, promptTokens, completionTokens, message)
+			if err := sender.Send(msg); err != nil {
 				server.logger.Errorf("Predict: stream Send failed: %v", err)
 				return err
 			}
@@ -83,15 +275,43 @@ func (server *Server) Predict(predictRequest *proto.PredictRequest, stream proto
 		}
 	}
 
-	response, err := server.service.Predict(modelPath, prompt, args, streamFunc)
+	var coalescer *inferenceengine.Coalescer
+	if streamFunc != nil {
+		coalescer = requestopts.NewCoalescer(streamFunc, predictRequest.Options)
+		if coalescer.Enabled() {
+			streamFunc = coalescer.Stream
+		}
+		args.SpecialToken = specialTokenFunc(func(msg *proto.PredictResponse) error {
+			prefill.Generating()
+			return sender.Send(msg)
+		}, coalescer)
+	}
+
+	result, err := server.service.Predict(ctx, modelPath, prompt, args, streamFunc, onLoad)
+	if err == nil && coalescer != nil && coalescer.Enabled() {
+		err = coalescer.Flush()
+	}
+	if stored != nil {
+		server.results.finish(resultID(args.ClientKey, args.RequestID), stored, result, err)
+	}
+	if err == nil && sender != nil {
+		err = sender.Send(finalResponse(result))
+	}
+	if sender != nil {
+		prefill.Stop()
+		if sendErr := sender.Close(); sendErr != nil && err == nil {
+			err = sendErr
+		}
+	}
 	if err != nil {
 		server.logger.Errorf("Predict: failed: %v", err)
-		return err
+		return toStatus(err)
 	}
 
 	if !streamMode {
-		msg := proto.PredictResponse{Message: []byte(response)}
-		if err := stream.Send(&msg); err != nil {
+		msg := finalResponse(result)
+		msg.Message = []byte(result.Text)
+		if err := send(msg); err != nil {
 			server.logger.Errorf("Predict: stream Send failed (non-streaming): %v", err)
 			return err
 		}
@@ -101,6 +321,395 @@ func (server *Server) Predict(predictRequest *proto.PredictRequest, stream proto
 	return nil
 }
 
+func (server *Server) Score(ctx context.Context, req *proto.ScoreRequest) (*proto.ScoreResponse, error) {
+	server.logger.Infof("Score: model=%s, text=%d bytes", req.Model, len(req.Text))
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(ctx),
+		RequestID: requestID(ctx),
+	}
+	res, err := server.service.Score(ctx, req.Model, req.Text, args)
+	if err != nil {
+		server.logger.Errorf("Score: failed: %v", err)
+		return nil, toStatus(err)
+	}
+	resp := &proto.ScoreResponse{
+		Tokens:        int32(res.Tokens),
+		LogLikelihood: res.LogLikelihood,
+		Perplexity:    res.Perplexity,
+		TotalMs:       durationMs(res.TotalTime),
+	}
+	if req.TokenLogprobs {
+		resp.TokenLogprobs = res.TokenLogprobs
+	}
+	return resp, nil
+}
+
+func (server *Server) Embed(ctx context.Context, req *proto.EmbedRequest) (*proto.EmbedResponse, error) {
+	server.logger.Infof("Embed: model=%s, texts=%d", req.Model, len(req.Texts))
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(ctx),
+		RequestID: requestID(ctx),
+	}
+	res, err := server.service.Embed(ctx, req.Model, req.Texts, args)
+	if err != nil {
+		server.logger.Errorf("Embed: failed: %v", err)
+		return nil, toStatus(err)
+	}
+	resp := &proto.EmbedResponse{
+		PromptTokens: int32(res.PromptTokens),
+		TotalMs:      durationMs(res.TotalTime),
+	}
+	for _, embd := range res.Embeddings {
+		resp.Embeddings = append(resp.Embeddings, &proto.Embedding{Values: embd})
+	}
+	return resp, nil
+}
+
+func (server *Server) Classify(ctx context.Context, req *proto.ClassifyRequest) (*proto.ClassifyResponse, error) {
+	server.logger.Infof("Classify: model=%s, texts=%d", req.Model, len(req.Texts))
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(ctx),
+		RequestID: requestID(ctx),
+	}
+	res, err := server.service.Classify(ctx, req.Model, req.Texts, args)
+	if err != nil {
+		server.logger.Errorf("Classify: failed: %v", err)
+		return nil, toStatus(err)
+	}
+	resp := &proto.ClassifyResponse{
+		PromptTokens: int32(res.PromptTokens),
+		TotalMs:      durationMs(res.TotalTime),
+	}
+	for _, labels := range res.Labels {
+		c := &proto.Classification{}
+		for _, l := range labels {
+			c.Labels = append(c.Labels, &proto.LabelScore{Label: l.Label, Score: l.Score, Probability: l.Probability})
+		}
+		resp.Results = append(resp.Results, c)
+	}
+	return resp, nil
+}
+
+func (server *Server) Similarity(ctx context.Context, req *proto.SimilarityRequest) (*proto.SimilarityResponse, error) {
+	server.logger.Infof("Similarity: model=%s, candidates=%d", req.Model, len(req.Candidates))
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(ctx),
+		RequestID: requestID(ctx),
+	}
+	res, err := server.service.Similarity(ctx, req.Model, req.Query, req.Candidates, args)
+	if err != nil {
+		server.logger.Errorf("Similarity: failed: %v", err)
+		return nil, toStatus(err)
+	}
+	return &proto.SimilarityResponse{
+		Scores:       res.Scores,
+		PromptTokens: int32(res.PromptTokens),
+		TotalMs:      durationMs(res.TotalTime),
+	}, nil
+}
+
+func (server *Server) Bench(ctx context.Context, req *proto.BenchRequest) (*proto.BenchResponse, error) {
+	server.logger.Infof("Bench: model=%s, n_prompt=%d, n_gen=%d, repetitions=%d", req.Model, req.NPrompt, req.NGen, req.Repetitions)
+	results, err := server.service.Bench(ctx, req.Model, inferenceengine.BenchOptions{
+		NPrompt:     int(req.NPrompt),
+		NGen:        int(req.NGen),
+		Repetitions: int(req.Repetitions),
+	})
+	if err != nil {
+		server.logger.Errorf("Bench: failed: %v", err)
+		return nil, toStatus(err)
+	}
+	resp := &proto.BenchResponse{}
+	for _, res := range results {
+		msg := &proto.BenchResult{
+			Test:            res.Test,
+			NPrompt:         int32(res.NPrompt),
+			NGen:            int32(res.NGen),
+			TokensPerSecond: res.TokensPerSecond,
+			Stddev:          res.StdDev,
+		}
+		for _, d := range res.Samples {
+			msg.SamplesMs = append(msg.SamplesMs, durationMs(d))
+		}
+		resp.Results = append(resp.Results, msg)
+	}
+	return resp, nil
+}
+
+func (server *Server) GetServerStatus(ctx context.Context, req *proto.GetServerStatusRequest) (*proto.GetServerStatusResponse, error) {
+	stats := server.service.Stats()
+	resp := &proto.GetServerStatusResponse{
+		NParallel:             int32(stats.NParallel),
+		MaxConcurrentRequests: int32(stats.MaxConcurrent),
+		MaxQueue:              int32(stats.MaxQueue),
+		ActiveSlots:           int32(stats.ActiveSlots),
+		QueueDepth:            int32(stats.QueueDepth),
+		KvCells:               int32(stats.KvCells),
+		KvCellsUsed:           int32(stats.KvCellsUsed),
+		LoadedModels:          server.service.ListModelsFor(clientKey(ctx)),
+		SystemInfo:            systemInfoToProto(llamacppbindings.DetectCapabilities()),
+	}
+	for _, t := range stats.Tenants {
+		resp.Tenants = append(resp.Tenants, &proto.TenantQueueStatus{
+			Tenant:     t.Tenant,
+			Weight:     float32(t.Weight),
+			QueueDepth: int32(t.Depth),
+		})
+	}
+	for _, h := range server.service.ModelHealth(clientKey(ctx)) {
+		resp.ModelHealth = append(resp.ModelHealth, &proto.ModelHealth{
+			Model:           h.Model,
+			Healthy:         h.Healthy,
+			Error:           h.Error,
+			LatencyMs:       durationMs(h.Latency),
+			CheckedAtUnixMs: h.CheckedAt.UnixMilli(),
+		})
+	}
+	return resp, nil
+}
+
+func systemInfoToProto(c llamacppbindings.Capabilities) *proto.SystemInfo {
+	info := &proto.SystemInfo{
+		CpuFeatures: c.CPUFeatures,
+		Raw:         c.SystemInfo,
+	}
+	for _, d := range c.Devices {
+		info.Devices = append(info.Devices, &proto.Device{
+			Name:        d.Name,
+			Description: d.Description,
+			Type:        string(d.Type),
+			MemoryFree:  d.MemoryFree,
+			MemoryTotal: d.MemoryTotal,
+		})
+	}
+	return info
+}
+
+// WatchEvents streams model lifecycle events until the client goes away or
+// the server stops. Events that occurred before the call are not replayed.
+func (server *Server) WatchEvents(req *proto.WatchEventsRequest, stream proto.LLMServer_WatchEventsServer) error {
+	events, cancel := server.service.WatchEvents()
+	defer cancel()
+	key := clientKey(stream.Context())
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if server.service.CheckAccess(key, ev.Path) != nil {
+				continue
+			}
+			if err := stream.Send(modelEventToProto(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func modelEventToProto(ev modelmanagement.Event) *proto.ModelEvent {
+	msg := &proto.ModelEvent{
+		Path:            ev.Path,
+		TimestampUnixMs: ev.Time.UnixMilli(),
+		LoadDurationMs:  durationMs(ev.Duration),
+	}
+	if ev.Err != nil {
+		msg.Error = ev.Err.Error()
+	}
+	switch ev.Type {
+	case modelmanagement.EventLoadStarted:
+		msg.Type = proto.ModelEventType_MODEL_EVENT_LOAD_STARTED
+	case modelmanagement.EventLoadCompleted:
+		msg.Type = proto.ModelEventType_MODEL_EVENT_LOAD_COMPLETED
+	case modelmanagement.EventLoadFailed:
+		msg.Type = proto.ModelEventType_MODEL_EVENT_LOAD_FAILED
+	case modelmanagement.EventLoadCanceled:
+		msg.Type = proto.ModelEventType_MODEL_EVENT_LOAD_CANCELED
+	case modelmanagement.EventUnloaded:
+		msg.Type = proto.ModelEventType_MODEL_EVENT_UNLOADED
+	}
+	return msg
+}
+
+func (server *Server) GetVersion(ctx context.Context, req *proto.GetVersionRequest) (*proto.GetVersionResponse, error) {
+	info := version.Get()
+	return &proto.GetVersionResponse{
+		Version:         info.Version,
+		GitCommit:       info.Commit,
+		GoVersion:       info.GoVersion,
+		LlamaCppVersion: info.LlamaCppVersion,
+		Backends:        llamacppbindings.Backends(),
+	}, nil
+}
+
+// finalResponse builds the last response of a Predict call, which carries
+// the token counts, finish reason and timings but (when streaming) no text.
+func finalResponse(result inferenceengine.Result) *proto.PredictResponse {
+	return &proto.PredictResponse{
+		Tokens:           int32(result.PromptTokens + result.CompletionTokens),
+		PromptTokens:     int32(result.PromptTokens),
+		CompletionTokens: int32(result.CompletionTokens),
+		FinishReason:     finishReasonToProto(result.FinishReason),
+		Timings:          timingsToProto(result.Timings),
+
+		DraftTokens:         int32(result.DraftTokens),
+		DraftAcceptedTokens: int32(result.DraftAcceptedTokens),
+		Cached:              result.Cached,
+		Annotations:         result.Annotations,
+		Beams:               beamsToProto(result.Beams),
+
+		MaxTokens:       int32(result.MaxTokens),
+		MaxTokensReason: maxTokensReasonToProto(result.MaxTokensReason),
+	}
+}
+
+func maxTokensReasonToProto(r inferenceengine.MaxTokensReason) proto.MaxTokensReason {
+	switch r {
+	case inferenceengine.MaxTokensRequested:
+		return proto.MaxTokensReason_MAX_TOKENS_REASON_REQUESTED
+	case inferenceengine.MaxTokensContext:
+		return proto.MaxTokensReason_MAX_TOKENS_REASON_CONTEXT
+	default:
+		return proto.MaxTokensReason_MAX_TOKENS_REASON_UNSPECIFIED
+	}
+}
+
+func beamsToProto(beams []inferenceengine.Beam) []*proto.Beam {
+	var out []*proto.Beam
+	for _, b := range beams {
+		out = append(out, &proto.Beam{
+			Text:         b.Text,
+			Tokens:       int32(b.Tokens),
+			Logprob:      b.LogProb,
+			Score:        b.Score,
+			FinishReason: finishReasonToProto(b.FinishReason),
+		})
+	}
+	return out
+}
+
+func finishReasonToProto(r inferenceengine.FinishReason) proto.FinishReason {
+	switch r {
+	case inferenceengine.FinishStop:
+		return proto.FinishReason_FINISH_REASON_STOP
+	case inferenceengine.FinishLength:
+		return proto.FinishReason_FINISH_REASON_LENGTH
+	default:
+		return proto.FinishReason_FINISH_REASON_UNSPECIFIED
+	}
+}
+
+// specialTokensFromProto maps the request's special-token mode; unknown
+// values use the server default.
+func specialTokensFromProto(m proto.SpecialTokens) inferenceengine.SpecialTokens {
+	switch m {
+	case proto.SpecialTokens_SPECIAL_TOKENS_RENDER:
+		return inferenceengine.SpecialTokensRender
+	case proto.SpecialTokens_SPECIAL_TOKENS_SKIP:
+		return inferenceengine.SpecialTokensSkip
+	case proto.SpecialTokens_SPECIAL_TOKENS_EVENT:
+		return inferenceengine.SpecialTokensEvent
+	default:
+		return inferenceengine.SpecialTokensDefault
+	}
+}
+
+// maxTokensPolicyFromProto maps the request's max_tokens policy; unknown
+// values use the server default.
+func maxTokensPolicyFromProto(p proto.MaxTokensPolicy) inferenceengine.MaxTokensPolicy {
+	switch p {
+	case proto.MaxTokensPolicy_MAX_TOKENS_POLICY_CLAMP:
+		return inferenceengine.MaxTokensClamp
+	case proto.MaxTokensPolicy_MAX_TOKENS_POLICY_REJECT:
+		return inferenceengine.MaxTokensReject
+	default:
+		return inferenceengine.MaxTokensPolicyDefault
+	}
+}
+
+func timingsToProto(t inferenceengine.Timings) *proto.PredictTimings {
+	return &proto.PredictTimings{
+		QueueMs:            durationMs(t.QueueTime),
+		TimeToFirstTokenMs: durationMs(t.TimeToFirstToken),
+		TotalMs:            durationMs(t.TotalTime),
+		InterTokenLatency: &proto.LatencyDistribution{
+			Count:  int32(t.TokenLatency.Count),
+			MeanMs: durationMs(t.TokenLatency.Mean),
+			P50Ms:  durationMs(t.TokenLatency.P50),
+			P90Ms:  durationMs(t.TokenLatency.P90),
+			P99Ms:  durationMs(t.TokenLatency.P99),
+			MaxMs:  durationMs(t.TokenLatency.Max),
+		},
+	}
+}
+
+func durationMs(d time.Duration) float32 {
+	return float32(d.Seconds() * 1000)
+}
+
+// specialTokenFunc sends control tokens in SPECIAL_TOKENS_EVENT mode as
+// messages of their own, after the text the coalescer holds back.
+func specialTokenFunc(send func(*proto.PredictResponse) error, coalescer *inferenceengine.Coalescer) inferenceengine.SpecialTokenFunc {
+	return func(token, promptTokens, completionTokens int, piece string) error {
+		if err := coalescer.Flush(); err != nil {
+			return err
+		}
+		return send(&proto.PredictResponse{
+			Token:            int32(token),
+			Tokens:           int32(promptTokens + completionTokens),
+			PromptTokens:     int32(promptTokens),
+			CompletionTokens: int32(completionTokens),
+			SpecialToken:     &proto.SpecialToken{Id: int32(token), Piece: piece},
+		})
+	}
+}
+
+// chunkResponse is the streamed message of generated text.
+func chunkResponse(tokenIDs []int, promptTokens, completionTokens int, message string) *proto.PredictResponse {
+	msg := &proto.PredictResponse{
+		Message:          []byte(message),
+		Tokens:           int32(promptTokens + completionTokens),
+		PromptTokens:     int32(promptTokens),
+		CompletionTokens: int32(completionTokens),
+		TokenIds:         make([]int32, len(tokenIDs)),
+	}
+	for i, id := range tokenIDs {
+		msg.TokenIds[i] = int32(id)
+	}
+	if len(tokenIDs) > 0 {
+		msg.Token = msg.TokenIds[len(tokenIDs)-1]
+	}
+	return msg
+}
+
+// clientKey returns the caller's API key from the "x-api-key" metadata or a
+// bearer "authorization" header. Empty if the caller is anonymous.
+func clientKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(v []string) string {
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0]
+	}
+	return requestopts.ClientKey(first(md.Get("x-api-key")), first(md.Get("authorization")))
+}
+
+// requestID returns the "x-request-id" metadata value, or a new random ID if
+// the client sent none.
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-request-id"); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func buildPredictArgs(req *proto.PredictRequest) inferenceengine.PredictArgs {
 	nPredict := int(req.MaxTokens)
 	if nPredict < 0 {
@@ -130,6 +739,9 @@ func buildPredictArgs(req *proto.PredictRequest) inferenceengine.PredictArgs {
 		LengthPenalty:     1.0,
 		RandomSeed:        -1,
 	}
+	for _, img := range req.Images {
+		args.Images = append(args.Images, inferenceengine.Image{Data: img.Data, MimeType: img.MimeType})
+	}
 
 	if req.Options == nil {
 		return args
@@ -200,59 +812,22 @@ func buildPredictArgs(req *proto.PredictRequest) inferenceengine.PredictArgs {
 	if opts.RandomSeed != nil {
 		args.RandomSeed = int(*opts.RandomSeed)
 	}
-
-	return args
-}
-
-func (server *Server) logPredictOptions(opts *proto.PredictRequest_Options) {
-	if opts.MinP != nil {
-		server.logger.Infof("  option min_p: %.3f", *opts.MinP)
-	}
-	if opts.MinTokensToKeep != nil {
-		server.logger.Infof("  option min_tokens_to_keep: %d", *opts.MinTokensToKeep)
-	}
-	if opts.MaxKvSize != nil {
-		server.logger.Infof("  option max_kv_size: %d", *opts.MaxKvSize)
-	}
-	if opts.PrefillStepSize != nil {
-		server.logger.Infof("  option prefill_step_size: %d", *opts.PrefillStepSize)
-	}
-	if opts.KvBits != nil {
-		server.logger.Infof("  option kv_bits: %d", *opts.KvBits)
-	}
-	if opts.KvGroupSize != nil {
-		server.logger.Infof("  option kv_group_size: %d", *opts.KvGroupSize)
-	}
-	if opts.QuantizedKvStart != nil {
-		server.logger.Infof("  option quantized_kv_start: %d", *opts.QuantizedKvStart)
-	}
-	if opts.RepetitionPenalty != nil {
-		server.logger.Infof("  option repetition_penalty: %.3f", *opts.RepetitionPenalty)
-	}
-	if opts.LengthPenalty != nil {
-		server.logger.Infof("  option length_penalty: %.3f", *opts.LengthPenalty)
+	if opts.PromptLookup != nil {
+		args.PromptLookup = max(int(*opts.PromptLookup), 0)
 	}
-	if opts.DiversityPenalty != nil {
-		server.logger.Infof("  option diversity_penalty: %.3f", *opts.DiversityPenalty)
+	if opts.Regex != nil {
+		args.Regex = *opts.Regex
 	}
-	if opts.NoRepeatNgramSize != nil {
-		server.logger.Infof("  option no_repeat_ngram_size: %d", *opts.NoRepeatNgramSize)
+	if opts.BeamWidth != nil {
+		args.BeamWidth = max(int(*opts.BeamWidth), 0)
 	}
-	if opts.RandomSeed != nil {
-		server.logger.Infof("  option random_seed: %d", *opts.RandomSeed)
+	args.ReturnBeams = opts.ReturnBeams
+	for _, id := range opts.StopTokenIds {
+		args.StopTokenIDs = append(args.StopTokenIDs, int(id))
 	}
-}
+	args.BannedStrings = opts.BannedStrings
+	args.SpecialTokens = specialTokensFromProto(opts.SpecialTokens)
+	args.MaxTokensPolicy = maxTokensPolicyFromProto(opts.MaxTokensPolicy)
 
-func (server *Server) logSamplingBehavior(args inferenceengine.PredictArgs) {
-	if args.Temp == 0.0 || args.TopK == 1 {
-		server.logger.Infof("Predict: GREEDY SAMPLING (deterministic)")
-	} else {
-		server.logger.Infof("Predict: RANDOMIZED SAMPLING (temp=%.3f)", args.Temp)
-	}
-	if args.RepetitionPenalty != 1.0 {
-		server.logger.Infof("Predict: repetition_penalty=%.3f", args.RepetitionPenalty)
-	}
-	if args.RandomSeed >= 0 {
-		server.logger.Infof("Predict: seed=%d (reproducible)", args.RandomSeed)
-	}
+	return args
 }
@@ -0,0 +1,309 @@
+package grpcserver
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SlowConsumerPolicy decides what happens when a client reads a Predict
+// stream slower than tokens are generated and its buffer fills up.
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerAbort keeps generating while the buffer is full and
+	// aborts the request with RESOURCE_EXHAUSTED if the client has not
+	// caught up after StreamOptions.SlowConsumerTimeout, or at once when
+	// twice StreamOptions.BufferSize responses are queued. Generation never
+	// waits for the client.
+	SlowConsumerAbort SlowConsumerPolicy = iota
+	// SlowConsumerPause blocks generation until the client catches up.
+	// Note that this stalls every slot in the shared batch.
+	SlowConsumerPause
+)
+
+// ParseSlowConsumerPolicy parses "abort" or "pause".
+func ParseSlowConsumerPolicy(s string) (SlowConsumerPolicy, error) {
+	switch strings.ToLower(s) {
+	case "abort":
+		return SlowConsumerAbort, nil
+	case "pause":
+		return SlowConsumerPause, nil
+	default:
+		return 0, fmt.Errorf("unknown slow-consumer policy %q (want abort or pause)", s)
+	}
+}
+
+func (p SlowConsumerPolicy) String() string {
+	switch p {
+	case SlowConsumerAbort:
+		return "abort"
+	case SlowConsumerPause:
+		return "pause"
+	default:
+		return "unknown"
+	}
+}
+
+// StreamOptions configures per-stream buffering of Predict responses.
+type StreamOptions struct {
+	BufferSize          int
+	SlowConsumerPolicy  SlowConsumerPolicy
+	SlowConsumerTimeout time.Duration
+
+	// PrefillKeepalive is the longest a stream stays silent while its prompt
+	// is processed; the last prefill progress is repeated when it elapses.
+	// Zero disables keepalives (progress is still sent per prompt chunk).
+	PrefillKeepalive time.Duration
+}
+
+// bufferedSender decouples the engine goroutine from stream.Send: responses
+// are queued and sent by a dedicated goroutine, so a slow client only delays
+// its own stream. Past BufferSize queued responses the slow-consumer policy
+// applies: pause blocks Send until the client catches up, abort keeps
+// queueing without blocking and fails the stream with RESOURCE_EXHAUSTED if
+// the queue is still over BufferSize after SlowConsumerTimeout, which the
+// next Send then reports so the request is aborted. So that a fast generator
+// can't grow the queue without bound meanwhile, abort also fails the stream
+// as soon as the queue reaches twice BufferSize.
+type bufferedSender struct {
+	opts   StreamOptions
+	failed chan struct{}
+	done   chan struct{}
+
+	mx     sync.Mutex
+	cond   sync.Cond // broadcast when the queue changes or the stream fails
+	queue  []*proto.PredictResponse
+	closed bool
+	err    error
+
+	// overflow expires the stream while the queue is over BufferSize under
+	// the abort policy; overflowSince is when it went over, zero if it is not.
+	overflow      *time.Timer
+	overflowSince time.Time
+}
+
+func newBufferedSender(send func(*proto.PredictResponse) error, opts StreamOptions) *bufferedSender {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1
+	}
+	b := &bufferedSender{
+		opts:   opts,
+		failed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	b.cond.L = &b.mx
+	go b.loop(send)
+	return b
+}
+
+func (b *bufferedSender) loop(send func(*proto.PredictResponse) error) {
+	defer close(b.done)
+	for {
+		b.mx.Lock()
+		for len(b.queue) == 0 && !b.closed && b.err == nil {
+			b.cond.Wait()
+		}
+		// After a failure the queued responses are dropped.
+		if b.err != nil || len(b.queue) == 0 {
+			b.mx.Unlock()
+			return
+		}
+		msg := b.queue[0]
+		b.queue[0] = nil
+		b.queue = b.queue[1:]
+		if b.overflow != nil && len(b.queue) <= b.opts.BufferSize {
+			b.overflow.Stop()
+			b.overflow, b.overflowSince = nil, time.Time{}
+		}
+		b.cond.Broadcast()
+		b.mx.Unlock()
+
+		if err := send(msg); err != nil {
+			b.fail(err)
+			return
+		}
+	}
+}
+
+func (b *bufferedSender) fail(err error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.failLocked(err)
+}
+
+func (b *bufferedSender) failLocked(err error) {
+	if b.err != nil {
+		return
+	}
+	b.err = err
+	b.queue = nil
+	if b.overflow != nil {
+		b.overflow.Stop()
+		b.overflow = nil
+	}
+	close(b.failed)
+	b.cond.Broadcast()
+}
+
+// expire fails the stream if its queue has been over BufferSize for
+// SlowConsumerTimeout; it runs on the overflow timer.
+func (b *bufferedSender) expire() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	// The queue may have drained, or gone over again, since the timer fired.
+	if b.overflowSince.IsZero() || time.Since(b.overflowSince) < b.opts.SlowConsumerTimeout {
+		return
+	}
+	b.failLocked(status.Errorf(codes.ResourceExhausted,
+		"client did not read the stream for %s (buffer of %d responses full)",
+		b.opts.SlowConsumerTimeout, b.opts.BufferSize))
+}
+
+func (b *bufferedSender) getErr() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.err
+}
+
+// Send queues msg for delivery. It returns an error if an earlier send
+// failed or if the slow-consumer policy gave up on the client. Only the
+// pause policy blocks.
+func (b *bufferedSender) Send(msg *proto.PredictResponse) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.opts.SlowConsumerPolicy == SlowConsumerPause {
+		for len(b.queue) >= b.opts.BufferSize && b.err == nil {
+			b.cond.Wait()
+		}
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.queue) >= 2*b.opts.BufferSize {
+		b.failLocked(status.Errorf(codes.ResourceExhausted,
+			"client fell %d responses behind the stream", len(b.queue)))
+		return b.err
+	}
+	b.queue = append(b.queue, msg)
+	if len(b.queue) > b.opts.BufferSize && b.overflow == nil {
+		b.overflowSince = time.Now()
+		b.overflow = time.AfterFunc(b.opts.SlowConsumerTimeout, b.expire)
+	}
+	b.cond.Broadcast()
+	return nil
+}
+
+// Close flushes the queued responses and waits for them to be sent.
+// If the stream already failed it returns immediately, since the sending
+// goroutine may still be blocked on a client that stopped reading.
+func (b *bufferedSender) Close() error {
+	b.mx.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mx.Unlock()
+	select {
+	case <-b.done:
+	case <-b.failed:
+	}
+	return b.getErr()
+}
+
+// prefillReporter sends prefill progress messages on a Predict stream and,
+// until the first token arrives, repeats the latest progress every keepalive
+// interval so proxies don't time out a stream that is busy on a long prompt.
+type prefillReporter struct {
+	sender *bufferedSender
+
+	mx         sync.Mutex
+	processed  int
+	total      int
+	lastSent   time.Time
+	generating bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startPrefillReporter(sender *bufferedSender, keepalive time.Duration) *prefillReporter {
+	p := &prefillReporter{
+		sender:   sender,
+		lastSent: time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if keepalive <= 0 {
+		close(p.done)
+		return p
+	}
+	go p.loop(keepalive)
+	return p
+}
+
+func (p *prefillReporter) loop(keepalive time.Duration) {
+	defer close(p.done)
+	ticker := time.NewTicker(keepalive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mx.Lock()
+			due := !p.generating && time.Since(p.lastSent) >= keepalive
+			processed, total := p.processed, p.total
+			if due {
+				p.lastSent = time.Now()
+			}
+			p.mx.Unlock()
+			if due {
+				if err := p.sender.Send(prefillMessage(processed, total)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Progress records and sends prefill progress; it is a PrefillProgressFunc.
+func (p *prefillReporter) Progress(processed, total int) error {
+	p.mx.Lock()
+	p.processed, p.total = processed, total
+	p.lastSent = time.Now()
+	p.mx.Unlock()
+	return p.sender.Send(prefillMessage(processed, total))
+}
+
+// Generating stops keepalives once tokens are flowing.
+func (p *prefillReporter) Generating() {
+	p.mx.Lock()
+	p.generating = true
+	p.mx.Unlock()
+}
+
+// Stop terminates the keepalive goroutine. It must be called before the
+// sender is closed.
+func (p *prefillReporter) Stop() {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	close(p.stop)
+	<-p.done
+}
+
+func prefillMessage(processed, total int) *proto.PredictResponse {
+	return &proto.PredictResponse{
+		PrefillProgress: &proto.PrefillProgress{
+			Processed: int32(processed),
+			Total:     int32(total),
+		},
+	}
+}
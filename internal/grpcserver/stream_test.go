@@ -0,0 +1,173 @@
+package grpcserver
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gatedClient is a stream whose client only reads while its gate is open.
+type gatedClient struct {
+	gate chan struct{}
+	fail error // returned by the send of the second response if set
+
+	mu   sync.Mutex
+	sent []int32
+}
+
+func newGatedClient() *gatedClient {
+	return &gatedClient{gate: make(chan struct{})}
+}
+
+func (c *gatedClient) send(msg *proto.PredictResponse) error {
+	<-c.gate
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail != nil && len(c.sent) == 1 {
+		return c.fail
+	}
+	c.sent = append(c.sent, msg.Token)
+	return nil
+}
+
+func (c *gatedClient) received() []int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int32(nil), c.sent...)
+}
+
+func token(i int) *proto.PredictResponse {
+	return &proto.PredictResponse{Token: int32(i)}
+}
+
+func tokens(n int) []int32 {
+	out := make([]int32, n)
+	for i := range out {
+		out[i] = int32(i)
+	}
+	return out
+}
+
+func TestBufferedSenderPauseBlocks(t *testing.T) {
+	client := newGatedClient()
+	b := newBufferedSender(client.send, StreamOptions{BufferSize: 2, SlowConsumerPolicy: SlowConsumerPause})
+
+	// One response is held by the sending goroutine, two fill the buffer.
+	for i := range 3 {
+		require.NoError(t, b.Send(token(i)))
+	}
+	require.Eventually(t, func() bool {
+		b.mx.Lock()
+		defer b.mx.Unlock()
+		return len(b.queue) == 2
+	}, time.Second, time.Millisecond)
+
+	sent := make(chan error, 1)
+	go func() { sent <- b.Send(token(3)) }()
+	select {
+	case <-sent:
+		t.Fatal("Send returned with a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(client.gate)
+	require.NoError(t, <-sent)
+	require.NoError(t, b.Close())
+	require.Equal(t, tokens(4), client.received())
+}
+
+func TestBufferedSenderAbortDoesNotBlock(t *testing.T) {
+	client := newGatedClient()
+	defer close(client.gate)
+	b := newBufferedSender(client.send, StreamOptions{
+		BufferSize:          2,
+		SlowConsumerPolicy:  SlowConsumerAbort,
+		SlowConsumerTimeout: 100 * time.Millisecond,
+	})
+
+	// Sends past the buffer, up to twice its size, return at once while the
+	// timeout runs.
+	start := time.Now()
+	for i := range 4 {
+		require.NoError(t, b.Send(token(i)))
+	}
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+
+	// Once it elapsed the next Send reports the abort.
+	require.Eventually(t, func() bool {
+		return status.Code(b.Send(token(0))) == codes.ResourceExhausted
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, codes.ResourceExhausted, status.Code(b.Close()))
+}
+
+func TestBufferedSenderAbortHardLimit(t *testing.T) {
+	client := newGatedClient()
+	defer close(client.gate)
+	b := newBufferedSender(client.send, StreamOptions{
+		BufferSize:          2,
+		SlowConsumerPolicy:  SlowConsumerAbort,
+		SlowConsumerTimeout: time.Minute,
+	})
+
+	// One response is held by the sending goroutine, four fill twice the
+	// buffer.
+	require.NoError(t, b.Send(token(0)))
+	require.Eventually(t, func() bool {
+		b.mx.Lock()
+		defer b.mx.Unlock()
+		return len(b.queue) == 0
+	}, time.Second, time.Millisecond)
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, b.Send(token(i)))
+	}
+
+	// The next Send fails the stream without waiting for the timeout.
+	require.Equal(t, codes.ResourceExhausted, status.Code(b.Send(token(5))))
+	require.Equal(t, codes.ResourceExhausted, status.Code(b.Close()))
+}
+
+func TestBufferedSenderAbortTimeoutResetsWhenClientCatchesUp(t *testing.T) {
+	client := newGatedClient()
+	b := newBufferedSender(client.send, StreamOptions{
+		BufferSize:          2,
+		SlowConsumerPolicy:  SlowConsumerAbort,
+		SlowConsumerTimeout: 200 * time.Millisecond,
+	})
+
+	for i := range 4 {
+		require.NoError(t, b.Send(token(i)))
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(client.gate)
+	require.Eventually(t, func() bool { return len(client.received()) == 4 }, time.Second, time.Millisecond)
+
+	// The overflow timer is stopped once the buffer drained.
+	time.Sleep(250 * time.Millisecond)
+	require.NoError(t, b.Send(token(4)))
+	require.NoError(t, b.Close())
+	require.Equal(t, tokens(5), client.received())
+}
+
+func TestBufferedSenderDropsAfterFailure(t *testing.T) {
+	client := newGatedClient()
+	client.fail = errors.New("stream reset")
+	close(client.gate)
+	b := newBufferedSender(client.send, StreamOptions{BufferSize: 4})
+
+	require.NoError(t, b.Send(token(0)))
+	require.NoError(t, b.Send(token(1)))
+	require.Eventually(t, func() bool { return b.Send(token(2)) != nil }, time.Second, time.Millisecond)
+
+	// Responses queued after the failed one are dropped, and later Sends
+	// and Close report the failure.
+	require.ErrorIs(t, b.Send(token(3)), client.fail)
+	require.ErrorIs(t, b.Close(), client.fail)
+	require.Equal(t, []int32{0}, client.received())
+}
@@ -0,0 +1,77 @@
+package grpcserver
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PredictUpload is Predict with the prompt received in pieces, for prompts
+// larger than the gRPC message size limit such as long documents.
+func (server *Server) PredictUpload(stream proto.LLMServer_PredictUploadServer) error {
+	req, err := receiveUpload(stream.Recv, server.opts.MaxUploadBytes)
+	if err != nil {
+		server.logger.Errorf("PredictUpload: failed: %v", err)
+		return err
+	}
+	server.logger.Debugf("PredictUpload: received a prompt of %d bytes", len(req.Prompt))
+	return server.Predict(req, stream)
+}
+
+// receiveUpload reads the messages of a PredictUpload call until the client
+// closes its side of the stream and returns the request with the whole
+// prompt. A prompt over limit bytes fails with RESOURCE_EXHAUSTED as soon as
+// it is; 0 means no limit.
+func receiveUpload(recv func() (*proto.PredictUploadRequest, error), limit int) (*proto.PredictRequest, error) {
+	first, err := recv()
+	if errors.Is(err, io.EOF) || (err == nil && first.Request == nil) {
+		return nil, status.Error(codes.InvalidArgument, "the first PredictUpload message must carry the request")
+	}
+	if err != nil {
+		return nil, err
+	}
+	req := first.Request
+
+	var prompt strings.Builder
+	add := func(piece string) error {
+		if limit > 0 && prompt.Len()+len(piece) > limit {
+			return status.Errorf(codes.ResourceExhausted, "the prompt is over %d bytes (--max-upload-bytes)", limit)
+		}
+		prompt.WriteString(piece)
+		return nil
+	}
+	if err := add(req.Prompt); err != nil {
+		return nil, err
+	}
+	if err := add(string(first.PromptChunk)); err != nil {
+		return nil, err
+	}
+	for {
+		msg, err := recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if msg.Request != nil {
+			return nil, status.Error(codes.InvalidArgument, "only the first PredictUpload message may carry the request")
+		}
+		if err := add(string(msg.PromptChunk)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pieces may split characters, so only the whole prompt is checked.
+	if !utf8.ValidString(prompt.String()) {
+		return nil, status.Error(codes.InvalidArgument, "the prompt is not valid UTF-8")
+	}
+	req.Prompt = prompt.String()
+	return req, nil
+}
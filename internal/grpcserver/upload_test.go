@@ -0,0 +1,61 @@
+package grpcserver
+
+import (
+	"io"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// uploadMessages returns a recv function yielding msgs, then io.EOF.
+func uploadMessages(msgs ...*proto.PredictUploadRequest) func() (*proto.PredictUploadRequest, error) {
+	return func() (*proto.PredictUploadRequest, error) {
+		if len(msgs) == 0 {
+			return nil, io.EOF
+		}
+		msg := msgs[0]
+		msgs = msgs[1:]
+		return msg, nil
+	}
+}
+
+func TestReceiveUploadJoinsPieces(t *testing.T) {
+	// "é" is split across two pieces.
+	req, err := receiveUpload(uploadMessages(
+		&proto.PredictUploadRequest{Request: &proto.PredictRequest{Model: "m", Prompt: "caf"}},
+		&proto.PredictUploadRequest{PromptChunk: []byte{0xc3}},
+		&proto.PredictUploadRequest{PromptChunk: []byte{0xa9, '!'}},
+	), 0)
+	require.NoError(t, err)
+	require.Equal(t, "m", req.Model)
+	require.Equal(t, "café!", req.Prompt)
+}
+
+func TestReceiveUploadErrors(t *testing.T) {
+	_, err := receiveUpload(uploadMessages(), 0)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = receiveUpload(uploadMessages(&proto.PredictUploadRequest{PromptChunk: []byte("x")}), 0)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = receiveUpload(uploadMessages(
+		&proto.PredictUploadRequest{Request: &proto.PredictRequest{}},
+		&proto.PredictUploadRequest{Request: &proto.PredictRequest{}},
+	), 0)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = receiveUpload(uploadMessages(
+		&proto.PredictUploadRequest{Request: &proto.PredictRequest{}, PromptChunk: []byte{0xc3}},
+	), 0)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = receiveUpload(uploadMessages(
+		&proto.PredictUploadRequest{Request: &proto.PredictRequest{Prompt: "abc"}},
+		&proto.PredictUploadRequest{PromptChunk: []byte("de")},
+	), 4)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
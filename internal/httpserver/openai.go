@@ -2,14 +2,22 @@ package httpserver
 
 import (
 	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"strings"
 	"time"
 
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/chattemplate"
 	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/llmservice"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
 )
 
 // --- ID generation ---
@@ -71,13 +79,24 @@ type oaiChatMessage struct {
 }
 
 type oaiChatCompletionRequest struct {
-	Model       string           `json:"model"`
-	Messages    []oaiChatMessage `json:"messages"`
-	MaxTokens   *int             `json:"max_tokens,omitempty"`
-	Temperature *float32         `json:"temperature,omitempty"`
-	TopP        *float32         `json:"top_p,omitempty"`
-	Stream      bool             `json:"stream"`
-	Stop        any              `json:"stop,omitempty"`
+	Model       string            `json:"model"`
+	Messages    []oaiChatMessage  `json:"messages"`
+	MaxTokens   *int              `json:"max_tokens,omitempty"`
+	Temperature *float32          `json:"temperature,omitempty"`
+	TopP        *float32          `json:"top_p,omitempty"`
+	Stream      bool              `json:"stream"`
+	Stop        any               `json:"stop,omitempty"`
+	Tools       []json.RawMessage `json:"tools,omitempty"`
+
+	// ChatTemplateKwargs sets extra chat template variables, as in
+	// llama.cpp's server.
+	ChatTemplateKwargs *oaiChatTemplateKwargs `json:"chat_template_kwargs,omitempty"`
+}
+
+type oaiChatTemplateKwargs struct {
+	SystemPrompt *string `json:"system_prompt,omitempty"`
+	DateString   string  `json:"date_string,omitempty"`
+	CurrentDate  bool    `json:"current_date,omitempty"`
 }
 
 type oaiChatChoiceMessage struct {
@@ -106,6 +125,33 @@ type oaiChatCompletionResponse struct {
 	Usage   *oaiUsage                 `json:"usage,omitempty"`
 }
 
+// oaiEmbeddingsRequest.Input is a string or an array of strings; token
+// arrays are not supported.
+type oaiEmbeddingsRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`
+	EncodingFormat string          `json:"encoding_format,omitempty"`
+	Dimensions     *int            `json:"dimensions,omitempty"`
+}
+
+type oaiEmbedding struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding any    `json:"embedding"` // []float32, or a base64 string
+}
+
+type oaiEmbeddingsUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+type oaiEmbeddingsResponse struct {
+	Object string             `json:"object"`
+	Data   []oaiEmbedding     `json:"data"`
+	Model  string             `json:"model"`
+	Usage  oaiEmbeddingsUsage `json:"usage"`
+}
+
 type oaiErrorDetail struct {
 	Message string  `json:"message"`
 	Type    string  `json:"type"`
@@ -118,35 +164,47 @@ type oaiErrorResponse struct {
 }
 
 // --- Chat template ---
-// ChatML is the most common format for instruction-tuned models (used by
-// SmolLM2-Instruct, Qwen, Mistral-Instruct, many others).
 
-func applyChatMLTemplate(messages []oaiChatMessage) string {
-	var sb strings.Builder
-	for _, msg := range messages {
-		sb.WriteString("<|im_start|>")
-		sb.WriteString(msg.Role)
-		sb.WriteString("\n")
-		sb.WriteString(msg.Content)
-		sb.WriteString("<|im_end|>\n")
+// newChatTemplateVars collects the template variables of req; now is the
+// date used for "current_date".
+func newChatTemplateVars(req *oaiChatCompletionRequest, now time.Time) chattemplate.Vars {
+	vars := chattemplate.Vars{Tools: req.Tools}
+	if kw := req.ChatTemplateKwargs; kw != nil {
+		vars.SystemPrompt = kw.SystemPrompt
+		vars.DateString = kw.DateString
+		if vars.DateString == "" && kw.CurrentDate {
+			vars.DateString = now.Format("02 Jan 2006")
+		}
+	}
+	return vars
+}
+
+// chatMessages converts the request messages for the chat template.
+func chatMessages(messages []oaiChatMessage) []chattemplate.Message {
+	out := make([]chattemplate.Message, len(messages))
+	for i, msg := range messages {
+		out[i] = chattemplate.Message{Role: msg.Role, Content: msg.Content}
 	}
-	sb.WriteString("<|im_start|>assistant\n")
-	return sb.String()
+	return out
 }
 
 // --- Handlers ---
 
+func newOAIModelObject(path string, created int64) oaiModelObject {
+	return oaiModelObject{
+		ID:      path,
+		Object:  "model",
+		Created: created,
+		OwnedBy: "local",
+	}
+}
+
 func (s *Server) handleV1Models(w http.ResponseWriter, r *http.Request) {
-	paths := s.service.ListModels()
+	paths := s.service.ListModelsFor(clientKey(r))
 	now := time.Now().Unix()
 	models := make([]oaiModelObject, 0, len(paths))
 	for _, p := range paths {
-		models = append(models, oaiModelObject{
-			ID:      p,
-			Object:  "model",
-			Created: now,
-			OwnedBy: "local",
-		})
+		models = append(models, newOAIModelObject(p, now))
 	}
 	writeJSON(w, http.StatusOK, oaiModelList{
 		Object: "list",
@@ -154,6 +212,116 @@ func (s *Server) handleV1Models(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleV1Model retrieves one loaded model. Model IDs are paths: the mux
+// collapses the double slash of /v1/models//models/x.gguf, so the ID is also
+// matched with its leading slash restored.
+func (s *Server) handleV1Model(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("model")
+	for _, p := range s.service.ListModelsFor(clientKey(r)) {
+		if p == id || p == "/"+id {
+			writeJSON(w, http.StatusOK, newOAIModelObject(p, time.Now().Unix()))
+			return
+		}
+	}
+	writeOAIErrorCode(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+		fmt.Sprintf("the model %q does not exist or is not loaded", id))
+}
+
+// handleV1NotFound answers unknown /v1 routes with an OpenAI error body
+// instead of the mux's plain-text 404.
+func (s *Server) handleV1NotFound(w http.ResponseWriter, r *http.Request) {
+	writeOAIError(w, http.StatusNotFound, "invalid_request_error",
+		fmt.Sprintf("unknown request URL: %s %s", r.Method, r.URL.Path))
+}
+
+// --- /v1/embeddings ---
+
+// embeddingsInput decodes the input of an embeddings request.
+func embeddingsInput(raw json.RawMessage) ([]string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []string{text}, nil
+	}
+	var texts []string
+	if err := json.Unmarshal(raw, &texts); err != nil {
+		return nil, errors.New("input must be a string or an array of strings")
+	}
+	return texts, nil
+}
+
+// base64Embedding encodes v as little-endian float32s, as the OpenAI API
+// does for encoding_format "base64".
+func base64Embedding(v []float32) string {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func (s *Server) handleV1Embeddings(w http.ResponseWriter, r *http.Request) {
+	var req oaiEmbeddingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
+	if req.Model == "" {
+		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "model is required")
+		return
+	}
+	if err := s.service.CheckAccess(clientKey(r), req.Model); err != nil {
+		writeOAIErrorCode(w, http.StatusForbidden, "permission_error", "model_access_denied", err.Error())
+		return
+	}
+	input, err := embeddingsInput(req.Input)
+	if err != nil {
+		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeOAIError(w, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("unsupported encoding_format %q", req.EncodingFormat))
+		return
+	}
+	if req.Dimensions != nil {
+		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "dimensions is not supported")
+		return
+	}
+
+	s.logger.Infof("v1/embeddings: model=%s, inputs=%d", req.Model, len(input))
+
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(r),
+		RequestID: requestID(w, r),
+	}
+	res, err := s.service.Embed(r.Context(), req.Model, input, args)
+	if err != nil {
+		s.logger.Errorf("v1/embeddings failed: %v", err)
+		writeOAIPredictError(w, err)
+		return
+	}
+
+	data := make([]oaiEmbedding, len(res.Embeddings))
+	for i, v := range res.Embeddings {
+		data[i] = oaiEmbedding{Object: "embedding", Index: i, Embedding: v}
+		if req.EncodingFormat == "base64" {
+			data[i].Embedding = base64Embedding(v)
+		}
+	}
+	writeJSON(w, http.StatusOK, oaiEmbeddingsResponse{
+		Object: "list",
+		Data:   data,
+		Model:  req.Model,
+		Usage: oaiEmbeddingsUsage{
+			PromptTokens: res.PromptTokens,
+			TotalTokens:  res.PromptTokens,
+		},
+	})
+}
+
 // --- /v1/completions ---
 
 func (s *Server) handleV1Completions(w http.ResponseWriter, r *http.Request) {
@@ -162,10 +330,17 @@ func (s *Server) handleV1Completions(w http.ResponseWriter, r *http.Request) {
 		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
 		return
 	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
 	if req.Model == "" {
 		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "model is required")
 		return
 	}
+	if err := s.service.CheckAccess(clientKey(r), req.Model); err != nil {
+		writeOAIErrorCode(w, http.StatusForbidden, "permission_error", "model_access_denied", err.Error())
+		return
+	}
 
 	maxTokens := 16
 	if req.MaxTokens != nil {
@@ -175,8 +350,15 @@ func (s *Server) handleV1Completions(w http.ResponseWriter, r *http.Request) {
 	s.logger.Infof("v1/completions: model=%s, max_tokens=%d, stream=%v", req.Model, maxTokens, req.Stream)
 
 	args := buildOAIPredictArgs(maxTokens, req.Temperature, req.TopP)
+	args.ClientKey = clientKey(r)
+	args.RequestID = requestID(w, r)
 
 	if req.Stream {
+		// Reject before the stream starts, with a proper status.
+		if err := s.service.CheckQuota(args.ClientKey); err != nil {
+			writeOAIPredictError(w, err)
+			return
+		}
 		s.handleV1CompletionsStream(w, r, &req, args)
 	} else {
 		s.handleV1CompletionsNonStream(w, r, &req, args)
@@ -184,25 +366,25 @@ func (s *Server) handleV1Completions(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleV1CompletionsNonStream(w http.ResponseWriter, r *http.Request, req *oaiCompletionRequest, args inferenceengine.PredictArgs) {
-	text, err := s.service.Predict(req.Model, req.Prompt, args, nil)
+	res, err := s.service.Predict(r.Context(), req.Model, req.Prompt, args, nil, nil)
 	if err != nil {
 		s.logger.Errorf("v1/completions failed: %v", err)
-		writeOAIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		writeOAIPredictError(w, err)
 		return
 	}
 
-	finishReason := "stop"
+	finishReason := res.FinishReason.String()
 	writeJSON(w, http.StatusOK, oaiCompletionResponse{
 		ID:      generateID("cmpl-"),
 		Object:  "text_completion",
 		Created: time.Now().Unix(),
 		Model:   req.Model,
 		Choices: []oaiCompletionChoice{{
-			Text:         text,
+			Text:         res.Text,
 			Index:        0,
 			FinishReason: &finishReason,
 		}},
-		Usage: &oaiUsage{},
+		Usage: newOAIUsage(res),
 	})
 }
 
@@ -219,14 +401,7 @@ func (s *Server) handleV1CompletionsStream(w http.ResponseWriter, r *http.Reques
 
 	id := generateID("cmpl-")
 	created := time.Now().Unix()
-	ctx := r.Context()
-
-	streamFunc := func(token, tokens int, message string) error {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	streamFunc := func(_ []int, _, _ int, message string) error {
 		chunk := oaiCompletionResponse{
 			ID:      id,
 			Object:  "text_completion",
@@ -243,13 +418,13 @@ func (s *Server) handleV1CompletionsStream(w http.ResponseWriter, r *http.Reques
 		return nil
 	}
 
-	_, err := s.service.Predict(req.Model, req.Prompt, args, streamFunc)
+	res, err := s.service.Predict(r.Context(), req.Model, req.Prompt, args, streamFunc, nil)
 	if err != nil {
 		s.logger.Errorf("v1/completions streaming failed: %v", err)
 		return
 	}
 
-	finishReason := "stop"
+	finishReason := res.FinishReason.String()
 	final := oaiCompletionResponse{
 		ID:      id,
 		Object:  "text_completion",
@@ -274,10 +449,17 @@ func (s *Server) handleV1ChatCompletions(w http.ResponseWriter, r *http.Request)
 		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
 		return
 	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
 	if req.Model == "" {
 		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "model is required")
 		return
 	}
+	if err := s.service.CheckAccess(clientKey(r), req.Model); err != nil {
+		writeOAIErrorCode(w, http.StatusForbidden, "permission_error", "model_access_denied", err.Error())
+		return
+	}
 	if len(req.Messages) == 0 {
 		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", "messages is required and must be non-empty")
 		return
@@ -291,10 +473,17 @@ func (s *Server) handleV1ChatCompletions(w http.ResponseWriter, r *http.Request)
 	s.logger.Infof("v1/chat/completions: model=%s, messages=%d, max_tokens=%d, stream=%v",
 		req.Model, len(req.Messages), maxTokens, req.Stream)
 
-	prompt := applyChatMLTemplate(req.Messages)
+	prompt := chattemplate.ChatML(chatMessages(req.Messages), newChatTemplateVars(&req, time.Now()))
 	args := buildOAIPredictArgs(maxTokens, req.Temperature, req.TopP)
+	args.ClientKey = clientKey(r)
+	args.RequestID = requestID(w, r)
 
 	if req.Stream {
+		// Reject before the stream starts, with a proper status.
+		if err := s.service.CheckQuota(args.ClientKey); err != nil {
+			writeOAIPredictError(w, err)
+			return
+		}
 		s.handleV1ChatCompletionsStream(w, r, &req, prompt, args)
 	} else {
 		s.handleV1ChatCompletionsNonStream(w, r, &req, prompt, args)
@@ -302,14 +491,14 @@ func (s *Server) handleV1ChatCompletions(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Server) handleV1ChatCompletionsNonStream(w http.ResponseWriter, r *http.Request, req *oaiChatCompletionRequest, prompt string, args inferenceengine.PredictArgs) {
-	text, err := s.service.Predict(req.Model, prompt, args, nil)
+	res, err := s.service.Predict(r.Context(), req.Model, prompt, args, nil, nil)
 	if err != nil {
 		s.logger.Errorf("v1/chat/completions failed: %v", err)
-		writeOAIError(w, http.StatusInternalServerError, "server_error", err.Error())
+		writeOAIPredictError(w, err)
 		return
 	}
 
-	finishReason := "stop"
+	finishReason := res.FinishReason.String()
 	writeJSON(w, http.StatusOK, oaiChatCompletionResponse{
 		ID:      generateID("chatcmpl-"),
 		Object:  "chat.completion",
@@ -319,11 +508,11 @@ func (s *Server) handleV1ChatCompletionsNonStream(w http.ResponseWriter, r *http
 			Index: 0,
 			Message: &oaiChatChoiceMessage{
 				Role:    "assistant",
-				Content: text,
+				Content: res.Text,
 			},
 			FinishReason: &finishReason,
 		}},
-		Usage: &oaiUsage{},
+		Usage: newOAIUsage(res),
 	})
 }
 
@@ -340,8 +529,6 @@ func (s *Server) handleV1ChatCompletionsStream(w http.ResponseWriter, r *http.Re
 
 	id := generateID("chatcmpl-")
 	created := time.Now().Unix()
-	ctx := r.Context()
-
 	// First chunk: assistant role announcement
 	roleChunk := oaiChatCompletionResponse{
 		ID:      id,
@@ -357,12 +544,7 @@ func (s *Server) handleV1ChatCompletionsStream(w http.ResponseWriter, r *http.Re
 	fmt.Fprintf(w, "data: %s\n\n", data)
 	flusher.Flush()
 
-	streamFunc := func(token, tokens int, message string) error {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	streamFunc := func(_ []int, _, _ int, message string) error {
 		chunk := oaiChatCompletionResponse{
 			ID:      id,
 			Object:  "chat.completion.chunk",
@@ -379,14 +561,14 @@ func (s *Server) handleV1ChatCompletionsStream(w http.ResponseWriter, r *http.Re
 		return nil
 	}
 
-	_, err := s.service.Predict(req.Model, prompt, args, streamFunc)
+	res, err := s.service.Predict(r.Context(), req.Model, prompt, args, streamFunc, nil)
 	if err != nil {
 		s.logger.Errorf("v1/chat/completions streaming failed: %v", err)
 		return
 	}
 
 	// Final chunk: finish_reason with empty delta
-	finishReason := "stop"
+	finishReason := res.FinishReason.String()
 	final := oaiChatCompletionResponse{
 		ID:      id,
 		Object:  "chat.completion.chunk",
@@ -426,13 +608,55 @@ func buildOAIPredictArgs(maxTokens int, temperature, topP *float32) inferenceeng
 	return args
 }
 
+func newOAIUsage(res inferenceengine.Result) *oaiUsage {
+	return &oaiUsage{
+		PromptTokens:     res.PromptTokens,
+		CompletionTokens: res.CompletionTokens,
+		TotalTokens:      res.PromptTokens + res.CompletionTokens,
+	}
+}
+
 func writeOAIError(w http.ResponseWriter, status int, errType, message string) {
+	writeOAIErrorCode(w, status, errType, "", message)
+}
+
+// writeOAIErrorCode is writeOAIError with a machine-readable error code.
+func writeOAIErrorCode(w http.ResponseWriter, status int, errType, code, message string) {
+	detail := oaiErrorDetail{
+		Message: message,
+		Type:    errType,
+	}
+	if code != "" {
+		detail.Code = &code
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(oaiErrorResponse{
-		Error: oaiErrorDetail{
-			Message: message,
-			Type:    errType,
-		},
-	})
+	json.NewEncoder(w).Encode(oaiErrorResponse{Error: detail})
+}
+
+// writeOAIPredictError reports a failed non-streaming prediction or
+// embedding; a full request queue is a 429 so clients back off and retry.
+func writeOAIPredictError(w http.ResponseWriter, err error) {
+	var contextExceeded *inferenceengine.ContextExceededError
+	switch {
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeOAIErrorCode(w, http.StatusNotFound, "invalid_request_error", "model_not_found", err.Error())
+	case errors.As(err, &contextExceeded):
+		writeOAIErrorCode(w, http.StatusBadRequest, "invalid_request_error", "context_length_exceeded", err.Error())
+	case errors.Is(err, inferenceengine.ErrNothingToEmbed),
+		errors.Is(err, inferenceengine.ErrClassifierModel),
+		errors.Is(err, inferenceengine.ErrInvalidGrammar),
+		errors.Is(err, inferenceengine.ErrUnsupportedOption),
+		errors.Is(err, llamacppbindings.ErrInvalidText):
+		writeOAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+	case errors.Is(err, llmservice.ErrRequestRejected):
+		writeOAIErrorCode(w, http.StatusBadRequest, "invalid_request_error", "content_filter", err.Error())
+	case errors.Is(err, inferenceengine.ErrQueueFull):
+		writeOAIError(w, http.StatusTooManyRequests, "rate_limit_error", err.Error())
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		setRetryAfter(w, err)
+		writeOAIErrorCode(w, http.StatusTooManyRequests, "insufficient_quota", "insufficient_quota", err.Error())
+	default:
+		writeOAIError(w, http.StatusInternalServerError, "server_error", err.Error())
+	}
 }
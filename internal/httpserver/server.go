@@ -3,36 +3,72 @@ package httpserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/grammar"
 	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
 	"github.com/hypernetix/llamacpp_server/internal/llmservice"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
+	"github.com/hypernetix/llamacpp_server/internal/requestopts"
+	"github.com/hypernetix/llamacpp_server/internal/version"
 )
 
+// Options configures the HTTP server.
+type Options struct {
+	// PrefillKeepalive is the longest a /completions stream stays silent
+	// while its prompt is processed; the latest prefill event is repeated
+	// then. Zero disables keepalives.
+	PrefillKeepalive time.Duration
+}
+
 type Server struct {
 	service    *llmservice.Service
+	opts       Options
 	logger     logging.SprintfLogger
 	httpServer *http.Server
 }
 
-func NewServer(service *llmservice.Service, addr string, logger logging.SprintfLogger) *Server {
+func NewServer(service *llmservice.Service, addr string, opts Options, logger logging.SprintfLogger) *Server {
 	s := &Server{
 		service: service,
+		opts:    opts,
 		logger:  logger.With("module", "httpserver"),
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /version", s.handleVersion)
 	mux.HandleFunc("POST /models/load", s.handleLoadModel)
+	mux.HandleFunc("POST /models/cancel", s.handleCancelLoad)
+	mux.HandleFunc("GET /models/load-log", s.handleLoadLog)
+	mux.HandleFunc("GET /models/info", s.handleModelInfo)
 	mux.HandleFunc("POST /completions", s.handleCompletions)
+	mux.HandleFunc("POST /score", s.handleScore)
+	mux.HandleFunc("POST /embeddings", s.handleEmbeddings)
+	mux.HandleFunc("POST /classify", s.handleClassify)
+	mux.HandleFunc("POST /similarity", s.handleSimilarity)
+	mux.HandleFunc("POST /bench", s.handleBench)
+	mux.Handle("GET /metrics", metrics.Handler())
 
 	// OpenAI-compatible API (v1)
 	mux.HandleFunc("GET /v1/models", s.handleV1Models)
+	mux.HandleFunc("GET /v1/models/{model...}", s.handleV1Model)
+	mux.HandleFunc("POST /v1/embeddings", s.handleV1Embeddings)
 	mux.HandleFunc("POST /v1/completions", s.handleV1Completions)
 	mux.HandleFunc("POST /v1/chat/completions", s.handleV1ChatCompletions)
+	mux.HandleFunc("/v1/", s.handleV1NotFound)
 
 	s.httpServer = &http.Server{
 		Addr:    addr,
@@ -42,7 +78,6 @@ func NewServer(service *llmservice.Service, addr string, logger logging.SprintfL
 }
 
 func (s *Server) Start(listener net.Listener) error {
-	s.logger.Infof("HTTP server listening at %s", listener.Addr().String())
 	return s.httpServer.Serve(listener)
 }
 
@@ -52,18 +87,185 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 // --- Health ---
 
+// handleHealth answers 503 while the last health probe of a loaded model
+// failed, so that load balancers stop routing to the server.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !s.service.Healthy() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unhealthy"})
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// --- Status ---
+
+type tenantQueueStatus struct {
+	Tenant     string  `json:"tenant"`
+	Weight     float64 `json:"weight"`
+	QueueDepth int     `json:"queue_depth"`
+}
+
+type statusResponse struct {
+	NParallel             int                 `json:"n_parallel"`
+	MaxConcurrentRequests int                 `json:"max_concurrent_requests"`
+	MaxQueue              int                 `json:"max_queue"`
+	ActiveSlots           int                 `json:"active_slots"`
+	QueueDepth            int                 `json:"queue_depth"`
+	KvCells               int                 `json:"kv_cells"`
+	KvCellsUsed           int                 `json:"kv_cells_used"`
+	Tenants               []tenantQueueStatus `json:"tenants"`
+	LoadedModels          []string            `json:"loaded_models"`
+	SystemInfo            systemInfo          `json:"system_info"`
+	ModelHealth           []modelHealth       `json:"model_health"`
+}
+
+type modelHealth struct {
+	Model           string  `json:"model"`
+	Healthy         bool    `json:"healthy"`
+	Error           string  `json:"error,omitempty"`
+	LatencyMs       float64 `json:"latency_ms"`
+	CheckedAtUnixMs int64   `json:"checked_at_unix_ms"`
+}
+
+type systemInfo struct {
+	CPUFeatures []string `json:"cpu_features"`
+	Devices     []device `json:"devices"`
+	Raw         string   `json:"raw"`
+}
+
+type device struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	MemoryFree  uint64 `json:"memory_free"`
+	MemoryTotal uint64 `json:"memory_total"`
+}
+
+func newSystemInfo(c llamacppbindings.Capabilities) systemInfo {
+	info := systemInfo{
+		CPUFeatures: c.CPUFeatures,
+		Devices:     make([]device, 0, len(c.Devices)),
+		Raw:         c.SystemInfo,
+	}
+	for _, d := range c.Devices {
+		info.Devices = append(info.Devices, device{
+			Name:        d.Name,
+			Description: d.Description,
+			Type:        string(d.Type),
+			MemoryFree:  d.MemoryFree,
+			MemoryTotal: d.MemoryTotal,
+		})
+	}
+	return info
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	stats := s.service.Stats()
+	resp := statusResponse{
+		NParallel:             stats.NParallel,
+		MaxConcurrentRequests: stats.MaxConcurrent,
+		MaxQueue:              stats.MaxQueue,
+		ActiveSlots:           stats.ActiveSlots,
+		QueueDepth:            stats.QueueDepth,
+		KvCells:               stats.KvCells,
+		KvCellsUsed:           stats.KvCellsUsed,
+		Tenants:               make([]tenantQueueStatus, 0, len(stats.Tenants)),
+		LoadedModels:          s.service.ListModelsFor(clientKey(r)),
+		SystemInfo:            newSystemInfo(llamacppbindings.DetectCapabilities()),
+		ModelHealth:           []modelHealth{},
+	}
+	for _, t := range stats.Tenants {
+		resp.Tenants = append(resp.Tenants, tenantQueueStatus{
+			Tenant:     t.Tenant,
+			Weight:     t.Weight,
+			QueueDepth: t.Depth,
+		})
+	}
+	for _, h := range s.service.ModelHealth(clientKey(r)) {
+		resp.ModelHealth = append(resp.ModelHealth, modelHealth{
+			Model:           h.Model,
+			Healthy:         h.Healthy,
+			Error:           h.Error,
+			LatencyMs:       durationMs(h.Latency),
+			CheckedAtUnixMs: h.CheckedAt.UnixMilli(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// --- Version ---
+
+type versionResponse struct {
+	Version         string   `json:"version"`
+	GitCommit       string   `json:"git_commit"`
+	GoVersion       string   `json:"go_version"`
+	LlamaCppVersion string   `json:"llama_cpp_version"`
+	Backends        []string `json:"backends"`
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	info := version.Get()
+	writeJSON(w, http.StatusOK, versionResponse{
+		Version:         info.Version,
+		GitCommit:       info.Commit,
+		GoVersion:       info.GoVersion,
+		LlamaCppVersion: info.LlamaCppVersion,
+		Backends:        llamacppbindings.Backends(),
+	})
+}
+
 // --- Load Model ---
 
 type loadModelRequest struct {
 	Path string `json:"path"`
+
+	// Per-model overrides of the server flags.
+	NGpuLayers  *int   `json:"n_gpu_layers,omitempty"`
+	UseMmap     *bool  `json:"use_mmap,omitempty"`
+	UseMlock    *bool  `json:"use_mlock,omitempty"`
+	KvCacheType string `json:"kv_cache_type,omitempty"`
+	CtxSize     int    `json:"ctx_size,omitempty"`
+
+	MaxBatchTokens int `json:"max_batch_tokens,omitempty"`
+	MaxBatchSeqs   int `json:"max_batch_seqs,omitempty"`
+	BatchWaitMs    int `json:"batch_wait_ms,omitempty"`
 }
 
 type loadModelEvent struct {
-	Progress float32 `json:"progress"`
+	Progress    float32       `json:"progress"`
+	Stage       string        `json:"stage"`
+	BytesLoaded int64         `json:"bytes_loaded"`
+	BytesTotal  int64         `json:"bytes_total"`
+	EtaMs       float64       `json:"eta_ms"`
+	Warnings    []loadWarning `json:"warnings,omitempty"`
+}
+
+type loadWarning struct {
+	Reason   string            `json:"reason"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func newLoadModelEvent(progress modelmanagement.LoadProgress) loadModelEvent {
+	return loadModelEvent{
+		Progress:    progress.Fraction,
+		Stage:       progress.Stage.String(),
+		BytesLoaded: progress.BytesLoaded,
+		BytesTotal:  progress.BytesTotal,
+		EtaMs:       durationMs(progress.ETA),
+		Warnings:    newLoadWarnings(progress.Warnings),
+	}
+}
+
+func newLoadWarnings(warnings []modelmanagement.LoadWarning) []loadWarning {
+	if len(warnings) == 0 {
+		return nil
+	}
+	out := make([]loadWarning, len(warnings))
+	for i, w := range warnings {
+		out[i] = loadWarning{Reason: w.Reason, Message: w.Message, Metadata: w.Metadata}
+	}
+	return out
 }
 
 func (s *Server) handleLoadModel(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +278,27 @@ func (s *Server) handleLoadModel(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "path is required")
 		return
 	}
+	if !s.checkAccess(w, r, req.Path) {
+		return
+	}
+
+	overrides := modelmanagement.LoadOverrides{
+		NGpuLayers:  req.NGpuLayers,
+		UseMmap:     req.UseMmap,
+		UseMlock:    req.UseMlock,
+		KvCacheType: req.KvCacheType,
+		CtxSize:     req.CtxSize,
+
+		MaxBatchTokens: req.MaxBatchTokens,
+		MaxBatchSeqs:   req.MaxBatchSeqs,
+		BatchWait:      time.Duration(req.BatchWaitMs) * time.Millisecond,
+	}
+	// Checked before the event stream starts so that it is answered with a
+	// plain 400 rather than an error event.
+	if err := llmservice.ValidateOverrides(overrides); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -87,13 +310,13 @@ func (s *Server) handleLoadModel(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	onProgress := func(progress float32) {
-		data, _ := json.Marshal(loadModelEvent{Progress: progress})
+	onProgress := func(progress modelmanagement.LoadProgress) {
+		data, _ := json.Marshal(newLoadModelEvent(progress))
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		flusher.Flush()
 	}
 
-	err := s.service.LoadModel(req.Path, onProgress)
+	err := s.service.LoadModel(r.Context(), req.Path, overrides, onProgress)
 	if err != nil {
 		s.logger.Errorf("LoadModel failed: %v", err)
 		fmt.Fprintf(w, "event: error\ndata: %s\n\n", jsonString(err.Error()))
@@ -105,6 +328,128 @@ func (s *Server) handleLoadModel(w http.ResponseWriter, r *http.Request) {
 	flusher.Flush()
 }
 
+// --- Cancel Load ---
+
+func (s *Server) handleCancelLoad(w http.ResponseWriter, r *http.Request) {
+	var req loadModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	if !s.checkAccess(w, r, req.Path) {
+		return
+	}
+
+	err := s.service.CancelLoad(req.Path)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "canceling"})
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+	case errors.Is(err, modelmanagement.ErrModelNotLoading):
+		writeError(w, http.StatusConflict, "%v", err)
+	default:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+	}
+}
+
+// --- Load Log ---
+
+func (s *Server) handleLoadLog(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	if !s.checkAccess(w, r, path) {
+		return
+	}
+
+	lines, err := s.service.LoadLog(path)
+	switch {
+	case err == nil:
+		if lines == nil {
+			lines = []string{}
+		}
+		writeJSON(w, http.StatusOK, map[string][]string{"lines": lines})
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+	case errors.Is(err, modelmanagement.ErrModelLoading):
+		writeError(w, http.StatusConflict, "%v", err)
+	default:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+	}
+}
+
+// --- Model info ---
+
+type specialToken struct {
+	ID    int    `json:"id"`
+	Piece string `json:"piece"`
+}
+
+type modelInfoResponse struct {
+	Description string        `json:"description"`
+	Size        uint64        `json:"size"`
+	NParams     uint64        `json:"n_params"`
+	AddBOS      bool          `json:"add_bos"`
+	Bos         *specialToken `json:"bos,omitempty"`
+	Eos         *specialToken `json:"eos,omitempty"`
+	Eot         *specialToken `json:"eot,omitempty"`
+	NCtxTrain   int           `json:"n_ctx_train"`
+	NEmbd       int           `json:"n_embd"`
+	NLayer      int           `json:"n_layer"`
+	NHead       int           `json:"n_head"`
+	RopeType    string        `json:"rope_type"`
+}
+
+func newSpecialToken(t *llmservice.SpecialToken) *specialToken {
+	if t == nil {
+		return nil
+	}
+	return &specialToken{ID: t.ID, Piece: t.Piece}
+}
+
+func (s *Server) handleModelInfo(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	if !s.checkAccess(w, r, path) {
+		return
+	}
+
+	info, err := s.service.ModelInfo(path)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, modelInfoResponse{
+			Description: info.Desc,
+			Size:        info.Size,
+			NParams:     info.NParams,
+			AddBOS:      info.AddBOS,
+			Bos:         newSpecialToken(info.Bos),
+			Eos:         newSpecialToken(info.Eos),
+			Eot:         newSpecialToken(info.Eot),
+			NCtxTrain:   info.NCtxTrain,
+			NEmbd:       info.NEmbd,
+			NLayer:      info.NLayer,
+			NHead:       info.NHead,
+			RopeType:    info.RopeType.String(),
+		})
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+	case errors.Is(err, modelmanagement.ErrModelLoading):
+		writeError(w, http.StatusConflict, "%v", err)
+	default:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+	}
+}
+
 // --- Completions ---
 
 type completionRequest struct {
@@ -131,12 +476,122 @@ type completionOptions struct {
 	DiversityPenalty  *float32 `json:"diversity_penalty,omitempty"`
 	NoRepeatNgramSize *int32   `json:"no_repeat_ngram_size,omitempty"`
 	RandomSeed        *int32   `json:"random_seed,omitempty"`
+
+	StreamIntervalTokens *int32 `json:"stream_interval_tokens,omitempty"`
+	StreamIntervalMs     *int32 `json:"stream_interval_ms,omitempty"`
+
+	PromptLookup  *int32   `json:"prompt_lookup,omitempty"`
+	Regex         *string  `json:"regex,omitempty"`
+	StopTokenIDs  []int    `json:"stop_token_ids,omitempty"`
+	BannedStrings []string `json:"banned_strings,omitempty"`
+	SpecialTokens string   `json:"special_tokens,omitempty"` // render, skip or event
+
+	BeamWidth   *int32 `json:"beam_width,omitempty"`
+	ReturnBeams bool   `json:"return_beams,omitempty"`
+
+	MaxTokensPolicy string `json:"max_tokens_policy,omitempty"` // clamp or reject
+}
+
+// GetStreamIntervalTokens and GetStreamIntervalMs implement
+// requestopts.StreamInterval like the getters of the gRPC options.
+func (o *completionOptions) GetStreamIntervalTokens() int32 {
+	if o != nil && o.StreamIntervalTokens != nil {
+		return *o.StreamIntervalTokens
+	}
+	return 0
+}
+
+func (o *completionOptions) GetStreamIntervalMs() int32 {
+	if o != nil && o.StreamIntervalMs != nil {
+		return *o.StreamIntervalMs
+	}
+	return 0
 }
 
 type completionResponse struct {
 	Message string `json:"message"`
 	Token   int    `json:"token"`
 	Tokens  int    `json:"tokens"`
+	// TokenIDs are set on streamed chunks: the tokens the message was
+	// decoded from, several if they were coalesced.
+	TokenIDs []int `json:"token_ids,omitempty"`
+
+	// On streamed chunks, the counts so far; otherwise the totals.
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+
+	// Set on non-streaming responses only.
+	FinishReason string           `json:"finish_reason,omitempty"`
+	Timings      *timingsResponse `json:"timings,omitempty"`
+
+	DraftTokens         int `json:"draft_tokens,omitempty"`
+	DraftAcceptedTokens int `json:"draft_accepted_tokens,omitempty"`
+
+	// Cached is set when the completion was replayed from the completion
+	// cache.
+	Cached bool `json:"cached,omitempty"`
+	// Annotations are added by the server's post-processing hooks.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Beams are all finished beams of a beam search with return_beams.
+	Beams []beamResponse `json:"beams,omitempty"`
+
+	// MaxTokens is the token limit the completion ran with. MaxTokensReason
+	// is "context" if it was lowered to the room the prompt left, otherwise
+	// "requested".
+	MaxTokens       int    `json:"max_tokens,omitempty"`
+	MaxTokensReason string `json:"max_tokens_reason,omitempty"`
+}
+
+type beamResponse struct {
+	Text         string  `json:"text"`
+	Tokens       int     `json:"tokens"`
+	Logprob      float64 `json:"logprob"`
+	Score        float64 `json:"score"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+func newBeamsResponse(beams []inferenceengine.Beam) []beamResponse {
+	var out []beamResponse
+	for _, b := range beams {
+		out = append(out, beamResponse{
+			Text:         b.Text,
+			Tokens:       b.Tokens,
+			Logprob:      b.LogProb,
+			Score:        b.Score,
+			FinishReason: b.FinishReason.String(),
+		})
+	}
+	return out
+}
+
+type timingsResponse struct {
+	QueueMs            float64             `json:"queue_ms"`
+	TimeToFirstTokenMs float64             `json:"time_to_first_token_ms"`
+	TotalMs            float64             `json:"total_ms"`
+	InterTokenLatency  latencyDistribution `json:"inter_token_latency"`
+}
+
+type latencyDistribution struct {
+	Count  int     `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+type prefillEvent struct {
+	Processed int `json:"processed"`
+	Total     int `json:"total"`
+}
+
+// specialTokenEvent is a control token streamed in special_tokens=event mode.
+type specialTokenEvent struct {
+	Token            int    `json:"token"`
+	Tokens           int    `json:"tokens"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Piece            string `json:"piece"`
 }
 
 func (s *Server) handleCompletions(w http.ResponseWriter, r *http.Request) {
@@ -145,16 +600,55 @@ func (s *Server) handleCompletions(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
 		return
 	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
 
-	s.logger.Infof("Completions: model=%s, max_tokens=%d, stream=%v, temp=%.3f",
-		req.Model, req.MaxTokens, req.Stream, req.Temperature)
+	args := buildPredictArgs(&req)
+	s.logger.Infof("Completions: model=%s, stream=%v, %s",
+		req.Model, req.Stream, strings.Join(args.ActiveOptions(), ", "))
 
 	if req.MaxTokens == 0 {
 		writeJSON(w, http.StatusOK, completionResponse{})
 		return
 	}
 
-	args := buildPredictArgs(&req)
+	args.ClientKey = clientKey(r)
+	args.RequestID = requestID(w, r)
+	if err := args.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if args.Regex != "" {
+		// Fail before a stream starts rather than in it.
+		if _, err := grammar.FromRegex(args.Regex); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid regex: %v", err)
+			return
+		}
+	}
+	if !s.checkAccess(w, r, req.Model) {
+		return
+	}
+	if err := s.service.CheckQuota(args.ClientKey); err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+	if req.Options != nil && req.Options.SpecialTokens != "" {
+		mode, err := inferenceengine.ParseSpecialTokens(req.Options.SpecialTokens)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		args.SpecialTokens = mode
+	}
+	if req.Options != nil && req.Options.MaxTokensPolicy != "" {
+		policy, err := inferenceengine.ParseMaxTokensPolicy(req.Options.MaxTokensPolicy)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		args.MaxTokensPolicy = policy
+	}
 
 	if req.Stream {
 		s.handleStreamingCompletion(w, r, &req, args)
@@ -174,45 +668,461 @@ func (s *Server) handleStreamingCompletion(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	ctx := r.Context()
+	events := newEventStream(w, flusher, s.opts.PrefillKeepalive)
+	defer events.Close()
 
-	streamFunc := func(token, tokens int, message string) error {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	streamFunc := func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
 		data, _ := json.Marshal(completionResponse{
-			Message: message,
-			Token:   token,
-			Tokens:  tokens,
+			Message:          message,
+			Token:            tokenIDs[len(tokenIDs)-1],
+			Tokens:           promptTokens + completionTokens,
+			TokenIDs:         tokenIDs,
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
 		})
-		fmt.Fprintf(w, "data: %s\n\n", data)
-		flusher.Flush()
+		events.SendToken("", data)
 		return nil
 	}
 
-	_, err := s.service.Predict(req.Model, req.Prompt, args, streamFunc)
+	args.PrefillProgress = events.Progress
+
+	// Only sent when the server auto-loads the model for this request.
+	onLoad := func(progress modelmanagement.LoadProgress) {
+		data, _ := json.Marshal(newLoadModelEvent(progress))
+		events.Send("load", data)
+	}
+
+	coalescer := requestopts.NewCoalescer(streamFunc, req.Options)
+	if coalescer.Enabled() {
+		streamFunc = coalescer.Stream
+	}
+
+	args.SpecialToken = func(token, promptTokens, completionTokens int, piece string) error {
+		// Text held back by the coalescer goes first.
+		if err := coalescer.Flush(); err != nil {
+			return err
+		}
+		data, _ := json.Marshal(specialTokenEvent{
+			Token:            token,
+			Tokens:           promptTokens + completionTokens,
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			Piece:            piece,
+		})
+		events.SendToken("special", data)
+		return nil
+	}
+
+	result, err := s.service.Predict(r.Context(), req.Model, req.Prompt, args, streamFunc, onLoad)
+	if err == nil {
+		err = coalescer.Flush()
+	}
 	if err != nil {
 		s.logger.Errorf("Completions streaming failed: %v", err)
-		fmt.Fprintf(w, "event: error\ndata: %s\n\n", jsonString(err.Error()))
-		flusher.Flush()
+		events.Send("error", []byte(jsonString(err.Error())))
 		return
 	}
 
-	fmt.Fprintf(w, "data: [DONE]\n\n")
-	flusher.Flush()
+	data, _ := json.Marshal(newTimingsResponse(result.Timings))
+	events.Send("timings", data)
+	if len(result.Annotations) > 0 {
+		data, _ := json.Marshal(result.Annotations)
+		events.Send("annotations", data)
+	}
+	events.Send("", []byte("[DONE]"))
 }
 
 func (s *Server) handleNonStreamingCompletion(w http.ResponseWriter, r *http.Request, req *completionRequest, args inferenceengine.PredictArgs) {
-	response, err := s.service.Predict(req.Model, req.Prompt, args, nil)
-	if err != nil {
+	result, err := s.service.Predict(r.Context(), req.Model, req.Prompt, args, nil, nil)
+	var contextExceeded *inferenceengine.ContextExceededError
+	switch {
+	case err == nil:
+	case errors.Is(err, inferenceengine.ErrQueueFull):
+		writeError(w, http.StatusTooManyRequests, "%v", err)
+		return
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		writeQuotaError(w, err)
+		return
+	case errors.Is(err, llamacppbindings.ErrInvalidText),
+		errors.Is(err, inferenceengine.ErrBeamUnsupported),
+		errors.Is(err, inferenceengine.ErrUnsupportedOption),
+		errors.Is(err, llmservice.ErrRequestRejected),
+		errors.As(err, &contextExceeded):
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	default:
 		s.logger.Errorf("Completions failed: %v", err)
 		writeError(w, http.StatusInternalServerError, "prediction failed: %v", err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, completionResponse{Message: response})
+	writeJSON(w, http.StatusOK, completionResponse{
+		Message:          result.Text,
+		Tokens:           result.PromptTokens + result.CompletionTokens,
+		PromptTokens:     result.PromptTokens,
+		CompletionTokens: result.CompletionTokens,
+		FinishReason:     result.FinishReason.String(),
+		Timings:          newTimingsResponse(result.Timings),
+
+		DraftTokens:         result.DraftTokens,
+		DraftAcceptedTokens: result.DraftAcceptedTokens,
+		Cached:              result.Cached,
+		Annotations:         result.Annotations,
+		Beams:               newBeamsResponse(result.Beams),
+
+		MaxTokens:       result.MaxTokens,
+		MaxTokensReason: result.MaxTokensReason.String(),
+	})
+}
+
+func newTimingsResponse(t inferenceengine.Timings) *timingsResponse {
+	return &timingsResponse{
+		QueueMs:            durationMs(t.QueueTime),
+		TimeToFirstTokenMs: durationMs(t.TimeToFirstToken),
+		TotalMs:            durationMs(t.TotalTime),
+		InterTokenLatency: latencyDistribution{
+			Count:  t.TokenLatency.Count,
+			MeanMs: durationMs(t.TokenLatency.Mean),
+			P50Ms:  durationMs(t.TokenLatency.P50),
+			P90Ms:  durationMs(t.TokenLatency.P90),
+			P99Ms:  durationMs(t.TokenLatency.P99),
+			MaxMs:  durationMs(t.TokenLatency.Max),
+		},
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
+
+// --- Score ---
+
+type scoreRequest struct {
+	Model         string `json:"model"`
+	Text          string `json:"text"`
+	TokenLogprobs bool   `json:"token_logprobs"`
+}
+
+type scoreResponse struct {
+	Tokens        int       `json:"tokens"`
+	LogLikelihood float64   `json:"log_likelihood"`
+	Perplexity    float64   `json:"perplexity"`
+	TokenLogprobs []float32 `json:"token_logprobs,omitempty"`
+	TotalMs       float64   `json:"total_ms"`
+}
+
+func (s *Server) handleScore(w http.ResponseWriter, r *http.Request) {
+	var req scoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if !s.checkAccess(w, r, req.Model) {
+		return
+	}
+
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(r),
+		RequestID: requestID(w, r),
+	}
+	res, err := s.service.Score(r.Context(), req.Model, req.Text, args)
+	var contextExceeded *inferenceengine.ContextExceededError
+	switch {
+	case err == nil:
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		writeQuotaError(w, err)
+		return
+	case errors.Is(err, inferenceengine.ErrNothingToScore), errors.As(err, &contextExceeded),
+		errors.Is(err, llamacppbindings.ErrInvalidText):
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		s.logger.Errorf("Score failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "score failed: %v", err)
+		return
+	}
+
+	resp := scoreResponse{
+		Tokens:        res.Tokens,
+		LogLikelihood: res.LogLikelihood,
+		Perplexity:    res.Perplexity,
+		TotalMs:       durationMs(res.TotalTime),
+	}
+	if req.TokenLogprobs {
+		resp.TokenLogprobs = res.TokenLogprobs
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Embeddings   [][]float32 `json:"embeddings"`
+	PromptTokens int         `json:"prompt_tokens"`
+	TotalMs      float64     `json:"total_ms"`
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req embeddingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if !s.checkAccess(w, r, req.Model) {
+		return
+	}
+
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(r),
+		RequestID: requestID(w, r),
+	}
+	res, err := s.service.Embed(r.Context(), req.Model, req.Input, args)
+	var contextExceeded *inferenceengine.ContextExceededError
+	switch {
+	case err == nil:
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		writeQuotaError(w, err)
+		return
+	case errors.Is(err, inferenceengine.ErrNothingToEmbed), errors.As(err, &contextExceeded),
+		errors.Is(err, llamacppbindings.ErrInvalidText),
+		errors.Is(err, inferenceengine.ErrClassifierModel):
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		s.logger.Errorf("Embeddings failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "embeddings failed: %v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, embeddingsResponse{
+		Embeddings:   res.Embeddings,
+		PromptTokens: res.PromptTokens,
+		TotalMs:      durationMs(res.TotalTime),
+	})
+}
+
+type classifyRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type labelScore struct {
+	Label       string  `json:"label"`
+	Score       float32 `json:"score"`
+	Probability float32 `json:"probability"`
+}
+
+type classifyResponse struct {
+	Results      [][]labelScore `json:"results"`
+	PromptTokens int            `json:"prompt_tokens"`
+	TotalMs      float64        `json:"total_ms"`
+}
+
+func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
+	var req classifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if !s.checkAccess(w, r, req.Model) {
+		return
+	}
+
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(r),
+		RequestID: requestID(w, r),
+	}
+	res, err := s.service.Classify(r.Context(), req.Model, req.Input, args)
+	var contextExceeded *inferenceengine.ContextExceededError
+	switch {
+	case err == nil:
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		writeQuotaError(w, err)
+		return
+	case errors.Is(err, inferenceengine.ErrNothingToEmbed), errors.As(err, &contextExceeded),
+		errors.Is(err, llamacppbindings.ErrInvalidText),
+		errors.Is(err, inferenceengine.ErrNotClassifier):
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		s.logger.Errorf("Classify failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "classify failed: %v", err)
+		return
+	}
+
+	resp := classifyResponse{
+		Results:      make([][]labelScore, len(res.Labels)),
+		PromptTokens: res.PromptTokens,
+		TotalMs:      durationMs(res.TotalTime),
+	}
+	for i, labels := range res.Labels {
+		for _, l := range labels {
+			resp.Results[i] = append(resp.Results[i], labelScore{Label: l.Label, Score: l.Score, Probability: l.Probability})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type similarityRequest struct {
+	Model      string   `json:"model"`
+	Query      string   `json:"query"`
+	Candidates []string `json:"candidates"`
+}
+
+type similarityResponse struct {
+	Scores       []float32 `json:"scores"`
+	PromptTokens int       `json:"prompt_tokens"`
+	TotalMs      float64   `json:"total_ms"`
+}
+
+func (s *Server) handleSimilarity(w http.ResponseWriter, r *http.Request) {
+	var req similarityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if !s.checkAccess(w, r, req.Model) {
+		return
+	}
+
+	args := inferenceengine.PredictArgs{
+		ClientKey: clientKey(r),
+		RequestID: requestID(w, r),
+	}
+	res, err := s.service.Similarity(r.Context(), req.Model, req.Query, req.Candidates, args)
+	var contextExceeded *inferenceengine.ContextExceededError
+	switch {
+	case err == nil:
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		writeQuotaError(w, err)
+		return
+	case errors.Is(err, inferenceengine.ErrNothingToEmbed), errors.As(err, &contextExceeded),
+		errors.Is(err, llamacppbindings.ErrInvalidText),
+		errors.Is(err, inferenceengine.ErrClassifierModel):
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		s.logger.Errorf("Similarity failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "similarity failed: %v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, similarityResponse{
+		Scores:       res.Scores,
+		PromptTokens: res.PromptTokens,
+		TotalMs:      durationMs(res.TotalTime),
+	})
+}
+
+type benchRequest struct {
+	Model       string `json:"model"`
+	NPrompt     int    `json:"n_prompt"`
+	NGen        int    `json:"n_gen"`
+	Repetitions int    `json:"repetitions"`
+}
+
+type benchResult struct {
+	Test            string    `json:"test"`
+	NPrompt         int       `json:"n_prompt"`
+	NGen            int       `json:"n_gen"`
+	TokensPerSecond float64   `json:"tokens_per_second"`
+	Stddev          float64   `json:"stddev"`
+	SamplesMs       []float64 `json:"samples_ms"`
+}
+
+func (s *Server) handleBench(w http.ResponseWriter, r *http.Request) {
+	var req benchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.service.ResolveModel("")
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if !s.checkAccess(w, r, req.Model) {
+		return
+	}
+
+	results, err := s.service.Bench(r.Context(), req.Model, inferenceengine.BenchOptions{
+		NPrompt:     req.NPrompt,
+		NGen:        req.NGen,
+		Repetitions: req.Repetitions,
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, modelmanagement.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	default:
+		s.logger.Errorf("Bench failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "bench failed: %v", err)
+		return
+	}
+
+	resp := struct {
+		Results []benchResult `json:"results"`
+	}{Results: []benchResult{}}
+	for _, res := range results {
+		out := benchResult{
+			Test:            res.Test,
+			NPrompt:         res.NPrompt,
+			NGen:            res.NGen,
+			TokensPerSecond: res.TokensPerSecond,
+			Stddev:          res.StdDev,
+		}
+		for _, d := range res.Samples {
+			out.SamplesMs = append(out.SamplesMs, durationMs(d))
+		}
+		resp.Results = append(resp.Results, out)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func buildPredictArgs(req *completionRequest) inferenceengine.PredictArgs {
@@ -314,12 +1224,41 @@ func buildPredictArgs(req *completionRequest) inferenceengine.PredictArgs {
 	if opts.RandomSeed != nil {
 		args.RandomSeed = int(*opts.RandomSeed)
 	}
+	if opts.PromptLookup != nil {
+		args.PromptLookup = max(int(*opts.PromptLookup), 0)
+	}
+	if opts.Regex != nil {
+		args.Regex = *opts.Regex
+	}
+	if opts.BeamWidth != nil {
+		args.BeamWidth = max(int(*opts.BeamWidth), 0)
+	}
+	args.ReturnBeams = opts.ReturnBeams
+	args.StopTokenIDs = opts.StopTokenIDs
+	args.BannedStrings = opts.BannedStrings
 
 	return args
 }
 
 // --- Helpers ---
 
+// clientKey returns the caller's API key from the X-Api-Key header or a
+// bearer Authorization header. Empty if the caller is anonymous.
+func clientKey(r *http.Request) string {
+	return requestopts.ClientKey(r.Header.Get("X-Api-Key"), r.Header.Get("Authorization"))
+}
+
+// requestID returns the X-Request-Id header, or a new random ID if the client
+// sent none, and echoes it in the response.
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	id := r.Header.Get("X-Request-Id")
+	if id == "" {
+		id = generateID("")
+	}
+	w.Header().Set("X-Request-Id", id)
+	return id
+}
+
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -333,6 +1272,32 @@ func writeError(w http.ResponseWriter, status int, format string, args ...interf
 	json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
+// checkAccess answers 403 and returns false if the caller's API key may not
+// use model.
+func (s *Server) checkAccess(w http.ResponseWriter, r *http.Request, model string) bool {
+	if err := s.service.CheckAccess(clientKey(r), model); err != nil {
+		writeError(w, http.StatusForbidden, "%v", err)
+		return false
+	}
+	return true
+}
+
+// writeQuotaError rejects a request whose client has used up its token
+// budget with 429 and a Retry-After header.
+func writeQuotaError(w http.ResponseWriter, err error) {
+	setRetryAfter(w, err)
+	writeError(w, http.StatusTooManyRequests, "%v", err)
+}
+
+// setRetryAfter sets Retry-After to the seconds until a quota resets.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		seconds := int(math.Ceil(time.Until(exceeded.ResetAt).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 0)))
+	}
+}
+
 func jsonString(s string) string {
 	b, _ := json.Marshal(s)
 	return string(b)
@@ -0,0 +1,119 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// eventStream writes the SSE events of a streaming completion. Until the
+// first token it repeats the latest prefill progress after every keepalive
+// interval of silence, like the gRPC Predict stream, so proxies don't time
+// out a stream that is busy on a long prompt.
+type eventStream struct {
+	w       io.Writer
+	flusher http.Flusher
+
+	mx       sync.Mutex
+	prefill  prefillEvent
+	lastSent time.Time
+	ticker   *time.Ticker // nil once tokens are flowing
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newEventStream(w io.Writer, flusher http.Flusher, keepalive time.Duration) *eventStream {
+	s := &eventStream{
+		w:        w,
+		flusher:  flusher,
+		lastSent: time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if keepalive <= 0 {
+		close(s.done)
+		return s
+	}
+	s.ticker = time.NewTicker(keepalive / 2)
+	go s.loop(s.ticker.C, keepalive)
+	return s
+}
+
+func (s *eventStream) loop(tick <-chan time.Time, keepalive time.Duration) {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-tick:
+			s.mx.Lock()
+			// A tick may still be buffered after the first token.
+			if s.ticker != nil && time.Since(s.lastSent) >= keepalive {
+				data, _ := json.Marshal(s.prefill)
+				s.writeLocked("prefill", data)
+			}
+			s.mx.Unlock()
+		}
+	}
+}
+
+// Send writes an event; an empty name sends a plain data message.
+func (s *eventStream) Send(event string, data []byte) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.writeLocked(event, data)
+}
+
+// SendToken writes an event carrying generated tokens. Keepalives stop under
+// the same lock, so none is written after it.
+func (s *eventStream) SendToken(event string, data []byte) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.stopKeepalivesLocked()
+	s.writeLocked(event, data)
+}
+
+// Progress records and sends prefill progress; it is a PrefillProgressFunc.
+func (s *eventStream) Progress(processed, total int) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.prefill = prefillEvent{Processed: processed, Total: total}
+	data, _ := json.Marshal(s.prefill)
+	s.writeLocked("prefill", data)
+	return nil
+}
+
+// Close stops keepalives and waits for their goroutine. It must be called
+// once, before the handler returns.
+func (s *eventStream) Close() {
+	s.mx.Lock()
+	s.stopKeepalivesLocked()
+	s.mx.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	close(s.stop)
+	<-s.done
+}
+
+func (s *eventStream) stopKeepalivesLocked() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+		s.ticker = nil
+	}
+}
+
+func (s *eventStream) writeLocked(event string, data []byte) {
+	if event != "" {
+		fmt.Fprintf(s.w, "event: %s\n", event)
+	}
+	fmt.Fprintf(s.w, "data: %s\n\n", data)
+	s.flusher.Flush()
+	s.lastSent = time.Now()
+}
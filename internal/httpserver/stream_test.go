@@ -0,0 +1,72 @@
+package httpserver
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordWriter keeps what was flushed to it.
+type recordWriter struct {
+	mu      sync.Mutex
+	pending strings.Builder
+	flushed string
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending.Write(p)
+}
+
+func (w *recordWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushed += w.pending.String()
+	w.pending.Reset()
+}
+
+func (w *recordWriter) events() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Split(strings.TrimSuffix(w.flushed, "\n\n"), "\n\n")
+}
+
+func TestEventStreamRepeatsPrefillUntilFirstToken(t *testing.T) {
+	w := &recordWriter{}
+	s := newEventStream(w, w, 20*time.Millisecond)
+	require.NoError(t, s.Progress(512, 2048))
+
+	// The silent stream repeats the latest progress.
+	require.Eventually(t, func() bool { return len(w.events()) >= 3 }, time.Second, time.Millisecond)
+	for _, ev := range w.events() {
+		require.Equal(t, "event: prefill\ndata: {\"processed\":512,\"total\":2048}", ev)
+	}
+
+	s.SendToken("", []byte(`{"message":"Hi"}`))
+	n := len(w.events())
+	time.Sleep(60 * time.Millisecond)
+	s.Send("", []byte("[DONE]"))
+	s.Close()
+
+	events := w.events()
+	require.Len(t, events, n+1)
+	require.Equal(t, `data: {"message":"Hi"}`, events[n-1])
+	require.Equal(t, "data: [DONE]", events[n])
+}
+
+func TestEventStreamWithoutKeepalive(t *testing.T) {
+	w := &recordWriter{}
+	s := newEventStream(w, w, 0)
+	require.NoError(t, s.Progress(1, 2))
+	time.Sleep(20 * time.Millisecond)
+	s.Send("error", []byte(`"failed"`))
+	s.Close()
+	require.Equal(t, []string{
+		"event: prefill\ndata: {\"processed\":1,\"total\":2}",
+		"event: error\ndata: \"failed\"",
+	}, w.events())
+}
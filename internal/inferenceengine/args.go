@@ -0,0 +1,104 @@
+package inferenceengine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedOption is returned for a prediction option the engine does
+// not implement or a value out of its range.
+var ErrUnsupportedOption = errors.New("unsupported option")
+
+// Validate rejects args the engine cannot honor. KV cache quantization is
+// a property of the shared context, chosen per model when it is loaded, so
+// KvBits, KvGroupSize and QuantizedKvStart must be zero, and diverse beam
+// search is not implemented, so DiversityPenalty must be too. A
+// MinTokensToKeep above TopK contradicts it and is rejected too.
+func (a PredictArgs) Validate() error {
+	switch {
+	case a.KvBits != 0, a.KvGroupSize != 0, a.QuantizedKvStart != 0:
+		return fmt.Errorf("%w: kv_bits, kv_group_size and quantized_kv_start (the KV cache type is set per model when it is loaded)", ErrUnsupportedOption)
+	case a.DiversityPenalty != 0:
+		return fmt.Errorf("%w: diversity_penalty", ErrUnsupportedOption)
+	case a.MinTokensToKeep < 0:
+		return fmt.Errorf("%w: negative min_tokens_to_keep", ErrUnsupportedOption)
+	case a.BeamWidth <= 1 && a.TopK > 0 && a.MinTokensToKeep > int(a.TopK):
+		return fmt.Errorf("%w: min_tokens_to_keep %d over top_k %d", ErrUnsupportedOption, a.MinTokensToKeep, a.TopK)
+	case a.MaxKvSize < 0:
+		return fmt.Errorf("%w: negative max_kv_size", ErrUnsupportedOption)
+	case a.PrefillStepSize < 0:
+		return fmt.Errorf("%w: negative prefill_step_size", ErrUnsupportedOption)
+	case a.NoRepeatNgramSize < 0:
+		return fmt.Errorf("%w: negative no_repeat_ngram_size", ErrUnsupportedOption)
+	}
+	return nil
+}
+
+// minKeep is the min_keep passed to the truncating samplers: at least one
+// token always survives, whatever MinTokensToKeep says.
+func (a PredictArgs) minKeep() int {
+	return max(a.MinTokensToKeep, 1)
+}
+
+// Greedy reports whether a samples the most likely token: with temp 0 or
+// top_k 1 the other sampling options cannot change the outcome, so the
+// sampler chain is a plain argmax after the grammar and the repetition
+// penalty, and the output is deterministic.
+func (a PredictArgs) Greedy() bool {
+	return a.Temp <= 0 || a.TopK == 1
+}
+
+// maxTokensFor returns how many tokens a request may generate after a
+// prompt of promptTokens in a context of budget tokens, and why.
+func maxTokensFor(args PredictArgs, promptTokens, budget int) (int, MaxTokensReason, error) {
+	if promptTokens+args.NPredict <= budget {
+		return args.NPredict, MaxTokensRequested, nil
+	}
+	room := budget - promptTokens
+	if room <= 0 {
+		return 0, 0, &ContextExceededError{PromptTokens: promptTokens, SlotBudget: budget}
+	}
+	if args.MaxTokensPolicy == MaxTokensReject {
+		return 0, 0, &ContextExceededError{PromptTokens: promptTokens, SlotBudget: budget, MaxTokens: args.NPredict}
+	}
+	return room, MaxTokensContext, nil
+}
+
+// ActiveOptions lists the options of a that shape the prediction, as
+// name=value pairs for logs. Options left at their defaults are omitted.
+func (a PredictArgs) ActiveOptions() []string {
+	opts := []string{fmt.Sprintf("max_tokens=%d", a.NPredict)}
+	add := func(active bool, name string, value any) {
+		if active {
+			opts = append(opts, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	if a.BeamWidth > 1 {
+		add(true, "beam_width", a.BeamWidth)
+		add(a.LengthPenalty != 1, "length_penalty", a.LengthPenalty)
+		add(a.ReturnBeams, "return_beams", true)
+	} else if a.Greedy() {
+		add(true, "greedy", true)
+		add(a.RepetitionPenalty != 0 && a.RepetitionPenalty != 1, "repetition_penalty", a.RepetitionPenalty)
+		add(a.PromptLookup > 0, "prompt_lookup", a.PromptLookup)
+	} else {
+		add(true, "temp", a.Temp)
+		add(a.TopP > 0 && a.TopP < 1, "top_p", a.TopP)
+		add(a.TopK > 0, "top_k", a.TopK)
+		add(a.MinP > 0, "min_p", a.MinP)
+		add(a.MinTokensToKeep > 1, "min_tokens_to_keep", a.MinTokensToKeep)
+		add(a.RepetitionPenalty != 0 && a.RepetitionPenalty != 1, "repetition_penalty", a.RepetitionPenalty)
+		add(a.RandomSeed >= 0, "random_seed", a.RandomSeed)
+		add(a.PromptLookup > 0, "prompt_lookup", a.PromptLookup)
+	}
+	add(a.MaxKvSize > 0, "max_kv_size", a.MaxKvSize)
+	add(a.PrefillStepSize > 0, "prefill_step_size", a.PrefillStepSize)
+	add(a.NoRepeatNgramSize > 0, "no_repeat_ngram_size", a.NoRepeatNgramSize)
+	add(a.Regex != "", "regex", fmt.Sprintf("%q", a.Regex))
+	add(len(a.StopTokenIDs) > 0, "stop_token_ids", a.StopTokenIDs)
+	add(len(a.BannedStrings) > 0, "banned_strings", len(a.BannedStrings))
+	add(len(a.Images) > 0, "images", len(a.Images))
+	add(a.SpecialTokens != SpecialTokensDefault, "special_tokens", a.SpecialTokens)
+	add(a.MaxTokensPolicy != MaxTokensPolicyDefault, "max_tokens_policy", a.MaxTokensPolicy)
+	return opts
+}
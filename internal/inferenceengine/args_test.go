@@ -0,0 +1,80 @@
+package inferenceengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictArgsValidate(t *testing.T) {
+	require.NoError(t, PredictArgs{NPredict: 10, MaxKvSize: 512, PrefillStepSize: 64, NoRepeatNgramSize: 3}.Validate())
+
+	for name, args := range map[string]PredictArgs{
+		"kv_bits":              {KvBits: 4},
+		"kv_group_size":        {KvGroupSize: 64},
+		"quantized_kv_start":   {QuantizedKvStart: 1},
+		"diversity_penalty":    {DiversityPenalty: 0.5},
+		"max_kv_size":          {MaxKvSize: -1},
+		"no_repeat_ngram_size": {NoRepeatNgramSize: -2},
+		"min_tokens_to_keep":   {TopK: 5, MinTokensToKeep: 10},
+	} {
+		err := args.Validate()
+		require.ErrorIs(t, err, ErrUnsupportedOption, name)
+		require.Contains(t, err.Error(), name)
+	}
+}
+
+func TestPredictArgsMinKeep(t *testing.T) {
+	require.Equal(t, 1, PredictArgs{}.minKeep())
+	require.Equal(t, 5, PredictArgs{TopK: 5, MinTokensToKeep: 5}.minKeep())
+	require.NoError(t, PredictArgs{TopK: 5, MinTokensToKeep: 5}.Validate())
+	// Beam search does not sample, so top_k does not bound it.
+	require.NoError(t, PredictArgs{BeamWidth: 2, TopK: 5, MinTokensToKeep: 10}.Validate())
+}
+
+func TestPredictArgsActiveOptions(t *testing.T) {
+	args := PredictArgs{NPredict: 32, Temp: 0.7, TopK: 40, TopP: 1, RandomSeed: -1, NoRepeatNgramSize: 2, LengthPenalty: 2}
+	require.Equal(t, []string{"max_tokens=32", "temp=0.7", "top_k=40", "no_repeat_ngram_size=2"}, args.ActiveOptions())
+
+	// Beam search ignores the sampling options.
+	args.BeamWidth = 4
+	require.Equal(t, []string{"max_tokens=32", "beam_width=4", "length_penalty=2", "no_repeat_ngram_size=2"}, args.ActiveOptions())
+}
+
+func TestMaxTokensFor(t *testing.T) {
+	n, reason, err := maxTokensFor(PredictArgs{NPredict: 100}, 50, 200)
+	require.NoError(t, err)
+	require.Equal(t, 100, n)
+	require.Equal(t, MaxTokensRequested, reason)
+
+	n, reason, err = maxTokensFor(PredictArgs{NPredict: 100, MaxTokensPolicy: MaxTokensClamp}, 150, 200)
+	require.NoError(t, err)
+	require.Equal(t, 50, n)
+	require.Equal(t, MaxTokensContext, reason)
+
+	var exceeded *ContextExceededError
+	_, _, err = maxTokensFor(PredictArgs{NPredict: 100, MaxTokensPolicy: MaxTokensReject}, 150, 200)
+	require.ErrorAs(t, err, &exceeded)
+	require.Equal(t, 100, exceeded.MaxTokens)
+
+	// A prompt that fills the context fails under either policy.
+	_, _, err = maxTokensFor(PredictArgs{NPredict: 100}, 200, 200)
+	require.ErrorAs(t, err, &exceeded)
+	require.Zero(t, exceeded.MaxTokens)
+}
+
+func TestPredictArgsGreedy(t *testing.T) {
+	require.True(t, PredictArgs{}.Greedy())
+	require.True(t, PredictArgs{Temp: 0.7, TopK: 1}.Greedy())
+	require.False(t, PredictArgs{Temp: 0.7, TopK: 40}.Greedy())
+
+	// Greedy requests share sampler chains whatever their sampling options.
+	a := PredictArgs{TopP: 0.9, MinP: 0.05, RandomSeed: 1, RepetitionPenalty: 1.1}
+	b := PredictArgs{Temp: 0.7, TopK: 1, RandomSeed: -1, RepetitionPenalty: 1.1}
+	require.Equal(t, newSamplerKey(a), newSamplerKey(b))
+	b.RepetitionPenalty = 1.2
+	require.NotEqual(t, newSamplerKey(a), newSamplerKey(b))
+
+	require.Equal(t, []string{"max_tokens=8", "greedy=true", "repetition_penalty=1.1"},
+		PredictArgs{NPredict: 8, TopP: 0.9, RepetitionPenalty: 1.1}.ActiveOptions())
+}
@@ -0,0 +1,49 @@
+package inferenceengine
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+)
+
+// banList holds the token sequences of banned strings. Before each sample,
+// the token that would complete a banned sequence after the tokens so far
+// gets a logit of -Inf, so the model continues differently instead.
+//
+// Matching is on tokens, so a banned string the model spells with different
+// tokens than the tokenizer's (e.g. split into single characters) slips
+// through.
+type banList [][]int
+
+// newBanList tokenizes each string as is and with a leading space, the form
+// it takes after a word in most vocabularies.
+func newBanList(vocab *llamacppbindings.Vocab, banned []string) (banList, error) {
+	var bans banList
+	for _, str := range banned {
+		if str == "" {
+			continue
+		}
+		for _, variant := range []string{str, " " + str} {
+			tokens, err := vocab.Tokenize(variant, false, false)
+			if err != nil {
+				return nil, fmt.Errorf("tokenize banned string %q: %w", str, err)
+			}
+			if len(tokens) > 0 && !slices.ContainsFunc(bans, func(b []int) bool { return slices.Equal(b, tokens) }) {
+				bans = append(bans, tokens)
+			}
+		}
+	}
+	return bans, nil
+}
+
+// apply masks the tokens that would complete a banned sequence after history.
+func (b banList) apply(logits []float32, history []int) {
+	for _, seq := range b {
+		n := len(seq) - 1
+		if n <= len(history) && slices.Equal(history[len(history)-n:], seq[:n]) {
+			logits[seq[n]] = float32(math.Inf(-1))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package inferenceengine
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBanListApply(t *testing.T) {
+	bans := banList{{3}, {1, 2}, {4, 5, 6}}
+	tests := []struct {
+		name    string
+		history []int
+		banned  []int
+	}{
+		{"empty history", nil, []int{3}},
+		{"prefix of two-token ban", []int{0, 1}, []int{3, 2}},
+		{"prefix of three-token ban", []int{4, 5}, []int{3, 6}},
+		{"partial prefix", []int{5}, []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logits := make([]float32, 8)
+			bans.apply(logits, tt.history)
+			for token, l := range logits {
+				require.Equal(t, slices.Contains(tt.banned, token), math.IsInf(float64(l), -1), "token %d", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package inferenceengine
+
+import (
+	"time"
+)
+
+// Batching tunes how the scheduler fills each decode step, trading time to
+// first token against throughput. Zero fields leave the limit to BatchSize
+// and NParallel and dispatch without waiting.
+type Batching struct {
+	// MaxTokens caps the tokens decoded per step, at most BatchSize. Lower
+	// values shorten the steps, and so the latency between streamed tokens,
+	// while long prompts are prefilled.
+	MaxTokens int
+	// MaxWait holds the first decode after the engine was idle for up to
+	// this long, so that requests arriving together share their prefill
+	// steps instead of the first one starting alone.
+	MaxWait time.Duration
+	// MaxSeqs caps the slots that add tokens to a step. Generating slots
+	// go first; if there are more, they take turns.
+	MaxSeqs int
+}
+
+// override returns b with the non-zero fields of o.
+func (b Batching) override(o Batching) Batching {
+	if o.MaxTokens > 0 {
+		b.MaxTokens = o.MaxTokens
+	}
+	if o.MaxWait > 0 {
+		b.MaxWait = o.MaxWait
+	}
+	if o.MaxSeqs > 0 {
+		b.MaxSeqs = o.MaxSeqs
+	}
+	return b
+}
+
+// stepTokens returns how many tokens the next decode may hold.
+func (e *Engine) stepTokens() int {
+	n := e.batch.Cap()
+	if e.batching.MaxTokens > 0 && e.batching.MaxTokens < n {
+		n = e.batching.MaxTokens
+	}
+	return n
+}
+
+// stepSlots returns the indexes of the generating and of the prefilling
+// slots that may add tokens to the next decode. Each generating slot needs
+// one token, so at most maxTokens of them are returned, and at most
+// Batching.MaxSeqs slots in all. When slots are left out, the next step
+// starts after the last one taken so that each gets its turn.
+func (e *Engine) stepSlots(maxTokens int) (generating, prefilling []int) {
+	maxSeqs := e.batching.MaxSeqs
+	if maxSeqs <= 0 {
+		maxSeqs = len(e.slots)
+	}
+	maxGenerating := min(maxSeqs, maxTokens)
+
+	n := len(e.slots)
+	for k := range n {
+		i := (e.stepOffset + k) % n
+		switch e.slots[i].state {
+		case slotGenerating:
+			generating = append(generating, i)
+		case slotPrefilling:
+			prefilling = append(prefilling, i)
+		}
+	}
+	switch {
+	case len(generating) > maxGenerating:
+		generating = generating[:maxGenerating]
+		prefilling = nil
+		e.stepOffset = generating[len(generating)-1] + 1
+	case len(prefilling) > maxSeqs-len(generating):
+		prefilling = prefilling[:maxSeqs-len(generating)]
+		if len(prefilling) > 0 {
+			e.stepOffset = prefilling[len(prefilling)-1] + 1
+		}
+	}
+	e.stepOffset %= n
+	return generating, prefilling
+}
+
+// waitForBatch holds the first decode after the engine was idle for up to
+// Batching.MaxWait, assigning the requests that arrive meanwhile to slots.
+// It returns early once all slots are busy or on Stop.
+func (e *Engine) waitForBatch() {
+	if e.batching.MaxWait <= 0 || !e.hasActiveSlots() {
+		return
+	}
+	timer := time.NewTimer(e.batching.MaxWait)
+	defer timer.Stop()
+	for {
+		e.drainPendingRequests()
+		if e.findIdleSlot() == nil {
+			return
+		}
+		select {
+		case <-e.queue.ready:
+		case <-timer.C:
+			return
+		case <-e.quit:
+			return
+		}
+	}
+}
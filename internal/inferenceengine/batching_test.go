@@ -0,0 +1,61 @@
+package inferenceengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchingOverride(t *testing.T) {
+	defaults := Batching{MaxTokens: 512, MaxWait: 5 * time.Millisecond}
+	require.Equal(t, defaults, defaults.override(Batching{}))
+	require.Equal(t, Batching{MaxTokens: 128, MaxWait: 5 * time.Millisecond, MaxSeqs: 2},
+		defaults.override(Batching{MaxTokens: 128, MaxSeqs: 2}))
+}
+
+func testEngine(states ...slotState) *Engine {
+	e := &Engine{}
+	for i, state := range states {
+		e.slots = append(e.slots, &slot{id: i, seqId: i, state: state})
+	}
+	return e
+}
+
+func TestStepSlots(t *testing.T) {
+	e := testEngine(slotPrefilling, slotGenerating, slotIdle, slotGenerating, slotPrefilling)
+
+	// Unlimited: every active slot, generating ones first.
+	generating, prefilling := e.stepSlots(2048)
+	require.Equal(t, []int{1, 3}, generating)
+	require.Equal(t, []int{0, 4}, prefilling)
+	require.Equal(t, 0, e.stepOffset)
+
+	// Generating slots leave no room for prefills and take turns.
+	e.batching.MaxSeqs = 1
+	generating, prefilling = e.stepSlots(2048)
+	require.Equal(t, []int{1}, generating)
+	require.Empty(t, prefilling)
+	generating, _ = e.stepSlots(2048)
+	require.Equal(t, []int{3}, generating)
+	generating, _ = e.stepSlots(2048)
+	require.Equal(t, []int{1}, generating)
+
+	// Prefilling slots take turns in the room the generating ones leave.
+	e.batching.MaxSeqs = 3
+	e.stepOffset = 0
+	generating, prefilling = e.stepSlots(2048)
+	require.Equal(t, []int{1, 3}, generating)
+	require.Equal(t, []int{0}, prefilling)
+	_, prefilling = e.stepSlots(2048)
+	require.Equal(t, []int{4}, prefilling)
+	_, prefilling = e.stepSlots(2048)
+	require.Equal(t, []int{0}, prefilling)
+
+	// The token budget bounds the generating slots too.
+	e.batching.MaxSeqs = 0
+	e.stepOffset = 0
+	generating, prefilling = e.stepSlots(1)
+	require.Equal(t, []int{1}, generating)
+	require.Empty(t, prefilling)
+}
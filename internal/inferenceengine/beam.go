@@ -0,0 +1,400 @@
+package inferenceengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+)
+
+// ErrBeamUnsupported is returned for a beam search with an option that only
+// applies to sampling.
+var ErrBeamUnsupported = errors.New("not supported with beam search")
+
+// maxBeamWidth bounds PredictArgs.BeamWidth; each beam holds two sequences
+// of the search's context.
+const maxBeamWidth = 16
+
+// Beam is one finished hypothesis of a beam search.
+type Beam struct {
+	Text   string
+	Tokens int
+	// LogProb is the sum of the natural log probabilities of the tokens,
+	// including the end-of-generation token if there was one.
+	LogProb float64
+	// Score is LogProb divided by Tokens^LengthPenalty; beams are ranked
+	// by it.
+	Score        float64
+	FinishReason FinishReason
+
+	tokenIDs []int // streamed with the text of the best beam
+}
+
+// beam is a hypothesis of a running beam search.
+type beam struct {
+	tokens  []int // generated so far
+	logprob float64
+	seq     int // sequence holding its KV cells
+	finish  FinishReason
+}
+
+// beamCandidate extends a live beam by one token.
+type beamCandidate struct {
+	parent  int // index into the live beams
+	token   int
+	logprob float64 // of the extended beam
+}
+
+// beamStep picks the width best candidates, best first. Candidates whose
+// token ends generation finish their beam instead of extending it; they do
+// not count against width. The sequences of the returned live beams are
+// those of their parents.
+func beamStep(live []beam, cands []beamCandidate, width int, ends func(token int) bool) (next, finished []beam) {
+	slices.SortStableFunc(cands, func(a, b beamCandidate) int {
+		switch {
+		case a.logprob > b.logprob:
+			return -1
+		case a.logprob < b.logprob:
+			return 1
+		}
+		return 0
+	})
+	for _, c := range cands {
+		if len(next) == width {
+			break
+		}
+		parent := live[c.parent]
+		if ends(c.token) {
+			finished = append(finished, beam{tokens: parent.tokens, logprob: c.logprob, finish: FinishStop})
+			continue
+		}
+		next = append(next, beam{
+			tokens:  append(slices.Clip(parent.tokens), c.token),
+			logprob: c.logprob,
+			seq:     parent.seq,
+		})
+	}
+	return next, finished
+}
+
+// beamScore normalizes logprob by length, as in Hugging Face transformers:
+// a penalty above 0 favors longer beams, below 0 shorter ones.
+func beamScore(logprob float64, tokens int, lengthPenalty float32) float64 {
+	return logprob / math.Pow(float64(max(tokens, 1)), float64(lengthPenalty))
+}
+
+// topLogprobs appends the k tokens of highest probability under logits, with
+// their log probabilities, to dst, in no particular order.
+func topLogprobs(dst []beamCandidate, logits []float32, k int) []beamCandidate {
+	maxLogit := logits[0]
+	for _, l := range logits[1:] {
+		maxLogit = max(maxLogit, l)
+	}
+	var sum float64
+	for _, l := range logits {
+		sum += math.Exp(float64(l - maxLogit))
+	}
+	logSum := float64(maxLogit) + math.Log(sum)
+
+	start := len(dst)
+	worst := -1 // index in dst of the lowest of the top k once full
+	for token, l := range logits {
+		if len(dst)-start < k {
+			dst = append(dst, beamCandidate{token: token, logprob: float64(l)})
+			if len(dst)-start == k {
+				worst = lowestCandidate(dst, start)
+			}
+			continue
+		}
+		if float64(l) > dst[worst].logprob {
+			dst[worst] = beamCandidate{token: token, logprob: float64(l)}
+			worst = lowestCandidate(dst, start)
+		}
+	}
+	for i := start; i < len(dst); i++ {
+		dst[i].logprob -= logSum
+	}
+	return dst
+}
+
+func lowestCandidate(cands []beamCandidate, start int) int {
+	lowest := start
+	for i := start + 1; i < len(cands); i++ {
+		if cands[i].logprob < cands[lowest].logprob {
+			lowest = i
+		}
+	}
+	return lowest
+}
+
+// beamSearch generates with beam search instead of sampling. Like Bench it
+// runs on a context of its own, which keeps two sequences per beam in a
+// unified KV cache, so it does not take a slot; searches run one at a time.
+// The text is streamed once the search is done.
+func (e *Engine) beamSearch(ctx context.Context, model ModelContext, prompt string, args PredictArgs, stream StreamFunc) (Result, error) {
+	switch {
+	case args.BeamWidth > maxBeamWidth:
+		return Result{}, fmt.Errorf("%w: beam width %d over %d", ErrBeamUnsupported, args.BeamWidth, maxBeamWidth)
+	case args.Regex != "":
+		return Result{}, fmt.Errorf("%w: regex", ErrBeamUnsupported)
+	case len(args.BannedStrings) > 0:
+		return Result{}, fmt.Errorf("%w: banned strings", ErrBeamUnsupported)
+	case args.PromptLookup > 0:
+		return Result{}, fmt.Errorf("%w: prompt lookup", ErrBeamUnsupported)
+	}
+
+	submitTime := time.Now()
+	ctx, cancel := e.requestContext(ctx)
+	defer cancel()
+	select {
+	case e.beamSem <- struct{}{}:
+		defer func() { <-e.beamSem }()
+	case <-ctx.Done():
+		return Result{}, context.Cause(ctx)
+	}
+	startTime := time.Now()
+	queueTimeSeconds.ObserveWithExemplar(startTime.Sub(submitTime).Seconds(), requestExemplar(args.RequestID), model.metricLabels()...)
+
+	vocab := model.Model.Vocab()
+	tokens, err := vocab.Tokenize(prompt, true, true)
+	if err != nil {
+		return Result{}, fmt.Errorf("tokenize: %w", err)
+	}
+	ctxSize := e.opts.CtxSize
+	if model.CtxSize > 0 {
+		ctxSize = model.CtxSize
+	}
+	if args.MaxKvSize > 0 && args.MaxKvSize < ctxSize {
+		ctxSize = args.MaxKvSize
+	}
+	if args.NPredict <= 0 {
+		// No limit: search until the context is full.
+		args.NPredict = ctxSize
+		args.MaxTokensPolicy = MaxTokensClamp
+	}
+	maxTokens, maxTokensReason, err := maxTokensFor(args, len(tokens), ctxSize)
+	if err != nil {
+		return Result{}, err
+	}
+
+	width := args.BeamWidth
+	nBatch := min(e.opts.BatchSize, max(len(tokens), width))
+	params := llamacppbindings.NewContextDefaultParams()
+	params.SetNCtx(len(tokens) + width*maxTokens)
+	params.SetNBatch(nBatch)
+	params.SetNSeqMax(2 * width)
+	params.SetKvUnified(true)
+	params.SetNThreads(e.opts.NThreads)
+	params.SetNThreadsBatch(e.opts.NThreadsBatch)
+	if e.opts.FlashAttn {
+		params.SetFlashAttention(true)
+	}
+	if kvCacheType := e.kvCacheType(model); kvCacheType != "" {
+		params.SetTypeKV(kvCacheType)
+	}
+	logger := e.requestLogger(args.RequestID)
+	var beams []Beam
+	llamacppbindings.WithNativeLogger(logger, func() {
+		var lctx *llamacppbindings.Context
+		lctx, err = llamacppbindings.NewContext(model.Model, params)
+		if err != nil {
+			err = fmt.Errorf("beam search: create context: %w", err)
+			return
+		}
+		defer lctx.Free()
+		batch := llamacppbindings.BatchInit(nBatch, 0, 1)
+		defer batch.Free()
+		b := &beamRun{
+			ctx:       ctx,
+			lctx:      lctx,
+			memory:    lctx.Memory(),
+			batch:     batch,
+			vocab:     vocab,
+			args:      args,
+			width:     width,
+			maxTokens: maxTokens,
+		}
+		beams, err = b.run(tokens)
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	best := beams[0]
+	res := Result{
+		Text:             best.Text,
+		PromptTokens:     len(tokens),
+		CompletionTokens: best.Tokens,
+		FinishReason:     best.FinishReason,
+		MaxTokens:        maxTokens,
+		MaxTokensReason:  maxTokensReason,
+	}
+	if args.ReturnBeams {
+		res.Beams = beams
+	}
+	if stream != nil && best.Text != "" {
+		if err := stream(best.tokenIDs, len(tokens), best.Tokens, best.Text); err != nil {
+			return Result{}, err
+		}
+	}
+	now := time.Now()
+	res.Timings = Timings{
+		QueueTime:        startTime.Sub(submitTime),
+		TimeToFirstToken: now.Sub(submitTime),
+		TotalTime:        now.Sub(submitTime),
+	}
+	timeToFirstTokenSeconds.ObserveWithExemplar(res.Timings.TimeToFirstToken.Seconds(), requestExemplar(args.RequestID), model.metricLabels()...)
+	e.logger.Infof("beam search done (width=%d, prompt=%d, tokens=%d, score=%.3f, %s, request=%s)",
+		width, len(tokens), best.Tokens, best.Score, now.Sub(startTime), args.RequestID)
+	return res, nil
+}
+
+// beamRun is one beam search on its own context.
+type beamRun struct {
+	ctx       context.Context
+	lctx      *llamacppbindings.Context
+	memory    *llamacppbindings.Memory
+	batch     *llamacppbindings.Batch
+	vocab     *llamacppbindings.Vocab
+	args      PredictArgs
+	width     int
+	maxTokens int
+}
+
+// run prefills prompt on sequence 0 and extends the beams one token per
+// decode until width beams have finished or the live ones reach maxTokens.
+// Live beams alternate between sequences [0, width) and [width, 2*width): a
+// step copies each parent's cells to its children in the other half, then
+// drops the old half.
+func (b *beamRun) run(prompt []int) ([]Beam, error) {
+	for i := 0; i < len(prompt); i += b.batch.Cap() {
+		chunk := prompt[i:min(i+b.batch.Cap(), len(prompt))]
+		b.batch.Clear()
+		for j, token := range chunk {
+			b.batch.Add(token, i+j, 0, i+j == len(prompt)-1)
+		}
+		if err := b.decode(); err != nil {
+			return nil, err
+		}
+	}
+
+	live := []beam{{seq: 0}}
+	outputs := []int{b.batch.NTokens() - 1} // batch index of each live beam's logits
+	var finished []beam
+	var cands []beamCandidate
+	var history []int // for NoRepeatNgramSize
+	ends := func(token int) bool {
+		return b.vocab.IsEog(token) || slices.Contains(b.args.StopTokenIDs, token)
+	}
+	for step := 0; ; step++ {
+		cands = cands[:0]
+		for i, lb := range live {
+			logits, err := b.lctx.Logits(outputs[i])
+			if err != nil {
+				return nil, fmt.Errorf("beam search: %w", err)
+			}
+			if b.args.NoRepeatNgramSize > 0 {
+				history = append(append(history[:0], prompt...), lb.tokens...)
+				blockRepeatedNgrams(logits, history, b.args.NoRepeatNgramSize)
+			}
+			start := len(cands)
+			cands = topLogprobs(cands, logits, 2*b.width)
+			for k := start; k < len(cands); k++ {
+				cands[k].parent = i
+				cands[k].logprob += lb.logprob
+			}
+		}
+		next, done := beamStep(live, cands, b.width, ends)
+		finished = append(finished, done...)
+		if len(finished) >= b.width || len(next) == 0 {
+			break
+		}
+		if step+1 >= b.maxTokens {
+			for _, nb := range next {
+				nb.finish = FinishLength
+				finished = append(finished, nb)
+			}
+			break
+		}
+
+		half := (step + 1) % 2 * b.width
+		for j := range next {
+			b.memory.SeqCp(next[j].seq, half+j, -1, -1)
+			next[j].seq = half + j
+		}
+		for seq := b.width - half; seq < 2*b.width-half; seq++ {
+			b.memory.SeqRm(seq, -1, -1)
+		}
+
+		b.batch.Clear()
+		outputs = outputs[:0]
+		for _, nb := range next {
+			outputs = append(outputs, b.batch.NTokens())
+			b.batch.Add(nb.tokens[len(nb.tokens)-1], len(prompt)+len(nb.tokens)-1, nb.seq, true)
+		}
+		if err := b.decode(); err != nil {
+			return nil, err
+		}
+		live = next
+	}
+	return b.rank(finished)
+}
+
+func (b *beamRun) decode() error {
+	if err := context.Cause(b.ctx); err != nil {
+		return err
+	}
+	if err := b.lctx.Decode(b.batch); err != nil {
+		return fmt.Errorf("beam search: decode: %w", err)
+	}
+	return nil
+}
+
+// rank returns the finished beams as Beams, best first.
+func (b *beamRun) rank(finished []beam) ([]Beam, error) {
+	beams := make([]Beam, 0, len(finished))
+	for _, fb := range finished {
+		text, err := b.text(fb.tokens)
+		if err != nil {
+			return nil, err
+		}
+		beams = append(beams, Beam{
+			Text:         text,
+			Tokens:       len(fb.tokens),
+			LogProb:      fb.logprob,
+			Score:        beamScore(fb.logprob, len(fb.tokens), b.args.LengthPenalty),
+			FinishReason: fb.finish,
+			tokenIDs:     fb.tokens,
+		})
+	}
+	slices.SortStableFunc(beams, func(x, y Beam) int {
+		switch {
+		case x.Score > y.Score:
+			return -1
+		case x.Score < y.Score:
+			return 1
+		}
+		return 0
+	})
+	return beams, nil
+}
+
+// text renders tokens; control tokens are left out unless the request
+// renders them.
+func (b *beamRun) text(tokens []int) (string, error) {
+	var text []byte
+	for _, token := range tokens {
+		if b.args.SpecialTokens != SpecialTokensRender && b.vocab.IsControl(token) {
+			continue
+		}
+		var err error
+		if text, err = b.vocab.AppendPiece(text, token); err != nil {
+			return "", fmt.Errorf("token to piece: %w", err)
+		}
+	}
+	return string(text), nil
+}
@@ -0,0 +1,57 @@
+package inferenceengine
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopLogprobs(t *testing.T) {
+	logits := []float32{1, 4, 2, 4.5, 0}
+	top := topLogprobs(nil, logits, 3)
+	slices.SortFunc(top, func(a, b beamCandidate) int { return a.token - b.token })
+	require.Equal(t, []int{1, 2, 3}, []int{top[0].token, top[1].token, top[2].token})
+
+	var sum float64
+	for _, l := range logits {
+		sum += math.Exp(float64(l))
+	}
+	require.InDelta(t, 4.5-math.Log(sum), top[2].logprob, 1e-6)
+}
+
+func TestBeamStep(t *testing.T) {
+	const eog = 99
+	ends := func(token int) bool { return token == eog }
+	live := []beam{{tokens: []int{1}, logprob: -1, seq: 0}, {tokens: []int{2}, logprob: -2, seq: 1}}
+	cands := []beamCandidate{
+		{parent: 1, token: 5, logprob: -2.5},
+		{parent: 0, token: eog, logprob: -1.2},
+		{parent: 0, token: 3, logprob: -1.5},
+		{parent: 0, token: 4, logprob: -3},
+	}
+	next, finished := beamStep(live, cands, 2, ends)
+
+	// The end-of-generation candidate finishes its beam without taking
+	// one of the two places.
+	require.Len(t, finished, 1)
+	require.Equal(t, []int{1}, finished[0].tokens)
+	require.Equal(t, FinishStop, finished[0].finish)
+	require.Len(t, next, 2)
+	require.Equal(t, []int{1, 3}, next[0].tokens)
+	require.Equal(t, 0, next[0].seq)
+	require.Equal(t, []int{2, 5}, next[1].tokens)
+	require.Equal(t, 1, next[1].seq)
+
+	// Children of one parent do not share their token slices.
+	next[0].tokens[0] = 7
+	require.Equal(t, []int{1}, live[0].tokens)
+}
+
+func TestBeamScore(t *testing.T) {
+	require.Equal(t, -4.0, beamScore(-4, 4, 0))
+	require.Equal(t, -1.0, beamScore(-4, 4, 1))
+	require.Equal(t, -0.25, beamScore(-4, 4, 2))
+	require.Equal(t, -4.0, beamScore(-4, 0, 1))
+}
@@ -0,0 +1,181 @@
+package inferenceengine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+)
+
+// BenchOptions selects the benchmarks to run, like llama-bench's -p, -n and
+// -r. Zero values use the llama-bench defaults (pp512, tg128, 3 repetitions);
+// a negative NPrompt or NGen skips that test.
+type BenchOptions struct {
+	NPrompt     int
+	NGen        int
+	Repetitions int
+}
+
+// BenchResult is the throughput of one test, e.g. "pp512" (prompt
+// processing of 512 tokens) or "tg128" (generation of 128 tokens).
+type BenchResult struct {
+	Test            string
+	NPrompt         int
+	NGen            int
+	TokensPerSecond float64 // mean over the repetitions
+	StdDev          float64
+	Samples         []time.Duration
+}
+
+func (o BenchOptions) withDefaults() BenchOptions {
+	if o.NPrompt == 0 {
+		o.NPrompt = 512
+	}
+	if o.NGen == 0 {
+		o.NGen = 128
+	}
+	if o.Repetitions <= 0 {
+		o.Repetitions = 3
+	}
+	return o
+}
+
+// Bench measures prompt processing and generation throughput of a model on
+// a context of its own, sized for the test. It does not go through the slots,
+// so it runs alongside, and competes for compute with, live requests.
+func (e *Engine) Bench(ctx context.Context, model ModelContext, opts BenchOptions) ([]BenchResult, error) {
+	ctx, cancel := e.requestContext(ctx)
+	defer cancel()
+	opts = opts.withDefaults()
+	nCtx := max(opts.NPrompt, 0) + max(opts.NGen, 0)
+	if nCtx == 0 {
+		return nil, fmt.Errorf("bench: nothing to run")
+	}
+	nBatch := min(e.opts.BatchSize, max(opts.NPrompt, 1))
+
+	params := llamacppbindings.NewContextDefaultParams()
+	params.SetNCtx(nCtx)
+	params.SetNBatch(nBatch)
+	params.SetNSeqMax(1)
+	params.SetNThreads(e.opts.NThreads)
+	params.SetNThreadsBatch(e.opts.NThreadsBatch)
+	if e.opts.FlashAttn {
+		params.SetFlashAttention(true)
+	}
+	if kvCacheType := e.kvCacheType(model); kvCacheType != "" {
+		params.SetTypeKV(kvCacheType)
+	}
+	lctx, err := llamacppbindings.NewContext(model.Model, params)
+	if err != nil {
+		return nil, fmt.Errorf("bench: create context: %w", err)
+	}
+	defer lctx.Free()
+
+	b := &bench{
+		ctx:    ctx,
+		lctx:   lctx,
+		memory: lctx.Memory(),
+		batch:  llamacppbindings.BatchInit(nBatch, 0, 1),
+		nVocab: model.Model.Vocab().NTokens(),
+		rng:    rand.New(rand.NewSource(1)),
+	}
+	defer b.batch.Free()
+
+	// Warm up, as llama-bench does, so one-time allocations are not measured.
+	if err := b.decode(1, 0); err != nil {
+		return nil, err
+	}
+
+	var results []BenchResult
+	if opts.NPrompt > 0 {
+		res, err := b.run(fmt.Sprintf("pp%d", opts.NPrompt), opts.NPrompt, 0, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	if opts.NGen > 0 {
+		res, err := b.run(fmt.Sprintf("tg%d", opts.NGen), 0, opts.NGen, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+type bench struct {
+	ctx    context.Context
+	lctx   *llamacppbindings.Context
+	memory *llamacppbindings.Memory
+	batch  *llamacppbindings.Batch
+	nVocab int
+	rng    *rand.Rand
+}
+
+func (b *bench) run(test string, nPrompt, nGen int, opts BenchOptions) (BenchResult, error) {
+	res := BenchResult{Test: test, NPrompt: nPrompt, NGen: nGen}
+	for i := 0; i < opts.Repetitions; i++ {
+		b.memory.SeqRm(0, -1, -1)
+		start := time.Now()
+		if nPrompt > 0 {
+			if err := b.decode(nPrompt, 0); err != nil {
+				return res, err
+			}
+		}
+		for pos := 0; pos < nGen; pos++ {
+			if err := b.decode(1, pos); err != nil {
+				return res, err
+			}
+		}
+		res.Samples = append(res.Samples, time.Since(start))
+	}
+	res.TokensPerSecond, res.StdDev = tokensPerSecond(nPrompt+nGen, res.Samples)
+	return res, nil
+}
+
+// decode feeds n random tokens starting at pos, in batches, and waits for the
+// computation to finish.
+func (b *bench) decode(n, pos int) error {
+	for n > 0 {
+		if err := context.Cause(b.ctx); err != nil {
+			return err
+		}
+		chunk := min(n, b.batch.Cap())
+		b.batch.Clear()
+		for j := 0; j < chunk; j++ {
+			b.batch.Add(b.rng.Intn(b.nVocab), pos+j, 0, j == chunk-1)
+		}
+		if err := b.lctx.Decode(b.batch); err != nil {
+			return fmt.Errorf("bench: decode: %w", err)
+		}
+		pos += chunk
+		n -= chunk
+	}
+	b.lctx.Synchronize()
+	return nil
+}
+
+// tokensPerSecond returns the mean and standard deviation of the per-sample
+// throughput.
+func tokensPerSecond(tokens int, samples []time.Duration) (mean, stddev float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	rates := make([]float64, len(samples))
+	for i, d := range samples {
+		rates[i] = float64(tokens) / d.Seconds()
+		mean += rates[i]
+	}
+	mean /= float64(len(rates))
+	if len(rates) > 1 {
+		for _, r := range rates {
+			stddev += (r - mean) * (r - mean)
+		}
+		stddev = math.Sqrt(stddev / float64(len(rates)-1))
+	}
+	return mean, stddev
+}
@@ -0,0 +1,94 @@
+package inferenceengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrNotClassifier is returned by Classify for a model without a
+// classification head (rank pooling).
+var ErrNotClassifier = errors.New("model has no classification head")
+
+// LabelScore is one output of a classification head.
+type LabelScore struct {
+	Label string
+	// Score is the raw output (logit).
+	Score float32
+	// Probability is the softmax of the scores over all labels, or the
+	// sigmoid of the score for a single-output head (e.g. a reward model).
+	Probability float32
+}
+
+// ClassifyResult holds the label scores of each input text, in order.
+type ClassifyResult struct {
+	Labels       [][]LabelScore
+	PromptTokens int
+	TotalTime    time.Duration
+}
+
+// Classify runs texts through the classification head of a reranker, reward
+// or judge model. It shares the embedding path, and its micro-batching, with
+// Embed. Only RequestID of args is used.
+func (e *Engine) Classify(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (ClassifyResult, error) {
+	start := time.Now()
+	ctx, cancel := e.requestContext(ctx)
+	defer cancel()
+	if model.Model.NClsOut() == 0 {
+		return ClassifyResult{}, ErrNotClassifier
+	}
+	req, err := e.newEmbedRequest(ctx, model, texts, args)
+	if err != nil {
+		return ClassifyResult{}, err
+	}
+	req.classify = true
+	if err := e.embedder.submit(req); err != nil {
+		return ClassifyResult{}, err
+	}
+
+	labels := make([]string, model.Model.NClsOut())
+	for i := range labels {
+		if labels[i] = model.Model.ClsLabel(i); labels[i] == "" {
+			labels[i] = fmt.Sprintf("LABEL_%d", i)
+		}
+	}
+	res := ClassifyResult{
+		Labels:       make([][]LabelScore, len(texts)),
+		PromptTokens: req.nTokens,
+		TotalTime:    time.Since(start),
+	}
+	for i, scores := range req.embeddings {
+		res.Labels[i] = labelScores(labels, scores)
+	}
+	return res, nil
+}
+
+func labelScores(labels []string, scores []float32) []LabelScore {
+	out := make([]LabelScore, min(len(labels), len(scores)))
+	if len(out) == 1 {
+		out[0] = LabelScore{
+			Label:       labels[0],
+			Score:       scores[0],
+			Probability: float32(1 / (1 + math.Exp(-float64(scores[0])))),
+		}
+		return out
+	}
+	maxScore := math.Inf(-1)
+	for _, s := range scores[:len(out)] {
+		maxScore = math.Max(maxScore, float64(s))
+	}
+	var sum float64
+	for _, s := range scores[:len(out)] {
+		sum += math.Exp(float64(s) - maxScore)
+	}
+	for i := range out {
+		out[i] = LabelScore{
+			Label:       labels[i],
+			Score:       scores[i],
+			Probability: float32(math.Exp(float64(scores[i])-maxScore) / sum),
+		}
+	}
+	return out
+}
@@ -0,0 +1,411 @@
+package inferenceengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+)
+
+// embedMaxSeqs caps the texts packed into one embedding batch; each needs a
+// sequence of its own.
+const embedMaxSeqs = 64
+
+var (
+	// ErrNothingToEmbed is returned for an empty input list or a text that
+	// tokenizes to no tokens.
+	ErrNothingToEmbed = errors.New("nothing to embed")
+	// ErrClassifierModel is returned by Embed for a model whose output is a
+	// classification head rather than an embedding.
+	ErrClassifierModel = errors.New("model has a classification head, use Classify")
+)
+
+// EmbedResult holds one L2-normalized embedding per input text, in order.
+type EmbedResult struct {
+	Embeddings   [][]float32
+	PromptTokens int
+	TotalTime    time.Duration
+}
+
+// Embed computes the embeddings of texts. Texts of concurrent calls are
+// micro-batched: each decode packs as many pending texts as fit in BatchSize
+// tokens, so many small requests cost about as much as one large one. A text
+// must fit in BatchSize tokens on its own. Only RequestID of args is used.
+func (e *Engine) Embed(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (EmbedResult, error) {
+	start := time.Now()
+	ctx, cancel := e.requestContext(ctx)
+	defer cancel()
+	req, err := e.newEmbedRequest(ctx, model, texts, args)
+	if err != nil {
+		return EmbedResult{}, err
+	}
+	if err := e.embedder.submit(req); err != nil {
+		return EmbedResult{}, err
+	}
+	return EmbedResult{
+		Embeddings:   req.embeddings,
+		PromptTokens: req.nTokens,
+		TotalTime:    time.Since(start),
+	}, nil
+}
+
+func (e *Engine) newEmbedRequest(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (*embedRequest, error) {
+	if len(texts) == 0 {
+		return nil, ErrNothingToEmbed
+	}
+	req := &embedRequest{
+		ctx:        ctx,
+		model:      model,
+		logger:     e.requestLogger(args.RequestID),
+		tokens:     make([][]int, len(texts)),
+		embeddings: make([][]float32, len(texts)),
+		done:       make(chan error, 1),
+	}
+	vocab := model.Model.Vocab()
+	for i, text := range texts {
+		tokens, err := vocab.Tokenize(text, true, true)
+		if err != nil {
+			return nil, fmt.Errorf("tokenize: %w", err)
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("%w: input %d is empty", ErrNothingToEmbed, i)
+		}
+		if len(tokens) > e.opts.BatchSize {
+			return nil, &ContextExceededError{PromptTokens: len(tokens), SlotBudget: e.opts.BatchSize}
+		}
+		req.tokens[i] = tokens
+		req.nTokens += len(tokens)
+	}
+	return req, nil
+}
+
+type embedRequest struct {
+	ctx    context.Context
+	model  ModelContext
+	logger logging.SprintfLogger
+	tokens [][]int
+	// nTokens counts the tokens of all texts.
+	nTokens int
+	// classify keeps the raw classifier scores instead of normalizing.
+	classify bool
+
+	// Owned by the embedder goroutine until done is signaled.
+	embeddings [][]float32
+	next       int // index of the first text not yet batched
+	inFlight   int // texts in the current batch
+	done       chan error
+}
+
+// embedder computes embeddings on a context of its own, in embeddings mode,
+// next to the engine's generation context.
+type embedder struct {
+	opts   Options
+	logger logging.SprintfLogger
+
+	requests chan *embedRequest
+	releases chan releaseRequest
+	quit     chan struct{}
+	done     chan struct{}
+
+	// llama.cpp state — owned by the run goroutine
+	model   *llamacppbindings.Model
+	context *llamacppbindings.Context
+	memory  *llamacppbindings.Memory // nil for models without a KV cache
+	batch   *llamacppbindings.Batch
+	pending []*embedRequest
+}
+
+func newEmbedder(opts Options, logger logging.SprintfLogger) *embedder {
+	b := &embedder{
+		opts:     opts,
+		logger:   logger,
+		requests: make(chan *embedRequest),
+		releases: make(chan releaseRequest),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// submit hands req to the embedder goroutine and waits for it to finish or
+// for req.ctx to be done. A canceled request is dropped before its next batch.
+func (b *embedder) submit(req *embedRequest) error {
+	select {
+	case b.requests <- req:
+	case <-b.quit:
+		return ErrEngineStopped
+	case <-req.ctx.Done():
+		return context.Cause(req.ctx)
+	}
+	select {
+	case err := <-req.done:
+		return err
+	case <-req.ctx.Done():
+		return context.Cause(req.ctx)
+	}
+}
+
+// release frees the embedding context if it was created for model.
+func (b *embedder) release(model *llamacppbindings.Model) {
+	r := releaseRequest{model: model, done: make(chan struct{})}
+	select {
+	case b.releases <- r:
+		<-r.done
+	case <-b.done:
+	}
+}
+
+func (b *embedder) stop() {
+	close(b.quit)
+	<-b.done
+}
+
+func (b *embedder) run() {
+	defer close(b.done)
+	defer b.teardown()
+	for {
+		if len(b.pending) == 0 {
+			select {
+			case req := <-b.requests:
+				b.pending = append(b.pending, req)
+			case r := <-b.releases:
+				b.releaseContext(r)
+				continue
+			case <-b.quit:
+				return
+			}
+		}
+		// Everything that arrived while the previous batch was decoding goes
+		// into the next one.
+		for drained := false; !drained; {
+			select {
+			case req := <-b.requests:
+				b.pending = append(b.pending, req)
+			case r := <-b.releases:
+				b.releaseContext(r)
+			case <-b.quit:
+				b.failPending(ErrEngineStopped)
+				return
+			default:
+				drained = true
+			}
+		}
+		b.dropCanceled()
+		if len(b.pending) == 0 {
+			continue
+		}
+		if err := b.ensureContext(b.pending[0]); err != nil {
+			b.finish(b.pending[0], err)
+			continue
+		}
+		if err := b.checkPooling(b.pending[0]); err != nil {
+			b.finish(b.pending[0], err)
+			continue
+		}
+		b.decodeBatch()
+	}
+}
+
+// releaseContext tears the context down if it belongs to the released
+// model. Requests for the model can only still be pending if they were
+// canceled, so they are dropped first.
+func (b *embedder) releaseContext(r releaseRequest) {
+	if b.context != nil && b.model == r.model {
+		b.dropCanceled()
+		b.teardown()
+	}
+	close(r.done)
+}
+
+// dropCanceled removes pending requests whose context is done.
+func (b *embedder) dropCanceled() {
+	kept := b.pending[:0]
+	for _, req := range b.pending {
+		if err := context.Cause(req.ctx); err != nil {
+			req.done <- err
+			continue
+		}
+		kept = append(kept, req)
+	}
+	b.pending = kept
+}
+
+// finish completes req and removes it from the pending list.
+func (b *embedder) finish(req *embedRequest, err error) {
+	req.done <- err
+	for i, p := range b.pending {
+		if p == req {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *embedder) failPending(err error) {
+	for _, req := range b.pending {
+		req.done <- err
+	}
+	b.pending = nil
+}
+
+// decodeBatch packs texts of pending requests for the current model, oldest
+// first, into one batch, decodes it and stores the embeddings. Requests for
+// other models wait until the current model's requests are done.
+func (b *embedder) decodeBatch() {
+	b.batch.Clear()
+	var batched []*embedRequest
+	seq := 0
+pack:
+	for _, req := range b.pending {
+		if req.model.Model != b.model || b.checkPooling(req) != nil {
+			continue
+		}
+		for req.next+req.inFlight < len(req.tokens) {
+			tokens := req.tokens[req.next+req.inFlight]
+			if seq == embedMaxSeqs || b.batch.NTokens()+len(tokens) > b.batch.Cap() {
+				break pack
+			}
+			for pos, token := range tokens {
+				b.batch.Add(token, pos, seq, true)
+			}
+			if req.inFlight == 0 {
+				batched = append(batched, req)
+			}
+			req.inFlight++
+			seq++
+		}
+	}
+
+	var err error
+	llamacppbindings.WithNativeLogger(b.batchLogger(batched), func() {
+		err = b.context.Decode(b.batch)
+	})
+	if b.memory != nil {
+		b.memory.Clear(true)
+	}
+
+	seq = 0
+	for _, req := range batched {
+		reqErr := err
+		for ; req.inFlight > 0; req.inFlight-- {
+			if reqErr == nil {
+				reqErr = b.store(req, seq)
+			}
+			req.next++
+			seq++
+		}
+		if reqErr != nil {
+			b.finish(req, fmt.Errorf("embed: %w", reqErr))
+		} else if req.next == len(req.tokens) {
+			b.finish(req, nil)
+		}
+	}
+}
+
+// checkPooling tells whether the current context produces what req wants:
+// classifier scores need rank pooling, embeddings any other.
+func (b *embedder) checkPooling(req *embedRequest) error {
+	rank := b.context.PoolingType() == llamacppbindings.PoolingRank
+	switch {
+	case req.classify && !rank:
+		return ErrNotClassifier
+	case !req.classify && rank:
+		return ErrClassifierModel
+	}
+	return nil
+}
+
+// store copies the output of sequence seq into the result slot of req's next
+// text, normalizing embeddings.
+func (b *embedder) store(req *embedRequest, seq int) error {
+	embd, err := b.context.SeqEmbeddings(seq)
+	if err != nil {
+		return err
+	}
+	if req.classify {
+		req.embeddings[req.next] = append([]float32(nil), embd...)
+		return nil
+	}
+	var norm float64
+	for _, v := range embd {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+	out := make([]float32, len(embd))
+	for i, v := range embd {
+		if norm > 0 {
+			out[i] = float32(float64(v) / norm)
+		}
+	}
+	req.embeddings[req.next] = out
+	return nil
+}
+
+func (b *embedder) batchLogger(batched []*embedRequest) logging.SprintfLogger {
+	if len(batched) == 1 {
+		return batched[0].logger
+	}
+	return nil // inherit the global llama.cpp logger
+}
+
+// ensureContext switches the embedding context to the model of req.
+func (b *embedder) ensureContext(req *embedRequest) error {
+	if b.context != nil && b.model == req.model.Model {
+		return nil
+	}
+	b.teardown()
+	var err error
+	llamacppbindings.WithNativeLogger(req.logger, func() {
+		err = b.initContext(req.model.Model, llamacppbindings.PoolingUnspecified)
+		// Generative models have no pooling of their own; average their
+		// token embeddings.
+		if err == nil && b.context.PoolingType() == llamacppbindings.PoolingNone {
+			b.teardown()
+			err = b.initContext(req.model.Model, llamacppbindings.PoolingMean)
+		}
+	})
+	return err
+}
+
+func (b *embedder) initContext(model *llamacppbindings.Model, pooling llamacppbindings.PoolingType) error {
+	params := llamacppbindings.NewContextDefaultParams()
+	params.SetEmbeddings(true)
+	params.SetPoolingType(pooling)
+	params.SetNCtx(b.opts.BatchSize)
+	params.SetNBatch(b.opts.BatchSize)
+	params.SetNUbatch(b.opts.BatchSize)
+	params.SetNSeqMax(embedMaxSeqs)
+	params.SetNThreads(b.opts.NThreads)
+	params.SetNThreadsBatch(b.opts.NThreadsBatch)
+	if b.opts.FlashAttn {
+		params.SetFlashAttention(true)
+	}
+	ctx, err := llamacppbindings.NewContext(model, params)
+	if err != nil {
+		return fmt.Errorf("create embedding context: %w", err)
+	}
+	b.model = model
+	b.context = ctx
+	b.memory = ctx.Memory()
+	b.batch = llamacppbindings.BatchInit(b.opts.BatchSize, 0, 1)
+	b.logger.Infof("embedding context ready (nBatch=%d, maxSeqs=%d)", b.opts.BatchSize, embedMaxSeqs)
+	return nil
+}
+
+func (b *embedder) teardown() {
+	if b.batch != nil {
+		b.batch.Free()
+		b.batch = nil
+	}
+	if b.context != nil {
+		b.context.Free()
+		b.context = nil
+	}
+	b.memory = nil
+	b.model = nil
+}
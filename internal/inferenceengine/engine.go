@@ -1,15 +1,143 @@
 package inferenceengine
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
 )
 
-// StreamFunc is a function type for streaming a prediction
-type StreamFunc func(token, tokens int, message string) error
+// StreamFunc receives the generated text as it is produced, with the IDs of
+// the tokens it was decoded from, the number of prompt tokens and the number
+// of tokens generated so far, these included.
+type StreamFunc func(tokenIDs []int, promptTokens, completionTokens int, message string) error
+
+// PrefillProgressFunc is called after each prompt chunk is decoded with the
+// number of prompt tokens processed so far and the prompt length.
+type PrefillProgressFunc func(processed, total int) error
+
+// SpecialTokenFunc receives a control token generated in SpecialTokensEvent
+// mode, with its text and the token counts as passed to StreamFunc.
+type SpecialTokenFunc func(token, promptTokens, completionTokens int, piece string) error
+
+// SpecialTokens selects how generated control tokens, such as <|im_start|>
+// in a ChatML model's output, are delivered. End-of-generation and stop
+// tokens end generation and are never output.
+type SpecialTokens int
+
+const (
+	// SpecialTokensDefault uses Options.SpecialTokens.
+	SpecialTokensDefault SpecialTokens = iota
+	// SpecialTokensRender outputs control tokens as text like any other
+	// token. This is the default.
+	SpecialTokensRender
+	// SpecialTokensSkip leaves control tokens out of the output. They still
+	// count as generated tokens.
+	SpecialTokensSkip
+	// SpecialTokensEvent passes control tokens to PredictArgs.SpecialToken
+	// instead of the stream and leaves them out of Result.Text. Without a
+	// SpecialToken func it behaves like SpecialTokensSkip.
+	SpecialTokensEvent
+)
+
+// ParseSpecialTokens parses "render", "skip" or "event".
+func ParseSpecialTokens(s string) (SpecialTokens, error) {
+	switch strings.ToLower(s) {
+	case "render":
+		return SpecialTokensRender, nil
+	case "skip":
+		return SpecialTokensSkip, nil
+	case "event":
+		return SpecialTokensEvent, nil
+	default:
+		return 0, fmt.Errorf("unknown special tokens mode %q (want render, skip or event)", s)
+	}
+}
+
+func (m SpecialTokens) String() string {
+	switch m {
+	case SpecialTokensDefault:
+		return "default"
+	case SpecialTokensRender:
+		return "render"
+	case SpecialTokensSkip:
+		return "skip"
+	case SpecialTokensEvent:
+		return "event"
+	default:
+		return "unknown"
+	}
+}
+
+// MaxTokensPolicy selects what happens when a prompt leaves less room in the
+// slot's context than max_tokens asks for.
+type MaxTokensPolicy int
+
+const (
+	// MaxTokensPolicyDefault uses Options.MaxTokensPolicy.
+	MaxTokensPolicyDefault MaxTokensPolicy = iota
+	// MaxTokensClamp lowers max_tokens to the room left, reported as
+	// MaxTokensContext in the result. This is the default.
+	MaxTokensClamp
+	// MaxTokensReject fails the request with a ContextExceededError.
+	MaxTokensReject
+)
+
+// ParseMaxTokensPolicy parses "clamp" or "reject".
+func ParseMaxTokensPolicy(s string) (MaxTokensPolicy, error) {
+	switch strings.ToLower(s) {
+	case "clamp":
+		return MaxTokensClamp, nil
+	case "reject":
+		return MaxTokensReject, nil
+	default:
+		return 0, fmt.Errorf("unknown max tokens policy %q (want clamp or reject)", s)
+	}
+}
+
+func (p MaxTokensPolicy) String() string {
+	switch p {
+	case MaxTokensPolicyDefault:
+		return "default"
+	case MaxTokensClamp:
+		return "clamp"
+	case MaxTokensReject:
+		return "reject"
+	default:
+		return "unknown"
+	}
+}
+
+// MaxTokensReason tells where the effective max_tokens of a prediction came
+// from.
+type MaxTokensReason int
+
+const (
+	// MaxTokensRequested means max_tokens is the request's.
+	MaxTokensRequested MaxTokensReason = iota
+	// MaxTokensContext means max_tokens is the room the prompt left in the
+	// slot's context, because the request asked for more (or, for a beam
+	// search, for no limit).
+	MaxTokensContext
+)
+
+func (r MaxTokensReason) String() string {
+	switch r {
+	case MaxTokensRequested:
+		return "requested"
+	case MaxTokensContext:
+		return "context"
+	default:
+		return "unknown"
+	}
+}
 
 // PredictArgs are the arguments for a prediction
 type PredictArgs struct {
@@ -29,11 +157,195 @@ type PredictArgs struct {
 	DiversityPenalty  float32
 	NoRepeatNgramSize int
 	RandomSeed        int
+
+	// ClientKey identifies the caller (e.g. its API key) for fair scheduling.
+	// Empty means anonymous.
+	ClientKey string
+
+	// RequestID identifies the request in logs, including the llama.cpp
+	// output it causes. Optional.
+	RequestID string
+
+	// StopTokenIDs end generation like an end-of-generation token, e.g. the
+	// end-of-turn token of a fine-tune whose GGUF does not flag it as such.
+	// The stop token is not part of the output.
+	StopTokenIDs []int
+
+	// BannedStrings are phrases the output must not contain: the token that
+	// would complete one is never sampled.
+	BannedStrings []string
+
+	// Regex, if set, constrains the output to match this regular expression
+	// (Go syntax) in full.
+	Regex string
+
+	// PromptLookup, if positive, enables prompt-lookup decoding with up to
+	// this many draft tokens per step.
+	PromptLookup int
+
+	// BeamWidth, if above 1, generates with a beam search over this many
+	// beams instead of sampling, so the sampling parameters are ignored.
+	// LengthPenalty then normalizes the beam scores; see Beam.Score.
+	// ReturnBeams fills Result.Beams with all finished beams.
+	BeamWidth   int
+	ReturnBeams bool
+
+	// Images are attached to the prompt for a vision model.
+	Images []Image
+
+	// SpecialTokens selects how generated control tokens are output, and
+	// SpecialToken receives them in SpecialTokensEvent mode.
+	SpecialTokens SpecialTokens
+	SpecialToken  SpecialTokenFunc
+
+	// MaxTokensPolicy applies when the prompt leaves less room than
+	// NPredict.
+	MaxTokensPolicy MaxTokensPolicy
+
+	// PrefillProgress, if set, reports prompt processing progress. It is
+	// called from the engine goroutine and must not block for long.
+	PrefillProgress PrefillProgressFunc
+}
+
+// Image is an encoded image file attached to a prompt.
+type Image struct {
+	Data     []byte
+	MimeType string
 }
 
-// PredictionsManager interface defines the operations for managing predictions
+var (
+	// ErrEngineStopped is returned for requests that were pending or running
+	// when the engine was stopped.
+	ErrEngineStopped = errors.New("engine stopped")
+	// ErrQueueFull is returned when the number of pending requests has
+	// reached Options.MaxQueue.
+	ErrQueueFull = errors.New("request queue is full")
+	// ErrModelBusy is returned when a request needs a different model than
+	// the one the running requests use.
+	ErrModelBusy = errors.New("cannot switch model while requests are active")
+	// ErrModelUnloaded is returned for requests still running on a model
+	// when it is unloaded.
+	ErrModelUnloaded = errors.New("model was unloaded")
+	// ErrInvalidGrammar is returned for an output constraint that cannot be
+	// compiled.
+	ErrInvalidGrammar = errors.New("invalid grammar")
+	// ErrNoProjector is returned for a request with images when the model
+	// has no multimodal projector to encode them. The engine cannot load
+	// projectors yet, so this applies to every model.
+	ErrNoProjector = errors.New("model has no multimodal projector loaded")
+)
+
+// ContextExceededError is returned when a prompt does not fit in a slot's
+// share of the context or, with MaxTokensReject, leaves less room than
+// MaxTokens.
+type ContextExceededError struct {
+	PromptTokens int
+	SlotBudget   int
+	MaxTokens    int // set if the prompt alone fits
+}
+
+func (e *ContextExceededError) Error() string {
+	if e.MaxTokens > 0 {
+		return fmt.Sprintf("prompt (%d tokens) and max_tokens (%d) exceed slot budget (%d)", e.PromptTokens, e.MaxTokens, e.SlotBudget)
+	}
+	return fmt.Sprintf("prompt (%d tokens) exceeds slot budget (%d)", e.PromptTokens, e.SlotBudget)
+}
+
+// FinishReason tells why generation of a prediction ended.
+type FinishReason int
+
+const (
+	// FinishStop means the model produced an end-of-generation or stop token.
+	FinishStop FinishReason = iota
+	// FinishLength means the token limit or the slot's context budget was reached.
+	FinishLength
+)
+
+func (r FinishReason) String() string {
+	switch r {
+	case FinishStop:
+		return "stop"
+	case FinishLength:
+		return "length"
+	default:
+		return "unknown"
+	}
+}
+
+// Result is the outcome of a completed prediction.
+type Result struct {
+	Text             string
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     FinishReason
+	Timings          Timings
+
+	// Prompt-lookup decoding: draft tokens proposed and accepted.
+	DraftTokens         int
+	DraftAcceptedTokens int
+
+	// Cached is set when the result was replayed from the completion cache
+	// instead of generated; its Timings then only cover the replay.
+	Cached bool
+
+	// MaxTokens is the token limit the prediction ran with and
+	// MaxTokensReason why it differs from the request's, if it does.
+	MaxTokens       int
+	MaxTokensReason MaxTokensReason
+
+	// Beams are the finished beams of a beam search, best first, if
+	// PredictArgs.ReturnBeams was set. Text is that of the first one.
+	Beams []Beam
+
+	// Annotations are added by the service's post-processing hooks.
+	Annotations map[string]string
+}
+
+// ModelContext is a loaded model and the context settings to run it with.
+// Zero fields fall back to the engine Options.
+type ModelContext struct {
+	Model       *llamacppbindings.Model
+	CtxSize     int
+	KvCacheType string // f16, q8_0 or q4_0
+	Batching    Batching
+	// Replicas are copies of Model for the engines of a Replicas, in
+	// engine order; empty if the model was loaded once.
+	Replicas []*llamacppbindings.Model
+
+	// Name and Quantization label the metric series of the model's
+	// requests, e.g. "qwen" and "Q4_K - Medium".
+	Name         string
+	Quantization string
+}
+
+// metricLabels returns the values of the model and quantization labels of
+// the model's metric series.
+func (m ModelContext) metricLabels() []string {
+	return []string{m.Name, m.Quantization}
+}
+
+// requestExemplar returns the exemplar labels that link an observation to
+// its request, or nil if the request has no ID.
+func requestExemplar(requestID string) metrics.Labels {
+	if requestID == "" {
+		return nil
+	}
+	return metrics.Labels{"request_id": requestID}
+}
+
+// PredictionsManager interface defines the operations for managing predictions.
+// Predict fails with ctx.Err() once ctx is done, whether the request is still
+// queued or already running. Stop cancels all predictions in flight, which
+// then fail with ErrEngineStopped. Release frees what the engine keeps on a
+// model that is about to be freed; no call may use the model any more.
 type PredictionsManager interface {
-	Predict(model *llamacppbindings.Model, prompt string, args PredictArgs, stream StreamFunc) (string, error)
+	Predict(ctx context.Context, model ModelContext, prompt string, args PredictArgs, stream StreamFunc) (Result, error)
+	Score(ctx context.Context, model ModelContext, text string, args PredictArgs) (ScoreResult, error)
+	Embed(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (EmbedResult, error)
+	Classify(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (ClassifyResult, error)
+	Bench(ctx context.Context, model ModelContext, opts BenchOptions) ([]BenchResult, error)
+	Stats() Stats
+	Release(model ModelContext)
 	Stop()
 }
 
@@ -45,25 +357,102 @@ type Options struct {
 	NThreads      int
 	NThreadsBatch int
 	FlashAttn     bool
+	KvCacheType   string // f16, q8_0 or q4_0; empty keeps the llama.cpp default
+
+	// MaxConcurrent limits how many requests are decoded at once. Zero or a
+	// value above NParallel means NParallel.
+	MaxConcurrent int
+	// MaxQueue limits how many requests may wait for a slot; further
+	// requests fail with ErrQueueFull. Zero means unlimited.
+	MaxQueue int
+
+	// Batching is the default of the scheduler knobs; a model's
+	// ModelContext.Batching overrides its non-zero fields.
+	Batching Batching
+
+	// SamplerCacheSize is how many sampler chains of finished requests are
+	// kept for reuse by requests with the same sampling parameters. Zero
+	// means 2*NParallel; a negative value disables the cache.
+	SamplerCacheSize int
+
+	// TenantWeights maps client keys to their share of the request queue.
+	// Keys not listed get weight 1.
+	TenantWeights map[string]float64
+
+	// SpecialTokens is the mode for requests that leave
+	// PredictArgs.SpecialTokens at SpecialTokensDefault. Its own default is
+	// SpecialTokensRender.
+	SpecialTokens SpecialTokens
+
+	// MaxTokensPolicy is the policy for requests that leave
+	// PredictArgs.MaxTokensPolicy at MaxTokensPolicyDefault. Its own default
+	// is MaxTokensClamp.
+	MaxTokensPolicy MaxTokensPolicy
+}
+
+// Stats is a point-in-time snapshot of engine utilization.
+type Stats struct {
+	NParallel     int
+	MaxConcurrent int
+	MaxQueue      int
+	ActiveSlots   int
+	QueueDepth    int
+	Tenants       []TenantQueueStats
+
+	// KvCells is the size of the shared KV cache and KvCellsUsed the cells
+	// held by all slots, as of the last decode. Both are 0 before the first
+	// request creates the context.
+	KvCells     int
+	KvCellsUsed int
 }
 
 // Engine implements continuous batching inference with a single shared
 // context and N concurrent slots. It satisfies PredictionsManager.
+// Embeddings are computed on a second context, see Embed.
 type Engine struct {
-	opts   Options
-	logger logging.SprintfLogger
+	opts         Options
+	logger       logging.SprintfLogger
+	nativeLogger logging.SprintfLogger // for llama.cpp output without a request
 
 	// llama.cpp state — owned by the run goroutine, never accessed concurrently
 	model   *llamacppbindings.Model
+	ctxSize int
 	vocab   *llamacppbindings.Vocab
 	context *llamacppbindings.Context
 	memory  *llamacppbindings.Memory
 	batch   *llamacppbindings.Batch
 	slots   []*slot
 
-	requests chan *request
-	quit     chan struct{}
-	done     chan struct{}
+	batching   Batching // of the current model
+	stepOffset int      // first slot considered by stepSlots
+
+	kvLabels []string // of the KV cache series of the current model
+
+	// reused by every tick to spare allocations
+	tokens    *tokenTable
+	targets   []sampleTarget
+	prefilled []*slot
+	sampleSet llamacppbindings.SampleSet
+
+	// batchLog is the logger of decodes, built by batchLogger and reset
+	// whenever a slot is assigned or finished.
+	batchLog logging.SprintfLogger
+
+	embedder *embedder
+	samplers *samplerCache // owned by the run goroutine
+	beamSem  chan struct{} // held by the running beam search
+
+	queue       *fairQueue
+	activeSlots atomic.Int32
+	kvCells     atomic.Int64
+	kvCellsUsed atomic.Int64
+	releases    chan releaseRequest
+	quit        chan struct{}
+	done        chan struct{}
+
+	// stopCtx is canceled by Stop; every request context derives from it.
+	stopCtx    context.Context
+	cancelStop context.CancelCauseFunc
 }
 
 var _ PredictionsManager = (*Engine)(nil)
@@ -80,53 +469,158 @@ func New(opts Options, logger logging.SprintfLogger) *Engine {
 	if opts.BatchSize <= 0 {
 		opts.BatchSize = 2048
 	}
+	if opts.MaxConcurrent <= 0 || opts.MaxConcurrent > opts.NParallel {
+		opts.MaxConcurrent = opts.NParallel
+	}
+	if opts.MaxQueue < 0 {
+		opts.MaxQueue = 0
+	}
+	opts.Batching = Batching{}.override(opts.Batching)
+	if opts.SamplerCacheSize == 0 {
+		opts.SamplerCacheSize = 2 * opts.NParallel
+	} else if opts.SamplerCacheSize < 0 {
+		opts.SamplerCacheSize = 0
+	}
+	if opts.SpecialTokens == SpecialTokensDefault {
+		opts.SpecialTokens = SpecialTokensRender
+	}
+	if opts.MaxTokensPolicy == MaxTokensPolicyDefault {
+		opts.MaxTokensPolicy = MaxTokensClamp
+	}
 
 	e := &Engine{
-		opts:     opts,
-		logger:   logger.With("module", "engine"),
-		requests: make(chan *request, opts.NParallel*2),
-		quit:     make(chan struct{}),
-		done:     make(chan struct{}),
+		opts:         opts,
+		logger:       logger.With("module", "engine"),
+		nativeLogger: logger.With("module", "llama.cpp"),
+		embedder:     newEmbedder(opts, logger.With("module", "embedder")),
+		samplers:     newSamplerCache(opts.SamplerCacheSize),
+		beamSem:      make(chan struct{}, 1),
+		queue:        newFairQueue(opts.TenantWeights, opts.MaxQueue),
+		releases:     make(chan releaseRequest),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
 	}
+	e.stopCtx, e.cancelStop = context.WithCancelCause(context.Background())
 
 	go e.run()
 	return e
 }
 
-// Predict submits a request to the engine and blocks until completion.
+// Predict submits a request to the engine and blocks until completion or
+// until ctx is done.
 func (e *Engine) Predict(
-	model *llamacppbindings.Model,
+	ctx context.Context,
+	model ModelContext,
 	prompt string,
 	args PredictArgs,
 	stream StreamFunc,
-) (string, error) {
-	req := &request{
+) (Result, error) {
+	if len(args.Images) > 0 {
+		return Result{}, ErrNoProjector
+	}
+	if args.SpecialTokens == SpecialTokensDefault {
+		args.SpecialTokens = e.opts.SpecialTokens
+	}
+	if args.MaxTokensPolicy == MaxTokensPolicyDefault {
+		args.MaxTokensPolicy = e.opts.MaxTokensPolicy
+	}
+	if args.BeamWidth > 1 {
+		return e.beamSearch(ctx, model, prompt, args, stream)
+	}
+	ctx, cancel := e.requestContext(ctx)
+	defer cancel()
+	res, err := e.submit(ctx, &request{
 		model:  model,
 		prompt: prompt,
 		args:   args,
 		stream: stream,
-		done:   make(chan requestResult, 1),
+	})
+	return res.result, err
+}
+
+// requestContext returns a context for one request that is also canceled,
+// with ErrEngineStopped as its cause, when the engine stops.
+func (e *Engine) requestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := context.AfterFunc(e.stopCtx, func() { cancel(ErrEngineStopped) })
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// submit queues a request and waits for its result or until ctx is done.
+func (e *Engine) submit(ctx context.Context, req *request) (requestResult, error) {
+	if err := context.Cause(ctx); err != nil {
+		return requestResult{}, err
+	}
+	req.ctx = ctx
+	req.done = make(chan requestResult, 1)
+	req.submitTime = time.Now()
+
+	if err := e.queue.push(req); err != nil {
+		return requestResult{}, err
 	}
 
 	select {
-	case e.requests <- req:
-	case <-e.quit:
-		return "", fmt.Errorf("engine stopped")
+	case res := <-req.done:
+		return res, res.err
+	case <-ctx.Done():
+		if e.queue.remove(req) {
+			return requestResult{}, context.Cause(ctx)
+		}
+		// The request is running; the engine drops it on its next tick.
+		res := <-req.done
+		return res, res.err
 	}
+}
+
+// Stats returns the current slot utilization and queue depths.
+func (e *Engine) Stats() Stats {
+	return Stats{
+		NParallel:     e.opts.NParallel,
+		MaxConcurrent: e.opts.MaxConcurrent,
+		MaxQueue:      e.opts.MaxQueue,
+		ActiveSlots:   int(e.activeSlots.Load()),
+		QueueDepth:    e.queue.len(),
+		Tenants:       e.queue.stats(),
+		KvCells:       int(e.kvCells.Load()),
+		KvCellsUsed:   int(e.kvCellsUsed.Load()),
+	}
+}
+
+// releaseRequest asks a run goroutine to free its context on model.
+type releaseRequest struct {
+	model *llamacppbindings.Model
+	done  chan struct{}
+}
 
-	res := <-req.done
-	return res.text, res.err
+// Release frees the generation and embedding contexts if they were created
+// for model.
+func (e *Engine) Release(model ModelContext) {
+	r := releaseRequest{model: model.Model, done: make(chan struct{})}
+	select {
+	case e.releases <- r:
+		<-r.done
+	case <-e.done:
+	}
+	e.embedder.release(r.model)
 }
 
 // Stop shuts down the engine and waits for the run goroutine to finish.
+// The contexts of queued and running requests are canceled first, so a
+// request blocked outside the engine (e.g. on a slow stream) gives up too;
+// they all fail with ErrEngineStopped.
 func (e *Engine) Stop() {
 	select {
 	case <-e.quit:
 		return
 	default:
+		e.cancelStop(ErrEngineStopped)
 		close(e.quit)
 	}
 	<-e.done
+	e.embedder.stop()
 }
 
 // ---------------------------------------------------------------------------
@@ -135,19 +629,28 @@ func (e *Engine) Stop() {
 
 func (e *Engine) run() {
 	defer close(e.done)
-	e.logger.Infof("started (nParallel=%d, ctxSize=%d, batchSize=%d)",
-		e.opts.NParallel, e.opts.CtxSize, e.opts.BatchSize)
+	e.logger.Infof("started (nParallel=%d, maxConcurrent=%d, maxQueue=%d, ctxSize=%d, batchSize=%d, maxBatchTokens=%d, maxBatchSeqs=%d, batchWait=%s)",
+		e.opts.NParallel, e.opts.MaxConcurrent, e.opts.MaxQueue, e.opts.CtxSize, e.opts.BatchSize,
+		e.opts.Batching.MaxTokens, e.opts.Batching.MaxSeqs, e.opts.Batching.MaxWait)
 
 	for {
 		// When idle, block waiting for a request or shutdown signal.
 		if !e.hasActiveSlots() {
-			select {
-			case req := <-e.requests:
-				e.handleRequest(req)
-			case <-e.quit:
-				e.shutdown()
-				return
+			req := e.queue.pop()
+			if req == nil {
+				select {
+				case <-e.queue.ready:
+					continue
+				case r := <-e.releases:
+					e.release(r)
+					continue
+				case <-e.quit:
+					e.shutdown()
+					return
+				}
 			}
+			e.handleRequest(req)
+			e.waitForBatch()
 		}
 
 		// Non-blocking: assign any additional queued requests to idle slots.
@@ -155,9 +658,11 @@ func (e *Engine) run() {
 
 		select {
 		case <-e.quit:
-			e.abortAll(fmt.Errorf("engine stopped"))
+			e.abortAll(ErrEngineStopped)
 			e.shutdown()
 			return
+		case r := <-e.releases:
+			e.release(r)
 		default:
 		}
 
@@ -166,12 +671,17 @@ func (e *Engine) run() {
 				e.logger.Errorf("fatal tick error: %v", err)
 				e.abortAll(err)
 			}
+			e.updateKvUsage()
 		}
 	}
 }
 
 func (e *Engine) handleRequest(req *request) {
-	if err := e.ensureContext(req.model); err != nil {
+	if err := context.Cause(req.ctx); err != nil {
+		req.done <- requestResult{err: err}
+		return
+	}
+	if err := e.ensureContext(req); err != nil {
 		req.done <- requestResult{err: err}
 		return
 	}
@@ -191,18 +701,21 @@ func (e *Engine) drainPendingRequests() {
 		if idle == nil {
 			return
 		}
-		select {
-		case req := <-e.requests:
-			if err := e.ensureContext(req.model); err != nil {
-				req.done <- requestResult{err: err}
-				continue
-			}
-			if err := e.assignSlot(idle, req); err != nil {
-				req.done <- requestResult{err: err}
-			}
-		default:
+		req := e.queue.pop()
+		if req == nil {
 			return
 		}
+		if err := context.Cause(req.ctx); err != nil {
+			req.done <- requestResult{err: err}
+			continue
+		}
+		if err := e.ensureContext(req); err != nil {
+			req.done <- requestResult{err: err}
+			continue
+		}
+		if err := e.assignSlot(idle, req); err != nil {
+			req.done <- requestResult{err: err}
+		}
 	}
 }
 
@@ -210,22 +723,81 @@ func (e *Engine) drainPendingRequests() {
 // context lifecycle
 // ---------------------------------------------------------------------------
 
-func (e *Engine) ensureContext(model *llamacppbindings.Model) error {
-	if e.context != nil && e.model == model {
+func (e *Engine) ensureContext(req *request) error {
+	if e.context != nil && e.model == req.model.Model {
 		return nil
 	}
 	if e.context != nil {
 		if e.hasActiveSlots() {
-			return fmt.Errorf("cannot switch model while requests are active")
+			return ErrModelBusy
 		}
 		e.teardown()
 	}
-	return e.initContext(model)
+	var err error
+	llamacppbindings.WithNativeLogger(e.requestLogger(req.args.RequestID), func() {
+		err = e.initContext(req.model)
+	})
+	return err
+}
+
+// requestLogger returns the logger for llama.cpp output caused by a request.
+func (e *Engine) requestLogger(requestID string) logging.SprintfLogger {
+	if requestID == "" {
+		return e.nativeLogger
+	}
+	return e.nativeLogger.With("request", requestID)
+}
+
+// batchLogger returns the logger for llama.cpp output of a decode: the
+// request's logger if only one slot is active, otherwise one naming all
+// requests in the batch. It is built once per change of the active slots.
+func (e *Engine) batchLogger() logging.SprintfLogger {
+	if e.batchLog == nil {
+		e.batchLog = e.newBatchLogger()
+	}
+	return e.batchLog
+}
+
+func (e *Engine) newBatchLogger() logging.SprintfLogger {
+	var active []*slot
+	for _, s := range e.slots {
+		if s.state != slotIdle {
+			active = append(active, s)
+		}
+	}
+	if len(active) == 1 {
+		return active[0].logger
+	}
+	ids := make([]string, 0, len(active))
+	for _, s := range active {
+		if s.requestID != "" {
+			ids = append(ids, s.requestID)
+		}
+	}
+	if len(ids) == 0 {
+		return e.nativeLogger
+	}
+	return e.nativeLogger.With("requests", strings.Join(ids, ","))
 }
 
-func (e *Engine) initContext(model *llamacppbindings.Model) error {
+func (e *Engine) kvCacheType(model ModelContext) string {
+	if model.KvCacheType != "" {
+		return model.KvCacheType
+	}
+	return e.opts.KvCacheType
+}
+
+func (e *Engine) initContext(model ModelContext) error {
+	ctxSize := e.opts.CtxSize
+	if model.CtxSize > 0 {
+		ctxSize = model.CtxSize
+	}
+	if nCtxTrain := model.Model.NCtxTrain(); nCtxTrain > 0 && ctxSize > nCtxTrain {
+		e.logger.Warnf("context size %d exceeds the %d tokens the model was trained with; output may degrade past them",
+			ctxSize, nCtxTrain)
+	}
 	params := llamacppbindings.NewContextDefaultParams()
-	params.SetNCtx(e.opts.CtxSize)
+	params.SetNCtx(ctxSize)
 	params.SetNBatch(e.opts.BatchSize)
 	params.SetNSeqMax(e.opts.NParallel)
 	params.SetNThreads(e.opts.NThreads)
@@ -233,8 +805,11 @@ func (e *Engine) initContext(model *llamacppbindings.Model) error {
 	if e.opts.FlashAttn {
 		params.SetFlashAttention(true)
 	}
+	if kvCacheType := e.kvCacheType(model); kvCacheType != "" {
+		params.SetTypeKV(kvCacheType)
+	}
 
-	ctx, err := llamacppbindings.NewContext(model, params)
+	ctx, err := llamacppbindings.NewContext(model.Model, params)
 	if err != nil {
 		return fmt.Errorf("create shared context: %w", err)
 	}
@@ -245,23 +820,31 @@ func (e *Engine) initContext(model *llamacppbindings.Model) error {
 		return fmt.Errorf("context has no memory")
 	}
 
-	e.model = model
-	e.vocab = model.Vocab()
+	e.model = model.Model
+	e.ctxSize = ctxSize
+	e.vocab = model.Model.Vocab()
+	e.tokens = newTokenTable(e.vocab)
 	e.context = ctx
 	e.memory = mem
 	e.batch = llamacppbindings.BatchInit(e.opts.BatchSize, 0, e.opts.NParallel)
+	e.batching = e.opts.Batching.override(model.Batching)
+	e.stepOffset = 0
+	e.kvLabels = model.metricLabels()
 
 	e.slots = make([]*slot, e.opts.NParallel)
 	for i := range e.slots {
 		e.slots[i] = &slot{id: i, seqId: i, state: slotIdle}
 	}
+	e.batchLog = nil
 
-	e.logger.Infof("shared context ready (nCtx=%d, nBatch=%d, slots=%d)",
-		e.opts.CtxSize, e.opts.BatchSize, e.opts.NParallel)
+	e.updateKvUsage()
+	e.logger.Infof("shared context ready (nCtx=%d, nBatch=%d, slots=%d, maxBatchTokens=%d, maxBatchSeqs=%d, batchWait=%s)",
+		ctxSize, e.opts.BatchSize, e.opts.NParallel, e.batching.MaxTokens, e.batching.MaxSeqs, e.batching.MaxWait)
 	return nil
 }
 
 func (e *Engine) teardown() {
+	e.samplers.clear()
 	if e.batch != nil {
 		e.batch.Free()
 		e.batch = nil
@@ -273,18 +856,59 @@ func (e *Engine) teardown() {
 	e.memory = nil
 	e.model = nil
 	e.vocab = nil
+	e.tokens = nil
 	e.slots = nil
+	e.updateKvUsage()
+}
+
+var (
+	kvCacheCells = metrics.NewGauge("llamacpp_kv_cache_cells",
+		"Size of the shared KV cache in cells (tokens).",
+		"model", "quantization")
+	kvCacheUsedCells = metrics.NewGauge("llamacpp_kv_cache_used_cells",
+		"KV cache cells held by all slots.",
+		"model", "quantization")
+)
+
+// updateKvUsage publishes the KV cache size and usage for Stats. It must
+// run on the engine goroutine, which owns the context. Without a context
+// it drops the series of the model it had.
+func (e *Engine) updateKvUsage() {
+	var cells, used int
+	if e.context != nil {
+		cells = e.context.NCells()
+		used = e.context.NCellsUsed()
+	}
+	e.kvCells.Store(int64(cells))
+	e.kvCellsUsed.Store(int64(used))
+	if e.kvLabels == nil {
+		return
+	}
+	if e.context == nil {
+		kvCacheCells.Delete(e.kvLabels...)
+		kvCacheUsedCells.Delete(e.kvLabels...)
+		e.kvLabels = nil
+		return
+	}
+	kvCacheCells.Set(float64(cells), e.kvLabels...)
+	kvCacheUsedCells.Set(float64(used), e.kvLabels...)
+}
+
+// release tears the context down if it belongs to the released model. No
+// slot should still run the model; any that does is aborted rather than left
+// to use freed memory.
+func (e *Engine) release(r releaseRequest) {
+	if e.context != nil && e.model == r.model {
+		e.abortAll(ErrModelUnloaded)
+		e.teardown()
+	}
+	close(r.done)
 }
 
 func (e *Engine) shutdown() {
 	e.teardown()
-	for {
-		select {
-		case req := <-e.requests:
-			req.done <- requestResult{err: fmt.Errorf("engine stopped")}
-		default:
-			return
-		}
+	for _, req := range e.queue.close() {
+		req.done <- requestResult{err: ErrEngineStopped}
 	}
 }
 
@@ -301,7 +925,12 @@ func (e *Engine) hasActiveSlots() bool {
 	return false
 }
 
+// findIdleSlot returns a slot for the next request, or nil if all slots are
+// busy or MaxConcurrent requests are already running.
 func (e *Engine) findIdleSlot() *slot {
+	if int(e.activeSlots.Load()) >= e.opts.MaxConcurrent {
+		return nil
+	}
 	for _, s := range e.slots {
 		if s.state == slotIdle {
 			return s
@@ -311,30 +940,74 @@ func (e *Engine) findIdleSlot() *slot {
 }
 
 func (e *Engine) assignSlot(s *slot, req *request) error {
-	tokens, err := e.vocab.Tokenize(req.prompt, true, true)
+	logger := e.requestLogger(req.args.RequestID)
+	var err error
+	llamacppbindings.WithNativeLogger(logger, func() {
+		err = e.prepareSlot(s, req, logger)
+	})
+	e.batchLog = nil
+	return err
+}
+
+func (e *Engine) prepareSlot(s *slot, req *request, logger logging.SprintfLogger) error {
+	tokens, err := e.vocab.AppendTokens(s.tokenBuf[:0], req.prompt, true, true)
 	if err != nil {
 		return fmt.Errorf("tokenize: %w", err)
 	}
+	// A prompt too long for the context is rejected below; its buffer is
+	// not worth keeping.
+	if cap(tokens) <= e.ctxSize {
+		s.tokenBuf = tokens
+	}
 
-	perSlotCtx := e.opts.CtxSize / e.opts.NParallel
-	maxTokens := req.args.NPredict
-	if len(tokens)+maxTokens > perSlotCtx {
-		maxTokens = perSlotCtx - len(tokens)
-		if maxTokens <= 0 {
-			return fmt.Errorf("prompt (%d tokens) exceeds slot budget (%d)", len(tokens), perSlotCtx)
+	perSlotCtx := e.ctxSize / e.opts.NParallel
+	if req.score {
+		if err := e.prepareScoreSlot(s, req, tokens, perSlotCtx); err != nil {
+			return err
 		}
+		s.logger = logger
+		e.activeSlots.Add(1)
+		e.logger.Infof("slot %d: assigned for scoring (tokens=%d, seqId=%d, tenant=%s, request=%s)",
+			s.id, len(tokens), s.seqId, TenantID(req.args.ClientKey), req.args.RequestID)
+		return nil
+	}
+	if req.args.MaxKvSize > 0 && req.args.MaxKvSize < perSlotCtx {
+		perSlotCtx = req.args.MaxKvSize
+	}
+	maxTokens, maxTokensReason, err := maxTokensFor(req.args, len(tokens), perSlotCtx)
+	if err != nil {
+		return err
+	}
+
+	bans, err := newBanList(e.vocab, req.args.BannedStrings)
+	if err != nil {
+		return err
 	}
 
-	chain, sampler, err := buildSamplerChain(req.args, e.logger)
+	key, chain, err := e.samplers.get(req.args, e.vocab, e.logger)
 	if err != nil {
 		return err
 	}
+	acceptPrompt(chain, tokens)
 
 	e.memory.SeqRm(s.seqId, -1, -1)
-	s.assign(tokens, maxTokens, chain, sampler, req)
+	s.assign(tokens, maxTokens, chain, chain.Sampler(), req)
+	s.maxTokensReason = maxTokensReason
+	s.samplerKey = key
+	s.logger = logger
+	if len(bans) > 0 {
+		s.bans = bans
+	}
+	if n := req.args.NoRepeatNgramSize; n > 0 {
+		s.noRepeat = newNgramIndex(n, tokens)
+	}
+	if s.masksLogits() && s.history == nil {
+		s.history = slices.Clone(tokens)
+	}
+	e.activeSlots.Add(1)
 
-	e.logger.Infof("slot %d: assigned (prompt=%d, maxGen=%d, seqId=%d)",
-		s.id, len(tokens), maxTokens, s.seqId)
+	e.logger.Infof("slot %d: assigned (prompt=%d, maxGen=%d, seqId=%d, tenant=%s, request=%s)",
+		s.id, len(tokens), maxTokens, s.seqId, TenantID(req.args.ClientKey), req.args.RequestID)
 	return nil
 }
 
@@ -344,13 +1017,23 @@ func (e *Engine) finishSlot(s *slot, err error) {
 	dur := time.Since(s.startTime)
 	if err != nil {
 		e.logger.Infof("slot %d: error after %s: %v", s.id, dur, err)
+	} else if s.scoring {
+		e.logger.Infof("slot %d: scored %d tokens in %s", s.id, len(s.logprobs), dur)
 	} else if dur.Seconds() > 0 {
 		tps := float64(s.generated) / dur.Seconds()
-		e.logger.Infof("slot %d: done (%d tokens, %s, %.1f tok/s)",
-			s.id, s.generated, dur, tps)
+		e.logger.Infof("slot %d: done (%d tokens, %s, %.1f tok/s, ttft=%s)",
+			s.id, s.generated, dur, tps, s.timings().TimeToFirstToken)
 	}
 
+	if s.samplerChain != nil {
+		e.samplers.put(s.samplerKey, s.samplerChain)
+		s.samplerChain = nil
+		s.sampler = nil
+	}
 	s.finish(err)
+	e.batchLog = nil
+	e.activeSlots.Add(-1)
+	e.updateKvUsage()
 }
 
 func (e *Engine) abortAll(err error) {
@@ -367,30 +1050,72 @@ func (e *Engine) abortAll(err error) {
 
 type sampleTarget struct {
 	slotIdx  int
-	batchIdx int // position in the batch array — passed to llama_sampler_sample
+	batchIdx int   // position in the batch array — passed to llama_sampler_sample
+	draft    []int // prompt-lookup tokens fed after batchIdx, to verify
+	sample   int   // index in e.sampleSet if sampled by the decode call, or -1
 }
 
 func (e *Engine) tick() error {
+	// Drop requests whose caller went away before spending a decode on them.
+	for _, s := range e.slots {
+		if s.state == slotIdle {
+			continue
+		}
+		if err := context.Cause(s.ctx); err != nil {
+			e.logger.Debugf("slot %d: canceled by caller", s.id)
+			e.finishSlot(s, err)
+		}
+	}
+	if !e.hasActiveSlots() {
+		return nil
+	}
+
 	e.batch.Clear()
-	var targets []sampleTarget
+	targets := e.targets[:0]
+	defer func() { e.targets = targets[:0] }()
 
 	// Phase 1: decode tokens from generating slots (one token each, highest
-	// priority because they are blocking streaming output).
-	for i, s := range e.slots {
-		if s.state != slotGenerating {
-			continue
-		}
+	// priority because they are blocking streaming output), followed by
+	// prompt-lookup drafts in the capacity the other slots leave.
+	maxTokens := e.stepTokens()
+	generating, prefilling := e.stepSlots(maxTokens)
+	spare := maxTokens - len(generating)
+	for _, i := range generating {
+		s := e.slots[i]
 		batchIdx := e.batch.NTokens()
 		e.batch.Add(s.nextToken, s.pos, s.seqId, true)
 		s.pos++
-		targets = append(targets, sampleTarget{slotIdx: i, batchIdx: batchIdx})
+		var draft []int
+		if s.lookup > 0 {
+			draft = lookupDraft(s.history, min(s.lookup, s.maxTokens-s.generated-1, spare))
+			for _, token := range draft {
+				e.batch.Add(token, s.pos, s.seqId, true)
+				s.pos++
+			}
+			spare -= len(draft)
+		}
+		targets = append(targets, sampleTarget{slotIdx: i, batchIdx: batchIdx, draft: draft, sample: -1})
 	}
 
 	// Phase 2: fill remaining capacity with prefill chunks. Long prompts
 	// are split across ticks so generating slots aren't starved.
-	remaining := e.batch.Cap() - e.batch.NTokens()
-	for i, s := range e.slots {
-		if s.state != slotPrefilling || remaining <= 0 {
+	prefilled := e.prefilled[:0]
+	defer func() { e.prefilled = prefilled[:0] }()
+	var scoreTargets []scoreTarget
+	remaining := maxTokens - e.batch.NTokens()
+	for _, i := range prefilling {
+		s := e.slots[i]
+		if remaining <= 0 {
+			break
+		}
+
+		if s.scoring {
+			var n int
+			n, scoreTargets = e.addScoreChunk(s, remaining, scoreTargets)
+			remaining -= n
+			if s.prefillProgress != nil {
+				prefilled = append(prefilled, s)
+			}
 			continue
 		}
 
@@ -399,6 +1124,9 @@ func (e *Engine) tick() error {
 		if chunk > remaining {
 			chunk = remaining
 		}
+		if s.prefillStep > 0 && chunk > s.prefillStep {
+			chunk = s.prefillStep
+		}
 
 		for j := 0; j < chunk; j++ {
 			last := s.prefillIdx+j+1 == len(s.promptTokens)
@@ -407,12 +1135,15 @@ func (e *Engine) tick() error {
 			s.pos++
 
 			if last {
-				targets = append(targets, sampleTarget{slotIdx: i, batchIdx: batchIdx})
+				targets = append(targets, sampleTarget{slotIdx: i, batchIdx: batchIdx, sample: -1})
 			}
 		}
 
 		s.prefillIdx += chunk
 		remaining -= chunk
+		if s.prefillProgress != nil {
+			prefilled = append(prefilled, s)
+		}
 
 		if s.prefillIdx >= len(s.promptTokens) {
 			s.state = slotGenerating
@@ -424,46 +1155,144 @@ func (e *Engine) tick() error {
 		return nil
 	}
 
-	// Phase 3: single decode call for the entire batch.
-	if err := e.context.Decode(e.batch); err != nil {
-		return fmt.Errorf("decode: %w", err)
+	// Phase 3: single decode call for the entire batch, which also samples
+	// the first token of each target whose logits need no banning.
+	e.sampleSet.Reset()
+	for k := range targets {
+		if s := e.slots[targets[k].slotIdx]; !s.masksLogits() {
+			targets[k].sample = e.sampleSet.Add(s.sampler, targets[k].batchIdx)
+		}
+	}
+	var decodeErr error
+	if llamacppbindings.NativeLogEnabled() {
+		llamacppbindings.WithNativeLogger(e.batchLogger(), func() {
+			decodeErr = e.context.DecodeAndSample(e.batch, &e.sampleSet)
+		})
+	} else {
+		decodeErr = e.context.DecodeAndSample(e.batch, &e.sampleSet)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("decode: %w", decodeErr)
+	}
+
+	for _, s := range prefilled {
+		if err := s.prefillProgress(s.prefillIdx, len(s.promptTokens)); err != nil {
+			e.finishSlot(s, err)
+		}
 	}
 
+	e.collectScores(scoreTargets)
+
 	// Phase 4: sample at each target's batch position and dispatch results.
 	// llama_sampler_sample takes the batch index (not a contiguous output index).
 	for _, t := range targets {
 		s := e.slots[t.slotIdx]
-		token := s.sampler.Sample(e.context, t.batchIdx)
-
-		if e.vocab.IsEog(token) {
-			e.logger.Debugf("slot %d: EoG", s.id)
-			e.finishSlot(s, nil)
-			continue
+		if s.state == slotIdle {
+			continue // finished by a failed progress callback above
 		}
-
-		if s.generated >= s.maxTokens {
-			e.logger.Debugf("slot %d: max tokens reached (%d)", s.id, s.maxTokens)
-			e.finishSlot(s, nil)
-			continue
+		var token int
+		if t.sample >= 0 {
+			token = e.sampleSet.Token(t.sample)
+		} else {
+			token = e.sample(s, t.batchIdx)
+		}
+		accepted := 0
+		for e.emitToken(s, token) && accepted < len(t.draft) && token == t.draft[accepted] {
+			accepted++
+			token = e.sample(s, t.batchIdx+accepted)
 		}
 
-		piece, err := e.vocab.TokenToPiece(token)
-		if err != nil {
-			e.finishSlot(s, fmt.Errorf("token to piece: %w", err))
-			continue
+		if len(t.draft) > 0 {
+			s.draftTokens += len(t.draft)
+			s.draftAccepted += accepted
+			draftTokensTotal.Add(float64(len(t.draft)), s.metricLabels...)
+			draftAcceptedTokensTotal.Add(float64(accepted), s.metricLabels...)
+			if s.state != slotIdle {
+				// Forget the rejected draft tokens.
+				s.pos -= len(t.draft) - accepted
+				e.memory.SeqRm(s.seqId, s.pos, -1)
+			}
 		}
+	}
 
-		if s.stream != nil {
-			if err := s.stream(token, s.inputCount+s.generated, piece); err != nil {
-				e.finishSlot(s, err)
-				continue
+	return nil
+}
+
+// sample samples the next token of s from the logits at batchIdx.
+func (e *Engine) sample(s *slot, batchIdx int) int {
+	if s.masksLogits() {
+		if logits, err := e.context.Logits(batchIdx); err == nil {
+			s.bans.apply(logits, s.history)
+			if s.noRepeat != nil {
+				s.noRepeat.block(logits, s.history)
 			}
 		}
+	}
+	return s.sampler.Sample(e.context, batchIdx)
+}
 
-		s.response.WriteString(piece)
-		s.generated++
-		s.nextToken = token
+// emitToken dispatches a token sampled for s and reports whether s goes on
+// generating.
+func (e *Engine) emitToken(s *slot, token int) bool {
+	if e.tokens.isEog(token) {
+		e.logger.Debugf("slot %d: EoG", s.id)
+		s.finishReason = FinishStop
+		e.finishSlot(s, nil)
+		return false
 	}
 
-	return nil
+	if slices.Contains(s.stopTokens, token) {
+		e.logger.Debugf("slot %d: stop token %d", s.id, token)
+		s.finishReason = FinishStop
+		e.finishSlot(s, nil)
+		return false
+	}
+
+	if s.generated >= s.maxTokens {
+		e.logger.Debugf("slot %d: max tokens reached (%d)", s.id, s.maxTokens)
+		s.finishReason = FinishLength
+		e.finishSlot(s, nil)
+		return false
+	}
+
+	s.recordToken()
+
+	if s.specialTokens != SpecialTokensRender && e.tokens.isControl(token) {
+		return e.emitControlToken(s, token)
+	}
+
+	piece, err := e.tokens.piece(token)
+	if err != nil {
+		e.finishSlot(s, fmt.Errorf("token to piece: %w", err))
+		return false
+	}
+
+	if s.stream != nil {
+		if err := s.stream([]int{token}, s.inputCount, s.generated+1, piece); err != nil {
+			e.finishSlot(s, err)
+			return false
+		}
+	}
+
+	s.response.WriteString(piece)
+	s.advance(token)
+	return true
+}
+
+// emitControlToken outputs a control token in skip or event mode: it stays
+// out of the text and, in event mode, goes to the slot's SpecialTokenFunc.
+func (e *Engine) emitControlToken(s *slot, token int) bool {
+	if s.specialTokens == SpecialTokensEvent && s.specialToken != nil {
+		piece, err := e.tokens.piece(token)
+		if err != nil {
+			e.finishSlot(s, fmt.Errorf("token to piece: %w", err))
+			return false
+		}
+		if err := s.specialToken(token, s.inputCount, s.generated+1, piece); err != nil {
+			e.finishSlot(s, err)
+			return false
+		}
+	}
+	s.advance(token)
+	return true
 }
@@ -0,0 +1,59 @@
+package inferenceengine
+
+import "github.com/hypernetix/llamacpp_server/internal/metrics"
+
+// Prompt-lookup decoding: a draft-free form of speculative decoding. The
+// last tokens of a sequence are looked up in the sequence itself (prompt and
+// generated text); the tokens that followed the most recent earlier match are
+// fed as a draft behind the next token and verified in the same decode.
+// Outputs that copy from the prompt, like summaries and extractions, accept
+// long drafts. The verification samples every position with the request's
+// own sampler and keeps a draft token only if it equals the sample, so the
+// output distribution is unchanged.
+
+// N-gram sizes tried for a match, longest first. Single tokens match too
+// often to be good predictors.
+const (
+	lookupNgramMax = 3
+	lookupNgramMin = 2
+)
+
+var (
+	draftTokensTotal = metrics.NewCounter("llamacpp_draft_tokens_total",
+		"Draft tokens proposed by prompt-lookup decoding.",
+		"model", "quantization")
+	draftAcceptedTokensTotal = metrics.NewCounter("llamacpp_draft_accepted_tokens_total",
+		"Draft tokens proposed by prompt-lookup decoding that the model accepted.",
+		"model", "quantization")
+)
+
+// lookupDraft returns up to n tokens that followed the most recent earlier
+// occurrence of the longest matching suffix n-gram of history.
+func lookupDraft(history []int, n int) []int {
+	if n <= 0 {
+		return nil
+	}
+	for size := lookupNgramMax; size >= lookupNgramMin; size-- {
+		if len(history) <= size {
+			continue
+		}
+		suffix := history[len(history)-size:]
+		for i := len(history) - size - 1; i >= 0; i-- {
+			if !equalTokens(history[i:i+size], suffix) {
+				continue
+			}
+			start := i + size
+			return history[start:min(start+n, len(history))]
+		}
+	}
+	return nil
+}
+
+func equalTokens(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package inferenceengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupDraft(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []int
+		n       int
+		want    []int
+	}{
+		{"no match", []int{1, 2, 3, 4, 5}, 4, nil},
+		{"trigram", []int{1, 2, 3, 4, 5, 9, 1, 2, 3}, 2, []int{4, 5}},
+		{"capped by history", []int{7, 8, 9, 7, 8}, 5, []int{9, 7, 8}},
+		{"most recent match", []int{1, 2, 3, 1, 2, 4, 1, 2}, 1, []int{4}},
+		{"longest n-gram first", []int{5, 1, 2, 6, 0, 1, 2, 7, 5, 1, 2}, 1, []int{6}},
+		{"unigram ignored", []int{1, 2, 3, 1}, 2, nil},
+		{"disabled", []int{1, 2, 1, 2}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, lookupDraft(tt.history, tt.n))
+		})
+	}
+}
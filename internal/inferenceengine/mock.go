@@ -0,0 +1,324 @@
+package inferenceengine
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+)
+
+// MockOptions configures the mock engine.
+type MockOptions struct {
+	NParallel int
+	// MaxQueue limits how many requests may wait for a slot, as in Options.
+	MaxQueue int
+	// TokenDelay is the time spent per generated token; prompt tokens take a
+	// tenth of it.
+	TokenDelay time.Duration
+	// EmbeddingSize is the dimension of the embeddings; 0 means 384.
+	EmbeddingSize int
+}
+
+// ErrMockUnsupported is returned by the mock engine for operations it does
+// not simulate.
+var ErrMockUnsupported = errors.New("not supported by the mock backend")
+
+// mockWords are the pieces the mock engine generates; token IDs are their
+// index plus mockTokenBase.
+var mockWords = []string{
+	"the", "model", "server", "token", "stream", "request", "quick", "brown",
+	"fox", "jumps", "over", "lazy", "dog", "and", "a", "of", "to", "in", "is",
+	"it", "that", "with", "for", "on", "as", "was", "by", "at", "this", "from",
+	"batch", "slot", "cache", "prompt",
+}
+
+const mockTokenBase = 1000
+
+// MockEngine is a PredictionsManager that generates deterministic synthetic
+// output without a model, for API-level tests, client development and load
+// tests of the serving layers. The output depends only on the prompt, the
+// token limit and, when sampling, the seed; other sampling parameters are
+// accepted and ignored. Prompt tokens are whitespace-separated words.
+type MockEngine struct {
+	opts   MockOptions
+	logger logging.SprintfLogger
+
+	slots       chan struct{}
+	activeSlots atomic.Int32
+	queueDepth  atomic.Int32
+
+	stopCtx    context.Context
+	cancelStop context.CancelCauseFunc
+}
+
+var _ PredictionsManager = (*MockEngine)(nil)
+
+// NewMock creates a mock engine.
+func NewMock(opts MockOptions, logger logging.SprintfLogger) *MockEngine {
+	if opts.NParallel <= 0 {
+		opts.NParallel = 1
+	}
+	if opts.EmbeddingSize <= 0 {
+		opts.EmbeddingSize = 384
+	}
+	stopCtx, cancelStop := context.WithCancelCause(context.Background())
+	return &MockEngine{
+		opts:       opts,
+		logger:     logger.With("module", "inferenceengine.MockEngine"),
+		slots:      make(chan struct{}, opts.NParallel),
+		stopCtx:    stopCtx,
+		cancelStop: cancelStop,
+	}
+}
+
+// mockHash seeds the generator for text.
+func mockHash(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return h.Sum64()
+}
+
+func mockTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+// sleep waits for d or until ctx is done.
+func (e *MockEngine) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return context.Cause(ctx)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// acquire waits for a free slot like the engine's queue does. The returned
+// context is canceled with ErrEngineStopped when the engine stops.
+func (e *MockEngine) acquire(ctx context.Context) (context.Context, func(), error) {
+	if e.stopCtx.Err() != nil {
+		return nil, nil, ErrEngineStopped
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(e.stopCtx, func() { cancel(ErrEngineStopped) })
+	release := func() {
+		stop()
+		cancel(context.Canceled)
+	}
+	if err := context.Cause(ctx); err != nil {
+		release()
+		return nil, nil, err
+	}
+	if depth := e.queueDepth.Add(1); e.opts.MaxQueue > 0 && int(depth) > e.opts.MaxQueue {
+		e.queueDepth.Add(-1)
+		release()
+		return nil, nil, ErrQueueFull
+	}
+	select {
+	case e.slots <- struct{}{}:
+		e.queueDepth.Add(-1)
+	case <-ctx.Done():
+		e.queueDepth.Add(-1)
+		err := context.Cause(ctx)
+		release()
+		return nil, nil, err
+	}
+	e.activeSlots.Add(1)
+	return ctx, func() {
+		e.activeSlots.Add(-1)
+		<-e.slots
+		release()
+	}, nil
+}
+
+func (e *MockEngine) Predict(ctx context.Context, model ModelContext, prompt string, args PredictArgs, stream StreamFunc) (Result, error) {
+	if len(args.Images) > 0 {
+		return Result{}, ErrNoProjector
+	}
+	if args.BeamWidth > 1 {
+		return Result{}, ErrMockUnsupported
+	}
+	submitted := time.Now()
+	ctx, release, err := e.acquire(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer release()
+	var res Result
+	res.Timings.QueueTime = time.Since(submitted)
+
+	res.PromptTokens = max(mockTokens(prompt), 1)
+	res.MaxTokens = args.NPredict
+	if err := e.sleep(ctx, time.Duration(res.PromptTokens)*e.opts.TokenDelay/10); err != nil {
+		return Result{}, err
+	}
+	if args.PrefillProgress != nil {
+		if err := args.PrefillProgress(res.PromptTokens, res.PromptTokens); err != nil {
+			return Result{}, err
+		}
+	}
+
+	seed := mockHash(prompt)
+	if !args.Greedy() && args.RandomSeed >= 0 {
+		seed ^= uint64(args.RandomSeed)
+	}
+	rng := rand.New(rand.NewPCG(seed, 0))
+	// The natural length of the answer: it stops on its own unless the
+	// token limit is lower.
+	length := 16 + rng.IntN(48)
+	n := length
+	res.FinishReason = FinishStop
+	if args.NPredict > 0 && args.NPredict < length {
+		n = args.NPredict
+		res.FinishReason = FinishLength
+	}
+
+	var text strings.Builder
+	var latencies []time.Duration
+	last := time.Now()
+	for i := 0; i < n; i++ {
+		if err := e.sleep(ctx, e.opts.TokenDelay); err != nil {
+			return Result{}, err
+		}
+		w := rng.IntN(len(mockWords))
+		token := mockTokenBase + w
+		if containsToken(args.StopTokenIDs, token) {
+			res.FinishReason = FinishStop
+			break
+		}
+		piece := " " + mockWords[w]
+		if i == 0 {
+			piece = strings.ToUpper(mockWords[w][:1]) + mockWords[w][1:]
+		}
+		if i == length-1 {
+			piece += "."
+		}
+		now := time.Now()
+		if i == 0 {
+			res.Timings.TimeToFirstToken = now.Sub(submitted)
+		} else {
+			latencies = append(latencies, now.Sub(last))
+		}
+		last = now
+		text.WriteString(piece)
+		res.CompletionTokens++
+		if stream != nil {
+			if err := stream([]int{token}, res.PromptTokens, res.CompletionTokens, piece); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+	res.Text = text.String()
+	res.Timings.TotalTime = time.Since(submitted)
+	res.Timings.TokenLatency = newLatencyStats(latencies)
+	e.logger.Debugf("Predict: %d prompt tokens, %d generated (request=%s)",
+		res.PromptTokens, res.CompletionTokens, args.RequestID)
+	return res, nil
+}
+
+func containsToken(ids []int, token int) bool {
+	for _, id := range ids {
+		if id == token {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *MockEngine) Score(ctx context.Context, model ModelContext, text string, args PredictArgs) (ScoreResult, error) {
+	start := time.Now()
+	n := mockTokens(text)
+	if n < 2 {
+		return ScoreResult{}, ErrNothingToScore
+	}
+	ctx, release, err := e.acquire(ctx)
+	if err != nil {
+		return ScoreResult{}, err
+	}
+	defer release()
+	if err := e.sleep(ctx, time.Duration(n)*e.opts.TokenDelay/10); err != nil {
+		return ScoreResult{}, err
+	}
+	rng := rand.New(rand.NewPCG(mockHash(text), 0))
+	logprobs := make([]float32, n-1)
+	for i := range logprobs {
+		logprobs[i] = -0.1 - 6*rng.Float32()
+	}
+	return newScoreResult(logprobs, time.Since(start)), nil
+}
+
+// Embed returns a unit vector per text that depends only on the text.
+func (e *MockEngine) Embed(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (EmbedResult, error) {
+	start := time.Now()
+	if len(texts) == 0 {
+		return EmbedResult{}, ErrNothingToEmbed
+	}
+	res := EmbedResult{Embeddings: make([][]float32, len(texts))}
+	for _, text := range texts {
+		if text == "" {
+			return EmbedResult{}, ErrNothingToEmbed
+		}
+		res.PromptTokens += mockTokens(text)
+	}
+	ctx, release, err := e.acquire(ctx)
+	if err != nil {
+		return EmbedResult{}, err
+	}
+	defer release()
+	if err := e.sleep(ctx, time.Duration(res.PromptTokens)*e.opts.TokenDelay/10); err != nil {
+		return EmbedResult{}, err
+	}
+	for i, text := range texts {
+		rng := rand.New(rand.NewPCG(mockHash(text), 1))
+		v := make([]float32, e.opts.EmbeddingSize)
+		var norm float64
+		for j := range v {
+			v[j] = float32(rng.NormFloat64())
+			norm += float64(v[j]) * float64(v[j])
+		}
+		norm = math.Sqrt(norm)
+		for j := range v {
+			v[j] = float32(float64(v[j]) / norm)
+		}
+		res.Embeddings[i] = v
+	}
+	res.TotalTime = time.Since(start)
+	return res, nil
+}
+
+// Classify fails: the mock model has no classification head.
+func (e *MockEngine) Classify(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (ClassifyResult, error) {
+	return ClassifyResult{}, ErrNotClassifier
+}
+
+func (e *MockEngine) Bench(ctx context.Context, model ModelContext, opts BenchOptions) ([]BenchResult, error) {
+	return nil, ErrMockUnsupported
+}
+
+func (e *MockEngine) Stats() Stats {
+	return Stats{
+		NParallel:     e.opts.NParallel,
+		MaxConcurrent: e.opts.NParallel,
+		MaxQueue:      e.opts.MaxQueue,
+		ActiveSlots:   int(e.activeSlots.Load()),
+		QueueDepth:    int(e.queueDepth.Load()),
+	}
+}
+
+// Release does nothing: the mock keeps nothing per model.
+func (e *MockEngine) Release(model ModelContext) {}
+
+// Stop cancels all requests in flight; they fail with ErrEngineStopped.
+func (e *MockEngine) Stop() {
+	e.cancelStop(ErrEngineStopped)
+}
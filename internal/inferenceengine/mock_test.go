@@ -0,0 +1,74 @@
+package inferenceengine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockPredictIsDeterministic(t *testing.T) {
+	e := NewMock(MockOptions{}, logging.NewSprintfLogger())
+	defer e.Stop()
+	args := PredictArgs{NPredict: 8, RandomSeed: -1}
+
+	var streamed string
+	var streamedIDs []int
+	first, err := e.Predict(context.Background(), ModelContext{}, "hello world", args, func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+		streamed += message
+		streamedIDs = append(streamedIDs, tokenIDs...)
+		require.Equal(t, 2, promptTokens)
+		require.Equal(t, len(streamedIDs), completionTokens)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, first.PromptTokens)
+	require.Equal(t, 8, first.CompletionTokens)
+	require.Len(t, streamedIDs, 8)
+	require.Equal(t, FinishLength, first.FinishReason)
+	require.Equal(t, first.Text, streamed)
+
+	second, err := e.Predict(context.Background(), ModelContext{}, "hello world", args, nil)
+	require.NoError(t, err)
+	require.Equal(t, first.Text, second.Text)
+
+	other, err := e.Predict(context.Background(), ModelContext{}, "something else", args, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, first.Text, other.Text)
+}
+
+func TestMockQueueFull(t *testing.T) {
+	e := NewMock(MockOptions{NParallel: 1, MaxQueue: 1, TokenDelay: time.Hour}, logging.NewSprintfLogger())
+	go e.Predict(context.Background(), ModelContext{}, "a", PredictArgs{}, nil)
+	go e.Predict(context.Background(), ModelContext{}, "b", PredictArgs{}, nil)
+	require.Eventually(t, func() bool {
+		s := e.Stats()
+		return s.ActiveSlots == 1 && s.QueueDepth == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := e.Predict(context.Background(), ModelContext{}, "c", PredictArgs{}, nil)
+	require.ErrorIs(t, err, ErrQueueFull)
+
+	e.Stop()
+	_, err = e.Predict(context.Background(), ModelContext{}, "d", PredictArgs{}, nil)
+	require.ErrorIs(t, err, ErrEngineStopped)
+}
+
+func TestMockEmbedReturnsUnitVectors(t *testing.T) {
+	e := NewMock(MockOptions{EmbeddingSize: 16}, logging.NewSprintfLogger())
+	defer e.Stop()
+	res, err := e.Embed(context.Background(), ModelContext{}, []string{"a b", "c"}, PredictArgs{})
+	require.NoError(t, err)
+	require.Len(t, res.Embeddings, 2)
+	require.Equal(t, 3, res.PromptTokens)
+	var norm float32
+	for _, x := range res.Embeddings[0] {
+		norm += x * x
+	}
+	require.InDelta(t, 1, norm, 1e-5)
+
+	_, err = e.Embed(context.Background(), ModelContext{}, []string{""}, PredictArgs{})
+	require.ErrorIs(t, err, ErrNothingToEmbed)
+}
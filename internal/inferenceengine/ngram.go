@@ -0,0 +1,77 @@
+package inferenceengine
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+)
+
+// ngramIndex implements NoRepeatNgramSize for a slot: it maps each n-1
+// token prefix seen in the prompt and output to the tokens that followed
+// it, so that masking the tokens that would complete a repeated n-gram
+// costs one lookup per step instead of a scan of the history.
+type ngramIndex struct {
+	n    int
+	next map[string][]int
+	key  []byte // scratch for prefix keys
+}
+
+func newNgramIndex(n int, history []int) *ngramIndex {
+	x := &ngramIndex{n: n, next: map[string][]int{}}
+	for end := n; end <= len(history); end++ {
+		x.add(history[:end])
+	}
+	return x
+}
+
+// prefixKey encodes tokens as a map key.
+func (x *ngramIndex) prefixKey(tokens []int) []byte {
+	x.key = x.key[:0]
+	for _, t := range tokens {
+		x.key = binary.LittleEndian.AppendUint32(x.key, uint32(t))
+	}
+	return x.key
+}
+
+// add records the n-gram that ends history, if it is long enough.
+func (x *ngramIndex) add(history []int) {
+	if len(history) < x.n {
+		return
+	}
+	ngram := history[len(history)-x.n:]
+	key := x.prefixKey(ngram[:x.n-1])
+	token := ngram[x.n-1]
+	if followers := x.next[string(key)]; !slices.Contains(followers, token) {
+		x.next[string(key)] = append(followers, token)
+	}
+}
+
+// block masks the tokens that, appended to history, would repeat one of the
+// recorded n-grams.
+func (x *ngramIndex) block(logits []float32, history []int) {
+	if len(history) < x.n-1 {
+		return
+	}
+	key := x.prefixKey(history[len(history)-(x.n-1):])
+	for _, token := range x.next[string(key)] {
+		if token < len(logits) {
+			logits[token] = float32(math.Inf(-1))
+		}
+	}
+}
+
+// blockRepeatedNgrams masks the tokens that would repeat an n-gram of
+// history, as no_repeat_ngram_size does in Hugging Face transformers. It
+// scans history, which suits the beams of a beam search: they share no
+// index and are short-lived.
+func blockRepeatedNgrams(logits []float32, history []int, n int) {
+	if n <= 0 || len(history) < n-1 {
+		return
+	}
+	prefix := history[len(history)-(n-1):]
+	for i := 0; i+n <= len(history); i++ {
+		if token := history[i+n-1]; token < len(logits) && slices.Equal(history[i:i+n-1], prefix) {
+			logits[token] = float32(math.Inf(-1))
+		}
+	}
+}
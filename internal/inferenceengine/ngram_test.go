@@ -0,0 +1,43 @@
+package inferenceengine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockRepeatedNgrams(t *testing.T) {
+	inf := float32(math.Inf(-1))
+	logits := make([]float32, 6)
+	// The bigrams starting with the last token, 1, are 1 2 and 1 4.
+	blockRepeatedNgrams(logits, []int{1, 2, 3, 1, 4, 1}, 2)
+	require.Equal(t, []float32{0, 0, inf, 0, inf, 0}, logits)
+
+	logits = make([]float32, 6)
+	blockRepeatedNgrams(logits, []int{1, 2, 3, 1, 2}, 3)
+	require.Equal(t, []float32{0, 0, 0, inf, 0, 0}, logits)
+
+	logits = make([]float32, 6)
+	blockRepeatedNgrams(logits, []int{1}, 3)
+	require.Equal(t, make([]float32, 6), logits)
+}
+
+// The index a slot keeps as it generates masks the same tokens as a scan of
+// the whole history.
+func TestNgramIndexMatchesScan(t *testing.T) {
+	history := []int{1, 2, 3, 1, 2, 4, 2, 3, 1, 2, 3, 3, 3}
+	for n := 1; n <= 4; n++ {
+		x := newNgramIndex(n, history[:3])
+		for end := 3; end <= len(history); end++ {
+			if end > 3 {
+				x.add(history[:end])
+			}
+			want := make([]float32, 6)
+			blockRepeatedNgrams(want, history[:end], n)
+			got := make([]float32, 6)
+			x.block(got, history[:end])
+			require.Equal(t, want, got, "n=%d, history=%v", n, history[:end])
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package inferenceengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+)
+
+// anonymousTenant is the queue key used for requests without a client key.
+const anonymousTenant = ""
+
+// TenantID returns a stable identifier for a client key that is safe to show
+// in logs and stats (the key itself is a credential).
+func TenantID(clientKey string) string {
+	if clientKey == anonymousTenant {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(clientKey))
+	return "key-" + hex.EncodeToString(sum[:4])
+}
+
+// tenantQueue holds the pending requests of one client in FIFO order.
+type tenantQueue struct {
+	pending    []*request
+	weight     float64
+	lastFinish float64 // virtual finish tag of the most recently enqueued request
+}
+
+// fairQueue is a weighted fair queue of pending requests keyed by client key.
+//
+// Each request receives a virtual finish tag when enqueued:
+//
+//	tag = max(virtualTime, tenant.lastFinish) + 1/weight
+//
+// and pop always returns the head request with the smallest tag. A tenant that
+// submits hundreds of requests therefore only advances its own tags, and
+// requests from other tenants interleave with it in proportion to their weights.
+type fairQueue struct {
+	mx            sync.Mutex
+	tenants       map[string]*tenantQueue
+	weights       map[string]float64
+	virtualTime   float64
+	size          int
+	ready         chan struct{}
+	tags          map[*request]float64
+	defaultWeight float64
+	limit         int // maximum pending requests; 0 means unlimited
+	closed        bool
+}
+
+func newFairQueue(weights map[string]float64, limit int) *fairQueue {
+	w := make(map[string]float64, len(weights))
+	for k, v := range weights {
+		if v > 0 {
+			w[k] = v
+		}
+	}
+	return &fairQueue{
+		tenants:       make(map[string]*tenantQueue),
+		weights:       w,
+		ready:         make(chan struct{}, 1),
+		tags:          make(map[*request]float64),
+		defaultWeight: 1.0,
+		limit:         limit,
+	}
+}
+
+// push enqueues a request under its client key and wakes the engine.
+// It fails with ErrEngineStopped if the queue has been closed and with
+// ErrQueueFull if it already holds limit requests.
+func (q *fairQueue) push(req *request) error {
+	q.mx.Lock()
+	if q.closed {
+		q.mx.Unlock()
+		return ErrEngineStopped
+	}
+	if q.limit > 0 && q.size >= q.limit {
+		q.mx.Unlock()
+		return ErrQueueFull
+	}
+	key := req.args.ClientKey
+	tq, ok := q.tenants[key]
+	if !ok {
+		weight, ok := q.weights[key]
+		if !ok {
+			weight = q.defaultWeight
+		}
+		tq = &tenantQueue{weight: weight, lastFinish: q.virtualTime}
+		q.tenants[key] = tq
+	}
+	start := tq.lastFinish
+	if q.virtualTime > start {
+		start = q.virtualTime
+	}
+	tag := start + 1.0/tq.weight
+	tq.lastFinish = tag
+	tq.pending = append(tq.pending, req)
+	q.tags[req] = tag
+	q.size++
+	q.mx.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop removes and returns the pending request with the smallest finish tag,
+// or nil if the queue is empty.
+func (q *fairQueue) pop() *request {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return q.popLocked()
+}
+
+func (q *fairQueue) popLocked() *request {
+	var bestKey string
+	var best *request
+	bestTag := 0.0
+	for key, tq := range q.tenants {
+		if len(tq.pending) == 0 {
+			continue
+		}
+		head := tq.pending[0]
+		tag := q.tags[head]
+		if best == nil || tag < bestTag || (tag == bestTag && key < bestKey) {
+			best, bestKey, bestTag = head, key, tag
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	tq := q.tenants[bestKey]
+	tq.pending[0] = nil
+	tq.pending = tq.pending[1:]
+	if len(tq.pending) == 0 {
+		// Idle tenants carry no state forward; a later request starts
+		// from the current virtual time like a newcomer.
+		delete(q.tenants, bestKey)
+	}
+	delete(q.tags, best)
+	q.virtualTime = bestTag
+	q.size--
+	return best
+}
+
+// remove drops a request that is still pending. It returns false if the
+// request was already popped.
+func (q *fairQueue) remove(req *request) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	key := req.args.ClientKey
+	tq, ok := q.tenants[key]
+	if !ok {
+		return false
+	}
+	for i, r := range tq.pending {
+		if r != req {
+			continue
+		}
+		tq.pending = append(tq.pending[:i], tq.pending[i+1:]...)
+		if len(tq.pending) == 0 {
+			delete(q.tenants, key)
+		}
+		delete(q.tags, req)
+		q.size--
+		return true
+	}
+	return false
+}
+
+// close rejects further pushes and returns all pending requests.
+func (q *fairQueue) close() []*request {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	q.closed = true
+	var reqs []*request
+	for {
+		req := q.popLocked()
+		if req == nil {
+			return reqs
+		}
+		reqs = append(reqs, req)
+	}
+}
+
+// len returns the total number of pending requests.
+func (q *fairQueue) len() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return q.size
+}
+
+// TenantQueueStats describes the pending requests of one client.
+type TenantQueueStats struct {
+	Tenant string
+	Weight float64
+	Depth  int
+}
+
+// stats returns per-tenant queue depths sorted by tenant.
+func (q *fairQueue) stats() []TenantQueueStats {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	stats := make([]TenantQueueStats, 0, len(q.tenants))
+	for key, tq := range q.tenants {
+		if len(tq.pending) == 0 {
+			continue
+		}
+		stats = append(stats, TenantQueueStats{
+			Tenant: TenantID(key),
+			Weight: tq.weight,
+			Depth:  len(tq.pending),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Tenant < stats[j].Tenant })
+	return stats
+}
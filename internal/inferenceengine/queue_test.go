@@ -0,0 +1,114 @@
+package inferenceengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pushAll queues a request of tenant key for each name, which is kept as
+// the prompt to tell the requests apart.
+func pushAll(t *testing.T, q *fairQueue, key string, names ...string) []*request {
+	t.Helper()
+	var reqs []*request
+	for _, name := range names {
+		req := &request{args: PredictArgs{ClientKey: key}, prompt: name}
+		require.NoError(t, q.push(req))
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// popAll drains the queue and returns the prompts in pop order.
+func popAll(q *fairQueue) []string {
+	var names []string
+	for req := q.pop(); req != nil; req = q.pop() {
+		names = append(names, req.prompt)
+	}
+	return names
+}
+
+func TestFairQueueFIFOWithinTenant(t *testing.T) {
+	q := newFairQueue(nil, 0)
+	require.Nil(t, q.pop())
+	pushAll(t, q, "a", "a1", "a2", "a3")
+	require.Equal(t, 3, q.len())
+	require.Equal(t, []string{"a1", "a2", "a3"}, popAll(q))
+	require.Equal(t, 0, q.len())
+}
+
+func TestFairQueueInterleavesTenants(t *testing.T) {
+	q := newFairQueue(nil, 0)
+	// A tenant that queued first does not hold back one that queued later.
+	pushAll(t, q, "a", "a1", "a2", "a3", "a4")
+	pushAll(t, q, "b", "b1", "b2")
+	require.Equal(t, []string{"a1", "b1", "a2", "b2", "a3", "a4"}, popAll(q))
+}
+
+func TestFairQueueNewTenantStartsAtVirtualTime(t *testing.T) {
+	q := newFairQueue(nil, 0)
+	pushAll(t, q, "a", "a1", "a2", "a3", "a4")
+	require.Equal(t, "a1", q.pop().prompt)
+	require.Equal(t, "a2", q.pop().prompt)
+
+	// c was idle while a was served, so it gets no credit for that time
+	// and takes turns with a from here on.
+	pushAll(t, q, "c", "c1", "c2")
+	require.Equal(t, []string{"a3", "c1", "a4", "c2"}, popAll(q))
+}
+
+func TestFairQueueWeights(t *testing.T) {
+	q := newFairQueue(map[string]float64{"heavy": 2, "ignored": 0}, 0)
+	pushAll(t, q, "heavy", "h1", "h2", "h3", "h4")
+	pushAll(t, q, "light", "l1", "l2")
+	require.Equal(t, []string{"h1", "h2", "l1", "h3", "h4", "l2"}, popAll(q))
+
+	// Non-positive weights fall back to the default.
+	pushAll(t, q, "ignored", "i1")
+	require.Equal(t, []TenantQueueStats{{Tenant: TenantID("ignored"), Weight: 1, Depth: 1}}, q.stats())
+}
+
+func TestFairQueueRemove(t *testing.T) {
+	q := newFairQueue(nil, 0)
+	a := pushAll(t, q, "a", "a1", "a2")
+	b := pushAll(t, q, "b", "b1")
+
+	// A canceled request leaves the queue and frees its place.
+	require.True(t, q.remove(a[0]))
+	require.False(t, q.remove(a[0]))
+	require.Equal(t, 2, q.len())
+
+	// Removing the last request of a tenant drops the tenant.
+	require.True(t, q.remove(b[0]))
+	require.Equal(t, []TenantQueueStats{{Tenant: TenantID("a"), Weight: 1, Depth: 1}}, q.stats())
+
+	require.Equal(t, "a2", q.pop().prompt)
+	require.False(t, q.remove(a[1]))
+	require.Equal(t, 0, q.len())
+	require.Empty(t, q.stats())
+}
+
+func TestFairQueueLimit(t *testing.T) {
+	q := newFairQueue(nil, 2)
+	pushAll(t, q, "a", "a1")
+	pushAll(t, q, "b", "b1")
+	require.ErrorIs(t, q.push(&request{args: PredictArgs{ClientKey: "c"}}), ErrQueueFull)
+
+	// Popped and removed requests no longer count.
+	q.pop()
+	c := pushAll(t, q, "c", "c1")
+	require.True(t, q.remove(c[0]))
+	pushAll(t, q, "c", "c2")
+	require.Equal(t, 2, q.len())
+}
+
+func TestFairQueueClose(t *testing.T) {
+	q := newFairQueue(nil, 0)
+	pushAll(t, q, "a", "a1", "a2")
+	pushAll(t, q, "b", "b1")
+
+	pending := q.close()
+	require.Len(t, pending, 3)
+	require.Equal(t, 0, q.len())
+	require.ErrorIs(t, q.push(&request{}), ErrEngineStopped)
+}
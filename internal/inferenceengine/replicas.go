@@ -0,0 +1,158 @@
+package inferenceengine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// ReplicaPolicy decides which replica serves a request.
+type ReplicaPolicy int
+
+const (
+	// ReplicaLeastLoaded picks the replica with the fewest running and
+	// queued requests.
+	ReplicaLeastLoaded ReplicaPolicy = iota
+	// ReplicaRoundRobin takes the replicas in turn.
+	ReplicaRoundRobin
+)
+
+// ParseReplicaPolicy parses "least-loaded" or "round-robin".
+func ParseReplicaPolicy(s string) (ReplicaPolicy, error) {
+	switch strings.ToLower(s) {
+	case "least-loaded":
+		return ReplicaLeastLoaded, nil
+	case "round-robin":
+		return ReplicaRoundRobin, nil
+	default:
+		return 0, fmt.Errorf("unknown replica policy %q (want least-loaded or round-robin)", s)
+	}
+}
+
+func (p ReplicaPolicy) String() string {
+	switch p {
+	case ReplicaLeastLoaded:
+		return "least-loaded"
+	case ReplicaRoundRobin:
+		return "round-robin"
+	default:
+		return "unknown"
+	}
+}
+
+// Replicas spreads requests over engines that each serve their own copy of
+// the model, e.g. one per GPU, so that a single model gets the throughput
+// of all of them. Engine i runs ModelContext.Replicas[i]; a model loaded
+// without replicas runs on any engine. It satisfies PredictionsManager.
+type Replicas struct {
+	engines []PredictionsManager
+	policy  ReplicaPolicy
+	next    atomic.Uint64
+}
+
+// NewReplicas returns a Replicas over engines, which it owns.
+func NewReplicas(engines []PredictionsManager, policy ReplicaPolicy) *Replicas {
+	return &Replicas{engines: engines, policy: policy}
+}
+
+// pick returns the index of the engine for the next request.
+func (r *Replicas) pick() int {
+	n := len(r.engines)
+	start := int(r.next.Add(1)-1) % n
+	if r.policy == ReplicaRoundRobin {
+		return start
+	}
+	// Among equally loaded engines the rotating start spreads the
+	// requests, as round-robin would.
+	best, bestLoad := start, -1
+	for k := 0; k < n; k++ {
+		i := (start + k) % n
+		stats := r.engines[i].Stats()
+		if load := stats.ActiveSlots + stats.QueueDepth; bestLoad < 0 || load < bestLoad {
+			best, bestLoad = i, load
+		}
+	}
+	return best
+}
+
+// replica returns the engine for the next request and model as that engine
+// runs it.
+func (r *Replicas) replica(model ModelContext) (PredictionsManager, ModelContext) {
+	i := r.pick()
+	if i < len(model.Replicas) {
+		model.Model = model.Replicas[i]
+	}
+	return r.engines[i], model
+}
+
+func (r *Replicas) Predict(ctx context.Context, model ModelContext, prompt string, args PredictArgs, stream StreamFunc) (Result, error) {
+	engine, model := r.replica(model)
+	return engine.Predict(ctx, model, prompt, args, stream)
+}
+
+func (r *Replicas) Score(ctx context.Context, model ModelContext, text string, args PredictArgs) (ScoreResult, error) {
+	engine, model := r.replica(model)
+	return engine.Score(ctx, model, text, args)
+}
+
+func (r *Replicas) Embed(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (EmbedResult, error) {
+	engine, model := r.replica(model)
+	return engine.Embed(ctx, model, texts, args)
+}
+
+func (r *Replicas) Classify(ctx context.Context, model ModelContext, texts []string, args PredictArgs) (ClassifyResult, error) {
+	engine, model := r.replica(model)
+	return engine.Classify(ctx, model, texts, args)
+}
+
+func (r *Replicas) Bench(ctx context.Context, model ModelContext, opts BenchOptions) ([]BenchResult, error) {
+	engine, model := r.replica(model)
+	return engine.Bench(ctx, model, opts)
+}
+
+// Stats adds up the stats of the engines.
+func (r *Replicas) Stats() Stats {
+	var total Stats
+	tenants := map[string]int{} // index in total.Tenants
+	for _, engine := range r.engines {
+		stats := engine.Stats()
+		total.NParallel += stats.NParallel
+		total.MaxConcurrent += stats.MaxConcurrent
+		total.MaxQueue += stats.MaxQueue
+		total.ActiveSlots += stats.ActiveSlots
+		total.QueueDepth += stats.QueueDepth
+		for _, t := range stats.Tenants {
+			if i, ok := tenants[t.Tenant]; ok {
+				total.Tenants[i].Depth += t.Depth
+				continue
+			}
+			tenants[t.Tenant] = len(total.Tenants)
+			total.Tenants = append(total.Tenants, t)
+		}
+		total.KvCells += stats.KvCells
+		total.KvCellsUsed += stats.KvCellsUsed
+	}
+	slices.SortFunc(total.Tenants, func(a, b TenantQueueStats) int {
+		return strings.Compare(a.Tenant, b.Tenant)
+	})
+	return total
+}
+
+// Release releases each engine's replica of model.
+func (r *Replicas) Release(model ModelContext) {
+	for i, engine := range r.engines {
+		replica := model
+		if i < len(model.Replicas) {
+			replica.Model = model.Replicas[i]
+		}
+		engine.Release(replica)
+	}
+}
+
+func (r *Replicas) Stop() {
+	for _, engine := range r.engines {
+		engine.Stop()
+	}
+}
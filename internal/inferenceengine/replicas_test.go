@@ -0,0 +1,87 @@
+package inferenceengine
+
+import (
+	"context"
+	"testing"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine records the models it is asked to run.
+type fakeEngine struct {
+	PredictionsManager
+	stats    Stats
+	models   []*llamacppbindings.Model
+	released []*llamacppbindings.Model
+}
+
+func (f *fakeEngine) Predict(_ context.Context, model ModelContext, _ string, _ PredictArgs, _ StreamFunc) (Result, error) {
+	f.models = append(f.models, model.Model)
+	return Result{}, nil
+}
+
+func (f *fakeEngine) Stats() Stats { return f.stats }
+
+func (f *fakeEngine) Release(model ModelContext) {
+	f.released = append(f.released, model.Model)
+}
+
+func TestReplicasRoundRobin(t *testing.T) {
+	a, b := &fakeEngine{}, &fakeEngine{}
+	r := NewReplicas([]PredictionsManager{a, b}, ReplicaRoundRobin)
+	ra, rb := new(llamacppbindings.Model), new(llamacppbindings.Model)
+	model := ModelContext{Model: ra, Replicas: []*llamacppbindings.Model{ra, rb}}
+
+	for i := 0; i < 4; i++ {
+		_, err := r.Predict(context.Background(), model, "", PredictArgs{}, nil)
+		require.NoError(t, err)
+	}
+	// Each engine runs its own replica.
+	require.Equal(t, []*llamacppbindings.Model{ra, ra}, a.models)
+	require.Equal(t, []*llamacppbindings.Model{rb, rb}, b.models)
+}
+
+func TestReplicasLeastLoaded(t *testing.T) {
+	a := &fakeEngine{stats: Stats{NParallel: 4, ActiveSlots: 4, QueueDepth: 2}}
+	b := &fakeEngine{stats: Stats{NParallel: 4, ActiveSlots: 3}}
+	r := NewReplicas([]PredictionsManager{a, b}, ReplicaLeastLoaded)
+	model := ModelContext{Model: new(llamacppbindings.Model)}
+
+	for i := 0; i < 3; i++ {
+		_, err := r.Predict(context.Background(), model, "", PredictArgs{}, nil)
+		require.NoError(t, err)
+	}
+	require.Empty(t, a.models)
+	require.Len(t, b.models, 3)
+
+	stats := r.Stats()
+	require.Equal(t, 8, stats.NParallel)
+	require.Equal(t, 7, stats.ActiveSlots)
+	require.Equal(t, 2, stats.QueueDepth)
+}
+
+func TestParseReplicaPolicy(t *testing.T) {
+	for _, p := range []ReplicaPolicy{ReplicaLeastLoaded, ReplicaRoundRobin} {
+		got, err := ParseReplicaPolicy(p.String())
+		require.NoError(t, err)
+		require.Equal(t, p, got)
+	}
+	_, err := ParseReplicaPolicy("random")
+	require.Error(t, err)
+}
+
+func TestReplicasReleaseEachReplica(t *testing.T) {
+	a, b := &fakeEngine{}, &fakeEngine{}
+	r := NewReplicas([]PredictionsManager{a, b}, ReplicaLeastLoaded)
+	ra, rb := new(llamacppbindings.Model), new(llamacppbindings.Model)
+	r.Release(ModelContext{Model: ra, Replicas: []*llamacppbindings.Model{ra, rb}})
+	require.Equal(t, []*llamacppbindings.Model{ra}, a.released)
+	require.Equal(t, []*llamacppbindings.Model{rb}, b.released)
+
+	// A model loaded once may have run on either engine.
+	r.Release(ModelContext{Model: ra})
+	require.Equal(t, []*llamacppbindings.Model{ra, ra}, a.released)
+	require.Equal(t, []*llamacppbindings.Model{rb, ra}, b.released)
+}
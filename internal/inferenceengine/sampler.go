@@ -2,14 +2,21 @@ package inferenceengine
 
 import (
 	"fmt"
+	"strings"
 
 	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/grammar"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
 )
 
+// penaltyLastN is how many of the latest tokens the repetition penalty
+// looks back on.
+const penaltyLastN = 64
+
 // buildSamplerChain constructs a sampler chain matching the given PredictArgs.
 // The caller owns the returned chain and must Free it.
-func buildSamplerChain(args PredictArgs, logger logging.SprintfLogger) (
+func buildSamplerChain(args PredictArgs, vocab *llamacppbindings.Vocab, logger logging.SprintfLogger) (
 	*llamacppbindings.SamplerChain, *llamacppbindings.Sampler, error) {
 
 	chainParams := llamacppbindings.NewSamplerChainDefaultParams()
@@ -18,8 +25,23 @@ func buildSamplerChain(args PredictArgs, logger logging.SprintfLogger) (
 		return nil, nil, fmt.Errorf("sampler chain: %w", err)
 	}
 
+	// The grammar goes first so later samplers only see allowed tokens.
+	if args.Regex != "" {
+		gbnf, err := grammar.FromRegex(args.Regex)
+		if err != nil {
+			chain.Free()
+			return nil, nil, fmt.Errorf("%w: regex: %v", ErrInvalidGrammar, err)
+		}
+		s, err := llamacppbindings.NewGrammarSampler(vocab, gbnf)
+		if err != nil {
+			chain.Free()
+			return nil, nil, fmt.Errorf("%w: regex: %v", ErrInvalidGrammar, err)
+		}
+		chain.AddSampler(s)
+	}
+
 	if args.RepetitionPenalty != 0 && args.RepetitionPenalty != 1.0 {
-		s, err := llamacppbindings.NewPenaltiesSampler(64, args.RepetitionPenalty, 0.0, 0.0)
+		s, err := llamacppbindings.NewPenaltiesSampler(penaltyLastN, args.RepetitionPenalty, 0.0, 0.0)
 		if err != nil {
 			logger.Warnf("penalties sampler unavailable: %v", err)
 		} else {
@@ -27,6 +49,19 @@ func buildSamplerChain(args PredictArgs, logger logging.SprintfLogger) (
 		}
 	}
 
+	// Truncating the candidates or flattening their distribution keeps the
+	// most likely token on top, so a greedy request skips straight to it.
+	if args.Greedy() {
+		greedy, err := llamacppbindings.NewGreedySampler()
+		if err != nil {
+			chain.Free()
+			return nil, nil, fmt.Errorf("greedy sampler: %w", err)
+		}
+		chain.AddSampler(greedy)
+		return chain, chain.Sampler(), nil
+	}
+
+	// top-k takes no min_keep; Validate ensures TopK >= MinTokensToKeep.
 	if args.TopK > 0 {
 		s, err := llamacppbindings.NewTopKSampler(int(args.TopK))
 		if err != nil {
@@ -37,7 +72,7 @@ func buildSamplerChain(args PredictArgs, logger logging.SprintfLogger) (
 	}
 
 	if args.TopP > 0.0 && args.TopP < 1.0 {
-		s, err := llamacppbindings.NewTopPSampler(args.TopP, args.MinTokensToKeep)
+		s, err := llamacppbindings.NewTopPSampler(args.TopP, args.minKeep())
 		if err != nil {
 			logger.Warnf("top-p sampler unavailable: %v", err)
 		} else {
@@ -46,7 +81,7 @@ func buildSamplerChain(args PredictArgs, logger logging.SprintfLogger) (
 	}
 
 	if args.MinP > 0.0 {
-		s, err := llamacppbindings.NewMinPSampler(args.MinP, args.MinTokensToKeep)
+		s, err := llamacppbindings.NewMinPSampler(args.MinP, args.minKeep())
 		if err != nil {
 			chain.Free()
 			return nil, nil, fmt.Errorf("min-p sampler: %w", err)
@@ -54,32 +89,177 @@ func buildSamplerChain(args PredictArgs, logger logging.SprintfLogger) (
 		chain.AddSampler(s)
 	}
 
-	if args.Temp > 0.0 {
-		s, err := llamacppbindings.NewTempSampler(args.Temp)
-		if err != nil {
-			chain.Free()
-			return nil, nil, fmt.Errorf("temp sampler: %w", err)
+	s, err := llamacppbindings.NewTempSampler(args.Temp)
+	if err != nil {
+		chain.Free()
+		return nil, nil, fmt.Errorf("temp sampler: %w", err)
+	}
+	chain.AddSampler(s)
+
+	var seed uint32 = 0xFFFFFFFF
+	if args.RandomSeed >= 0 {
+		seed = uint32(args.RandomSeed)
+	}
+	dist, err := llamacppbindings.NewDistSampler(seed)
+	if err != nil {
+		chain.Free()
+		return nil, nil, fmt.Errorf("dist sampler: %w", err)
+	}
+	chain.AddSampler(dist)
+
+	return chain, chain.Sampler(), nil
+}
+
+// acceptPrompt feeds the prompt tokens within the penalty window into the
+// penalty samplers of chain, as llama.cpp's examples do, so the repetition
+// penalty covers the prompt from the first generated token on. Sampled
+// tokens are accepted by Sample itself. The grammar never sees the prompt:
+// it constrains the output only.
+func acceptPrompt(chain *llamacppbindings.SamplerChain, tokens []int) {
+	tail := tokens[max(0, len(tokens)-penaltyLastN):]
+	for i := 0; i < chain.N(); i++ {
+		smpl := chain.Get(i)
+		if smpl.Name() != "penalties" {
+			continue
 		}
-		chain.AddSampler(s)
+		for _, token := range tail {
+			smpl.Accept(token)
+		}
+	}
+}
+
+// samplerKey holds the PredictArgs that shape a sampler chain; requests with
+// equal keys get identically configured chains. Greedy requests ignore the
+// sampling options, so their keys leave them out and they share chains.
+type samplerKey struct {
+	regex             string
+	repetitionPenalty float32
+	topK              int32
+	topP              float32
+	minP              float32
+	minTokensToKeep   int
+	temp              float32
+	randomSeed        int
+}
 
-		var seed uint32 = 0xFFFFFFFF
-		if args.RandomSeed >= 0 {
-			seed = uint32(args.RandomSeed)
+func newSamplerKey(args PredictArgs) samplerKey {
+	if args.Greedy() {
+		return samplerKey{
+			regex:             args.Regex,
+			repetitionPenalty: args.RepetitionPenalty,
+			topK:              1,
 		}
-		dist, err := llamacppbindings.NewDistSampler(seed)
-		if err != nil {
-			chain.Free()
-			return nil, nil, fmt.Errorf("dist sampler: %w", err)
+	}
+	return samplerKey{
+		regex:             args.Regex,
+		repetitionPenalty: args.RepetitionPenalty,
+		topK:              args.TopK,
+		topP:              args.TopP,
+		minP:              args.MinP,
+		minTokensToKeep:   args.minKeep(),
+		temp:              args.Temp,
+		randomSeed:        args.RandomSeed,
+	}
+}
+
+// samplerCache keeps the sampler chains of finished requests so a request
+// with the same parameters resets one instead of building all its samplers
+// again. When full, the least recently used chain is freed. Chains depend
+// on the vocab (grammars), so the cache is cleared when the model changes.
+// It is owned by the engine goroutine.
+type samplerCache struct {
+	idle  map[samplerKey][]cachedChain // oldest first
+	size  int
+	limit int
+	clock uint64
+}
+
+type cachedChain struct {
+	chain *llamacppbindings.SamplerChain
+	used  uint64 // clock when put
+}
+
+var samplerCacheRequests = metrics.NewCounter("llamacpp_sampler_cache_requests_total",
+	"Sampler chains requested from the cache, by result (hit or miss).", "result")
+
+func newSamplerCache(limit int) *samplerCache {
+	return &samplerCache{
+		idle:  make(map[samplerKey][]cachedChain),
+		limit: limit,
+	}
+}
+
+// get returns a chain for args, reset to its initial state, or builds one.
+func (c *samplerCache) get(args PredictArgs, vocab *llamacppbindings.Vocab, logger logging.SprintfLogger) (
+	samplerKey, *llamacppbindings.SamplerChain, error) {
+
+	key := newSamplerKey(args)
+	if chains := c.idle[key]; len(chains) > 0 {
+		chain := chains[len(chains)-1].chain
+		chains[len(chains)-1] = cachedChain{}
+		if len(chains) == 1 {
+			delete(c.idle, key)
+		} else {
+			c.idle[key] = chains[:len(chains)-1]
 		}
-		chain.AddSampler(dist)
-	} else {
-		greedy, err := llamacppbindings.NewGreedySampler()
-		if err != nil {
-			chain.Free()
-			return nil, nil, fmt.Errorf("greedy sampler: %w", err)
+		c.size--
+		chain.Reset()
+		samplerCacheRequests.Inc("hit")
+		return key, chain, nil
+	}
+	samplerCacheRequests.Inc("miss")
+	chain, _, err := buildSamplerChain(args, vocab, logger)
+	if err != nil {
+		return key, nil, err
+	}
+	logger.Debugf("sampler chain: %s", strings.Join(chain.Names(), " -> "))
+	return key, chain, nil
+}
+
+// put keeps chain for reuse, evicting the least recently used chain if the
+// cache is full. With a limit of 0 it frees chain.
+func (c *samplerCache) put(key samplerKey, chain *llamacppbindings.SamplerChain) {
+	if c.limit <= 0 {
+		chain.Free()
+		return
+	}
+	if c.size >= c.limit {
+		c.evict()
+	}
+	c.clock++
+	c.idle[key] = append(c.idle[key], cachedChain{chain: chain, used: c.clock})
+	c.size++
+}
+
+// evict frees the least recently used chain.
+func (c *samplerCache) evict() {
+	var oldest samplerKey
+	var found bool
+	for key, chains := range c.idle {
+		if !found || chains[0].used < c.idle[oldest][0].used {
+			oldest, found = key, true
 		}
-		chain.AddSampler(greedy)
 	}
+	if !found {
+		return
+	}
+	chains := c.idle[oldest]
+	chains[0].chain.Free()
+	if len(chains) == 1 {
+		delete(c.idle, oldest)
+	} else {
+		c.idle[oldest] = chains[1:]
+	}
+	c.size--
+}
 
-	return chain, chain.Sampler(), nil
+// clear frees all cached chains.
+func (c *samplerCache) clear() {
+	for key, chains := range c.idle {
+		for _, cached := range chains {
+			cached.chain.Free()
+		}
+		delete(c.idle, key)
+	}
+	c.size = 0
 }
@@ -0,0 +1,148 @@
+package inferenceengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// scoreChunkSize caps the tokens a scoring slot decodes per tick. Every
+// scored token needs its own row of logits, so large chunks would blow up
+// the context's output buffer (n_tokens × n_vocab floats).
+const scoreChunkSize = 256
+
+// ErrNothingToScore is returned for a text that tokenizes to fewer than two
+// tokens: the first token has no context to be scored against.
+var ErrNothingToScore = errors.New("text too short to score")
+
+// ScoreResult is the log-likelihood of a text under the model. Log
+// probabilities are natural logs.
+type ScoreResult struct {
+	// Tokens is the number of scored tokens: all but the first one.
+	Tokens        int
+	LogLikelihood float64
+	Perplexity    float64
+	// TokenLogprobs holds the log probability of each scored token.
+	TokenLogprobs []float32
+	TotalTime     time.Duration
+}
+
+func newScoreResult(logprobs []float32, totalTime time.Duration) ScoreResult {
+	var sum float64
+	for _, lp := range logprobs {
+		sum += float64(lp)
+	}
+	res := ScoreResult{
+		Tokens:        len(logprobs),
+		LogLikelihood: sum,
+		TokenLogprobs: logprobs,
+		TotalTime:     totalTime,
+	}
+	if len(logprobs) > 0 {
+		res.Perplexity = math.Exp(-sum / float64(len(logprobs)))
+	}
+	return res
+}
+
+// Score evaluates the log-likelihood of text under the model without
+// generating. It is scheduled like a prediction and shares the slots.
+// Only ClientKey, RequestID and PrefillProgress of args are used.
+func (e *Engine) Score(ctx context.Context, model ModelContext, text string, args PredictArgs) (ScoreResult, error) {
+	ctx, cancel := e.requestContext(ctx)
+	defer cancel()
+	res, err := e.submit(ctx, &request{
+		model:  model,
+		prompt: text,
+		args:   args,
+		score:  true,
+	})
+	return res.score, err
+}
+
+// scoreTarget is a batch position whose logits give the probability of the
+// next prompt token of a scoring slot.
+type scoreTarget struct {
+	slot     *slot
+	batchIdx int
+	pos      int // index into promptTokens of the token fed at batchIdx
+}
+
+// addScoreChunk feeds the next prompt tokens of a scoring slot into the batch,
+// requesting logits for every token that has a successor. It returns the
+// number of tokens added.
+func (e *Engine) addScoreChunk(s *slot, remaining int, targets []scoreTarget) (int, []scoreTarget) {
+	chunk := len(s.promptTokens) - s.prefillIdx
+	if chunk > remaining {
+		chunk = remaining
+	}
+	if chunk > scoreChunkSize {
+		chunk = scoreChunkSize
+	}
+	for j := 0; j < chunk; j++ {
+		pos := s.prefillIdx + j
+		wantLogits := pos+1 < len(s.promptTokens)
+		batchIdx := e.batch.NTokens()
+		e.batch.Add(s.promptTokens[pos], s.pos, s.seqId, wantLogits)
+		s.pos++
+		if wantLogits {
+			targets = append(targets, scoreTarget{slot: s, batchIdx: batchIdx, pos: pos})
+		}
+	}
+	s.prefillIdx += chunk
+	return chunk, targets
+}
+
+// collectScores records the log probabilities of the decoded score targets
+// and finishes the scoring slots that reached the end of their text.
+func (e *Engine) collectScores(targets []scoreTarget) {
+	for _, t := range targets {
+		s := t.slot
+		if s.state == slotIdle {
+			continue // finished by a failed progress callback
+		}
+		logits, err := e.context.Logits(t.batchIdx)
+		if err != nil {
+			e.finishSlot(s, fmt.Errorf("score: %w", err))
+			continue
+		}
+		s.logprobs = append(s.logprobs, tokenLogprob(logits, s.promptTokens[t.pos+1]))
+	}
+	for _, s := range e.slots {
+		if s.state == slotPrefilling && s.scoring && s.prefillIdx >= len(s.promptTokens) {
+			e.finishSlot(s, nil)
+		}
+	}
+}
+
+// tokenLogprob returns log softmax(logits)[token].
+func tokenLogprob(logits []float32, token int) float32 {
+	maxLogit := float64(logits[0])
+	for _, l := range logits[1:] {
+		if float64(l) > maxLogit {
+			maxLogit = float64(l)
+		}
+	}
+	var sum float64
+	for _, l := range logits {
+		sum += math.Exp(float64(l) - maxLogit)
+	}
+	return float32(float64(logits[token]) - maxLogit - math.Log(sum))
+}
+
+// prepareScoreSlot assigns a scoring request to a slot. Scoring needs the
+// whole text in the slot's context, but no sampler.
+func (e *Engine) prepareScoreSlot(s *slot, req *request, tokens []int, perSlotCtx int) error {
+	if len(tokens) < 2 {
+		return ErrNothingToScore
+	}
+	if len(tokens) > perSlotCtx {
+		return &ContextExceededError{PromptTokens: len(tokens), SlotBudget: perSlotCtx}
+	}
+	e.memory.SeqRm(s.seqId, -1, -1)
+	s.assign(tokens, 0, nil, nil, req)
+	s.scoring = true
+	s.logprobs = make([]float32, 0, len(tokens)-1)
+	return nil
+}
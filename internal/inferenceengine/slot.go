@@ -1,10 +1,13 @@
 package inferenceengine
 
 import (
+	"context"
 	"strings"
 	"time"
 
 	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
 )
 
 type slotState int
@@ -36,25 +39,63 @@ type slot struct {
 	pos   int
 
 	// prefill
-	promptTokens []int
-	prefillIdx   int
-	inputCount   int
+	promptTokens    []int
+	tokenBuf        []int // backs promptTokens, reused for the next prompt
+	prefillIdx      int
+	prefillStep     int // max prompt tokens per tick; 0 means no limit
+	inputCount      int
+	prefillProgress PrefillProgressFunc
 
-	// generation
-	nextToken int
-	generated int
-	maxTokens int
+	// scoring: log probabilities of the prompt tokens instead of generation
+	scoring  bool
+	logprobs []float32
 
-	// sampler (per-slot, owns lifecycle)
+	// generation
+	nextToken    int
+	generated    int
+	maxTokens    int
+	finishReason FinishReason
+	stopTokens   []int
+	bans         banList     // banned strings, see BannedStrings
+	noRepeat     *ngramIndex // see NoRepeatNgramSize; nil if off
+
+	// whether maxTokens is the request's, see Result.MaxTokensReason
+	maxTokensReason MaxTokensReason
+
+	// control token output, see PredictArgs.SpecialTokens
+	specialTokens SpecialTokens
+	specialToken  SpecialTokenFunc
+
+	// prompt-lookup decoding
+	lookup        int   // max draft tokens per step; 0 disables
+	history       []int // prompt and generated tokens; nil unless needed
+	draftTokens   int
+	draftAccepted int
+
+	// sampler (per-slot, owns lifecycle; the engine caches it by samplerKey
+	// when the request finishes)
 	samplerChain *llamacppbindings.SamplerChain
 	sampler      *llamacppbindings.Sampler
+	samplerKey   samplerKey
 
 	// request data
-	stream   StreamFunc
-	resultCh chan requestResult
-	response strings.Builder
-
-	startTime time.Time
+	requestID string
+	logger    logging.SprintfLogger // routes llama.cpp output of this request
+	ctx       context.Context
+	stream    StreamFunc
+	resultCh  chan requestResult
+	response  strings.Builder
+
+	// metric series labels and latency exemplar of the request
+	metricLabels []string
+	exemplar     metrics.Labels
+
+	// timings
+	submitTime     time.Time
+	startTime      time.Time
+	firstTokenTime time.Time
+	lastTokenTime  time.Time
+	tokenGaps      []time.Duration
 }
 
 // assign initialises a slot for a new request.
@@ -66,16 +107,89 @@ func (s *slot) assign(tokens []int, maxTokens int,
 	s.pos = 0
 	s.promptTokens = tokens
 	s.prefillIdx = 0
+	s.prefillStep = req.args.PrefillStepSize
 	s.inputCount = len(tokens)
+	s.prefillProgress = req.args.PrefillProgress
+	s.scoring = false
+	s.logprobs = nil
 	s.nextToken = 0
 	s.generated = 0
 	s.maxTokens = maxTokens
+	s.maxTokensReason = MaxTokensRequested
+	s.finishReason = FinishStop
+	s.stopTokens = req.args.StopTokenIDs
+	s.bans = nil
+	s.noRepeat = nil
+	s.specialTokens = req.args.SpecialTokens
+	s.specialToken = req.args.SpecialToken
+	s.lookup = req.args.PromptLookup
+	s.history = nil
+	if s.lookup > 0 {
+		s.history = append(make([]int, 0, len(tokens)+maxTokens), tokens...)
+	}
+	s.draftTokens = 0
+	s.draftAccepted = 0
 	s.samplerChain = chain
 	s.sampler = sampler
+	s.requestID = req.args.RequestID
+	s.metricLabels = req.model.metricLabels()
+	s.exemplar = requestExemplar(req.args.RequestID)
+	s.ctx = req.ctx
 	s.stream = req.stream
 	s.resultCh = req.done
 	s.response.Reset()
+	s.submitTime = req.submitTime
 	s.startTime = time.Now()
+	s.firstTokenTime = time.Time{}
+	s.lastTokenTime = time.Time{}
+	s.tokenGaps = s.tokenGaps[:0]
+
+	queueTimeSeconds.ObserveWithExemplar(s.startTime.Sub(s.submitTime).Seconds(), s.exemplar, s.metricLabels...)
+}
+
+// masksLogits reports whether s edits the logits before sampling, so its
+// token cannot be sampled inside the decode call.
+func (s *slot) masksLogits() bool {
+	return len(s.bans) > 0 || s.noRepeat != nil
+}
+
+// advance counts token as generated and makes it the next input.
+func (s *slot) advance(token int) {
+	s.generated++
+	s.nextToken = token
+	if s.history != nil {
+		s.history = append(s.history, token)
+	}
+	if s.noRepeat != nil {
+		s.noRepeat.add(s.history)
+	}
+}
+
+// recordToken notes that a token was sampled now.
+func (s *slot) recordToken() {
+	now := time.Now()
+	if s.firstTokenTime.IsZero() {
+		s.firstTokenTime = now
+		timeToFirstTokenSeconds.ObserveWithExemplar(now.Sub(s.submitTime).Seconds(), s.exemplar, s.metricLabels...)
+	} else {
+		gap := now.Sub(s.lastTokenTime)
+		s.tokenGaps = append(s.tokenGaps, gap)
+		interTokenLatencySeconds.ObserveWithExemplar(gap.Seconds(), s.exemplar, s.metricLabels...)
+	}
+	s.lastTokenTime = now
+}
+
+// timings summarizes the slot's request timings as of now.
+func (s *slot) timings() Timings {
+	t := Timings{
+		QueueTime:    s.startTime.Sub(s.submitTime),
+		TotalTime:    time.Since(s.submitTime),
+		TokenLatency: newLatencyStats(s.tokenGaps),
+	}
+	if !s.firstTokenTime.IsZero() {
+		t.TimeToFirstToken = s.firstTokenTime.Sub(s.submitTime)
+	}
+	return t
 }
 
 // finish frees per-request resources and sends the result.
@@ -87,25 +201,55 @@ func (s *slot) finish(err error) {
 	}
 	if err != nil {
 		s.resultCh <- requestResult{err: err}
+	} else if s.scoring {
+		s.resultCh <- requestResult{score: newScoreResult(s.logprobs, time.Since(s.submitTime))}
 	} else {
-		s.resultCh <- requestResult{text: s.response.String()}
+		s.resultCh <- requestResult{result: Result{
+			Text:             s.response.String(),
+			PromptTokens:     s.inputCount,
+			CompletionTokens: s.generated,
+			FinishReason:     s.finishReason,
+			Timings:          s.timings(),
+
+			DraftTokens:         s.draftTokens,
+			DraftAcceptedTokens: s.draftAccepted,
+
+			MaxTokens:       s.maxTokens,
+			MaxTokensReason: s.maxTokensReason,
+		}}
 	}
 	s.state = slotIdle
+	s.requestID = ""
+	s.exemplar = nil
+	s.logger = nil
+	s.ctx = nil
 	s.stream = nil
+	s.prefillProgress = nil
 	s.resultCh = nil
 	s.promptTokens = nil
+	s.scoring = false
+	s.logprobs = nil
+	s.history = nil
+	s.stopTokens = nil
+	s.bans = nil
+	s.noRepeat = nil
+	s.specialToken = nil
 }
 
 // request is a pending inference request waiting for a slot.
 type request struct {
-	model  *llamacppbindings.Model
-	prompt string
-	args   PredictArgs
-	stream StreamFunc
-	done   chan requestResult
+	ctx        context.Context
+	model      ModelContext
+	prompt     string
+	args       PredictArgs
+	stream     StreamFunc
+	score      bool // compute the prompt's log-likelihood instead of generating
+	done       chan requestResult
+	submitTime time.Time
 }
 
 type requestResult struct {
-	text string
-	err  error
+	result Result
+	score  ScoreResult
+	err    error
 }
@@ -0,0 +1,70 @@
+package inferenceengine
+
+import (
+	"strings"
+	"time"
+)
+
+// Coalescer batches streamed tokens into fewer, larger messages. Tokens are
+// forwarded once everyTokens tokens have accumulated or every has elapsed
+// since the last forwarded message, whichever comes first. A zero value for
+// either limit disables that limit; with both zero every token is forwarded.
+type Coalescer struct {
+	stream      StreamFunc
+	everyTokens int
+	every       time.Duration
+
+	pending          strings.Builder
+	tokenIDs         []int
+	promptTokens     int
+	completionTokens int
+	lastFlush        time.Time
+}
+
+// NewCoalescer wraps stream with token coalescing.
+func NewCoalescer(stream StreamFunc, everyTokens int, every time.Duration) *Coalescer {
+	return &Coalescer{
+		stream:      stream,
+		everyTokens: everyTokens,
+		every:       every,
+		lastFlush:   time.Now(),
+	}
+}
+
+// Enabled reports whether any coalescing limit is set.
+func (c *Coalescer) Enabled() bool {
+	return c.everyTokens > 1 || c.every > 0
+}
+
+// Stream is a StreamFunc that buffers the token and forwards the buffered
+// text when a limit is reached.
+func (c *Coalescer) Stream(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+	c.pending.WriteString(message)
+	c.tokenIDs = append(c.tokenIDs, tokenIDs...)
+	c.promptTokens = promptTokens
+	c.completionTokens = completionTokens
+
+	if c.everyTokens > 0 && len(c.tokenIDs) >= c.everyTokens {
+		return c.Flush()
+	}
+	if c.every > 0 && time.Since(c.lastFlush) >= c.every {
+		return c.Flush()
+	}
+	if c.everyTokens <= 0 && c.every <= 0 {
+		return c.Flush()
+	}
+	return nil
+}
+
+// Flush forwards any buffered text. It must be called once generation ends.
+func (c *Coalescer) Flush() error {
+	if len(c.tokenIDs) == 0 {
+		return nil
+	}
+	message := c.pending.String()
+	tokenIDs := c.tokenIDs
+	c.pending.Reset()
+	c.tokenIDs = nil // the stream may keep tokenIDs
+	c.lastFlush = time.Now()
+	return c.stream(tokenIDs, c.promptTokens, c.completionTokens, message)
+}
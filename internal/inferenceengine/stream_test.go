@@ -0,0 +1,29 @@
+package inferenceengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescerForwardsTokenIDs(t *testing.T) {
+	type chunk struct {
+		tokenIDs                       []int
+		promptTokens, completionTokens int
+		message                        string
+	}
+	var chunks []chunk
+	c := NewCoalescer(func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+		chunks = append(chunks, chunk{tokenIDs, promptTokens, completionTokens, message})
+		return nil
+	}, 2, 0)
+
+	for i, piece := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Stream([]int{10 + i}, 5, i+1, piece))
+	}
+	require.NoError(t, c.Flush())
+	require.Equal(t, []chunk{
+		{[]int{10, 11}, 5, 2, "ab"},
+		{[]int{12}, 5, 3, "c"},
+	}, chunks)
+}
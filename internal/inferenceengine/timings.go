@@ -0,0 +1,75 @@
+package inferenceengine
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+)
+
+var (
+	// The latency histograms keep the request ID of an observation per
+	// bucket as an exemplar.
+	queueTimeSeconds = metrics.NewHistogram("llamacpp_queue_time_seconds",
+		"Time a request waited in the queue before it was assigned a slot.",
+		metrics.DefLatencyBuckets, "model", "quantization")
+	timeToFirstTokenSeconds = metrics.NewHistogram("llamacpp_time_to_first_token_seconds",
+		"Time from request submission until its first token was sampled.",
+		metrics.DefLatencyBuckets, "model", "quantization")
+	interTokenLatencySeconds = metrics.NewHistogram("llamacpp_inter_token_latency_seconds",
+		"Time between consecutive sampled tokens of a request.",
+		metrics.DefLatencyBuckets, "model", "quantization")
+)
+
+// Timings describes where the time of one prediction went. All durations are
+// measured inside the engine, so they are not skewed by network or client
+// buffering.
+type Timings struct {
+	QueueTime        time.Duration // submitted → assigned to a slot
+	TimeToFirstToken time.Duration // submitted → first token sampled; zero if none was
+	TotalTime        time.Duration // submitted → finished
+	TokenLatency     LatencyStats  // intervals between consecutive tokens
+}
+
+// LatencyStats summarizes a latency distribution.
+type LatencyStats struct {
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// newLatencyStats computes the summary of samples; samples is sorted in place.
+func newLatencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	var sum time.Duration
+	for _, d := range samples {
+		sum += d
+	}
+	return LatencyStats{
+		Count: len(samples),
+		Mean:  sum / time.Duration(len(samples)),
+		P50:   percentile(samples, 0.50),
+		P90:   percentile(samples, 0.90),
+		P99:   percentile(samples, 0.99),
+		Max:   samples[len(samples)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,77 @@
+package inferenceengine
+
+import (
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+)
+
+// tokenTable caches what emitToken needs to know about a token, so that a
+// generated token costs no cgo calls once it was seen. It belongs to the
+// vocab of the current model and to the run goroutine.
+type tokenTable struct {
+	vocab   *llamacppbindings.Vocab
+	entries []tokenEntry // indexed by token
+	buf     []byte       // reused by AppendPiece
+}
+
+type tokenEntry struct {
+	flags tokenFlags
+	piece string
+}
+
+type tokenFlags uint8
+
+const (
+	tokenKnown tokenFlags = 1 << iota // eog and control are set
+	tokenEog
+	tokenControl
+	tokenHasPiece
+)
+
+func newTokenTable(vocab *llamacppbindings.Vocab) *tokenTable {
+	return &tokenTable{vocab: vocab, entries: make([]tokenEntry, vocab.NTokens())}
+}
+
+// lookup returns the entry of token with its flags filled in. Tokens outside
+// the vocab get an entry that is not cached.
+func (t *tokenTable) lookup(token int) *tokenEntry {
+	var e *tokenEntry
+	if token >= 0 && token < len(t.entries) {
+		e = &t.entries[token]
+	} else {
+		e = &tokenEntry{}
+	}
+	if e.flags&tokenKnown == 0 {
+		e.flags |= tokenKnown
+		if t.vocab.IsEog(token) {
+			e.flags |= tokenEog
+		}
+		if t.vocab.IsControl(token) {
+			e.flags |= tokenControl
+		}
+	}
+	return e
+}
+
+func (t *tokenTable) isEog(token int) bool {
+	return t.lookup(token).flags&tokenEog != 0
+}
+
+func (t *tokenTable) isControl(token int) bool {
+	return t.lookup(token).flags&tokenControl != 0
+}
+
+// piece returns the text of token, like Vocab.TokenToPiece.
+func (t *tokenTable) piece(token int) (string, error) {
+	e := t.lookup(token)
+	if e.flags&tokenHasPiece != 0 {
+		return e.piece, nil
+	}
+	var err error
+	t.buf, err = t.vocab.AppendPiece(t.buf[:0], token)
+	if err != nil {
+		return "", err
+	}
+	e.piece = string(t.buf)
+	e.flags |= tokenHasPiece
+	return e.piece, nil
+}
@@ -0,0 +1,54 @@
+package llmservice
+
+import (
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/audit"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+)
+
+// newAuditRecord fills in the fields of an audit record that all operations
+// share.
+func newAuditRecord(op, modelPath string, args inferenceengine.PredictArgs, start time.Time, err error) audit.Record {
+	rec := audit.Record{
+		Time:      time.Now(),
+		RequestID: args.RequestID,
+		Client:    audit.ClientID(args.ClientKey),
+		Operation: op,
+		Model:     modelPath,
+		LatencyMs: durationMs(time.Since(start)),
+	}
+	if op == "predict" {
+		rec.Params = auditParams(args)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// auditParams summarizes the sampling parameters of a prediction.
+func auditParams(args inferenceengine.PredictArgs) audit.Params {
+	p := audit.Params{
+		MaxTokens:         args.NPredict,
+		Temperature:       args.Temp,
+		TopP:              args.TopP,
+		TopK:              args.TopK,
+		MinP:              args.MinP,
+		RepetitionPenalty: args.RepetitionPenalty,
+		PromptLookup:      args.PromptLookup,
+		Regex:             args.Regex != "",
+		StopTokens:        len(args.StopTokenIDs),
+		BannedStrings:     len(args.BannedStrings),
+		Images:            len(args.Images),
+	}
+	if args.RandomSeed >= 0 {
+		seed := args.RandomSeed
+		p.Seed = &seed
+	}
+	return p
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,211 @@
+package llmservice
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+)
+
+// CacheOptions configures the completion cache. It stores the results of
+// reproducible predictions — greedy or with a fixed seed — and replays them
+// for identical requests instead of running the model again.
+type CacheOptions struct {
+	// MaxEntries limits the number of stored completions; 0 disables the
+	// cache.
+	MaxEntries int
+	// MaxBytes limits the total size of the stored text; 0 means unlimited.
+	MaxBytes int64
+	// TTL is how long a completion is served; 0 means until evicted.
+	TTL time.Duration
+}
+
+var (
+	completionCacheHits = metrics.NewCounter("llamacpp_completion_cache_hits_total",
+		"Predictions served from the completion cache.",
+		"model", "quantization")
+	completionCacheMisses = metrics.NewCounter("llamacpp_completion_cache_misses_total",
+		"Cacheable predictions that were not in the completion cache.",
+		"model", "quantization")
+	completionCacheBytes = metrics.NewGauge("llamacpp_completion_cache_bytes",
+		"Size of the text held by the completion cache.")
+)
+
+// cacheEntryOverhead approximates the memory of an entry and of each
+// recorded stream event beyond their text.
+const cacheEntryOverhead = 64
+
+// streamEvent is a recorded StreamFunc or SpecialTokenFunc call.
+type streamEvent struct {
+	tokenIDs                       []int // the control token of special events
+	promptTokens, completionTokens int
+	text                           string
+	special                        bool
+}
+
+type cachedCompletion struct {
+	key     string
+	result  inferenceengine.Result
+	events  []streamEvent
+	size    int64
+	expires time.Time // zero if the entry does not expire
+}
+
+// completionCache is an LRU cache of completions, safe for concurrent use.
+// Identical requests in flight at the same time all run the model; the
+// last to finish is kept.
+type completionCache struct {
+	opts CacheOptions
+	now  func() time.Time
+
+	mx      sync.Mutex
+	lru     *list.List // of *cachedCompletion, most recently used first
+	entries map[string]*list.Element
+	bytes   int64
+}
+
+func newCompletionCache(opts CacheOptions) *completionCache {
+	return &completionCache{
+		opts:    opts,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// cacheable reports whether a prediction with args always produces the same
+// output: greedy sampling or a fixed seed. Images are not cached.
+func cacheable(args inferenceengine.PredictArgs) bool {
+	return (args.Greedy() || args.RandomSeed >= 0 || args.BeamWidth > 1) && len(args.Images) == 0
+}
+
+// completionKey identifies a prediction by everything that shapes its
+// output: the model and its context settings, the prompt and the sampling
+// parameters. Request metadata and callbacks are left out. Entries survive
+// reloading a model file from the same path; the TTL bounds how long.
+func completionKey(mc inferenceengine.ModelContext, modelPath, prompt string, args inferenceengine.PredictArgs) string {
+	args.ClientKey = ""
+	args.RequestID = ""
+	args.PrefillProgress = nil
+	args.SpecialToken = nil
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %d %q\n", modelPath, mc.CtxSize, mc.KvCacheType)
+	io.WriteString(h, prompt)
+	fmt.Fprintf(h, "\n%#v", args)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the completion stored under key, if any and not expired.
+func (c *completionCache) get(key string) (*cachedCompletion, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cachedCompletion)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.removeLocked(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return entry, true
+}
+
+// put stores a completion, evicting the least recently used ones beyond the
+// limits. A completion larger than MaxBytes is not stored.
+func (c *completionCache) put(key string, result inferenceengine.Result, events []streamEvent) {
+	entry := &cachedCompletion{
+		key:    key,
+		result: result,
+		events: events,
+		size:   int64(cacheEntryOverhead + len(result.Text)),
+	}
+	for _, ev := range events {
+		entry.size += int64(cacheEntryOverhead + len(ev.text) + 8*len(ev.tokenIDs))
+	}
+	if c.opts.MaxBytes > 0 && entry.size > c.opts.MaxBytes {
+		return
+	}
+	if c.opts.TTL > 0 {
+		entry.expires = c.now().Add(c.opts.TTL)
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+	for c.lru.Len() > c.opts.MaxEntries || (c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes) {
+		c.removeLocked(c.lru.Back())
+	}
+	completionCacheBytes.Set(float64(c.bytes))
+}
+
+func (c *completionCache) removeLocked(el *list.Element) {
+	entry := c.lru.Remove(el).(*cachedCompletion)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+	completionCacheBytes.Set(float64(c.bytes))
+}
+
+// replay sends the recorded stream of a cached completion to the callbacks
+// of a new request and returns its result. Control tokens go to
+// args.SpecialToken if it is set.
+func (entry *cachedCompletion) replay(args inferenceengine.PredictArgs, stream inferenceengine.StreamFunc, start time.Time) (inferenceengine.Result, error) {
+	for _, ev := range entry.events {
+		var err error
+		switch {
+		case ev.special && args.SpecialToken != nil:
+			err = args.SpecialToken(ev.tokenIDs[0], ev.promptTokens, ev.completionTokens, ev.text)
+		case !ev.special && stream != nil:
+			err = stream(ev.tokenIDs, ev.promptTokens, ev.completionTokens, ev.text)
+		}
+		if err != nil {
+			return inferenceengine.Result{}, err
+		}
+	}
+	res := entry.result
+	res.Cached = true
+	res.Timings = inferenceengine.Timings{TotalTime: time.Since(start)}
+	return res, nil
+}
+
+// recordStream wraps the callbacks of args and stream so the events they
+// receive are appended to events.
+func recordStream(args *inferenceengine.PredictArgs, stream inferenceengine.StreamFunc, events *[]streamEvent) inferenceengine.StreamFunc {
+	special := args.SpecialToken
+	args.SpecialToken = func(token, promptTokens, completionTokens int, piece string) error {
+		*events = append(*events, streamEvent{
+			tokenIDs:         []int{token},
+			promptTokens:     promptTokens,
+			completionTokens: completionTokens,
+			text:             piece,
+			special:          true,
+		})
+		if special != nil {
+			return special(token, promptTokens, completionTokens, piece)
+		}
+		return nil
+	}
+	return func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+		*events = append(*events, streamEvent{
+			tokenIDs:         tokenIDs,
+			promptTokens:     promptTokens,
+			completionTokens: completionTokens,
+			text:             message,
+		})
+		if stream != nil {
+			return stream(tokenIDs, promptTokens, completionTokens, message)
+		}
+		return nil
+	}
+}
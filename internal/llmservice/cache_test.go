@@ -0,0 +1,134 @@
+package llmservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheable(t *testing.T) {
+	require.True(t, cacheable(inferenceengine.PredictArgs{Temp: 0, RandomSeed: -1}))
+	require.True(t, cacheable(inferenceengine.PredictArgs{Temp: 0.8, RandomSeed: 42}))
+	require.False(t, cacheable(inferenceengine.PredictArgs{Temp: 0.8, RandomSeed: -1}))
+	require.False(t, cacheable(inferenceengine.PredictArgs{
+		Images: []inferenceengine.Image{{Data: []byte{1}, MimeType: "image/png"}},
+	}))
+}
+
+func TestCompletionKey(t *testing.T) {
+	mc := inferenceengine.ModelContext{CtxSize: 4096}
+	args := inferenceengine.PredictArgs{NPredict: 16, RandomSeed: -1}
+	key := completionKey(mc, "/m.gguf", "Hello", args)
+
+	// Request metadata and callbacks do not matter.
+	other := args
+	other.ClientKey = "client"
+	other.RequestID = "req"
+	other.PrefillProgress = func(int, int) error { return nil }
+	require.Equal(t, key, completionKey(mc, "/m.gguf", "Hello", other))
+
+	other = args
+	other.NPredict = 17
+	require.NotEqual(t, key, completionKey(mc, "/m.gguf", "Hello", other))
+	require.NotEqual(t, key, completionKey(mc, "/m.gguf", "Hello!", args))
+	require.NotEqual(t, key, completionKey(mc, "/n.gguf", "Hello", args))
+	require.NotEqual(t, key, completionKey(inferenceengine.ModelContext{CtxSize: 2048}, "/m.gguf", "Hello", args))
+
+	a, b := args, args
+	a.BannedStrings = []string{"a b"}
+	b.BannedStrings = []string{"a", "b"}
+	require.NotEqual(t, completionKey(mc, "/m.gguf", "Hello", a), completionKey(mc, "/m.gguf", "Hello", b))
+}
+
+func TestCompletionCacheEviction(t *testing.T) {
+	c := newCompletionCache(CacheOptions{MaxEntries: 2})
+	c.put("a", inferenceengine.Result{Text: "A"}, nil)
+	c.put("b", inferenceengine.Result{Text: "B"}, nil)
+	_, ok := c.get("a") // a is now the most recently used
+	require.True(t, ok)
+	c.put("c", inferenceengine.Result{Text: "C"}, nil)
+
+	_, ok = c.get("b")
+	require.False(t, ok)
+	for _, key := range []string{"a", "c"} {
+		_, ok := c.get(key)
+		require.True(t, ok, key)
+	}
+
+	// A size limit evicts as many entries as needed and skips entries that
+	// are too large on their own.
+	c = newCompletionCache(CacheOptions{MaxEntries: 10, MaxBytes: 3 * cacheEntryOverhead})
+	c.put("a", inferenceengine.Result{Text: "A"}, nil)
+	c.put("b", inferenceengine.Result{Text: "B"}, nil)
+	c.put("big", inferenceengine.Result{Text: string(make([]byte, 3*cacheEntryOverhead))}, nil)
+	_, ok = c.get("big")
+	require.False(t, ok)
+	c.put("c", inferenceengine.Result{Text: string(make([]byte, cacheEntryOverhead))}, nil)
+	_, ok = c.get("a")
+	require.False(t, ok)
+	_, ok = c.get("c")
+	require.True(t, ok)
+	require.LessOrEqual(t, c.bytes, c.opts.MaxBytes)
+}
+
+func TestCompletionCacheTTL(t *testing.T) {
+	now := time.Now()
+	c := newCompletionCache(CacheOptions{MaxEntries: 10, TTL: time.Minute})
+	c.now = func() time.Time { return now }
+	c.put("a", inferenceengine.Result{Text: "A"}, nil)
+
+	now = now.Add(59 * time.Second)
+	_, ok := c.get("a")
+	require.True(t, ok)
+	now = now.Add(time.Second)
+	_, ok = c.get("a")
+	require.False(t, ok)
+	require.Zero(t, c.lru.Len())
+	require.Zero(t, c.bytes)
+}
+
+func TestCompletionCacheReplay(t *testing.T) {
+	var events []streamEvent
+	args := inferenceengine.PredictArgs{}
+	stream := recordStream(&args, nil, &events)
+	require.NoError(t, stream([]int{10}, 2, 1, "Hello"))
+	require.NoError(t, args.SpecialToken(11, 2, 2, "<|im_start|>"))
+	require.NoError(t, stream([]int{12}, 2, 3, " world"))
+
+	c := newCompletionCache(CacheOptions{MaxEntries: 1})
+	c.put("k", inferenceengine.Result{Text: "Hello world", CompletionTokens: 3}, events)
+	entry, ok := c.get("k")
+	require.True(t, ok)
+
+	var text, special []string
+	var tokenIDs []int
+	res, err := entry.replay(inferenceengine.PredictArgs{
+		SpecialToken: func(token, promptTokens, completionTokens int, piece string) error {
+			special = append(special, piece)
+			return nil
+		},
+	}, func(ids []int, promptTokens, completionTokens int, message string) error {
+		text = append(text, message)
+		tokenIDs = append(tokenIDs, ids...)
+		return nil
+	}, time.Now())
+	require.NoError(t, err)
+	require.True(t, res.Cached)
+	require.Equal(t, "Hello world", res.Text)
+	require.Equal(t, 3, res.CompletionTokens)
+	require.Equal(t, []string{"Hello", " world"}, text)
+	require.Equal(t, []string{"<|im_start|>"}, special)
+	require.Equal(t, []int{10, 12}, tokenIDs)
+
+	// Without a SpecialToken func control tokens are skipped.
+	text = nil
+	_, err = entry.replay(inferenceengine.PredictArgs{}, func(_ []int, _, _ int, message string) error {
+		text = append(text, message)
+		return nil
+	}, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, []string{"Hello", " world"}, text)
+}
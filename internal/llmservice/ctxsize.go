@@ -0,0 +1,118 @@
+package llmservice
+
+import (
+	"fmt"
+	"strconv"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+)
+
+// WarningCtxSizeClamped is the reason of the load warning reported when a
+// model's context size was reduced. Its metadata holds the "requested" and
+// the resulting "ctx_size", and the "limit" that applied: "max_ctx_size",
+// "n_ctx_train" or "memory".
+const WarningCtxSizeClamped = "CTX_SIZE_CLAMPED"
+
+// kvMemoryShare is the part of a device's free memory the KV cache may
+// take; the rest is left to the compute buffers.
+const kvMemoryShare = 0.9
+
+// minCtxSize is the smallest context size the memory limit clamps to;
+// llama.cpp pads context sizes to this multiple anyway.
+const minCtxSize = 256
+
+// contextDefaults are the engine's context settings, which apply to models
+// loaded without overrides.
+type contextDefaults struct {
+	ctxSize     int
+	nParallel   int
+	kvCacheType string
+}
+
+// ctxLimit bounds the context size of a model; a zero size is no bound.
+type ctxLimit struct {
+	name string
+	size int
+}
+
+// clampCtxSize returns requested reduced to the smallest of limits, and the
+// warning to report if it was reduced.
+func clampCtxSize(requested int, limits ...ctxLimit) (int, *modelmanagement.LoadWarning) {
+	ctxSize, limit := requested, ""
+	for _, l := range limits {
+		if l.size > 0 && l.size < ctxSize {
+			ctxSize, limit = l.size, l.name
+		}
+	}
+	if limit == "" {
+		return requested, nil
+	}
+	return ctxSize, &modelmanagement.LoadWarning{
+		Reason:  WarningCtxSizeClamped,
+		Message: fmt.Sprintf("context size reduced from %d to %d (%s)", requested, ctxSize, limit),
+		Metadata: map[string]string{
+			"requested": strconv.Itoa(requested),
+			"ctx_size":  strconv.Itoa(ctxSize),
+			"limit":     limit,
+		},
+	}
+}
+
+// memoryCtxSize returns the context size whose KV cache, at bytesPerCell,
+// fits in kvMemoryShare of freeMemory, or 0 if unknown.
+func memoryCtxSize(freeMemory uint64, bytesPerCell float64) int {
+	if freeMemory == 0 || bytesPerCell <= 0 {
+		return 0
+	}
+	n := int(float64(freeMemory)*kvMemoryShare/bytesPerCell) / minCtxSize * minCtxSize
+	return max(n, minCtxSize)
+}
+
+// kvBytesPerCell estimates the KV cache memory of one cell of info's model:
+// a key and a value of the model's KV width in each layer.
+func kvBytesPerCell(info llamacppbindings.ModelInfo, kvCacheType string) float64 {
+	if info.NHead == 0 || info.IsRecurrent {
+		return 0
+	}
+	width := info.NEmbd / info.NHead * info.NHeadKV
+	var elemBytes float64
+	switch kvCacheType {
+	case "q8_0":
+		elemBytes = 34.0 / 32 // blocks of 32 int8 and an f16 scale
+	case "q4_0":
+		elemBytes = 18.0 / 32
+	default:
+		elemBytes = 2
+	}
+	return 2 * float64(info.NLayer*width) * elemBytes
+}
+
+// kvFreeMemory returns the free memory of the device that holds the KV
+// cache: the least free of gpus if layers are offloaded to them, else the
+// CPU's. It is 0 if unknown.
+func kvFreeMemory(devices []llamacppbindings.Device, nGpuLayers int, gpus []int) uint64 {
+	var cpu uint64
+	var gpuDevices []llamacppbindings.Device
+	for _, d := range devices {
+		switch d.Type {
+		case llamacppbindings.DeviceCPU:
+			cpu = d.MemoryFree
+		case llamacppbindings.DeviceGPU, llamacppbindings.DeviceIGPU:
+			gpuDevices = append(gpuDevices, d)
+		}
+	}
+	if nGpuLayers == 0 || len(gpuDevices) == 0 {
+		return cpu
+	}
+	var free uint64
+	for _, gpu := range gpus {
+		if gpu < 0 || gpu >= len(gpuDevices) {
+			continue
+		}
+		if m := gpuDevices[gpu].MemoryFree; free == 0 || m < free {
+			free = m
+		}
+	}
+	return free
+}
@@ -0,0 +1,47 @@
+package llmservice
+
+import (
+	"testing"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClampCtxSize(t *testing.T) {
+	ctxSize, warning := clampCtxSize(4096, ctxLimit{"max_ctx_size", 0}, ctxLimit{"n_ctx_train", 8192})
+	require.Equal(t, 4096, ctxSize)
+	require.Nil(t, warning)
+
+	// The smallest limit applies.
+	ctxSize, warning = clampCtxSize(32768,
+		ctxLimit{"max_ctx_size", 16384}, ctxLimit{"n_ctx_train", 8192}, ctxLimit{"memory", 0})
+	require.Equal(t, 8192, ctxSize)
+	require.Equal(t, WarningCtxSizeClamped, warning.Reason)
+	require.Equal(t, map[string]string{"requested": "32768", "ctx_size": "8192", "limit": "n_ctx_train"}, warning.Metadata)
+}
+
+func TestMemoryCtxSize(t *testing.T) {
+	// Llama 3 8B: 32 layers, 4096 wide with 8 of 32 heads for keys and values.
+	info := llamacppbindings.ModelInfo{NEmbd: 4096, NLayer: 32, NHead: 32, NHeadKV: 8}
+	perCell := kvBytesPerCell(info, "f16")
+	require.Equal(t, float64(128<<10), perCell)
+	require.Equal(t, 7168, memoryCtxSize(1<<30, perCell))
+	require.Equal(t, minCtxSize, memoryCtxSize(1<<20, perCell))
+	require.Zero(t, memoryCtxSize(0, perCell))
+
+	// Recurrent models keep a fixed-size state instead.
+	info.IsRecurrent = true
+	require.Zero(t, kvBytesPerCell(info, "f16"))
+}
+
+func TestKvFreeMemory(t *testing.T) {
+	devices := []llamacppbindings.Device{
+		{Type: llamacppbindings.DeviceGPU, MemoryFree: 8 << 30},
+		{Type: llamacppbindings.DeviceGPU, MemoryFree: 4 << 30},
+		{Type: llamacppbindings.DeviceCPU, MemoryFree: 32 << 30},
+	}
+	require.Equal(t, uint64(8<<30), kvFreeMemory(devices, -1, []int{0}))
+	require.Equal(t, uint64(4<<30), kvFreeMemory(devices, -1, []int{0, 1}))
+	require.Equal(t, uint64(32<<30), kvFreeMemory(devices, 0, []int{0}))
+}
@@ -0,0 +1,224 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+)
+
+// HealthCheckOptions configures the periodic self-check of loaded models,
+// which is off by default. It catches a model that stopped producing output,
+// e.g. after a GPU or driver fault, before clients do.
+type HealthCheckOptions struct {
+	// Interval is the time between two probes of each loaded model; 0
+	// disables the check.
+	Interval time.Duration
+	// MaxLatency marks a model unhealthy if generating the probe token
+	// took longer, not counting the time the probe waited for a slot. 0
+	// only fails probes that return an error or do not finish within
+	// Interval.
+	MaxLatency time.Duration
+}
+
+// healthProbePrompt is the prompt a probe greedily generates one token
+// from.
+const healthProbePrompt = "Hello"
+
+var healthChecks = metrics.NewCounter("llamacpp_model_health_checks_total",
+	"Model health probes, by model and result (ok, failed or slow).",
+	"model", "result")
+
+// ModelHealth is the result of the last health probe of a model.
+type ModelHealth struct {
+	Model     string
+	Healthy   bool
+	Error     string        // why the probe failed; empty if Healthy
+	Latency   time.Duration // time spent generating, without queueing
+	CheckedAt time.Time
+}
+
+// healthChecker probes the loaded models of a service in the background.
+type healthChecker struct {
+	opts    HealthCheckOptions
+	service *Service
+	quit    chan struct{}
+	done    chan struct{}
+
+	mx      sync.Mutex
+	results map[string]ModelHealth
+}
+
+func newHealthChecker(s *Service, opts HealthCheckOptions) *healthChecker {
+	h := &healthChecker{
+		opts:    opts,
+		service: s,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+		results: map[string]ModelHealth{},
+	}
+	go h.run()
+	return h
+}
+
+func (s *Service) withHealthCheck(opts HealthCheckOptions) *Service {
+	if opts.Interval > 0 {
+		s.health = newHealthChecker(s, opts)
+		s.logger.Infof("model health check: every %s, max latency %s", opts.Interval, opts.MaxLatency)
+	}
+	return s
+}
+
+func (h *healthChecker) run() {
+	defer close(h.done)
+	ticker := time.NewTicker(h.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.checkAll()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+func (h *healthChecker) stop() {
+	close(h.quit)
+	<-h.done
+}
+
+// checkAll probes each loaded model in turn and forgets the results of
+// models that were unloaded.
+func (h *healthChecker) checkAll() {
+	models := h.service.ListModels()
+	for _, model := range models {
+		select {
+		case <-h.quit:
+			return
+		default:
+		}
+		h.checkModel(model)
+	}
+
+	loaded := make(map[string]bool, len(models))
+	for _, model := range models {
+		loaded[model] = true
+	}
+	h.mx.Lock()
+	for model := range h.results {
+		if !loaded[model] {
+			delete(h.results, model)
+		}
+	}
+	h.mx.Unlock()
+}
+
+// checkModel probes model and records the result, or forgets the model if
+// it was not probed.
+func (h *healthChecker) checkModel(model string) {
+	res, ok := h.check(model)
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if ok {
+		h.results[model] = res
+	} else {
+		delete(h.results, model)
+	}
+}
+
+// check generates one token with model. It bypasses the hooks, quota, audit
+// log and completion cache, which are meant for client requests, and never
+// loads the model: one that was unloaded since the models were listed, or
+// is being loaded again, is not probed. Neither are classifier models, which
+// cannot generate. check returns false for the models it did not probe.
+func (h *healthChecker) check(model string) (ModelHealth, bool) {
+	res := ModelHealth{Model: model, CheckedAt: time.Now()}
+	ctx, cancel := context.WithTimeout(context.Background(), h.opts.Interval)
+	defer cancel()
+	go func() {
+		select {
+		case <-h.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var result inferenceengine.Result
+	mc, release, err := h.service.acquireModel(model)
+	if errors.Is(err, modelmanagement.ErrModelNotFound) || errors.Is(err, modelmanagement.ErrModelLoading) {
+		return res, false
+	}
+	if err == nil {
+		defer release()
+	}
+	if err == nil && mc.Model != nil && mc.Model.NClsOut() > 0 {
+		return res, false
+	}
+	if err == nil {
+		args := inferenceengine.PredictArgs{NPredict: 1, TopK: 1, RandomSeed: -1, RequestID: "health-check"}
+		result, err = h.service.predictionsManager.Predict(ctx, mc, healthProbePrompt, args, nil)
+	}
+	res.Latency = result.Timings.TotalTime - result.Timings.QueueTime
+
+	switch {
+	case err != nil:
+		res.Error = err.Error()
+		healthChecks.Inc(model, "failed")
+	case h.opts.MaxLatency > 0 && res.Latency > h.opts.MaxLatency:
+		res.Error = fmt.Sprintf("probe took %s, over the %s limit", res.Latency.Round(time.Millisecond), h.opts.MaxLatency)
+		healthChecks.Inc(model, "slow")
+	default:
+		res.Healthy = true
+		healthChecks.Inc(model, "ok")
+	}
+	if !res.Healthy {
+		h.service.logger.Warnf("health check of %s failed: %s", model, res.Error)
+	}
+	return res, true
+}
+
+// ModelHealth returns the result of the last health probe of each loaded
+// model the client with the given key may use, sorted by model. It is
+// empty if the check is disabled. Models loaded since the last round of
+// probes are not listed yet.
+func (s *Service) ModelHealth(clientKey string) []ModelHealth {
+	if s.health == nil {
+		return nil
+	}
+	s.health.mx.Lock()
+	defer s.health.mx.Unlock()
+	models := make([]string, 0, len(s.health.results))
+	for model := range s.health.results {
+		models = append(models, model)
+	}
+	models = s.acl.Filter(clientKey, models)
+	sort.Strings(models)
+	out := make([]ModelHealth, 0, len(models))
+	for _, model := range models {
+		out = append(out, s.health.results[model])
+	}
+	return out
+}
+
+// Healthy reports whether the last health probe of every loaded model
+// succeeded. It is true if the check is disabled.
+func (s *Service) Healthy() bool {
+	if s.health == nil {
+		return true
+	}
+	s.health.mx.Lock()
+	defer s.health.mx.Unlock()
+	for _, res := range s.health.results {
+		if !res.Healthy {
+			return false
+		}
+	}
+	return true
+}
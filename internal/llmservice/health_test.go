@@ -0,0 +1,59 @@
+package llmservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelHealth(t *testing.T) {
+	s := &Service{}
+	require.True(t, s.Healthy())
+	require.Empty(t, s.ModelHealth(""))
+
+	s.health = &healthChecker{results: map[string]ModelHealth{
+		"b.gguf": {Model: "b.gguf", Healthy: true},
+		"a.gguf": {Model: "a.gguf", Healthy: true},
+	}}
+	require.True(t, s.Healthy())
+	health := s.ModelHealth("")
+	require.Len(t, health, 2)
+	require.Equal(t, "a.gguf", health[0].Model)
+
+	s.health.results["b.gguf"] = ModelHealth{Model: "b.gguf", Error: "decode failed"}
+	require.False(t, s.Healthy())
+	require.Equal(t, "decode failed", s.ModelHealth("")[1].Error)
+}
+
+func TestHealthCheckSkipsUnloadedModel(t *testing.T) {
+	s := NewService(Options{Backend: BackendMock, AutoLoad: true}, logging.NewSprintfLogger())
+	defer s.Stop()
+	// A checker without its background loop, probing when the test says.
+	h := &healthChecker{
+		opts:    HealthCheckOptions{Interval: time.Second},
+		service: s,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+		results: map[string]ModelHealth{},
+	}
+	close(h.done)
+	s.health = h
+
+	const model = "/models/a.gguf"
+	require.NoError(t, s.LoadModel(context.Background(), model, modelmanagement.LoadOverrides{}, nil))
+	h.checkAll()
+	require.True(t, h.results[model].Healthy)
+
+	// The model is unloaded after checkAll listed it: the probe neither
+	// loads it again nor fails, and its result is dropped.
+	require.NoError(t, s.UnloadModel(model))
+	h.checkModel(model)
+	require.Empty(t, s.ListModels())
+	require.Empty(t, h.results)
+	require.True(t, s.Healthy())
+}
@@ -0,0 +1,103 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+)
+
+// HookRequest is a Predict call as seen by hooks. Hooks may change Prompt
+// and Args; Model is for information only.
+type HookRequest struct {
+	Model  string
+	Prompt string
+	Args   inferenceengine.PredictArgs
+}
+
+// Hook pre- and post-processes Predict calls, e.g. for guardrails or PII
+// scrubbing. Hooks are registered with Options.Hooks; BeforePredict runs in
+// registration order and AfterPredict in reverse order.
+type Hook interface {
+	// BeforePredict may rewrite req, or reject it by returning an error,
+	// preferably a *RejectedError.
+	BeforePredict(ctx context.Context, req *HookRequest) error
+	// AfterPredict may rewrite res.Text and add res.Annotations. For
+	// streamed requests it runs after the text was sent; use a StreamHook
+	// to change streamed text.
+	AfterPredict(ctx context.Context, req *HookRequest, res *inferenceengine.Result) error
+}
+
+// StreamHook is a Hook that also filters streamed text.
+type StreamHook interface {
+	Hook
+	// FilterStream returns the text to send instead of piece. It sees
+	// one piece at a time, so it cannot match text split across pieces.
+	// An error stops the generation.
+	FilterStream(ctx context.Context, req *HookRequest, piece string) (string, error)
+}
+
+// ErrRequestRejected matches every *RejectedError.
+var ErrRequestRejected = errors.New("request rejected")
+
+// RejectedError is returned by a hook that refuses a request.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("request rejected: %s", e.Reason)
+}
+
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRequestRejected
+}
+
+// beforePredict runs the BeforePredict hooks.
+func (s *Service) beforePredict(ctx context.Context, req *HookRequest) error {
+	for _, h := range s.hooks {
+		if err := h.BeforePredict(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterPredict runs the AfterPredict hooks on a successful result.
+func (s *Service) afterPredict(ctx context.Context, req *HookRequest, res *inferenceengine.Result) error {
+	if len(s.hooks) == 0 {
+		return nil
+	}
+	if res.Annotations == nil {
+		res.Annotations = make(map[string]string)
+	}
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		if err := s.hooks[i].AfterPredict(ctx, req, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterStream passes the pieces sent to stream through the StreamHooks.
+func (s *Service) filterStream(ctx context.Context, req *HookRequest, stream inferenceengine.StreamFunc) inferenceengine.StreamFunc {
+	var filters []StreamHook
+	for _, h := range s.hooks {
+		if f, ok := h.(StreamHook); ok {
+			filters = append(filters, f)
+		}
+	}
+	if stream == nil || len(filters) == 0 {
+		return stream
+	}
+	return func(tokenIDs []int, promptTokens, completionTokens int, message string) error {
+		var err error
+		for _, f := range filters {
+			if message, err = f.FilterStream(ctx, req, message); err != nil {
+				return err
+			}
+		}
+		return stream(tokenIDs, promptTokens, completionTokens, message)
+	}
+}
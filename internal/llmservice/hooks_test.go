@@ -0,0 +1,72 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testHook records its calls in log and redacts "secret".
+type testHook struct {
+	name string
+	log  *[]string
+}
+
+func (h testHook) BeforePredict(ctx context.Context, req *HookRequest) error {
+	*h.log = append(*h.log, "before "+h.name)
+	if strings.Contains(req.Prompt, "forbidden") {
+		return &RejectedError{Reason: "forbidden topic"}
+	}
+	req.Prompt = h.name + ":" + req.Prompt
+	return nil
+}
+
+func (h testHook) AfterPredict(ctx context.Context, req *HookRequest, res *inferenceengine.Result) error {
+	*h.log = append(*h.log, "after "+h.name)
+	res.Annotations[h.name] = "checked"
+	return nil
+}
+
+func (h testHook) FilterStream(ctx context.Context, req *HookRequest, piece string) (string, error) {
+	return strings.ReplaceAll(piece, "secret", "******"), nil
+}
+
+func TestHooks(t *testing.T) {
+	var log []string
+	s := &Service{hooks: []Hook{testHook{"a", &log}, testHook{"b", &log}}}
+	ctx := context.Background()
+
+	req := &HookRequest{Model: "/m.gguf", Prompt: "Hello"}
+	require.NoError(t, s.beforePredict(ctx, req))
+	require.Equal(t, "b:a:Hello", req.Prompt)
+
+	var res inferenceengine.Result
+	require.NoError(t, s.afterPredict(ctx, req, &res))
+	require.Equal(t, map[string]string{"a": "checked", "b": "checked"}, res.Annotations)
+	require.Equal(t, []string{"before a", "before b", "after b", "after a"}, log)
+
+	var sent []string
+	stream := s.filterStream(ctx, req, func(_ []int, _, _ int, message string) error {
+		sent = append(sent, message)
+		return nil
+	})
+	require.NoError(t, stream([]int{1}, 1, 1, "the secret is"))
+	require.Equal(t, []string{"the ****** is"}, sent)
+
+	err := s.beforePredict(ctx, &HookRequest{Prompt: "forbidden"})
+	require.True(t, errors.Is(err, ErrRequestRejected))
+	require.EqualError(t, err, "request rejected: forbidden topic")
+}
+
+func TestNoHooks(t *testing.T) {
+	s := &Service{}
+	var res inferenceengine.Result
+	require.NoError(t, s.afterPredict(context.Background(), &HookRequest{}, &res))
+	require.Nil(t, res.Annotations)
+	require.Nil(t, s.filterStream(context.Background(), &HookRequest{}, nil))
+}
@@ -1,69 +1,264 @@
 package llmservice
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
 	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/modelcrypt"
 	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
 )
 
 type LoadModelOptions struct {
 	NGpuLayers  int
 	UseMmap     bool
+	UseMlock    bool
 	SplitMode   int
 	MainGpu     int
 	TensorSplit []float32
+
+	// ReplicaGpus, if it lists two or more GPUs, loads each model once on
+	// each of them (split mode none) instead of once across them, for an
+	// inferenceengine.Replicas with one engine per GPU.
+	ReplicaGpus []int
+
+	// DecryptionKey decrypts models encrypted with modelcrypt. Encrypted
+	// models fail to load without it.
+	DecryptionKey []byte
+	// DecryptDir holds decrypted copies of models on platforms without
+	// anonymous memory files ("" for the system temp directory).
+	DecryptDir string
+
+	// MaxCtxSize caps the context size of every model; 0 is no cap. Context
+	// sizes are also reduced to n_parallel times the context the model was
+	// trained with and to what the KV cache can take of the free memory.
+	MaxCtxSize int
 }
 
+// ErrModelLoadFailed is returned when llama.cpp could not load a model file.
+var ErrModelLoadFailed = errors.New("model load failed")
+
 type ModelData struct {
 	ModelParams *llamacppbindings.ModelParams
 	Model       *llamacppbindings.Model
+	Replicas    []*llamacppbindings.Model // Model first; see ReplicaGpus
+
+	// Context settings requested when the model was loaded; zero values
+	// use the engine defaults.
+	CtxSize     int
+	KvCacheType string
+	Batching    inferenceengine.Batching
+
+	// Quantization is the file type of the model, e.g. "Q4_K - Medium".
+	Quantization string
 }
 
 func (md *ModelData) Destroy() error {
-	if md.Model != nil {
+	if len(md.Replicas) > 0 {
+		for _, model := range md.Replicas {
+			model.Free()
+		}
+	} else if md.Model != nil {
 		md.Model.Free()
 	}
 	return nil
 }
 
-func newLoadModelFunc(options LoadModelOptions, logger logging.SprintfLogger) modelmanagement.LoadModelFunc {
+// overrideBatching returns the batching knobs a model was loaded with.
+func overrideBatching(o modelmanagement.LoadOverrides) inferenceengine.Batching {
+	return inferenceengine.Batching{
+		MaxTokens: o.MaxBatchTokens,
+		MaxWait:   o.BatchWait,
+		MaxSeqs:   o.MaxBatchSeqs,
+	}
+}
+
+func newLoadModelFunc(options LoadModelOptions, defaults contextDefaults, logger logging.SprintfLogger) modelmanagement.LoadModelFunc[*ModelData] {
 	cmd := &loadModelCmd{
-		options: options,
-		logger:  logger.With("module", "loadModelCmd"),
+		options:  options,
+		defaults: defaults,
+		logger:   logger.With("module", "loadModelCmd"),
 	}
 	return cmd.Do
 }
 
 type loadModelCmd struct {
-	options LoadModelOptions
-	logger  logging.SprintfLogger
+	options  LoadModelOptions
+	defaults contextDefaults
+	logger   logging.SprintfLogger
 }
 
-func (cmd *loadModelCmd) Do(path string, progress modelmanagement.LoadModelProgressFunc) (interface{}, error) {
-	cmd.logger.Debugf("Do: %s", path)
+func (cmd *loadModelCmd) Do(ctx context.Context, path string, overrides modelmanagement.LoadOverrides, progress modelmanagement.LoadProgressFunc) (*ModelData, error) {
+	cmd.logger.Debugf("Do: %s, overrides: %+v", path, overrides)
+
+	options := cmd.options
+	if overrides.NGpuLayers != nil {
+		options.NGpuLayers = *overrides.NGpuLayers
+	}
+	if overrides.UseMmap != nil {
+		options.UseMmap = *overrides.UseMmap
+	}
+	if overrides.UseMlock != nil {
+		options.UseMlock = *overrides.UseMlock
+	}
 
 	modelParams := llamacppbindings.NewModelDefaultParams()
-	modelParams.SetNGpuLayers(cmd.options.NGpuLayers)
-	modelParams.SetUseMmap(cmd.options.UseMmap)
-	modelParams.SetSplitMode(cmd.options.SplitMode)
-	modelParams.SetMainGpu(cmd.options.MainGpu)
-	if len(cmd.options.TensorSplit) > 0 {
-		modelParams.SetTensorSplit(cmd.options.TensorSplit)
+	// llama.cpp copies the params; they are only needed for the load.
+	defer modelParams.Free()
+	modelParams.SetNGpuLayers(options.NGpuLayers)
+	modelParams.SetUseMmap(options.UseMmap)
+	modelParams.SetUseMlock(options.UseMlock)
+	modelParams.SetSplitMode(options.SplitMode)
+	modelParams.SetMainGpu(options.MainGpu)
+	if len(options.TensorSplit) > 0 {
+		modelParams.SetTensorSplit(options.TensorSplit)
 	}
-	modelParams.SetProgressCallback(progress)
 
-	cmd.logger.Debugf("Do: modelParams: %+v", modelParams)
+	gpus := []int{options.MainGpu}
+	if len(options.ReplicaGpus) > 1 {
+		gpus = options.ReplicaGpus
+		modelParams.SetSplitMode(llamacppbindings.SplitModeNone)
+	}
+	loaded := 0 // replicas done, for the progress of a load over several GPUs
+
+	var bytesTotal int64
+	var warnings []modelmanagement.LoadWarning
+	report := func(stage modelmanagement.LoadStage, fraction float32) bool {
+		if stage == modelmanagement.LoadStageLoading {
+			fraction = (float32(loaded) + fraction) / float32(len(gpus))
+		}
+		return progress(modelmanagement.LoadProgress{
+			Stage:       stage,
+			Fraction:    fraction,
+			BytesLoaded: int64(float64(bytesTotal) * float64(fraction)),
+			BytesTotal:  bytesTotal,
+			Warnings:    warnings,
+		})
+	}
+	modelParams.SetProgressCallback(func(fraction float32) bool {
+		return report(modelmanagement.LoadStageLoading, fraction)
+	})
 
-	model, err := llamacppbindings.LoadModelFromFile(path, modelParams)
+	if !report(modelmanagement.LoadStageReading, 0) {
+		return nil, llamacppbindings.ErrLoadAborted
+	}
+	loadPath := path
+	encrypted, err := modelcrypt.IsEncrypted(path)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrModelLoadFailed, err)
 	}
+	if encrypted {
+		if options.DecryptionKey == nil {
+			return nil, fmt.Errorf("%w: %s is encrypted and no model key is configured", ErrModelLoadFailed, path)
+		}
+		plaintext, err := modelcrypt.Decrypted(ctx, path, options.DecryptionKey, options.DecryptDir)
+		if ctx.Err() != nil {
+			return nil, llamacppbindings.ErrLoadAborted
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrModelLoadFailed, err)
+		}
+		// Once loaded, the model no longer needs the decrypted copy.
+		defer plaintext.Close()
+		if !plaintext.Anonymous {
+			// Close wipes the file, which must not be mapped then.
+			modelParams.SetUseMmap(false)
+		}
+		loadPath = plaintext.Path
+		cmd.logger.Infof("Decrypted %s (in memory: %v)", path, plaintext.Anonymous)
+	}
+
+	// llama.cpp only reports a fraction; derive bytes from the file size.
+	if fi, err := os.Stat(loadPath); err == nil {
+		bytesTotal = fi.Size()
+	}
+
+	cmd.logger.Debugf("Do: modelParams: %+v", modelParams)
+
+	var models []*llamacppbindings.Model
+	nativeLogger := cmd.logger.With("module", "llama.cpp", "model", path)
+	for _, gpu := range gpus {
+		var model *llamacppbindings.Model
+		if len(gpus) > 1 {
+			modelParams.SetMainGpu(gpu)
+		}
+		llamacppbindings.WithNativeLogger(nativeLogger, func() {
+			model, err = llamacppbindings.LoadModelFromFileContext(ctx, loadPath, modelParams)
+		})
+		if err != nil {
+			for _, m := range models {
+				m.Free()
+			}
+			if errors.Is(err, llamacppbindings.ErrLoadAborted) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", ErrModelLoadFailed, err)
+		}
+		models = append(models, model)
+		loaded++
+	}
+	model := models[0]
+	ctxSize, warning := cmd.ctxSize(model, options, overrides, gpus)
+	if warning != nil {
+		cmd.logger.Warnf("%s: %s", path, warning.Message)
+		warnings = append(warnings, *warning)
+	}
+	report(modelmanagement.LoadStageReady, 1)
 
 	modelData := &ModelData{
 		ModelParams: modelParams,
 		Model:       model,
+		CtxSize:     ctxSize,
+		KvCacheType: overrides.KvCacheType,
+		Batching:    overrideBatching(overrides),
+
+		Quantization: quantization(model.Info().Desc),
 	}
 
+	if len(models) > 1 {
+		modelData.Replicas = models
+		cmd.logger.Infof("Loaded %s on GPUs %v", path, gpus)
+	}
 	cmd.logger.Debugf("Do: model loaded, info: %+v", model.Info())
 	return modelData, nil
 }
+
+// ctxSize returns the context size of a loaded model, clamped to
+// MaxCtxSize, to what the model was trained for and to the free memory, and
+// the warning to report if it was reduced. It is 0 for the engine default
+// if that needs no clamping.
+func (cmd *loadModelCmd) ctxSize(model *llamacppbindings.Model, options LoadModelOptions, overrides modelmanagement.LoadOverrides, gpus []int) (int, *modelmanagement.LoadWarning) {
+	requested := overrides.CtxSize
+	if requested == 0 {
+		requested = cmd.defaults.ctxSize
+	}
+	kvCacheType := overrides.KvCacheType
+	if kvCacheType == "" {
+		kvCacheType = cmd.defaults.kvCacheType
+	}
+	info := model.Info()
+	free := kvFreeMemory(llamacppbindings.Devices(), options.NGpuLayers, gpus)
+	ctxSize, warning := clampCtxSize(requested,
+		ctxLimit{"max_ctx_size", options.MaxCtxSize},
+		ctxLimit{"n_ctx_train", info.NCtxTrain * max(cmd.defaults.nParallel, 1)},
+		ctxLimit{"memory", memoryCtxSize(free, kvBytesPerCell(info, kvCacheType))})
+	if warning == nil {
+		return overrides.CtxSize, nil
+	}
+	return ctxSize, warning
+}
+
+// quantization returns the file type in a model description such as "qwen2
+// 1.5B Q4_K - Medium", which follows the architecture and the size.
+func quantization(desc string) string {
+	fields := strings.SplitN(desc, " ", 3)
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[2]
+}
@@ -0,0 +1,57 @@
+package llmservice
+
+import (
+	"context"
+	"time"
+
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+)
+
+// Inference backends.
+const (
+	// BackendLlama runs models with llama.cpp.
+	BackendLlama = "llama"
+	// BackendMock generates deterministic synthetic output without loading
+	// anything, see inferenceengine.MockEngine. Any model path "loads".
+	BackendMock = "mock"
+)
+
+// mockLoadSteps is how many loading progress reports a mock load makes.
+const mockLoadSteps = 4
+
+// newMockLoadModelFunc returns a load function that reports progress like a
+// real load over about loadTime and returns a model without llama.cpp state.
+func newMockLoadModelFunc(loadTime time.Duration, logger logging.SprintfLogger) modelmanagement.LoadModelFunc[*ModelData] {
+	logger = logger.With("module", "mockLoadModel")
+	return func(ctx context.Context, path string, overrides modelmanagement.LoadOverrides, progress modelmanagement.LoadProgressFunc) (*ModelData, error) {
+		logger.Debugf("loading mock model %s", path)
+		if !progress(modelmanagement.LoadProgress{Stage: modelmanagement.LoadStageReading}) {
+			return nil, llamacppbindings.ErrLoadAborted
+		}
+		for i := 1; i <= mockLoadSteps; i++ {
+			select {
+			case <-time.After(loadTime / mockLoadSteps):
+			case <-ctx.Done():
+				return nil, llamacppbindings.ErrLoadAborted
+			}
+			fraction := float32(i) / mockLoadSteps
+			if !progress(modelmanagement.LoadProgress{Stage: modelmanagement.LoadStageLoading, Fraction: fraction}) {
+				return nil, llamacppbindings.ErrLoadAborted
+			}
+		}
+		progress(modelmanagement.LoadProgress{Stage: modelmanagement.LoadStageReady, Fraction: 1})
+		return &ModelData{
+			CtxSize:     overrides.CtxSize,
+			KvCacheType: overrides.KvCacheType,
+			Batching:    overrideBatching(overrides),
+		}, nil
+	}
+}
+
+// mockModelInfo describes a model loaded by the mock backend, which has no
+// vocab and so no special tokens.
+var mockModelInfo = ModelInfo{
+	ModelInfo: llamacppbindings.ModelInfo{Desc: "mock synthetic generator", HasDecoder: true, RopeType: llamacppbindings.RopeNone},
+}
@@ -0,0 +1,147 @@
+package llmservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/metrics"
+)
+
+// Moderator scores generated text against moderation categories.
+type Moderator interface {
+	// Moderate returns the probability of each category for text.
+	Moderate(ctx context.Context, text string) (map[string]float32, error)
+}
+
+// ModerationPolicy decides which categories are flagged and which block a
+// completion.
+type ModerationPolicy struct {
+	// Threshold is the probability from which a category is flagged; 0
+	// means 0.5.
+	Threshold float32
+	// Ignore lists categories that are never flagged, such as a "safe"
+	// label of the classifier.
+	Ignore []string
+	// Block lists the flagged categories that reject the completion with
+	// a *RejectedError; "*" blocks on any flag. Other flags only annotate.
+	Block []string
+}
+
+// ModerationOptions configures the built-in moderation hook, which scores
+// every completion with a classifier model.
+type ModerationOptions struct {
+	// Model is the path of a loaded classification model whose labels are
+	// the categories; empty disables moderation.
+	Model  string
+	Policy ModerationPolicy
+}
+
+// Annotations set by the moderation hook.
+const (
+	// AnnotationModerationFlagged lists the flagged categories, comma
+	// separated, or is empty.
+	AnnotationModerationFlagged = "moderation.flagged"
+	// AnnotationModerationScorePrefix is followed by a category name and
+	// holds its probability.
+	AnnotationModerationScorePrefix = "moderation.score."
+)
+
+var moderationFlags = metrics.NewCounter("llamacpp_moderation_flags_total",
+	"Completions flagged by output moderation, by category and action (annotate or block).",
+	"category", "action")
+
+// NewModerationHook returns a hook that scores each completion with m,
+// annotates the result with the scores and flagged categories, and rejects
+// it if a blocking category is flagged. Streamed text has been sent by then,
+// so only the final response of a stream reports the rejection.
+func NewModerationHook(m Moderator, policy ModerationPolicy) Hook {
+	if policy.Threshold <= 0 {
+		policy.Threshold = 0.5
+	}
+	return &moderationHook{moderator: m, policy: policy}
+}
+
+type moderationHook struct {
+	moderator Moderator
+	policy    ModerationPolicy
+}
+
+func (h *moderationHook) BeforePredict(ctx context.Context, req *HookRequest) error {
+	return nil
+}
+
+func (h *moderationHook) AfterPredict(ctx context.Context, req *HookRequest, res *inferenceengine.Result) error {
+	if res.Text == "" {
+		res.Annotations[AnnotationModerationFlagged] = ""
+		return nil
+	}
+	scores, err := h.moderator.Moderate(ctx, res.Text)
+	if err != nil {
+		return fmt.Errorf("moderation: %w", err)
+	}
+	var flagged, blocking []string
+	for category, p := range scores {
+		res.Annotations[AnnotationModerationScorePrefix+category] = fmt.Sprintf("%.3f", p)
+		if p < h.policy.Threshold || slices.Contains(h.policy.Ignore, category) {
+			continue
+		}
+		flagged = append(flagged, category)
+		if slices.Contains(h.policy.Block, "*") || slices.Contains(h.policy.Block, category) {
+			blocking = append(blocking, category)
+		}
+	}
+	slices.Sort(flagged)
+	slices.Sort(blocking)
+	res.Annotations[AnnotationModerationFlagged] = strings.Join(flagged, ",")
+	for _, category := range flagged {
+		action := "annotate"
+		if slices.Contains(blocking, category) {
+			action = "block"
+		}
+		moderationFlags.Inc(category, action)
+	}
+	if len(blocking) > 0 {
+		return &RejectedError{Reason: "output flagged as " + strings.Join(blocking, ", ")}
+	}
+	return nil
+}
+
+// classifierModerator moderates with a classification model of the service.
+// Its calls are not counted against the client's quota or audited.
+type classifierModerator struct {
+	service *Service
+	model   string
+}
+
+func (m *classifierModerator) Moderate(ctx context.Context, text string) (map[string]float32, error) {
+	mc, release, err := m.service.modelContext(ctx, m.model, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	res, err := m.service.predictionsManager.Classify(ctx, mc, []string{text}, inferenceengine.PredictArgs{})
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float32)
+	if len(res.Labels) > 0 {
+		for _, l := range res.Labels[0] {
+			scores[l.Label] = l.Probability
+		}
+	}
+	return scores, nil
+}
+
+// withModeration registers the built-in moderation hook if opts enables it.
+// Being registered after Options.Hooks, its AfterPredict runs first and sees
+// the generated text before they rewrite it.
+func (s *Service) withModeration(opts ModerationOptions) *Service {
+	if opts.Model != "" {
+		hook := NewModerationHook(&classifierModerator{service: s, model: opts.Model}, opts.Policy)
+		s.hooks = append(slices.Clip(s.hooks), hook)
+	}
+	return s
+}
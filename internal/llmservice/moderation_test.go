@@ -0,0 +1,55 @@
+package llmservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeModerator map[string]float32
+
+func (m fakeModerator) Moderate(ctx context.Context, text string) (map[string]float32, error) {
+	return m, nil
+}
+
+func moderate(t *testing.T, hook Hook, text string) (inferenceengine.Result, error) {
+	t.Helper()
+	res := inferenceengine.Result{Text: text, Annotations: map[string]string{}}
+	err := hook.AfterPredict(context.Background(), &HookRequest{}, &res)
+	return res, err
+}
+
+func TestModerationHook(t *testing.T) {
+	scores := fakeModerator{"safe": 0.9, "violence": 0.7, "hate": 0.6, "sexual": 0.1}
+
+	// Flags only annotate by default.
+	hook := NewModerationHook(scores, ModerationPolicy{Ignore: []string{"safe"}})
+	res, err := moderate(t, hook, "text")
+	require.NoError(t, err)
+	require.Equal(t, "hate,violence", res.Annotations[AnnotationModerationFlagged])
+	require.Equal(t, "0.700", res.Annotations[AnnotationModerationScorePrefix+"violence"])
+	require.Equal(t, "0.100", res.Annotations[AnnotationModerationScorePrefix+"sexual"])
+
+	hook = NewModerationHook(scores, ModerationPolicy{Threshold: 0.65, Ignore: []string{"safe"}})
+	res, err = moderate(t, hook, "text")
+	require.NoError(t, err)
+	require.Equal(t, "violence", res.Annotations[AnnotationModerationFlagged])
+
+	hook = NewModerationHook(scores, ModerationPolicy{Ignore: []string{"safe"}, Block: []string{"hate", "sexual"}})
+	_, err = moderate(t, hook, "text")
+	require.True(t, errors.Is(err, ErrRequestRejected))
+	require.EqualError(t, err, "request rejected: output flagged as hate")
+
+	hook = NewModerationHook(scores, ModerationPolicy{Ignore: []string{"safe"}, Block: []string{"*"}})
+	_, err = moderate(t, hook, "text")
+	require.EqualError(t, err, "request rejected: output flagged as hate, violence")
+
+	// Empty completions are not moderated.
+	res, err = moderate(t, hook, "")
+	require.NoError(t, err)
+	require.Empty(t, res.Annotations[AnnotationModerationFlagged])
+}
@@ -1,11 +1,20 @@
 package llmservice
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/hypernetix/llamacpp_server/internal/acl"
+	"github.com/hypernetix/llamacpp_server/internal/audit"
+	llamacppbindings "github.com/hypernetix/llamacpp_server/internal/bindings"
 	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
 	"github.com/hypernetix/llamacpp_server/internal/logging"
 	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+	"github.com/hypernetix/llamacpp_server/internal/quota"
 )
 
 type PredictOptions struct {
@@ -15,77 +24,625 @@ type PredictOptions struct {
 	NThreadsBatch int
 	CtxSize       int
 	BatchSize     int
+	KvCacheType   string
+	MaxConcurrent int
+	MaxQueue      int
+	Batching      inferenceengine.Batching
+	SamplerCache  int
+	TenantWeights map[string]float64
+	SpecialTokens inferenceengine.SpecialTokens
+	// MaxTokensPolicy applies to requests whose prompt leaves less room
+	// than their max_tokens and that set no policy of their own.
+	MaxTokensPolicy inferenceengine.MaxTokensPolicy
+	// ReplicaPolicy spreads requests over the engines of models loaded
+	// with LoadModelOptions.ReplicaGpus.
+	ReplicaPolicy inferenceengine.ReplicaPolicy
 }
 
 type Options struct {
 	Model   LoadModelOptions
 	Predict PredictOptions
+
+	// AutoLoad makes Predict load a model that is not loaded yet instead of
+	// failing with modelmanagement.ErrModelNotFound.
+	AutoLoad bool
+
+	// Backend is BackendLlama (the default when empty) or BackendMock.
+	Backend string
+	// MockTokenDelay is the time the mock backend spends per generated
+	// token; loading a model takes 100 times as long.
+	MockTokenDelay time.Duration
+
+	// Cache configures the completion cache, which is off by default.
+	Cache CacheOptions
+
+	// Audit, if set, receives a record of every Predict, Score, Embed and
+	// Classify call.
+	Audit *audit.Logger
+
+	// Quota, if set, enforces token budgets per client key on Predict,
+	// Score, Embed and Classify. Completions replayed from the cache are
+	// free.
+	Quota *quota.Tracker
+
+	// ACL, if set, restricts which client keys may use which models. The
+	// transports enforce it through CheckAccess.
+	ACL *acl.List
+
+	// Hooks pre- and post-process every Predict call.
+	Hooks []Hook
+
+	// Moderation, if its Model is set, flags or blocks completions with a
+	// classifier model, after Hooks.
+	Moderation ModerationOptions
+
+	// HealthCheck configures the periodic probe of loaded models.
+	HealthCheck HealthCheckOptions
+
+	// DefaultModel is the model of requests that name none; if empty they
+	// fail with modelmanagement.ErrModelNotFound. Aliases maps other names
+	// to model paths, so that all the names of a path share its loaded
+	// instance. See ResolveModel.
+	DefaultModel string
+	Aliases      map[string]string
+
+	// Schedule loads and unloads models at set times of day, see
+	// modelmanagement.Scheduler.
+	Schedule []modelmanagement.ScheduleEntry
 }
 
 type Service struct {
-	modelManager       modelmanagement.ModelManager
+	modelManager       modelmanagement.ModelManager[*ModelData]
 	predictionsManager inferenceengine.PredictionsManager
+	autoLoad           bool
+	cache              *completionCache // nil if disabled
+	audit              *audit.Logger    // nil if disabled
+	quota              *quota.Tracker   // nil if disabled
+	acl                *acl.List        // nil if disabled
+	hooks              []Hook
+	health             *healthChecker             // nil if disabled
+	scheduler          *modelmanagement.Scheduler // nil if no schedule
+	defaultModel       string
+	aliases            map[string]string
 	logger             logging.SprintfLogger
 }
 
 func NewService(opts Options, logger logging.SprintfLogger) *Service {
-	loadModelFunc := newLoadModelFunc(opts.Model, logger)
-	modelMgr := modelmanagement.NewModelManager(loadModelFunc, logger)
-
 	nParallel := opts.Predict.NParallel
 	if nParallel <= 0 {
 		nParallel = 1
 	}
+	var cache *completionCache
+	if opts.Cache.MaxEntries > 0 {
+		cache = newCompletionCache(opts.Cache)
+		logger.Infof("completion cache: %d entries, %d bytes, ttl %s",
+			opts.Cache.MaxEntries, opts.Cache.MaxBytes, opts.Cache.TTL)
+	}
 
-	predictionsMgr := inferenceengine.New(inferenceengine.Options{
-		NParallel:     nParallel,
-		CtxSize:       opts.Predict.CtxSize,
-		BatchSize:     opts.Predict.BatchSize,
-		NThreads:      opts.Predict.NThreads,
-		NThreadsBatch: opts.Predict.NThreadsBatch,
-		FlashAttn:     opts.Predict.FlashAttn,
+	if opts.Backend == BackendMock {
+		logger.Infof("mock backend: synthetic output, %s per token", opts.MockTokenDelay)
+		return (&Service{
+			modelManager: modelmanagement.NewModelManager(newMockLoadModelFunc(100*opts.MockTokenDelay, logger), logger),
+			predictionsManager: inferenceengine.NewMock(inferenceengine.MockOptions{
+				NParallel:  nParallel,
+				MaxQueue:   opts.Predict.MaxQueue,
+				TokenDelay: opts.MockTokenDelay,
+			}, logger),
+			autoLoad:     opts.AutoLoad,
+			cache:        cache,
+			audit:        opts.Audit,
+			quota:        opts.Quota,
+			acl:          opts.ACL,
+			hooks:        opts.Hooks,
+			defaultModel: opts.DefaultModel,
+			aliases:      opts.Aliases,
+			logger:       logger.With("module", "llmservice.Service"),
+		}).withModeration(opts.Moderation).withHealthCheck(opts.HealthCheck).withSchedule(opts.Schedule)
+	}
+
+	loadModelFunc := newLoadModelFunc(opts.Model, contextDefaults{
+		ctxSize:     opts.Predict.CtxSize,
+		nParallel:   nParallel,
+		kvCacheType: opts.Predict.KvCacheType,
 	}, logger)
+	modelMgr := modelmanagement.NewModelManager(loadModelFunc, logger)
+
+	engineOpts := inferenceengine.Options{
+		NParallel:        nParallel,
+		CtxSize:          opts.Predict.CtxSize,
+		BatchSize:        opts.Predict.BatchSize,
+		NThreads:         opts.Predict.NThreads,
+		NThreadsBatch:    opts.Predict.NThreadsBatch,
+		FlashAttn:        opts.Predict.FlashAttn,
+		KvCacheType:      opts.Predict.KvCacheType,
+		MaxConcurrent:    opts.Predict.MaxConcurrent,
+		MaxQueue:         opts.Predict.MaxQueue,
+		Batching:         opts.Predict.Batching,
+		SamplerCacheSize: opts.Predict.SamplerCache,
+		TenantWeights:    opts.Predict.TenantWeights,
+		SpecialTokens:    opts.Predict.SpecialTokens,
+		MaxTokensPolicy:  opts.Predict.MaxTokensPolicy,
+	}
+	var predictionsMgr inferenceengine.PredictionsManager
+	if gpus := opts.Model.ReplicaGpus; len(gpus) > 1 {
+		// One engine, with its own context and slots, per model replica.
+		engines := make([]inferenceengine.PredictionsManager, len(gpus))
+		for i, gpu := range gpus {
+			engines[i] = inferenceengine.New(engineOpts, logger.With("gpu", gpu))
+		}
+		predictionsMgr = inferenceengine.NewReplicas(engines, opts.Predict.ReplicaPolicy)
+		logger.Infof("model replicas on GPUs %v, %s", gpus, opts.Predict.ReplicaPolicy)
+	} else {
+		predictionsMgr = inferenceengine.New(engineOpts, logger)
+	}
 	logger.Infof("continuous batching enabled (slots=%d)", nParallel)
 
-	return &Service{
+	return (&Service{
 		modelManager:       modelMgr,
 		predictionsManager: predictionsMgr,
+		autoLoad:           opts.AutoLoad,
+		cache:              cache,
+		audit:              opts.Audit,
+		quota:              opts.Quota,
+		acl:                opts.ACL,
+		hooks:              opts.Hooks,
+		defaultModel:       opts.DefaultModel,
+		aliases:            opts.Aliases,
 		logger:             logger.With("module", "llmservice.Service"),
+	}).withModeration(opts.Moderation).withHealthCheck(opts.HealthCheck).withSchedule(opts.Schedule)
+}
+
+func (s *Service) withSchedule(entries []modelmanagement.ScheduleEntry) *Service {
+	if len(entries) > 0 {
+		load := func(ctx context.Context, path string) error {
+			return s.LoadModel(ctx, path, modelmanagement.LoadOverrides{}, nil)
+		}
+		s.scheduler = modelmanagement.NewScheduler(entries, load, s.UnloadModel, s.logger)
+		s.logger.Infof("model schedule: %d entries", len(entries))
 	}
+	return s
 }
 
-func (s *Service) LoadModel(path string, onProgress func(float32)) error {
+func (s *Service) LoadModel(ctx context.Context, path string, overrides modelmanagement.LoadOverrides, onProgress modelmanagement.LoadModelProgressFunc) error {
+	path = s.ResolveModel(path)
 	s.logger.Debugf("LoadModel: %s", path)
-	model, err := s.modelManager.LoadModel(path, onProgress)
-	if err != nil {
+	if err := ValidateOverrides(overrides); err != nil {
 		return err
 	}
-	md, ok := model.(*ModelData)
-	if !ok {
-		return fmt.Errorf("invalid model type")
+	if _, err := s.modelManager.LoadModel(ctx, path, overrides, onProgress); err != nil {
+		return err
+	}
+	md, release, err := s.modelManager.Acquire(path)
+	if err != nil {
+		return nil // unloaded again meanwhile
+	}
+	defer release()
+	if md.Model == nil {
+		return nil // mock backend
 	}
 	s.logger.Debugf("LoadModel: loaded, params: %+v, info: %+v",
 		md.ModelParams, md.Model.Info())
 	return nil
 }
 
-func (s *Service) Predict(modelPath string, prompt string, args inferenceengine.PredictArgs, stream inferenceengine.StreamFunc) (string, error) {
-	model, err := s.modelManager.GetModel(modelPath)
+// UnloadModel frees a model once the calls using it have finished, or
+// cancels its load. Calls that start meanwhile no longer find it, or load it
+// again with auto-load.
+func (s *Service) UnloadModel(path string) error {
+	path = s.ResolveModel(path)
+	s.logger.Debugf("UnloadModel: %s", path)
+	return s.modelManager.UnloadModel(path, func(md *ModelData) {
+		s.predictionsManager.Release(md.modelContext())
+	})
+}
+
+// ErrInvalidLoadOption is returned for LoadModel overrides out of range.
+var ErrInvalidLoadOption = errors.New("invalid load option")
+
+// ValidateKvCacheType checks a KV cache type name; empty means the default.
+func ValidateKvCacheType(kvCacheType string) error {
+	switch strings.ToLower(kvCacheType) {
+	case "", "f16", "q8_0", "q4_0":
+		return nil
+	default:
+		return fmt.Errorf("%w: kv cache type %q (want f16, q8_0 or q4_0)", ErrInvalidLoadOption, kvCacheType)
+	}
+}
+
+// ValidateOverrides checks LoadModel overrides, failing with
+// ErrInvalidLoadOption for values out of range.
+func ValidateOverrides(o modelmanagement.LoadOverrides) error {
+	if err := ValidateKvCacheType(o.KvCacheType); err != nil {
+		return err
+	}
+	if o.CtxSize < 0 {
+		return fmt.Errorf("%w: negative context size %d", ErrInvalidLoadOption, o.CtxSize)
+	}
+	if o.MaxBatchTokens < 0 || o.MaxBatchSeqs < 0 || o.BatchWait < 0 {
+		return fmt.Errorf("%w: negative batching limit", ErrInvalidLoadOption)
+	}
+	if o.NGpuLayers != nil && *o.NGpuLayers < -1 {
+		return fmt.Errorf("%w: gpu layers %d", ErrInvalidLoadOption, *o.NGpuLayers)
+	}
+	return nil
+}
+
+// CancelLoad aborts a model load that is in progress.
+func (s *Service) CancelLoad(path string) error {
+	path = s.ResolveModel(path)
+	s.logger.Debugf("CancelLoad: %s", path)
+	return s.modelManager.CancelLoad(path)
+}
+
+// Predict runs a prediction until it completes or ctx is done. With
+// auto-load enabled a model that is not loaded yet is loaded first, reporting
+// progress to onLoad (which may be nil). With the completion cache enabled,
+// a reproducible prediction made before is replayed from the cache.
+func (s *Service) Predict(ctx context.Context, modelPath string, prompt string, args inferenceengine.PredictArgs, stream inferenceengine.StreamFunc, onLoad modelmanagement.LoadModelProgressFunc) (inferenceengine.Result, error) {
+	modelPath = s.ResolveModel(modelPath)
+	start := time.Now()
+	req := &HookRequest{Model: modelPath, Prompt: prompt, Args: args}
+	var res inferenceengine.Result
+	err := s.beforePredict(ctx, req)
+	if err == nil {
+		res, err = s.predict(ctx, start, modelPath, req.Prompt, req.Args, s.filterStream(ctx, req, stream), onLoad)
+	}
+	if err == nil {
+		err = s.afterPredict(ctx, req, &res)
+	}
+	if !res.Cached {
+		s.quota.Add(args.ClientKey, res.PromptTokens+res.CompletionTokens)
+	}
+	if s.audit != nil {
+		rec := newAuditRecord("predict", modelPath, args, start, err)
+		rec.PromptTokens = res.PromptTokens
+		rec.CompletionTokens = res.CompletionTokens
+		rec.QueueMs = durationMs(res.Timings.QueueTime)
+		rec.Cached = res.Cached
+		if err == nil {
+			rec.FinishReason = res.FinishReason.String()
+		}
+		s.audit.Log(rec, prompt)
+	}
+	return res, err
+}
+
+func (s *Service) predict(ctx context.Context, start time.Time, modelPath string, prompt string, args inferenceengine.PredictArgs, stream inferenceengine.StreamFunc, onLoad modelmanagement.LoadModelProgressFunc) (inferenceengine.Result, error) {
+	if err := args.Validate(); err != nil {
+		return inferenceengine.Result{}, err
+	}
+	if err := s.quota.Check(args.ClientKey); err != nil {
+		return inferenceengine.Result{}, err
+	}
+	mc, release, err := s.modelContext(ctx, modelPath, onLoad)
+	if err != nil {
+		return inferenceengine.Result{}, err
+	}
+	defer release()
+	if s.cache == nil || !cacheable(args) {
+		return s.predictionsManager.Predict(ctx, mc, prompt, args, stream)
+	}
+
+	key := completionKey(mc, modelPath, prompt, args)
+	if entry, ok := s.cache.get(key); ok {
+		completionCacheHits.Inc(mc.Name, mc.Quantization)
+		s.logger.Debugf("Predict: served from the completion cache (request=%s)", args.RequestID)
+		return entry.replay(args, stream, start)
+	}
+	completionCacheMisses.Inc(mc.Name, mc.Quantization)
+	var events []streamEvent
+	stream = recordStream(&args, stream, &events)
+	res, err := s.predictionsManager.Predict(ctx, mc, prompt, args, stream)
+	if err == nil {
+		s.cache.put(key, res, events)
+	}
+	return res, err
+}
+
+// Score computes the log-likelihood and perplexity of text under the model,
+// auto-loading it like Predict.
+func (s *Service) Score(ctx context.Context, modelPath string, text string, args inferenceengine.PredictArgs) (inferenceengine.ScoreResult, error) {
+	modelPath = s.ResolveModel(modelPath)
+	start := time.Now()
+	res, err := s.score(ctx, modelPath, text, args)
+	if res.Tokens > 0 {
+		s.quota.Add(args.ClientKey, res.Tokens+1)
+	}
+	if s.audit != nil {
+		rec := newAuditRecord("score", modelPath, args, start, err)
+		if res.Tokens > 0 {
+			rec.PromptTokens = res.Tokens + 1 // the first token is not scored
+		}
+		s.audit.Log(rec, text)
+	}
+	return res, err
+}
+
+func (s *Service) score(ctx context.Context, modelPath string, text string, args inferenceengine.PredictArgs) (inferenceengine.ScoreResult, error) {
+	if err := s.quota.Check(args.ClientKey); err != nil {
+		return inferenceengine.ScoreResult{}, err
+	}
+	mc, release, err := s.modelContext(ctx, modelPath, nil)
+	if err != nil {
+		return inferenceengine.ScoreResult{}, err
+	}
+	defer release()
+	return s.predictionsManager.Score(ctx, mc, text, args)
+}
+
+// Embed computes L2-normalized embeddings of texts, auto-loading the model
+// like Predict.
+func (s *Service) Embed(ctx context.Context, modelPath string, texts []string, args inferenceengine.PredictArgs) (inferenceengine.EmbedResult, error) {
+	modelPath = s.ResolveModel(modelPath)
+	start := time.Now()
+	res, err := s.embed(ctx, modelPath, texts, args)
+	s.quota.Add(args.ClientKey, res.PromptTokens)
+	if s.audit != nil {
+		rec := newAuditRecord("embed", modelPath, args, start, err)
+		rec.Params.Texts = len(texts)
+		rec.PromptTokens = res.PromptTokens
+		s.audit.Log(rec, strings.Join(texts, "\x00"))
+	}
+	return res, err
+}
+
+func (s *Service) embed(ctx context.Context, modelPath string, texts []string, args inferenceengine.PredictArgs) (inferenceengine.EmbedResult, error) {
+	if err := s.quota.Check(args.ClientKey); err != nil {
+		return inferenceengine.EmbedResult{}, err
+	}
+	mc, release, err := s.modelContext(ctx, modelPath, nil)
+	if err != nil {
+		return inferenceengine.EmbedResult{}, err
+	}
+	defer release()
+	return s.predictionsManager.Embed(ctx, mc, texts, args)
+}
+
+// Classify returns the label scores of a classification model (reranker,
+// reward or judge) for each text, auto-loading the model like Predict.
+func (s *Service) Classify(ctx context.Context, modelPath string, texts []string, args inferenceengine.PredictArgs) (inferenceengine.ClassifyResult, error) {
+	modelPath = s.ResolveModel(modelPath)
+	start := time.Now()
+	res, err := s.classify(ctx, modelPath, texts, args)
+	s.quota.Add(args.ClientKey, res.PromptTokens)
+	if s.audit != nil {
+		rec := newAuditRecord("classify", modelPath, args, start, err)
+		rec.Params.Texts = len(texts)
+		rec.PromptTokens = res.PromptTokens
+		s.audit.Log(rec, strings.Join(texts, "\x00"))
+	}
+	return res, err
+}
+
+func (s *Service) classify(ctx context.Context, modelPath string, texts []string, args inferenceengine.PredictArgs) (inferenceengine.ClassifyResult, error) {
+	if err := s.quota.Check(args.ClientKey); err != nil {
+		return inferenceengine.ClassifyResult{}, err
+	}
+	mc, release, err := s.modelContext(ctx, modelPath, nil)
+	if err != nil {
+		return inferenceengine.ClassifyResult{}, err
+	}
+	defer release()
+	return s.predictionsManager.Classify(ctx, mc, texts, args)
+}
+
+// SimilarityResult holds the cosine similarity of the query to each
+// candidate, in order.
+type SimilarityResult struct {
+	Scores       []float32
+	PromptTokens int
+	TotalTime    time.Duration
+}
+
+// Similarity embeds query and candidates in one call and scores each
+// candidate by cosine similarity to the query.
+func (s *Service) Similarity(ctx context.Context, modelPath string, query string, candidates []string, args inferenceengine.PredictArgs) (SimilarityResult, error) {
+	if len(candidates) == 0 {
+		return SimilarityResult{}, fmt.Errorf("%w: no candidates", inferenceengine.ErrNothingToEmbed)
+	}
+	texts := append([]string{query}, candidates...)
+	res, err := s.Embed(ctx, modelPath, texts, args)
+	if err != nil {
+		return SimilarityResult{}, err
+	}
+	scores := make([]float32, len(candidates))
+	for i, embd := range res.Embeddings[1:] {
+		scores[i] = dot(res.Embeddings[0], embd) // embeddings are normalized
+	}
+	return SimilarityResult{
+		Scores:       scores,
+		PromptTokens: res.PromptTokens,
+		TotalTime:    res.TotalTime,
+	}, nil
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// CheckQuota returns a *quota.ExceededError if the token budget of the
+// client with the given key is used up, so a transport can reject a request
+// before it starts streaming.
+func (s *Service) CheckQuota(clientKey string) error {
+	return s.quota.Check(clientKey)
+}
+
+// Bench measures prefill and generation throughput of a model.
+func (s *Service) Bench(ctx context.Context, modelPath string, opts inferenceengine.BenchOptions) ([]inferenceengine.BenchResult, error) {
+	modelPath = s.ResolveModel(modelPath)
+	mc, release, err := s.modelContext(ctx, modelPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.predictionsManager.Bench(ctx, mc, opts)
+}
+
+// modelContext returns the model for a call, which holds it until it calls
+// release.
+func (s *Service) modelContext(ctx context.Context, modelPath string, onLoad modelmanagement.LoadModelProgressFunc) (inferenceengine.ModelContext, func(), error) {
+	if s.autoLoad {
+		if _, err := s.modelManager.GetOrLoad(ctx, modelPath, onLoad); err != nil {
+			return inferenceengine.ModelContext{}, nil, err
+		}
+	}
+	return s.acquireModel(modelPath)
+}
+
+// acquireModel returns the context of a loaded model without loading it,
+// and a func releasing the model for UnloadModel.
+func (s *Service) acquireModel(modelPath string) (inferenceengine.ModelContext, func(), error) {
+	md, release, err := s.modelManager.Acquire(modelPath)
 	if err != nil {
-		return "", err
+		return inferenceengine.ModelContext{}, nil, err
+	}
+	mc := md.modelContext()
+	mc.Name = s.modelName(modelPath)
+	return mc, release, nil
+}
+
+// modelName returns the name that labels the metric series of a model: its
+// first alias in sort order, or the base name of its path.
+func (s *Service) modelName(path string) string {
+	name := ""
+	for alias, target := range s.aliases {
+		if target == path && (name == "" || alias < name) {
+			name = alias
+		}
 	}
-	md, ok := model.(*ModelData)
-	if !ok {
-		return "", fmt.Errorf("invalid model type")
+	if name == "" {
+		name = filepath.Base(path)
 	}
-	return s.predictionsManager.Predict(md.Model, prompt, args, stream)
+	return name
+}
+
+func (md *ModelData) modelContext() inferenceengine.ModelContext {
+	return inferenceengine.ModelContext{
+		Model:       md.Model,
+		CtxSize:     md.CtxSize,
+		KvCacheType: md.KvCacheType,
+		Batching:    md.Batching,
+		Replicas:    md.Replicas,
+
+		Quantization: md.Quantization,
+	}
+}
+
+// LoadLog returns the llama.cpp output captured while the model loaded.
+func (s *Service) LoadLog(path string) ([]string, error) {
+	path = s.ResolveModel(path)
+	md, err := s.modelManager.GetModel(path)
+	if err != nil {
+		return nil, err
+	}
+	if md.Model == nil {
+		return nil, nil // mock backend
+	}
+	return md.Model.LoadLog(), nil
+}
+
+// SpecialToken is a special token of a model's vocab and its rendered text.
+type SpecialToken struct {
+	ID    int
+	Piece string
+}
+
+// ModelInfo describes a loaded model for clients that build raw prompts.
+type ModelInfo struct {
+	llamacppbindings.ModelInfo
+	AddBOS bool
+	// Special tokens; nil if the vocab has no such token.
+	Bos, Eos, Eot *SpecialToken
+}
+
+// ModelInfo returns the description and special tokens of a loaded model.
+func (s *Service) ModelInfo(path string) (ModelInfo, error) {
+	path = s.ResolveModel(path)
+	md, err := s.modelManager.GetModel(path)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	if md.Model == nil {
+		return mockModelInfo, nil
+	}
+	vocab := md.Model.Vocab()
+	info := ModelInfo{
+		ModelInfo: md.Model.Info(),
+		AddBOS:    vocab.AddBOS(),
+	}
+	for _, t := range []struct {
+		id  int
+		dst **SpecialToken
+	}{
+		{vocab.Bos(), &info.Bos},
+		{vocab.Eos(), &info.Eos},
+		{vocab.Eot(), &info.Eot},
+	} {
+		if t.id < 0 {
+			continue
+		}
+		piece, err := vocab.TokenToPiece(t.id)
+		if err != nil {
+			return ModelInfo{}, fmt.Errorf("token %d: %w", t.id, err)
+		}
+		*t.dst = &SpecialToken{ID: t.id, Piece: piece}
+	}
+	return info, nil
+}
+
+// WatchEvents subscribes to model lifecycle events; see ModelManager.Watch.
+func (s *Service) WatchEvents() (<-chan modelmanagement.Event, func()) {
+	return s.modelManager.Watch()
 }
 
 func (s *Service) ListModels() []string {
 	return s.modelManager.ListModels()
 }
 
+// ListModelsFor returns the loaded models the client with the given key may
+// use.
+func (s *Service) ListModelsFor(clientKey string) []string {
+	return s.acl.Filter(clientKey, s.ListModels())
+}
+
+// ResolveModel returns the path of the model a request names: the default
+// model for an empty name, the target of an alias, or name itself.
+func (s *Service) ResolveModel(name string) string {
+	if name == "" {
+		name = s.defaultModel
+	}
+	if path, ok := s.aliases[name]; ok {
+		return path
+	}
+	return name
+}
+
+// CheckAccess returns an *acl.DeniedError if the client with the given key
+// may not load or use the model.
+func (s *Service) CheckAccess(clientKey, modelPath string) error {
+	modelPath = s.ResolveModel(modelPath)
+	if modelPath == "" {
+		return nil // fails later as not found
+	}
+	return s.acl.Check(clientKey, modelPath)
+}
+
+// Stats returns the inference engine's slot and queue utilization.
+func (s *Service) Stats() inferenceengine.Stats {
+	return s.predictionsManager.Stats()
+}
+
 func (s *Service) Stop() {
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+	if s.health != nil {
+		s.health.stop()
+	}
 	s.predictionsManager.Stop()
 	s.modelManager.Stop()
 }
@@ -0,0 +1,48 @@
+package llmservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/hypernetix/llamacpp_server/internal/modelmanagement"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveModel(t *testing.T) {
+	s := &Service{}
+	require.Equal(t, "", s.ResolveModel(""))
+	require.Equal(t, "/models/a.gguf", s.ResolveModel("/models/a.gguf"))
+
+	s = &Service{
+		defaultModel: "chat",
+		aliases:      map[string]string{"chat": "/models/a.gguf", "assistant": "/models/a.gguf"},
+	}
+	require.Equal(t, "/models/a.gguf", s.ResolveModel(""))
+	require.Equal(t, "/models/a.gguf", s.ResolveModel("chat"))
+	require.Equal(t, "/models/a.gguf", s.ResolveModel("assistant"))
+	require.Equal(t, "/models/b.gguf", s.ResolveModel("/models/b.gguf"))
+
+	// Metric series are labeled with the first alias, or the file name.
+	require.Equal(t, "assistant", s.modelName("/models/a.gguf"))
+	require.Equal(t, "b.gguf", s.modelName("/models/b.gguf"))
+	require.Equal(t, "Q4_K - Medium", quantization("qwen2 1.5B Q4_K - Medium"))
+}
+
+func TestUnloadModel(t *testing.T) {
+	s := NewService(Options{Backend: BackendMock}, logging.NewSprintfLogger())
+	defer s.Stop()
+	ctx := context.Background()
+
+	require.NoError(t, s.LoadModel(ctx, "/models/a.gguf", modelmanagement.LoadOverrides{}, nil))
+	_, err := s.Predict(ctx, "/models/a.gguf", "Hello", inferenceengine.PredictArgs{NPredict: 2}, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, s.UnloadModel("/models/a.gguf"))
+	require.Empty(t, s.ListModels())
+	_, err = s.Predict(ctx, "/models/a.gguf", "Hello", inferenceengine.PredictArgs{NPredict: 2}, nil, nil)
+	require.ErrorIs(t, err, modelmanagement.ErrModelNotFound)
+	require.ErrorIs(t, s.UnloadModel("/models/a.gguf"), modelmanagement.ErrModelNotFound)
+}
@@ -0,0 +1,398 @@
+// Package metrics implements the small set of Prometheus metric types the
+// server needs (counters, gauges and histograms with labels) and renders them
+// in the Prometheus text exposition format, or in the OpenMetrics format,
+// which also carries the exemplars of histograms.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefLatencyBuckets are histogram buckets, in seconds, suited to request and
+// token latencies.
+var DefLatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+type collector interface {
+	write(w io.Writer, openMetrics bool)
+}
+
+// Registry holds a set of metrics and renders them.
+type Registry struct {
+	mx         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default is the registry used by the New* constructors and Handler.
+var Default = NewRegistry()
+
+func (r *Registry) register(c collector) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WritePrometheus writes all metrics in the Prometheus text format.
+func (r *Registry) WritePrometheus(w io.Writer) {
+	r.write(w, false)
+}
+
+// WriteOpenMetrics writes all metrics in the OpenMetrics text format, with
+// the exemplars of histograms.
+func (r *Registry) WriteOpenMetrics(w io.Writer) {
+	r.write(w, true)
+	io.WriteString(w, "# EOF\n")
+}
+
+func (r *Registry) write(w io.Writer, openMetrics bool) {
+	r.mx.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mx.Unlock()
+	for _, c := range collectors {
+		c.write(w, openMetrics)
+	}
+}
+
+// Handler serves the Default registry, in the OpenMetrics format to
+// scrapers that accept it, such as Prometheus with exemplar storage.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+			Default.WriteOpenMetrics(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Default.WritePrometheus(w)
+	})
+}
+
+// Labels are the labels of an exemplar, e.g. {"request_id": "..."}.
+type Labels map[string]string
+
+// vec tracks one series per label value combination.
+type vec[T any] struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+
+	mx     sync.Mutex
+	series map[string]*T
+	labels map[string][]string
+	newT   func() *T
+}
+
+func newVec[T any](name, help, typ string, labelNames []string, newT func() *T) *vec[T] {
+	return &vec[T]{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		series:     make(map[string]*T),
+		labels:     make(map[string][]string),
+		newT:       newT,
+	}
+}
+
+func (v *vec[T]) get(labelValues []string) *T {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s: got %d label values, want %d", v.name, len(labelValues), len(v.labelNames)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newT()
+		v.series[key] = s
+		v.labels[key] = append([]string(nil), labelValues...)
+	}
+	return s
+}
+
+// Delete removes the series with the given label values, e.g. of a model
+// that was unloaded.
+func (v *vec[T]) Delete(labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	delete(v.series, key)
+	delete(v.labels, key)
+}
+
+// each calls fn for every series in a stable order.
+func (v *vec[T]) each(fn func(labels string, s *T)) {
+	v.mx.Lock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type entry struct {
+		labels string
+		s      *T
+	}
+	entries := make([]entry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, entry{labels: formatLabels(v.labelNames, v.labels[k]), s: v.series[k]})
+	}
+	v.mx.Unlock()
+	for _, e := range entries {
+		fn(e.labels, e.s)
+	}
+}
+
+func (v *vec[T]) writeHeader(w io.Writer, openMetrics bool) {
+	name, help := v.name, escapeHelp(v.help)
+	if openMetrics {
+		// OpenMetrics names counters without the _total of their samples.
+		if v.typ == "counter" {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		help = escapeLabel(v.help)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, v.typ)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	*vec[counterSeries]
+}
+
+type counterSeries struct {
+	mx    sync.Mutex
+	value float64
+}
+
+// NewCounter creates and registers a counter in the Default registry.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{newVec(name, help, "counter", labelNames, func() *counterSeries { return &counterSeries{} })}
+	Default.register(c)
+	return c
+}
+
+// Add increases the counter by delta (which must not be negative).
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	s := c.get(labelValues)
+	s.mx.Lock()
+	s.value += delta
+	s.mx.Unlock()
+}
+
+// Inc increases the counter by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) write(w io.Writer, openMetrics bool) {
+	c.writeHeader(w, openMetrics)
+	c.each(func(labels string, s *counterSeries) {
+		s.mx.Lock()
+		v := s.value
+		s.mx.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatFloat(v))
+	})
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	*vec[counterSeries]
+}
+
+// NewGauge creates and registers a gauge in the Default registry.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{newVec(name, help, "gauge", labelNames, func() *counterSeries { return &counterSeries{} })}
+	Default.register(g)
+	return g
+}
+
+// Set sets the gauge value.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	s := g.get(labelValues)
+	s.mx.Lock()
+	s.value = value
+	s.mx.Unlock()
+}
+
+// Add adds delta (which may be negative) to the gauge.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	s := g.get(labelValues)
+	s.mx.Lock()
+	s.value += delta
+	s.mx.Unlock()
+}
+
+func (g *Gauge) write(w io.Writer, openMetrics bool) {
+	g.writeHeader(w, openMetrics)
+	g.each(func(labels string, s *counterSeries) {
+		s.mx.Lock()
+		v := s.value
+		s.mx.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatFloat(v))
+	})
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	*vec[histogramSeries]
+	buckets []float64
+}
+
+type histogramSeries struct {
+	mx        sync.Mutex
+	counts    []uint64
+	count     uint64
+	sum       float64
+	exemplars []*exemplar // of each bucket and +Inf; nil until one is set
+}
+
+// exemplar is an observation kept with the labels that identify it.
+type exemplar struct {
+	labels Labels
+	value  float64
+	at     time.Time
+}
+
+// NewHistogram creates and registers a histogram in the Default registry.
+// buckets are upper bounds in increasing order; +Inf is implicit.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{
+		vec: newVec(name, help, "histogram", labelNames, func() *histogramSeries {
+			return &histogramSeries{counts: make([]uint64, len(b))}
+		}),
+		buckets: b,
+	}
+	Default.register(h)
+	return h
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.ObserveWithExemplar(value, nil, labelValues...)
+}
+
+// ObserveWithExemplar records one value and, unless exemplarLabels is nil,
+// keeps it as the exemplar of its bucket, so that a slow data point links
+// to the request behind it. Exemplars are only written in the OpenMetrics
+// format.
+func (h *Histogram) ObserveWithExemplar(value float64, exemplarLabels Labels, labelValues ...string) {
+	s := h.get(labelValues)
+	i := sort.SearchFloat64s(h.buckets, value)
+	var e *exemplar
+	if exemplarLabels != nil {
+		e = &exemplar{labels: exemplarLabels, value: value, at: time.Now()}
+	}
+	s.mx.Lock()
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+	if e != nil {
+		if s.exemplars == nil {
+			s.exemplars = make([]*exemplar, len(s.counts)+1)
+		}
+		s.exemplars[i] = e
+	}
+	s.mx.Unlock()
+}
+
+func (h *Histogram) write(w io.Writer, openMetrics bool) {
+	h.writeHeader(w, openMetrics)
+	h.each(func(labels string, s *histogramSeries) {
+		s.mx.Lock()
+		counts := append([]uint64(nil), s.counts...)
+		count, sum := s.count, s.sum
+		exemplars := append([]*exemplar(nil), s.exemplars...)
+		s.mx.Unlock()
+		if !openMetrics {
+			exemplars = nil
+		}
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d%s\n", h.name, withLabel(labels, "le", formatFloat(upper)), cumulative, formatExemplar(exemplars, i))
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d%s\n", h.name, withLabel(labels, "le", "+Inf"), count, formatExemplar(exemplars, len(h.buckets)))
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, count)
+	})
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = n + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatExemplar returns the exemplar suffix of bucket i's sample, or "" if
+// it has none.
+func formatExemplar(exemplars []*exemplar, i int) string {
+	if i >= len(exemplars) || exemplars[i] == nil {
+		return ""
+	}
+	e := exemplars[i]
+	names := make([]string, 0, len(e.labels))
+	for name := range e.labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, len(names))
+	for j, name := range names {
+		values[j] = e.labels[name]
+	}
+	labels := formatLabels(names, values)
+	if labels == "" {
+		labels = "{}"
+	}
+	at := float64(e.at.UnixMilli()) / 1000
+	return " # " + labels + " " + formatFloat(e.value) + " " + strconv.FormatFloat(at, 'f', 3, 64)
+}
+
+// withLabel appends name="value" to an already formatted label set.
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramWritesCumulativeBuckets(t *testing.T) {
+	reg := NewRegistry()
+	h := &Histogram{
+		vec: newVec("test_latency_seconds", "Test latency.", "histogram", []string{"model"}, func() *histogramSeries {
+			return &histogramSeries{counts: make([]uint64, 2)}
+		}),
+		buckets: []float64{0.1, 1},
+	}
+	reg.register(h)
+
+	h.Observe(0.05, "a")
+	h.Observe(0.5, "a")
+	h.Observe(5, "a")
+
+	var buf bytes.Buffer
+	reg.WritePrometheus(&buf)
+	out := buf.String()
+
+	require.Contains(t, out, "# TYPE test_latency_seconds histogram\n")
+	require.Contains(t, out, `test_latency_seconds_bucket{model="a",le="0.1"} 1`+"\n")
+	require.Contains(t, out, `test_latency_seconds_bucket{model="a",le="1"} 2`+"\n")
+	require.Contains(t, out, `test_latency_seconds_bucket{model="a",le="+Inf"} 3`+"\n")
+	require.Contains(t, out, `test_latency_seconds_sum{model="a"} 5.55`+"\n")
+	require.Contains(t, out, `test_latency_seconds_count{model="a"} 3`+"\n")
+}
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	require.Equal(t, `{path="a\"b\\c\nd"}`, formatLabels([]string{"path"}, []string{"a\"b\\c\nd"}))
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	c := &Counter{newVec("test_total", "Test.", "counter", []string{"a", "b"}, func() *counterSeries { return &counterSeries{} })}
+	require.Panics(t, func() { c.Inc("only-one") })
+}
+
+func TestOpenMetricsWritesExemplars(t *testing.T) {
+	reg := NewRegistry()
+	h := &Histogram{
+		vec: newVec("test_latency_seconds", "Test latency.", "histogram", []string{"model"}, func() *histogramSeries {
+			return &histogramSeries{counts: make([]uint64, 1)}
+		}),
+		buckets: []float64{1},
+	}
+	c := &Counter{newVec("test_requests_total", "Test.", "counter", nil, func() *counterSeries { return &counterSeries{} })}
+	reg.register(h)
+	reg.register(c)
+
+	h.ObserveWithExemplar(0.5, Labels{"request_id": "r1"}, "a")
+	h.Observe(2, "a")
+	c.Inc()
+
+	var buf bytes.Buffer
+	reg.WritePrometheus(&buf)
+	require.NotContains(t, buf.String(), "request_id")
+
+	buf.Reset()
+	reg.WriteOpenMetrics(&buf)
+	out := buf.String()
+	require.Regexp(t, `test_latency_seconds_bucket\{model="a",le="1"\} 1 # \{request_id="r1"\} 0.5 \d+\.\d{3}`+"\n", out)
+	require.Contains(t, out, `test_latency_seconds_bucket{model="a",le="+Inf"} 2`+"\n")
+	require.Contains(t, out, "# TYPE test_requests counter\ntest_requests_total 1\n")
+	require.True(t, strings.HasSuffix(out, "# EOF\n"))
+
+	h.Delete("a")
+	buf.Reset()
+	reg.WritePrometheus(&buf)
+	require.NotContains(t, buf.String(), `model="a"`)
+}
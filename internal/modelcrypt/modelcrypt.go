@@ -0,0 +1,191 @@
+// Package modelcrypt encrypts model files at rest and decrypts them for
+// loading without writing the plaintext to disk where the platform allows.
+//
+// An encrypted file starts with a header (the magic "GGUFENC1", the chunk
+// size as a little-endian uint32 and a 7-byte random nonce prefix), followed
+// by the plaintext in chunks sealed with AES-256-GCM. The nonce of a chunk is
+// the prefix, the chunk's big-endian uint32 index and a byte that is 1 for the
+// last chunk, so chunks cannot be reordered, dropped or truncated unnoticed.
+package modelcrypt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// KeySize is the length of an AES-256 key.
+const KeySize = 32
+
+// ChunkSize is the plaintext size of each chunk written by Encrypt.
+const ChunkSize = 1 << 20
+
+const (
+	magic      = "GGUFENC1"
+	prefixSize = 7
+	headerSize = len(magic) + 4 + prefixSize
+	maxChunk   = 64 << 20
+)
+
+var (
+	// ErrNotEncrypted is returned for input without the encrypted header.
+	ErrNotEncrypted = errors.New("not an encrypted model file")
+	// ErrDecrypt is returned when the key is wrong or the file was
+	// modified or truncated.
+	ErrDecrypt = errors.New("model decryption failed: wrong key or corrupt file")
+)
+
+// ParseKey decodes a 32-byte key given as 64 hex digits or in base64.
+// Surrounding whitespace is ignored.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := hex.DecodeString(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key must be %d bytes as hex or base64", KeySize)
+}
+
+// IsEncrypted reports whether the file at path has the encrypted header.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	buf := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(buf) == magic, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 0, prefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// Encrypt writes src to dst encrypted with key.
+func Encrypt(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.LittleEndian.PutUint32(header[len(magic):], ChunkSize)
+	prefix := header[len(magic)+4:]
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	// Read one chunk ahead to know which chunk is the last.
+	r := bufio.NewReaderSize(src, ChunkSize+1)
+	buf := make([]byte, ChunkSize)
+	sealed := make([]byte, 0, ChunkSize+aead.Overhead())
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		_, peekErr := r.Peek(1)
+		last := peekErr != nil
+		if last && !errors.Is(peekErr, io.EOF) {
+			return peekErr
+		}
+		sealed = aead.Seal(sealed[:0], chunkNonce(prefix, index, last), buf[:n], header)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if index == ^uint32(0) {
+			return errors.New("file too large")
+		}
+	}
+}
+
+// Decrypt writes the plaintext of src, encrypted with key, to dst. It
+// returns ErrDecrypt if the key does not match or src was tampered with;
+// dst may have received part of the plaintext by then. It stops early if
+// ctx is done.
+func Decrypt(ctx context.Context, dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil || !bytes.Equal(header[:len(magic)], []byte(magic)) {
+		return ErrNotEncrypted
+	}
+	chunkSize := int(binary.LittleEndian.Uint32(header[len(magic):]))
+	if chunkSize <= 0 || chunkSize > maxChunk {
+		return fmt.Errorf("%w: invalid chunk size %d", ErrDecrypt, chunkSize)
+	}
+	prefix := header[len(magic)+4:]
+
+	sealedSize := chunkSize + aead.Overhead()
+	r := bufio.NewReaderSize(src, sealedSize+1)
+	sealed := make([]byte, sealedSize)
+	plain := make([]byte, 0, chunkSize)
+	for index := uint32(0); ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(r, sealed)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		_, peekErr := r.Peek(1)
+		last := peekErr != nil
+		if last && !errors.Is(peekErr, io.EOF) {
+			return peekErr
+		}
+		plain, err = aead.Open(plain[:0], chunkNonce(prefix, index, last), sealed[:n], header)
+		if err != nil {
+			return ErrDecrypt
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package modelcrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func encrypt(t *testing.T, plain, key []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Encrypt(&buf, bytes.NewReader(plain), key))
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 17} {
+		plain := make([]byte, size)
+		_, err := rand.Read(plain)
+		require.NoError(t, err)
+		sealed := encrypt(t, plain, key)
+
+		var got bytes.Buffer
+		require.NoError(t, Decrypt(context.Background(), &got, bytes.NewReader(sealed), key), size)
+		require.True(t, bytes.Equal(plain, got.Bytes()), size)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	key := testKey(t)
+	plain := make([]byte, 2*ChunkSize+5)
+	sealed := encrypt(t, plain, key)
+	decrypt := func(data, key []byte) error {
+		return Decrypt(context.Background(), &bytes.Buffer{}, bytes.NewReader(data), key)
+	}
+
+	require.ErrorIs(t, decrypt(sealed, testKey(t)), ErrDecrypt)
+
+	flipped := bytes.Clone(sealed)
+	flipped[len(flipped)/2] ^= 1
+	require.ErrorIs(t, decrypt(flipped, key), ErrDecrypt)
+
+	// Truncating at a chunk boundary drops the last chunk.
+	chunk := ChunkSize + 16
+	require.ErrorIs(t, decrypt(sealed[:headerSize+2*chunk], key), ErrDecrypt)
+	require.ErrorIs(t, decrypt(sealed[:headerSize+chunk], key), ErrDecrypt)
+
+	require.ErrorIs(t, decrypt([]byte("GGUF plain model"), key), ErrNotEncrypted)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Decrypt(ctx, &bytes.Buffer{}, bytes.NewReader(sealed), key)
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(t)
+	got, err := ParseKey(hex.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+	got, err = ParseKey(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+	_, err = ParseKey("abcd")
+	require.Error(t, err)
+}
+
+func TestDecrypted(t *testing.T) {
+	key := testKey(t)
+	plain := []byte("GGUF model weights")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "m.gguf.enc")
+	require.NoError(t, os.WriteFile(path, encrypt(t, plain, key), 0o600))
+
+	encrypted, err := IsEncrypted(path)
+	require.NoError(t, err)
+	require.True(t, encrypted)
+
+	p, err := Decrypted(context.Background(), path, key, dir)
+	require.NoError(t, err)
+	got, err := os.ReadFile(p.Path)
+	require.NoError(t, err)
+	require.Equal(t, plain, got)
+	require.NoError(t, p.Close())
+	if !p.Anonymous {
+		_, err := os.Stat(p.Path)
+		require.True(t, os.IsNotExist(err))
+	}
+
+	_, err = Decrypted(context.Background(), path, testKey(t), dir)
+	require.ErrorIs(t, err, ErrDecrypt)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1) // no plaintext left behind
+
+	plainPath := filepath.Join(dir, "plain.gguf")
+	require.NoError(t, os.WriteFile(plainPath, plain, 0o600))
+	encrypted, err = IsEncrypted(plainPath)
+	require.NoError(t, err)
+	require.False(t, encrypted)
+}
+
+func TestTempPlaintextIsWiped(t *testing.T) {
+	p, err := newTempPlaintext(t.TempDir())
+	require.NoError(t, err)
+	_, err = p.f.Write([]byte("secret"))
+	require.NoError(t, err)
+	require.NoError(t, wipe(p.f))
+	got, err := os.ReadFile(p.Path)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, 6), got)
+	require.NoError(t, p.Close())
+	_, err = os.Stat(p.Path)
+	require.True(t, os.IsNotExist(err))
+}
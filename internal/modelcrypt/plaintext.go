@@ -0,0 +1,81 @@
+package modelcrypt
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Plaintext is a decrypted copy of a model that can be opened by path.
+type Plaintext struct {
+	// Path opens the decrypted model.
+	Path string
+	// Anonymous is true if the copy lives in memory only. Otherwise it is
+	// a temporary file that must not be memory-mapped, because Close
+	// overwrites it.
+	Anonymous bool
+
+	f *os.File
+}
+
+// Decrypted decrypts the model at path into anonymous memory where the
+// platform supports it (Linux) and into a temporary file in tmpDir ("" for
+// the default) otherwise. The caller must Close it, normally as soon as the
+// model is loaded.
+func Decrypted(ctx context.Context, path string, key []byte, tmpDir string) (*Plaintext, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	p, err := newPlaintext(tmpDir)
+	if err != nil {
+		return nil, fmt.Errorf("creating decrypted copy: %w", err)
+	}
+	if err := Decrypt(ctx, p.f, src, key); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close releases the decrypted copy. A temporary file is overwritten with
+// zeros before it is removed.
+func (p *Plaintext) Close() error {
+	if p.Anonymous {
+		return p.f.Close()
+	}
+	err := wipe(p.f)
+	if cerr := p.f.Close(); err == nil {
+		err = cerr
+	}
+	if rerr := os.Remove(p.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+func newTempPlaintext(tmpDir string) (*Plaintext, error) {
+	f, err := os.CreateTemp(tmpDir, "model-*.gguf")
+	if err != nil {
+		return nil, err
+	}
+	return &Plaintext{Path: f.Name(), f: f}, nil
+}
+
+// wipe overwrites f with zeros and syncs it.
+func wipe(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zeros := make([]byte, 1<<20)
+	for off := int64(0); off < fi.Size(); off += int64(len(zeros)) {
+		n := min(int64(len(zeros)), fi.Size()-off)
+		if _, err := f.WriteAt(zeros[:n], off); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
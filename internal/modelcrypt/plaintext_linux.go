@@ -0,0 +1,23 @@
+package modelcrypt
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// newPlaintext creates a memfd, so the plaintext never reaches a file
+// system. It falls back to a temporary file on kernels without memfd.
+func newPlaintext(tmpDir string) (*Plaintext, error) {
+	fd, err := unix.MemfdCreate("model", unix.MFD_CLOEXEC)
+	if err != nil {
+		return newTempPlaintext(tmpDir)
+	}
+	f := os.NewFile(uintptr(fd), "memfd:model")
+	return &Plaintext{
+		Path:      fmt.Sprintf("/proc/self/fd/%d", fd),
+		Anonymous: true,
+		f:         f,
+	}, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package modelcrypt
+
+// newPlaintext creates a temporary file; only Linux has anonymous files
+// that can be opened by path.
+func newPlaintext(tmpDir string) (*Plaintext, error) {
+	return newTempPlaintext(tmpDir)
+}
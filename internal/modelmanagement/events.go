@@ -0,0 +1,102 @@
+package modelmanagement
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the kind of a model lifecycle event.
+type EventType int
+
+const (
+	EventLoadStarted EventType = iota
+	EventLoadCompleted
+	EventLoadFailed
+	EventLoadCanceled
+	EventUnloaded
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLoadStarted:
+		return "load_started"
+	case EventLoadCompleted:
+		return "load_completed"
+	case EventLoadFailed:
+		return "load_failed"
+	case EventLoadCanceled:
+		return "load_canceled"
+	case EventUnloaded:
+		return "unloaded"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a model lifecycle event.
+type Event struct {
+	Type     EventType
+	Path     string
+	Time     time.Time
+	Err      error         // set for EventLoadFailed and EventLoadCanceled
+	Duration time.Duration // load time, set when a load ends
+}
+
+// eventBufferSize is how many events a watcher may fall behind before
+// further events are dropped for it.
+const eventBufferSize = 64
+
+// eventBroker fans out events to watchers without ever blocking the
+// manager on a slow one.
+type eventBroker struct {
+	mx       sync.Mutex
+	watchers map[chan Event]struct{}
+	closed   bool
+}
+
+func (b *eventBroker) watch() (<-chan Event, func()) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	ch := make(chan Event, eventBufferSize)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.watchers == nil {
+		b.watchers = make(map[chan Event]struct{})
+	}
+	b.watchers[ch] = struct{}{}
+	return ch, func() {
+		b.mx.Lock()
+		defer b.mx.Unlock()
+		if _, ok := b.watchers[ch]; ok {
+			delete(b.watchers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish returns how many watchers missed the event because they were full.
+func (b *eventBroker) publish(ev Event) int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	dropped := 0
+	for ch := range b.watchers {
+		select {
+		case ch <- ev:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+func (b *eventBroker) close() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.closed = true
+	for ch := range b.watchers {
+		close(ch)
+	}
+	b.watchers = nil
+}
@@ -1,8 +1,11 @@
 package modelmanagement
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/hypernetix/llamacpp_server/internal/logging"
 )
@@ -13,28 +16,67 @@ type DestroyableModel interface {
 }
 
 // ModelState represents the state of a model being loaded
-type ModelState struct {
-	Model      interface{}
-	Progresses []func(float32)
+type ModelState[T any] struct {
+	Model      T
+	Loaded     bool                    // the load func returned; Model and Err are final
+	Progresses []LoadModelProgressFunc // nil entries belong to callers that left
+	Waiters    int                     // callers waiting for the load to finish
 	Err        error
-	Wg         sync.WaitGroup
+	Done       chan struct{} // closed when the load func returns
 	Mx         sync.Mutex
+	ctx        context.Context // the load's own context, see LoadModel
+	cancel     context.CancelFunc
 	logger     logging.SprintfLogger
+	users      sync.WaitGroup // callers holding the model, see Acquire
 }
 
-// ModelManager interface defines the operations for managing model loading
-type ModelManager interface {
-	LoadModel(path string, progress LoadModelProgressFunc) (interface{}, error)
-	GetModel(path string) (interface{}, error)
+// ModelManager interface defines the operations for managing model loading.
+// T is the type of the loaded models.
+type ModelManager[T any] interface {
+	LoadModel(ctx context.Context, path string, overrides LoadOverrides, progress LoadModelProgressFunc) (T, error)
+	GetModel(path string) (T, error)
+	// Acquire is GetModel for a caller that uses the model until it calls
+	// release; UnloadModel waits for it.
+	Acquire(path string) (model T, release func(), err error)
+	GetOrLoad(ctx context.Context, path string, progress LoadModelProgressFunc) (T, error)
 	ListModels() []string
+	CancelLoad(path string) error
+	// UnloadModel removes a model, or cancels its load, and frees it once
+	// no caller holds it; beforeFree runs just before.
+	UnloadModel(path string, beforeFree func(T)) error
+	// Watch subscribes to model lifecycle events. The channel is closed
+	// when cancel is called or the manager stops.
+	Watch() (events <-chan Event, cancel func())
 	Stop()
 }
 
 // LoadModelProgressFunc is a function type for reporting loading progress
-type LoadModelProgressFunc func(float32)
+type LoadModelProgressFunc func(LoadProgress)
+
+// LoadProgressFunc reports loading progress from a LoadModelFunc. It returns
+// false once the load was canceled; the load func should then abort.
+type LoadProgressFunc func(LoadProgress) bool
+
+// LoadOverrides replaces server-wide load settings for one model. Nil or
+// zero fields keep the defaults. They only apply when the call actually
+// starts a load; a model that is loaded or loading is returned as is.
+type LoadOverrides struct {
+	NGpuLayers  *int
+	UseMmap     *bool
+	UseMlock    *bool
+	KvCacheType string
+	CtxSize     int
+
+	// Batching knobs of the scheduler while the model is in use; zero
+	// values keep the server defaults.
+	MaxBatchTokens int
+	MaxBatchSeqs   int
+	BatchWait      time.Duration
+}
 
-// LoadModelFunc is a function type for loading a model
-type LoadModelFunc func(path string, progress LoadModelProgressFunc) (interface{}, error)
+// LoadModelFunc is a function type for loading a model. It should abort once
+// ctx is done, either by checking it or when progress returns false.
+type LoadModelFunc[T any] func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (T, error)
 
 // ErrModelManagerClosed is returned when the model manager is closed
 var ErrModelManagerClosed = fmt.Errorf("model manager is closed")
@@ -42,95 +84,148 @@ var ErrModelManagerClosed = fmt.Errorf("model manager is closed")
 // ErrModelNotFound is returned when a model is not found
 var ErrModelNotFound = fmt.Errorf("model not found")
 
-type modelManager struct {
-	ModelStates   map[string]*ModelState
-	LoadModelFunc LoadModelFunc
+// ErrModelLoading is returned by GetModel while the model is still loading
+var ErrModelLoading = fmt.Errorf("model is loading")
+
+// ErrModelNotLoading is returned by CancelLoad when the model already finished loading
+var ErrModelNotLoading = fmt.Errorf("model is not loading")
+
+// ErrLoadCanceled is returned when a model load was canceled with CancelLoad
+// or abandoned by all its callers
+var ErrLoadCanceled = fmt.Errorf("model load canceled")
+
+type modelManager[T any] struct {
+	ModelStates   map[string]*ModelState[T]
+	LoadModelFunc LoadModelFunc[T]
 	Closed        bool
 	Mx            sync.Mutex
+	events        eventBroker
 	logger        logging.SprintfLogger
 }
 
 // NewModelManager creates a new model manager instance
-func NewModelManager(loadModelFunc LoadModelFunc, logger logging.SprintfLogger) ModelManager {
-	return &modelManager{
-		ModelStates:   make(map[string]*ModelState),
+func NewModelManager[T any](loadModelFunc LoadModelFunc[T], logger logging.SprintfLogger) ModelManager[T] {
+	return &modelManager[T]{
+		ModelStates:   make(map[string]*ModelState[T]),
 		LoadModelFunc: loadModelFunc,
 		Closed:        false,
 		logger:        logger,
 	}
 }
 
-func (state *ModelState) addProgress(progress func(float32)) {
+// join registers a caller waiting for the model and returns its slot in
+// Progresses.
+func (state *ModelState[T]) join(progress LoadModelProgressFunc) int {
 	state.Mx.Lock()
 	defer state.Mx.Unlock()
+	state.Waiters++
 	state.Progresses = append(state.Progresses, progress)
+	return len(state.Progresses) - 1
+}
+
+// leave unregisters a caller that stopped waiting. A load nobody waits for
+// any more is canceled.
+func (state *ModelState[T]) leave(slot int) {
+	state.Mx.Lock()
+	defer state.Mx.Unlock()
+	state.Waiters--
+	if slot < len(state.Progresses) {
+		state.Progresses[slot] = nil
+	}
+	if state.Waiters == 0 && !state.Loaded {
+		state.cancel()
+	}
 }
 
-func (state *ModelState) getProgresses() []func(float32) {
+func (state *ModelState[T]) getProgresses() []LoadModelProgressFunc {
 	state.Mx.Lock()
 	defer state.Mx.Unlock()
-	progresses := make([]func(float32), len(state.Progresses))
-	copy(progresses, state.Progresses)
+	progresses := make([]LoadModelProgressFunc, 0, len(state.Progresses))
+	for _, p := range state.Progresses {
+		if p != nil {
+			progresses = append(progresses, p)
+		}
+	}
 	return progresses
 }
 
-func (state *ModelState) broadcastingProgressFunc() func(float32) {
+// broadcastingProgressFunc forwards progress to all waiters, at most once
+// per percent within a stage, and fills in the ETA.
+func (state *ModelState[T]) broadcastingProgressFunc() LoadProgressFunc {
 	var lastIntProgress int = 0
-	return func(progress float32) {
-		if progress > 0.0 && progress < 1.0 {
-			intProgress := int(progress * 100.0)
+	lastStage := LoadStage(-1)
+	start := time.Now()
+	return func(progress LoadProgress) bool {
+		if state.ctx.Err() != nil {
+			return false
+		}
+		if progress.Stage == lastStage && progress.Fraction > 0.0 && progress.Fraction < 1.0 {
+			intProgress := int(progress.Fraction * 100.0)
 			if intProgress < lastIntProgress+1 {
-				return
+				return true
 			}
 			lastIntProgress = intProgress
 		}
+		lastStage = progress.Stage
+		if progress.ETA == 0 {
+			progress.ETA = estimateETA(start, progress.Fraction)
+		}
 		progresses := state.getProgresses()
 		for _, p := range progresses {
 			p(progress)
 		}
+		return true
 	}
 }
 
-func (state *ModelState) getModel() (interface{}, error) {
+// getModel returns the load result; loaded is false while still loading.
+func (state *ModelState[T]) getModel() (model T, loaded bool, err error) {
 	state.Mx.Lock()
 	defer state.Mx.Unlock()
 	if state.Err != nil {
-		return nil, state.Err
+		return model, state.Loaded, state.Err
 	}
-	return state.Model, nil
+	return state.Model, state.Loaded, nil
 }
 
-func (state *ModelState) saveLoaded(model interface{}, err error) {
+func (state *ModelState[T]) saveLoaded(model T, err error) {
 	state.Mx.Lock()
 	defer state.Mx.Unlock()
 	state.Model = model
+	state.Loaded = true
 	state.Err = err
 	state.Progresses = nil
 }
 
-func (state *ModelState) free() {
+func (state *ModelState[T]) free() {
 	state.Mx.Lock()
 	defer state.Mx.Unlock()
 
-	if state.Model != nil { // Check if model exists
-		if dm, ok := state.Model.(DestroyableModel); ok {
+	if state.Loaded && state.Err == nil {
+		state.destroy(state.Model)
+		var zero T
+		state.Model = zero // Clear it regardless of whether it was Destroyable or if Destroy failed
+	}
+	state.Loaded = false
+	state.Err = nil
+	state.Progresses = nil
+}
+
+func (state *ModelState[T]) destroy(model T) {
+	if dm, ok := any(model).(DestroyableModel); ok {
+		if state.logger != nil {
+			state.logger.Debugf("Destroying model")
+		}
+		err := dm.Destroy()
+		if err != nil {
 			if state.logger != nil {
-				state.logger.Debugf("Destroying model")
-			}
-			err := dm.Destroy()
-			if err != nil {
-				if state.logger != nil {
-					state.logger.Errorf("Failed to destroy model: %v", err)
-				}
+				state.logger.Errorf("Failed to destroy model: %v", err)
 			}
 		}
-		state.Model = nil // Nil it out regardless of whether it was Destroyable or if Destroy failed
 	}
-	state.Err = nil
-	state.Progresses = nil
 }
 
-func (m *modelManager) initiateLoad(path string, progress LoadModelProgressFunc) (*ModelState, bool, error) {
+func (m *modelManager[T]) initiateLoad(path string) (*ModelState[T], bool, error) {
 	m.Mx.Lock()
 	defer m.Mx.Unlock()
 	if m.Closed {
@@ -142,17 +237,19 @@ func (m *modelManager) initiateLoad(path string, progress LoadModelProgressFunc)
 		if m.logger != nil {
 			logger = m.logger.With("path", path)
 		}
-		state = &ModelState{
+		ctx, cancel := context.WithCancel(context.Background())
+		state = &ModelState[T]{
+			Done:   make(chan struct{}),
+			ctx:    ctx,
+			cancel: cancel,
 			logger: logger,
 		}
 		m.ModelStates[path] = state
-		state.Wg.Add(1)
 	}
-	state.addProgress(progress)
 	return state, ok, nil
 }
 
-func (m *modelManager) removeState(path string, state *ModelState) {
+func (m *modelManager[T]) removeState(path string, state *ModelState[T]) {
 	m.Mx.Lock()
 	defer m.Mx.Unlock()
 	currentState := m.ModelStates[path]
@@ -161,77 +258,225 @@ func (m *modelManager) removeState(path string, state *ModelState) {
 	}
 }
 
-func (m *modelManager) LoadModel(path string, progress LoadModelProgressFunc) (interface{}, error) {
-	state, ok, err := m.initiateLoad(path, progress)
+// LoadModel loads the model, or joins a load of it in progress. The load runs
+// in its own context: a caller whose ctx is done stops waiting with ctx.Err(),
+// and the load is only canceled once no caller waits for it any more.
+func (m *modelManager[T]) LoadModel(ctx context.Context, path string, overrides LoadOverrides, progress LoadModelProgressFunc) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	state, ok, err := m.initiateLoad(path)
 	if err != nil {
-		return nil, err
+		return zero, err
 	}
 
 	if ok {
-		model, err := state.getModel()
-		if model != nil || (err == nil) {
-			return model, err
+		model, loaded, err := state.getModel()
+		if loaded && err == nil {
+			return model, nil
 		}
+		if loaded {
+			// An earlier load failed, retry it.
+			m.removeState(path, state)
+			return m.LoadModel(ctx, path, overrides, progress)
+		}
+	}
 
-		m.removeState(path, state)
-
-		state.Wg.Wait()
-
-		return m.LoadModel(path, progress)
+	slot := state.join(progress)
+	if !ok {
+		go m.load(path, overrides, state)
 	}
 
-	defer state.Wg.Done()
+	select {
+	case <-state.Done:
+		model, _, err := state.getModel()
+		return model, err
+	case <-ctx.Done():
+		state.leave(slot)
+		return zero, ctx.Err()
+	}
+}
 
-	model, err := m.LoadModelFunc(path, state.broadcastingProgressFunc())
+func (m *modelManager[T]) load(path string, overrides LoadOverrides, state *ModelState[T]) {
+	defer close(state.Done)
+	defer state.cancel()
+
+	start := time.Now()
+	m.publish(Event{Type: EventLoadStarted, Path: path, Time: start})
+
+	model, err := m.LoadModelFunc(state.ctx, path, overrides, state.broadcastingProgressFunc())
+	if err != nil && state.ctx.Err() != nil {
+		err = fmt.Errorf("%w: %v", ErrLoadCanceled, err)
+	} else if err == nil && state.ctx.Err() != nil {
+		// Canceled just as the load finished, e.g. by UnloadModel.
+		state.destroy(model)
+		var zero T
+		model, err = zero, ErrLoadCanceled
+	}
 
 	state.saveLoaded(model, err)
 
+	ev := Event{Type: EventLoadCompleted, Path: path, Time: time.Now(), Err: err, Duration: time.Since(start)}
+	if errors.Is(err, ErrLoadCanceled) {
+		ev.Type = EventLoadCanceled
+	} else if err != nil {
+		ev.Type = EventLoadFailed
+	}
+	m.publish(ev)
+}
+
+func (m *modelManager[T]) GetModel(path string) (T, error) {
+	m.Mx.Lock()
+	defer m.Mx.Unlock()
+	var zero T
+	if m.Closed {
+		return zero, ErrModelManagerClosed
+	}
+	state, ok := m.ModelStates[path]
+	if !ok {
+		return zero, ErrModelNotFound
+	}
+	model, loaded, err := state.getModel()
+	if !loaded {
+		return zero, ErrModelLoading
+	}
 	return model, err
 }
 
-func (m *modelManager) GetModel(path string) (interface{}, error) {
+// CancelLoad asks a running load to abort. Callers waiting in LoadModel get
+// ErrLoadCanceled once the load func gives up.
+func (m *modelManager[T]) CancelLoad(path string) error {
+	m.Mx.Lock()
+	defer m.Mx.Unlock()
+	if m.Closed {
+		return ErrModelManagerClosed
+	}
+	state, ok := m.ModelStates[path]
+	if !ok {
+		return ErrModelNotFound
+	}
+	state.Mx.Lock()
+	defer state.Mx.Unlock()
+	if state.Loaded {
+		return ErrModelNotLoading
+	}
+	state.cancel()
+	return nil
+}
+
+// Acquire returns the loaded model like GetModel and keeps it from being
+// freed by UnloadModel until release is called.
+func (m *modelManager[T]) Acquire(path string) (T, func(), error) {
 	m.Mx.Lock()
 	defer m.Mx.Unlock()
+	var zero T
+	if m.Closed {
+		return zero, nil, ErrModelManagerClosed
+	}
+	state, ok := m.ModelStates[path]
+	if !ok {
+		return zero, nil, ErrModelNotFound
+	}
+	model, loaded, err := state.getModel()
+	if !loaded {
+		return zero, nil, ErrModelLoading
+	}
+	if err != nil {
+		return zero, nil, err
+	}
+	state.users.Add(1)
+	return model, sync.OnceFunc(state.users.Done), nil
+}
+
+// UnloadModel removes the model, canceling its load if it is still loading,
+// so that GetModel no longer finds it and LoadModel loads it anew. Once the
+// callers that acquired it released it, beforeFree (if not nil) drops what
+// refers to the model and the model is freed.
+func (m *modelManager[T]) UnloadModel(path string, beforeFree func(T)) error {
+	m.Mx.Lock()
 	if m.Closed {
-		return nil, ErrModelManagerClosed
+		m.Mx.Unlock()
+		return ErrModelManagerClosed
 	}
 	state, ok := m.ModelStates[path]
 	if !ok {
-		return nil, ErrModelNotFound
+		m.Mx.Unlock()
+		return ErrModelNotFound
+	}
+	delete(m.ModelStates, path)
+	m.Mx.Unlock()
+
+	state.cancel()
+	<-state.Done
+	model, _, err := state.getModel()
+	if err != nil {
+		return nil // the load failed or was canceled, there is nothing to free
+	}
+	state.users.Wait()
+	if beforeFree != nil {
+		beforeFree(model)
 	}
-	return state.getModel()
+	state.free()
+	m.publish(Event{Type: EventUnloaded, Path: path, Time: time.Now()})
+	return nil
 }
 
-func (m *modelManager) ListModels() []string {
+// GetOrLoad returns the model if it is loaded, otherwise loads it (or joins
+// a load in progress) and reports progress like LoadModel.
+func (m *modelManager[T]) GetOrLoad(ctx context.Context, path string, progress LoadModelProgressFunc) (T, error) {
+	model, err := m.GetModel(path)
+	if err != ErrModelNotFound && err != ErrModelLoading {
+		return model, err
+	}
+	return m.LoadModel(ctx, path, LoadOverrides{}, progress)
+}
+
+func (m *modelManager[T]) Watch() (<-chan Event, func()) {
+	return m.events.watch()
+}
+
+func (m *modelManager[T]) publish(ev Event) {
+	if dropped := m.events.publish(ev); dropped > 0 && m.logger != nil {
+		m.logger.Warnf("%d event watcher(s) fell behind, dropped %s event for %s", dropped, ev.Type, ev.Path)
+	}
+}
+
+func (m *modelManager[T]) ListModels() []string {
 	m.Mx.Lock()
 	defer m.Mx.Unlock()
 	var paths []string
 	for path, state := range m.ModelStates {
-		if model, err := state.getModel(); model != nil && err == nil {
+		if _, loaded, err := state.getModel(); loaded && err == nil {
 			paths = append(paths, path)
 		}
 	}
 	return paths
 }
 
-func (m *modelManager) cancelLoads() []*ModelState {
+func (m *modelManager[T]) cancelLoads() map[string]*ModelState[T] {
 	m.Mx.Lock()
 	defer m.Mx.Unlock()
 	if m.Closed {
 		return nil
 	}
 	m.Closed = true
-	modelStates := make([]*ModelState, 0, len(m.ModelStates))
-	for _, state := range m.ModelStates {
-		modelStates = append(modelStates, state)
+	modelStates := make(map[string]*ModelState[T], len(m.ModelStates))
+	for path, state := range m.ModelStates {
+		modelStates[path] = state
 	}
 	return modelStates
 }
 
-func (m *modelManager) Stop() {
+func (m *modelManager[T]) Stop() {
 	modelStates := m.cancelLoads()
-	for _, state := range modelStates {
-		state.Wg.Wait()
+	for path, state := range modelStates {
+		<-state.Done
+		_, loaded, err := state.getModel()
 		state.free()
+		if loaded && err == nil {
+			m.publish(Event{Type: EventUnloaded, Path: path, Time: time.Now()})
+		}
 	}
+	m.events.close()
 }
@@ -1,6 +1,7 @@
 package modelmanagement_test
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -127,26 +128,26 @@ func newConcurrentMockLoad(loadDuration time.Duration, loadError error) *Concurr
 	}
 }
 
-func (m *ConcurrentMockLoad) Load(path string, progress modelmanagement.LoadModelProgressFunc) (interface{}, error) {
+func (m *ConcurrentMockLoad) Load(ctx context.Context, path string, overrides modelmanagement.LoadOverrides, progress modelmanagement.LoadProgressFunc) (any, error) {
 	m.mu.Lock()
 	m.loadCount++
 	m.mu.Unlock()
 
 	if progress != nil {
-		progress(0.0)
+		progress(modelmanagement.LoadProgress{Fraction: 0.0})
 	}
 
 	if m.loadDuration > 0 {
 		// Simulate loading taking time
 		time.Sleep(m.loadDuration / 2)
 		if progress != nil {
-			progress(0.5)
+			progress(modelmanagement.LoadProgress{Fraction: 0.5})
 		}
 		time.Sleep(m.loadDuration / 2)
 	}
 
 	if progress != nil {
-		progress(1.0)
+		progress(modelmanagement.LoadProgress{Fraction: 1.0})
 	}
 
 	if m.loadError != nil {
@@ -186,7 +187,7 @@ func TestConcurrentModelManagerLoading(t *testing.T) {
 	// Track progress calls
 	var progressMutex sync.Mutex
 	progressCalls := 0
-	progressFunc := func(p float32) {
+	progressFunc := func(p modelmanagement.LoadProgress) {
 		progressMutex.Lock()
 		defer progressMutex.Unlock()
 		progressCalls++
@@ -201,7 +202,7 @@ func TestConcurrentModelManagerLoading(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			// We don't verify the model since our mock returns nil, we only care about error checking
-			_, err := manager.LoadModel("test_model.bin", progressFunc)
+			_, err := manager.LoadModel(context.Background(), "test_model.bin", modelmanagement.LoadOverrides{}, progressFunc)
 			require.NoError(t, err)
 		}()
 	}
@@ -233,7 +234,7 @@ func TestRaceLoadAndStop(t *testing.T) {
 	for i := 0; i < numLoads; i++ {
 		go func(id int) {
 			defer wg.Done()
-			_, err := manager.LoadModel("test_model.bin", func(p float32) {})
+			_, err := manager.LoadModel(context.Background(), "test_model.bin", modelmanagement.LoadOverrides{}, func(p modelmanagement.LoadProgress) {})
 			resultsMu.Lock()
 			results[id] = err
 			resultsMu.Unlock()
@@ -261,7 +262,7 @@ func TestRaceLoadAndStop(t *testing.T) {
 	t.Logf("Successful loads: %d out of %d", successCount, numLoads)
 
 	// Try to load after stopping - should fail with a specific error
-	_, err := manager.LoadModel("another_model.bin", func(p float32) {})
+	_, err := manager.LoadModel(context.Background(), "another_model.bin", modelmanagement.LoadOverrides{}, func(p modelmanagement.LoadProgress) {})
 	require.Error(t, err)
 	require.Equal(t, modelmanagement.ErrModelManagerClosed, err)
 }
@@ -282,7 +283,7 @@ func TestConcurrentModelManagerDifferentPaths(t *testing.T) {
 	// Track progress calls
 	var progressMutex sync.Mutex
 	progressCalls := 0
-	progressFunc := func(p float32) {
+	progressFunc := func(p modelmanagement.LoadProgress) {
 		progressMutex.Lock()
 		defer progressMutex.Unlock()
 		progressCalls++
@@ -300,7 +301,7 @@ func TestConcurrentModelManagerDifferentPaths(t *testing.T) {
 		pathIndex := i
 		go func() {
 			defer wg.Done()
-			_, err := manager.LoadModel(modelPaths[pathIndex], progressFunc)
+			_, err := manager.LoadModel(context.Background(), modelPaths[pathIndex], modelmanagement.LoadOverrides{}, progressFunc)
 			require.NoError(t, err)
 		}()
 	}
@@ -323,7 +324,7 @@ func TestStopClosedModelManager(t *testing.T) {
 	manager.Stop()
 
 	// Verify manager reports it's closed when attempting to load
-	_, err := manager.LoadModel("test_model.bin", func(p float32) {})
+	_, err := manager.LoadModel(context.Background(), "test_model.bin", modelmanagement.LoadOverrides{}, func(p modelmanagement.LoadProgress) {})
 	require.Error(t, err)
 	require.Equal(t, modelmanagement.ErrModelManagerClosed, err)
 
@@ -1,25 +1,28 @@
 package modelmanagement
 
 import (
+	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 // simpleMockLoadFunc creates a loadFunc that simulates loading a model
-func simpleMockLoadFunc(delay time.Duration, returnErr error) LoadModelFunc {
-	return func(path string, progress LoadModelProgressFunc) (interface{}, error) {
+func simpleMockLoadFunc(delay time.Duration, returnErr error) LoadModelFunc[any] {
+	return func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (any, error) {
 		// Simulate progress updates
 		if progress != nil {
-			progress(0.0)
+			progress(LoadProgress{Fraction: 0.0})
 			if delay > 0 {
 				time.Sleep(delay / 2)
-				progress(0.5)
+				progress(LoadProgress{Fraction: 0.5})
 				time.Sleep(delay / 2)
 			}
-			progress(1.0)
+			progress(LoadProgress{Fraction: 1.0})
 		}
 
 		if returnErr != nil {
@@ -38,14 +41,14 @@ func TestImportLoadModel(t *testing.T) {
 
 	// Test progress tracking
 	progressCalled := false
-	progressFunc := func(p float32) {
+	progressFunc := func(p LoadProgress) {
 		progressCalled = true
-		require.GreaterOrEqual(t, p, float32(0.0))
-		require.LessOrEqual(t, p, float32(1.0))
+		require.GreaterOrEqual(t, p.Fraction, float32(0.0))
+		require.LessOrEqual(t, p.Fraction, float32(1.0))
 	}
 
 	// Load a model
-	_, err := manager.LoadModel("test_model.bin", progressFunc)
+	_, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, progressFunc)
 
 	// Verify results
 	require.NoError(t, err)
@@ -64,7 +67,7 @@ func TestImportLoadModelError(t *testing.T) {
 	manager := NewModelManager(simpleMockLoadFunc(0, expectedErr), nil)
 
 	// Load a model (should fail)
-	model, err := manager.LoadModel("test_model.bin", func(p float32) {})
+	model, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, func(p LoadProgress) {})
 
 	// Verify results
 	require.Error(t, err)
@@ -78,14 +81,14 @@ func TestImportLoadModelError(t *testing.T) {
 func TestRetryAfterModelLoadFailure(t *testing.T) {
 	// Create a mock loader that fails on first attempt but succeeds on second attempt
 	loadCount := 0
-	mockLoadFunc := func(path string, progress LoadModelProgressFunc) (interface{}, error) {
+	mockLoadFunc := func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (any, error) {
 		loadCount++
 
 		// Simulate progress
 		if progress != nil {
-			progress(0.0)
-			progress(0.5)
-			progress(1.0)
+			progress(LoadProgress{Fraction: 0.0})
+			progress(LoadProgress{Fraction: 0.5})
+			progress(LoadProgress{Fraction: 1.0})
 		}
 
 		if loadCount == 1 {
@@ -102,14 +105,272 @@ func TestRetryAfterModelLoadFailure(t *testing.T) {
 	defer manager.Stop()
 
 	// First load attempt should fail and be cached
-	_, err := manager.LoadModel("test_model.bin", func(p float32) {})
+	_, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, func(p LoadProgress) {})
 	require.Error(t, err)
 	require.Equal(t, "first load attempt failed", err.Error())
 	require.Equal(t, 1, loadCount)
 
 	// Second call to LoadModel should retry and succeed
-	model, err := manager.LoadModel("test_model.bin", func(p float32) {})
+	model, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, func(p LoadProgress) {})
 	require.NoError(t, err)
 	require.Nil(t, model) // Our mock returns nil model
 	require.Equal(t, 2, loadCount)
 }
+
+func TestCancelLoad(t *testing.T) {
+	started := make(chan struct{})
+	mockLoadFunc := func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (any, error) {
+		close(started)
+		for progress(LoadProgress{Stage: LoadStageLoading, Fraction: 0.5}) {
+			time.Sleep(time.Millisecond)
+		}
+		return nil, errors.New("aborted by progress callback")
+	}
+
+	manager := NewModelManager(mockLoadFunc, nil)
+	defer manager.Stop()
+
+	require.ErrorIs(t, manager.CancelLoad("test_model.bin"), ErrModelNotFound)
+
+	done := make(chan error)
+	go func() {
+		_, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, func(p LoadProgress) {})
+		done <- err
+	}()
+	<-started
+
+	require.NoError(t, manager.CancelLoad("test_model.bin"))
+	err := <-done
+	require.ErrorIs(t, err, ErrLoadCanceled)
+	require.ErrorIs(t, manager.CancelLoad("test_model.bin"), ErrModelNotLoading)
+}
+
+func TestWatchReportsLoadEvents(t *testing.T) {
+	manager := NewModelManager(simpleMockLoadFunc(0, nil), nil)
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	_, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, func(p LoadProgress) {})
+	require.NoError(t, err)
+
+	ev := <-events
+	require.Equal(t, EventLoadStarted, ev.Type)
+	require.Equal(t, "test_model.bin", ev.Path)
+	ev = <-events
+	require.Equal(t, EventLoadCompleted, ev.Type)
+	require.NoError(t, ev.Err)
+
+	manager.Stop()
+	ev = <-events
+	require.Equal(t, EventUnloaded, ev.Type)
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestGetModelWhileLoading(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockLoadFunc := func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (*int, error) {
+		close(started)
+		<-release
+		n := 42
+		return &n, nil
+	}
+
+	manager := NewModelManager(mockLoadFunc, nil)
+	defer manager.Stop()
+
+	done := make(chan *int)
+	go func() {
+		model, _ := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, nil)
+		done <- model
+	}()
+	<-started
+
+	_, err := manager.GetModel("test_model.bin")
+	require.ErrorIs(t, err, ErrModelLoading)
+
+	joined := make(chan *int)
+	go func() {
+		model, _ := manager.GetOrLoad(context.Background(), "test_model.bin", nil)
+		joined <- model
+	}()
+	close(release)
+
+	require.Equal(t, 42, *<-done)
+	require.Equal(t, 42, *<-joined)
+}
+
+func TestLoadModelHonorsDeadline(t *testing.T) {
+	aborted := make(chan error, 1)
+	mockLoadFunc := func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (any, error) {
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return nil, ctx.Err()
+	}
+
+	manager := NewModelManager(mockLoadFunc, nil)
+	defer manager.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := manager.LoadModel(ctx, "test_model.bin", LoadOverrides{}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The load is abandoned by its only caller, so it is canceled.
+	require.ErrorIs(t, <-aborted, context.Canceled)
+}
+
+func TestUnloadModelWaitsForUsers(t *testing.T) {
+	manager := NewModelManager(simpleMockLoadFunc(0, nil), nil)
+	defer manager.Stop()
+	events, cancel := manager.Watch()
+	defer cancel()
+
+	_, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, nil)
+	require.NoError(t, err)
+	<-events // started
+	<-events // completed
+	_, release, err := manager.Acquire("test_model.bin")
+	require.NoError(t, err)
+
+	unloaded := make(chan error)
+	freed := make(chan struct{})
+	go func() {
+		unloaded <- manager.UnloadModel("test_model.bin", func(any) { close(freed) })
+	}()
+
+	// The model is gone at once, but only freed once released.
+	require.Eventually(t, func() bool {
+		_, err := manager.GetModel("test_model.bin")
+		return errors.Is(err, ErrModelNotFound)
+	}, time.Second, time.Millisecond)
+	select {
+	case <-freed:
+		t.Fatal("model freed while in use")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	release() // a second call is a no-op
+	require.NoError(t, <-unloaded)
+	<-freed
+	require.Equal(t, EventUnloaded, (<-events).Type)
+
+	require.ErrorIs(t, manager.UnloadModel("test_model.bin", nil), ErrModelNotFound)
+	_, _, err = manager.Acquire("test_model.bin")
+	require.ErrorIs(t, err, ErrModelNotFound)
+}
+
+func TestUnloadModelCancelsLoad(t *testing.T) {
+	started := make(chan struct{})
+	mockLoadFunc := func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	manager := NewModelManager(mockLoadFunc, nil)
+	defer manager.Stop()
+
+	done := make(chan error)
+	go func() {
+		_, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, nil)
+		done <- err
+	}()
+	<-started
+	require.NoError(t, manager.UnloadModel("test_model.bin", func(any) { t.Error("freed a model that never loaded") }))
+	require.ErrorIs(t, <-done, ErrLoadCanceled)
+}
+
+// waiters returns how many callers wait for the load of path.
+func waiters[T any](manager ModelManager[T], path string) int {
+	m := manager.(*modelManager[T])
+	m.Mx.Lock()
+	state, ok := m.ModelStates[path]
+	m.Mx.Unlock()
+	if !ok {
+		return 0
+	}
+	state.Mx.Lock()
+	defer state.Mx.Unlock()
+	return state.Waiters
+}
+
+// gatedLoadFunc counts its calls and loads only once release is closed.
+func gatedLoadFunc(calls *atomic.Int32, release <-chan struct{}, err error) LoadModelFunc[*int] {
+	return func(ctx context.Context, path string, overrides LoadOverrides, progress LoadProgressFunc) (*int, error) {
+		calls.Add(1)
+		<-release
+		if err != nil {
+			return nil, err
+		}
+		n := 42
+		return &n, nil
+	}
+}
+
+func TestGetOrLoadReturnsLoadedModel(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	close(release)
+	manager := NewModelManager(gatedLoadFunc(&calls, release, nil), nil)
+	defer manager.Stop()
+
+	loaded, err := manager.LoadModel(context.Background(), "test_model.bin", LoadOverrides{}, nil)
+	require.NoError(t, err)
+
+	model, err := manager.GetOrLoad(context.Background(), "test_model.bin", func(LoadProgress) {
+		t.Error("progress reported for a loaded model")
+	})
+	require.NoError(t, err)
+	require.Same(t, loaded, model)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestGetOrLoadJoinsLoad(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	manager := NewModelManager(gatedLoadFunc(&calls, release, nil), nil)
+	defer manager.Stop()
+
+	const callers = 4
+	models := make(chan *int, callers)
+	for range callers {
+		go func() {
+			model, err := manager.GetOrLoad(context.Background(), "test_model.bin", nil)
+			assert.NoError(t, err)
+			models <- model
+		}()
+	}
+	require.Eventually(t, func() bool { return waiters(manager, "test_model.bin") == callers }, time.Second, time.Millisecond)
+	close(release)
+
+	first := <-models
+	for range callers - 1 {
+		require.Same(t, first, <-models)
+	}
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestGetOrLoadReportsFailureToAllWaiters(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loadErr := errors.New("invalid gguf")
+	manager := NewModelManager(gatedLoadFunc(&calls, release, loadErr), nil)
+	defer manager.Stop()
+
+	const callers = 3
+	errs := make(chan error, callers)
+	for range callers {
+		go func() {
+			_, err := manager.GetOrLoad(context.Background(), "test_model.bin", nil)
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return waiters(manager, "test_model.bin") == callers }, time.Second, time.Millisecond)
+	close(release)
+
+	for range callers {
+		require.ErrorIs(t, <-errs, loadErr)
+	}
+	require.EqualValues(t, 1, calls.Load())
+}
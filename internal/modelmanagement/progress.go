@@ -0,0 +1,66 @@
+package modelmanagement
+
+import "time"
+
+// LoadStage is the phase a model load is in.
+type LoadStage int
+
+const (
+	// LoadStageReading opens the file and parses its GGUF metadata.
+	LoadStageReading LoadStage = iota
+	// LoadStageLoading reads tensor data into memory and offloads the GPU
+	// layers; this is the long phase covered by Fraction.
+	LoadStageLoading
+	// LoadStageReady is reported once the model is usable.
+	LoadStageReady
+)
+
+func (s LoadStage) String() string {
+	switch s {
+	case LoadStageReading:
+		return "reading"
+	case LoadStageLoading:
+		return "loading"
+	case LoadStageReady:
+		return "ready"
+	default:
+		return "unknown"
+	}
+}
+
+// LoadProgress is a model load progress update.
+type LoadProgress struct {
+	Stage    LoadStage
+	Fraction float32 // 0..1 over the whole load
+
+	// BytesLoaded and BytesTotal refer to the model file; BytesTotal is 0
+	// if the size is unknown.
+	BytesLoaded int64
+	BytesTotal  int64
+
+	// ETA is the estimated remaining load time, extrapolated from the rate
+	// so far. Zero until an estimate is available.
+	ETA time.Duration
+
+	// Warnings, reported with LoadStageReady, list the load settings that
+	// were changed to let the model load.
+	Warnings []LoadWarning
+}
+
+// LoadWarning reports a load setting that was changed, e.g. a context size
+// reduced to fit in memory.
+type LoadWarning struct {
+	Reason   string // e.g. "CTX_SIZE_CLAMPED"
+	Message  string
+	Metadata map[string]string
+}
+
+// estimateETA extrapolates the remaining time of a load that started at
+// start and has reached fraction.
+func estimateETA(start time.Time, fraction float32) time.Duration {
+	if fraction <= 0 || fraction >= 1 {
+		return 0
+	}
+	elapsed := time.Since(start)
+	return time.Duration(float64(elapsed) * float64(1-fraction) / float64(fraction))
+}
@@ -0,0 +1,293 @@
+package modelmanagement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+)
+
+// ScheduleAction is what a ScheduleEntry does to its model.
+type ScheduleAction int
+
+const (
+	ScheduleLoad ScheduleAction = iota
+	ScheduleUnload
+)
+
+func (a ScheduleAction) String() string {
+	switch a {
+	case ScheduleLoad:
+		return "load"
+	case ScheduleUnload:
+		return "unload"
+	default:
+		return "unknown"
+	}
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleEntry loads or unloads a model at a time of day, in the local time
+// zone, every day or on some days of the week.
+type ScheduleEntry struct {
+	Days   uint8 // bit i is set for time.Weekday(i); 0 means every day
+	Hour   int
+	Minute int
+	Action ScheduleAction
+	Model  string
+}
+
+// ParseScheduleEntry parses "[DAYS] HH:MM load|unload MODEL", where DAYS is
+// a comma-separated list of days (sun, mon, ..., sat) and ranges of days
+// such as mon-fri, or * for every day, which is the default.
+func ParseScheduleEntry(s string) (ScheduleEntry, error) {
+	var e ScheduleEntry
+	fields := strings.Fields(s)
+	if len(fields) == 4 {
+		days, err := parseDays(fields[0])
+		if err != nil {
+			return e, fmt.Errorf("schedule entry %q: %w", s, err)
+		}
+		e.Days = days
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return e, fmt.Errorf("schedule entry %q: expected [DAYS] HH:MM load|unload MODEL", s)
+	}
+
+	hour, minute, ok := strings.Cut(fields[0], ":")
+	var err error
+	if ok {
+		e.Hour, err = strconv.Atoi(hour)
+	}
+	if ok && err == nil {
+		e.Minute, err = strconv.Atoi(minute)
+	}
+	if !ok || err != nil || e.Hour < 0 || e.Hour > 23 || e.Minute < 0 || e.Minute > 59 || len(minute) != 2 {
+		return e, fmt.Errorf("schedule entry %q: invalid time %q (want HH:MM)", s, fields[0])
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "load":
+		e.Action = ScheduleLoad
+	case "unload":
+		e.Action = ScheduleUnload
+	default:
+		return e, fmt.Errorf("schedule entry %q: unknown action %q (want load or unload)", s, fields[1])
+	}
+	e.Model = fields[2]
+	return e, nil
+}
+
+func parseDays(s string) (uint8, error) {
+	if s == "*" {
+		return 0, nil
+	}
+	var days uint8
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, ok := parseWeekday(first)
+		to := from
+		if ok && isRange {
+			to, ok = parseWeekday(last)
+		}
+		if !ok {
+			return 0, fmt.Errorf("invalid days %q (want e.g. mon-fri or sat,sun)", s)
+		}
+		// A range may wrap around the week, e.g. fri-mon.
+		for d := from; ; d = (d + 1) % 7 {
+			days |= 1 << d
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseWeekday(s string) (int, bool) {
+	for i, name := range weekdayNames {
+		if strings.EqualFold(s, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (e ScheduleEntry) String() string {
+	days := "*"
+	if e.Days != 0 {
+		var names []string
+		for i, name := range weekdayNames {
+			if e.Days&(1<<i) != 0 {
+				names = append(names, name)
+			}
+		}
+		days = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("%s %02d:%02d %s %s", days, e.Hour, e.Minute, e.Action, e.Model)
+}
+
+// at returns the time of the entry dayOffset days after the day of t, and
+// whether the entry runs on that day.
+func (e ScheduleEntry) at(t time.Time, dayOffset int) (time.Time, bool) {
+	at := time.Date(t.Year(), t.Month(), t.Day()+dayOffset, e.Hour, e.Minute, 0, 0, t.Location())
+	return at, e.Days == 0 || e.Days&(1<<at.Weekday()) != 0
+}
+
+// Next returns the first time after t that the entry runs.
+func (e ScheduleEntry) Next(t time.Time) time.Time {
+	for d := 0; ; d++ {
+		if at, ok := e.at(t, d); ok && at.After(t) {
+			return at
+		}
+	}
+}
+
+// Prev returns the last time at or before t that the entry ran.
+func (e ScheduleEntry) Prev(t time.Time) time.Time {
+	for d := 0; ; d-- {
+		if at, ok := e.at(t, d); ok && !at.After(t) {
+			return at
+		}
+	}
+}
+
+// Scheduler loads and unloads models at the times of its entries, e.g. to
+// serve a large model during the day and free the GPU for batch jobs at
+// night. Models are loaded and unloaded through the functions it was given,
+// so that the server can release what refers to a model before it is freed.
+type Scheduler struct {
+	entries []ScheduleEntry
+	load    func(ctx context.Context, model string) error
+	unload  func(model string) error
+	logger  logging.SprintfLogger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewScheduler starts a scheduler. It first loads the models whose last
+// entry before now was a load, as if the server had been running, and then
+// runs each entry when it is due; entries due together run in order. It
+// runs until Stop is called.
+func NewScheduler(entries []ScheduleEntry, load func(ctx context.Context, model string) error, unload func(model string) error, logger logging.SprintfLogger) *Scheduler {
+	s := &Scheduler{
+		entries: entries,
+		load:    load,
+		unload:  unload,
+		logger:  logger.With("module", "scheduler"),
+		done:    make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s
+}
+
+// Stop cancels a scheduled load in progress and stops the scheduler.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *Scheduler) run() {
+	defer close(s.done)
+	now := time.Now()
+	for _, model := range missedLoads(s.entries, now) {
+		s.runEntry(ScheduleEntry{Action: ScheduleLoad, Model: model})
+	}
+	for s.ctx.Err() == nil && len(s.entries) > 0 {
+		next, due := nextDue(s.entries, now)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+		for _, e := range due {
+			s.runEntry(e)
+		}
+		// Entries that came due while these ran are run late, not skipped.
+		now = next
+	}
+}
+
+func (s *Scheduler) runEntry(e ScheduleEntry) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	start := time.Now()
+	switch e.Action {
+	case ScheduleLoad:
+		s.logger.Infof("Loading model %s", e.Model)
+		if err := s.load(s.ctx, e.Model); err != nil {
+			s.logger.Errorf("Failed to load model %s: %v", e.Model, err)
+			return
+		}
+		s.logger.Infof("Loaded model %s in %.2fs", e.Model, time.Since(start).Seconds())
+	case ScheduleUnload:
+		s.logger.Infof("Unloading model %s", e.Model)
+		err := s.unload(e.Model)
+		if errors.Is(err, ErrModelNotFound) {
+			s.logger.Infof("Model %s is not loaded", e.Model)
+			return
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to unload model %s: %v", e.Model, err)
+			return
+		}
+		s.logger.Infof("Unloaded model %s in %.2fs", e.Model, time.Since(start).Seconds())
+	}
+}
+
+// nextDue returns the first time after now that entries run, and those
+// entries.
+func nextDue(entries []ScheduleEntry, now time.Time) (time.Time, []ScheduleEntry) {
+	var next time.Time
+	var due []ScheduleEntry
+	for _, e := range entries {
+		at := e.Next(now)
+		switch {
+		case next.IsZero() || at.Before(next):
+			next, due = at, []ScheduleEntry{e}
+		case at.Equal(next):
+			due = append(due, e)
+		}
+	}
+	return next, due
+}
+
+// missedLoads returns the models, in the order of their entries, whose last
+// entry run at or before now was a load.
+func missedLoads(entries []ScheduleEntry, now time.Time) []string {
+	type last struct {
+		at     time.Time
+		action ScheduleAction
+	}
+	lasts := map[string]last{}
+	var models []string
+	for _, e := range entries {
+		at := e.Prev(now)
+		l, ok := lasts[e.Model]
+		if !ok {
+			models = append(models, e.Model)
+		}
+		if !ok || !at.Before(l.at) {
+			lasts[e.Model] = last{at, e.Action}
+		}
+	}
+	var loads []string
+	for _, model := range models {
+		if lasts[model].action == ScheduleLoad {
+			loads = append(loads, model)
+		}
+	}
+	return loads
+}
@@ -0,0 +1,96 @@
+package modelmanagement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseEntry(t *testing.T, s string) ScheduleEntry {
+	t.Helper()
+	e, err := ParseScheduleEntry(s)
+	require.NoError(t, err)
+	return e
+}
+
+func TestParseScheduleEntry(t *testing.T) {
+	e := mustParseEntry(t, "08:00 load /models/big.gguf")
+	require.Equal(t, ScheduleEntry{Hour: 8, Action: ScheduleLoad, Model: "/models/big.gguf"}, e)
+	require.Equal(t, "* 08:00 load /models/big.gguf", e.String())
+
+	e = mustParseEntry(t, "mon-fri 20:30 unload big")
+	require.Equal(t, "mon,tue,wed,thu,fri 20:30 unload big", e.String())
+	e = mustParseEntry(t, "fri-mon,Wed 7:05 load big")
+	require.Equal(t, "sun,mon,wed,fri,sat 07:05 load big", e.String())
+
+	for _, s := range []string{
+		"",
+		"08:00 load",
+		"8 load big",
+		"24:00 load big",
+		"08:60 load big",
+		"08:0 load big",
+		"08:00 start big",
+		"weekdays 08:00 load big",
+		"mon-xyz 08:00 load big",
+		"08:00 load big extra",
+	} {
+		_, err := ParseScheduleEntry(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestScheduleEntryNextAndPrev(t *testing.T) {
+	// 2024-06-14 is a Friday.
+	fri := time.Date(2024, 6, 14, 12, 0, 0, 0, time.UTC)
+	daily := mustParseEntry(t, "08:00 load big")
+	require.Equal(t, time.Date(2024, 6, 15, 8, 0, 0, 0, time.UTC), daily.Next(fri))
+	require.Equal(t, time.Date(2024, 6, 14, 8, 0, 0, 0, time.UTC), daily.Prev(fri))
+
+	// Next is strictly after, Prev at or before.
+	at := time.Date(2024, 6, 14, 8, 0, 0, 0, time.UTC)
+	require.Equal(t, at.AddDate(0, 0, 1), daily.Next(at))
+	require.Equal(t, at, daily.Prev(at))
+
+	weekdays := mustParseEntry(t, "mon-fri 08:00 load big")
+	require.Equal(t, time.Date(2024, 6, 17, 8, 0, 0, 0, time.UTC), weekdays.Next(fri))
+	sun := time.Date(2024, 6, 16, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2024, 6, 14, 8, 0, 0, 0, time.UTC), weekdays.Prev(sun))
+}
+
+func TestScheduleDueAndMissed(t *testing.T) {
+	entries := []ScheduleEntry{
+		mustParseEntry(t, "08:00 load big"),
+		mustParseEntry(t, "20:00 unload big"),
+		mustParseEntry(t, "20:00 load batch"),
+		mustParseEntry(t, "08:00 unload batch"),
+	}
+	noon := time.Date(2024, 6, 14, 12, 0, 0, 0, time.UTC)
+	next, due := nextDue(entries, noon)
+	require.Equal(t, time.Date(2024, 6, 14, 20, 0, 0, 0, time.UTC), next)
+	require.Equal(t, entries[1:3], due)
+
+	require.Equal(t, []string{"big"}, missedLoads(entries, noon))
+	require.Equal(t, []string{"batch"}, missedLoads(entries, noon.Add(10*time.Hour)))
+	// An entry due right now counts as run.
+	require.Equal(t, []string{"batch"}, missedLoads(entries, time.Date(2024, 6, 14, 20, 0, 0, 0, time.UTC)))
+}
+
+func TestSchedulerLoadsMissedModelsAtStart(t *testing.T) {
+	now := time.Now()
+	entries := []ScheduleEntry{
+		{Hour: now.Hour(), Minute: now.Minute(), Action: ScheduleLoad, Model: "big"},
+	}
+	loaded := make(chan string, 1)
+	load := func(ctx context.Context, model string) error {
+		loaded <- model
+		return nil
+	}
+	unload := func(model string) error { return nil }
+	s := NewScheduler(entries, load, unload, logging.NewSprintfLogger())
+	defer s.Stop()
+	require.Equal(t, "big", <-loaded)
+}
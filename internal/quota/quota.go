@@ -0,0 +1,251 @@
+// Package quota enforces per-client token budgets over calendar days and
+// months in UTC. A request is admitted while its client's budget is not
+// used up and its tokens are counted when it finishes, so requests running
+// when the budget runs out may overshoot it.
+package quota
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Limits are token budgets. In Options.Limits, 0 leaves a period to
+// Options.Default and Unlimited lifts its cap; in Options.Default both mean
+// unlimited.
+type Limits struct {
+	Daily   int64
+	Monthly int64
+}
+
+// Unlimited is a budget without a cap.
+const Unlimited int64 = -1
+
+// Options configures a Tracker.
+type Options struct {
+	// Limits maps client keys (API keys) to their budgets.
+	Limits map[string]Limits
+	// Default applies to clients not in Limits, including anonymous ones,
+	// which share the empty key, and to the periods a client's Limits
+	// leave at 0.
+	Default Limits
+}
+
+// ErrQuotaExceeded matches every *ExceededError.
+var ErrQuotaExceeded = errors.New("token quota exceeded")
+
+// ExceededError is returned for a client whose budget is used up.
+type ExceededError struct {
+	Period  string // "daily" or "monthly"
+	Limit   int64
+	Used    int64
+	ResetAt time.Time // start of the next period
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s token quota exceeded: %d of %d tokens used, resets at %s",
+		e.Period, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Usage is a client's token count in the current day and month.
+type Usage struct {
+	Day         string `json:"day"` // 2006-01-02
+	DayTokens   int64  `json:"day_tokens"`
+	Month       string `json:"month"` // 2006-01
+	MonthTokens int64  `json:"month_tokens"`
+}
+
+// Tracker counts tokens per client and checks them against the budgets. It is
+// safe for concurrent use. A nil *Tracker admits everything.
+type Tracker struct {
+	opts Options
+	now  func() time.Time
+
+	mx    sync.Mutex
+	usage map[string]*Usage // by hashed client key, see clientID
+
+	saveMx sync.Mutex // orders concurrent Saves
+}
+
+// New creates a tracker with no usage recorded.
+func New(opts Options) *Tracker {
+	return &Tracker{
+		opts:  opts,
+		now:   time.Now,
+		usage: make(map[string]*Usage),
+	}
+}
+
+func (t *Tracker) limits(key string) Limits {
+	l, ok := t.opts.Limits[key]
+	if !ok {
+		return t.opts.Default
+	}
+	if l.Daily == 0 {
+		l.Daily = t.opts.Default.Daily
+	}
+	if l.Monthly == 0 {
+		l.Monthly = t.opts.Default.Monthly
+	}
+	return l
+}
+
+// clientID hashes a client key, so saved usage does not reveal API keys.
+func clientID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// current returns the usage of key, reset if its day or month has passed,
+// and records it for Add.
+func (t *Tracker) current(key string, now time.Time) *Usage {
+	id := clientID(key)
+	u, ok := t.usage[id]
+	if !ok {
+		u = &Usage{}
+		t.usage[id] = u
+	}
+	*u = t.peek(key, now)
+	return u
+}
+
+// peek returns the usage of key like current, without recording anything,
+// so that clients that never used a token take no space.
+func (t *Tracker) peek(key string, now time.Time) Usage {
+	day, month := now.Format("2006-01-02"), now.Format("2006-01")
+	var u Usage
+	if p, ok := t.usage[clientID(key)]; ok {
+		u = *p
+	}
+	if u.Day != day {
+		u.Day, u.DayTokens = day, 0
+	}
+	if u.Month != month {
+		u.Month, u.MonthTokens = month, 0
+	}
+	return u
+}
+
+// Check returns an *ExceededError if the budget of key is used up.
+func (t *Tracker) Check(key string) error {
+	if t == nil {
+		return nil
+	}
+	limits := t.limits(key)
+	if limits.Daily <= 0 && limits.Monthly <= 0 {
+		return nil
+	}
+	now := t.now().UTC()
+	t.mx.Lock()
+	u := t.peek(key, now)
+	t.mx.Unlock()
+
+	if limits.Monthly > 0 && u.MonthTokens >= limits.Monthly {
+		return &ExceededError{
+			Period:  "monthly",
+			Limit:   limits.Monthly,
+			Used:    u.MonthTokens,
+			ResetAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	if limits.Daily > 0 && u.DayTokens >= limits.Daily {
+		return &ExceededError{
+			Period:  "daily",
+			Limit:   limits.Daily,
+			Used:    u.DayTokens,
+			ResetAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return nil
+}
+
+// Add counts tokens used by key.
+func (t *Tracker) Add(key string, tokens int) {
+	if t == nil || tokens <= 0 {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	u := t.current(key, t.now().UTC())
+	u.DayTokens += int64(tokens)
+	u.MonthTokens += int64(tokens)
+}
+
+// Usage returns the tokens key has used in the current day and month.
+func (t *Tracker) Usage(key string) Usage {
+	if t == nil {
+		return Usage{}
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return t.peek(key, t.now().UTC())
+}
+
+// Save writes the usage of all clients to path, replacing the file
+// atomically. Clients are identified by a hash of their key. Clients that
+// used no tokens this month are dropped first. Of concurrent calls, the last
+// one writes the most recent usage.
+func (t *Tracker) Save(path string) error {
+	t.saveMx.Lock()
+	defer t.saveMx.Unlock()
+	month := t.now().UTC().Format("2006-01")
+	t.mx.Lock()
+	for id, u := range t.usage {
+		// Its day has passed as well.
+		if u.Month != month {
+			delete(t.usage, id)
+		}
+	}
+	data, err := json.Marshal(t.usage)
+	t.mx.Unlock()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load reads usage written by Save. A missing file is not an error.
+func (t *Tracker) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	usage := make(map[string]*Usage)
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for id, u := range usage {
+		if u == nil {
+			delete(usage, id)
+		}
+	}
+	t.mx.Lock()
+	t.usage = usage
+	t.mx.Unlock()
+	return nil
+}
@@ -0,0 +1,137 @@
+package quota
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTracker(opts Options, now *time.Time) *Tracker {
+	t := New(opts)
+	t.now = func() time.Time { return *now }
+	return t
+}
+
+func TestDailyQuota(t *testing.T) {
+	now := time.Date(2026, 3, 31, 22, 0, 0, 0, time.UTC)
+	tr := newTestTracker(Options{Limits: map[string]Limits{"a": {Daily: 100}}}, &now)
+
+	require.NoError(t, tr.Check("a"))
+	tr.Add("a", 60)
+	require.NoError(t, tr.Check("a"))
+	tr.Add("a", 60) // a running request may overshoot
+
+	err := tr.Check("a")
+	require.True(t, errors.Is(err, ErrQuotaExceeded))
+	var exceeded *ExceededError
+	require.True(t, errors.As(err, &exceeded))
+	require.Equal(t, "daily", exceeded.Period)
+	require.Equal(t, int64(100), exceeded.Limit)
+	require.Equal(t, int64(120), exceeded.Used)
+	require.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), exceeded.ResetAt)
+
+	// Other clients are not limited without a default.
+	tr.Add("b", 1000)
+	require.NoError(t, tr.Check("b"))
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, tr.Check("a"))
+	require.Equal(t, Usage{Day: "2026-04-01", Month: "2026-04"}, tr.Usage("a"))
+}
+
+func TestMonthlyQuota(t *testing.T) {
+	now := time.Date(2026, 12, 30, 12, 0, 0, 0, time.UTC)
+	tr := newTestTracker(Options{Default: Limits{Daily: 100, Monthly: 150}}, &now)
+	tr.Add("", 90)
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, tr.Check(""))
+	tr.Add("", 90)
+
+	var exceeded *ExceededError
+	require.True(t, errors.As(tr.Check(""), &exceeded))
+	require.Equal(t, "monthly", exceeded.Period)
+	require.Equal(t, int64(180), exceeded.Used)
+	require.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), exceeded.ResetAt)
+
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, tr.Check(""))
+}
+
+func TestSaveLoad(t *testing.T) {
+	now := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tr := newTestTracker(Options{Default: Limits{Daily: 10}}, &now)
+	require.NoError(t, tr.Load(path)) // missing file
+	tr.Add("secret-key", 12)
+	require.NoError(t, tr.Save(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret-key")
+
+	loaded := newTestTracker(Options{Default: Limits{Daily: 10}}, &now)
+	require.NoError(t, loaded.Load(path))
+	require.Error(t, loaded.Check("secret-key"))
+	require.Equal(t, int64(12), loaded.Usage("secret-key").MonthTokens)
+}
+
+func TestNilTracker(t *testing.T) {
+	var tr *Tracker
+	require.NoError(t, tr.Check("a"))
+	tr.Add("a", 10)
+	require.Equal(t, Usage{}, tr.Usage("a"))
+}
+
+func TestKeyLimitsFallBackToDefault(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	tr := newTestTracker(Options{
+		Limits: map[string]Limits{
+			"daily-only": {Daily: 1000},
+			"unlimited":  {Daily: Unlimited},
+		},
+		Default: Limits{Daily: 100, Monthly: 500},
+	}, &now)
+
+	// A key that sets only its daily budget keeps the default monthly one.
+	for range 6 {
+		tr.Add("daily-only", 100)
+		now = now.Add(24 * time.Hour)
+	}
+	var exceeded *ExceededError
+	require.True(t, errors.As(tr.Check("daily-only"), &exceeded))
+	require.Equal(t, "monthly", exceeded.Period)
+	require.Equal(t, int64(500), exceeded.Limit)
+
+	// Unlimited lifts the default's daily cap for the key.
+	tr.Add("unlimited", 400)
+	require.NoError(t, tr.Check("unlimited"))
+	tr.Add("unlimited", 200)
+	require.True(t, errors.As(tr.Check("unlimited"), &exceeded))
+	require.Equal(t, "monthly", exceeded.Period)
+}
+
+func TestUsageOfIdleClientsIsNotKept(t *testing.T) {
+	now := time.Date(2026, 7, 31, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tr := newTestTracker(Options{Default: Limits{Daily: 10}}, &now)
+
+	// Checks of clients that never use a token record nothing.
+	require.NoError(t, tr.Check("idle"))
+	require.Equal(t, Usage{Day: "2026-07-31", Month: "2026-07"}, tr.Usage("idle"))
+	require.Empty(t, tr.usage)
+
+	tr.Add("old", 5)
+	now = now.Add(24 * time.Hour)
+	tr.Add("new", 5)
+	require.NoError(t, tr.Save(path))
+
+	// Usage of a past month is dropped on save.
+	loaded := newTestTracker(Options{}, &now)
+	require.NoError(t, loaded.Load(path))
+	require.Len(t, loaded.usage, 1)
+	require.Equal(t, int64(5), loaded.Usage("new").DayTokens)
+}
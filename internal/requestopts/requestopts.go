@@ -0,0 +1,37 @@
+// Package requestopts reads the request options that the gRPC and HTTP APIs
+// take in the same form, so that both servers interpret them alike.
+package requestopts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/internal/inferenceengine"
+)
+
+// ClientKey returns the caller's API key given the values of the x-api-key
+// and authorization headers, or of the gRPC metadata of the same names. The
+// authorization value must be a bearer token. Empty if the caller is
+// anonymous.
+func ClientKey(apiKey, authorization string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	if key, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return key
+	}
+	return ""
+}
+
+// StreamInterval is implemented by the prediction options of both APIs.
+// The getters return 0 for unset options and for nil options.
+type StreamInterval interface {
+	GetStreamIntervalTokens() int32
+	GetStreamIntervalMs() int32
+}
+
+// NewCoalescer builds a token coalescer from the stream interval options.
+func NewCoalescer(stream inferenceengine.StreamFunc, opts StreamInterval) *inferenceengine.Coalescer {
+	every := time.Duration(opts.GetStreamIntervalMs()) * time.Millisecond
+	return inferenceengine.NewCoalescer(stream, int(opts.GetStreamIntervalTokens()), every)
+}
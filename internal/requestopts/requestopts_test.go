@@ -0,0 +1,15 @@
+package requestopts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientKey(t *testing.T) {
+	require.Equal(t, "key", ClientKey("key", ""))
+	require.Equal(t, "key", ClientKey("key", "Bearer other"))
+	require.Equal(t, "token", ClientKey("", "Bearer token"))
+	require.Equal(t, "", ClientKey("", "Basic dXNlcjpwYXNz"))
+	require.Equal(t, "", ClientKey("", ""))
+}
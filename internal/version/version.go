@@ -0,0 +1,63 @@
+// Package version holds build information of the server binary. The
+// variables are set at link time by the Makefile, e.g.
+//
+//	go build -ldflags "-X github.com/hypernetix/llamacpp_server/internal/version.Version=v1.2.3"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version is the server release, normally from "git describe".
+	Version = "dev"
+	// Commit is the git commit the server was built from. If not set at
+	// link time it is taken from the VCS info the Go toolchain embeds.
+	Commit = ""
+	// LlamaCppVersion is the llama.cpp release (build number tag, e.g.
+	// "b8323") the server was linked against.
+	LlamaCppVersion = "unknown"
+)
+
+// Info describes the running build.
+type Info struct {
+	Version         string
+	Commit          string
+	GoVersion       string
+	LlamaCppVersion string
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	return Info{
+		Version:         Version,
+		Commit:          commit(),
+		GoVersion:       runtime.Version(),
+		LlamaCppVersion: LlamaCppVersion,
+	}
+}
+
+func commit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if revision != "" {
+			if modified == "true" {
+				return revision + "-dirty"
+			}
+			return revision
+		}
+	}
+	return "unknown"
+}
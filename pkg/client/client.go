@@ -0,0 +1,226 @@
+// Package client talks to a llamacpp_server over gRPC or HTTP, attaching to
+// a running server or spawning one. Predictions are read as a PredictStream,
+// or through a channel with PredictChan.
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phayes/freeport"
+)
+
+type PredictRequest struct {
+	ModelName         string
+	Message           string
+	Temperature       float64
+	MaxTokens         int
+	Stream            bool
+	TopP              *float64
+	TopK              *int
+	MinP              *float64
+	RepetitionPenalty *float64
+	RandomSeed        *int
+
+	// The remaining proto.PredictRequest_Options fields; nil (or empty) leaves
+	// the server default.
+	MinTokensToKeep      *int
+	MaxKvSize            *int
+	PrefillStepSize      *int
+	KvBits               *int
+	KvGroupSize          *int
+	QuantizedKvStart     *int
+	LengthPenalty        *float64
+	DiversityPenalty     *float64
+	NoRepeatNgramSize    *int
+	StreamIntervalTokens *int
+	StreamIntervalMs     *int
+	PromptLookup         *int
+	Regex                *string
+	StopTokenIDs         []int
+	BannedStrings        []string
+}
+
+// hasOptions tells whether any field that maps to the request options is set.
+func (r PredictRequest) hasOptions() bool {
+	return r.MinP != nil || r.RepetitionPenalty != nil || r.RandomSeed != nil ||
+		r.MinTokensToKeep != nil || r.MaxKvSize != nil || r.PrefillStepSize != nil ||
+		r.KvBits != nil || r.KvGroupSize != nil || r.QuantizedKvStart != nil ||
+		r.LengthPenalty != nil || r.DiversityPenalty != nil || r.NoRepeatNgramSize != nil ||
+		r.StreamIntervalTokens != nil || r.StreamIntervalMs != nil || r.PromptLookup != nil ||
+		r.Regex != nil || len(r.StopTokenIDs) > 0 || len(r.BannedStrings) > 0
+}
+
+func float32Ptr(v *float64) *float32 {
+	if v == nil {
+		return nil
+	}
+	f := float32(*v)
+	return &f
+}
+
+func int32Ptr(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	i := int32(*v)
+	return &i
+}
+
+type PredictResponse struct {
+	Message string
+	Token   int32
+	Tokens  int32
+	// Error and Done are only set by PredictChan, on the last response
+	// before it closes the channel.
+	Error error
+	Done  bool
+}
+
+// Client is a connection to a server, safe for concurrent use.
+type Client interface {
+	// Shutdown closes the connection and stops a spawned server at once,
+	// aborting running streams.
+	Shutdown()
+	// ShutdownGraceful refuses new calls with ErrShuttingDown, waits until
+	// the running ones finish or ctx is done, then shuts down. It returns
+	// ctx.Err() if streams had to be aborted.
+	ShutdownGraceful(ctx context.Context) error
+	Ping(ctx context.Context) error
+	// LoadModel loads a model, forwarding progress to progress (which may be
+	// nil). It returns ctx.Err() if ctx is done first and wraps ErrLoadFailed
+	// if the server fails the load.
+	LoadModel(ctx context.Context, name string, progress chan<- LoadProgress) (LoadModelResult, error)
+	// Predict starts a prediction, which the caller reads with Recv until
+	// it returns an error (io.EOF at the end) and then closes. See
+	// PredictChan for a channel instead.
+	Predict(ctx context.Context, req PredictRequest) (PredictStream, error)
+	// Embed returns the embeddings of texts, in their order.
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// Transport is the protocol a Client talks to the server with.
+type Transport string
+
+const (
+	TransportGRPC Transport = "grpc"
+	TransportHTTP Transport = "http"
+)
+
+// Options configure a Client. Either ServerPath, to spawn a server, or
+// AttachPort, to attach to a running one, must be set.
+type Options struct {
+	ServerPath string
+	AttachHost string // defaults to 127.0.0.1
+	AttachPort int
+	Transport  Transport // defaults to TransportGRPC
+	NParallel  int       // --n-parallel of a spawned server, 0 for its default
+
+	// GRPCConns is the number of gRPC connections to the server, which calls
+	// are spread over round-robin; 0 or 1 uses a single connection. More
+	// connections help many parallel streams, which otherwise share one
+	// HTTP/2 flow-control window.
+	GRPCConns int
+
+	// Logger receives the client's logs; nil discards them.
+	Logger Logger
+}
+
+// Option changes an Options field; New applies them over its Options.
+type Option func(*Options)
+
+// WithServer spawns the server executable at path.
+func WithServer(path string) Option {
+	return func(o *Options) { o.ServerPath = path }
+}
+
+// WithAttach attaches to the server listening on host:port.
+func WithAttach(host string, port int) Option {
+	return func(o *Options) { o.AttachHost, o.AttachPort = host, port }
+}
+
+func WithTransport(t Transport) Option {
+	return func(o *Options) { o.Transport = t }
+}
+
+func WithNParallel(n int) Option {
+	return func(o *Options) { o.NParallel = n }
+}
+
+func WithGRPCConns(n int) Option {
+	return func(o *Options) { o.GRPCConns = n }
+}
+
+func WithLogger(logger Logger) Option {
+	return func(o *Options) { o.Logger = logger }
+}
+
+// New creates a Client from options with opts applied over them. It spawns
+// the server if options ask for it; the server may still be starting when
+// New returns, so callers Ping it until it answers.
+func New(options Options, opts ...Option) (Client, error) {
+	for _, opt := range opts {
+		opt(&options)
+	}
+	initialLogger := options.Logger
+	if initialLogger == nil {
+		initialLogger = nopLogger{}
+	}
+	logger := withModule(initialLogger, "client")
+
+	host := options.AttachHost
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	transport := options.Transport
+	if transport == "" {
+		transport = TransportGRPC
+	}
+	if transport != TransportGRPC && transport != TransportHTTP {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTransport, transport)
+	}
+	if options.ServerPath == "" && options.AttachPort == 0 {
+		return nil, fmt.Errorf("client: ServerPath or AttachPort is required")
+	}
+
+	useHTTP := transport == TransportHTTP
+	// Attach to an existing server
+	if options.AttachPort > 0 {
+		if useHTTP {
+			logger.Infof("Attaching to existing HTTP server at %s:%d", host, options.AttachPort)
+			return newHTTPClient(host, options.AttachPort, nil, logger)
+		}
+		logger.Infof("Attaching to existing gRPC server at %s:%d", host, options.AttachPort)
+		return newGRPCClient(host, options.AttachPort, options.GRPCConns, nil, logger)
+	}
+
+	// Spawn a new server process
+	port, err := freeport.GetFreePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed allocating free port: %v", err)
+	}
+
+	var portFlag string
+	if useHTTP {
+		portFlag = "--http-port"
+	} else {
+		portFlag = "--grpc-port"
+	}
+
+	args := []string{portFlag, fmt.Sprintf("%d", port)}
+	if options.NParallel > 0 {
+		args = append(args, "--n-parallel", fmt.Sprintf("%d", options.NParallel))
+	}
+	logger.Infof("Spawning server with %s transport on port %d (nParallel=%d)", transport, port, options.NParallel)
+
+	serverProcess, err := NewProcess(initialLogger, options.ServerPath, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server process: %v", err)
+	}
+
+	if useHTTP {
+		return newHTTPClient(host, port, serverProcess, logger)
+	}
+	return newGRPCClient(host, port, options.GRPCConns, serverProcess, logger)
+}
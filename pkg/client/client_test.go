@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOptions(t *testing.T) {
+	_, err := New(Options{})
+	require.Error(t, err)
+
+	_, err = New(Options{AttachPort: 1}, WithTransport("udp"))
+	require.ErrorIs(t, err, ErrInvalidTransport)
+
+	// Functional options apply over the struct.
+	c, err := New(Options{Transport: TransportGRPC}, WithAttach("127.0.0.1", 1), WithTransport(TransportHTTP))
+	require.NoError(t, err)
+	t.Cleanup(c.Shutdown)
+	require.IsType(t, &httpClient{}, c)
+	require.Equal(t, "http://127.0.0.1:1", c.(*httpClient).baseURL)
+}
+
+func TestPredictRequiresStream(t *testing.T) {
+	c, err := New(Options{AttachPort: 1, Transport: TransportHTTP})
+	require.NoError(t, err)
+	t.Cleanup(c.Shutdown)
+	_, err = c.Predict(context.Background(), PredictRequest{})
+	require.ErrorIs(t, err, ErrStreamRequired)
+}
+
+func TestHTTPPredictServerError(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"max_tokens must be positive"}`)
+	})
+
+	_, err := c.Predict(context.Background(), PredictRequest{Stream: true})
+	var serverErr *ServerError
+	require.ErrorAs(t, err, &serverErr)
+	require.Equal(t, "400 Bad Request", serverErr.Code)
+	require.Equal(t, "max_tokens must be positive", serverErr.Message)
+}
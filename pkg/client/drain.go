@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// inflight tracks running calls so a graceful shutdown can wait for them.
+type inflight struct {
+	mu      sync.Mutex
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// begin registers a call; the caller must call end once it is done,
+// including the end of any stream it started.
+func (f *inflight) begin() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closing {
+		return ErrShuttingDown
+	}
+	f.wg.Add(1)
+	return nil
+}
+
+func (f *inflight) end() {
+	f.wg.Done()
+}
+
+// drain refuses new calls and waits until the running ones end or ctx is
+// done.
+func (f *inflight) drain(ctx context.Context) error {
+	f.mu.Lock()
+	f.closing = true
+	f.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownGracefulWaitsForStream(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"message\":\"a\",\"token\":1,\"tokens\":1}\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := c.Predict(context.Background(), PredictRequest{Stream: true})
+	require.NoError(t, err)
+	defer stream.Close()
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "a", resp.Message)
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- c.ShutdownGraceful(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		_, err := c.Predict(context.Background(), PredictRequest{Stream: true})
+		return err == ErrShuttingDown
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown returned while a stream was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+	require.NoError(t, <-shutdownDone)
+}
+
+func TestShutdownGracefulTimeoutAbortsStream(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"message\":\"a\",\"token\":1,\"tokens\":1}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	stream, err := c.Predict(context.Background(), PredictRequest{Stream: true})
+	require.NoError(t, err)
+	defer stream.Close()
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "a", resp.Message)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, c.ShutdownGraceful(ctx), context.DeadlineExceeded)
+
+	_, err = stream.Recv()
+	require.Error(t, err)
+	require.NotEqual(t, io.EOF, err)
+}
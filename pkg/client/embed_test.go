@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPEmbed(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/embeddings", r.URL.Path)
+		var req httpEmbeddingsRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "m", req.Model)
+		require.Equal(t, []string{"a", "b"}, req.Input)
+		fmt.Fprint(w, `{"embeddings":[[1,0],[0,1]],"prompt_tokens":2}`)
+	})
+
+	embeddings, err := c.Embed(context.Background(), "m", []string{"a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{1, 0}, {0, 1}}, embeddings)
+}
+
+func TestHTTPEmbedServerError(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	})
+
+	_, err := c.Embed(context.Background(), "m", []string{"a"})
+	var serverErr *ServerError
+	require.ErrorAs(t, err, &serverErr)
+	require.Equal(t, "404 Not Found", serverErr.Code)
+	require.Equal(t, "model not found", serverErr.Message)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrShuttingDown is returned for calls made after a graceful shutdown
+	// began.
+	ErrShuttingDown = errors.New("client is shutting down")
+	// ErrLoadFailed is returned when the server reports that a model could
+	// not be loaded; the server's message is wrapped after it.
+	ErrLoadFailed = errors.New("model load failed")
+	// ErrInvalidTransport is returned by New for a transport other than
+	// TransportGRPC and TransportHTTP.
+	ErrInvalidTransport = errors.New("invalid transport")
+	// ErrStreamRequired is returned by Predict for a request without Stream
+	// set; the client only reads predictions as streams.
+	ErrStreamRequired = errors.New("only streaming predictions are supported")
+)
+
+// ServerError is a call the server rejected or failed. Errors reaching the
+// server, and ctx errors of canceled calls, are returned as they are.
+type ServerError struct {
+	// Code is the gRPC status code, such as "NotFound", or the HTTP status,
+	// such as "404 Not Found". It is empty for an error event of an HTTP
+	// stream, which the server sends after answering 200.
+	Code    string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	if e.Code == "" {
+		return "server error: " + e.Message
+	}
+	return fmt.Sprintf("server error (%s): %s", e.Code, e.Message)
+}
+
+// grpcError returns err of a gRPC call as a ServerError if the server
+// answered it, or ctx.Err() if the call was canceled.
+func grpcError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unavailable {
+		return &ServerError{Code: st.Code().String(), Message: st.Message()}
+	}
+	return err
+}
+
+// httpError returns the ServerError of a non-OK response with body; the
+// server sends its message as {"error": "..."}.
+func httpError(resp *http.Response, body []byte) *ServerError {
+	msg := strings.TrimSpace(string(body))
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		msg = payload.Error
+	}
+	return &ServerError{Code: resp.Status, Message: msg}
+}
@@ -0,0 +1,295 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"google.golang.org/grpc"
+)
+
+type grpcClient struct {
+	once          sync.Once
+	inflight      inflight
+	serverProcess Process
+	logger        Logger
+
+	// conns are the connections to the server and clients their stubs;
+	// calls are spread over them round-robin through next, so parallel
+	// streams do not share the flow-control window of a single connection.
+	conns   []*grpc.ClientConn
+	clients []proto.LLMServerClient
+	next    atomic.Uint32
+}
+
+func newGRPCClient(host string, port int, nConns int, serverProcess Process, logger Logger) (Client, error) {
+	address := fmt.Sprintf("%s:%d", host, port)
+	nConns = max(nConns, 1)
+	logger.Debugf("Dialing gRPC server at %s (%d connections)", address, nConns)
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithReadBufferSize(1 * 1024 * 1024),
+		grpc.WithInitialWindowSize(1 * 1024 * 1024),
+		grpc.WithInitialConnWindowSize(1 * 1024 * 1024),
+	}
+
+	c := &grpcClient{
+		serverProcess: serverProcess,
+		logger:        logger,
+	}
+	for range nConns {
+		conn, err := grpc.NewClient(address, dialOpts...)
+		if err != nil {
+			c.Shutdown()
+			return nil, err
+		}
+		c.conns = append(c.conns, conn)
+		c.clients = append(c.clients, proto.NewLLMServerClient(conn))
+	}
+
+	logger.Debugf("Connected to gRPC server at %s", address)
+
+	return c, nil
+}
+
+// client returns the stub of the connection the next call goes to.
+func (c *grpcClient) client() proto.LLMServerClient {
+	return c.clients[(c.next.Add(1)-1)%uint32(len(c.clients))]
+}
+
+func (c *grpcClient) Shutdown() {
+	c.once.Do(func() {
+		for _, conn := range c.conns {
+			conn.Close()
+		}
+		if c.serverProcess != nil {
+			c.serverProcess.Stop()
+			c.serverProcess = nil
+		}
+	})
+}
+
+// ShutdownGraceful lets running calls and streams finish before shutting
+// down; once ctx is done the remaining ones are aborted.
+func (c *grpcClient) ShutdownGraceful(ctx context.Context) error {
+	err := c.inflight.drain(ctx)
+	c.Shutdown()
+	return err
+}
+
+func (c *grpcClient) Ping(ctx context.Context) error {
+	_, err := c.client().Ping(ctx, &proto.PingRequest{})
+	if err != nil {
+		c.logger.Errorf("Ping: gRPC call failed: %v", err)
+		return err
+	}
+	c.logger.Debugf("Ping done")
+	return nil
+}
+
+func (c *grpcClient) LoadModel(ctx context.Context, name string, progress chan<- LoadProgress) (LoadModelResult, error) {
+	c.logger.Infof("LoadModel: %s", name)
+	if err := c.inflight.begin(); err != nil {
+		return LoadModelResult{}, err
+	}
+	defer c.inflight.end()
+	tracker := newLoadTracker(name, progress)
+
+	stream, err := c.client().LoadModel(ctx, &proto.LoadModelRequest{Path: name})
+	if err != nil {
+		return LoadModelResult{}, loadError(ctx, err)
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return LoadModelResult{}, loadError(ctx, err)
+		}
+		err = tracker.update(ctx, LoadProgress{
+			Fraction:    msg.Progress,
+			Stage:       loadStageName(msg.Stage),
+			BytesLoaded: msg.BytesLoaded,
+			BytesTotal:  msg.BytesTotal,
+			ETA:         time.Duration(msg.EtaMs * float32(time.Millisecond)),
+		})
+		if err != nil {
+			return LoadModelResult{}, err
+		}
+	}
+
+	result := tracker.done()
+	c.logger.Infof("LoadModel: loaded %s in %s", name, result.Duration)
+	return result, nil
+}
+
+// loadError tells a canceled load from one the server failed.
+func loadError(ctx context.Context, err error) error {
+	err = grpcError(ctx, err)
+	if serverErr, ok := err.(*ServerError); ok {
+		return fmt.Errorf("%w: %w", ErrLoadFailed, serverErr)
+	}
+	return err
+}
+
+func loadStageName(stage proto.LoadStage) string {
+	return strings.ToLower(strings.TrimPrefix(stage.String(), "LOAD_STAGE_"))
+}
+
+func (c *grpcClient) Predict(ctx context.Context, req PredictRequest) (PredictStream, error) {
+	c.logger.Infof("Predict: model=%s, max_tokens=%d, temp=%.3f",
+		req.ModelName, req.MaxTokens, req.Temperature)
+
+	if !req.Stream {
+		return nil, ErrStreamRequired
+	}
+	if err := c.inflight.begin(); err != nil {
+		return nil, err
+	}
+
+	protoReq := buildProtoRequest(req)
+
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	predictStream, err := c.client().Predict(streamCtx, protoReq)
+	if err != nil {
+		cancelStream()
+		c.inflight.end()
+		c.logger.Errorf("Predict: gRPC call failed: %v", err)
+		return nil, grpcError(ctx, err)
+	}
+	return &grpcPredictStream{
+		stream: predictStream,
+		ctx:    streamCtx,
+		logger: c.logger,
+		release: streamRelease{release: func() {
+			cancelStream()
+			c.inflight.end()
+		}},
+	}, nil
+}
+
+// grpcPredictStream reads the responses of a Predict call, skipping the
+// progress and timings messages.
+type grpcPredictStream struct {
+	stream  proto.LLMServer_PredictClient
+	ctx     context.Context
+	logger  Logger
+	release streamRelease
+}
+
+func (s *grpcPredictStream) Recv() (PredictResponse, error) {
+	for {
+		msg, err := s.stream.Recv()
+		if err != nil {
+			if err != io.EOF {
+				s.logger.Errorf("Predict: stream recv failed: %v", err)
+				err = grpcError(s.ctx, err)
+			}
+			s.release.do()
+			return PredictResponse{}, err
+		}
+		if l := msg.LoadProgress; l != nil {
+			s.logger.Debugf("Predict: auto-loading model %.0f%% (%s)", l.Progress*100, l.Stage)
+			continue
+		}
+		if p := msg.PrefillProgress; p != nil {
+			s.logger.Debugf("Predict: prefill %d/%d", p.Processed, p.Total)
+			continue
+		}
+		if t := msg.Timings; t != nil {
+			s.logger.Infof("Predict: finished (%s, prompt=%d, completion=%d tokens)",
+				msg.FinishReason, msg.PromptTokens, msg.CompletionTokens)
+			s.logger.Infof("Predict: server timings: queue=%.1fms, ttft=%.1fms, inter-token mean=%.1fms p99=%.1fms",
+				t.QueueMs, t.TimeToFirstTokenMs, t.InterTokenLatency.GetMeanMs(), t.InterTokenLatency.GetP99Ms())
+			continue
+		}
+		return PredictResponse{
+			Message: string(msg.Message),
+			Token:   msg.Token,
+			Tokens:  msg.Tokens,
+		}, nil
+	}
+}
+
+func (s *grpcPredictStream) Close() error {
+	s.release.do()
+	return nil
+}
+
+func (c *grpcClient) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if err := c.inflight.begin(); err != nil {
+		return nil, err
+	}
+	defer c.inflight.end()
+
+	resp, err := c.client().Embed(ctx, &proto.EmbedRequest{Model: model, Texts: texts})
+	if err != nil {
+		c.logger.Errorf("Embed: gRPC call failed: %v", err)
+		return nil, grpcError(ctx, err)
+	}
+	embeddings := make([][]float32, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		embeddings[i] = e.Values
+	}
+	c.logger.Debugf("Embed: %d texts, %d prompt tokens", len(texts), resp.PromptTokens)
+	return embeddings, nil
+}
+
+func buildProtoRequest(req PredictRequest) *proto.PredictRequest {
+	topP := float32(1.0)
+	if req.TopP != nil {
+		topP = float32(*req.TopP)
+	}
+	topK := int32(0)
+	if req.TopK != nil {
+		topK = int32(*req.TopK)
+	}
+
+	protoReq := &proto.PredictRequest{
+		Model:       req.ModelName,
+		Prompt:      req.Message,
+		Stream:      true,
+		MaxTokens:   int32(req.MaxTokens),
+		Temperature: float32(req.Temperature),
+		TopP:        topP,
+		TopK:        topK,
+	}
+
+	if req.hasOptions() {
+		stopTokenIDs := make([]int32, len(req.StopTokenIDs))
+		for i, id := range req.StopTokenIDs {
+			stopTokenIDs[i] = int32(id)
+		}
+		protoReq.Options = &proto.PredictRequest_Options{
+			MinP:                 float32Ptr(req.MinP),
+			MinTokensToKeep:      int32Ptr(req.MinTokensToKeep),
+			MaxKvSize:            int32Ptr(req.MaxKvSize),
+			PrefillStepSize:      int32Ptr(req.PrefillStepSize),
+			KvBits:               int32Ptr(req.KvBits),
+			KvGroupSize:          int32Ptr(req.KvGroupSize),
+			QuantizedKvStart:     int32Ptr(req.QuantizedKvStart),
+			RepetitionPenalty:    float32Ptr(req.RepetitionPenalty),
+			LengthPenalty:        float32Ptr(req.LengthPenalty),
+			DiversityPenalty:     float32Ptr(req.DiversityPenalty),
+			NoRepeatNgramSize:    int32Ptr(req.NoRepeatNgramSize),
+			RandomSeed:           int32Ptr(req.RandomSeed),
+			StreamIntervalTokens: int32Ptr(req.StreamIntervalTokens),
+			StreamIntervalMs:     int32Ptr(req.StreamIntervalMs),
+			PromptLookup:         int32Ptr(req.PromptLookup),
+			Regex:                req.Regex,
+			StopTokenIds:         stopTokenIDs,
+			BannedStrings:        req.BannedStrings,
+		}
+	}
+
+	return protoReq
+}
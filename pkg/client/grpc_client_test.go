@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// peerServer records the client address of each Ping.
+type peerServer struct {
+	proto.UnimplementedLLMServerServer
+	mu    sync.Mutex
+	peers map[string]int
+}
+
+func (s *peerServer) Ping(ctx context.Context, _ *proto.PingRequest) (*proto.PingResponse, error) {
+	p, _ := peer.FromContext(ctx)
+	s.mu.Lock()
+	s.peers[p.Addr.String()]++
+	s.mu.Unlock()
+	return &proto.PingResponse{}, nil
+}
+
+func TestGRPCClientSpreadsCallsOverConns(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	ps := &peerServer{peers: map[string]int{}}
+	proto.RegisterLLMServerServer(srv, ps)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	c, err := newGRPCClient("127.0.0.1", lis.Addr().(*net.TCPAddr).Port, 3, nil, &testLogger{t: t})
+	require.NoError(t, err)
+	t.Cleanup(c.Shutdown)
+
+	for range 6 {
+		require.NoError(t, c.Ping(context.Background()))
+	}
+	require.Len(t, ps.peers, 3)
+	for _, n := range ps.peers {
+		require.Equal(t, 2, n)
+	}
+
+	// Calls the server answers with a status fail with a ServerError.
+	_, err = c.LoadModel(context.Background(), "m.gguf", nil)
+	require.ErrorIs(t, err, ErrLoadFailed)
+	var serverErr *ServerError
+	require.ErrorAs(t, err, &serverErr)
+	require.Equal(t, "Unimplemented", serverErr.Code)
+}
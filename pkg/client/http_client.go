@@ -0,0 +1,435 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type httpClient struct {
+	once          sync.Once
+	inflight      inflight
+	serverProcess Process
+	baseURL       string
+	client        *http.Client
+	logger        Logger
+
+	// closed is canceled by Shutdown to abort the requests still running.
+	closed      context.Context
+	closeCancel context.CancelFunc
+}
+
+func newHTTPClient(host string, port int, serverProcess Process, logger Logger) (Client, error) {
+	baseURL := fmt.Sprintf("http://%s:%d", host, port)
+	logger.Debugf("HTTP client targeting %s", baseURL)
+
+	closed, closeCancel := context.WithCancel(context.Background())
+	return &httpClient{
+		serverProcess: serverProcess,
+		baseURL:       baseURL,
+		client:        &http.Client{},
+		logger:        logger,
+		closed:        closed,
+		closeCancel:   closeCancel,
+	}, nil
+}
+
+// withClose returns a context that is also canceled by Shutdown.
+func (c *httpClient) withClose(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.closed, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (c *httpClient) Shutdown() {
+	c.once.Do(func() {
+		c.closeCancel()
+		c.client.CloseIdleConnections()
+		if c.serverProcess != nil {
+			c.serverProcess.Stop()
+			c.serverProcess = nil
+		}
+	})
+}
+
+// ShutdownGraceful lets running calls and streams finish before shutting
+// down; once ctx is done the remaining ones are aborted.
+func (c *httpClient) ShutdownGraceful(ctx context.Context) error {
+	err := c.inflight.drain(ctx)
+	c.Shutdown()
+	return err
+}
+
+func (c *httpClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.logger.Errorf("Ping: HTTP request failed: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("health check failed: %s %s", resp.Status, string(body))
+	}
+	c.logger.Debugf("Ping done (HTTP)")
+	return nil
+}
+
+type httpLoadModelEvent struct {
+	Progress    float32 `json:"progress"`
+	Stage       string  `json:"stage"`
+	BytesLoaded int64   `json:"bytes_loaded"`
+	BytesTotal  int64   `json:"bytes_total"`
+	EtaMs       float64 `json:"eta_ms"`
+}
+
+func (c *httpClient) LoadModel(ctx context.Context, name string, progress chan<- LoadProgress) (LoadModelResult, error) {
+	c.logger.Infof("LoadModel (HTTP): %s", name)
+	if err := c.inflight.begin(); err != nil {
+		return LoadModelResult{}, err
+	}
+	defer c.inflight.end()
+	tracker := newLoadTracker(name, progress)
+
+	reqCtx, cancel := c.withClose(ctx)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]string{"path": name})
+	req, err := http.NewRequestWithContext(reqCtx, "POST", c.baseURL+"/models/load", bytes.NewReader(body))
+	if err != nil {
+		return LoadModelResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return LoadModelResult{}, ctx.Err()
+		}
+		return LoadModelResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return LoadModelResult{}, fmt.Errorf("%w: %w", ErrLoadFailed, httpError(resp, respBody))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			if strings.HasPrefix(line, "event: error") {
+				if scanner.Scan() {
+					var msg string
+					errData := strings.TrimPrefix(scanner.Text(), "data: ")
+					if json.Unmarshal([]byte(errData), &msg) != nil {
+						msg = errData
+					}
+					return LoadModelResult{}, fmt.Errorf("%w: %w", ErrLoadFailed, &ServerError{Message: msg})
+				}
+			}
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "[DONE]" {
+			result := tracker.done()
+			c.logger.Infof("LoadModel (HTTP): loaded %s in %s", name, result.Duration)
+			return result, nil
+		}
+		var evt httpLoadModelEvent
+		if err := json.Unmarshal([]byte(data), &evt); err != nil {
+			c.logger.Warnf("LoadModel: failed to parse SSE event: %v", err)
+			continue
+		}
+		err := tracker.update(ctx, LoadProgress{
+			Fraction:    evt.Progress,
+			Stage:       evt.Stage,
+			BytesLoaded: evt.BytesLoaded,
+			BytesTotal:  evt.BytesTotal,
+			ETA:         time.Duration(evt.EtaMs * float64(time.Millisecond)),
+		})
+		if err != nil {
+			return LoadModelResult{}, err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return LoadModelResult{}, ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return LoadModelResult{}, fmt.Errorf("SSE stream read error: %v", err)
+	}
+	return LoadModelResult{}, fmt.Errorf("SSE stream ended before the load completed")
+}
+
+type httpCompletionRequest struct {
+	Model       string                 `json:"model"`
+	Prompt      string                 `json:"prompt"`
+	Stream      bool                   `json:"stream"`
+	MaxTokens   int                    `json:"max_tokens"`
+	Temperature float64                `json:"temperature"`
+	TopP        float64                `json:"top_p"`
+	TopK        int                    `json:"top_k"`
+	Options     *httpCompletionOptions `json:"options,omitempty"`
+}
+
+type httpCompletionOptions struct {
+	MinP              *float32 `json:"min_p,omitempty"`
+	MinTokensToKeep   *int32   `json:"min_tokens_to_keep,omitempty"`
+	MaxKvSize         *int32   `json:"max_kv_size,omitempty"`
+	PrefillStepSize   *int32   `json:"prefill_step_size,omitempty"`
+	KvBits            *int32   `json:"kv_bits,omitempty"`
+	KvGroupSize       *int32   `json:"kv_group_size,omitempty"`
+	QuantizedKvStart  *int32   `json:"quantized_kv_start,omitempty"`
+	RepetitionPenalty *float32 `json:"repetition_penalty,omitempty"`
+	LengthPenalty     *float32 `json:"length_penalty,omitempty"`
+	DiversityPenalty  *float32 `json:"diversity_penalty,omitempty"`
+	NoRepeatNgramSize *int32   `json:"no_repeat_ngram_size,omitempty"`
+	RandomSeed        *int32   `json:"random_seed,omitempty"`
+
+	StreamIntervalTokens *int32 `json:"stream_interval_tokens,omitempty"`
+	StreamIntervalMs     *int32 `json:"stream_interval_ms,omitempty"`
+
+	PromptLookup  *int32   `json:"prompt_lookup,omitempty"`
+	Regex         *string  `json:"regex,omitempty"`
+	StopTokenIDs  []int    `json:"stop_token_ids,omitempty"`
+	BannedStrings []string `json:"banned_strings,omitempty"`
+}
+
+type httpCompletionResponse struct {
+	Message string `json:"message"`
+	Token   int    `json:"token"`
+	Tokens  int    `json:"tokens"`
+}
+
+func (c *httpClient) Predict(ctx context.Context, req PredictRequest) (PredictStream, error) {
+	c.logger.Infof("Predict (HTTP): model=%s, max_tokens=%d, temp=%.3f",
+		req.ModelName, req.MaxTokens, req.Temperature)
+
+	if !req.Stream {
+		return nil, ErrStreamRequired
+	}
+
+	httpReq := buildHTTPRequest(req)
+
+	body, err := json.Marshal(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.inflight.begin(); err != nil {
+		return nil, err
+	}
+	reqCtx, cancel := c.withClose(ctx)
+	httpRequest, err := http.NewRequestWithContext(reqCtx, "POST", c.baseURL+"/completions", bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		c.inflight.end()
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.client.Do(httpRequest)
+	if err != nil {
+		cancel()
+		c.inflight.end()
+		c.logger.Errorf("Predict: HTTP request failed: %v", err)
+		return nil, err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer c.inflight.end()
+		defer cancel()
+		defer httpResp.Body.Close()
+		respBody, _ := io.ReadAll(httpResp.Body)
+		return nil, httpError(httpResp, respBody)
+	}
+
+	return &httpPredictStream{
+		scanner: bufio.NewScanner(httpResp.Body),
+		logger:  c.logger,
+		release: streamRelease{release: func() {
+			cancel()
+			httpResp.Body.Close()
+			c.inflight.end()
+		}},
+	}, nil
+}
+
+// httpPredictStream reads the SSE events of a /completions call, skipping
+// the progress and timings events.
+type httpPredictStream struct {
+	scanner *bufio.Scanner
+	logger  Logger
+	release streamRelease
+	err     error // returned by every Recv once the stream ended
+}
+
+func (s *httpPredictStream) Recv() (PredictResponse, error) {
+	if s.err != nil {
+		return PredictResponse{}, s.err
+	}
+	scanner := s.scanner
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			if strings.HasPrefix(line, "event: error") {
+				err := io.ErrUnexpectedEOF
+				if scanner.Scan() {
+					var msg string
+					errData := strings.TrimPrefix(scanner.Text(), "data: ")
+					if json.Unmarshal([]byte(errData), &msg) != nil {
+						msg = errData
+					}
+					err = &ServerError{Message: msg}
+				}
+				return s.end(err)
+			}
+			if strings.HasPrefix(line, "event: prefill") || strings.HasPrefix(line, "event: load") {
+				scanner.Scan() // progress payload, not a token
+			}
+			if strings.HasPrefix(line, "event: timings") && scanner.Scan() {
+				s.logger.Infof("Predict: server timings: %s", strings.TrimPrefix(scanner.Text(), "data: "))
+			}
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "[DONE]" {
+			return s.end(io.EOF)
+		}
+		var evt httpCompletionResponse
+		if err := json.Unmarshal([]byte(data), &evt); err != nil {
+			s.logger.Warnf("Predict: failed to parse SSE event: %v", err)
+			continue
+		}
+		return PredictResponse{
+			Message: evt.Message,
+			Token:   int32(evt.Token),
+			Tokens:  int32(evt.Tokens),
+		}, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return s.end(fmt.Errorf("SSE stream read error: %v", err))
+	}
+	return s.end(io.EOF)
+}
+
+// end ends the stream with err.
+func (s *httpPredictStream) end(err error) (PredictResponse, error) {
+	s.err = err
+	s.release.do()
+	return PredictResponse{}, err
+}
+
+func (s *httpPredictStream) Close() error {
+	s.release.do()
+	return nil
+}
+
+type httpEmbeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type httpEmbeddingsResponse struct {
+	Embeddings   [][]float32 `json:"embeddings"`
+	PromptTokens int         `json:"prompt_tokens"`
+}
+
+func (c *httpClient) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if err := c.inflight.begin(); err != nil {
+		return nil, err
+	}
+	defer c.inflight.end()
+
+	reqCtx, cancel := c.withClose(ctx)
+	defer cancel()
+
+	body, _ := json.Marshal(httpEmbeddingsRequest{Model: model, Input: texts})
+	req, err := http.NewRequestWithContext(reqCtx, "POST", c.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.logger.Errorf("Embed: HTTP request failed: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, httpError(resp, respBody)
+	}
+	var out httpEmbeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	c.logger.Debugf("Embed (HTTP): %d texts, %d prompt tokens", len(texts), out.PromptTokens)
+	return out.Embeddings, nil
+}
+
+func buildHTTPRequest(req PredictRequest) *httpCompletionRequest {
+	topP := 1.0
+	if req.TopP != nil {
+		topP = *req.TopP
+	}
+	topK := 0
+	if req.TopK != nil {
+		topK = *req.TopK
+	}
+
+	httpReq := &httpCompletionRequest{
+		Model:       req.ModelName,
+		Prompt:      req.Message,
+		Stream:      true,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        topP,
+		TopK:        topK,
+	}
+
+	if req.hasOptions() {
+		httpReq.Options = &httpCompletionOptions{
+			MinP:                 float32Ptr(req.MinP),
+			MinTokensToKeep:      int32Ptr(req.MinTokensToKeep),
+			MaxKvSize:            int32Ptr(req.MaxKvSize),
+			PrefillStepSize:      int32Ptr(req.PrefillStepSize),
+			KvBits:               int32Ptr(req.KvBits),
+			KvGroupSize:          int32Ptr(req.KvGroupSize),
+			QuantizedKvStart:     int32Ptr(req.QuantizedKvStart),
+			RepetitionPenalty:    float32Ptr(req.RepetitionPenalty),
+			LengthPenalty:        float32Ptr(req.LengthPenalty),
+			DiversityPenalty:     float32Ptr(req.DiversityPenalty),
+			NoRepeatNgramSize:    int32Ptr(req.NoRepeatNgramSize),
+			RandomSeed:           int32Ptr(req.RandomSeed),
+			StreamIntervalTokens: int32Ptr(req.StreamIntervalTokens),
+			StreamIntervalMs:     int32Ptr(req.StreamIntervalMs),
+			PromptLookup:         int32Ptr(req.PromptLookup),
+			Regex:                req.Regex,
+			StopTokenIDs:         req.StopTokenIDs,
+			BannedStrings:        req.BannedStrings,
+		}
+	}
+
+	return httpReq
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// LoadProgress is one progress update of a model load.
+type LoadProgress struct {
+	Fraction    float32 // 0..1 over the whole load
+	Stage       string  // reading, loading or ready
+	BytesLoaded int64
+	BytesTotal  int64 // 0 if unknown
+	ETA         time.Duration
+}
+
+// LoadModelResult describes a completed model load.
+type LoadModelResult struct {
+	Model      string
+	Duration   time.Duration // wall time seen by the client
+	BytesTotal int64         // model file size, 0 if the server did not report it
+	Updates    int           // progress updates received
+}
+
+// loadTracker accumulates the result of a load while forwarding its progress.
+type loadTracker struct {
+	result   LoadModelResult
+	start    time.Time
+	progress chan<- LoadProgress
+}
+
+func newLoadTracker(name string, progress chan<- LoadProgress) *loadTracker {
+	return &loadTracker{
+		result:   LoadModelResult{Model: name},
+		start:    time.Now(),
+		progress: progress,
+	}
+}
+
+// update records p and forwards it unless ctx is done first, so a caller
+// that stops reading the progress channel can still cancel the load.
+func (t *loadTracker) update(ctx context.Context, p LoadProgress) error {
+	t.result.Updates++
+	if p.BytesTotal > 0 {
+		t.result.BytesTotal = p.BytesTotal
+	}
+	if t.progress == nil {
+		return nil
+	}
+	select {
+	case t.progress <- p:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *loadTracker) done() LoadModelResult {
+	t.result.Duration = time.Since(t.start)
+	return t.result
+}
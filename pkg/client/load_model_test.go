@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHTTPClient(t *testing.T, handler http.HandlerFunc) Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	c, err := newHTTPClient(host, p, nil, &testLogger{t: t})
+	require.NoError(t, err)
+	return c
+}
+
+func TestHTTPLoadModelResult(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"progress\":0,\"stage\":\"reading\",\"bytes_total\":100}\n\n")
+		fmt.Fprint(w, "data: {\"progress\":1,\"stage\":\"ready\",\"bytes_loaded\":100,\"bytes_total\":100}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	progress := make(chan LoadProgress, 2)
+	result, err := c.LoadModel(context.Background(), "m.gguf", progress)
+	require.NoError(t, err)
+	require.Equal(t, "m.gguf", result.Model)
+	require.Equal(t, int64(100), result.BytesTotal)
+	require.Equal(t, 2, result.Updates)
+	require.Equal(t, "reading", (<-progress).Stage)
+	require.Equal(t, LoadProgress{Fraction: 1, Stage: "ready", BytesLoaded: 100, BytesTotal: 100}, <-progress)
+}
+
+func TestHTTPLoadModelServerError(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: error\ndata: \"no such file\"\n\n")
+	})
+
+	_, err := c.LoadModel(context.Background(), "m.gguf", nil)
+	require.ErrorIs(t, err, ErrLoadFailed)
+	var serverErr *ServerError
+	require.ErrorAs(t, err, &serverErr)
+	require.Equal(t, "no such file", serverErr.Message)
+}
+
+func TestHTTPLoadModelTruncatedStream(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"progress\":0.5,\"stage\":\"loading\"}\n\n")
+	})
+
+	_, err := c.LoadModel(context.Background(), "m.gguf", nil)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrLoadFailed)
+}
+
+func TestHTTPLoadModelCanceledWhileProgressBlocked(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"progress\":0.5,\"stage\":\"loading\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// Nobody reads the progress channel; cancellation must still return.
+	_, err := c.LoadModel(ctx, "m.gguf", make(chan LoadProgress))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
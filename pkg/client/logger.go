@@ -0,0 +1,27 @@
+package client
+
+import "github.com/hypernetix/llamacpp_server/internal/logging"
+
+// Logger receives the client's logs. The server's logger implements it.
+type Logger interface {
+	Debugf(msg string, args ...interface{})
+	Infof(msg string, args ...interface{})
+	Warnf(msg string, args ...interface{})
+	Errorf(msg string, args ...interface{})
+}
+
+// nopLogger discards logs.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// withModule tags the lines of logger with module if it supports fields.
+func withModule(logger Logger, module string) Logger {
+	if l, ok := logger.(logging.SprintfLogger); ok {
+		return l.With("module", module)
+	}
+	return logger
+}
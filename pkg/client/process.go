@@ -1,4 +1,4 @@
-package llmservice
+package client
 
 import (
 	"bufio"
@@ -10,8 +10,6 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
-
-	"github.com/hypernetix/llamacpp_server/internal/logging"
 )
 
 type Process interface {
@@ -26,23 +24,23 @@ func (p *process) Stop() {
 	p.monitor.stop()
 }
 
-func NewProcess(logger logging.SprintfLogger, path string, args []string) (Process, error) {
+func NewProcess(logger Logger, path string, args []string) (Process, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	command := &command{
 		path:   path,
 		args:   args,
-		logger: logger.With("module", "process.command"),
+		logger: withModule(logger, "process.command"),
 	}
 	output := &output{
-		logger: logger.With("module", "process.output"),
+		logger: withModule(logger, "process.output"),
 	}
 	monitor := &monitor{
 		command:     command,
 		output:      output,
 		ctx:         ctx,
 		cancel:      cancel,
-		logger:      logger.With("module", "process.monitor"),
+		logger:      withModule(logger, "process.monitor"),
 		retryPeriod: 10 * time.Second, // Default retry period
 	}
 
@@ -56,7 +54,7 @@ func NewProcess(logger logging.SprintfLogger, path string, args []string) (Proce
 type monitor struct {
 	command     *command
 	output      *output
-	logger      logging.SprintfLogger
+	logger      Logger
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
@@ -139,7 +137,7 @@ func (l *monitor) stop() {
 }
 
 type output struct {
-	logger logging.SprintfLogger
+	logger Logger
 }
 
 func (r *output) read(stdout io.ReadCloser) {
@@ -160,7 +158,7 @@ func (r *output) read(stdout io.ReadCloser) {
 type command struct {
 	path   string
 	args   []string
-	logger logging.SprintfLogger
+	logger Logger
 }
 
 func (r *command) start(ctx context.Context) (*exec.Cmd, io.ReadCloser, error) {
@@ -1,4 +1,4 @@
-package llmservice
+package client
 
 import (
 	"bytes"
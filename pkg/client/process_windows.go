@@ -1,6 +1,6 @@
 //go:build windows
 
-package llmservice
+package client
 
 import (
 	"os/exec"
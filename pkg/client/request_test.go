@@ -0,0 +1,77 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRequestsMapAllOptions(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	floatPtr := func(f float64) *float64 { return &f }
+	regex := "[0-9]+"
+	req := PredictRequest{
+		ModelName:            "m",
+		Message:              "hi",
+		TopK:                 intPtr(40),
+		MinP:                 floatPtr(0.05),
+		RepetitionPenalty:    floatPtr(1.1),
+		RandomSeed:           intPtr(7),
+		MinTokensToKeep:      intPtr(2),
+		MaxKvSize:            intPtr(4096),
+		PrefillStepSize:      intPtr(256),
+		KvBits:               intPtr(8),
+		KvGroupSize:          intPtr(64),
+		QuantizedKvStart:     intPtr(1000),
+		LengthPenalty:        floatPtr(0.5),
+		DiversityPenalty:     floatPtr(0.25),
+		NoRepeatNgramSize:    intPtr(3),
+		StreamIntervalTokens: intPtr(4),
+		StreamIntervalMs:     intPtr(50),
+		PromptLookup:         intPtr(5),
+		Regex:                &regex,
+		StopTokenIDs:         []int{13, 42},
+		BannedStrings:        []string{"foo"},
+	}
+
+	p := buildProtoRequest(req)
+	require.Equal(t, int32(40), p.TopK)
+	require.NotNil(t, p.Options)
+	require.Equal(t, float32(0.05), p.Options.GetMinP())
+	require.Equal(t, float32(1.1), p.Options.GetRepetitionPenalty())
+	require.Equal(t, int32(7), p.Options.GetRandomSeed())
+	require.Equal(t, int32(2), p.Options.GetMinTokensToKeep())
+	require.Equal(t, int32(4096), p.Options.GetMaxKvSize())
+	require.Equal(t, int32(256), p.Options.GetPrefillStepSize())
+	require.Equal(t, int32(8), p.Options.GetKvBits())
+	require.Equal(t, int32(64), p.Options.GetKvGroupSize())
+	require.Equal(t, int32(1000), p.Options.GetQuantizedKvStart())
+	require.Equal(t, float32(0.5), p.Options.GetLengthPenalty())
+	require.Equal(t, float32(0.25), p.Options.GetDiversityPenalty())
+	require.Equal(t, int32(3), p.Options.GetNoRepeatNgramSize())
+	require.Equal(t, int32(4), p.Options.GetStreamIntervalTokens())
+	require.Equal(t, int32(50), p.Options.GetStreamIntervalMs())
+	require.Equal(t, int32(5), p.Options.GetPromptLookup())
+	require.Equal(t, regex, p.Options.GetRegex())
+	require.Equal(t, []int32{13, 42}, p.Options.GetStopTokenIds())
+	require.Equal(t, []string{"foo"}, p.Options.GetBannedStrings())
+
+	h := buildHTTPRequest(req)
+	require.Equal(t, 40, h.TopK)
+	require.NotNil(t, h.Options)
+	require.Equal(t, float32(0.05), *h.Options.MinP)
+	require.Equal(t, int32(2), *h.Options.MinTokensToKeep)
+	require.Equal(t, int32(1000), *h.Options.QuantizedKvStart)
+	require.Equal(t, float32(0.25), *h.Options.DiversityPenalty)
+	require.Equal(t, int32(50), *h.Options.StreamIntervalMs)
+	require.Equal(t, int32(5), *h.Options.PromptLookup)
+	require.Equal(t, regex, *h.Options.Regex)
+	require.Equal(t, []int{13, 42}, h.Options.StopTokenIDs)
+	require.Equal(t, []string{"foo"}, h.Options.BannedStrings)
+}
+
+func TestBuildRequestsWithoutOptions(t *testing.T) {
+	req := PredictRequest{ModelName: "m", Message: "hi"}
+	require.Nil(t, buildProtoRequest(req).Options)
+	require.Nil(t, buildHTTPRequest(req).Options)
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// PredictStream is a running prediction. Recv returns its chunks in order,
+// then io.EOF once the prediction finished or the error that ended it.
+// Nothing runs behind the caller's back: a chunk is only read from the
+// server when Recv is called, so a caller that stops reading cannot block
+// anything but the prediction itself. Close aborts the prediction if it is
+// still running and must be called once the caller is done; it is safe to
+// call more than once and concurrently with Recv.
+type PredictStream interface {
+	Recv() (PredictResponse, error)
+	Close() error
+}
+
+// streamRelease ends a stream once: it cancels its request and releases
+// its slot in the inflight calls.
+type streamRelease struct {
+	once    sync.Once
+	release func()
+}
+
+func (r *streamRelease) do() {
+	r.once.Do(r.release)
+}
+
+// predictChanBuffer is the number of responses PredictChan buffers, so a
+// consumer that processes the chunks slower than they arrive does not hold
+// up the stream.
+const predictChanBuffer = 64
+
+// PredictChan starts a prediction and returns a channel that delivers its
+// chunks, then a last response with Done set and Error set if the
+// prediction failed. PredictChan owns the channel: it closes it after the
+// last response, so the consumer ranges over it and must never close it.
+// A consumer that stops reading early cancels ctx, which aborts the
+// prediction and closes the channel once the buffered responses were
+// dropped; the responses it did not read are discarded.
+func PredictChan(ctx context.Context, svc Client, req PredictRequest) (<-chan PredictResponse, error) {
+	stream, err := svc.Predict(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := make(chan PredictResponse, predictChanBuffer)
+	go func() {
+		defer close(resp)
+		defer stream.Close()
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				msg = PredictResponse{Done: true}
+			} else if err != nil {
+				msg = PredictResponse{Error: err, Done: true}
+			}
+			select {
+			case resp <- msg:
+			case <-ctx.Done():
+				return
+			}
+			if msg.Done {
+				return
+			}
+		}
+	}()
+	return resp, nil
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictStreamClose(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; ; i++ {
+			fmt.Fprintf(w, "data: {\"message\":\"a\",\"token\":%d,\"tokens\":%d}\n\n", i, i)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	})
+
+	stream, err := c.Predict(context.Background(), PredictRequest{Stream: true})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// A caller that stops reading closes the stream, which ends the request
+	// so a graceful shutdown does not wait for it.
+	require.NoError(t, stream.Close())
+	require.NoError(t, stream.Close())
+	_, err = stream.Recv()
+	require.Error(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, c.ShutdownGraceful(ctx))
+}
+
+func TestPredictChan(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"message\":\"a\",\"token\":1,\"tokens\":1}\n\n")
+		fmt.Fprint(w, "data: {\"message\":\"b\",\"token\":2,\"tokens\":2}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	resp, err := PredictChan(context.Background(), c, PredictRequest{Stream: true})
+	require.NoError(t, err)
+	var message string
+	var done int
+	for r := range resp {
+		require.NoError(t, r.Error)
+		if r.Done {
+			done++
+			continue
+		}
+		message += r.Message
+	}
+	require.Equal(t, "ab", message)
+	require.Equal(t, 1, done)
+}
+
+func TestPredictChanConsumerStopsEarly(t *testing.T) {
+	c := newTestHTTPClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"message\":\"a\",\"token\":1,\"tokens\":1}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	// The consumer stops after the first chunk: cancelling ctx must end the
+	// prediction and close the channel.
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := PredictChan(ctx, c, PredictRequest{Stream: true})
+	require.NoError(t, err)
+	require.Equal(t, "a", (<-resp).Message)
+	cancel()
+	for range resp {
+	}
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Second)
+	defer drainCancel()
+	require.NoError(t, c.ShutdownGraceful(drainCtx))
+}
@@ -0,0 +1,142 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const chatPrompt = "<|im_start|>user\nWhat is the capital of France?<|im_end|>\n<|im_start|>assistant\n"
+
+// loadModel loads the test model and returns the progress messages.
+func loadModel(t *testing.T) []*proto.LoadModelResponse {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	stream, err := client.LoadModel(ctx, &proto.LoadModelRequest{Path: modelPath})
+	require.NoError(t, err)
+	var progress []*proto.LoadModelResponse
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return progress
+		}
+		require.NoError(t, err)
+		progress = append(progress, msg)
+	}
+}
+
+// predict runs req and returns the streamed text and the final response.
+func predict(t *testing.T, req *proto.PredictRequest) (string, *proto.PredictResponse) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stream, err := client.Predict(ctx, req)
+	require.NoError(t, err)
+	var text strings.Builder
+	var last *proto.PredictResponse
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		text.Write(msg.Message)
+		last = msg
+	}
+	require.NotNil(t, last, "no response")
+	return text.String(), last
+}
+
+func TestLoadModel(t *testing.T) {
+	progress := loadModel(t)
+	require.NotEmpty(t, progress)
+	final := progress[len(progress)-1]
+	require.Equal(t, proto.LoadStage_LOAD_STAGE_READY, final.Stage)
+	require.InDelta(t, 1, final.Progress, 1e-6)
+
+	// Loading again returns the loaded model.
+	require.NotEmpty(t, loadModel(t))
+}
+
+func TestModelNotFound(t *testing.T) {
+	_, err := client.GetModelInfo(context.Background(), &proto.GetModelInfoRequest{Path: "/no/such/model.gguf"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGetModelInfo(t *testing.T) {
+	loadModel(t)
+	info, err := client.GetModelInfo(context.Background(), &proto.GetModelInfoRequest{Path: modelPath})
+	require.NoError(t, err)
+	require.NotEmpty(t, info.Description)
+	require.NotZero(t, info.NParams)
+	require.NotNil(t, info.Eos)
+	require.Positive(t, info.NCtxTrain)
+	require.Positive(t, info.NLayer)
+}
+
+func TestPredictStreaming(t *testing.T) {
+	loadModel(t)
+	text, final := predict(t, &proto.PredictRequest{
+		Model:     modelPath,
+		Prompt:    chatPrompt,
+		Stream:    true,
+		MaxTokens: 16,
+	})
+	require.NotEmpty(t, strings.TrimSpace(text))
+	require.Positive(t, final.PromptTokens)
+	require.Positive(t, final.CompletionTokens)
+	require.LessOrEqual(t, final.CompletionTokens, int32(16))
+	require.NotEqual(t, proto.FinishReason_FINISH_REASON_UNSPECIFIED, final.FinishReason)
+	require.NotNil(t, final.Timings)
+}
+
+func TestPredictGreedyIsDeterministic(t *testing.T) {
+	loadModel(t)
+	req := &proto.PredictRequest{
+		Model:       modelPath,
+		Prompt:      chatPrompt,
+		MaxTokens:   24,
+		Temperature: 0,
+	}
+	first, _ := predict(t, req)
+	second, _ := predict(t, req)
+	require.NotEmpty(t, first)
+	require.Equal(t, first, second)
+}
+
+func TestPredictMessages(t *testing.T) {
+	loadModel(t)
+	text, final := predict(t, &proto.PredictRequest{
+		Model:     modelPath,
+		Messages:  []*proto.Message{{Role: "user", Content: "What is the capital of France?"}},
+		MaxTokens: 16,
+	})
+	require.NotEmpty(t, strings.TrimSpace(text))
+	require.Positive(t, final.CompletionTokens)
+}
+
+func TestScore(t *testing.T) {
+	loadModel(t)
+	res, err := client.Score(context.Background(), &proto.ScoreRequest{
+		Model:         modelPath,
+		Text:          "The capital of France is Paris.",
+		TokenLogprobs: true,
+	})
+	require.NoError(t, err)
+	require.Positive(t, res.Tokens)
+	require.Len(t, res.TokenLogprobs, int(res.Tokens))
+	require.Negative(t, res.LogLikelihood)
+	require.Greater(t, res.Perplexity, 1.0)
+}
@@ -0,0 +1,283 @@
+//go:build e2e
+
+// Package e2e runs the server binary against a real model and exercises the
+// gRPC API and the OpenAI-compatible HTTP gateway served next to it end to
+// end. It only builds with the e2e tag:
+//
+//	go test -tags e2e -v ./tests/e2e
+//
+// Environment:
+//
+//	LLAMACPP_E2E_MODEL      model file to use; if unset, LLAMACPP_E2E_MODEL_URL
+//	                        is downloaded once into LLAMACPP_E2E_CACHE
+//	LLAMACPP_E2E_MODEL_URL  defaults to SmolLM2-135M-Instruct Q4_K_M (~95MB)
+//	LLAMACPP_E2E_CACHE      download directory (default: the user cache dir)
+//	LLAMACPP_E2E_SERVER     server binary; if unset, cmd/llamacppserver is
+//	                        used when built, else it is built with go build
+//
+// The server must find the llama.cpp shared libraries, e.g. through
+// LD_LIBRARY_PATH as set by "make run-e2etest".
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const defaultModelURL = "https://huggingface.co/bartowski/SmolLM2-135M-Instruct-GGUF/resolve/main/SmolLM2-135M-Instruct-Q4_K_M.gguf"
+
+var (
+	// client talks to the server started by TestMain and httpURL is the
+	// base URL of its HTTP gateway; modelPath is loaded by the tests that
+	// need it.
+	client    proto.LLMServerClient
+	httpURL   string
+	modelPath string
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	var err error
+	modelPath, err = ensureModel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: model: %v\n", err)
+		return 1
+	}
+	tmp, err := os.MkdirTemp("", "llamacpp-e2e-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+	server, err := serverBinary(tmp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: server binary: %v\n", err)
+		return 1
+	}
+
+	addr, stop, err := startServer(server, filepath.Join(tmp, "server.log"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		return 1
+	}
+	defer stop()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: dial %s: %v\n", addr, err)
+		return 1
+	}
+	defer conn.Close()
+	client = proto.NewLLMServerClient(conn)
+	if err := waitReady(client, 30*time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: server not ready: %v\n", err)
+		dumpLog(filepath.Join(tmp, "server.log"))
+		return 1
+	}
+
+	code := m.Run()
+	if code != 0 {
+		dumpLog(filepath.Join(tmp, "server.log"))
+	}
+	return code
+}
+
+// ensureModel returns the path of the test model, downloading it on first
+// use.
+func ensureModel() (string, error) {
+	if path := os.Getenv("LLAMACPP_E2E_MODEL"); path != "" {
+		return filepath.Abs(path)
+	}
+	url := os.Getenv("LLAMACPP_E2E_MODEL_URL")
+	if url == "" {
+		url = defaultModelURL
+	}
+	dir := os.Getenv("LLAMACPP_E2E_CACHE")
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cache, "llamacpp-server-e2e")
+	}
+	path := filepath.Join(dir, filepath.Base(url))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "e2e: downloading %s\n", url)
+	return path, download(url, path)
+}
+
+// download fetches url to path through a temporary file, so an interrupted
+// download is not mistaken for a cached model.
+func download(url, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// serverBinary returns the server to test, building it into dir if there is
+// no prebuilt one.
+func serverBinary(dir string) (string, error) {
+	if path := os.Getenv("LLAMACPP_E2E_SERVER"); path != "" {
+		return filepath.Abs(path)
+	}
+	exe := ""
+	if runtime.GOOS == "windows" {
+		exe = ".exe"
+	}
+	prebuilt, err := filepath.Abs(filepath.Join("..", "..", "cmd", "llamacppserver", "llamacppserver"+exe))
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(prebuilt); err == nil {
+		return prebuilt, nil
+	}
+	path := filepath.Join(dir, "llamacppserver"+exe)
+	cmd := exec.Command("go", "build", "-o", path, "../../cmd/llamacppserver")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("go build: %w", err)
+	}
+	return path, nil
+}
+
+// freePort returns a TCP port that is free at the time of the call.
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startServer runs the server with gRPC and the HTTP gateway, logging to
+// logPath. It returns the gRPC address and a function that stops the
+// server, and sets httpURL.
+func startServer(path, logPath string) (string, func(), error) {
+	port, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+	httpPort, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return "", nil, err
+	}
+	cmd := exec.Command(path,
+		"--host", "127.0.0.1",
+		"--grpc-port", strconv.Itoa(port),
+		"--http-port", strconv.Itoa(httpPort),
+		"--ngpu", "0",
+		"--n-parallel", "2",
+		"--ctx-size", "2048",
+	)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return "", nil, fmt.Errorf("start %s: %w", path, err)
+	}
+	stop := func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+		done := make(chan struct{})
+		go func() {
+			_ = cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			_ = cmd.Process.Kill()
+			<-done
+		}
+		logFile.Close()
+	}
+	httpURL = "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(httpPort))
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), stop, nil
+}
+
+// waitReady pings the server and checks the health of its HTTP gateway
+// until both answer or timeout elapses.
+func waitReady(c proto.LLMServerClient, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := c.Ping(ctx, &proto.PingRequest{})
+		cancel()
+		if err == nil {
+			err = httpHealth()
+		}
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func httpHealth() error {
+	resp, err := http.Get(httpURL + "/health")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /health: %s", resp.Status)
+	}
+	return nil
+}
+
+func dumpLog(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "--- server log ---\n%s--- end of server log ---\n", data)
+}
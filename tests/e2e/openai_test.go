@@ -0,0 +1,136 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hypernetix/llamacpp_server/api/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The HTTP gateway is served by the same process and service as the gRPC
+// API, so the tests below load models over gRPC and use them over HTTP.
+
+type oaiChoice struct {
+	Text  string `json:"text"`
+	Delta *struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"delta"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+type oaiResponse struct {
+	Object  string      `json:"object"`
+	Choices []oaiChoice `json:"choices"`
+	Usage   *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// postJSON posts body to the gateway at path and returns the response,
+// which the caller closes.
+func postJSON(t *testing.T, path string, body any) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := http.Post(httpURL+path, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestOpenAIModelsListsGRPCLoads(t *testing.T) {
+	loadModel(t)
+	resp, err := http.Get(httpURL + "/v1/models")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	var ids []string
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	require.Contains(t, ids, modelPath)
+}
+
+func TestOpenAICompletionMatchesGRPC(t *testing.T) {
+	loadModel(t)
+	text, final := predict(t, &proto.PredictRequest{
+		Model:       modelPath,
+		Prompt:      chatPrompt,
+		MaxTokens:   24,
+		Temperature: 0,
+	})
+
+	resp := postJSON(t, "/v1/completions", map[string]any{
+		"model":       modelPath,
+		"prompt":      chatPrompt,
+		"max_tokens":  24,
+		"temperature": 0,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out oaiResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Equal(t, "text_completion", out.Object)
+	require.Len(t, out.Choices, 1)
+	require.Equal(t, text, out.Choices[0].Text)
+	require.NotNil(t, out.Usage)
+	require.Equal(t, int(final.CompletionTokens), out.Usage.CompletionTokens)
+}
+
+func TestOpenAIChatCompletionStream(t *testing.T) {
+	loadModel(t)
+	resp := postJSON(t, "/v1/chat/completions", map[string]any{
+		"model":      modelPath,
+		"messages":   []map[string]string{{"role": "user", "content": "What is the capital of France?"}},
+		"max_tokens": 16,
+		"stream":     true,
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var content strings.Builder
+	var finishReason *string
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		require.False(t, done, "chunk after [DONE]")
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk oaiResponse
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		require.Equal(t, "chat.completion.chunk", chunk.Object)
+		require.Len(t, chunk.Choices, 1)
+		if d := chunk.Choices[0].Delta; d != nil {
+			content.WriteString(d.Content)
+		}
+		if chunk.Choices[0].FinishReason != nil {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.True(t, done, "no [DONE]")
+	require.NotNil(t, finishReason)
+	require.NotEmpty(t, strings.TrimSpace(content.String()))
+}